@@ -2,138 +2,570 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
 	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+	"github.com/fatihserhatturan/logflux/internal/config"
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/internal/pipeline"
 	"github.com/fatihserhatturan/logflux/pkg/models"
 )
 
 func main() {
 	fmt.Println("🌊 LogFlux Collector - Starting...")
 
-	if len(os.Args) < 2 {
+	configPath := flag.String("config", "", "path to a YAML/JSON config file")
+	minLevel := flag.String("min-level", "", "drop entries below this level (DEBUG, INFO, WARNING, ERROR, CRITICAL)")
+	metricsAddr := flag.String("metrics-addr", "", "serve Prometheus metrics on this address (e.g. :9100)")
+	format := flag.String("format", sinks.FormatText, "stdout output format (text or json)")
+	color := flag.String("color", sinks.ColorAuto, "color levels in text output (auto, always or never)")
+	bufferSize := flag.Int("buffer", collector.DefaultBufferSize, "entries buffered between the sources and the pipeline")
+	overflow := flag.String("overflow", string(collector.OverflowBlock), "what a full buffer does (block, drop-newest or drop-oldest)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", defaultShutdownTimeout, "how long to wait for buffered entries to be written on shutdown")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	if *format != sinks.FormatText && *format != sinks.FormatJSON {
+		fmt.Printf("❌ Invalid -format %q (expected text or json)\n", *format)
+		os.Exit(1)
+	}
+	if *color != sinks.ColorAuto && *color != sinks.ColorAlways && *color != sinks.ColorNever {
+		fmt.Printf("❌ Invalid -color %q (expected auto, always or never)\n", *color)
+		os.Exit(1)
+	}
+	if *bufferSize <= 0 {
+		fmt.Printf("❌ Invalid -buffer %d (expected a positive size)\n", *bufferSize)
+		os.Exit(1)
+	}
+	policy, err := collector.ParseOverflowPolicy(*overflow)
+	if err != nil {
+		fmt.Printf("❌ Invalid -overflow: %v\n", err)
+		os.Exit(1)
+	}
+	if *shutdownTimeout <= 0 {
+		fmt.Printf("❌ Invalid -shutdown-timeout %s (expected a positive duration)\n", *shutdownTimeout)
+		os.Exit(1)
+	}
+
+	var cfg *config.Config
+	var components *config.Components
+	if *configPath != "" {
+		cfg, components, err = loadConfig(*configPath)
+	} else {
+		components, err = componentsFromArgs(flag.Args(), *format, *color)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
 		printUsage()
 		os.Exit(1)
 	}
 
-	mode := os.Args[1]
+	// SIGHUP reloads the config file, when there is one
+	var reloads chan os.Signal
+	if cfg != nil {
+		reloads = make(chan os.Signal, 1)
+		signal.Notify(reloads, syscall.SIGHUP)
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, components, runOptions{
+		minLevel:        *minLevel,
+		metricsAddr:     *metricsAddr,
+		bufferSize:      *bufferSize,
+		overflow:        policy,
+		shutdownTimeout: *shutdownTimeout,
+		configPath:      *configPath,
+		config:          cfg,
+		reloads:         reloads,
+	})
+	stop()
+	os.Exit(code)
+}
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+// defaultShutdownTimeout bounds the drain on shutdown
+const defaultShutdownTimeout = 10 * time.Second
 
-	logChan := make(chan *models.LogEntry, 100)
+// runOptions are the command line settings run applies
+type runOptions struct {
+	minLevel        string
+	metricsAddr     string
+	bufferSize      int
+	overflow        collector.OverflowPolicy
+	shutdownTimeout time.Duration
 
-	var err error
-	switch mode {
-	case "file":
-		err = startFileMode(ctx, logChan)
-	case "syslog":
-		err = startSyslogMode(ctx, logChan)
-	case "http":
-		err = startHTTPMode(ctx, logChan)
-	default:
-		fmt.Printf("❌ Unknown mode: %s\n", mode)
-		printUsage()
-		os.Exit(1)
+	// config was loaded from configPath and is reloaded from it on every
+	// signal from reloads
+	configPath string
+	config     *config.Config
+	reloads    <-chan os.Signal
+}
+
+// run collects until ctx is cancelled or every source has finished its
+// input, then stops the sources and waits up to the shutdown timeout for
+// the buffered entries to reach the sinks. It returns the exit code: 0
+// after a clean shutdown, 1 if the collector couldn't start, a source
+// failed for good or entries may have been lost.
+func run(ctx context.Context, components *config.Components, opts runOptions) int {
+	// The minimum level is always installed so it can be changed at runtime.
+	// -min-level overrides the config's.
+	predicates := components.Predicates
+	if components.Level == nil {
+		components.Level = pipeline.NewLevelFilter("")
+		predicates = append(predicates, components.Level.Predicate())
 	}
+	if opts.minLevel != "" {
+		level, err := models.ParseLevel(opts.minLevel)
+		if err != nil {
+			fmt.Printf("❌ Invalid -min-level: %v\n", err)
+			return 1
+		}
+		components.Level.SetMinLevel(level)
+	}
+	filter := pipeline.NewFilter(predicates...)
+	exposeLevelControl(components.Sources, components.Level)
+
+	// Sources stop with ctx, everything downstream only once it has drained
+	// or the shutdown timed out
+	drainCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
+	buffer, err := collector.NewBuffer(opts.bufferSize, opts.overflow)
 	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	go buffer.Run(drainCtx)
+
+	manager := collector.NewSourceManager()
+	for _, source := range components.Sources {
+		manager.Add(source)
+	}
+	for name, limit := range components.RateLimits {
+		if err := manager.SetRateLimit(name, limit); err != nil {
+			fmt.Printf("❌ Invalid rate limit: %v\n", err)
+			return 1
+		}
+	}
+
+	if err := manager.Start(ctx, buffer.In()); err != nil {
 		fmt.Printf("❌ Failed to start: %v\n", err)
-		os.Exit(1)
+		return 1
+	}
+
+	if opts.metricsAddr != "" {
+		startMetricsServer(opts.metricsAddr, components.Sinks)
 	}
 
 	fmt.Println("✅ Collector started, processing logs...")
 	fmt.Println("Press Ctrl+C to stop")
 
-	go processLogs(logChan)
+	// Stitch multiline entries before dedup so whole traces are compared
+	entries := buffer.Out()
+	if components.Multiline != nil {
+		entries = runStage(drainCtx, entries, components.Multiline.Run)
+	}
+	// Lines read raw are parsed once whole, across the pool's workers
+	if components.Parser != nil {
+		entries = runStage(drainCtx, entries, components.Parser.Run)
+	}
+	// Levels are guessed before sampling, which keeps a share per level
+	if components.Levels != nil {
+		entries = runStage(drainCtx, entries, components.Levels.Run)
+	}
+	if components.Sampler != nil {
+		entries = runStage(drainCtx, entries, components.Sampler.Run)
+	}
+	if components.Deduper != nil {
+		entries = runStage(drainCtx, entries, components.Deduper.Run)
+	}
+	if components.Templates != nil {
+		entries = runStage(drainCtx, entries, components.Templates.Run)
+	}
+	if components.Enricher != nil {
+		entries = runStage(drainCtx, entries, components.Enricher.Run)
+	}
+
+	p := pipeline.NewPipeline(components.Sinks, pipelineStages(components, filter)...)
+	if err := p.Start(drainCtx, entries); err != nil {
+		fmt.Printf("❌ Failed to start pipeline: %v\n", err)
+		manager.Stop()
+		return 1
+	}
 
-	<-sigChan
+	exposeHealth(components.Sources, manager, buffer, p)
+
+	reload := &reloader{
+		path:       opts.configPath,
+		started:    opts.config,
+		current:    opts.config,
+		chains:     components.SinkChains,
+		pipeline:   p,
+		filter:     filter,
+		level:      components.Level,
+		fixedLevel: opts.minLevel != "",
+	}
+	code := 0
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-manager.Finished():
+			fmt.Println("\n🏁 Every source has finished")
+			for _, source := range components.Sources {
+				if failed, ok := source.(interface{ Err() error }); ok && failed.Err() != nil {
+					fmt.Printf("❌ Source %s failed: %v\n", source.Name(), failed.Err())
+					code = 1
+				}
+			}
+			break wait
+		case <-opts.reloads:
+			fmt.Printf("🔄 Reloading %s\n", opts.configPath)
+			if err := reload.reload(); err != nil {
+				fmt.Printf("❌ Reload failed, keeping the running config: %v\n", err)
+			}
+		}
+	}
 	fmt.Println("\n🛑 Shutting down gracefully...")
+	if err := manager.Stop(); err != nil {
+		fmt.Printf("❌ Failed to stop sources: %v\n", err)
+	}
+
+	// Closing the buffer lets every stage finish once it has passed on
+	// what it holds, then the sinks are flushed
+	buffer.Close()
+	drained := make(chan error, 1)
+	go func() {
+		<-p.Done()
+		drained <- p.Stop()
+	}()
+
+	select {
+	case err := <-drained:
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			code = 1
+		}
+	case <-time.After(opts.shutdownTimeout):
+		fmt.Printf("❌ Shutdown timed out after %s, buffered entries may be lost\n", opts.shutdownTimeout)
+		code = 1
+	}
 	cancel()
-	time.Sleep(500 * time.Millisecond)
+
+	// Sources and the parse pool are done with the dead letter by now
+	if dl := components.DeadLetter; dl != nil {
+		if err := dl.Stop(); err != nil {
+			fmt.Printf("❌ Failed to stop dead letter sink: %v\n", err)
+			code = 1
+		}
+	}
+
+	fmt.Printf("📊 Processed %d entries, dropped %d\n", p.Processed(), p.Dropped()+buffer.Dropped())
+	if dropped := filter.Dropped(); dropped > 0 {
+		fmt.Printf("🔇 Filtered out %d entries\n", dropped)
+	}
+	if unacked := p.Unacked(); unacked > 0 {
+		fmt.Printf("⚠️  Left %d written entries unacked, their sinks failed to flush\n", unacked)
+	}
+	if dropped := buffer.Dropped(); dropped > 0 {
+		fmt.Printf("⚠️  Dropped %d entries on a full buffer (%s)\n", dropped, buffer.Policy())
+	}
+	for name := range components.RateLimits {
+		if dropped := manager.Limiter(name).Dropped(); dropped > 0 {
+			fmt.Printf("🚦 Rate limit dropped %d entries from %s\n", dropped, name)
+		}
+	}
+	if dl := components.DeadLetter; dl != nil && dl.Written() > 0 {
+		fmt.Printf("📥 Dead-lettered %d unparseable inputs to %s\n", dl.Written(), dl.Name())
+	}
 	fmt.Println("👋 Goodbye!")
+	return code
 }
 
-func startFileMode(ctx context.Context, out chan<- *models.LogEntry) error {
-	if len(os.Args) < 3 {
-		return fmt.Errorf("file path required")
+// pipelineStages puts the stages entries go through one at a time in order
+func pipelineStages(components *config.Components, filter *pipeline.Filter) []pipeline.Stage {
+	stages := []pipeline.Stage{pipeline.FilterStage(filter)}
+	if components.Coercer != nil {
+		stages = append(stages, components.Coercer.Stage())
+	}
+	if components.Redactor != nil {
+		stages = append(stages, pipeline.Transform(components.Redactor.Apply))
+	}
+	// Cut after redaction, so a secret straddling the cut is still caught
+	if components.Truncator != nil {
+		stages = append(stages, components.Truncator.Stage())
 	}
+	// Alerts come after redaction so their samples carry no secrets
+	for _, alerter := range components.Alerters {
+		stages = append(stages, alerter.Stage())
+	}
+	// Fields are reshaped last, for the sinks, so the stages above see
+	// them as the sources produced them
+	if components.Flattener != nil {
+		stages = append(stages, components.Flattener.Stage())
+	}
+	return stages
+}
+
+// exposeLevelControl lets HTTP sources change the minimum level
+func exposeLevelControl(srcs []collector.Source, level *pipeline.LevelFilter) {
+	for _, source := range srcs {
+		if supervisor, ok := source.(*collector.Supervisor); ok {
+			source = supervisor.Source()
+		}
+		if receiver, ok := source.(*sources.HTTPReceiver); ok {
+			receiver.SetLevelControl(level)
+		}
+	}
+}
+
+// healthStaleness is how long /health waits for an entry from any source
+// before reporting degraded
+const healthStaleness = 5 * time.Minute
 
-	logFile := os.Args[2]
+// exposeHealth makes the /health of HTTP sources reflect the buffer's fill,
+// the sinks' recent errors and whether any source is producing
+func exposeHealth(srcs []collector.Source, manager *collector.SourceManager, buffer *collector.Buffer, p *pipeline.Pipeline) {
+	lastActivity := func() time.Time {
+		var last time.Time
+		for _, source := range manager.Sources() {
+			if at := source.Stats().LastActivity; at.After(last) {
+				last = at
+			}
+		}
+		return last
+	}
+
+	for _, source := range srcs {
+		if supervisor, ok := source.(*collector.Supervisor); ok {
+			source = supervisor.Source()
+		}
+		if receiver, ok := source.(*sources.HTTPReceiver); ok {
+			receiver.AddHealthCheck("channel", sources.ChannelFillCheck(func() (int, int) {
+				return buffer.Len(), buffer.Size()
+			}))
+			receiver.AddHealthCheck("sinks", sources.SinkErrorCheck(p.SinkErrorRate))
+			receiver.AddHealthCheck("sources", sources.StalenessCheck(lastActivity, healthStaleness))
+		}
+	}
+}
+
+// loadConfig builds the collector components from a config file
+func loadConfig(path string) (*config.Config, *config.Components, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmt.Printf("📄 Loaded config: %s\n", path)
+
+	components, err := cfg.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, components, nil
+}
+
+// componentsFromArgs builds a single source and a stdout sink from the
+// positional mode arguments
+func componentsFromArgs(args []string, format, color string) (*config.Components, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("mode or -config required")
+	}
+
+	var source collector.Source
+	var err error
+	switch mode := args[0]; mode {
+	case "file":
+		source, err = newFileSource(args)
+	case "syslog":
+		source, err = newSyslogSource(args)
+	case "http":
+		source, err = newHTTPSource(args)
+	case "gelf":
+		source, err = newGELFSource(args)
+	case "replay":
+		source, err = newReplaySource(args)
+	default:
+		return nil, fmt.Errorf("unknown mode: %s", mode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	stdout := sinks.NewStdoutSinkWithFormat(os.Stdout, format)
+	stdout.SetColor(color)
+
+	return &config.Components{
+		Sources: []collector.Source{source},
+		Sinks:   []collector.Sink{stdout},
+	}, nil
+}
+
+func newFileSource(args []string) (collector.Source, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("file path required")
+	}
+
+	logFile := args[1]
 	logFile = filepath.Clean(logFile)
 
 	if _, err := os.Stat(logFile); os.IsNotExist(err) {
 		absPath, _ := filepath.Abs(logFile)
-		return fmt.Errorf("file not found: %s (absolute: %s)", logFile, absPath)
+		return nil, fmt.Errorf("file not found: %s (absolute: %s)", logFile, absPath)
 	}
 
 	fmt.Printf("📂 Reading from file: %s\n", logFile)
 
-	reader := sources.NewFileReader(logFile)
-	return reader.Start(ctx, out)
+	return sources.NewFileReader(logFile), nil
 }
 
-func startSyslogMode(ctx context.Context, out chan<- *models.LogEntry) error {
-	if len(os.Args) < 4 {
-		return fmt.Errorf("protocol and address required")
+func newSyslogSource(args []string) (collector.Source, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("protocol and address required")
 	}
 
-	protocol := os.Args[2]
-	addr := os.Args[3]
+	protocol := args[1]
+	addr := args[2]
 
 	fmt.Printf("📡 Starting syslog receiver: %s on %s\n", protocol, addr)
 
-	receiver := sources.NewSyslogReceiver(addr, protocol)
-	return receiver.Start(ctx, out)
+	return sources.NewSyslogReceiver(addr, protocol), nil
 }
 
-func startHTTPMode(ctx context.Context, out chan<- *models.LogEntry) error {
-	if len(os.Args) < 3 {
-		return fmt.Errorf("address required")
+func newHTTPSource(args []string) (collector.Source, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("address required")
 	}
 
-	addr := os.Args[2] // e.g., ":8080"
+	addr := args[1] // e.g., ":8080"
 
 	fmt.Printf("📡 Starting HTTP receiver on %s\n", addr)
 
-	receiver := sources.NewHTTPReceiver(addr)
-	return receiver.Start(ctx, out)
-}
-
-func processLogs(logChan <-chan *models.LogEntry) {
-	count := 0
-	for entry := range logChan {
-		count++
-		fmt.Printf("[%d] %s [%s] %s: %s",
-			count,
-			entry.Timestamp.Format(time.RFC3339),
-			entry.Level,
-			entry.Source,
-			entry.Message,
-		)
-		if len(entry.Message) > 0 && entry.Message[len(entry.Message)-1] != '\n' {
-			fmt.Println()
+	return sources.NewHTTPReceiver(addr), nil
+}
+
+func newGELFSource(args []string) (collector.Source, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("address required")
+	}
+
+	addr := args[1] // e.g., ":12201"
+
+	fmt.Printf("📡 Starting GELF receiver on %s\n", addr)
+
+	return sources.NewGELFReceiver(addr), nil
+}
+
+func newReplaySource(args []string) (collector.Source, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("capture path required")
+	}
+
+	path := filepath.Clean(args[1])
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+
+	// Optional speed multiplier, or "max" to ignore timing
+	var opts sources.ReplayOptions
+	if len(args) > 2 {
+		if args[2] == "max" {
+			opts.IgnoreTiming = true
+		} else {
+			speed, err := strconv.ParseFloat(args[2], 64)
+			if err != nil || speed <= 0 {
+				return nil, fmt.Errorf("invalid speed %q (expected a positive number or max)", args[2])
+			}
+			opts.Speed = speed
 		}
 	}
+
+	fmt.Printf("⏪ Replaying capture: %s\n", path)
+
+	return sources.NewReplayReaderWithOptions(path, opts), nil
+}
+
+// runStage starts a channel stage and returns its output
+func runStage(ctx context.Context, in <-chan *models.LogEntry, run func(context.Context, <-chan *models.LogEntry, chan<- *models.LogEntry)) <-chan *models.LogEntry {
+	out := make(chan *models.LogEntry, 100)
+	go run(ctx, in, out)
+	return out
+}
+
+// startMetricsServer serves /metrics on a separate admin address, plus
+// /recent when a memory sink is configured
+func startMetricsServer(addr string, sinkList []collector.Sink) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/stats", metrics.StatsHandler())
+
+	fmt.Printf("📊 Metrics available at http://%s/metrics\n", addr)
+	fmt.Printf("📈 Size and latency percentiles available at http://%s/stats\n", addr)
+
+	// Routed sinks count too
+	var all []collector.Sink
+	for _, sink := range sinkList {
+		if router, ok := sink.(*pipeline.Router); ok {
+			all = append(all, router.Sinks()...)
+			continue
+		}
+		all = append(all, sink)
+	}
+
+	for _, sink := range all {
+		if memory, ok := sink.(*sinks.MemorySink); ok {
+			mux.Handle("/recent", memory.Handler())
+			fmt.Printf("🧠 Recent entries available at http://%s/recent\n", addr)
+			break
+		}
+	}
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Printf("❌ Metrics server error: %v\n", err)
+		}
+	}()
 }
 
 func printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println("  File mode:   logflux file <path>")
-	fmt.Println("  Syslog mode: logflux syslog <udp|tcp> <address>")
-	fmt.Println("  HTTP mode:   logflux http <address>") // YENİ!
+	fmt.Println("  Config file: logflux -config <config.yaml>")
+	fmt.Println("  File mode:   logflux [options] file <path>")
+	fmt.Println("  Syslog mode: logflux [options] syslog <udp|tcp> <address>")
+	fmt.Println("  HTTP mode:   logflux [options] http <address>")
+	fmt.Println("  GELF mode:   logflux [options] gelf <address>")
+	fmt.Println("  Replay mode: logflux [options] replay <capture.jsonl> [speed|max]")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -config <path>      Load sources, sinks and filters from a YAML/JSON file, reloaded on SIGHUP")
+	fmt.Println("  -min-level <level>  Drop entries below level (e.g. WARNING)")
+	fmt.Println("  -metrics-addr <addr> Serve Prometheus metrics at <addr>/metrics, percentiles at /stats (and a memory sink at /recent)")
+	fmt.Println("  -format <text|json> Print entries as text (default) or JSON lines")
+	fmt.Println("  -color <mode>       Color levels: auto (default, TTY without NO_COLOR), always, never")
+	fmt.Println("  -buffer <n>         Entries buffered between sources and the pipeline (default 100)")
+	fmt.Println("  -overflow <policy>  When the buffer is full: block (default), drop-newest or drop-oldest")
+	fmt.Println("  -shutdown-timeout <d> Wait up to d for buffered entries on shutdown (default 10s), exit 1 if exceeded")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  logflux file test/testdata/sample.log")
 	fmt.Println("  logflux syslog udp :514")
 	fmt.Println("  logflux syslog tcp :514")
 	fmt.Println("  logflux http :8080")
+	fmt.Println("  logflux gelf :12201")
+	fmt.Println("  logflux replay capture.jsonl 10")
+	fmt.Println("  logflux -min-level WARNING http :8080")
+	fmt.Println("  logflux -format json file app.log | jq .message")
+	fmt.Println("  logflux -buffer 10000 -overflow drop-oldest syslog udp :514")
+	fmt.Println("  logflux -config config.yaml")
 }
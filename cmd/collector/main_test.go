@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/config"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// burstSource emits count entries as fast as it can, then signals sent
+type burstSource struct {
+	count int
+	sent  chan struct{}
+}
+
+func (b *burstSource) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	go func() {
+		defer close(b.sent)
+		for i := 0; i < b.count; i++ {
+			entry := models.NewLogEntry()
+			entry.Message = strconv.Itoa(i)
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *burstSource) Stop() error               { return nil }
+func (b *burstSource) Name() string              { return "burst" }
+func (b *burstSource) Stats() models.SourceStats { return models.SourceStats{} }
+
+// slowSink takes delay per write, or blocks until the write's context is
+// cancelled when stuck
+type slowSink struct {
+	delay time.Duration
+	stuck bool
+
+	mu      sync.Mutex
+	written int
+}
+
+func (s *slowSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	if s.stuck {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	s.written++
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *slowSink) Flush() error { return nil }
+func (s *slowSink) Name() string { return "slow" }
+
+func (s *slowSink) Written() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.written
+}
+
+// runUntilSent runs the collector and shuts it down once the source has
+// handed over every entry, returning the exit code and how long the
+// shutdown took
+func runUntilSent(t *testing.T, source *burstSource, sink collector.Sink, timeout time.Duration) (int, time.Duration) {
+	t.Helper()
+	components := &config.Components{
+		Sources: []collector.Source{source},
+		Sinks:   []collector.Sink{sink},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var stopped time.Time
+	go func() {
+		<-source.sent
+		stopped = time.Now()
+		cancel()
+	}()
+
+	code := run(ctx, components, runOptions{bufferSize: 50, overflow: collector.OverflowBlock, shutdownTimeout: timeout})
+	return code, time.Since(stopped)
+}
+
+func TestRun_CleanShutdownDrains(t *testing.T) {
+	source := &burstSource{count: 40, sent: make(chan struct{})}
+	sink := &slowSink{delay: time.Millisecond}
+
+	code, _ := runUntilSent(t, source, sink, 5*time.Second)
+	if code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	// Entries still buffered at the signal are written before run returns
+	if written := sink.Written(); written != 40 {
+		t.Errorf("Expected all 40 entries written, got %d", written)
+	}
+}
+
+func TestRun_StuckSinkTimesOut(t *testing.T) {
+	source := &burstSource{count: 5, sent: make(chan struct{})}
+	sink := &slowSink{stuck: true}
+
+	code, took := runUntilSent(t, source, sink, 100*time.Millisecond)
+	if code == 0 {
+		t.Error("Expected a non-zero exit code")
+	}
+	if took > 2*time.Second {
+		t.Errorf("Expected the shutdown to give up after the timeout, took %s", took)
+	}
+}
+
+// finishingSource is a burst source that reports finishing once it has
+// sent every entry, like a file read once
+type finishingSource struct {
+	burstSource
+}
+
+func (f *finishingSource) Done() <-chan struct{} { return f.sent }
+func (f *finishingSource) Err() error            { return nil }
+
+func TestRun_ExitsOnceSourcesFinish(t *testing.T) {
+	source := &finishingSource{burstSource{count: 40, sent: make(chan struct{})}}
+	sink := &slowSink{delay: time.Millisecond}
+	components := &config.Components{
+		Sources: []collector.Source{source},
+		Sinks:   []collector.Sink{sink},
+	}
+
+	done := make(chan int, 1)
+	go func() {
+		done <- run(context.Background(), components, runOptions{bufferSize: 50, overflow: collector.OverflowBlock, shutdownTimeout: 5 * time.Second})
+	}()
+
+	select {
+	case code := <-done:
+		if code != 0 {
+			t.Errorf("Expected exit code 0, got %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected run to return once its only source finished")
+	}
+	if written := sink.Written(); written != 40 {
+		t.Errorf("Expected all 40 entries written, got %d", written)
+	}
+}
+
+func TestRun_InvalidMinLevel(t *testing.T) {
+	components := &config.Components{
+		Sources: []collector.Source{&burstSource{sent: make(chan struct{})}},
+		Sinks:   []collector.Sink{&slowSink{}},
+	}
+	if code := run(context.Background(), components, runOptions{minLevel: "LOUD"}); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+}
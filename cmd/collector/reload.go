@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/config"
+	"github.com/fatihserhatturan/logflux/internal/pipeline"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// reloader applies a changed config file to a running collector without
+// losing what is buffered. The sinks and the stages entries go through one
+// at a time are swapped into the pipeline and the minimum level is updated
+// in place. Sources and the stream filters keep running as they started
+// until a restart, changes to them are only reported.
+type reloader struct {
+	path     string
+	started  *config.Config   // what the sources and stream filters run with
+	current  *config.Config   // what the sinks and stages were built from
+	chains   []collector.Sink // built for current.Sinks, in order
+	pipeline *pipeline.Pipeline
+	filter   *pipeline.Filter
+	level    *pipeline.LevelFilter
+
+	// fixedLevel keeps the level given with -min-level over the config's
+	fixedLevel bool
+}
+
+// reload reads the config file again and applies it. A config that
+// doesn't load or build leaves the running one untouched.
+func (r *reloader) reload() error {
+	next, err := config.Load(r.path)
+	if err != nil {
+		return err
+	}
+	components, err := next.BuildStages()
+	if err != nil {
+		return err
+	}
+	// Unchanged sinks are kept so they don't lose their connections and
+	// buffered writes
+	sinks, chains := r.pipeline.Sinks(), r.chains
+	if r.current.SinksChanged(next) {
+		built, err := r.current.RebuildSinks(next, r.chains)
+		if err != nil {
+			if built != nil {
+				// Sinks stopped for their successors were built again
+				r.swapSinks(built.Sinks, built.SinkChains)
+			}
+			return err
+		}
+		sinks, chains = built.Sinks, built.SinkChains
+	}
+
+	for _, section := range r.started.RestartRequired(next) {
+		fmt.Printf("⚠️  %s changed, restart to apply it\n", section)
+	}
+
+	if !r.fixedLevel {
+		var min models.LogLevel
+		if components.Level != nil {
+			min = components.Level.MinLevel()
+		}
+		r.level.SetMinLevel(min)
+	}
+	if err := r.pipeline.Reconfigure(sinks, pipelineStages(components, r.filter)...); err != nil {
+		// The new sinks are in place, only the old ones didn't stop cleanly
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	r.current, r.chains = next, chains
+
+	fmt.Printf("✅ Reloaded %s\n", r.path)
+	return nil
+}
+
+// swapSinks puts sinks in the pipeline, keeping its stages
+func (r *reloader) swapSinks(sinks, chains []collector.Sink) {
+	if err := r.pipeline.Reconfigure(sinks, r.pipeline.Stages()...); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	r.chains = chains
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/config"
+	"github.com/fatihserhatturan/logflux/internal/pipeline"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+const reloadConfig = `
+sources: [{type: http, params: {address: ':8080'}}]
+filters: {min_level: %s}
+`
+
+func TestReloader_MinLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(level string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(reloadConfig, level)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("error")
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, err := cfg.BuildStages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &slowSink{}
+	filter := pipeline.NewFilter(components.Predicates...)
+	p := pipeline.NewPipeline([]collector.Sink{sink}, pipelineStages(components, filter)...)
+	r := &reloader{path: path, started: cfg, current: cfg, pipeline: p, filter: filter, level: components.Level}
+
+	warning := func() bool {
+		entry := models.NewLogEntry()
+		entry.Level = models.LevelWarning
+		return p.Process(context.Background(), entry)
+	}
+	if warning() {
+		t.Fatal("Expected a WARNING dropped below ERROR")
+	}
+
+	writeConfig("warning")
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !warning() {
+		t.Error("Expected a WARNING kept after reloading with min_level warning")
+	}
+	if sink.Written() != 1 {
+		t.Errorf("Expected the running sink kept and written to, got %d writes", sink.Written())
+	}
+
+	// A broken config leaves the running one in place
+	writeConfig("LOUD")
+	if err := r.reload(); err == nil {
+		t.Error("Expected an error for an invalid level")
+	}
+	if components.Level.MinLevel() != models.LevelWarning {
+		t.Errorf("Expected the level kept at WARNING, got %s", components.Level.MinLevel())
+	}
+}
+
+const reloadSinksConfig = `
+sources: [{type: http, params: {address: ':8080'}}]
+sinks:
+  - {type: file, params: {path: %q, flush_interval: %s}, queue: {dir: %q}}
+  - {type: memory, params: {capacity: %d}}
+`
+
+func TestReloader_RebuildsOnlyChangedSinks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	out := filepath.Join(dir, "out.jsonl")
+	writeConfig := func(flushInterval string, capacity int) {
+		t.Helper()
+		config := fmt.Sprintf(reloadSinksConfig, out, flushInterval, filepath.Join(dir, "queue"), capacity)
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("1s", 10)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := pipeline.NewFilter(components.Predicates...)
+	p := pipeline.NewPipeline(components.Sinks, pipelineStages(components, filter)...)
+	r := &reloader{path: path, started: cfg, current: cfg, chains: components.SinkChains, pipeline: p, filter: filter, fixedLevel: true}
+	defer func() {
+		p.Stop()
+		for _, sink := range p.Sinks() {
+			if stopper, ok := sink.(interface{ Stop() error }); ok {
+				stopper.Stop()
+			}
+		}
+	}()
+
+	queued, memory := r.chains[0], r.chains[1]
+	writeConfig("1s", 20)
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if r.chains[0] != queued || p.Sinks()[0] != queued {
+		t.Error("Expected the unchanged queued sink kept")
+	}
+	if r.chains[1] == memory || p.Sinks()[1] != r.chains[1] {
+		t.Error("Expected the changed memory sink replaced")
+	}
+
+	// The new file sink shares the old one's queue, which is let go of
+	// before the new one opens it
+	writeConfig("2s", 20)
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if r.chains[0] == queued {
+		t.Fatal("Expected the changed queued sink replaced")
+	}
+	if err := queued.Write(context.Background(), models.NewLogEntry()); err == nil {
+		t.Error("Expected the replaced queue stopped")
+	}
+	entry := models.NewLogEntry()
+	entry.Message = "after reload"
+	if !p.Process(context.Background(), entry) {
+		t.Fatal("Expected the entry written to the new sinks")
+	}
+	if err := p.Sinks()[0].Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,3 +1,25 @@
 module github.com/fatihserhatturan/logflux
 
 go 1.21.5
+
+require (
+	github.com/gorilla/websocket v1.5.1
+	github.com/klauspost/compress v1.17.4
+	github.com/oklog/ulid/v2 v2.1.0
+	github.com/segmentio/kafka-go v0.4.47
+	go.opentelemetry.io/proto/otlp v1.0.0
+	google.golang.org/grpc v1.57.0
+	google.golang.org/protobuf v1.31.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.16.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20230530153820-e85fd2cbaebc // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230530153820-e85fd2cbaebc // indirect
+)
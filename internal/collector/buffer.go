@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// OverflowPolicy decides what a Buffer does with entries when it's full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock holds sources back until there is room
+	OverflowBlock OverflowPolicy = "block"
+
+	// OverflowDropNewest discards the entries that don't fit
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+
+	// OverflowDropOldest discards the oldest buffered entry to make room
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+)
+
+// DefaultBufferSize is the default number of entries a Buffer holds
+const DefaultBufferSize = 100
+
+// ParseOverflowPolicy validates a policy name
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(s); policy {
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q (expected block, drop-newest or drop-oldest)", s)
+	}
+}
+
+// Buffer holds entries between the sources and the pipeline. When the
+// pipeline falls behind and the buffer fills up, the policy decides whether
+// sources wait or entries are dropped. Dropped entries are acked and counted
+// in the channel_full metric.
+type Buffer struct {
+	size   int
+	policy OverflowPolicy
+
+	in  chan *models.LogEntry
+	out chan *models.LogEntry
+
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	queued  atomic.Int64
+	dropped atomic.Int64
+}
+
+// NewBuffer creates a buffer of size entries, DefaultBufferSize if size
+// isn't positive. An empty policy blocks.
+func NewBuffer(size int, policy OverflowPolicy) (*Buffer, error) {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	if policy == "" {
+		policy = OverflowBlock
+	}
+	if _, err := ParseOverflowPolicy(string(policy)); err != nil {
+		return nil, err
+	}
+
+	return &Buffer{
+		size:    size,
+		policy:  policy,
+		in:      make(chan *models.LogEntry),
+		out:     make(chan *models.LogEntry),
+		closing: make(chan struct{}),
+	}, nil
+}
+
+// In is where sources write
+func (b *Buffer) In() chan<- *models.LogEntry {
+	return b.in
+}
+
+// Out is where the pipeline reads
+func (b *Buffer) Out() <-chan *models.LogEntry {
+	return b.out
+}
+
+// Close stops taking entries from In. Run hands over what is buffered and
+// then closes Out, so the pipeline can tell it has seen every entry. Unlike
+// closing In it is safe while a source might still write.
+func (b *Buffer) Close() {
+	b.closeOnce.Do(func() { close(b.closing) })
+}
+
+// Run moves entries from In to Out until ctx is cancelled, or until Close
+// is called or In is closed and the buffered entries were handed over.
+// Out is closed when it returns.
+func (b *Buffer) Run(ctx context.Context) {
+	defer close(b.out)
+
+	queue := newEntryRing(b.size)
+	in := b.in
+	closing := b.closing
+	for {
+		var out chan<- *models.LogEntry
+		var next *models.LogEntry
+		if queue.len() > 0 {
+			out = b.out
+			next = queue.peek()
+		}
+		if in == nil && out == nil && closing == nil {
+			return
+		}
+
+		// A full blocking buffer holds the sources back
+		receive := in
+		if b.policy == OverflowBlock && queue.len() == b.size {
+			receive = nil
+		}
+
+		select {
+		case entry, ok := <-receive:
+			if !ok {
+				// Hand over what is left, then close
+				in, closing = nil, nil
+				continue
+			}
+			if queue.len() == b.size {
+				if b.policy == OverflowDropNewest {
+					b.drop(entry)
+					continue
+				}
+				b.drop(queue.pop())
+			}
+			queue.push(entry)
+			b.queued.Store(int64(queue.len()))
+		case out <- next:
+			queue.pop()
+			b.queued.Store(int64(queue.len()))
+		case <-closing:
+			in, closing = nil, nil
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// drop discards an entry that didn't fit
+func (b *Buffer) drop(entry *models.LogEntry) {
+	b.dropped.Add(1)
+	metrics.EntriesDropped.WithLabelValues(metrics.ReasonChannelFull).Inc()
+	entry.Ack()
+}
+
+// Dropped returns how many entries were dropped because the buffer was full
+func (b *Buffer) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+// Len returns how many entries are buffered
+func (b *Buffer) Len() int {
+	return int(b.queued.Load())
+}
+
+// Size returns how many entries the buffer holds when full
+func (b *Buffer) Size() int {
+	return b.size
+}
+
+// Policy returns the overflow policy
+func (b *Buffer) Policy() OverflowPolicy {
+	return b.policy
+}
+
+// entryRing is a fixed size FIFO queue of entries
+type entryRing struct {
+	entries []*models.LogEntry
+	head    int
+	count   int
+}
+
+func newEntryRing(size int) *entryRing {
+	return &entryRing{entries: make([]*models.LogEntry, size)}
+}
+
+func (r *entryRing) len() int {
+	return r.count
+}
+
+func (r *entryRing) push(entry *models.LogEntry) {
+	r.entries[(r.head+r.count)%len(r.entries)] = entry
+	r.count++
+}
+
+func (r *entryRing) peek() *models.LogEntry {
+	return r.entries[r.head]
+}
+
+func (r *entryRing) pop() *models.LogEntry {
+	entry := r.entries[r.head]
+	r.entries[r.head] = nil
+	r.head = (r.head + 1) % len(r.entries)
+	r.count--
+	return entry
+}
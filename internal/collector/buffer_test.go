@@ -0,0 +1,187 @@
+package collector
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// numberedEntry creates an entry whose message is i, counting its ack
+func numberedEntry(i int, acked *atomic.Int64) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Message = strconv.Itoa(i)
+	entry.OnAck(func() { acked.Add(1) })
+	return entry
+}
+
+// readSlowly reads n entries, pausing before each like a slow pipeline
+func readSlowly(t *testing.T, out <-chan *models.LogEntry, n int) []string {
+	t.Helper()
+	var messages []string
+	for i := 0; i < n; i++ {
+		time.Sleep(5 * time.Millisecond)
+		select {
+		case entry := <-out:
+			messages = append(messages, entry.Message)
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout after %d entries", len(messages))
+		}
+	}
+	return messages
+}
+
+func TestBuffer_DropPolicies(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		expected []string
+	}{
+		{OverflowDropNewest, []string{"0", "1", "2"}},
+		{OverflowDropOldest, []string{"7", "8", "9"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			buffer, err := NewBuffer(3, tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go buffer.Run(ctx)
+
+			// Nothing reads yet, yet the sources never wait
+			var acked atomic.Int64
+			for i := 0; i < 10; i++ {
+				select {
+				case buffer.In() <- numberedEntry(i, &acked):
+				case <-time.After(time.Second):
+					t.Fatalf("Entry %d blocked", i)
+				}
+			}
+
+			got := readSlowly(t, buffer.Out(), 3)
+			if len(got) != len(tt.expected) || got[0] != tt.expected[0] || got[1] != tt.expected[1] || got[2] != tt.expected[2] {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			select {
+			case entry := <-buffer.Out():
+				t.Errorf("Expected nothing more, got %q", entry.Message)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			if dropped := buffer.Dropped(); dropped != 7 {
+				t.Errorf("Expected 7 dropped, got %d", dropped)
+			}
+			if got := acked.Load(); got != 7 {
+				t.Errorf("Expected the dropped entries acked, got %d acks", got)
+			}
+		})
+	}
+}
+
+func TestBuffer_Block(t *testing.T) {
+	buffer, err := NewBuffer(3, OverflowBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go buffer.Run(ctx)
+
+	var acked atomic.Int64
+	var sent atomic.Int64
+	go func() {
+		for i := 0; i < 6; i++ {
+			buffer.In() <- numberedEntry(i, &acked)
+			sent.Add(1)
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if got := sent.Load(); got != 3 {
+		t.Errorf("Expected the source held back after 3 entries, sent %d", got)
+	}
+
+	got := readSlowly(t, buffer.Out(), 6)
+	for i, message := range got {
+		if message != strconv.Itoa(i) {
+			t.Errorf("Expected every entry in order, got %v", got)
+			break
+		}
+	}
+	if dropped := buffer.Dropped(); dropped != 0 {
+		t.Errorf("Expected nothing dropped, got %d", dropped)
+	}
+}
+
+func TestBuffer_ClosedInput(t *testing.T) {
+	buffer, err := NewBuffer(5, OverflowDropOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		buffer.Run(context.Background())
+		close(done)
+	}()
+
+	var acked atomic.Int64
+	buffer.In() <- numberedEntry(0, &acked)
+	buffer.In() <- numberedEntry(1, &acked)
+	close(buffer.In())
+
+	// Buffered entries are handed over before Out closes
+	got := readSlowly(t, buffer.Out(), 2)
+	if got[0] != "0" || got[1] != "1" {
+		t.Errorf("Expected [0 1], got %v", got)
+	}
+	if _, ok := <-buffer.Out(); ok {
+		t.Error("Expected Out closed")
+	}
+	<-done
+}
+
+func TestBuffer_Close(t *testing.T) {
+	buffer, err := NewBuffer(5, OverflowBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go buffer.Run(context.Background())
+
+	var acked atomic.Int64
+	buffer.In() <- numberedEntry(0, &acked)
+	buffer.Close()
+
+	// A late write is never taken rather than panicking
+	select {
+	case buffer.In() <- numberedEntry(1, &acked):
+		t.Error("Expected a write after Close to block")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	got := readSlowly(t, buffer.Out(), 1)
+	if got[0] != "0" {
+		t.Errorf("Expected the buffered entry, got %v", got)
+	}
+	if _, ok := <-buffer.Out(); ok {
+		t.Error("Expected Out closed")
+	}
+}
+
+func TestNewBuffer_Defaults(t *testing.T) {
+	buffer, err := NewBuffer(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buffer.Policy() != OverflowBlock || buffer.size != DefaultBufferSize {
+		t.Errorf("Expected a blocking buffer of %d, got %s with %d", DefaultBufferSize, buffer.Policy(), buffer.size)
+	}
+
+	if _, err := NewBuffer(10, "drop-everything"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// FieldParseError holds why a dead-lettered input couldn't be parsed
+const FieldParseError = "parse_error"
+
+// DeadLetter keeps input that couldn't be parsed in a sink, typically a
+// file, so nothing is lost without a trace and operators can inspect and
+// replay it. Each input is written as an ERROR entry from its source with
+// the input as the message, the time it failed as the timestamp and the
+// error in Fields["parse_error"].
+type DeadLetter struct {
+	sink Sink
+
+	written atomic.Int64
+	failed  atomic.Int64
+}
+
+// NewDeadLetter creates a dead letter writing to sink
+func NewDeadLetter(sink Sink) *DeadLetter {
+	return &DeadLetter{sink: sink}
+}
+
+// Record writes raw input from source that failed to parse with err. It
+// returns an error when the sink failed, so the input was lost after all.
+func (dl *DeadLetter) Record(source string, raw []byte, err error) error {
+	entry := models.NewLogEntry()
+	entry.Level = models.LevelError
+	entry.Source = source
+	entry.Message = string(raw)
+	entry.Fields[FieldParseError] = err.Error()
+
+	if writeErr := dl.sink.Write(context.Background(), entry); writeErr != nil {
+		dl.failed.Add(1)
+		return fmt.Errorf("failed to dead-letter input from %s: %w", source, writeErr)
+	}
+	dl.written.Add(1)
+	return nil
+}
+
+// Flush flushes the sink
+func (dl *DeadLetter) Flush() error {
+	return dl.sink.Flush()
+}
+
+// Stop flushes the sink and stops it if it holds resources
+func (dl *DeadLetter) Stop() error {
+	err := dl.sink.Flush()
+	if stopper, ok := dl.sink.(interface{ Stop() error }); ok {
+		if stopErr := stopper.Stop(); stopErr != nil && err == nil {
+			err = stopErr
+		}
+	}
+	return err
+}
+
+// Name returns the sink's name
+func (dl *DeadLetter) Name() string {
+	return dl.sink.Name()
+}
+
+// Written returns how many inputs were dead-lettered
+func (dl *DeadLetter) Written() int64 {
+	return dl.written.Load()
+}
+
+// Failed returns how many inputs the sink failed to take
+func (dl *DeadLetter) Failed() int64 {
+	return dl.failed.Load()
+}
@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestDeadLetter_Record(t *testing.T) {
+	sink := &flakySink{failures: 1, err: errors.New("disk full")}
+	dl := NewDeadLetter(sink)
+
+	if err := dl.Record("app.log", []byte("{broken"), errors.New("unexpected end of JSON input")); err == nil {
+		t.Error("Expected an error when the sink fails")
+	}
+	if err := dl.Record("app.log", []byte("{broken"), errors.New("unexpected end of JSON input")); err != nil {
+		t.Fatalf("Expected the input dead-lettered, got %v", err)
+	}
+
+	if dl.Written() != 1 || dl.Failed() != 1 {
+		t.Errorf("Expected 1 written and 1 failed, got %d and %d", dl.Written(), dl.Failed())
+	}
+	entry := sink.written[0]
+	if entry.Message != "{broken" || entry.Source != "app.log" || entry.Level != models.LevelError {
+		t.Errorf("Expected the raw input as an ERROR from app.log, got %q %s from %q", entry.Message, entry.Level, entry.Source)
+	}
+	if entry.Fields[FieldParseError] != "unexpected end of JSON input" {
+		t.Errorf("Expected the parse error in fields, got %v", entry.Fields)
+	}
+	if entry.Timestamp.IsZero() {
+		t.Error("Expected the time it failed as the timestamp")
+	}
+}
@@ -0,0 +1,636 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+const (
+	segmentExt       = ".seg"
+	commitFile       = "commit"
+	recordHeaderSize = 8 // payload length and CRC32, big endian
+	maxRecordSize    = 64 << 20
+)
+
+// DiskQueueOptions configures a DiskQueue
+type DiskQueueOptions struct {
+	// Dir holds the queue's segments and commit offset. Each queue needs
+	// its own directory.
+	Dir string
+
+	// SegmentSize is the size in bytes after which a new segment is started
+	SegmentSize int64
+
+	// MaxSize caps the bytes kept on disk. When exceeded, the oldest
+	// segments are dropped even if they weren't delivered.
+	MaxSize int64
+
+	// RetryDelay is how long to wait before retrying a failed sink write
+	// or flush
+	RetryDelay time.Duration
+
+	// BatchSize is the most entries delivered before the sink is flushed
+	// and the offset committed
+	BatchSize int
+}
+
+// diskSegment is one file of the write-ahead log
+type diskSegment struct {
+	id      uint64
+	size    int64
+	entries int
+}
+
+// queuePosition points at a record in the log
+type queuePosition struct {
+	segment uint64
+	offset  int64
+	index   int // records before offset in the segment
+}
+
+// DiskQueue is a durable buffer in front of a sink. Written entries are
+// appended to a segmented write-ahead log, and a background reader delivers
+// them to the sink in batches, committing its offset only once the sink
+// flushed them.
+// After a crash or restart, delivery resumes from the last committed offset,
+// so entries are delivered at least once.
+type DiskQueue struct {
+	sink Sink
+	opts DiskQueueOptions
+
+	mu       sync.Mutex
+	segments []*diskSegment // oldest first, the last one is appended to
+	writer   *os.File
+	reader   *os.File
+	readerID uint64
+	commit   *os.File
+	pos      queuePosition // next record to deliver
+	total    int64
+	closed   bool
+
+	notify  chan struct{}
+	observe atomic.Pointer[func(err error)]
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	dropped atomic.Int64
+}
+
+// NewDiskQueue opens or creates the queue in opts.Dir and starts delivering
+// to sink, resuming after the last committed entry
+func NewDiskQueue(sink Sink, opts DiskQueueOptions) (*DiskQueue, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("disk queue directory required")
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = 16 << 20
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 1 << 30
+	}
+	if opts.MaxSize < opts.SegmentSize {
+		opts.MaxSize = opts.SegmentSize
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	q := &DiskQueue{
+		sink:   sink,
+		opts:   opts,
+		notify: make(chan struct{}, 1),
+	}
+	if err := q.open(); err != nil {
+		q.closeFiles()
+		return nil, err
+	}
+
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	q.wg.Add(1)
+	go q.run()
+
+	return q, nil
+}
+
+// open loads the segments and commit offset, repairing a torn last write
+func (q *DiskQueue) open() error {
+	paths, err := filepath.Glob(filepath.Join(q.opts.Dir, "*"+segmentExt))
+	if err != nil {
+		return fmt.Errorf("failed to list segments: %w", err)
+	}
+
+	for _, path := range paths {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		size, entries, err := scanSegment(path, -1)
+		if err != nil {
+			return err
+		}
+		q.segments = append(q.segments, &diskSegment{id: id, size: size, entries: entries})
+		q.total += size
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].id < q.segments[j].id })
+
+	if len(q.segments) == 0 {
+		q.segments = append(q.segments, &diskSegment{id: 1})
+	}
+
+	last := q.segments[len(q.segments)-1]
+	q.writer, err = os.OpenFile(q.segmentPath(last.id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+
+	q.commit, err = os.OpenFile(filepath.Join(q.opts.Dir, commitFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open commit file: %w", err)
+	}
+	return q.loadCommit()
+}
+
+// loadCommit restores the read position and removes fully delivered segments
+func (q *DiskQueue) loadCommit() error {
+	var buf [16]byte
+	q.pos = queuePosition{segment: q.segments[0].id}
+
+	if n, err := q.commit.ReadAt(buf[:], 0); err == nil && n == len(buf) {
+		segment := binary.BigEndian.Uint64(buf[:8])
+		offset := int64(binary.BigEndian.Uint64(buf[8:]))
+
+		// Everything on disk was delivered unless a later segment is found
+		last := q.segments[len(q.segments)-1]
+		q.pos = queuePosition{segment: last.id, offset: last.size, index: last.entries}
+
+		for _, seg := range q.segments {
+			if seg.id < segment {
+				continue
+			}
+			if seg.id == segment {
+				size, index, err := scanSegment(q.segmentPath(seg.id), offset)
+				if err != nil {
+					return err
+				}
+				q.pos = queuePosition{segment: seg.id, offset: size, index: index}
+			} else {
+				// The committed segment is gone, resume at the next one
+				q.pos = queuePosition{segment: seg.id}
+			}
+			break
+		}
+	}
+
+	for len(q.segments) > 1 && q.segments[0].id < q.pos.segment {
+		q.removeOldest()
+	}
+	return nil
+}
+
+// scanSegment validates the records of a segment up to limit bytes, or all
+// of them if limit is negative. A torn or corrupt tail is truncated away.
+// It returns the valid size and the number of records in it.
+func scanSegment(path string, limit int64) (int64, int, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open segment: %w", err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	var size int64
+	entries := 0
+	for limit < 0 || size < limit {
+		payload, err := readRecord(r)
+		if err != nil {
+			break
+		}
+		size += recordHeaderSize + int64(len(payload))
+		entries++
+	}
+
+	if limit < 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to stat segment: %w", err)
+		}
+		if info.Size() != size {
+			fmt.Printf("⚠️  Truncating %d corrupt bytes from %s\n", info.Size()-size, path)
+			if err := file.Truncate(size); err != nil {
+				return 0, 0, fmt.Errorf("failed to repair segment: %w", err)
+			}
+		}
+	}
+	return size, entries, nil
+}
+
+// readRecord reads one length-prefixed, checksummed record
+func readRecord(r io.Reader) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:4])
+	if length > maxRecordSize {
+		return nil, fmt.Errorf("record of %d bytes exceeds limit", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+	return payload, nil
+}
+
+// Write appends the entry to the log. It returns once the entry is on disk,
+// not once it reached the sink.
+func (q *DiskQueue) Write(ctx context.Context, entry *models.LogEntry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entry: %w", err)
+	}
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return fmt.Errorf("disk queue closed")
+	}
+
+	seg := q.segments[len(q.segments)-1]
+	if seg.size > 0 && seg.size+int64(len(record)) > q.opts.SegmentSize {
+		if err := q.roll(); err != nil {
+			return err
+		}
+		seg = q.segments[len(q.segments)-1]
+	}
+
+	if _, err := q.writer.Write(record); err != nil {
+		// Don't leave a partial record for the reader to trip over
+		q.writer.Truncate(seg.size)
+		return fmt.Errorf("failed to append to disk queue: %w", err)
+	}
+	seg.size += int64(len(record))
+	seg.entries++
+	q.total += int64(len(record))
+
+	q.enforceMaxSize()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// roll starts a new segment
+func (q *DiskQueue) roll() error {
+	id := q.segments[len(q.segments)-1].id + 1
+	writer, err := os.OpenFile(q.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+
+	q.writer.Close()
+	q.writer = writer
+	q.segments = append(q.segments, &diskSegment{id: id})
+	return nil
+}
+
+// enforceMaxSize drops the oldest segments while the log is over MaxSize,
+// counting their undelivered entries as dropped
+func (q *DiskQueue) enforceMaxSize() {
+	for q.total > q.opts.MaxSize && len(q.segments) > 1 {
+		oldest := q.segments[0]
+		undelivered := oldest.entries
+		if q.pos.segment == oldest.id {
+			undelivered -= q.pos.index
+			q.pos = queuePosition{segment: q.segments[1].id}
+			q.saveCommit()
+		}
+
+		q.removeOldest()
+		q.dropped.Add(int64(undelivered))
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonQueueFull).Add(int64(undelivered))
+	}
+}
+
+// removeOldest deletes the oldest segment
+func (q *DiskQueue) removeOldest() {
+	oldest := q.segments[0]
+	if q.reader != nil && q.readerID == oldest.id {
+		q.reader.Close()
+		q.reader = nil
+	}
+	if err := os.Remove(q.segmentPath(oldest.id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("Error removing segment: %v\n", err)
+	}
+
+	q.total -= oldest.size
+	q.segments = q.segments[1:]
+}
+
+// run delivers entries to the sink until the queue is stopped. A batch is
+// only committed once the sink flushed it, so entries a sink buffers or
+// fails to send are delivered again after a restart.
+func (q *DiskQueue) run() {
+	defer q.wg.Done()
+
+	for {
+		entries, from, next := q.nextBatch()
+		if len(entries) == 0 {
+			select {
+			case <-q.notify:
+				continue
+			case <-q.ctx.Done():
+				return
+			}
+		}
+
+		for _, entry := range entries {
+			if !q.deliver(entry) {
+				return
+			}
+		}
+		if !q.flushSink() {
+			return
+		}
+		q.advance(from, next)
+	}
+}
+
+// nextBatch reads up to BatchSize records from the read position, without
+// moving it. It returns no entries when every written record was read.
+func (q *DiskQueue) nextBatch() ([]*models.LogEntry, queuePosition, queuePosition) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var entries []*models.LogEntry
+	for {
+		seg := q.segments[0]
+		if q.pos.offset >= seg.size {
+			if len(q.segments) == 1 {
+				return nil, q.pos, q.pos
+			}
+			// The segment was fully delivered
+			q.pos = queuePosition{segment: q.segments[1].id}
+			q.saveCommit()
+			q.removeOldest()
+			continue
+		}
+
+		from, next := q.pos, q.pos
+		for len(entries) < q.opts.BatchSize && next.offset < seg.size {
+			payload, err := q.readAt(seg.id, next.offset)
+			if err != nil {
+				if len(entries) > 0 || next != from {
+					// Deliver what was read, the next batch starts here
+					break
+				}
+				// Unreadable records can't be skipped reliably, give up on the segment
+				fmt.Printf("Error reading segment %d: %v\n", seg.id, err)
+				q.dropped.Add(int64(seg.entries - from.index))
+				metrics.EntriesDropped.WithLabelValues(metrics.ReasonQueueFull).Add(int64(seg.entries - from.index))
+				next = queuePosition{segment: seg.id, offset: seg.size, index: seg.entries}
+				break
+			}
+
+			next = queuePosition{
+				segment: seg.id,
+				offset:  next.offset + recordHeaderSize + int64(len(payload)),
+				index:   next.index + 1,
+			}
+
+			entry := &models.LogEntry{}
+			if err := json.Unmarshal(payload, entry); err != nil {
+				fmt.Printf("Error decoding queued entry: %v\n", err)
+				q.dropped.Add(1)
+				continue
+			}
+			entry.ReceivedAt = time.Now()
+			entries = append(entries, entry)
+		}
+
+		if len(entries) == 0 {
+			// Nothing deliverable was read, commit past it
+			q.pos = next
+			q.saveCommit()
+			continue
+		}
+		return entries, from, next
+	}
+}
+
+// readAt reads the record at offset in the given segment
+func (q *DiskQueue) readAt(id uint64, offset int64) ([]byte, error) {
+	if q.reader == nil || q.readerID != id {
+		if q.reader != nil {
+			q.reader.Close()
+		}
+		reader, err := os.Open(q.segmentPath(id))
+		if err != nil {
+			q.reader = nil
+			return nil, err
+		}
+		q.reader, q.readerID = reader, id
+	}
+	return readRecord(io.NewSectionReader(q.reader, offset, 1<<62))
+}
+
+// deliver writes the entry to the sink, retrying retryable errors. It
+// returns false if the queue was stopped first.
+func (q *DiskQueue) deliver(entry *models.LogEntry) bool {
+	for {
+		err := q.sink.Write(q.ctx, entry)
+		q.observed(err)
+		if err == nil {
+			return true
+		}
+		if q.ctx.Err() != nil {
+			return false
+		}
+		if !IsRetryable(err) {
+			fmt.Printf("❌ Dropping queued entry for %s: %v\n", q.sink.Name(), err)
+			q.dropped.Add(1)
+			metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Inc()
+			return true
+		}
+
+		timer := time.NewTimer(q.opts.RetryDelay)
+		select {
+		case <-timer.C:
+		case <-q.ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// flushSink flushes the sink, retrying retryable errors. It returns false
+// if the queue was stopped first.
+func (q *DiskQueue) flushSink() bool {
+	for {
+		err := q.sink.Flush()
+		q.observed(err)
+		if err == nil {
+			return true
+		}
+		if q.ctx.Err() != nil {
+			return false
+		}
+		if !IsRetryable(err) {
+			// The sink already dropped what it couldn't send
+			fmt.Printf("❌ Error flushing queued entries for %s: %v\n", q.sink.Name(), err)
+			return true
+		}
+
+		timer := time.NewTimer(q.opts.RetryDelay)
+		select {
+		case <-timer.C:
+		case <-q.ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// ObserveSends reports the outcome of every write and flush the queue
+// makes to its sink
+func (q *DiskQueue) ObserveSends(observe func(err error)) bool {
+	q.observe.Store(&observe)
+	return true
+}
+
+// observed reports the outcome of a write or flush to the observer
+func (q *DiskQueue) observed(err error) {
+	if observe := q.observe.Load(); observe != nil {
+		(*observe)(err)
+	}
+}
+
+// advance commits past a flushed batch, unless its segment was dropped in
+// the meantime
+func (q *DiskQueue) advance(from, next queuePosition) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pos == from {
+		q.pos = next
+		q.saveCommit()
+	}
+}
+
+// saveCommit persists the read position. The 16-byte record is rewritten in
+// place, which a process crash can't tear.
+func (q *DiskQueue) saveCommit() {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], q.pos.segment)
+	binary.BigEndian.PutUint64(buf[8:], uint64(q.pos.offset))
+	if _, err := q.commit.WriteAt(buf[:], 0); err != nil {
+		fmt.Printf("Error saving queue offset: %v\n", err)
+	}
+}
+
+func (q *DiskQueue) segmentPath(id uint64) string {
+	return filepath.Join(q.opts.Dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// Pending returns how many entries are waiting to be delivered
+func (q *DiskQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending := -q.pos.index
+	for _, seg := range q.segments {
+		pending += seg.entries
+	}
+	return pending
+}
+
+// Dropped returns how many entries were discarded without being delivered
+func (q *DiskQueue) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+// Flush syncs the log to disk and flushes the sink
+func (q *DiskQueue) Flush() error {
+	q.mu.Lock()
+	var err error
+	if !q.closed {
+		err = q.writer.Sync()
+	}
+	q.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to sync disk queue: %w", err)
+	}
+	return q.sink.Flush()
+}
+
+// Stop stops delivery and closes the log. Undelivered entries stay on disk
+// for the next start.
+func (q *DiskQueue) Stop() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	q.cancel()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	q.closeFiles()
+	q.mu.Unlock()
+
+	if stopper, ok := q.sink.(interface{ Stop() error }); ok {
+		return stopper.Stop()
+	}
+	return nil
+}
+
+func (q *DiskQueue) closeFiles() {
+	for _, f := range []*os.File{q.writer, q.reader, q.commit} {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+// Name returns the wrapped sink's name
+func (q *DiskQueue) Name() string {
+	return q.sink.Name()
+}
@@ -0,0 +1,303 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// recordingSink records delivered messages. Once block is set, the write of
+// that message records it and then hangs until the queue is stopped,
+// standing in for a crash between delivery and commit. Flush fails with
+// flushErr when set.
+type recordingSink struct {
+	mu        sync.Mutex
+	delivered []string
+	block     string
+	flushErr  error
+	flushes   int
+}
+
+func (s *recordingSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	s.mu.Lock()
+	s.delivered = append(s.delivered, entry.Message)
+	block := entry.Message == s.block
+	s.mu.Unlock()
+
+	if block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (s *recordingSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+	return s.flushErr
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.delivered...)
+}
+
+func waitForDelivered(t *testing.T, sink *recordingSink, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if messages := sink.messages(); len(messages) >= n {
+			return messages
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d deliveries, got %v", n, sink.messages())
+	return nil
+}
+
+func enqueue(t *testing.T, q *DiskQueue, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		entry := models.NewLogEntry()
+		entry.Message = fmt.Sprintf("entry %d", i)
+		if err := q.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiskQueue_ResumesAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskQueueOptions{Dir: dir, SegmentSize: 512, RetryDelay: time.Millisecond, BatchSize: 1}
+
+	first := &recordingSink{block: "entry 6"}
+	q, err := NewDiskQueue(first, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, q, 0, 20)
+
+	// Entries 0-5 are committed; entry 6 reached the sink but wasn't committed
+	waitForDelivered(t, first, 7)
+	if err := q.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	second := &recordingSink{}
+	q, err = NewDiskQueue(second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+
+	messages := waitForDelivered(t, second, 14)
+	for i, message := range messages {
+		expected := fmt.Sprintf("entry %d", 6+i)
+		if message != expected {
+			t.Errorf("Expected %q at %d after restart, got %q", expected, i, message)
+		}
+	}
+	if len(messages) != 14 {
+		t.Errorf("Expected 14 redelivered entries, got %d", len(messages))
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if q.Pending() != 0 {
+		t.Errorf("Expected nothing pending, got %d", q.Pending())
+	}
+}
+
+func TestDiskQueue_CommitsOnlyAfterFlush(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskQueueOptions{Dir: dir, RetryDelay: time.Millisecond}
+
+	// The sink takes the entries but can't flush them
+	first := &recordingSink{flushErr: fmt.Errorf("connection refused")}
+	q, err := NewDiskQueue(first, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, q, 0, 3)
+	waitForDelivered(t, first, 3)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		first.mu.Lock()
+		flushes := first.flushes
+		first.mu.Unlock()
+		if flushes >= 3 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if q.Pending() != 3 {
+		t.Errorf("Expected 3 pending while the flush fails, got %d", q.Pending())
+	}
+	if err := q.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing was committed, so everything is delivered again
+	second := &recordingSink{}
+	q, err = NewDiskQueue(second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+
+	messages := waitForDelivered(t, second, 3)
+	for i, message := range messages {
+		if expected := fmt.Sprintf("entry %d", i); message != expected {
+			t.Errorf("Expected %q at %d after restart, got %q", expected, i, message)
+		}
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for q.Pending() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if q.Pending() != 0 {
+		t.Errorf("Expected nothing pending once flushed, got %d", q.Pending())
+	}
+}
+
+func TestDiskQueue_RestartWithoutPending(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskQueueOptions{Dir: dir, SegmentSize: 256, RetryDelay: time.Millisecond}
+
+	first := &recordingSink{}
+	q, err := NewDiskQueue(first, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, q, 0, 10)
+	waitForDelivered(t, first, 10)
+	time.Sleep(20 * time.Millisecond)
+	q.Stop()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(segments) != 1 {
+		t.Errorf("Expected delivered segments to be removed, got %d left", len(segments))
+	}
+
+	second := &recordingSink{}
+	q, err = NewDiskQueue(second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, q, 10, 12)
+	messages := waitForDelivered(t, second, 2)
+	q.Stop()
+
+	if len(messages) != 2 || messages[0] != "entry 10" || messages[1] != "entry 11" {
+		t.Errorf("Expected only the new entries, got %v", messages)
+	}
+}
+
+func TestDiskQueue_TornWrite(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskQueueOptions{Dir: dir, RetryDelay: time.Millisecond}
+
+	q, err := NewDiskQueue(&recordingSink{block: "entry 0"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, q, 0, 3)
+	q.Stop()
+
+	// A crash mid-append leaves half a record behind
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 1, 0, 0xde, 0xad})
+	f.Close()
+
+	sink := &recordingSink{}
+	q, err = NewDiskQueue(sink, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+	enqueue(t, q, 3, 4)
+
+	messages := waitForDelivered(t, sink, 4)
+	expected := []string{"entry 0", "entry 1", "entry 2", "entry 3"}
+	for i := range expected {
+		if messages[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, messages)
+			break
+		}
+	}
+}
+
+func TestDiskQueue_MaxSizeDropsOldest(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskQueueOptions{Dir: dir, SegmentSize: 1024, MaxSize: 4096, RetryDelay: time.Millisecond}
+
+	sink := &recordingSink{block: "entry 0"}
+	q, err := NewDiskQueue(sink, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+
+	// Delivery is held up on the first entry, so nothing else leaves the
+	// queue but by being dropped
+	enqueue(t, q, 0, 1)
+	waitForDelivered(t, sink, 1)
+	enqueue(t, q, 1, 200)
+
+	if q.Dropped() == 0 {
+		t.Error("Expected entries to be dropped once the queue is full")
+	}
+
+	var total int64
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	for _, path := range segments {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += info.Size()
+	}
+	if total > opts.MaxSize {
+		t.Errorf("Expected at most %d bytes on disk, got %d", opts.MaxSize, total)
+	}
+	if int64(q.Pending())+q.Dropped() != 200 {
+		t.Errorf("Expected pending (%d) + dropped (%d) to account for all 200 entries", q.Pending(), q.Dropped())
+	}
+}
+
+func TestDiskQueue_RetriesFailedWrites(t *testing.T) {
+	sink := &flakySink{failures: 2, err: fmt.Errorf("connection refused")}
+	q, err := NewDiskQueue(sink, DiskQueueOptions{Dir: t.TempDir(), RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Stop()
+
+	if err := q.Write(context.Background(), models.NewLogEntry()); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, written := sink.counts(); written == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("Expected the entry to be delivered after retries")
+}
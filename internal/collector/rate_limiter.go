@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitMode decides what happens to entries over the limit
+type RateLimitMode string
+
+const (
+	// RateLimitDrop discards entries over the limit
+	RateLimitDrop RateLimitMode = "drop"
+
+	// RateLimitBlock holds entries back until the limit allows them,
+	// pushing back on the source
+	RateLimitBlock RateLimitMode = "block"
+)
+
+// RateLimit configures the limit applied to one source
+type RateLimit struct {
+	PerSecond float64
+	Burst     int // defaults to PerSecond
+	Mode      RateLimitMode
+}
+
+// RateLimiter is a token bucket: it refills at a steady rate up to a burst
+// size, and every entry takes one token
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	dropped atomic.Int64
+}
+
+// NewRateLimiter creates a limiter allowing perSecond entries per second
+// with bursts of up to burst entries
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst <= 0 {
+		burst = int(perSecond)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens earned since the last call. Must hold rl.mu.
+func (rl *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(rl.last).Seconds()
+	rl.last = now
+
+	rl.tokens += elapsed * rl.rate
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+}
+
+// reserve takes a token if one is available, otherwise reports how long
+// until one will be
+func (rl *RateLimiter) reserve() (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.refill(time.Now())
+	if rl.tokens >= 1 {
+		rl.tokens--
+		return true, 0
+	}
+
+	missing := 1 - rl.tokens
+	return false, time.Duration(missing / rl.rate * float64(time.Second))
+}
+
+// Allow takes a token without waiting. Denied calls are counted as dropped.
+func (rl *RateLimiter) Allow() bool {
+	ok, _ := rl.reserve()
+	if !ok {
+		rl.dropped.Add(1)
+	}
+	return ok
+}
+
+// Wait blocks until a token is available or ctx is done
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		ok, wait := rl.reserve()
+		if ok {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Dropped returns how many entries Allow has rejected
+func (rl *RateLimiter) Dropped() int64 {
+	return rl.dropped.Load()
+}
+
+// validate checks a RateLimit before it is used
+func (l RateLimit) validate() error {
+	if l.PerSecond <= 0 {
+		return fmt.Errorf("rate limit must be positive, got %v", l.PerSecond)
+	}
+	switch l.Mode {
+	case "", RateLimitDrop, RateLimitBlock:
+		return nil
+	default:
+		return fmt.Errorf("unsupported rate limit mode: %s", l.Mode)
+	}
+}
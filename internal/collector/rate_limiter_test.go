@@ -0,0 +1,189 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// burstSource emits count entries, waiting interval between them
+type burstSource struct {
+	name     string
+	count    int
+	interval time.Duration
+}
+
+func (b *burstSource) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	go func() {
+		for i := 0; i < b.count; i++ {
+			entry := models.NewLogEntry()
+			entry.Source = b.name
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+				return
+			}
+			if b.interval > 0 {
+				time.Sleep(b.interval)
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *burstSource) Stop() error               { return nil }
+func (b *burstSource) Name() string              { return b.name }
+func (b *burstSource) Stats() models.SourceStats { return models.SourceStats{} }
+
+// countFor counts entries per source until nothing arrives for a while
+func countFor(out <-chan *models.LogEntry, quiet time.Duration) map[string]int {
+	counts := make(map[string]int)
+	for {
+		select {
+		case entry := <-out:
+			counts[entry.Source]++
+		case <-time.After(quiet):
+			return counts
+		}
+	}
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	limiter := NewRateLimiter(10, 5)
+
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		if limiter.Allow() {
+			allowed++
+		}
+	}
+
+	if allowed != 5 {
+		t.Errorf("Expected burst of 5 to be allowed, got %d", allowed)
+	}
+	if limiter.Dropped() != 15 {
+		t.Errorf("Expected 15 dropped, got %d", limiter.Dropped())
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	limiter := NewRateLimiter(100, 1)
+
+	if !limiter.Allow() {
+		t.Fatal("Expected first entry to be allowed")
+	}
+	if limiter.Allow() {
+		t.Fatal("Expected bucket to be empty")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !limiter.Allow() {
+		t.Error("Expected a token after refilling")
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter(50, 1)
+	limiter.Allow()
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("Expected Wait to block for a token, returned after %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Expected error from cancelled Wait")
+	}
+}
+
+func TestSourceManager_RateLimitDropsBurst(t *testing.T) {
+	manager := NewSourceManager()
+	manager.Add(&burstSource{name: "noisy", count: 100})
+	manager.Add(&burstSource{name: "quiet", count: 5, interval: 20 * time.Millisecond})
+
+	if err := manager.SetRateLimit("noisy", RateLimit{PerSecond: 10, Burst: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetRateLimit("quiet", RateLimit{PerSecond: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 200)
+	if err := manager.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	counts := countFor(out, 300*time.Millisecond)
+
+	// The burst fills the bucket, then only a few refilled tokens get through
+	if counts["noisy"] < 10 || counts["noisy"] > 20 {
+		t.Errorf("Expected the noisy source to be throttled to about 10, got %d", counts["noisy"])
+	}
+	if dropped := manager.Limiter("noisy").Dropped(); dropped == 0 {
+		t.Error("Expected dropped entries to be counted")
+	}
+
+	// A slow steady stream passes untouched
+	if counts["quiet"] != 5 {
+		t.Errorf("Expected all 5 quiet entries, got %d", counts["quiet"])
+	}
+	if dropped := manager.Limiter("quiet").Dropped(); dropped != 0 {
+		t.Errorf("Expected no drops for the quiet source, got %d", dropped)
+	}
+}
+
+func TestSourceManager_RateLimitBlock(t *testing.T) {
+	manager := NewSourceManager()
+	manager.Add(&burstSource{name: "noisy", count: 10})
+
+	if err := manager.SetRateLimit("noisy", RateLimit{PerSecond: 100, Burst: 1, Mode: RateLimitBlock}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 20)
+
+	start := time.Now()
+	if err := manager.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	for i := 0; i < 10; i++ {
+		select {
+		case <-out:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected all entries to be delayed, not dropped; got %d", i)
+		}
+	}
+
+	// Nine entries wait ~10ms each for a token
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected entries to be delayed, finished in %v", elapsed)
+	}
+}
+
+func TestSourceManager_SetRateLimitValidation(t *testing.T) {
+	manager := NewSourceManager()
+
+	if err := manager.SetRateLimit("a", RateLimit{PerSecond: 0}); err == nil {
+		t.Error("Expected error for zero rate")
+	}
+	if err := manager.SetRateLimit("a", RateLimit{PerSecond: 1, Mode: "sometimes"}); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+	if manager.Limiter("a") != nil {
+		t.Error("Expected no limiter after invalid config")
+	}
+}
@@ -0,0 +1,343 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// StatusError is returned by sinks when a remote endpoint answers with an
+// unsuccessful HTTP status
+type StatusError struct {
+	Op         string // what was attempted, e.g. "push request"
+	StatusCode int
+	Body       string // start of the response body, may be empty
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s returned %d", e.Op, e.StatusCode)
+	}
+	return fmt.Sprintf("%s returned %d: %s", e.Op, e.StatusCode, e.Body)
+}
+
+// permanentError marks an error that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, e.g. a payload the remote end
+// rejected
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsRetryable reports whether a sink error may go away on retry. Errors
+// marked Permanent, 4xx statuses other than 429 and cancellations are not
+// retryable; network errors and 5xx statuses are.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == http.StatusTooManyRequests || status.StatusCode >= 500
+	}
+	return true
+}
+
+// RetryOptions configures a Retrier
+type RetryOptions struct {
+	// MaxAttempts is how many times an operation is tried in total
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, doubled on each attempt
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between attempts
+	MaxDelay time.Duration
+
+	// Jitter is the fraction of each delay that is randomized, from 0 (none)
+	// to 1, so retries from many writers don't line up
+	Jitter float64
+
+	// DeadLetter receives entries whose write failed permanently or ran out
+	// of attempts. Nil drops them.
+	DeadLetter Sink
+}
+
+// Retrier wraps a sink and retries failed writes and flushes with
+// exponential backoff. A BatchSink sends every batch through the Retrier,
+// from Flush or its own timer, so an entry is dead-lettered once it has
+// failed MaxAttempts sends or was rejected for good; until then the sink
+// keeps it and sends it again.
+type Retrier struct {
+	sink Sink
+	opts RetryOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	// attempts counts the failed sends of entries a BatchSink still keeps,
+	// by entry. sends numbers the batches seen, to forget entries the sink
+	// dropped.
+	attempts map[*models.LogEntry]*sendAttempts
+	sends    int64
+
+	deadLettered atomic.Int64
+}
+
+// NewRetrier wraps sink with the given retry policy
+func NewRetrier(sink Sink, opts RetryOptions) *Retrier {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 100 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 10 * time.Second
+	}
+	if opts.MaxDelay < opts.BaseDelay {
+		opts.MaxDelay = opts.BaseDelay
+	}
+	if opts.Jitter < 0 {
+		opts.Jitter = 0
+	}
+	if opts.Jitter > 1 {
+		opts.Jitter = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Retrier{
+		sink:     sink,
+		opts:     opts,
+		ctx:      ctx,
+		cancel:   cancel,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		attempts: make(map[*models.LogEntry]*sendAttempts),
+	}
+	if batching, ok := sink.(BatchSink); ok {
+		batching.SetBatchHook(r.sendBatch)
+	}
+	return r
+}
+
+// Write writes the entry, retrying retryable errors. Entries that still
+// fail go to the dead-letter sink, if any. A BatchSink only buffers the
+// entry, so its Write isn't retried.
+func (r *Retrier) Write(ctx context.Context, entry *models.LogEntry) error {
+	if _, ok := r.sink.(BatchSink); ok {
+		return r.sink.Write(ctx, entry)
+	}
+
+	err := r.retry(ctx, func() error {
+		return r.sink.Write(ctx, entry)
+	})
+	if err == nil || r.opts.DeadLetter == nil || ctx.Err() != nil {
+		return err
+	}
+
+	if dlErr := r.opts.DeadLetter.Write(ctx, entry); dlErr != nil {
+		return fmt.Errorf("%w (dead letter failed: %v)", err, dlErr)
+	}
+	r.deadLettered.Add(1)
+	return nil
+}
+
+// sendAttempts tracks the failed sends of one entry
+type sendAttempts struct {
+	failed   int
+	lastSeen int64 // the send it last failed in
+}
+
+// forgetAfter is how many batch sends an entry may go unseen before its
+// attempts are forgotten. A sink drops entries when it keeps too many, and
+// those never come back; forgetting one still kept only gives it more
+// attempts.
+const forgetAfter = 1000
+
+// sendBatch is the hook a BatchSink sends every batch through. Entries
+// that failed retryably go back to the sink until they have failed
+// MaxAttempts sends; those and the ones rejected for good go to the
+// dead-letter sink, or are dropped without one.
+func (r *Retrier) sendBatch(batch []*models.LogEntry, send SendFunc) ([]*models.LogEntry, error) {
+	retry, rejected, err := send(batch)
+
+	failed := make(map[*models.LogEntry]bool, len(retry))
+	for _, entry := range retry {
+		failed[entry] = true
+	}
+
+	giveUp := rejected
+	var keep []*models.LogEntry
+	r.mu.Lock()
+	r.sends++
+	for _, entry := range batch {
+		if !failed[entry] {
+			delete(r.attempts, entry)
+			continue
+		}
+		attempts, ok := r.attempts[entry]
+		if !ok {
+			attempts = &sendAttempts{}
+			r.attempts[entry] = attempts
+		}
+		attempts.failed++
+		attempts.lastSeen = r.sends
+		if attempts.failed < r.opts.MaxAttempts {
+			keep = append(keep, entry)
+			continue
+		}
+		delete(r.attempts, entry)
+		giveUp = append(giveUp, entry)
+	}
+	if r.sends%forgetAfter == 0 {
+		for entry, attempts := range r.attempts {
+			if r.sends-attempts.lastSeen >= forgetAfter {
+				delete(r.attempts, entry)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	if len(giveUp) == 0 {
+		return keep, err
+	}
+	if len(keep) == 0 {
+		err = Permanent(fmt.Errorf("giving up on %d entries: %w", len(giveUp), err))
+	}
+	if r.opts.DeadLetter == nil {
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Add(int64(len(giveUp)))
+		return keep, err
+	}
+
+	for i, entry := range giveUp {
+		if dlErr := r.opts.DeadLetter.Write(r.ctx, entry); dlErr != nil {
+			metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Add(int64(len(giveUp) - i))
+			return keep, fmt.Errorf("%w (dead letter failed: %v)", err, dlErr)
+		}
+		r.deadLettered.Add(1)
+	}
+	if len(keep) == 0 {
+		return nil, nil
+	}
+	return keep, err
+}
+
+// ObserveSends passes observe on to the wrapped sink, if it sends in the
+// background
+func (r *Retrier) ObserveSends(observe func(err error)) bool {
+	if observer, ok := r.sink.(SendObserver); ok {
+		return observer.ObserveSends(observe)
+	}
+	return false
+}
+
+// Flush flushes the wrapped sink, retrying retryable errors, then the
+// dead-letter sink
+func (r *Retrier) Flush() error {
+	err := r.retry(r.ctx, r.sink.Flush)
+	if r.opts.DeadLetter != nil {
+		if dlErr := r.opts.DeadLetter.Flush(); dlErr != nil && err == nil {
+			err = fmt.Errorf("failed to flush dead letter sink: %w", dlErr)
+		}
+	}
+	return err
+}
+
+// Stop abandons pending retries and stops the wrapped and dead-letter sinks
+// if they hold resources
+func (r *Retrier) Stop() error {
+	r.cancel()
+
+	var err error
+	for _, sink := range []Sink{r.sink, r.opts.DeadLetter} {
+		if stopper, ok := sink.(interface{ Stop() error }); ok {
+			if stopErr := stopper.Stop(); stopErr != nil && err == nil {
+				err = stopErr
+			}
+		}
+	}
+	return err
+}
+
+// Name returns the wrapped sink's name
+func (r *Retrier) Name() string {
+	return r.sink.Name()
+}
+
+// DeadLettered returns how many entries were handed to the dead-letter sink
+func (r *Retrier) DeadLettered() int64 {
+	return r.deadLettered.Load()
+}
+
+// retry runs op until it succeeds, fails permanently, runs out of attempts
+// or ctx is done
+func (r *Retrier) retry(ctx context.Context, op func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = op(); err == nil {
+			return nil
+		}
+		if !IsRetryable(err) {
+			return err
+		}
+		if attempt >= r.opts.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(r.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry cancelled: %w", err)
+		}
+	}
+}
+
+// delay returns the backoff before the given retry, with jitter applied
+func (r *Retrier) delay(attempt int) time.Duration {
+	d := r.opts.BaseDelay
+	for i := 1; i < attempt && d < r.opts.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > r.opts.MaxDelay {
+		d = r.opts.MaxDelay
+	}
+
+	if r.opts.Jitter > 0 {
+		r.mu.Lock()
+		f := r.rand.Float64()
+		r.mu.Unlock()
+		d -= time.Duration(float64(d) * r.opts.Jitter * f)
+	}
+	return d
+}
@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// flakySink fails the first failures writes with err, then succeeds
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	attempts int
+	written  []*models.LogEntry
+}
+
+func (s *flakySink) Write(ctx context.Context, entry *models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	if s.attempts <= s.failures {
+		return s.err
+	}
+	s.written = append(s.written, entry)
+	return nil
+}
+
+func (s *flakySink) Flush() error { return nil }
+func (s *flakySink) Name() string { return "flaky" }
+
+func (s *flakySink) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts, len(s.written)
+}
+
+func TestRetrier_FailsTwiceThenSucceeds(t *testing.T) {
+	sink := &flakySink{failures: 2, err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	dead := &flakySink{}
+	r := NewRetrier(sink, RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond, DeadLetter: dead})
+
+	if err := r.Write(context.Background(), models.NewLogEntry()); err != nil {
+		t.Fatalf("Expected write to succeed on the third attempt, got %v", err)
+	}
+
+	attempts, written := sink.counts()
+	if attempts != 3 || written != 1 {
+		t.Errorf("Expected 3 attempts and 1 write, got %d and %d", attempts, written)
+	}
+	if _, n := dead.counts(); n != 0 || r.DeadLettered() != 0 {
+		t.Errorf("Expected nothing dead-lettered, got %d", n)
+	}
+}
+
+func TestRetrier_ExhaustedGoesToDeadLetter(t *testing.T) {
+	sink := &flakySink{failures: 10, err: &StatusError{Op: "push request", StatusCode: 503}}
+	dead := &flakySink{}
+	r := NewRetrier(sink, RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond, DeadLetter: dead})
+
+	entry := models.NewLogEntry()
+	if err := r.Write(context.Background(), entry); err != nil {
+		t.Fatalf("Expected dead-lettered write to succeed, got %v", err)
+	}
+
+	if attempts, _ := sink.counts(); attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if len(dead.written) != 1 || dead.written[0] != entry {
+		t.Errorf("Expected the entry in the dead-letter sink, got %v", dead.written)
+	}
+	if r.DeadLettered() != 1 {
+		t.Errorf("Expected 1 dead-lettered entry, got %d", r.DeadLettered())
+	}
+}
+
+func TestRetrier_PermanentErrorsAreNotRetried(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"bad request", &StatusError{Op: "push request", StatusCode: 400, Body: "invalid labels"}},
+		{"marked permanent", Permanent(errors.New("payload rejected"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &flakySink{failures: 10, err: tt.err}
+			r := NewRetrier(sink, RetryOptions{MaxAttempts: 5, BaseDelay: time.Millisecond})
+
+			err := r.Write(context.Background(), models.NewLogEntry())
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected the sink's error, got %v", err)
+			}
+			if attempts, _ := sink.counts(); attempts != 1 {
+				t.Errorf("Expected 1 attempt, got %d", attempts)
+			}
+		})
+	}
+}
+
+func TestRetrier_ContextCancelled(t *testing.T) {
+	sink := &flakySink{failures: 10, err: errors.New("connection reset")}
+	dead := &flakySink{}
+	r := NewRetrier(sink, RetryOptions{MaxAttempts: 10, BaseDelay: time.Hour, DeadLetter: dead})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- r.Write(ctx, models.NewLogEntry()) }()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("Expected an error after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Write to return when ctx is cancelled")
+	}
+	if _, n := dead.counts(); n != 0 {
+		t.Errorf("Expected nothing dead-lettered after cancellation, got %d", n)
+	}
+}
+
+func TestRetrier_Delay(t *testing.T) {
+	r := NewRetrier(&flakySink{}, RetryOptions{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second})
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, want := range expected {
+		if got := r.delay(i + 1); got != want {
+			t.Errorf("Expected delay %v before retry %d, got %v", want, i+1, got)
+		}
+	}
+
+	jittered := NewRetrier(&flakySink{}, RetryOptions{BaseDelay: 100 * time.Millisecond, Jitter: 0.5})
+	for i := 0; i < 100; i++ {
+		if d := jittered.delay(1); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("Expected jittered delay within [50ms, 100ms], got %v", d)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("connection refused"), true},
+		{&StatusError{StatusCode: 500}, true},
+		{&StatusError{StatusCode: 429}, true},
+		{&StatusError{StatusCode: 404}, false},
+		{fmt.Errorf("wrapped: %w", &StatusError{StatusCode: 502}), true},
+		{Permanent(&StatusError{StatusCode: 503}), false},
+		{context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.expected {
+			t.Errorf("IsRetryable(%v): expected %v, got %v", tt.err, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Sink represents a destination that collected log entries are shipped to
+type Sink interface {
+	// Write delivers a single log entry to the sink
+	Write(ctx context.Context, entry *models.LogEntry) error
+
+	// Flush forces any buffered entries to be written out
+	Flush() error
+
+	// Name returns the sink identifier
+	Name() string
+}
+
+// SendFunc makes one attempt at sending a batch. It returns the entries
+// that failed but may go through if sent again, the entries the remote end
+// refused for good, and the error behind them.
+type SendFunc func(batch []*models.LogEntry) (retry, rejected []*models.LogEntry, err error)
+
+// BatchHook runs every attempt a batching sink makes at sending a batch,
+// from Flush or the sink's own timer. It returns the entries the sink
+// should keep and send again later.
+type BatchHook func(batch []*models.LogEntry, send SendFunc) (keep []*models.LogEntry, err error)
+
+// BatchSink is implemented by sinks that buffer entries and send them in
+// batches. Write only buffers, so send failures show up in the hook and in
+// Flush instead.
+type BatchSink interface {
+	Sink
+
+	// SetBatchHook routes every batch send through hook. It must be
+	// called before the first Write.
+	SetBatchHook(hook BatchHook)
+}
+
+// SendObserver is implemented by sinks that send in the background, from a
+// batch timer or a queue, so a failed send never reaches Write
+type SendObserver interface {
+	Sink
+
+	// ObserveSends calls observe with the outcome of every background send,
+	// nil on success. It reports whether the sink sends in the background
+	// at all; a wrapper only does if the sink it wraps does.
+	ObserveSends(observe func(err error)) bool
+}
@@ -0,0 +1,280 @@
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// errSinkStopped is returned by Write once a batching sink is stopped, as
+// nothing would send the entry any more
+var errSinkStopped = collector.Permanent(errors.New("sink is stopped"))
+
+// keptBatches is how many batches worth of entries that failed to send are
+// kept for the next flush. Beyond that the oldest are dropped.
+const keptBatches = 10
+
+// batchOptions configures a batcher
+type batchOptions struct {
+	// Size is how many entries are buffered before a send, and the most
+	// sent in one request
+	Size int
+
+	// FlushInterval is how often a partial batch is sent
+	FlushInterval time.Duration
+
+	// Retries is how many times a flush resends entries that failed with a
+	// retryable error before leaving them for the next flush
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled on each attempt
+	Backoff time.Duration
+}
+
+// batcher buffers entries for a sink that sends them in batches and runs
+// its flush timer. Entries a send fails on with a retryable error stay
+// buffered, so a failed flush doesn't lose them. Every send goes through
+// the hook, when one is set.
+type batcher struct {
+	name    string
+	opts    batchOptions
+	send    collector.SendFunc
+	hook    collector.BatchHook
+	observe func(err error) // told the outcome of every send, if set
+
+	mu      sync.Mutex
+	buffer  []*models.LogEntry
+	fresh   int // entries added since the last send
+	stopped bool
+
+	// flushMu is held while sending, so flushes go out in order and a
+	// flush returns only once earlier sends are done
+	flushMu sync.Mutex
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newBatcher creates a batcher for the named sink and starts its flush timer
+func newBatcher(name string, opts batchOptions, send collector.SendFunc) *batcher {
+	b := &batcher{
+		name: name,
+		opts: opts,
+		send: send,
+		hook: dropRejected,
+		done: make(chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go b.flushLoop()
+
+	return b
+}
+
+// dropRejected is the hook used when none is set. Entries refused for good
+// are dropped and counted, the rest are kept.
+func dropRejected(batch []*models.LogEntry, send collector.SendFunc) ([]*models.LogEntry, error) {
+	retry, rejected, err := send(batch)
+	if len(rejected) > 0 {
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Add(int64(len(rejected)))
+	}
+	return retry, err
+}
+
+// setHook routes every send through hook
+func (b *batcher) setHook(hook collector.BatchHook) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	b.hook = hook
+}
+
+// observeSends reports the outcome of every send to observe
+func (b *batcher) observeSends(observe func(err error)) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	b.observe = observe
+}
+
+// sendObserved makes one send, reporting its outcome before any hook sees
+// it
+func (b *batcher) sendObserved(batch []*models.LogEntry) ([]*models.LogEntry, []*models.LogEntry, error) {
+	retry, rejected, err := b.send(batch)
+	if b.observe != nil {
+		b.observe(err)
+	}
+	return retry, rejected, err
+}
+
+// flushLoop periodically sends partial batches
+func (b *batcher) flushLoop() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			if err := b.flush(); err != nil {
+				fmt.Printf("Error flushing %s: %v\n", b.name, err)
+			}
+		}
+	}
+}
+
+// add buffers an entry, sending once a batch is full. The entry is kept
+// whatever becomes of that send, so only a stopped batcher fails.
+func (b *batcher) add(entry *models.LogEntry) error {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return errSinkStopped
+	}
+	b.buffer = append(b.buffer, entry)
+	b.fresh++
+	full := b.fresh >= b.opts.Size
+	b.mu.Unlock()
+
+	if full {
+		if err := b.flush(); err != nil {
+			fmt.Printf("Error flushing %s: %v\n", b.name, err)
+		}
+	}
+	return nil
+}
+
+// flush sends everything buffered. Entries that fail with a retryable
+// error are resent with backoff, and kept for the next flush if retries run
+// out. The flush lock is only held while sending, so a backoff holds up
+// neither the flush timer nor stop.
+func (b *batcher) flush() error {
+	backoff := b.opts.Backoff
+	var rejected error
+
+	for attempt := 0; ; attempt++ {
+		kept, err, dropped := b.sendBuffered()
+		if dropped != nil {
+			rejected = dropped
+		}
+		if kept == 0 {
+			return rejected
+		}
+		if attempt >= b.opts.Retries {
+			return fmt.Errorf("%d entries kept for retry after %d retries: %w", kept, attempt, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-b.done:
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// sendBuffered makes one attempt at sending the buffer. It returns how
+// many entries were kept for another attempt with the error that kept
+// them, and the error for entries rejected for good.
+func (b *batcher) sendBuffered() (kept int, err, rejected error) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	batch := b.buffer
+	b.buffer = nil
+	b.fresh = 0
+	b.mu.Unlock()
+
+	var failed []*models.LogEntry
+	for len(batch) > 0 {
+		n := min(len(batch), b.opts.Size)
+		keep, sendErr := b.hook(batch[:n], b.sendObserved)
+		batch = batch[n:]
+
+		switch {
+		case sendErr == nil:
+		case len(keep) == 0:
+			rejected = sendErr
+		default:
+			err = sendErr
+			failed = append(failed, keep...)
+			if len(keep) == n {
+				// The endpoint is likely down, the rest waits for the
+				// next attempt
+				failed = append(failed, batch...)
+				batch = nil
+			}
+		}
+	}
+
+	b.keep(failed)
+	return len(failed), err, rejected
+}
+
+// keep puts entries that failed back in front of the buffer, dropping the
+// oldest once more than keptBatches batches are waiting
+func (b *batcher) keep(entries []*models.LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buffer = append(entries, b.buffer...)
+	if over := len(b.buffer) - keptBatches*b.opts.Size; over > 0 {
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Add(int64(over))
+		b.buffer = b.buffer[over:]
+	}
+}
+
+// stop stops the flush timer and makes a last attempt at sending. Entries
+// still buffered after it are dropped.
+func (b *batcher) stop() error {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return nil
+	}
+	b.stopped = true
+	close(b.done)
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	err := b.flush()
+
+	b.mu.Lock()
+	if len(b.buffer) > 0 {
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Add(int64(len(b.buffer)))
+		b.buffer = nil
+	}
+	b.mu.Unlock()
+	return err
+}
+
+// labelKey returns a stable key identifying a label set
+func labelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
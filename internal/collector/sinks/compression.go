@@ -0,0 +1,91 @@
+package sinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codecs for files and request bodies. The names double as
+// the Content-Encoding sent with compressed requests.
+const (
+	CompressionNone   = ""
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+	CompressionSnappy = "snappy" // the framing format, not raw blocks
+)
+
+// compressionExtensions maps codecs to the extension of files they write
+var compressionExtensions = map[string]string{
+	CompressionGzip:   ".gz",
+	CompressionZstd:   ".zst",
+	CompressionSnappy: ".sz",
+}
+
+// CheckCompression returns an error for an unknown codec
+func CheckCompression(codec string) error {
+	if _, ok := compressionExtensions[codec]; !ok && codec != CompressionNone {
+		return fmt.Errorf("unsupported compression %q (expected gzip, zstd or snappy)", codec)
+	}
+	return nil
+}
+
+// compressor is a compressed stream over a writer. Flush makes everything
+// written so far decodable, Close ends the stream without closing the
+// writer underneath.
+type compressor interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+// newCompressor starts a compressed stream on w
+func newCompressor(codec string, w io.Writer) (compressor, error) {
+	switch codec {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	case CompressionSnappy:
+		return s2.NewWriter(w, s2.WriterSnappyCompat(), s2.WriterConcurrency(1)), nil
+	default:
+		if err := CheckCompression(codec); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no compression to start")
+	}
+}
+
+// newBodyRequest creates a POST request, compressing the body and setting
+// its Content-Encoding when a codec is given
+func newBodyRequest(url, contentType string, body []byte, codec string) (*http.Request, error) {
+	if codec != CompressionNone {
+		var buf bytes.Buffer
+		c, err := newCompressor(codec, &buf)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.Write(body); err != nil {
+			return nil, fmt.Errorf("failed to compress request: %w", err)
+		}
+		if err := c.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress request: %w", err)
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if codec != CompressionNone {
+		req.Header.Set("Content-Encoding", codec)
+	}
+	return req, nil
+}
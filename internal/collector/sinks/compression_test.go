@@ -0,0 +1,260 @@
+package sinks
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// decompress wraps r in a reader for codec
+func decompress(t *testing.T, codec string, r io.Reader) io.Reader {
+	t.Helper()
+
+	switch codec {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gr
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(zr.Close)
+		return zr
+	case CompressionSnappy:
+		return s2.NewReader(r)
+	}
+	return r
+}
+
+// readCompressedLines decodes every line of a compressed JSONL file
+func readCompressedLines(t *testing.T, codec, path string) []*models.LogEntry {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []*models.LogEntry
+	scanner := bufio.NewScanner(decompress(t, codec, file))
+	for scanner.Scan() {
+		var entry models.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Failed to decompress %s: %v", path, err)
+	}
+	return entries
+}
+
+func TestFileSink_Compression(t *testing.T) {
+	tests := []struct {
+		codec string
+		path  string
+	}{
+		{CompressionGzip, "out.jsonl.gz"},
+		{CompressionZstd, "out.jsonl.zst"},
+		{CompressionSnappy, "out.jsonl.sz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.codec, func(t *testing.T) {
+			dir := t.TempDir()
+
+			// Two runs append two streams to the same file
+			var written []string
+			for run := 0; run < 2; run++ {
+				sink, err := NewFileSinkWithOptions(filepath.Join(dir, "out.jsonl"), FileSinkOptions{Compression: tt.codec})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 50; i++ {
+					entry := models.NewLogEntry()
+					entry.Message = fmt.Sprintf("run %d message %d", run, i)
+					written = append(written, entry.Message)
+					if err := sink.Write(context.Background(), entry); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := sink.Stop(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			read := readCompressedLines(t, tt.codec, filepath.Join(dir, tt.path))
+			if len(read) != len(written) {
+				t.Fatalf("Expected %d entries, got %d", len(written), len(read))
+			}
+			for i, entry := range read {
+				if entry.Message != written[i] {
+					t.Errorf("Line %d: expected %q, got %q", i, written[i], entry.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestFileSink_CompressionFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl.zst")
+
+	sink, err := NewFileSinkWithOptions(path, FileSinkOptions{Compression: CompressionZstd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	entry := models.NewLogEntry()
+	entry.Message = "flushed"
+	if err := sink.Write(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream isn't ended, but what was flushed decodes
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(decompress(t, CompressionZstd, file)).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("Failed to decompress flushed data: %v", err)
+	}
+	var read models.LogEntry
+	if err := json.Unmarshal(line, &read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Message != "flushed" {
+		t.Errorf("Expected flushed, got %q", read.Message)
+	}
+}
+
+func TestFileSink_CompressionRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl.gz")
+
+	sink, err := NewFileSinkWithOptions(path, FileSinkOptions{Compression: CompressionGzip, MaxSize: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flushing pushes the compressed bytes to disk, where they're counted
+	const n = 10
+	for i := 0; i < n; i++ {
+		entry := models.NewLogEntry()
+		entry.Message = fmt.Sprintf("rotation message number %d", i)
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotated files keep the extension last, so they still decompress
+	var files []string
+	for i := 1; ; i++ {
+		rotated := filepath.Join(filepath.Dir(path), fmt.Sprintf("out.jsonl.%d.gz", i))
+		if _, err := os.Stat(rotated); err != nil {
+			break
+		}
+		files = append(files, rotated)
+	}
+	if len(files) == 0 {
+		t.Fatal("Expected rotated files")
+	}
+	files = append(files, path)
+
+	var total int
+	for _, f := range files {
+		for _, entry := range readCompressedLines(t, CompressionGzip, f) {
+			expected := fmt.Sprintf("rotation message number %d", total)
+			if entry.Message != expected {
+				t.Errorf("Expected %q, got %q", expected, entry.Message)
+			}
+			total++
+		}
+	}
+	if total != n {
+		t.Errorf("Expected %d entries across files, got %d", n, total)
+	}
+}
+
+// encodingServer records decompressed request bodies with their encoding
+type encodingServer struct {
+	t         *testing.T
+	mu        sync.Mutex
+	encodings []string
+	bodies    [][]byte
+	status    int
+}
+
+func (es *encodingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	encoding := r.Header.Get("Content-Encoding")
+	body, err := io.ReadAll(decompress(es.t, encoding, r.Body))
+	if err != nil {
+		http.Error(w, "bad body", http.StatusBadRequest)
+		return
+	}
+
+	es.mu.Lock()
+	es.encodings = append(es.encodings, encoding)
+	es.bodies = append(es.bodies, body)
+	es.mu.Unlock()
+
+	w.WriteHeader(es.status)
+	if es.status == http.StatusOK {
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}
+}
+
+// splitLines splits an NDJSON body into non-empty lines
+func splitLines(body []byte) [][]byte {
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+		}
+	}
+	return lines
+}
+
+func TestCheckCompression(t *testing.T) {
+	for _, codec := range []string{CompressionNone, CompressionGzip, CompressionZstd, CompressionSnappy} {
+		if err := CheckCompression(codec); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", codec, err)
+		}
+	}
+	if err := CheckCompression("lz4"); err == nil {
+		t.Error("Expected an error for lz4")
+	}
+	if _, err := NewFileSinkWithOptions(filepath.Join(t.TempDir(), "out.jsonl"), FileSinkOptions{Compression: "lz4"}); err == nil {
+		t.Error("Expected the file sink to reject lz4")
+	}
+}
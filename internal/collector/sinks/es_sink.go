@@ -0,0 +1,298 @@
+//go:build !no_sink_elasticsearch
+
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// ESSinkOptions configures an ESSink
+type ESSinkOptions struct {
+	// URL is the Elasticsearch/OpenSearch base URL, e.g. http://localhost:9200
+	URL string
+
+	// Index is the target index. A Go reference date starting at "2006" is
+	// replaced by the entry's date, e.g. "logs-2006.01.02" -> "logs-2024.01.02".
+	Index string
+
+	// BatchSize is how many entries are buffered before a bulk request
+	BatchSize int
+
+	// FlushInterval is how often a partial batch is sent
+	FlushInterval time.Duration
+
+	// MaxRetries is how many times a flush retries failed items. Items that
+	// still fail are kept for the next flush.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubled on each attempt
+	RetryBackoff time.Duration
+
+	// Client is the HTTP client used for requests
+	Client *http.Client
+
+	// Fingerprint makes the document ID the entry's fingerprint instead of
+	// its ID, so an entry delivered twice overwrites its first copy
+	Fingerprint bool
+
+	// FingerprintFields are the parts hashed into the fingerprint, see
+	// LogEntry.FingerprintOf. Defaults to models.DefaultFingerprintFields.
+	FingerprintFields []string
+
+	// Compression compresses bulk requests with a codec such as
+	// CompressionGzip, which Elasticsearch and OpenSearch accept
+	Compression string
+}
+
+// ESSink ships log entries to Elasticsearch/OpenSearch using the _bulk API
+type ESSink struct {
+	opts    ESSinkOptions
+	batches *batcher
+}
+
+// NewESSink creates a bulk sink and starts its flush timer
+func NewESSink(opts ESSinkOptions) (*ESSink, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("elasticsearch URL required")
+	}
+	if err := CheckCompression(opts.Compression); err != nil {
+		return nil, err
+	}
+	if opts.Index == "" {
+		opts.Index = "logflux-2006.01.02"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 100 * time.Millisecond
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if len(opts.FingerprintFields) == 0 {
+		opts.FingerprintFields = models.DefaultFingerprintFields
+	}
+	opts.URL = strings.TrimRight(opts.URL, "/")
+
+	s := &ESSink{opts: opts}
+	s.batches = newBatcher(s.Name(), batchOptions{
+		Size:          opts.BatchSize,
+		FlushInterval: opts.FlushInterval,
+		Retries:       opts.MaxRetries,
+		Backoff:       opts.RetryBackoff,
+	}, s.send)
+
+	return s, nil
+}
+
+// Write buffers an entry, sending a bulk request once the batch is full.
+// It fails once the sink is stopped.
+func (s *ESSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	return s.batches.add(entry)
+}
+
+// Flush sends all buffered entries, retrying failed items. Items that still
+// fail with a retryable status are kept for the next flush.
+func (s *ESSink) Flush() error {
+	return s.batches.flush()
+}
+
+// Stop stops the flush timer and sends whatever is left
+func (s *ESSink) Stop() error {
+	return s.batches.stop()
+}
+
+// SetBatchHook routes every bulk request through hook
+func (s *ESSink) SetBatchHook(hook collector.BatchHook) {
+	s.batches.setHook(hook)
+}
+
+// ObserveSends reports the outcome of every bulk request to observe
+func (s *ESSink) ObserveSends(observe func(err error)) bool {
+	s.batches.observeSends(observe)
+	return true
+}
+
+// send makes one bulk request, sorting out the items worth retrying from
+// the ones rejected for good
+func (s *ESSink) send(batch []*models.LogEntry) ([]*models.LogEntry, []*models.LogEntry, error) {
+	result, err := s.bulk(batch)
+	if err != nil {
+		if collector.IsRetryable(err) {
+			// The whole request failed, every item is retryable
+			return batch, nil, err
+		}
+		return nil, batch, err
+	}
+
+	var rejectedErr error
+	if len(result.rejected) > 0 {
+		rejectedErr = collector.Permanent(fmt.Errorf("%d entries rejected: %s", len(result.rejected), strings.Join(result.reasons, "; ")))
+		if len(result.retry) == 0 {
+			return nil, result.rejected, rejectedErr
+		}
+		fmt.Printf("⚠️  %s: %v\n", s.Name(), rejectedErr)
+	}
+	if len(result.retry) > 0 {
+		return result.retry, result.rejected, fmt.Errorf("%d of %d entries failed with a retryable status", len(result.retry), len(batch))
+	}
+	return nil, nil, nil
+}
+
+// bulkItemResult is the per-item part of a _bulk response
+type bulkItemResult struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// bulkResponse is the relevant part of a _bulk response
+type bulkResponse struct {
+	Errors bool                        `json:"errors"`
+	Items  []map[string]bulkItemResult `json:"items"`
+}
+
+// bulkResult sorts the items of a bulk request that didn't go through
+type bulkResult struct {
+	retry    []*models.LogEntry // failed with a retryable status
+	rejected []*models.LogEntry // rejected for good
+	reasons  []string           // why each rejected item was rejected
+}
+
+// bulk performs one _bulk request
+func (s *ESSink) bulk(batch []*models.LogEntry) (bulkResult, error) {
+	body, err := s.encodeBulk(batch)
+	if err != nil {
+		return bulkResult{}, collector.Permanent(err)
+	}
+
+	req, err := newBodyRequest(s.opts.URL+"/_bulk", "application/x-ndjson", body, s.opts.Compression)
+	if err != nil {
+		return bulkResult{}, err
+	}
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return bulkResult{}, fmt.Errorf("bulk request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return bulkResult{}, fmt.Errorf("failed to read bulk response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return bulkResult{}, &collector.StatusError{Op: "bulk request", StatusCode: resp.StatusCode}
+	}
+	if resp.StatusCode >= 300 {
+		// The request itself is bad, retrying won't help
+		return bulkResult{}, collector.Permanent(&collector.StatusError{
+			Op:         "bulk request",
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(respBody)),
+		})
+	}
+
+	var response bulkResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return bulkResult{}, fmt.Errorf("invalid bulk response: %w", err)
+	}
+	if !response.Errors {
+		return bulkResult{}, nil
+	}
+	if len(response.Items) != len(batch) {
+		return bulkResult{}, fmt.Errorf("bulk response has %d items, expected %d", len(response.Items), len(batch))
+	}
+
+	var result bulkResult
+	for i, item := range response.Items {
+		for _, res := range item {
+			switch {
+			case res.Status < 300:
+			case res.Status == http.StatusTooManyRequests || res.Status >= 500:
+				result.retry = append(result.retry, batch[i])
+			default:
+				result.rejected = append(result.rejected, batch[i])
+				result.reasons = append(result.reasons, fmt.Sprintf("%s: %d %s", batch[i].ID, res.Status, string(res.Error)))
+			}
+		}
+	}
+	return result, nil
+}
+
+// encodeBulk renders a batch as _bulk NDJSON: an action line then the document
+func (s *ESSink) encodeBulk(batch []*models.LogEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, entry := range batch {
+		action := map[string]map[string]string{
+			"index": {
+				"_index": s.indexName(entry.Timestamp),
+				"_id":    s.documentID(entry),
+			},
+		}
+		if err := enc.Encode(action); err != nil {
+			return nil, fmt.Errorf("failed to encode bulk action: %w", err)
+		}
+		if err := enc.Encode(esDocument(entry)); err != nil {
+			return nil, fmt.Errorf("failed to encode document: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// documentID returns the entry's ID, or its fingerprint with Fingerprint
+func (s *ESSink) documentID(entry *models.LogEntry) string {
+	if s.opts.Fingerprint {
+		return entry.FingerprintOf(s.opts.FingerprintFields...)
+	}
+	return entry.ID
+}
+
+// indexName resolves the date pattern in the index name
+func (s *ESSink) indexName(ts time.Time) string {
+	idx := strings.Index(s.opts.Index, "2006")
+	if idx < 0 {
+		return s.opts.Index
+	}
+	return s.opts.Index[:idx] + ts.UTC().Format(s.opts.Index[idx:])
+}
+
+// esDocument maps an entry into the indexed document
+func esDocument(entry *models.LogEntry) map[string]interface{} {
+	doc := map[string]interface{}{
+		"@timestamp": entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		"level":      entry.Level,
+		"source":     entry.Source,
+		"message":    entry.Message,
+	}
+	if len(entry.Fields) > 0 {
+		doc["fields"] = entry.Fields
+	}
+	return doc
+}
+
+// Name returns the sink name
+func (s *ESSink) Name() string {
+	return fmt.Sprintf("elasticsearch:%s", s.opts.URL)
+}
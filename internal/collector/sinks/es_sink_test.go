@@ -0,0 +1,477 @@
+//go:build !no_sink_elasticsearch
+
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// bulkServer mimics the _bulk endpoint, deciding each item's status by its ID
+type bulkServer struct {
+	mu       sync.Mutex
+	requests [][]string // document IDs per request
+	docs     []map[string]interface{}
+	indices  []string
+	status   func(id string, attempt int) int
+	attempts map[string]int
+}
+
+func newBulkServer(status func(id string, attempt int) int) (*bulkServer, *httptest.Server) {
+	bs := &bulkServer{status: status, attempts: make(map[string]int)}
+	return bs, httptest.NewServer(http.HandlerFunc(bs.handle))
+}
+
+func (bs *bulkServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/_bulk" || r.Method != http.MethodPost {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var ids []string
+	var items []map[string]interface{}
+	hasErrors := false
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			http.Error(w, "bad action", http.StatusBadRequest)
+			return
+		}
+		if !scanner.Scan() {
+			http.Error(w, "missing document", http.StatusBadRequest)
+			return
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			http.Error(w, "bad document", http.StatusBadRequest)
+			return
+		}
+
+		id := action["index"]["_id"]
+		ids = append(ids, id)
+		bs.indices = append(bs.indices, action["index"]["_index"])
+
+		status := bs.status(id, bs.attempts[id])
+		bs.attempts[id]++
+		result := map[string]interface{}{"_id": id, "status": status}
+		if status >= 300 {
+			hasErrors = true
+			result["error"] = map[string]string{"type": "test_error"}
+		} else {
+			bs.docs = append(bs.docs, doc)
+		}
+		items = append(items, map[string]interface{}{"index": result})
+	}
+	bs.requests = append(bs.requests, ids)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"took":   1,
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
+
+func testEntry(id string, msg string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.ID = id
+	entry.Message = msg
+	entry.Source = "test"
+	return entry
+}
+
+func TestESSink_BulkDocument(t *testing.T) {
+	bs, server := newBulkServer(func(string, int) int { return http.StatusCreated })
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{URL: server.URL, Index: "logs", BatchSize: 10, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	entry := testEntry("a", "hello")
+	entry.Level = models.LevelError
+	entry.Timestamp = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry.Fields["user"] = "alice"
+
+	if err := sink.Write(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	if len(bs.requests) != 0 {
+		t.Fatal("Expected entry to be buffered until flush")
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if len(bs.docs) != 1 {
+		t.Fatalf("Expected 1 document, got %d", len(bs.docs))
+	}
+	doc := bs.docs[0]
+	if doc["@timestamp"] != "2024-01-02T03:04:05Z" {
+		t.Errorf("Expected @timestamp 2024-01-02T03:04:05Z, got %v", doc["@timestamp"])
+	}
+	if doc["level"] != "ERROR" || doc["source"] != "test" || doc["message"] != "hello" {
+		t.Errorf("Unexpected document: %v", doc)
+	}
+	fields, _ := doc["fields"].(map[string]interface{})
+	if fields["user"] != "alice" {
+		t.Errorf("Expected fields.user alice, got %v", doc["fields"])
+	}
+	if bs.indices[0] != "logs" {
+		t.Errorf("Expected index logs, got %s", bs.indices[0])
+	}
+}
+
+func TestESSink_FingerprintIDs(t *testing.T) {
+	bs, server := newBulkServer(func(string, int) int { return http.StatusCreated })
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{URL: server.URL, BatchSize: 10, FlushInterval: time.Hour, Fingerprint: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	// A redelivered entry arrives under a new ID
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, entry := range []*models.LogEntry{testEntry("a", "hello"), testEntry("b", "hello"), testEntry("c", "bye")} {
+		entry.Timestamp = ts
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := bs.requests[0]
+	if ids[0] != ids[1] || ids[0] == ids[2] {
+		t.Errorf("Expected the copies to share a document ID, got %v", ids)
+	}
+	if ids[0] == "a" {
+		t.Errorf("Expected a fingerprint rather than the entry ID, got %s", ids[0])
+	}
+}
+
+func TestESSink_BatchSize(t *testing.T) {
+	bs, server := newBulkServer(func(string, int) int { return http.StatusCreated })
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{URL: server.URL, BatchSize: 3, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	for i := 0; i < 7; i++ {
+		if err := sink.Write(context.Background(), testEntry(fmt.Sprintf("e%d", i), "msg")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bs.mu.Lock()
+	sent := len(bs.requests)
+	bs.mu.Unlock()
+	if sent != 2 {
+		t.Errorf("Expected 2 full batches sent, got %d", sent)
+	}
+
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bs.docs) != 7 {
+		t.Errorf("Expected 7 documents after stop, got %d", len(bs.docs))
+	}
+}
+
+func TestESSink_FlushInterval(t *testing.T) {
+	bs, server := newBulkServer(func(string, int) int { return http.StatusCreated })
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{URL: server.URL, BatchSize: 100, FlushInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), testEntry("a", "msg"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		bs.mu.Lock()
+		n := len(bs.docs)
+		bs.mu.Unlock()
+		if n == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected buffered entry to be flushed by the timer")
+}
+
+func TestESSink_PartialFailureRetriesFailedItems(t *testing.T) {
+	// "b" is rejected with 429 on the first attempt only
+	bs, server := newBulkServer(func(id string, attempt int) int {
+		if id == "b" && attempt == 0 {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusCreated
+	})
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{
+		URL:           server.URL,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	for _, id := range []string{"a", "b", "c"} {
+		sink.Write(context.Background(), testEntry(id, "msg"))
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+
+	if len(bs.requests) != 2 {
+		t.Fatalf("Expected 2 bulk requests, got %d", len(bs.requests))
+	}
+	if got := strings.Join(bs.requests[1], ","); got != "b" {
+		t.Errorf("Expected retry to carry only the failed item, got %s", got)
+	}
+	if len(bs.docs) != 3 {
+		t.Errorf("Expected 3 indexed documents, got %d", len(bs.docs))
+	}
+}
+
+func TestESSink_PermanentFailure(t *testing.T) {
+	bs, server := newBulkServer(func(id string, attempt int) int {
+		if id == "bad" {
+			return http.StatusBadRequest
+		}
+		return http.StatusCreated
+	})
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{URL: server.URL, BatchSize: 10, FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), testEntry("ok", "msg"))
+	sink.Write(context.Background(), testEntry("bad", "msg"))
+
+	err = sink.Flush()
+	if err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("Expected error mentioning the rejected entry, got %v", err)
+	}
+	if collector.IsRetryable(err) {
+		t.Errorf("Expected rejected entries to be a permanent error, got %v", err)
+	}
+	if len(bs.requests) != 1 {
+		t.Errorf("Expected permanent failures not to be retried, got %d requests", len(bs.requests))
+	}
+}
+
+func TestESSink_RetriesExhausted(t *testing.T) {
+	bs, server := newBulkServer(func(string, int) int { return http.StatusServiceUnavailable })
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{
+		URL:           server.URL,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		MaxRetries:    2,
+		RetryBackoff:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), testEntry("a", "msg"))
+	if err := sink.Flush(); err == nil {
+		t.Error("Expected error after retries are exhausted")
+	}
+	if len(bs.requests) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(bs.requests))
+	}
+}
+
+func TestESSink_FailedItemsKeptForNextFlush(t *testing.T) {
+	// "a" fails with 503 in the first two requests
+	bs, server := newBulkServer(func(id string, attempt int) int {
+		if id == "a" && attempt < 2 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusCreated
+	})
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{
+		URL:           server.URL,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		MaxRetries:    -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), testEntry("a", "msg"))
+	for i := 0; i < 2; i++ {
+		if err := sink.Flush(); err == nil || !collector.IsRetryable(err) {
+			t.Fatalf("Flush %d: expected a retryable error, got %v", i, err)
+		}
+	}
+	sink.Write(context.Background(), testEntry("b", "msg"))
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Expected the third flush to succeed, got %v", err)
+	}
+
+	if got := strings.Join(bs.requests[2], ","); got != "a,b" {
+		t.Errorf("Expected the kept item to be sent first, got %s", got)
+	}
+	if len(bs.docs) != 2 {
+		t.Errorf("Expected 2 indexed documents, got %d", len(bs.docs))
+	}
+}
+
+func TestESSink_WriteAfterStop(t *testing.T) {
+	_, server := newBulkServer(func(string, int) int { return http.StatusCreated })
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{URL: server.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Stop()
+
+	err = sink.Write(context.Background(), testEntry("late", "msg"))
+	if err == nil {
+		t.Fatal("Expected Write after Stop to fail")
+	}
+	if collector.IsRetryable(err) {
+		t.Errorf("Expected a permanent error, got %v", err)
+	}
+}
+
+func TestESSink_StopDuringBackoff(t *testing.T) {
+	_, server := newBulkServer(func(string, int) int { return http.StatusServiceUnavailable })
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{
+		URL:           server.URL,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		MaxRetries:    5,
+		RetryBackoff:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write(context.Background(), testEntry("a", "msg"))
+
+	flushed := make(chan error, 1)
+	go func() { flushed <- sink.Flush() }()
+	time.Sleep(50 * time.Millisecond)
+
+	// The flush is backing off, which must hold up neither another flush
+	// nor Stop
+	stopped := make(chan error, 1)
+	go func() { stopped <- sink.Stop() }()
+	for name, ch := range map[string]chan error{"Flush": flushed, "Stop": stopped} {
+		select {
+		case err := <-ch:
+			if err == nil {
+				t.Errorf("Expected %s to report the failed send", name)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s stalled behind the retry backoff", name)
+		}
+	}
+}
+
+func TestESSink_IndexName(t *testing.T) {
+	ts := time.Date(2024, 3, 7, 23, 0, 0, 0, time.FixedZone("X", -2*3600))
+
+	tests := []struct {
+		index    string
+		expected string
+	}{
+		{"logs", "logs"},
+		{"logs-2006.01.02", "logs-2024.03.08"},
+		{"app-logs-2006-01", "app-logs-2024-03"},
+	}
+
+	for _, tt := range tests {
+		sink := &ESSink{opts: ESSinkOptions{Index: tt.index}}
+		if got := sink.indexName(ts); got != tt.expected {
+			t.Errorf("indexName(%q): expected %s, got %s", tt.index, tt.expected, got)
+		}
+	}
+}
+
+func TestNewESSink_RequiresURL(t *testing.T) {
+	if _, err := NewESSink(ESSinkOptions{}); err == nil {
+		t.Error("Expected error for missing URL")
+	}
+}
+
+func TestESSink_Compression(t *testing.T) {
+	es := &encodingServer{t: t, status: http.StatusOK}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	sink, err := NewESSink(ESSinkOptions{URL: server.URL, Compression: CompressionZstd, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(context.Background(), testEntry("1", "compressed bulk")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(es.bodies) != 1 {
+		t.Fatalf("Expected 1 bulk request, got %d", len(es.bodies))
+	}
+	if es.encodings[0] != CompressionZstd {
+		t.Errorf("Expected Content-Encoding zstd, got %q", es.encodings[0])
+	}
+	lines := splitLines(es.bodies[0])
+	if len(lines) != 2 {
+		t.Fatalf("Expected action and document lines, got %d", len(lines))
+	}
+	var doc models.LogEntry
+	if err := json.Unmarshal(lines[1], &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Message != "compressed bulk" {
+		t.Errorf("Expected compressed bulk, got %q", doc.Message)
+	}
+}
@@ -0,0 +1,407 @@
+package sinks
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// FileSinkOptions configures a FileSink
+type FileSinkOptions struct {
+	// FlushInterval is how often buffered lines are flushed to disk
+	FlushInterval time.Duration
+
+	// MaxSize is the size in bytes after which the file is rotated.
+	// Zero disables rotation. Compressed files count their compressed
+	// size as it reaches the disk.
+	MaxSize int64
+
+	// Compression compresses the files with a codec such as
+	// CompressionZstd, adding its extension to the path unless it's there,
+	// e.g. logs.jsonl.zst. Reopening a file appends a new stream, which
+	// decompresses as one with the earlier ones. Unset writes plain text.
+	Compression string
+
+	// Template renders each entry as a line instead of JSON, see Formatter
+	Template string
+
+	// Partition, when set, makes the path a directory and writes each
+	// entry to the file named by formatting its UTC timestamp with this
+	// layout, e.g. "2006/01/02/15.jsonl" for one file per hour. The entry's
+	// own time is used rather than the clock so replayed logs land where
+	// they belong.
+	Partition string
+
+	// MaxOpenFiles caps how many partition files are kept open for entries
+	// arriving out of order. The least recently written is closed first.
+	// Defaults to 8.
+	MaxOpenFiles int
+}
+
+// DefaultMaxOpenFiles is the default cap on open partition files
+const DefaultMaxOpenFiles = 8
+
+// FileSink appends log entries to a file as JSON lines, or as lines
+// rendered with a template
+type FileSink struct {
+	path      string
+	opts      FileSinkOptions
+	formatter *Formatter
+
+	mu      sync.Mutex
+	files   map[string]*outputFile
+	recent  *list.List // of *outputFile, most recently written first
+	stopped bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// outputFile is one open output file
+type outputFile struct {
+	path     string
+	codec    string
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+	rotation int
+	elem     *list.Element
+
+	// Compressed files only: the stream the writer feeds and what it has
+	// written to the file
+	compressor compressor
+	counter    *countingWriter
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewFileSink creates a file sink with default options
+func NewFileSink(path string) (*FileSink, error) {
+	return NewFileSinkWithOptions(path, FileSinkOptions{})
+}
+
+// NewFileSinkWithOptions creates a file sink, creating the output directory
+// if needed. Partition directories are created as entries arrive.
+func NewFileSinkWithOptions(path string, opts FileSinkOptions) (*FileSink, error) {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.MaxOpenFiles <= 0 {
+		opts.MaxOpenFiles = DefaultMaxOpenFiles
+	}
+	if err := CheckCompression(opts.Compression); err != nil {
+		return nil, err
+	}
+	if opts.Partition == "" {
+		path = withExtension(path, opts.Compression)
+	}
+
+	fs := &FileSink{
+		path:   path,
+		opts:   opts,
+		files:  make(map[string]*outputFile),
+		recent: list.New(),
+		done:   make(chan struct{}),
+	}
+
+	if opts.Template != "" {
+		formatter, err := NewFormatter(opts.Template)
+		if err != nil {
+			return nil, err
+		}
+		fs.formatter = formatter
+	}
+
+	if opts.Partition == "" {
+		if _, err := fs.fileFor(path); err != nil {
+			return nil, err
+		}
+	}
+
+	fs.wg.Add(1)
+	go fs.flushLoop()
+
+	return fs, nil
+}
+
+// pathFor returns the file an entry is written to
+func (fs *FileSink) pathFor(entry *models.LogEntry) string {
+	if fs.opts.Partition == "" {
+		return fs.path
+	}
+	path := filepath.Join(fs.path, entry.Timestamp.UTC().Format(fs.opts.Partition))
+	return withExtension(path, fs.opts.Compression)
+}
+
+// withExtension adds the codec's extension to path unless it's there
+func withExtension(path, codec string) string {
+	ext := compressionExtensions[codec]
+	if strings.HasSuffix(path, ext) {
+		return path
+	}
+	return path + ext
+}
+
+// fileFor returns the open file for path, opening it and closing the least
+// recently written one if too many are open. Must be called with fs.mu held.
+func (fs *FileSink) fileFor(path string) (*outputFile, error) {
+	if of, ok := fs.files[path]; ok {
+		fs.recent.MoveToFront(of.elem)
+		return of, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	of := &outputFile{path: path, codec: fs.opts.Compression}
+	if err := of.open(); err != nil {
+		return nil, err
+	}
+
+	for len(fs.files) >= fs.opts.MaxOpenFiles {
+		oldest := fs.recent.Back().Value.(*outputFile)
+		fs.forget(oldest)
+		if err := oldest.close(); err != nil {
+			of.close()
+			return nil, err
+		}
+	}
+	of.elem = fs.recent.PushFront(of)
+	fs.files[path] = of
+	return of, nil
+}
+
+// forget drops a file from the open set. Must be called with fs.mu held.
+func (fs *FileSink) forget(of *outputFile) {
+	fs.recent.Remove(of.elem)
+	delete(fs.files, of.path)
+}
+
+// open opens the output file in append mode
+func (of *outputFile) open() error {
+	file, err := os.OpenFile(of.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	of.file = file
+	of.size = info.Size()
+	if of.codec == CompressionNone {
+		of.writer = bufio.NewWriter(file)
+		return nil
+	}
+
+	of.counter = &countingWriter{w: file, n: info.Size()}
+	of.compressor, err = newCompressor(of.codec, of.counter)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	of.writer = bufio.NewWriter(of.compressor)
+	return nil
+}
+
+// written returns the size of the file including what is buffered, for
+// compressed files what has reached the disk
+func (of *outputFile) written() int64 {
+	if of.counter != nil {
+		return of.counter.n
+	}
+	return of.size
+}
+
+// flush writes buffered lines to the file. A compressed stream is flushed
+// too, so everything written so far can be decompressed.
+func (of *outputFile) flush() error {
+	if err := of.writer.Flush(); err != nil {
+		return err
+	}
+	if of.compressor != nil {
+		return of.compressor.Flush()
+	}
+	return nil
+}
+
+// close flushes and closes the file, ending a compressed stream first so
+// the file is valid
+func (of *outputFile) close() error {
+	flushErr := of.writer.Flush()
+	var endErr error
+	if of.compressor != nil {
+		endErr = of.compressor.Close()
+	}
+	closeErr := of.file.Close()
+	if flushErr != nil {
+		return fmt.Errorf("failed to flush: %w", flushErr)
+	}
+	if endErr != nil {
+		return fmt.Errorf("failed to end compressed stream: %w", endErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close: %w", closeErr)
+	}
+	return nil
+}
+
+// flushLoop periodically flushes buffered lines
+func (fs *FileSink) flushLoop() {
+	defer fs.wg.Done()
+
+	ticker := time.NewTicker(fs.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fs.done:
+			return
+		case <-ticker.C:
+			if err := fs.Flush(); err != nil {
+				fmt.Printf("Error flushing %s: %v\n", fs.path, err)
+			}
+		}
+	}
+}
+
+// Write appends a single entry as one line
+func (fs *FileSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	line, err := fs.format(entry)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.stopped {
+		return fmt.Errorf("file sink stopped")
+	}
+
+	of, err := fs.fileFor(fs.pathFor(entry))
+	if err != nil {
+		return err
+	}
+
+	if fs.opts.MaxSize > 0 && of.written() > 0 && of.written()+int64(len(line)) > fs.opts.MaxSize {
+		if err := of.rotate(); err != nil {
+			// Start afresh with the next entry rather than keep a half
+			// rotated file
+			of.file.Close()
+			fs.forget(of)
+			return err
+		}
+	}
+
+	n, err := of.writer.Write(line)
+	of.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+	return nil
+}
+
+// format renders an entry as a newline-terminated line
+func (fs *FileSink) format(entry *models.LogEntry) ([]byte, error) {
+	if fs.formatter != nil {
+		return fs.formatter.Format(entry)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entry: %w", err)
+	}
+	return append(line, '\n'), nil
+}
+
+// rotate moves the file to the next free path.N and starts a new one.
+// Compressed files keep their extension last, e.g. logs.jsonl.1.zst.
+func (of *outputFile) rotate() error {
+	if err := of.close(); err != nil {
+		return fmt.Errorf("failed to close before rotation: %w", err)
+	}
+
+	// Find the next unused suffix so earlier rotations are never overwritten
+	ext := compressionExtensions[of.codec]
+	for {
+		of.rotation++
+		rotated := fmt.Sprintf("%s.%d%s", strings.TrimSuffix(of.path, ext), of.rotation, ext)
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			if err := os.Rename(of.path, rotated); err != nil {
+				return fmt.Errorf("failed to rotate file: %w", err)
+			}
+			break
+		}
+	}
+
+	return of.open()
+}
+
+// Flush writes buffered lines to disk
+func (fs *FileSink) Flush() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.stopped {
+		return nil
+	}
+	for _, of := range fs.files {
+		if err := of.flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop flushes remaining lines and closes the files
+func (fs *FileSink) Stop() error {
+	fs.mu.Lock()
+	if fs.stopped {
+		fs.mu.Unlock()
+		return nil
+	}
+	fs.stopped = true
+	close(fs.done)
+
+	var firstErr error
+	for _, of := range fs.files {
+		if err := of.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	fs.files = nil
+	fs.recent.Init()
+	fs.mu.Unlock()
+
+	fs.wg.Wait()
+	return firstErr
+}
+
+// Name returns the sink name
+func (fs *FileSink) Name() string {
+	return fmt.Sprintf("file:%s", fs.path)
+}
@@ -0,0 +1,280 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// readJSONLines decodes every line of a JSONL file into log entries
+func readJSONLines(t *testing.T, path string) []*models.LogEntry {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []*models.LogEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry models.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestFileSink_RoundTrip(t *testing.T) {
+	// Output directory does not exist yet
+	path := filepath.Join(t.TempDir(), "nested", "dir", "out.jsonl")
+
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var written []*models.LogEntry
+	for i := 0; i < n; i++ {
+		entry := models.NewLogEntry()
+		entry.Level = models.LevelWarning
+		entry.Source = "test"
+		entry.Message = fmt.Sprintf("message %d", i)
+		entry.Fields["index"] = float64(i)
+		written = append(written, entry)
+
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := readJSONLines(t, path)
+	if len(read) != n {
+		t.Fatalf("Expected %d lines, got %d", n, len(read))
+	}
+
+	for i, entry := range read {
+		want := written[i]
+		if entry.ID != want.ID {
+			t.Errorf("Line %d: expected ID %s, got %s", i, want.ID, entry.ID)
+		}
+		if entry.Message != want.Message {
+			t.Errorf("Line %d: expected message %q, got %q", i, want.Message, entry.Message)
+		}
+		if entry.Level != want.Level {
+			t.Errorf("Line %d: expected level %s, got %s", i, want.Level, entry.Level)
+		}
+		if !entry.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("Line %d: expected timestamp %v, got %v", i, want.Timestamp, entry.Timestamp)
+		}
+		if entry.Fields["index"] != float64(i) {
+			t.Errorf("Line %d: expected index field %d, got %v", i, i, entry.Fields["index"])
+		}
+	}
+}
+
+func TestFileSink_FlushInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+
+	sink, err := NewFileSinkWithOptions(path, FileSinkOptions{FlushInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	if err := sink.Write(context.Background(), models.NewLogEntry()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Timer flush should make the line visible without an explicit Flush
+	deadline := time.Now().Add(1 * time.Second)
+	for {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed flush never happened")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFileSink_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+
+	sink, err := NewFileSinkWithOptions(path, FileSinkOptions{MaxSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 30
+	for i := 0; i < n; i++ {
+		entry := models.NewLogEntry()
+		entry.Message = fmt.Sprintf("rotation message number %d", i)
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("Expected rotated file %s.1: %v", path, err)
+	}
+
+	// Every entry must be in exactly one file, in order
+	files := []string{}
+	for i := 1; ; i++ {
+		rotated := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(rotated); err != nil {
+			break
+		}
+		files = append(files, rotated)
+	}
+	files = append(files, path)
+
+	var total int
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 1024 {
+			t.Errorf("%s exceeds max size: %d bytes", f, info.Size())
+		}
+
+		for _, entry := range readJSONLines(t, f) {
+			expected := fmt.Sprintf("rotation message number %d", total)
+			if entry.Message != expected {
+				t.Errorf("Expected %q, got %q", expected, entry.Message)
+			}
+			total++
+		}
+	}
+
+	if total != n {
+		t.Errorf("Expected %d entries across files, got %d", n, total)
+	}
+}
+
+func TestFileSink_Partition(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+
+	sink, err := NewFileSinkWithOptions(dir, FileSinkOptions{Partition: "2006/01/02/15.jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("Expected no directory before the first entry")
+	}
+
+	// Two hours of entries, the second hour's interleaved with late ones
+	// from the first
+	start := time.Date(2024, 1, 2, 15, 50, 0, 0, time.UTC)
+	offsets := []time.Duration{0, 5 * time.Minute, 15 * time.Minute, 9 * time.Minute, 20 * time.Minute, 30 * time.Minute}
+	for i, offset := range offsets {
+		entry := models.NewLogEntry()
+		entry.Timestamp = start.Add(offset)
+		entry.Message = fmt.Sprintf("message %d", i)
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"2024/01/02/15.jsonl": {"message 0", "message 1", "message 3"},
+		"2024/01/02/16.jsonl": {"message 2", "message 4", "message 5"},
+	}
+	for name, messages := range expected {
+		entries := readJSONLines(t, filepath.Join(dir, name))
+		if len(entries) != len(messages) {
+			t.Fatalf("%s: expected %d entries, got %d", name, len(messages), len(entries))
+		}
+		for i, entry := range entries {
+			if entry.Message != messages[i] {
+				t.Errorf("%s: expected %q, got %q", name, messages[i], entry.Message)
+			}
+		}
+	}
+
+	files := 0
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files++
+		}
+		return nil
+	})
+	if files != len(expected) {
+		t.Errorf("Expected %d files, got %d", len(expected), files)
+	}
+}
+
+func TestFileSink_PartitionEviction(t *testing.T) {
+	dir := t.TempDir()
+
+	sink, err := NewFileSinkWithOptions(dir, FileSinkOptions{Partition: "15.jsonl", MaxOpenFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cycling through three hours keeps only two files open, so each
+	// write after the first round reopens an evicted file
+	start := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 9; i++ {
+		entry := models.NewLogEntry()
+		entry.Timestamp = start.Add(time.Duration(i%3) * time.Hour)
+		entry.Message = fmt.Sprintf("message %d", i)
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+		sink.mu.Lock()
+		open := len(sink.files)
+		sink.mu.Unlock()
+		if open > 2 {
+			t.Fatalf("Expected at most 2 open files, got %d", open)
+		}
+	}
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for hour := 0; hour < 3; hour++ {
+		entries := readJSONLines(t, filepath.Join(dir, fmt.Sprintf("%d.jsonl", 10+hour)))
+		if len(entries) != 3 {
+			t.Fatalf("Hour %d: expected 3 entries, got %d", 10+hour, len(entries))
+		}
+		for i, entry := range entries {
+			expected := fmt.Sprintf("message %d", hour+3*i)
+			if entry.Message != expected {
+				t.Errorf("Hour %d: expected %q, got %q", 10+hour, expected, entry.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+//go:build !no_sink_kafka
+
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Required acks settings for KafkaSinkOptions
+const (
+	AcksNone = "none" // don't wait for the broker
+	AcksOne  = "one"  // wait for the partition leader
+	AcksAll  = "all"  // wait for all in-sync replicas
+)
+
+// KafkaMessage is a single record to publish
+type KafkaMessage struct {
+	Key   []byte
+	Value []byte
+}
+
+// KafkaProducer publishes messages to a topic. It is satisfied by a
+// kafka-go writer in production and by fakes in tests.
+type KafkaProducer interface {
+	Produce(ctx context.Context, msgs []KafkaMessage) error
+	Close() error
+}
+
+// KafkaSinkOptions configures a KafkaSink
+type KafkaSinkOptions struct {
+	// Brokers lists the bootstrap brokers, e.g. localhost:9092
+	Brokers []string
+
+	// Topic is the topic entries are published to
+	Topic string
+
+	// BatchSize is how many entries are buffered before producing
+	BatchSize int
+
+	// FlushInterval is how often a partial batch is produced
+	FlushInterval time.Duration
+
+	// RequiredAcks is one of "none", "one" or "all"
+	RequiredAcks string
+
+	// WriteTimeout bounds a single produce call so a stuck broker can't
+	// block the pipeline
+	WriteTimeout time.Duration
+
+	// Producer overrides the kafka-go producer, mainly for tests
+	Producer KafkaProducer
+}
+
+// KafkaSink publishes log entries to Kafka as JSON, keyed by entry ID
+type KafkaSink struct {
+	opts     KafkaSinkOptions
+	producer KafkaProducer
+
+	mu     sync.Mutex
+	buffer []*models.LogEntry
+
+	flushMu sync.Mutex
+	stopped bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewKafkaSink creates a Kafka sink and starts its flush timer
+func NewKafkaSink(opts KafkaSinkOptions) (*KafkaSink, error) {
+	if opts.Topic == "" {
+		return nil, fmt.Errorf("kafka topic required")
+	}
+	if opts.Producer == nil && len(opts.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers required")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	if opts.RequiredAcks == "" {
+		opts.RequiredAcks = AcksAll
+	}
+
+	acks, err := parseRequiredAcks(opts.RequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+
+	producer := opts.Producer
+	if producer == nil {
+		producer = newKafkaWriter(opts, acks)
+	}
+
+	s := &KafkaSink{
+		opts:     opts,
+		producer: producer,
+		done:     make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.flushLoop()
+
+	return s, nil
+}
+
+// parseRequiredAcks maps the acks setting onto kafka-go's values
+func parseRequiredAcks(acks string) (kafka.RequiredAcks, error) {
+	switch strings.ToLower(acks) {
+	case AcksNone, "0":
+		return kafka.RequireNone, nil
+	case AcksOne, "1":
+		return kafka.RequireOne, nil
+	case AcksAll, "-1":
+		return kafka.RequireAll, nil
+	default:
+		return 0, fmt.Errorf("unsupported required acks: %s", acks)
+	}
+}
+
+// flushLoop periodically produces partial batches
+func (s *KafkaSink) flushLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				fmt.Printf("Error flushing %s: %v\n", s.Name(), err)
+			}
+		}
+	}
+}
+
+// Write buffers an entry, producing once the batch is full
+func (s *KafkaSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	s.mu.Lock()
+	s.buffer = append(s.buffer, entry)
+	full := len(s.buffer) >= s.opts.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Flush produces all buffered entries. Entries that fail are dropped and
+// counted rather than retried forever.
+func (s *KafkaSink) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.buffer
+	s.buffer = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	msgs := make([]KafkaMessage, 0, len(batch))
+	for _, entry := range batch {
+		value, err := json.Marshal(entry)
+		if err != nil {
+			metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Inc()
+			continue
+		}
+		msgs = append(msgs, KafkaMessage{Key: []byte(entry.ID), Value: value})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.WriteTimeout)
+	defer cancel()
+
+	if err := s.producer.Produce(ctx, msgs); err != nil {
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Add(int64(len(msgs)))
+		return fmt.Errorf("failed to produce %d entries: %w", len(msgs), err)
+	}
+	return nil
+}
+
+// Stop stops the flush timer, produces whatever is left and closes the producer
+func (s *KafkaSink) Stop() error {
+	s.flushMu.Lock()
+	if s.stopped {
+		s.flushMu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	close(s.done)
+	s.flushMu.Unlock()
+
+	s.wg.Wait()
+	flushErr := s.Flush()
+	if err := s.producer.Close(); err != nil {
+		return fmt.Errorf("failed to close producer: %w", err)
+	}
+	return flushErr
+}
+
+// Name returns the sink name
+func (s *KafkaSink) Name() string {
+	return fmt.Sprintf("kafka:%s", s.opts.Topic)
+}
+
+// kafkaBatchTimeout is how long the kafka-go writer waits to fill a
+// partition's batch. The sink hands over whole batches already, so waiting
+// for more would only hold up every produce call.
+const kafkaBatchTimeout = time.Millisecond
+
+// kafkaWriter adapts a kafka-go writer to KafkaProducer
+type kafkaWriter struct {
+	writer *kafka.Writer
+}
+
+func newKafkaWriter(opts KafkaSinkOptions, acks kafka.RequiredAcks) *kafkaWriter {
+	return &kafkaWriter{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(opts.Brokers...),
+			Topic:        opts.Topic,
+			Balancer:     &kafka.Hash{},
+			BatchSize:    opts.BatchSize,
+			BatchTimeout: kafkaBatchTimeout,
+			RequiredAcks: acks,
+			WriteTimeout: opts.WriteTimeout,
+		},
+	}
+}
+
+// Produce writes messages synchronously
+func (kw *kafkaWriter) Produce(ctx context.Context, msgs []KafkaMessage) error {
+	records := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		records[i] = kafka.Message{Key: msg.Key, Value: msg.Value}
+	}
+	return kw.writer.WriteMessages(ctx, records...)
+}
+
+// Close flushes and closes the writer
+func (kw *kafkaWriter) Close() error {
+	return kw.writer.Close()
+}
@@ -0,0 +1,241 @@
+//go:build !no_sink_kafka
+
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// mockProducer records produced messages and can be told to fail or block
+type mockProducer struct {
+	mu      sync.Mutex
+	batches [][]KafkaMessage
+	err     error
+	block   bool
+	closed  bool
+}
+
+func (m *mockProducer) Produce(ctx context.Context, msgs []KafkaMessage) error {
+	if m.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.batches = append(m.batches, msgs)
+	return nil
+}
+
+func (m *mockProducer) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func (m *mockProducer) messages() []KafkaMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var all []KafkaMessage
+	for _, batch := range m.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func TestKafkaSink_ProducesJSONKeyedByID(t *testing.T) {
+	producer := &mockProducer{}
+	sink, err := NewKafkaSink(KafkaSinkOptions{Topic: "logs", Producer: producer, BatchSize: 10, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Level = models.LevelError
+	entry.Source = "api"
+	entry.Message = "boom"
+	entry.Fields["user"] = "alice"
+
+	if err := sink.Write(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := producer.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(msgs))
+	}
+	if string(msgs[0].Key) != entry.ID {
+		t.Errorf("Expected key %s, got %s", entry.ID, msgs[0].Key)
+	}
+
+	var decoded models.LogEntry
+	if err := json.Unmarshal(msgs[0].Value, &decoded); err != nil {
+		t.Fatalf("Invalid JSON value: %v", err)
+	}
+	if decoded.ID != entry.ID || decoded.Message != "boom" || decoded.Level != models.LevelError || decoded.Fields["user"] != "alice" {
+		t.Errorf("Unexpected decoded entry: %+v", decoded)
+	}
+	if !producer.closed {
+		t.Error("Expected producer to be closed on stop")
+	}
+}
+
+func TestKafkaSink_Batching(t *testing.T) {
+	producer := &mockProducer{}
+	sink, err := NewKafkaSink(KafkaSinkOptions{Topic: "logs", Producer: producer, BatchSize: 3, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	for i := 0; i < 7; i++ {
+		sink.Write(context.Background(), models.NewLogEntry())
+	}
+
+	producer.mu.Lock()
+	batches := len(producer.batches)
+	producer.mu.Unlock()
+	if batches != 2 {
+		t.Errorf("Expected 2 full batches, got %d", batches)
+	}
+
+	sink.Flush()
+	if got := len(producer.messages()); got != 7 {
+		t.Errorf("Expected 7 messages after flush, got %d", got)
+	}
+}
+
+func TestKafkaSink_FlushInterval(t *testing.T) {
+	producer := &mockProducer{}
+	sink, err := NewKafkaSink(KafkaSinkOptions{Topic: "logs", Producer: producer, FlushInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), models.NewLogEntry())
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(producer.messages()) == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected buffered entry to be produced by the timer")
+}
+
+func TestKafkaSink_ProduceError(t *testing.T) {
+	producer := &mockProducer{err: errors.New("broker unavailable")}
+	sink, err := NewKafkaSink(KafkaSinkOptions{Topic: "logs", Producer: producer, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	dropped := metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError)
+	before := dropped.Value()
+
+	sink.Write(context.Background(), models.NewLogEntry())
+	err = sink.Write(context.Background(), models.NewLogEntry())
+	if err == nil {
+		t.Fatal("Expected produce error from Write")
+	}
+	if !errors.Is(err, producer.err) {
+		t.Errorf("Expected wrapped broker error, got %v", err)
+	}
+	if got := dropped.Value() - before; got != 2 {
+		t.Errorf("Expected 2 dropped entries, got %d", got)
+	}
+}
+
+func TestKafkaSink_WriteTimeout(t *testing.T) {
+	producer := &mockProducer{block: true}
+	sink, err := NewKafkaSink(KafkaSinkOptions{
+		Topic:         "logs",
+		Producer:      producer,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		WriteTimeout:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sink.Write(context.Background(), models.NewLogEntry())
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Write blocked on a stuck producer")
+	}
+}
+
+func TestNewKafkaSink_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts KafkaSinkOptions
+	}{
+		{"missing topic", KafkaSinkOptions{Brokers: []string{"localhost:9092"}}},
+		{"missing brokers", KafkaSinkOptions{Topic: "logs"}},
+		{"bad acks", KafkaSinkOptions{Topic: "logs", Brokers: []string{"localhost:9092"}, RequiredAcks: "most"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKafkaSink(tt.opts); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestParseRequiredAcks(t *testing.T) {
+	for _, acks := range []string{"none", "one", "all", "ALL", "0", "1", "-1"} {
+		if _, err := parseRequiredAcks(acks); err != nil {
+			t.Errorf("parseRequiredAcks(%q): unexpected error %v", acks, err)
+		}
+	}
+	if _, err := parseRequiredAcks(fmt.Sprint(2)); err == nil {
+		t.Error("Expected error for unsupported acks")
+	}
+}
+
+func TestNewKafkaWriter_BatchTimeout(t *testing.T) {
+	kw := newKafkaWriter(KafkaSinkOptions{
+		Brokers:       []string{"localhost:9092"},
+		Topic:         "logs",
+		BatchSize:     100,
+		FlushInterval: 5 * time.Second,
+	}, 0)
+	defer kw.Close()
+
+	// Batches are already built by the sink, a produce call must not wait
+	// up to a flush interval for more messages
+	if kw.writer.BatchTimeout >= 5*time.Second {
+		t.Errorf("Expected a short batch timeout, got %s", kw.writer.BatchTimeout)
+	}
+}
@@ -0,0 +1,287 @@
+//go:build !no_sink_loki
+
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// LokiSinkOptions configures a LokiSink
+type LokiSinkOptions struct {
+	// URL is the Loki base URL, e.g. http://localhost:3100
+	URL string
+
+	// Labels are static labels added to every stream
+	Labels map[string]string
+
+	// LabelFields lists entry fields that become stream labels. All other
+	// fields are appended to the log line as key=value pairs.
+	LabelFields []string
+
+	// BatchSize is how many entries are buffered before a push
+	BatchSize int
+
+	// FlushInterval is how often a partial batch is pushed
+	FlushInterval time.Duration
+
+	// MaxRetries is how many times a flush retries a push that failed with
+	// a retryable status. Entries that still fail are kept for the next
+	// flush.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubled on each attempt
+	RetryBackoff time.Duration
+
+	// Client is the HTTP client used for requests
+	Client *http.Client
+
+	// Compression compresses push requests with a codec such as
+	// CompressionGzip, which Loki accepts
+	Compression string
+}
+
+// LokiSink pushes log entries to Grafana Loki
+type LokiSink struct {
+	opts        LokiSinkOptions
+	labelFields map[string]bool
+	batches     *batcher
+}
+
+// NewLokiSink creates a Loki push sink and starts its flush timer
+func NewLokiSink(opts LokiSinkOptions) (*LokiSink, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("loki URL required")
+	}
+	if err := CheckCompression(opts.Compression); err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 100 * time.Millisecond
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	opts.URL = strings.TrimRight(opts.URL, "/")
+
+	s := &LokiSink{
+		opts:        opts,
+		labelFields: make(map[string]bool),
+	}
+	for _, name := range opts.LabelFields {
+		s.labelFields[name] = true
+	}
+	s.batches = newBatcher(s.Name(), batchOptions{
+		Size:          opts.BatchSize,
+		FlushInterval: opts.FlushInterval,
+		Retries:       opts.MaxRetries,
+		Backoff:       opts.RetryBackoff,
+	}, s.send)
+
+	return s, nil
+}
+
+// Write buffers an entry, pushing once the batch is full. It fails once the
+// sink is stopped.
+func (s *LokiSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	return s.batches.add(entry)
+}
+
+// Flush pushes all buffered entries, retrying pushes that fail with a
+// retryable status. Entries that still fail are kept for the next flush.
+func (s *LokiSink) Flush() error {
+	return s.batches.flush()
+}
+
+// Stop stops the flush timer and pushes whatever is left
+func (s *LokiSink) Stop() error {
+	return s.batches.stop()
+}
+
+// SetBatchHook routes every push through hook
+func (s *LokiSink) SetBatchHook(hook collector.BatchHook) {
+	s.batches.setHook(hook)
+}
+
+// ObserveSends reports the outcome of every push to observe
+func (s *LokiSink) ObserveSends(observe func(err error)) bool {
+	s.batches.observeSends(observe)
+	return true
+}
+
+// send pushes a batch, handing it back to be retried if the failure is
+// retryable
+func (s *LokiSink) send(batch []*models.LogEntry) ([]*models.LogEntry, []*models.LogEntry, error) {
+	err := s.push(batch)
+	switch {
+	case err == nil:
+		return nil, nil, nil
+	case collector.IsRetryable(err):
+		return batch, nil, err
+	default:
+		return nil, batch, err
+	}
+}
+
+// lokiStream is a single stream in a push request
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// lokiPush is the body of a push request
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+// push sends a batch to the push API
+func (s *LokiSink) push(batch []*models.LogEntry) error {
+	body, err := json.Marshal(s.buildPush(batch))
+	if err != nil {
+		return collector.Permanent(fmt.Errorf("failed to encode push request: %w", err))
+	}
+
+	req, err := newBodyRequest(s.opts.URL+"/loki/api/v1/push", "application/json", body, s.opts.Compression)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &collector.StatusError{
+			Op:         "push request",
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(msg)),
+		}
+	}
+	return nil
+}
+
+// buildPush groups entries into streams by label set. Loki rejects
+// out-of-order entries within a stream, so values are sorted by timestamp.
+func (s *LokiSink) buildPush(batch []*models.LogEntry) lokiPush {
+	type stream struct {
+		labels  map[string]string
+		entries []*models.LogEntry
+	}
+	streams := make(map[string]*stream)
+	var keys []string
+
+	for _, entry := range batch {
+		labels := s.labels(entry)
+		key := labelKey(labels)
+		st, ok := streams[key]
+		if !ok {
+			st = &stream{labels: labels}
+			streams[key] = st
+			keys = append(keys, key)
+		}
+		st.entries = append(st.entries, entry)
+	}
+
+	push := lokiPush{Streams: make([]lokiStream, 0, len(keys))}
+	for _, key := range keys {
+		st := streams[key]
+		sort.SliceStable(st.entries, func(i, j int) bool {
+			return st.entries[i].Timestamp.Before(st.entries[j].Timestamp)
+		})
+
+		values := make([][2]string, len(st.entries))
+		for i, entry := range st.entries {
+			values[i] = [2]string{
+				strconv.FormatInt(entry.Timestamp.UnixNano(), 10),
+				s.line(entry),
+			}
+		}
+		push.Streams = append(push.Streams, lokiStream{Stream: st.labels, Values: values})
+	}
+	return push
+}
+
+// labels builds the stream labels for an entry
+func (s *LokiSink) labels(entry *models.LogEntry) map[string]string {
+	labels := make(map[string]string, len(s.opts.Labels)+2+len(s.labelFields))
+	for k, v := range s.opts.Labels {
+		labels[k] = v
+	}
+	labels["source"] = entry.Source
+	labels["level"] = string(entry.Level)
+
+	for name := range s.labelFields {
+		if value, ok := entry.Fields[name]; ok {
+			labels[sanitizeLabelName(name)] = fmt.Sprint(value)
+		}
+	}
+	return labels
+}
+
+// line renders the log line: the message followed by the non-label fields
+func (s *LokiSink) line(entry *models.LogEntry) string {
+	line := strings.TrimRight(entry.Message, "\r\n")
+
+	var names []string
+	for name := range entry.Fields {
+		if !s.labelFields[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return line
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(line)
+	for _, name := range names {
+		value := fmt.Sprint(entry.Fields[name])
+		if strings.ContainsAny(value, " \"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", name, value)
+	}
+	return b.String()
+}
+
+// sanitizeLabelName maps a field name onto Loki's [a-zA-Z_][a-zA-Z0-9_]* label syntax
+func sanitizeLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// Name returns the sink name
+func (s *LokiSink) Name() string {
+	return fmt.Sprintf("loki:%s", s.opts.URL)
+}
@@ -0,0 +1,430 @@
+//go:build !no_sink_loki
+
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// lokiServer records push requests sent to it
+type lokiServer struct {
+	mu     sync.Mutex
+	pushes []lokiPush
+	status int
+	fails  int // pushes to answer with 500 before status
+}
+
+func newLokiServer(status int) (*lokiServer, *httptest.Server) {
+	ls := &lokiServer{status: status}
+	return ls, httptest.NewServer(http.HandlerFunc(ls.handle))
+}
+
+func (ls *lokiServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/loki/api/v1/push" || r.Method != http.MethodPost {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var push lokiPush
+	if err := json.NewDecoder(r.Body).Decode(&push); err != nil {
+		http.Error(w, "bad body", http.StatusBadRequest)
+		return
+	}
+
+	ls.mu.Lock()
+	ls.pushes = append(ls.pushes, push)
+	status := ls.status
+	if ls.fails > 0 {
+		ls.fails--
+		status = http.StatusInternalServerError
+	}
+	ls.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func lokiEntry(source string, level models.LogLevel, ts time.Time, msg string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Source = source
+	entry.Level = level
+	entry.Timestamp = ts
+	entry.Message = msg
+	return entry
+}
+
+func TestLokiSink_PushBody(t *testing.T) {
+	ls, server := newLokiServer(http.StatusNoContent)
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{
+		URL:           server.URL,
+		Labels:        map[string]string{"job": "logflux"},
+		BatchSize:     100,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	base := time.Unix(1700000000, 0)
+	entries := []*models.LogEntry{
+		lokiEntry("api", models.LevelInfo, base.Add(2*time.Second), "second"),
+		lokiEntry("api", models.LevelError, base, "boom"),
+		lokiEntry("api", models.LevelInfo, base.Add(time.Second), "first"),
+	}
+	for _, entry := range entries {
+		sink.Write(context.Background(), entry)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if len(ls.pushes) != 1 {
+		t.Fatalf("Expected 1 push, got %d", len(ls.pushes))
+	}
+	streams := ls.pushes[0].Streams
+	if len(streams) != 2 {
+		t.Fatalf("Expected 2 streams, got %d", len(streams))
+	}
+
+	info := streams[0]
+	if info.Stream["source"] != "api" || info.Stream["level"] != "INFO" || info.Stream["job"] != "logflux" {
+		t.Errorf("Unexpected labels: %v", info.Stream)
+	}
+	if len(info.Values) != 2 {
+		t.Fatalf("Expected 2 values, got %d", len(info.Values))
+	}
+
+	// Values must be ascending within a stream
+	expected := [][2]string{
+		{"1700000001000000000", "first"},
+		{"1700000002000000000", "second"},
+	}
+	for i, v := range info.Values {
+		if v != expected[i] {
+			t.Errorf("Value %d: expected %v, got %v", i, expected[i], v)
+		}
+	}
+
+	if streams[1].Stream["level"] != "ERROR" || streams[1].Values[0][1] != "boom" {
+		t.Errorf("Unexpected error stream: %+v", streams[1])
+	}
+}
+
+func TestLokiSink_LabelFields(t *testing.T) {
+	sink := &LokiSink{labelFields: map[string]bool{"env": true, "k8s.pod": true}}
+
+	entry := lokiEntry("api", models.LevelInfo, time.Now(), "request done\n")
+	entry.Fields["env"] = "prod"
+	entry.Fields["k8s.pod"] = "api-1"
+	entry.Fields["status"] = 200
+	entry.Fields["path"] = "/a b"
+
+	labels := sink.labels(entry)
+	if labels["env"] != "prod" || labels["k8s_pod"] != "api-1" {
+		t.Errorf("Expected field labels, got %v", labels)
+	}
+	if _, ok := labels["status"]; ok {
+		t.Error("Expected non-label field to stay out of labels")
+	}
+
+	if got, want := sink.line(entry), `request done path="/a b" status=200`; got != want {
+		t.Errorf("Expected line %q, got %q", want, got)
+	}
+}
+
+func TestLokiSink_BatchSize(t *testing.T) {
+	ls, server := newLokiServer(http.StatusNoContent)
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	for i := 0; i < 5; i++ {
+		sink.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "msg"))
+	}
+
+	ls.mu.Lock()
+	pushes := len(ls.pushes)
+	ls.mu.Unlock()
+	if pushes != 2 {
+		t.Errorf("Expected 2 pushes, got %d", pushes)
+	}
+
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ls.pushes) != 3 {
+		t.Errorf("Expected remaining entry pushed on stop, got %d pushes", len(ls.pushes))
+	}
+}
+
+func TestLokiSink_FlushInterval(t *testing.T) {
+	ls, server := newLokiServer(http.StatusNoContent)
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, FlushInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "msg"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		ls.mu.Lock()
+		n := len(ls.pushes)
+		ls.mu.Unlock()
+		if n == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected buffered entry to be pushed by the timer")
+}
+
+func TestLokiSink_ErrorStatus(t *testing.T) {
+	_, server := newLokiServer(http.StatusBadRequest)
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "msg"))
+	err = sink.Flush()
+	if err == nil {
+		t.Fatal("Expected error for rejected push")
+	}
+	if collector.IsRetryable(err) {
+		t.Errorf("Expected a 400 to be permanent, got %v", err)
+	}
+}
+
+func TestLokiSink_RetriesServerErrors(t *testing.T) {
+	ls, server := newLokiServer(http.StatusNoContent)
+	ls.fails = 2
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "msg"))
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Expected the retried push to succeed, got %v", err)
+	}
+	if len(ls.pushes) != 3 {
+		t.Errorf("Expected 3 pushes, got %d", len(ls.pushes))
+	}
+}
+
+func TestLokiSink_KeepsBatchForNextFlush(t *testing.T) {
+	ls, server := newLokiServer(http.StatusNoContent)
+	ls.fails = 1
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, FlushInterval: time.Hour, MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "kept"))
+	err = sink.Flush()
+	if err == nil || !collector.IsRetryable(err) {
+		t.Fatalf("Expected a retryable error, got %v", err)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Expected the next flush to push the kept batch, got %v", err)
+	}
+
+	if len(ls.pushes) != 2 {
+		t.Fatalf("Expected 2 pushes, got %d", len(ls.pushes))
+	}
+	if values := ls.pushes[1].Streams[0].Values; len(values) != 1 || values[0][1] != "kept" {
+		t.Errorf("Expected the kept entry to be pushed again, got %v", values)
+	}
+}
+
+func TestLokiSink_RetrierRetriesFlush(t *testing.T) {
+	ls, server := newLokiServer(http.StatusNoContent)
+	ls.fails = 2
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, FlushInterval: time.Hour, MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetter := NewMemorySink(10)
+	retrier := collector.NewRetrier(sink, collector.RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond, DeadLetter: deadLetter})
+	defer retrier.Stop()
+
+	retrier.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "msg"))
+	if err := retrier.Flush(); err != nil {
+		t.Fatalf("Expected the retried flush to succeed, got %v", err)
+	}
+	if len(ls.pushes) != 3 {
+		t.Errorf("Expected 3 pushes, got %d", len(ls.pushes))
+	}
+	if n := retrier.DeadLettered(); n != 0 {
+		t.Errorf("Expected nothing dead-lettered, got %d", n)
+	}
+}
+
+func TestLokiSink_RetrierDeadLetters(t *testing.T) {
+	ls, server := newLokiServer(http.StatusNoContent)
+	ls.fails = 5
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, FlushInterval: time.Hour, MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetter := NewMemorySink(10)
+	retrier := collector.NewRetrier(sink, collector.RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond, DeadLetter: deadLetter})
+	defer retrier.Stop()
+
+	retrier.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "lost"))
+	if err := retrier.Flush(); err != nil {
+		t.Fatalf("Expected the dead-lettered entry to end the flush, got %v", err)
+	}
+	if len(ls.pushes) != 3 {
+		t.Errorf("Expected 3 pushes, got %d", len(ls.pushes))
+	}
+	if n := retrier.DeadLettered(); n != 1 {
+		t.Fatalf("Expected 1 entry dead-lettered, got %d", n)
+	}
+	if recent := deadLetter.Recent(RecentFilter{}); len(recent) != 1 || recent[0].Message != "lost" {
+		t.Errorf("Expected the entry in the dead letter sink, got %v", recent)
+	}
+
+	// Nothing is left for the next flush
+	if err := retrier.Flush(); err != nil {
+		t.Errorf("Expected an empty flush to succeed, got %v", err)
+	}
+	if len(ls.pushes) != 3 {
+		t.Errorf("Expected no more pushes, got %d", len(ls.pushes))
+	}
+}
+
+func TestLokiSink_RetrierCoversTimerFlush(t *testing.T) {
+	ls, server := newLokiServer(http.StatusBadRequest)
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, FlushInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetter := NewMemorySink(10)
+	retrier := collector.NewRetrier(sink, collector.RetryOptions{DeadLetter: deadLetter})
+	defer retrier.Stop()
+
+	// The sink's own timer sends the rejected batch, and it still reaches
+	// the dead letter sink
+	retrier.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "rejected"))
+	deadline := time.Now().Add(2 * time.Second)
+	for retrier.DeadLettered() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := retrier.DeadLettered(); n != 1 {
+		t.Errorf("Expected the timer flush to dead-letter 1 entry, got %d", n)
+	}
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if len(ls.pushes) != 1 {
+		t.Errorf("Expected 1 push, got %d", len(ls.pushes))
+	}
+}
+
+func TestLokiSink_ObserveSends(t *testing.T) {
+	ls, server := newLokiServer(http.StatusNoContent)
+	ls.fails = 1
+	defer server.Close()
+
+	sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, FlushInterval: time.Hour, MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retrier := collector.NewRetrier(sink, collector.RetryOptions{BaseDelay: time.Millisecond})
+	defer retrier.Stop()
+
+	var mu sync.Mutex
+	var outcomes []error
+	if !retrier.ObserveSends(func(err error) {
+		mu.Lock()
+		outcomes = append(outcomes, err)
+		mu.Unlock()
+	}) {
+		t.Fatal("Expected the retrier to pass the observer on to the sink")
+	}
+
+	retrier.Write(context.Background(), lokiEntry("api", models.LevelInfo, time.Now(), "msg"))
+	if err := retrier.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(outcomes) != 2 || outcomes[0] == nil || outcomes[1] != nil {
+		t.Errorf("Expected a failed push then a successful one, got %v", outcomes)
+	}
+}
+
+func TestLokiSink_Compression(t *testing.T) {
+	for _, codec := range []string{CompressionNone, CompressionGzip, CompressionZstd, CompressionSnappy} {
+		t.Run(codec, func(t *testing.T) {
+			es := &encodingServer{t: t, status: http.StatusNoContent}
+			server := httptest.NewServer(es)
+			defer server.Close()
+
+			sink, err := NewLokiSink(LokiSinkOptions{URL: server.URL, Compression: codec, FlushInterval: time.Hour})
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := lokiEntry("app", models.LevelInfo, time.Unix(1700000000, 0), "compressed push")
+			if err := sink.Write(context.Background(), entry); err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Stop(); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(es.bodies) != 1 {
+				t.Fatalf("Expected 1 push, got %d", len(es.bodies))
+			}
+			if es.encodings[0] != codec {
+				t.Errorf("Expected Content-Encoding %q, got %q", codec, es.encodings[0])
+			}
+			var push lokiPush
+			if err := json.Unmarshal(es.bodies[0], &push); err != nil {
+				t.Fatalf("Invalid push body: %v", err)
+			}
+			if len(push.Streams) != 1 || push.Streams[0].Values[0][1] != "compressed push" {
+				t.Errorf("Unexpected push after decompression: %+v", push)
+			}
+		})
+	}
+}
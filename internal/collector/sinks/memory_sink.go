@@ -0,0 +1,136 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// DefaultMemoryCapacity is how many entries a MemorySink keeps by default
+const DefaultMemoryCapacity = 1000
+
+// RecentFilter narrows the entries returned by MemorySink.Recent
+type RecentFilter struct {
+	Level  models.LogLevel // minimum level, empty for all
+	Source string          // exact source, empty for all
+	Limit  int             // newest entries to return, 0 for all
+}
+
+// matches reports whether the entry passes the filter
+func (f RecentFilter) matches(entry *models.LogEntry) bool {
+	if f.Level != "" && !entry.Level.AtLeast(f.Level) {
+		return false
+	}
+	return f.Source == "" || entry.Source == f.Source
+}
+
+// MemorySink keeps the most recent entries in a fixed-size ring buffer so
+// they can be inspected over HTTP without external storage
+type MemorySink struct {
+	mu    sync.RWMutex
+	ring  []*models.LogEntry
+	next  int // slot the next entry goes into
+	count int // entries currently held
+}
+
+// NewMemorySink creates a sink holding up to capacity entries
+func NewMemorySink(capacity int) *MemorySink {
+	if capacity <= 0 {
+		capacity = DefaultMemoryCapacity
+	}
+
+	return &MemorySink{
+		ring: make([]*models.LogEntry, capacity),
+	}
+}
+
+// Write stores the entry, evicting the oldest one when full
+func (s *MemorySink) Write(ctx context.Context, entry *models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ring[s.next] = entry
+	s.next = (s.next + 1) % len(s.ring)
+	if s.count < len(s.ring) {
+		s.count++
+	}
+	return nil
+}
+
+// Recent returns the newest entries matching the filter, oldest first
+func (s *MemorySink) Recent(filter RecentFilter) []*models.LogEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []*models.LogEntry
+	// Walk from newest to oldest so Limit keeps the newest
+	for i := 1; i <= s.count; i++ {
+		entry := s.ring[(s.next-i+len(s.ring))%len(s.ring)]
+		if !filter.matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// Handler serves the buffered entries as a JSON array, filtered by the
+// level, source and limit query parameters
+func (s *MemorySink) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := RecentFilter{Source: query.Get("source")}
+
+		if level := query.Get("level"); level != "" {
+			parsed, err := models.ParseLevel(level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.Level = parsed
+		}
+
+		if limit := query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = n
+		}
+
+		entries := s.Recent(filter)
+		if entries == nil {
+			entries = []*models.LogEntry{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	})
+}
+
+// Flush is a no-op, entries are stored as they are written
+func (s *MemorySink) Flush() error {
+	return nil
+}
+
+// Name returns the sink name
+func (s *MemorySink) Name() string {
+	return "memory"
+}
@@ -0,0 +1,137 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func queryRecent(t *testing.T, sink *MemorySink, query string) []*models.LogEntry {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	sink.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/recent"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var entries []*models.LogEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Expected JSON array, got %q: %v", rec.Body.String(), err)
+	}
+	return entries
+}
+
+func TestMemorySink_EvictsOldest(t *testing.T) {
+	sink := NewMemorySink(5)
+
+	for i := 0; i < 12; i++ {
+		entry := models.NewLogEntry()
+		entry.Message = fmt.Sprintf("entry %d", i)
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries := queryRecent(t, sink, "")
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		expected := fmt.Sprintf("entry %d", 7+i)
+		if entry.Message != expected {
+			t.Errorf("Expected %q at %d, got %q", expected, i, entry.Message)
+		}
+	}
+
+	entries = queryRecent(t, sink, "?limit=2")
+	if len(entries) != 2 || entries[0].Message != "entry 10" || entries[1].Message != "entry 11" {
+		t.Errorf("Expected the 2 newest entries, got %v", entries)
+	}
+}
+
+func TestMemorySink_Filters(t *testing.T) {
+	sink := NewMemorySink(10)
+
+	writes := []struct {
+		level  models.LogLevel
+		source string
+	}{
+		{models.LevelInfo, "api"},
+		{models.LevelError, "api"},
+		{models.LevelDebug, "worker"},
+		{models.LevelCritical, "worker"},
+		{models.LevelError, "worker"},
+	}
+	for _, w := range writes {
+		entry := models.NewLogEntry()
+		entry.Level = w.level
+		entry.Source = w.source
+		sink.Write(context.Background(), entry)
+	}
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"?level=ERROR", 3},
+		{"?level=error&source=worker", 2},
+		{"?source=api", 2},
+		{"?level=ERROR&limit=1", 1},
+		{"?source=missing", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			entries := queryRecent(t, sink, tt.query)
+			if len(entries) != tt.expected {
+				t.Errorf("Expected %d entries, got %d", tt.expected, len(entries))
+			}
+			for _, entry := range entries {
+				if strings.Contains(strings.ToLower(tt.query), "level=error") && !entry.Level.AtLeast(models.LevelError) {
+					t.Errorf("Unexpected %s entry", entry.Level)
+				}
+			}
+		})
+	}
+}
+
+func TestMemorySink_BadQuery(t *testing.T) {
+	sink := NewMemorySink(10)
+
+	for _, query := range []string{"?level=LOUD", "?limit=many", "?limit=-1"} {
+		rec := httptest.NewRecorder()
+		sink.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/recent"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestMemorySink_ConcurrentWrites(t *testing.T) {
+	sink := NewMemorySink(100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				sink.Write(context.Background(), models.NewLogEntry())
+				sink.Recent(RecentFilter{Limit: 10})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(sink.Recent(RecentFilter{})); n != 100 {
+		t.Errorf("Expected a full buffer of 100 entries, got %d", n)
+	}
+}
@@ -0,0 +1,473 @@
+//go:build !no_sink_otlp
+
+package sinks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// OTLP transports, named as in OTEL_EXPORTER_OTLP_PROTOCOL
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+)
+
+// otlpScope names the instrumentation scope records are exported under
+const otlpScope = "logflux"
+
+// OTLPSinkOptions configures an OTLPSink
+type OTLPSinkOptions struct {
+	// Endpoint is where logs are exported to. Over HTTP it is the base URL,
+	// e.g. http://localhost:4318, and /v1/logs is appended unless already
+	// there. Over gRPC it is host:port, e.g. localhost:4317, optionally with
+	// an https:// scheme to use TLS.
+	Endpoint string
+
+	// Protocol is OTLPProtocolHTTP (the default) or OTLPProtocolGRPC
+	Protocol string
+
+	// Headers are sent with every export, as gRPC metadata over gRPC
+	Headers map[string]string
+
+	// Resource holds static resource attributes, e.g. service.name
+	Resource map[string]string
+
+	// ResourceFields lists entry fields that become resource attributes,
+	// such as the tags the enricher adds. Entries are grouped by resource,
+	// all other fields become log record attributes.
+	ResourceFields []string
+
+	// BatchSize is how many entries are buffered before an export
+	BatchSize int
+
+	// FlushInterval is how often a partial batch is exported
+	FlushInterval time.Duration
+
+	// Timeout bounds each export
+	Timeout time.Duration
+
+	// Client is the HTTP client used for requests over HTTP
+	Client *http.Client
+
+	// Compression compresses exports with a codec such as CompressionGzip.
+	// Over gRPC only gzip is available.
+	Compression string
+}
+
+// OTLPSink exports log entries to an OpenTelemetry collector or any other
+// OTLP logs endpoint
+type OTLPSink struct {
+	opts           OTLPSinkOptions
+	resourceFields map[string]bool
+
+	conn   *grpc.ClientConn
+	client collogspb.LogsServiceClient
+
+	batches *batcher
+}
+
+// NewOTLPSink creates an OTLP logs exporter and starts its flush timer
+func NewOTLPSink(opts OTLPSinkOptions) (*OTLPSink, error) {
+	if opts.Endpoint == "" {
+		return nil, fmt.Errorf("otlp endpoint required")
+	}
+	if opts.Protocol == "" {
+		opts.Protocol = OTLPProtocolHTTP
+	}
+	if err := CheckCompression(opts.Compression); err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	s := &OTLPSink{opts: opts}
+	s.SetResourceFields(opts.ResourceFields)
+
+	switch opts.Protocol {
+	case OTLPProtocolHTTP:
+		if s.opts.Client == nil {
+			s.opts.Client = &http.Client{Timeout: opts.Timeout}
+		}
+		s.opts.Endpoint = strings.TrimRight(opts.Endpoint, "/")
+		if !strings.HasSuffix(s.opts.Endpoint, "/v1/logs") {
+			s.opts.Endpoint += "/v1/logs"
+		}
+	case OTLPProtocolGRPC:
+		creds := insecure.NewCredentials()
+		target := strings.TrimPrefix(opts.Endpoint, "http://")
+		if strings.HasPrefix(target, "https://") {
+			target = strings.TrimPrefix(target, "https://")
+			creds = credentials.NewTLS(&tls.Config{})
+		}
+		dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+		switch opts.Compression {
+		case CompressionNone:
+		case CompressionGzip:
+			dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor(grpcgzip.Name)))
+		default:
+			return nil, fmt.Errorf("otlp over gRPC only supports gzip compression, got %q", opts.Compression)
+		}
+		conn, err := grpc.Dial(target, dialOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial %s: %w", target, err)
+		}
+		s.conn = conn
+		s.client = collogspb.NewLogsServiceClient(conn)
+	default:
+		return nil, fmt.Errorf("unknown otlp protocol %q, expected %s or %s", opts.Protocol, OTLPProtocolHTTP, OTLPProtocolGRPC)
+	}
+
+	s.batches = newBatcher(s.Name(), batchOptions{
+		Size:          opts.BatchSize,
+		FlushInterval: opts.FlushInterval,
+	}, s.send)
+
+	return s, nil
+}
+
+// SetResourceFields replaces the entry fields that become resource
+// attributes. It must be called before the first Write.
+func (s *OTLPSink) SetResourceFields(names []string) {
+	s.resourceFields = make(map[string]bool, len(names))
+	for _, name := range names {
+		s.resourceFields[name] = true
+	}
+}
+
+// Write buffers an entry, exporting once the batch is full. It fails once
+// the sink is stopped.
+func (s *OTLPSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	return s.batches.add(entry)
+}
+
+// Flush exports all buffered entries. Entries whose export failed with a
+// retryable error are kept for the next flush.
+func (s *OTLPSink) Flush() error {
+	return s.batches.flush()
+}
+
+// SetBatchHook routes every export through hook
+func (s *OTLPSink) SetBatchHook(hook collector.BatchHook) {
+	s.batches.setHook(hook)
+}
+
+// ObserveSends reports the outcome of every export to observe
+func (s *OTLPSink) ObserveSends(observe func(err error)) bool {
+	s.batches.observeSends(observe)
+	return true
+}
+
+// Stop stops the flush timer, exports whatever is left and closes the
+// gRPC connection
+func (s *OTLPSink) Stop() error {
+	err := s.batches.stop()
+	if s.conn != nil {
+		if closeErr := s.conn.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close connection: %w", closeErr)
+		}
+	}
+	return err
+}
+
+// send exports a batch, handing it back to be retried if the failure is
+// retryable
+func (s *OTLPSink) send(batch []*models.LogEntry) ([]*models.LogEntry, []*models.LogEntry, error) {
+	err := s.export(s.buildRequest(batch))
+	switch {
+	case err == nil:
+		return nil, nil, nil
+	case collector.IsRetryable(err):
+		return batch, nil, err
+	default:
+		return nil, batch, err
+	}
+}
+
+// export sends a request over the configured transport
+func (s *OTLPSink) export(req *collogspb.ExportLogsServiceRequest) error {
+	var (
+		resp *collogspb.ExportLogsServiceResponse
+		err  error
+	)
+	if s.client != nil {
+		resp, err = s.exportGRPC(req)
+	} else {
+		resp, err = s.exportHTTP(req)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Rejected records are not retried, resending the batch would duplicate
+	// the accepted ones
+	if partial := resp.GetPartialSuccess(); partial.GetRejectedLogRecords() > 0 {
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonSinkError).Add(partial.GetRejectedLogRecords())
+		fmt.Printf("⚠️  %s rejected %d log records: %s\n", s.Name(), partial.GetRejectedLogRecords(), partial.GetErrorMessage())
+	}
+	return nil
+}
+
+// exportHTTP posts a request as protobuf
+func (s *OTLPSink) exportHTTP(export *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	body, err := proto.Marshal(export)
+	if err != nil {
+		return nil, collector.Permanent(fmt.Errorf("failed to encode export request: %w", err))
+	}
+
+	req, err := newBodyRequest(s.opts.Endpoint, "application/x-protobuf", body, s.opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range s.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("export request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		return nil, &collector.StatusError{
+			Op:         "export request",
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(msg)),
+		}
+	}
+
+	// An empty or undecodable body still means the batch was accepted
+	result := &collogspb.ExportLogsServiceResponse{}
+	_ = proto.Unmarshal(msg, result)
+	return result, nil
+}
+
+// exportGRPC calls the logs service
+func (s *OTLPSink) exportGRPC(req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+	defer cancel()
+	if len(s.opts.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(s.opts.Headers))
+	}
+
+	resp, err := s.client.Export(ctx, req)
+	if err != nil {
+		return nil, grpcExportError(err)
+	}
+	return resp, nil
+}
+
+// grpcExportError marks the status codes the OTLP spec doesn't allow
+// retrying as permanent
+func grpcExportError(err error) error {
+	code := status.Code(err)
+	err = fmt.Errorf("export request failed: %w", err)
+	switch code {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted,
+		codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+		return err
+	default:
+		return collector.Permanent(err)
+	}
+}
+
+// buildRequest groups entries into resources by their resource attributes
+func (s *OTLPSink) buildRequest(batch []*models.LogEntry) *collogspb.ExportLogsServiceRequest {
+	type resource struct {
+		attributes map[string]string
+		records    []*logspb.LogRecord
+	}
+	resources := make(map[string]*resource)
+	var keys []string
+
+	for _, entry := range batch {
+		attributes := s.resourceAttributes(entry)
+		key := labelKey(attributes)
+		res, ok := resources[key]
+		if !ok {
+			res = &resource{attributes: attributes}
+			resources[key] = res
+			keys = append(keys, key)
+		}
+		res.records = append(res.records, s.logRecord(entry))
+	}
+
+	req := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: make([]*logspb.ResourceLogs, 0, len(keys)),
+	}
+	for _, key := range keys {
+		res := resources[key]
+		values := make(map[string]interface{}, len(res.attributes))
+		for name, value := range res.attributes {
+			values[name] = value
+		}
+		req.ResourceLogs = append(req.ResourceLogs, &logspb.ResourceLogs{
+			Resource: &resourcepb.Resource{Attributes: keyValues(values)},
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: otlpScope},
+				LogRecords: res.records,
+			}},
+		})
+	}
+	return req
+}
+
+// resourceAttributes builds the resource an entry belongs to
+func (s *OTLPSink) resourceAttributes(entry *models.LogEntry) map[string]string {
+	attributes := make(map[string]string, len(s.opts.Resource)+len(s.resourceFields))
+	for name, value := range s.opts.Resource {
+		attributes[name] = value
+	}
+	for name := range s.resourceFields {
+		if value, ok := entry.Fields[name]; ok {
+			attributes[name] = fmt.Sprint(value)
+		}
+	}
+	return attributes
+}
+
+// logRecord maps an entry onto an OTLP log record. Fields that aren't
+// resource attributes become record attributes, along with the source.
+func (s *OTLPSink) logRecord(entry *models.LogEntry) *logspb.LogRecord {
+	attributes := make(map[string]interface{}, len(entry.Fields)+1)
+	for name, value := range entry.Fields {
+		if !s.resourceFields[name] {
+			attributes[name] = value
+		}
+	}
+	if entry.Source != "" {
+		attributes["source"] = entry.Source
+	}
+
+	record := &logspb.LogRecord{
+		SeverityNumber: severityNumber(entry.Level),
+		SeverityText:   string(entry.Level),
+		Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: entry.Message}},
+		Attributes:     keyValues(attributes),
+	}
+	if !entry.Timestamp.IsZero() {
+		record.TimeUnixNano = uint64(entry.Timestamp.UnixNano())
+	}
+	if !entry.ReceivedAt.IsZero() {
+		record.ObservedTimeUnixNano = uint64(entry.ReceivedAt.UnixNano())
+	}
+	return record
+}
+
+// severityNumber maps a level onto the first OTLP severity of its range
+func severityNumber(level models.LogLevel) logspb.SeverityNumber {
+	switch level {
+	case models.LevelDebug:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case models.LevelInfo:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case models.LevelWarning:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case models.LevelError:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case models.LevelCritical:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+}
+
+// keyValues converts a map into attributes sorted by key
+func keyValues(values map[string]interface{}) []*commonpb.KeyValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	kvs := make([]*commonpb.KeyValue, len(names))
+	for i, name := range names {
+		kvs[i] = &commonpb.KeyValue{Key: name, Value: anyValue(values[name])}
+	}
+	return kvs
+}
+
+// anyValue converts a field value keeping its type where OTLP has one
+func anyValue(value interface{}) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case time.Time:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Format(time.RFC3339Nano)}}
+	case []interface{}:
+		array := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, len(v))}
+		for i, item := range v {
+			array.Values[i] = anyValue(item)
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+	case []string:
+		array := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, len(v))}
+		for i, item := range v {
+			array.Values[i] = anyValue(item)
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+	case map[string]interface{}:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: keyValues(v)}}}
+	case map[string]string:
+		values := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			values[name] = item
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: keyValues(values)}}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}}
+	}
+}
+
+// Name returns the sink name
+func (s *OTLPSink) Name() string {
+	return fmt.Sprintf("otlp:%s", s.opts.Endpoint)
+}
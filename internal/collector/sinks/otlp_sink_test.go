@@ -0,0 +1,390 @@
+//go:build !no_sink_otlp
+
+package sinks
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// otlpReceiver records export requests sent to it over HTTP or gRPC
+type otlpReceiver struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	headers  []string
+	status   int
+	code     codes.Code
+	rejected int64
+}
+
+func (r *otlpReceiver) record(req *collogspb.ExportLogsServiceRequest, header string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.headers = append(r.headers, header)
+}
+
+func (r *otlpReceiver) received() []*collogspb.ExportLogsServiceRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*collogspb.ExportLogsServiceRequest(nil), r.requests...)
+}
+
+func (r *otlpReceiver) response() *collogspb.ExportLogsServiceResponse {
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if r.rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: r.rejected, ErrorMessage: "too old"}
+	}
+	return resp
+}
+
+func (r *otlpReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/v1/logs" || req.Method != http.MethodPost {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if req.Header.Get("Content-Type") != "application/x-protobuf" {
+		http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.status != 0 {
+		http.Error(w, "unavailable", r.status)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	export := &collogspb.ExportLogsServiceRequest{}
+	if err := proto.Unmarshal(body, export); err != nil {
+		http.Error(w, "bad body", http.StatusBadRequest)
+		return
+	}
+	r.record(export, req.Header.Get("Authorization"))
+
+	resp, _ := proto.Marshal(r.response())
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(resp)
+}
+
+func (r *otlpReceiver) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	if r.code != codes.OK {
+		return nil, status.Error(r.code, "refused")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := ""
+	if values := md.Get("authorization"); len(values) > 0 {
+		header = values[0]
+	}
+	r.record(req, header)
+	return r.response(), nil
+}
+
+// startOTLPGRPC serves a receiver over gRPC on a loopback port
+func startOTLPGRPC(t *testing.T, r *otlpReceiver) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, r)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func otlpEntries() []*models.LogEntry {
+	base := time.Unix(1700000000, 0)
+	levels := []models.LogLevel{models.LevelDebug, models.LevelInfo, models.LevelWarning, models.LevelError, models.LevelCritical}
+	entries := make([]*models.LogEntry, len(levels))
+	for i, level := range levels {
+		entry := models.NewLogEntry()
+		entry.Source = "api"
+		entry.Level = level
+		entry.Timestamp = base.Add(time.Duration(i) * time.Second)
+		entry.Message = string(level) + " message"
+		entry.Fields["env"] = "prod"
+		entry.Fields["status"] = 500
+		entry.Fields["latency"] = 1.5
+		entry.Fields["cached"] = true
+		entry.Fields["user"] = map[string]interface{}{"id": "42"}
+		entries[i] = entry
+	}
+	entries[4].Fields["env"] = "staging"
+	return entries
+}
+
+// attributes flattens key values into a map for comparison
+func attributes(kvs []*commonpb.KeyValue) map[string]*commonpb.AnyValue {
+	values := make(map[string]*commonpb.AnyValue, len(kvs))
+	for _, kv := range kvs {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func checkOTLPExport(t *testing.T, requests []*collogspb.ExportLogsServiceRequest) {
+	t.Helper()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 export request, got %d", len(requests))
+	}
+	resources := requests[0].ResourceLogs
+	if len(resources) != 2 {
+		t.Fatalf("Expected 2 resources split by env, got %d", len(resources))
+	}
+
+	prod := attributes(resources[0].Resource.Attributes)
+	if prod["env"].GetStringValue() != "prod" || prod["service.name"].GetStringValue() != "checkout" {
+		t.Errorf("Expected env=prod and service.name=checkout on the resource, got %v", prod)
+	}
+	if staging := attributes(resources[1].Resource.Attributes); staging["env"].GetStringValue() != "staging" {
+		t.Errorf("Expected env=staging on the second resource, got %v", staging)
+	}
+
+	records := resources[0].ScopeLogs[0].LogRecords
+	records = append(records, resources[1].ScopeLogs[0].LogRecords...)
+	expected := []logspb.SeverityNumber{
+		logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+		logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+		logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d", len(expected), len(records))
+	}
+	base := time.Unix(1700000000, 0)
+	for i, record := range records {
+		if record.SeverityNumber != expected[i] {
+			t.Errorf("Expected severity %s for %s, got %s", expected[i], record.SeverityText, record.SeverityNumber)
+		}
+		if record.TimeUnixNano != uint64(base.Add(time.Duration(i)*time.Second).UnixNano()) {
+			t.Errorf("Expected the entry timestamp, got %d", record.TimeUnixNano)
+		}
+		if record.ObservedTimeUnixNano == 0 {
+			t.Error("Expected the received time as the observed time")
+		}
+		if record.Body.GetStringValue() != record.SeverityText+" message" {
+			t.Errorf("Expected the message as the body, got %v", record.Body)
+		}
+
+		attrs := attributes(record.Attributes)
+		if _, ok := attrs["env"]; ok {
+			t.Error("Expected env on the resource only")
+		}
+		if attrs["source"].GetStringValue() != "api" {
+			t.Errorf("Expected source=api, got %v", attrs["source"])
+		}
+		if attrs["status"].GetIntValue() != 500 {
+			t.Errorf("Expected status as an int, got %v", attrs["status"])
+		}
+		if attrs["latency"].GetDoubleValue() != 1.5 {
+			t.Errorf("Expected latency as a double, got %v", attrs["latency"])
+		}
+		if !attrs["cached"].GetBoolValue() {
+			t.Errorf("Expected cached as a bool, got %v", attrs["cached"])
+		}
+		user := attributes(attrs["user"].GetKvlistValue().GetValues())
+		if user["id"].GetStringValue() != "42" {
+			t.Errorf("Expected user as a key value list, got %v", attrs["user"])
+		}
+	}
+}
+
+func TestOTLPSink_HTTP(t *testing.T) {
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sink, err := NewOTLPSink(OTLPSinkOptions{
+		Endpoint:       server.URL,
+		Headers:        map[string]string{"Authorization": "Bearer token"},
+		Resource:       map[string]string{"service.name": "checkout"},
+		ResourceFields: []string{"env"},
+		BatchSize:      100,
+		FlushInterval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	for _, entry := range otlpEntries() {
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(receiver.received()) != 0 {
+		t.Fatal("Expected entries buffered until flush")
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkOTLPExport(t, receiver.received())
+	if receiver.headers[0] != "Bearer token" {
+		t.Errorf("Expected the Authorization header, got %q", receiver.headers[0])
+	}
+	if sink.Name() != "otlp:"+server.URL+"/v1/logs" {
+		t.Errorf("Unexpected name %s", sink.Name())
+	}
+}
+
+func TestOTLPSink_GRPC(t *testing.T) {
+	receiver := &otlpReceiver{}
+	addr := startOTLPGRPC(t, receiver)
+
+	sink, err := NewOTLPSink(OTLPSinkOptions{
+		Endpoint:       addr,
+		Protocol:       OTLPProtocolGRPC,
+		Headers:        map[string]string{"authorization": "Bearer token"},
+		Resource:       map[string]string{"service.name": "checkout"},
+		ResourceFields: []string{"env"},
+		BatchSize:      5,
+		FlushInterval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fifth entry fills the batch
+	for _, entry := range otlpEntries() {
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkOTLPExport(t, receiver.received())
+	if receiver.headers[0] != "Bearer token" {
+		t.Errorf("Expected the authorization metadata, got %q", receiver.headers[0])
+	}
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOTLPSink_Errors(t *testing.T) {
+	receiver := &otlpReceiver{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sink, err := NewOTLPSink(OTLPSinkOptions{Endpoint: server.URL + "/v1/logs", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	// Write only buffers, the failure shows up on Flush
+	if err := sink.Write(context.Background(), models.NewLogEntry()); err != nil {
+		t.Fatalf("Expected Write to buffer the entry, got %v", err)
+	}
+	err = sink.Flush()
+	if err == nil || !collector.IsRetryable(err) {
+		t.Errorf("Expected a retryable error for 503, got %v", err)
+	}
+
+	tests := []struct {
+		code      codes.Code
+		retryable bool
+	}{
+		{codes.Unavailable, true},
+		{codes.ResourceExhausted, true},
+		{codes.InvalidArgument, false},
+		{codes.Unauthenticated, false},
+	}
+	for _, tt := range tests {
+		receiver := &otlpReceiver{code: tt.code}
+		sink, err := NewOTLPSink(OTLPSinkOptions{
+			Endpoint:      startOTLPGRPC(t, receiver),
+			Protocol:      OTLPProtocolGRPC,
+			FlushInterval: time.Hour,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sink.Write(context.Background(), models.NewLogEntry())
+		err = sink.Flush()
+		if err == nil || collector.IsRetryable(err) != tt.retryable {
+			t.Errorf("Expected retryable=%v for %s, got %v", tt.retryable, tt.code, err)
+		}
+		sink.Stop()
+	}
+}
+
+func TestOTLPSink_PartialSuccess(t *testing.T) {
+	receiver := &otlpReceiver{rejected: 1}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sink, err := NewOTLPSink(OTLPSinkOptions{Endpoint: server.URL, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Stop()
+
+	sink.Write(context.Background(), models.NewLogEntry())
+	sink.Write(context.Background(), models.NewLogEntry())
+	if err := sink.Flush(); err != nil {
+		t.Errorf("Expected a partial success not to fail the batch, got %v", err)
+	}
+}
+
+func TestNewOTLPSink_Invalid(t *testing.T) {
+	if _, err := NewOTLPSink(OTLPSinkOptions{}); err == nil {
+		t.Error("Expected an error without an endpoint")
+	}
+	if _, err := NewOTLPSink(OTLPSinkOptions{Endpoint: "localhost:4317", Protocol: "thrift"}); err == nil {
+		t.Error("Expected an error for an unknown protocol")
+	}
+	if _, err := NewOTLPSink(OTLPSinkOptions{Endpoint: "localhost:4317", Protocol: OTLPProtocolGRPC, Compression: CompressionZstd}); err == nil {
+		t.Error("Expected an error for zstd over gRPC")
+	}
+}
+
+func TestOTLPSink_GRPCGzip(t *testing.T) {
+	receiver := &otlpReceiver{}
+	addr := startOTLPGRPC(t, receiver)
+
+	sink, err := NewOTLPSink(OTLPSinkOptions{
+		Endpoint:       addr,
+		Protocol:       OTLPProtocolGRPC,
+		Resource:       map[string]string{"service.name": "checkout"},
+		ResourceFields: []string{"env"},
+		Compression:    CompressionGzip,
+		BatchSize:      5,
+		FlushInterval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range otlpEntries() {
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkOTLPExport(t, receiver.received())
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
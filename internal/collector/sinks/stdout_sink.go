@@ -0,0 +1,200 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Output formats supported by StdoutSink
+const (
+	FormatText = "text" // numbered human-readable lines
+	FormatJSON = "json" // one compact JSON object per line
+)
+
+// Color modes for the text format
+const (
+	ColorAuto   = "auto"   // color when writing to a terminal and NO_COLOR is unset
+	ColorAlways = "always" // always color
+	ColorNever  = "never"  // never color
+)
+
+// levelColors maps levels to the ANSI codes their token is wrapped in
+var levelColors = map[models.LogLevel]string{
+	models.LevelDebug:    "\x1b[90m",
+	models.LevelInfo:     "\x1b[36m",
+	models.LevelWarning:  "\x1b[33m",
+	models.LevelError:    "\x1b[31m",
+	models.LevelCritical: "\x1b[1;31m",
+}
+
+const colorReset = "\x1b[0m"
+
+// StdoutSink prints log entries in a human-readable format, or as JSON lines
+// for piping into other tools
+type StdoutSink struct {
+	w      io.Writer
+	format string
+	color  bool
+
+	// formatter replaces the format when set
+	formatter *Formatter
+
+	mu    sync.Mutex
+	count int
+}
+
+// NewStdoutSink creates a sink that prints to standard output
+func NewStdoutSink() *StdoutSink {
+	return NewStdoutSinkWithWriter(os.Stdout)
+}
+
+// NewStdoutSinkWithWriter creates a sink that prints to the given writer
+func NewStdoutSinkWithWriter(w io.Writer) *StdoutSink {
+	return NewStdoutSinkWithFormat(w, FormatText)
+}
+
+// NewStdoutSinkWithFormat creates a sink that prints to the given writer in
+// the given format. Unknown formats fall back to text. Levels are colored
+// when the writer is a terminal, see SetColor.
+func NewStdoutSinkWithFormat(w io.Writer, format string) *StdoutSink {
+	if format != FormatJSON {
+		format = FormatText
+	}
+
+	s := &StdoutSink{
+		w:      w,
+		format: format,
+	}
+	s.SetColor(ColorAuto)
+	return s
+}
+
+// NewStdoutSinkWithTemplate creates a sink that prints entries rendered
+// with a template, see Formatter
+func NewStdoutSinkWithTemplate(w io.Writer, text string) (*StdoutSink, error) {
+	formatter, err := NewFormatter(text)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewStdoutSinkWithFormat(w, FormatText)
+	s.formatter = formatter
+	return s, nil
+}
+
+// SetColor sets whether the level token is wrapped in ANSI color codes in
+// the text format. In auto mode, color is used only when the writer is a
+// terminal and the NO_COLOR environment variable is unset.
+func (s *StdoutSink) SetColor(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch mode {
+	case ColorAlways:
+		s.color = true
+	case ColorNever:
+		s.color = false
+	default:
+		s.color = isTerminal(s.w) && os.Getenv("NO_COLOR") == ""
+	}
+}
+
+// isTerminal reports whether w is a character device such as a TTY
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Write prints a single entry
+func (s *StdoutSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	if s.formatter != nil {
+		return s.writeTemplate(entry)
+	}
+	if s.format == FormatJSON {
+		return s.writeJSON(entry)
+	}
+	return s.writeText(entry)
+}
+
+// writeJSON prints the entry as a single JSON line
+func (s *StdoutSink) writeJSON(entry *models.LogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := s.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+	return nil
+}
+
+// writeTemplate prints the entry rendered with the template
+func (s *StdoutSink) writeTemplate(entry *models.LogEntry) error {
+	line, err := s.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+	return nil
+}
+
+// writeText prints the entry as a numbered human-readable line
+func (s *StdoutSink) writeText(entry *models.LogEntry) error {
+	level := string(entry.Level)
+	if code, ok := levelColors[entry.Level]; ok && s.color {
+		level = code + level + colorReset
+	}
+
+	_, err := fmt.Fprintf(s.w, "[%d] %s [%s] %s: %s",
+		s.count,
+		entry.Timestamp.Format(time.RFC3339),
+		level,
+		entry.Source,
+		entry.Message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+
+	// File lines keep their trailing newline, other sources don't
+	if !strings.HasSuffix(entry.Message, "\n") {
+		if _, err := fmt.Fprintln(s.w); err != nil {
+			return fmt.Errorf("failed to write entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Flush is a no-op, output is not buffered
+func (s *StdoutSink) Flush() error {
+	return nil
+}
+
+// Name returns the sink name
+func (s *StdoutSink) Name() string {
+	return "stdout"
+}
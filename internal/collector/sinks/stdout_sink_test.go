@@ -0,0 +1,191 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestStdoutSink_Format(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewStdoutSinkWithWriter(&buf)
+
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	first := models.NewLogEntry()
+	first.Timestamp = ts
+	first.Level = models.LevelError
+	first.Source = "api"
+	first.Message = "db down"
+
+	second := models.NewLogEntry()
+	second.Timestamp = ts
+	second.Source = "app.log"
+	second.Message = "line from file\n"
+
+	ctx := context.Background()
+	if err := sink.Write(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[1] 2024-01-02T15:04:05Z [ERROR] api: db down\n" +
+		"[2] 2024-01-02T15:04:05Z [INFO] app.log: line from file\n"
+
+	if buf.String() != expected {
+		t.Errorf("Expected output:\n%q\ngot:\n%q", expected, buf.String())
+	}
+}
+
+func TestStdoutSink_Name(t *testing.T) {
+	sink := NewStdoutSink()
+	if sink.Name() != "stdout" {
+		t.Errorf("Expected name 'stdout', got %q", sink.Name())
+	}
+}
+
+func TestStdoutSink_JSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewStdoutSinkWithFormat(&buf, FormatJSON)
+
+	entry := models.NewLogEntry()
+	entry.Timestamp = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	entry.Level = models.LevelWarning
+	entry.Source = "api"
+	entry.Message = "slow \"query\"\n"
+	entry.Fields["duration_ms"] = float64(1200)
+	entry.Fields["user"] = "alice"
+
+	if err := sink.Write(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(context.Background(), models.NewLogEntry()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var decoded models.LogEntry
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON line, got %q: %v", lines[0], err)
+	}
+	if decoded.ID != entry.ID {
+		t.Errorf("Expected ID %q, got %q", entry.ID, decoded.ID)
+	}
+	if !decoded.Timestamp.Equal(entry.Timestamp) || decoded.Level != entry.Level ||
+		decoded.Source != entry.Source || decoded.Message != entry.Message {
+		t.Errorf("Expected %+v to round-trip, got %+v", entry, decoded)
+	}
+	if !reflect.DeepEqual(decoded.Fields, entry.Fields) {
+		t.Errorf("Expected fields %v, got %v", entry.Fields, decoded.Fields)
+	}
+}
+
+func TestStdoutSink_UnknownFormatFallsBackToText(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewStdoutSinkWithFormat(&buf, "xml")
+
+	entry := models.NewLogEntry()
+	entry.Timestamp = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	entry.Source = "api"
+	entry.Message = "hello"
+
+	if err := sink.Write(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	expected := "[1] 2024-01-02T15:04:05Z [INFO] api: hello\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestStdoutSink_Color(t *testing.T) {
+	entry := models.NewLogEntry()
+	entry.Timestamp = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	entry.Level = models.LevelError
+	entry.Source = "api"
+	entry.Message = "db down"
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{ColorAlways, "[1] 2024-01-02T15:04:05Z [\x1b[31mERROR\x1b[0m] api: db down\n"},
+		{ColorNever, "[1] 2024-01-02T15:04:05Z [ERROR] api: db down\n"},
+		// A buffer is not a terminal
+		{ColorAuto, "[1] 2024-01-02T15:04:05Z [ERROR] api: db down\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			var buf bytes.Buffer
+			sink := NewStdoutSinkWithWriter(&buf)
+			sink.SetColor(tt.mode)
+
+			if err := sink.Write(context.Background(), entry); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestStdoutSink_ColorLevels(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewStdoutSinkWithWriter(&buf)
+	sink.SetColor(ColorAlways)
+
+	for level, code := range levelColors {
+		buf.Reset()
+		entry := models.NewLogEntry()
+		entry.Level = level
+		if err := sink.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), code+string(level)+colorReset) {
+			t.Errorf("Expected %s to be colored, got %q", level, buf.String())
+		}
+	}
+}
+
+func TestStdoutSink_NoColorEnv(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	// Even a terminal gets no color when NO_COLOR is set
+	sink := NewStdoutSinkWithFormat(os.Stdout, FormatText)
+	if sink.color {
+		t.Error("Expected color to be disabled when NO_COLOR is set")
+	}
+}
+
+func TestStdoutSink_JSONIgnoresColor(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewStdoutSinkWithFormat(&buf, FormatJSON)
+	sink.SetColor(ColorAlways)
+
+	entry := models.NewLogEntry()
+	entry.Level = models.LevelCritical
+	if err := sink.Write(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("Expected no escape sequences in JSON output, got %q", buf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Formatter renders entries with a text/template, for sinks whose output
+// layout is configurable. The template is executed with the *LogEntry, so
+// {{.Timestamp}}, {{.Level}}, {{.Source}}, {{.Message}}, {{.ID}} and
+// {{.Fields}} are available, along with these functions:
+//
+//	fields  renders a map as sorted key=value pairs: {{fields .Fields}}
+//	field   returns a single field, empty if unset: {{field . "status"}}
+//	time    formats a time with a Go layout: {{time "15:04:05" .Timestamp}}
+//	json    renders a value as JSON: {{json .Fields}}
+//
+// Every rendered entry ends with a newline.
+type Formatter struct {
+	tmpl *template.Template
+}
+
+// templateFuncs are the functions available to templates
+var templateFuncs = template.FuncMap{
+	"fields": formatFields,
+	"field": func(entry *models.LogEntry, name string) interface{} {
+		if value, ok := entry.Fields[name]; ok {
+			return value
+		}
+		return ""
+	},
+	"time": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	},
+}
+
+// NewFormatter parses a template. A template that doesn't parse is
+// reported here rather than on the first entry.
+func NewFormatter(text string) (*Formatter, error) {
+	tmpl, err := template.New("entry").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return &Formatter{tmpl: tmpl}, nil
+}
+
+// Format renders an entry as a single newline-terminated line
+func (f *Formatter) Format(entry *models.LogEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.tmpl.Execute(&buf, entry); err != nil {
+		return nil, fmt.Errorf("failed to render entry: %w", err)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// formatFields renders fields as key=value pairs sorted by key. Values with
+// spaces, quotes or an equals sign are quoted.
+func formatFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(formatFieldValue(fields[k]))
+	}
+	return b.String()
+}
+
+// formatFieldValue renders one field value for formatFields
+func formatFieldValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		s = ""
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			s = fmt.Sprint(v)
+		} else {
+			s = string(data)
+		}
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,134 @@
+package sinks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// templateEntry returns an entry with a known timestamp and fields
+func templateEntry() *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Timestamp = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	entry.Level = models.LevelWarning
+	entry.Source = "api"
+	entry.Message = "slow request"
+	entry.Fields["status"] = float64(200)
+	entry.Fields["path"] = "/users"
+	entry.Fields["user_agent"] = "curl/8.0 (linux)"
+	return entry
+}
+
+func TestFormatter_Format(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			"layout",
+			`{{.Timestamp.Format "2006-01-02T15:04:05Z07:00"}} {{.Level}} {{.Source}} {{.Message}} {{fields .Fields}}`,
+			`2024-01-02T15:04:05Z WARNING api slow request path=/users status=200 user_agent="curl/8.0 (linux)"` + "\n",
+		},
+		{"time helper", `{{time "15:04:05" .Timestamp}} [{{.Level}}] {{.Message}}`, "15:04:05 [WARNING] slow request\n"},
+		{"single field", `{{.Message}} status={{field . "status"}} missing={{field . "nope"}}`, "slow request status=200 missing=\n"},
+		{"json", `{{json .Fields}}`, `{"path":"/users","status":200,"user_agent":"curl/8.0 (linux)"}` + "\n"},
+		{"keeps trailing newline", "{{.Message}}\n", "slow request\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter, err := NewFormatter(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line, err := formatter.Format(templateEntry())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(line) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestFormatter_QuotesValues(t *testing.T) {
+	got := formatFields(map[string]interface{}{
+		"empty":  "",
+		"quoted": `say "hi"`,
+		"nested": map[string]interface{}{"a": float64(1)},
+		"flag":   true,
+	})
+	expected := `empty="" flag=true nested="{\"a\":1}" quoted="say \"hi\""`
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestNewFormatter_Invalid(t *testing.T) {
+	if _, err := NewFormatter("{{.Message"); err == nil || !strings.Contains(err.Error(), "invalid template") {
+		t.Errorf("Expected an invalid template error, got %v", err)
+	}
+	if _, err := NewStdoutSinkWithTemplate(os.Stdout, "{{nope .Message}}"); err == nil {
+		t.Error("Expected an error for an unknown function")
+	}
+	path := filepath.Join(t.TempDir(), "out.log")
+	if _, err := NewFileSinkWithOptions(path, FileSinkOptions{Template: "{{end}}"}); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
+
+func TestFormatter_ExecutionError(t *testing.T) {
+	formatter, err := NewFormatter(`{{.Nope}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := formatter.Format(templateEntry()); err == nil {
+		t.Error("Expected an error for an unknown entry field")
+	}
+}
+
+func TestStdoutSink_Template(t *testing.T) {
+	var buf strings.Builder
+	sink, err := NewStdoutSinkWithTemplate(&buf, `{{.Level}} {{.Source}}: {{.Message}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Write(context.Background(), templateEntry()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "WARNING api: slow request\n" {
+		t.Errorf("Expected the rendered line, got %q", buf.String())
+	}
+}
+
+func TestFileSink_Template(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	sink, err := NewFileSinkWithOptions(path, FileSinkOptions{Template: `{{.Level}} {{.Message}} {{fields .Fields}}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Write(context.Background(), templateEntry()); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `WARNING slow request path=/users status=200 user_agent="curl/8.0 (linux)"` + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
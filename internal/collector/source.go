@@ -5,7 +5,11 @@ import (
 	"github.com/fatihserhatturan/logflux/pkg/models"
 )
 
-// Source represents a log source that can stream log entries
+// Source represents a log source that can stream log entries.
+// Sources that must know when an entry was handled, to advance a checkpoint
+// say, register a callback with LogEntry.OnAck. The pipeline acks entries
+// once every sink accepted and flushed them, or they were dropped on
+// purpose.
 type Source interface {
 	// Start begins streaming logs to the output channel
 	Start(ctx context.Context, out chan<- *models.LogEntry) error
@@ -15,4 +19,7 @@ type Source interface {
 
 	// Name returns the source identifier
 	Name() string
+
+	// Stats returns a snapshot of the source's activity
+	Stats() models.SourceStats
 }
@@ -0,0 +1,267 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// SourceManager runs several sources together, fanning them into one channel
+type SourceManager struct {
+	mu      sync.Mutex
+	sources []Source
+	started []Source
+	running bool
+
+	// Per-source rate limits, keyed by source name
+	limits   map[string]RateLimit
+	limiters map[string]*RateLimiter
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	// finished is closed once every started source exited on its own
+	finished chan struct{}
+}
+
+// admittingSource is implemented by sources that answer their clients, so
+// entries over a drop limit can be refused before the client is told they
+// were accepted
+type admittingSource interface {
+	SetAdmission(allow func() bool)
+}
+
+// NewSourceManager creates an empty source manager
+func NewSourceManager() *SourceManager {
+	return &SourceManager{
+		limits:   make(map[string]RateLimit),
+		limiters: make(map[string]*RateLimiter),
+	}
+}
+
+// SetRateLimit limits how fast the named source may emit entries. Each source
+// gets its own limiter so a flood on one doesn't starve the others.
+// Must be called before Start/Run.
+func (sm *SourceManager) SetRateLimit(sourceName string, limit RateLimit) error {
+	if err := limit.validate(); err != nil {
+		return fmt.Errorf("%s: %w", sourceName, err)
+	}
+	if limit.Mode == "" {
+		limit.Mode = RateLimitDrop
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.limits[sourceName] = limit
+	sm.limiters[sourceName] = NewRateLimiter(limit.PerSecond, limit.Burst)
+	return nil
+}
+
+// Limiter returns the rate limiter for the named source, or nil if it has none
+func (sm *SourceManager) Limiter(sourceName string) *RateLimiter {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.limiters[sourceName]
+}
+
+// Add registers a source. Sources must be added before Start/Run.
+func (sm *SourceManager) Add(source Source) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.sources = append(sm.sources, source)
+}
+
+// Sources returns the registered sources
+func (sm *SourceManager) Sources() []Source {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return append([]Source(nil), sm.sources...)
+}
+
+// Start starts every source, all writing to out.
+// If any source fails to start, the ones already started are stopped and
+// the start errors are returned together.
+func (sm *SourceManager) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.running {
+		return fmt.Errorf("source manager already running")
+	}
+	if len(sm.sources) == 0 {
+		return fmt.Errorf("no sources configured")
+	}
+
+	sm.done = make(chan struct{})
+
+	var errs []error
+	for _, source := range sm.sources {
+		if err := source.Start(ctx, sm.sourceOutput(ctx, source, out)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+			continue
+		}
+		sm.started = append(sm.started, source)
+	}
+
+	if len(errs) > 0 {
+		// Roll back the sources that did come up
+		sm.stopStarted()
+		return fmt.Errorf("failed to start sources: %w", errors.Join(errs...))
+	}
+
+	sm.running = true
+	sm.finished = make(chan struct{})
+	go watchFinished(append([]Source(nil), sm.started...), sm.done, sm.finished)
+	return nil
+}
+
+// watchFinished closes finished once every source's loop has ended, unless
+// the manager is stopped first. A source that doesn't report its loop
+// ending never finishes.
+func watchFinished(sources []Source, stopped <-chan struct{}, finished chan struct{}) {
+	for _, source := range sources {
+		exiting, ok := source.(interface{ Done() <-chan struct{} })
+		if !ok {
+			return
+		}
+		select {
+		case <-exiting.Done():
+		case <-stopped:
+			return
+		}
+	}
+	close(finished)
+}
+
+// Finished is closed once every source has exited on its own while the
+// manager runs, such as files read once reaching their end
+func (sm *SourceManager) Finished() <-chan struct{} {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.finished
+}
+
+// sourceOutput returns the channel a source should write to. Rate limited
+// sources get their own channel, forwarded to out through their limiter,
+// unless they can refuse entries over a drop limit themselves.
+// Must hold sm.mu.
+func (sm *SourceManager) sourceOutput(ctx context.Context, source Source, out chan<- *models.LogEntry) chan<- *models.LogEntry {
+	name := source.Name()
+	limit, ok := sm.limits[name]
+	if !ok {
+		return out
+	}
+	limiter := sm.limiters[name]
+
+	if supervised, ok := source.(interface{ Source() Source }); ok {
+		source = supervised.Source()
+	}
+	if admitting, ok := source.(admittingSource); ok && limit.Mode == RateLimitDrop {
+		admitting.SetAdmission(func() bool {
+			if limiter.Allow() {
+				return true
+			}
+			countRateLimited(name)
+			return false
+		})
+		return out
+	}
+
+	in := make(chan *models.LogEntry, cap(out))
+	sm.wg.Add(1)
+	go sm.forward(ctx, name, in, out, limiter, limit.Mode)
+	return in
+}
+
+// countRateLimited records an entry the named source lost to its limit
+func countRateLimited(name string) {
+	metrics.EntriesDropped.WithLabelValues(metrics.ReasonRateLimited).Inc()
+	metrics.EntriesRateLimited.WithLabelValues(name).Inc()
+}
+
+// forward copies entries from the named source's channel to out, applying
+// the limiter. Entries over a drop limit were already accepted from the
+// client, so they can only be counted.
+func (sm *SourceManager) forward(ctx context.Context, name string, in <-chan *models.LogEntry, out chan<- *models.LogEntry, limiter *RateLimiter, mode RateLimitMode) {
+	defer sm.wg.Done()
+
+	// Cancelled when the manager stops so a blocked Wait returns
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-sm.done:
+			cancel()
+		case <-waitCtx.Done():
+		}
+	}()
+
+	for {
+		var entry *models.LogEntry
+		select {
+		case entry = <-in:
+		case <-waitCtx.Done():
+			return
+		}
+
+		if mode == RateLimitBlock {
+			if err := limiter.Wait(waitCtx); err != nil {
+				return
+			}
+		} else if !limiter.Allow() {
+			countRateLimited(name)
+			entry.Ack()
+			continue
+		}
+
+		select {
+		case out <- entry:
+		case <-waitCtx.Done():
+			return
+		}
+	}
+}
+
+// Run starts every source and blocks until ctx is cancelled, then stops them all
+func (sm *SourceManager) Run(ctx context.Context, out chan<- *models.LogEntry) error {
+	if err := sm.Start(ctx, out); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return sm.Stop()
+}
+
+// Stop stops every started source and returns any stop errors together
+func (sm *SourceManager) Stop() error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if !sm.running {
+		return nil
+	}
+	sm.running = false
+
+	return sm.stopStarted()
+}
+
+// stopStarted stops started sources in reverse order. Must hold sm.mu.
+func (sm *SourceManager) stopStarted() error {
+	var errs []error
+	for i := len(sm.started) - 1; i >= 0; i-- {
+		source := sm.started[i]
+		if err := source.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+		}
+	}
+	sm.started = nil
+
+	// Sources are down, release the rate limit forwarders
+	close(sm.done)
+	sm.wg.Wait()
+
+	return errors.Join(errs...)
+}
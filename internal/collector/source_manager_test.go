@@ -0,0 +1,235 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// fakeSource records its lifecycle calls
+type fakeSource struct {
+	name     string
+	startErr error
+	started  bool
+	stopped  bool
+}
+
+func (f *fakeSource) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	if f.startErr != nil {
+		return f.startErr
+	}
+	f.started = true
+	return nil
+}
+
+func (f *fakeSource) Stop() error {
+	f.stopped = true
+	return nil
+}
+
+func (f *fakeSource) Name() string {
+	return f.name
+}
+
+func (f *fakeSource) Stats() models.SourceStats {
+	return models.SourceStats{}
+}
+
+// finishingSource is a fake source whose loop ends when finish is closed
+type finishingSource struct {
+	fakeSource
+	finish chan struct{}
+}
+
+func (f *finishingSource) Done() <-chan struct{} { return f.finish }
+
+// freeAddr returns a loopback address with an unused port
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestSourceManager_FileAndHTTP(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(testFile, []byte("from file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	addr := freeAddr(t)
+
+	manager := NewSourceManager()
+	manager.Add(sources.NewFileReader(testFile))
+	manager.Add(sources.NewHTTPReceiver(addr))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan *models.LogEntry, 10)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run(ctx, out)
+	}()
+
+	// Post until the HTTP receiver is up
+	body, _ := json.Marshal(map[string]string{"message": "from http", "source": "http-client"})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Post("http://"+addr+"/logs", "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("HTTP receiver never came up: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	seen := make(map[string]bool)
+	timeout := time.After(2 * time.Second)
+	for len(seen) < 2 {
+		select {
+		case entry := <-out:
+			seen[entry.Source] = true
+		case <-timeout:
+			t.Fatalf("Only received entries from %v", seen)
+		}
+	}
+
+	if !seen[testFile] || !seen["http-client"] {
+		t.Errorf("Expected entries from both sources, got %v", seen)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	case <-time.After(7 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestSourceManager_StartFailureStopsStarted(t *testing.T) {
+	first := &fakeSource{name: "first"}
+	broken := &fakeSource{name: "broken", startErr: errors.New("bind failed")}
+	last := &fakeSource{name: "last"}
+
+	manager := NewSourceManager()
+	manager.Add(first)
+	manager.Add(broken)
+	manager.Add(last)
+
+	err := manager.Start(context.Background(), make(chan *models.LogEntry))
+	if err == nil {
+		t.Fatal("Expected start error")
+	}
+	if !errors.Is(err, broken.startErr) {
+		t.Errorf("Expected wrapped start error, got %v", err)
+	}
+
+	if !first.stopped || !last.stopped {
+		t.Error("Sources started before the failure should be stopped")
+	}
+	if broken.stopped {
+		t.Error("Source that failed to start should not be stopped")
+	}
+}
+
+func TestSourceManager_Finished(t *testing.T) {
+	first := &finishingSource{fakeSource: fakeSource{name: "first"}, finish: make(chan struct{})}
+	second := &finishingSource{fakeSource: fakeSource{name: "second"}, finish: make(chan struct{})}
+	manager := NewSourceManager()
+	manager.Add(first)
+	manager.Add(second)
+	if err := manager.Start(context.Background(), make(chan *models.LogEntry)); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	close(first.finish)
+	select {
+	case <-manager.Finished():
+		t.Fatal("Expected the manager unfinished while a source runs")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(second.finish)
+	select {
+	case <-manager.Finished():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the manager finished once every source was")
+	}
+}
+
+func TestSourceManager_NoSources(t *testing.T) {
+	manager := NewSourceManager()
+	if err := manager.Start(context.Background(), make(chan *models.LogEntry)); err == nil {
+		t.Error("Expected error with no sources")
+	}
+}
+
+func TestSourceManager_RateLimitRefusesHTTP(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := sources.NewHTTPReceiver(addr)
+
+	manager := NewSourceManager()
+	manager.Add(NewSupervisor(receiver, SupervisorOptions{}))
+	if err := manager.SetRateLimit(receiver.Name(), RateLimit{PerSecond: 0.001, Burst: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := make(chan *models.LogEntry, 10)
+	if err := manager.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+
+	post := func(path, body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post("http://"+addr+path, "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// The client hears about the limit instead of entries vanishing after
+	// a 202
+	for i, expected := range []int{http.StatusAccepted, http.StatusAccepted, http.StatusTooManyRequests} {
+		if resp := post("/logs", `{"message": "hello"}`); resp.StatusCode != expected {
+			t.Errorf("Request %d: expected %d, got %d", i, expected, resp.StatusCode)
+		}
+	}
+	if resp := post("/batch", `[{"message": "a"}, {"message": "b"}]`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected a batch over the limit to get 429, got %d", resp.StatusCode)
+	} else if resp.Header.Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	if len(out) != 2 {
+		t.Errorf("Expected 2 entries through, got %d", len(out))
+	}
+	if dropped := manager.Limiter(receiver.Name()).Dropped(); dropped != 3 {
+		t.Errorf("Expected 3 entries counted as rate limited, got %d", dropped)
+	}
+	if n := metrics.EntriesRateLimited.WithLabelValues(receiver.Name()).Value(); n != 3 {
+		t.Errorf("Expected the per-source metric to count 3, got %d", n)
+	}
+}
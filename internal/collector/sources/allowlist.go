@@ -0,0 +1,124 @@
+package sources
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+)
+
+// Allowlist holds the networks a receiver accepts clients from. A nil
+// Allowlist accepts everyone.
+type Allowlist struct {
+	nets []*net.IPNet
+}
+
+// ParseAllowlist parses CIDRs such as 10.0.0.0/8 or fd00::/8. A bare
+// address stands for itself alone. No entries yields a nil Allowlist.
+func ParseAllowlist(entries []string) (*Allowlist, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	a := &Allowlist{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			a.nets = append(a.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		a.nets = append(a.nets, network)
+	}
+	return a, nil
+}
+
+// Allows reports whether ip is in one of the networks
+func (a *Allowlist) Allows(ip net.IP) bool {
+	if a == nil {
+		return true
+	}
+	for _, network := range a.nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsAddr reports whether the host of a remote address is allowed.
+// Addresses without an IP are refused by a non-nil Allowlist.
+func (a *Allowlist) allowsAddr(addr net.Addr) bool {
+	if a == nil {
+		return true
+	}
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return a.Allows(addr.IP)
+	case *net.TCPAddr:
+		return a.Allows(addr.IP)
+	case nil:
+		return false
+	default:
+		return a.Allows(hostIP(addr.String()))
+	}
+}
+
+// hostIP returns the IP of a host:port or bare host, or nil
+func hostIP(hostport string) net.IP {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	// Zones such as fe80::1%eth0 don't affect which network an address is in
+	host, _, _ = strings.Cut(host, "%")
+	return net.ParseIP(strings.Trim(host, "[]"))
+}
+
+// clientIP returns the address an HTTP request came from. X-Forwarded-For
+// is only believed when the peer is a trusted proxy, and then read from the
+// right, skipping trusted proxies, since clients can prepend anything.
+func clientIP(r *http.Request, trustedProxies *Allowlist) net.IP {
+	ip := hostIP(r.RemoteAddr)
+	if trustedProxies == nil || ip == nil || !trustedProxies.Allows(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hostIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// Whatever came before an unreadable hop can't be trusted
+			return ip
+		}
+		ip = hop
+		if !trustedProxies.Allows(hop) {
+			return hop
+		}
+	}
+	return ip
+}
+
+// recordDenied counts a connection, datagram or request refused by an
+// allowlist
+func recordDenied(stats *sourceStats, source string) {
+	stats.clientDenied()
+	metrics.SourcesDenied.WithLabelValues(source).Inc()
+}
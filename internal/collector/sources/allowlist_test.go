@@ -0,0 +1,206 @@
+package sources
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func mustAllowlist(t *testing.T, entries ...string) *Allowlist {
+	t.Helper()
+	a, err := ParseAllowlist(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestParseAllowlist(t *testing.T) {
+	a := mustAllowlist(t, "10.0.0.0/8", "192.168.1.7", "fd00::/8")
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"10.1.2.3", true},
+		{"11.1.2.3", false},
+		{"192.168.1.7", true},
+		{"192.168.1.8", false},
+		{"::ffff:10.0.0.1", true},
+		{"fd12::1", true},
+		{"fe80::1", false},
+	}
+	for _, tt := range tests {
+		if got := a.Allows(net.ParseIP(tt.ip)); got != tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got %v", tt.ip, tt.allowed, got)
+		}
+	}
+
+	if a, err := ParseAllowlist(nil); err != nil || a != nil || !a.Allows(net.ParseIP("8.8.8.8")) {
+		t.Errorf("Expected no entries to allow everyone, got %v, %v", a, err)
+	}
+	for _, bad := range []string{"10.0.0.0/33", "example.com", ""} {
+		if _, err := ParseAllowlist([]string{bad}); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies := mustAllowlist(t, "10.0.0.0/8")
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		trusted   *Allowlist
+		expected  string
+	}{
+		{"no proxy", "203.0.113.5:4000", "", proxies, "203.0.113.5"},
+		{"header ignored without trusted proxies", "10.0.0.2:4000", "198.51.100.1", nil, "10.0.0.2"},
+		{"header ignored from untrusted peer", "203.0.113.5:4000", "198.51.100.1", proxies, "203.0.113.5"},
+		{"trusted proxy", "10.0.0.2:4000", "198.51.100.1", proxies, "198.51.100.1"},
+		{"spoofed hop skipped", "10.0.0.2:4000", "1.2.3.4, 198.51.100.1, 10.0.0.3", proxies, "198.51.100.1"},
+		{"garbage hop", "10.0.0.2:4000", "nonsense", proxies, "10.0.0.2"},
+		{"ipv6 peer", "[::1]:4000", "", proxies, "::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/health", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := clientIP(r, tt.trusted); !got.Equal(net.ParseIP(tt.expected)) {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// waitForDenied polls until the source counted a denied client
+func waitForDenied(t *testing.T, source interface{ Stats() models.SourceStats }) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for source.Stats().Denied == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the client to be denied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSyslogReceiver_Allowlist(t *testing.T) {
+	for _, protocol := range []string{"udp", "tcp"} {
+		t.Run(protocol+" allowed", func(t *testing.T) {
+			receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", protocol, SyslogReceiverOptions{
+				Allowlist: mustAllowlist(t, "127.0.0.0/8"),
+			})
+			out := make(chan *models.LogEntry, 10)
+			if err := receiver.Start(context.Background(), out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			conn, err := net.Dial(protocol, receiver.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if _, err := conn.Write([]byte("<13>Oct 11 22:14:15 host app: hello\n")); err != nil {
+				t.Fatal(err)
+			}
+
+			receiveEntry(t, out)
+			if denied := receiver.Stats().Denied; denied != 0 {
+				t.Errorf("Expected nothing denied, got %d", denied)
+			}
+		})
+
+		t.Run(protocol+" denied", func(t *testing.T) {
+			receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", protocol, SyslogReceiverOptions{
+				Allowlist: mustAllowlist(t, "10.0.0.0/8"),
+			})
+			out := make(chan *models.LogEntry, 10)
+			if err := receiver.Start(context.Background(), out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			conn, err := net.Dial(protocol, receiver.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.Write([]byte("<13>Oct 11 22:14:15 host app: hello\n"))
+
+			waitForDenied(t, receiver)
+			select {
+			case entry := <-out:
+				t.Errorf("Expected nothing received, got %q", entry.Message)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestHTTPReceiver_Allowlist(t *testing.T) {
+	tests := []struct {
+		name      string
+		allow     string
+		forwarded string
+		expected  int
+	}{
+		{"allowed", "127.0.0.0/8", "", http.StatusAccepted},
+		{"denied", "10.0.0.0/8", "", http.StatusForbidden},
+		{"forwarded client allowed", "203.0.113.0/24", "203.0.113.9", http.StatusAccepted},
+		{"forwarded client denied", "203.0.113.0/24", "198.51.100.1", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{
+				Allowlist:      mustAllowlist(t, tt.allow),
+				TrustedProxies: mustAllowlist(t, "127.0.0.1"),
+			})
+			out := make(chan *models.LogEntry, 10)
+			if err := receiver.Start(context.Background(), out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			var resp *http.Response
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/logs", strings.NewReader(`{"message": "hello"}`))
+				req.Header.Set("Content-Type", "application/json")
+				if tt.forwarded != "" {
+					req.Header.Set("X-Forwarded-For", tt.forwarded)
+				}
+				var err error
+				if resp, err = http.DefaultClient.Do(req); err == nil {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal(err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, resp.StatusCode)
+			}
+			if denied := receiver.Stats().Denied; (denied == 1) != (tt.expected == http.StatusForbidden) {
+				t.Errorf("Expected the denial counted, got %d", denied)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package sources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// cefMarker starts a CEF payload inside a syslog message
+const cefMarker = "CEF:"
+
+// cefMessage holds the parts of a CEF (Common Event Format) event:
+// CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+type cefMessage struct {
+	Version       string
+	DeviceVendor  string
+	DeviceProduct string
+	DeviceVersion string
+	SignatureID   string
+	Name          string
+	Severity      string
+	Extension     map[string]string
+}
+
+// findCEF locates a CEF payload in a syslog message body. The marker must
+// start the body or follow a space, as it does after an RFC 3164 header.
+func findCEF(s string) (string, bool) {
+	for offset := 0; ; {
+		idx := strings.Index(s[offset:], cefMarker)
+		if idx < 0 {
+			return "", false
+		}
+		idx += offset
+		if idx == 0 || s[idx-1] == ' ' {
+			return s[idx:], true
+		}
+		offset = idx + len(cefMarker)
+	}
+}
+
+// parseCEF parses a CEF event starting at the "CEF:" marker
+func parseCEF(s string) (*cefMessage, error) {
+	if !strings.HasPrefix(s, cefMarker) {
+		return nil, fmt.Errorf("missing %q marker", cefMarker)
+	}
+	rest := s[len(cefMarker):]
+
+	// Seven pipe-terminated header fields; pipes and backslashes are escaped
+	var header [7]string
+	for i := range header {
+		field, remaining, ok := cutCEFHeaderField(rest)
+		if !ok {
+			return nil, fmt.Errorf("truncated CEF header")
+		}
+		header[i] = field
+		rest = remaining
+	}
+
+	msg := &cefMessage{
+		Version:       strings.TrimSpace(header[0]),
+		DeviceVendor:  header[1],
+		DeviceProduct: header[2],
+		DeviceVersion: header[3],
+		SignatureID:   header[4],
+		Name:          header[5],
+		Severity:      strings.TrimSpace(header[6]),
+		Extension:     parseCEFExtension(rest),
+	}
+
+	return msg, nil
+}
+
+// cutCEFHeaderField returns the unescaped header field before the next
+// unescaped pipe and the text after it
+func cutCEFHeaderField(s string) (string, string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			b.WriteByte(s[i+1])
+			i++
+		case c == '|':
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
+
+// parseCEFExtension parses the space-separated key=value extension. Values
+// may contain spaces, so a value runs until the next " key=" pair. Equals
+// signs and backslashes in values are escaped, and \n and \r are newlines.
+func parseCEFExtension(s string) map[string]string {
+	ext := make(map[string]string)
+
+	// Find the key start and equals sign of every pair
+	type pair struct{ keyStart, eq int }
+	var pairs []pair
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=':
+			keyStart := strings.LastIndexByte(s[:i], ' ') + 1
+			if len(pairs) > 0 && keyStart <= pairs[len(pairs)-1].eq {
+				// No space since the last pair, so this belongs to its value
+				continue
+			}
+			if isCEFKey(s[keyStart:i]) {
+				pairs = append(pairs, pair{keyStart, i})
+			}
+		}
+	}
+
+	for i, p := range pairs {
+		end := len(s)
+		if i+1 < len(pairs) {
+			end = pairs[i+1].keyStart
+		}
+		key := s[p.keyStart:p.eq]
+		ext[key] = unescapeCEFValue(strings.TrimRight(s[p.eq+1:end], " "))
+	}
+
+	return ext
+}
+
+// isCEFKey reports whether s is a valid extension key
+func isCEFKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '_' || c == '.' || c == '-' || c == '[' || c == ']') {
+			return false
+		}
+	}
+	return true
+}
+
+// unescapeCEFValue decodes the escapes allowed in extension values
+func unescapeCEFValue(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case '=', '\\', '|':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// cefSeverityLevel maps a CEF severity, either 0-10 or one of the named
+// levels, to a LogLevel
+func cefSeverityLevel(severity string) (models.LogLevel, bool) {
+	if n, err := strconv.Atoi(severity); err == nil {
+		switch {
+		case n < 0 || n > 10:
+			return "", false
+		case n <= 3:
+			return models.LevelInfo, true
+		case n <= 6:
+			return models.LevelWarning, true
+		case n <= 8:
+			return models.LevelError, true
+		default:
+			return models.LevelCritical, true
+		}
+	}
+
+	switch strings.ToLower(severity) {
+	case "low":
+		return models.LevelInfo, true
+	case "medium":
+		return models.LevelWarning, true
+	case "high":
+		return models.LevelError, true
+	case "very-high":
+		return models.LevelCritical, true
+	}
+	return "", false
+}
+
+// applyCEF copies the decoded CEF parts into the entry. The event name
+// becomes the message and extension pairs become fields. It reports whether
+// the CEF severity set the level.
+func applyCEF(entry *models.LogEntry, msg *cefMessage) bool {
+	for key, value := range msg.Extension {
+		entry.Fields[key] = value
+	}
+
+	entry.Message = msg.Name
+	entry.Fields["cef_version"] = msg.Version
+	entry.Fields["vendor"] = msg.DeviceVendor
+	entry.Fields["product"] = msg.DeviceProduct
+	entry.Fields["product_version"] = msg.DeviceVersion
+	entry.Fields["signatureId"] = msg.SignatureID
+	entry.Fields["name"] = msg.Name
+	entry.Fields["cef_severity"] = msg.Severity
+
+	level, ok := cefSeverityLevel(msg.Severity)
+	if ok {
+		entry.Level = level
+	}
+	return ok
+}
@@ -0,0 +1,145 @@
+package sources
+
+import (
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestParseCEF(t *testing.T) {
+	input := `CEF:0|Palo Alto Networks|PAN-OS|10.1.0|THREAT\|spyware|Suspicious DNS Query|8|` +
+		`src=10.0.0.5 dst=203.0.113.9 spt=51514 dpt=53 proto=UDP act=blocked ` +
+		`msg=query for evil.example matched rule a\=b cs1Label=Rule cs1=Block C:\\temp\\dns ` +
+		`request=http://example.com/?q\=1 cs2=line1\nline2`
+
+	msg, err := parseCEF(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	header := map[string][2]string{
+		"version":        {"0", msg.Version},
+		"device vendor":  {"Palo Alto Networks", msg.DeviceVendor},
+		"device product": {"PAN-OS", msg.DeviceProduct},
+		"device version": {"10.1.0", msg.DeviceVersion},
+		"signature id":   {"THREAT|spyware", msg.SignatureID},
+		"name":           {"Suspicious DNS Query", msg.Name},
+		"severity":       {"8", msg.Severity},
+	}
+	for name, v := range header {
+		if v[0] != v[1] {
+			t.Errorf("Expected %s %q, got %q", name, v[0], v[1])
+		}
+	}
+
+	expected := map[string]string{
+		"src":      "10.0.0.5",
+		"dst":      "203.0.113.9",
+		"spt":      "51514",
+		"dpt":      "53",
+		"proto":    "UDP",
+		"act":      "blocked",
+		"msg":      "query for evil.example matched rule a=b",
+		"cs1Label": "Rule",
+		"cs1":      `Block C:\temp\dns`,
+		"request":  "http://example.com/?q=1",
+		"cs2":      "line1\nline2",
+	}
+	if len(msg.Extension) != len(expected) {
+		t.Errorf("Expected %d extension pairs, got %d: %v", len(expected), len(msg.Extension), msg.Extension)
+	}
+	for key, value := range expected {
+		if got := msg.Extension[key]; got != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, got)
+		}
+	}
+}
+
+func TestParseCEF_Invalid(t *testing.T) {
+	tests := []string{
+		"not cef",
+		"CEF:0|vendor|product|1.0|100|name",
+		`CEF:0|vendor|product|1.0|100|name\|8|`,
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseCEF(input); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		})
+	}
+}
+
+func TestCEFSeverityLevel(t *testing.T) {
+	tests := []struct {
+		severity string
+		expected models.LogLevel
+		ok       bool
+	}{
+		{"0", models.LevelInfo, true},
+		{"3", models.LevelInfo, true},
+		{"5", models.LevelWarning, true},
+		{"7", models.LevelError, true},
+		{"10", models.LevelCritical, true},
+		{"Very-High", models.LevelCritical, true},
+		{"medium", models.LevelWarning, true},
+		{"11", "", false},
+		{"Unknown", "", false},
+	}
+
+	for _, tt := range tests {
+		level, ok := cefSeverityLevel(tt.severity)
+		if ok != tt.ok || level != tt.expected {
+			t.Errorf("%q: expected (%s, %v), got (%s, %v)", tt.severity, tt.expected, tt.ok, level, ok)
+		}
+	}
+}
+
+func TestSyslogReceiver_ParseCEFEntry(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	raw := `<134>Oct 11 22:14:15 fw01 CEF:0|Fortinet|FortiGate|7.2|0000000013|traffic denied|9|src=192.168.1.20 dst=8.8.8.8 act=deny reason=policy\=block`
+	entry := receiver.parseSyslogMessage([]byte(raw))
+
+	if entry.Message != "traffic denied" {
+		t.Errorf("Expected CEF name as message, got %q", entry.Message)
+	}
+	// Priority 134 is local0.info, but CEF severity 9 wins
+	if entry.Level != models.LevelCritical {
+		t.Errorf("Expected CRITICAL level, got %s", entry.Level)
+	}
+
+	fields := map[string]string{
+		"vendor":      "Fortinet",
+		"product":     "FortiGate",
+		"signatureId": "0000000013",
+		"name":        "traffic denied",
+		"src":         "192.168.1.20",
+		"act":         "deny",
+		"reason":      "policy=block",
+		"facility":    "local0",
+	}
+	for key, value := range fields {
+		if entry.Fields[key] != value {
+			t.Errorf("Expected field %s=%q, got %v", key, value, entry.Fields[key])
+		}
+	}
+	if entry.Fields[FieldRawTimestamp] != "Oct 11 22:14:15" {
+		t.Errorf("Expected syslog timestamp to still be extracted, got %v", entry.Fields[FieldRawTimestamp])
+	}
+}
+
+func TestSyslogReceiver_NonCEFMarker(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	raw := "<14>Oct 11 22:14:15 host app: XCEF:0|not|really"
+	entry := receiver.parseSyslogMessage([]byte(raw))
+
+	if entry.Message != raw {
+		t.Errorf("Expected raw message, got %q", entry.Message)
+	}
+	if _, ok := entry.Fields["vendor"]; ok {
+		t.Error("Expected no CEF fields")
+	}
+}
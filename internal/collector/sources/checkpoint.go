@@ -0,0 +1,146 @@
+package sources
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxPendingAcks caps the lines waiting to be acked. A line that is never
+// acked, its write having failed, would otherwise hold the offset back and
+// keep every later mark in memory.
+const maxPendingAcks = 100000
+
+// staleAck is how long a line waits to be acked before the cap may move
+// the offset past it. Lines are only acked once their sinks flush, so a
+// busy file can have more than maxPendingAcks in flight that are all fine.
+const staleAck = time.Minute
+
+// checkpoint tracks which lines of a file have been acked and persists the
+// offset below which all of them were. Lines are acked out of order, so the
+// offset only moves past a line once everything before it is acked too, or
+// once more than maxPendingAcks lines wait behind it and it went unacked
+// for staleAck.
+type checkpoint struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	file       *os.File
+	generation uint64     // bumped when the file is rotated or truncated
+	pending    []*ackMark // in read order
+	committed  int64
+	skipped    int64 // unacked lines the offset was moved past
+}
+
+// ackMark is a line waiting to be acked, identified by the offset after it
+type ackMark struct {
+	end     int64
+	tracked time.Time
+	acked   bool
+}
+
+// openCheckpoint opens or creates the checkpoint file and loads the offset
+// saved in it, zero for a new file
+func openCheckpoint(path string) (*checkpoint, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	var buf [8]byte
+	if _, err := file.ReadAt(buf[:], 0); err != nil && !errors.Is(err, io.EOF) {
+		file.Close()
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	return &checkpoint{
+		now:       time.Now,
+		file:      file,
+		committed: int64(binary.BigEndian.Uint64(buf[:])),
+	}, nil
+}
+
+// track records a line ending at end and returns the func that acks it
+func (c *checkpoint) track(end int64) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mark := &ackMark{end: end, tracked: c.now()}
+	c.pending = append(c.pending, mark)
+	if len(c.pending) > maxPendingAcks && mark.tracked.Sub(c.pending[0].tracked) >= staleAck {
+		c.giveUp()
+	}
+	generation := c.generation
+	return func() { c.ack(mark, generation) }
+}
+
+// giveUp moves the offset past the oldest pending line as if it was acked,
+// and over the acked lines after it. That line isn't read again on restart.
+func (c *checkpoint) giveUp() {
+	if c.skipped == 0 {
+		fmt.Printf("⚠️  %d lines wait on an unacked one, moving the checkpoint past it\n", len(c.pending)-1)
+	}
+	c.skipped++
+	c.pending[0].acked = true
+	c.advance()
+}
+
+// ack marks a line as handled and advances the offset over every line at
+// the front that is now acked. Acks for a previous file are ignored.
+func (c *checkpoint) ack(mark *ackMark, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	mark.acked = true
+	c.advance()
+}
+
+// advance moves the offset over every line at the front that is acked
+func (c *checkpoint) advance() {
+	advanced := false
+	for len(c.pending) > 0 && c.pending[0].acked {
+		c.committed = c.pending[0].end
+		c.pending[0] = nil
+		c.pending = c.pending[1:]
+		advanced = true
+	}
+	if advanced {
+		c.save()
+	}
+}
+
+// reset starts over at the beginning of a rotated or truncated file. Lines
+// of the old file still in flight no longer hold the offset back.
+func (c *checkpoint) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.pending = nil
+	c.committed = 0
+	c.skipped = 0
+	c.save()
+}
+
+// offset returns the offset every line before has been acked up to
+func (c *checkpoint) offset() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.committed
+}
+
+// save persists the offset. The 8-byte record is rewritten in place, which
+// a process crash can't tear.
+func (c *checkpoint) save() {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(c.committed))
+	if _, err := c.file.WriteAt(buf[:], 0); err != nil {
+		fmt.Printf("Error saving checkpoint: %v\n", err)
+	}
+}
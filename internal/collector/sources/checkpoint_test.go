@@ -0,0 +1,99 @@
+package sources
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint_Watermark(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.ckpt")
+	cp, err := openCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.offset() != 0 {
+		t.Errorf("Expected a new checkpoint at 0, got %d", cp.offset())
+	}
+
+	first, second, third := cp.track(10), cp.track(25), cp.track(40)
+
+	// An ack after a gap doesn't move the offset until the gap is acked
+	third()
+	if cp.offset() != 0 {
+		t.Errorf("Expected 0 while earlier lines are pending, got %d", cp.offset())
+	}
+	first()
+	if cp.offset() != 10 {
+		t.Errorf("Expected 10, got %d", cp.offset())
+	}
+	second()
+	if cp.offset() != 40 {
+		t.Errorf("Expected 40, got %d", cp.offset())
+	}
+
+	reopened, err := openCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.offset() != 40 {
+		t.Errorf("Expected the saved offset 40, got %d", reopened.offset())
+	}
+}
+
+func TestCheckpoint_ResetIgnoresOldAcks(t *testing.T) {
+	cp, err := openCheckpoint(filepath.Join(t.TempDir(), "app.ckpt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := cp.track(100)
+	cp.reset()
+	current := cp.track(5)
+
+	old()
+	if cp.offset() != 0 {
+		t.Errorf("Expected an ack from before the reset to be ignored, got %d", cp.offset())
+	}
+	current()
+	if cp.offset() != 5 {
+		t.Errorf("Expected 5, got %d", cp.offset())
+	}
+}
+
+func TestCheckpoint_CapsPending(t *testing.T) {
+	cp, err := openCheckpoint(filepath.Join(t.TempDir(), "app.ckpt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	cp.now = func() time.Time { return now }
+
+	// The first line is never acked, the rest are
+	cp.track(1)
+	for i := 2; i <= maxPendingAcks; i++ {
+		cp.track(int64(i))()
+	}
+	if cp.offset() != 0 {
+		t.Errorf("Expected 0 while the first line is pending, got %d", cp.offset())
+	}
+
+	// Past the cap, a line that may still be waiting for its flush holds
+	// the offset back
+	cp.track(maxPendingAcks + 1)()
+	if cp.offset() != 0 {
+		t.Errorf("Expected 0 while the first line is recent, got %d", cp.offset())
+	}
+
+	// Once it went unacked for long, the offset moves past it
+	now = now.Add(staleAck)
+	last := cp.track(maxPendingAcks + 2)
+	if cp.offset() != maxPendingAcks+1 {
+		t.Errorf("Expected %d, got %d", maxPendingAcks+1, cp.offset())
+	}
+	last()
+	if cp.offset() != maxPendingAcks+2 || len(cp.pending) != 0 {
+		t.Errorf("Expected every line committed, got %d with %d pending", cp.offset(), len(cp.pending))
+	}
+}
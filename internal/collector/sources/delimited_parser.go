@@ -0,0 +1,70 @@
+package sources
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// splitDelimited splits a line into its values. CSV follows RFC 4180
+// quoting, TSV splits on every tab.
+func (fr *FileReader) splitDelimited(line string) ([]string, error) {
+	line = strings.TrimRight(line, "\r\n")
+
+	if fr.format == FormatTSV {
+		return strings.Split(line, "\t"), nil
+	}
+
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.Comma = fr.delimiter
+	reader.FieldsPerRecord = -1
+	return reader.Read()
+}
+
+// parseDelimitedLine maps a line's values onto the entry by column name.
+// Values past the last column are kept as column_<n>, counting from 1.
+// Returns false for a blank or badly quoted line.
+func (fr *FileReader) parseDelimitedLine(line string) (*models.LogEntry, bool) {
+	trimmed := strings.TrimRight(line, "\r\n")
+	if trimmed == "" {
+		return nil, false
+	}
+
+	values, err := fr.splitDelimited(trimmed)
+	if err != nil {
+		return nil, false
+	}
+
+	entry := models.NewLogEntry()
+	entry.Source = fr.filepath
+	hasMessage := false
+
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+
+		name := fmt.Sprintf("column_%d", i+1)
+		if i < len(fr.columns) {
+			name = fr.columns[i]
+			if name == "" {
+				continue
+			}
+		}
+		if fr.setNamedValue(entry, name, value) {
+			hasMessage = true
+		}
+	}
+
+	// Without a message column the whole line is the message
+	if !hasMessage {
+		entry.Message = trimmed
+	}
+	if _, ok := entry.Fields[FieldRawTimestamp]; !ok {
+		markIngestTime(entry)
+	}
+
+	return entry, true
+}
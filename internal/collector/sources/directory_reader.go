@@ -0,0 +1,284 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// DirectoryReader tails every file matching a glob pattern, picking up new
+// matches as they appear
+type DirectoryReader struct {
+	pattern      string
+	format       string
+	parser       Parser
+	keepRaw      bool
+	levels       models.LevelRules
+	deadLetter   DeadLetter
+	labels       SourceLabels
+	parsePool    bool
+	poll         PollOptions
+	scanInterval time.Duration
+
+	mu      sync.Mutex
+	readers map[string]*watchedFile
+	running bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	// Totals from readers that have been stopped
+	retired models.SourceStats
+}
+
+// watchedFile is a FileReader with the means to stop it
+type watchedFile struct {
+	reader *FileReader
+	cancel context.CancelFunc
+}
+
+// NewDirectoryReader creates a reader for all files matching pattern,
+// e.g. /var/log/myapp/*.log
+func NewDirectoryReader(pattern string) *DirectoryReader {
+	return NewDirectoryReaderWithFormat(pattern, FormatRaw)
+}
+
+// NewDirectoryReaderWithFormat creates a directory reader whose files are
+// parsed in the given format ("raw", "json" or "logfmt")
+func NewDirectoryReaderWithFormat(pattern string, format string) *DirectoryReader {
+	return &DirectoryReader{
+		pattern:      pattern,
+		format:       strings.ToLower(format),
+		scanInterval: 2 * time.Second,
+		readers:      make(map[string]*watchedFile),
+	}
+}
+
+// SetParser parses the lines of every file with p instead of the reader's
+// format. It must be called before Start.
+func (dr *DirectoryReader) SetParser(p Parser) {
+	dr.parser = p
+}
+
+// SetKeepRaw keeps each line of every file exactly as read in
+// Fields["_raw"]. It must be called before Start.
+func (dr *DirectoryReader) SetKeepRaw(keep bool) {
+	dr.keepRaw = keep
+}
+
+// SetLevelRules guesses the level of lines in every file the format leaves
+// at INFO. It must be called before Start.
+func (dr *DirectoryReader) SetLevelRules(rules models.LevelRules) {
+	dr.levels = rules
+}
+
+// SetDeadLetter sends lines of every file the format or parser fails on to
+// dl. It must be called before Start.
+func (dr *DirectoryReader) SetDeadLetter(dl DeadLetter) {
+	dr.deadLetter = dl
+}
+
+// SetLabels names the source and labels every entry from every file, so
+// they share a name instead of each carrying its path. It must be called
+// before Start.
+func (dr *DirectoryReader) SetLabels(labels SourceLabels) {
+	dr.labels = labels
+}
+
+// SetParsePool leaves parsing the lines of every file to the pipeline's
+// parse pool. The reader must use the raw format. It must be called before
+// Start.
+func (dr *DirectoryReader) SetParsePool(pool bool) error {
+	if pool && (dr.format != FormatRaw || dr.parser != nil) {
+		return fmt.Errorf("lines in %s format are already parsed", dr.format)
+	}
+	dr.parsePool = pool
+	return nil
+}
+
+// SetPolling changes how often every file is checked for new data. It
+// must be called before Start.
+func (dr *DirectoryReader) SetPolling(opts PollOptions) {
+	dr.poll = opts
+}
+
+// Start starts readers for the current matches and watches for new ones
+func (dr *DirectoryReader) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	// Glob only reports malformed patterns
+	if _, err := filepath.Glob(dr.pattern); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", dr.pattern, err)
+	}
+	switch dr.format {
+	case FormatRaw, FormatJSON, FormatLogfmt:
+	default:
+		return fmt.Errorf("unsupported format: %s", dr.format)
+	}
+
+	dr.mu.Lock()
+	if dr.running {
+		dr.mu.Unlock()
+		return fmt.Errorf("directory reader already running")
+	}
+	dr.running = true
+	ctx, dr.cancel = context.WithCancel(ctx)
+	dr.mu.Unlock()
+
+	if err := dr.scan(ctx, out); err != nil {
+		dr.Stop()
+		return err
+	}
+
+	dr.wg.Add(1)
+	go dr.scanLoop(ctx, out)
+
+	return nil
+}
+
+// scanLoop rescans the pattern until ctx is cancelled
+func (dr *DirectoryReader) scanLoop(ctx context.Context, out chan<- *models.LogEntry) {
+	defer dr.wg.Done()
+
+	ticker := time.NewTicker(dr.scanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := dr.scan(ctx, out); err != nil {
+				fmt.Printf("Error scanning %s: %v\n", dr.pattern, err)
+			}
+		}
+	}
+}
+
+// scan starts readers for new matches and stops readers whose file is gone
+func (dr *DirectoryReader) scan(ctx context.Context, out chan<- *models.LogEntry) error {
+	matches, err := filepath.Glob(dr.pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", dr.pattern, err)
+	}
+
+	current := make(map[string]bool, len(matches))
+	for _, path := range matches {
+		current[path] = true
+	}
+
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	if !dr.running {
+		return nil
+	}
+
+	// Files deleted since the last scan
+	for path, watched := range dr.readers {
+		if !current[path] {
+			dr.retire(path, watched)
+		}
+	}
+
+	for _, path := range matches {
+		if _, ok := dr.readers[path]; ok {
+			continue
+		}
+
+		fileCtx, cancel := context.WithCancel(ctx)
+		reader := NewFileReaderWithFormat(path, dr.format)
+		if dr.parser != nil {
+			reader.SetParser(dr.parser)
+		}
+		reader.SetKeepRaw(dr.keepRaw)
+		reader.SetLevelRules(dr.levels)
+		reader.SetDeadLetter(dr.deadLetter)
+		reader.SetLabels(dr.labels)
+		reader.SetParsePool(dr.parsePool)
+		reader.SetPolling(dr.poll)
+		if err := reader.Start(fileCtx, out); err != nil {
+			cancel()
+			// The file may have vanished between Glob and Start, retry next scan
+			fmt.Printf("Error starting reader for %s: %v\n", path, err)
+			continue
+		}
+		dr.readers[path] = &watchedFile{reader: reader, cancel: cancel}
+	}
+
+	return nil
+}
+
+// retire stops a file's reader, keeping its stats. Must hold dr.mu.
+func (dr *DirectoryReader) retire(path string, watched *watchedFile) {
+	watched.stop()
+	delete(dr.readers, path)
+	dr.retired = addStats(dr.retired, watched.reader.Stats())
+}
+
+// stop cancels the reader and waits for it to finish
+func (wf *watchedFile) stop() {
+	wf.cancel()
+	<-wf.reader.Done()
+}
+
+// Files returns the paths currently being read
+func (dr *DirectoryReader) Files() []string {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	paths := make([]string, 0, len(dr.readers))
+	for path := range dr.readers {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// Stats returns the combined activity of every file read so far
+func (dr *DirectoryReader) Stats() models.SourceStats {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	stats := dr.retired
+	for _, watched := range dr.readers {
+		stats = addStats(stats, watched.reader.Stats())
+	}
+	return stats
+}
+
+// addStats sums two snapshots, keeping the latest activity
+func addStats(a, b models.SourceStats) models.SourceStats {
+	a.EntriesProduced += b.EntriesProduced
+	a.BytesRead += b.BytesRead
+	a.Errors += b.Errors
+	if b.LastActivity.After(a.LastActivity) {
+		a.LastActivity = b.LastActivity
+	}
+	return a
+}
+
+// Stop stops watching and stops every file reader
+func (dr *DirectoryReader) Stop() error {
+	dr.mu.Lock()
+	if !dr.running {
+		dr.mu.Unlock()
+		return nil
+	}
+	dr.running = false
+	dr.cancel()
+
+	for path, watched := range dr.readers {
+		dr.retire(path, watched)
+	}
+	dr.mu.Unlock()
+
+	dr.wg.Wait()
+	return nil
+}
+
+// Name returns the source name
+func (dr *DirectoryReader) Name() string {
+	return fmt.Sprintf("dir:%s", dr.pattern)
+}
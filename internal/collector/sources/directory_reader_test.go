@@ -0,0 +1,147 @@
+package sources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestDirectoryReader_PicksUpNewFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.log")
+	second := filepath.Join(dir, "b.log")
+	if err := os.WriteFile(first, []byte("from a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("from b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Doesn't match the pattern
+	if err := os.WriteFile(filepath.Join(dir, "c.txt"), []byte("ignored\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewDirectoryReader(filepath.Join(dir, "*.log"))
+	reader.scanInterval = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	sources := make(map[string]string)
+	timeout := time.After(2 * time.Second)
+	for len(sources) < 2 {
+		select {
+		case entry := <-out:
+			sources[entry.Source] = entry.Message
+		case <-timeout:
+			t.Fatalf("timeout waiting for initial files, got %v", sources)
+		}
+	}
+	if sources[first] != "from a\n" || sources[second] != "from b\n" {
+		t.Errorf("Expected entries tagged with their file path, got %v", sources)
+	}
+
+	// A file created after Start is picked up by the rescan
+	third := filepath.Join(dir, "c.log")
+	if err := os.WriteFile(third, []byte("from c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case entry := <-out:
+		if entry.Source != third || entry.Message != "from c\n" {
+			t.Errorf("Expected line from %s, got %q from %s", third, entry.Message, entry.Source)
+		}
+	case <-timeout:
+		t.Fatal("timeout waiting for the new file")
+	}
+}
+
+func TestDirectoryReader_StopsReaderForDeletedFile(t *testing.T) {
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "keep.log")
+	gone := filepath.Join(dir, "gone.log")
+	for _, path := range []string{keep, gone} {
+		if err := os.WriteFile(path, []byte("line\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reader := NewDirectoryReader(filepath.Join(dir, "*.log"))
+	reader.scanInterval = 50 * time.Millisecond
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	if files := reader.Files(); len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %v", files)
+	}
+
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		files := reader.Files()
+		sort.Strings(files)
+		if len(files) == 1 && files[0] == keep {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("Expected reader for deleted file to be stopped, still reading %v", reader.Files())
+}
+
+func TestDirectoryReader_Stop(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.log"), []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewDirectoryReader(filepath.Join(dir, "*.log"))
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		reader.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return")
+	}
+	if files := reader.Files(); len(files) != 0 {
+		t.Errorf("Expected no readers after stop, got %v", files)
+	}
+}
+
+func TestDirectoryReader_InvalidConfig(t *testing.T) {
+	out := make(chan *models.LogEntry, 1)
+
+	if err := NewDirectoryReader("[").Start(context.Background(), out); err == nil {
+		t.Error("Expected error for malformed pattern")
+	}
+	if err := NewDirectoryReaderWithFormat("*.log", "xml").Start(context.Background(), out); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
@@ -0,0 +1,225 @@
+//go:build !no_source_docker
+
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDockerHost is the Docker daemon's local socket
+const DefaultDockerHost = "unix:///var/run/docker.sock"
+
+// dockerRequestTimeout bounds API calls other than streaming logs
+const dockerRequestTimeout = 10 * time.Second
+
+// DockerContainer describes a container as the DockerReader needs it
+type DockerContainer struct {
+	ID      string
+	Name    string
+	Image   string
+	Running bool
+
+	// TTY is only set by InspectContainer. A container with a TTY writes
+	// its logs as a raw stream rather than a multiplexed one.
+	TTY bool
+}
+
+// DockerFilter selects containers by label ("key" or "key=value") and by
+// name. Each name matches containers whose name contains it.
+type DockerFilter struct {
+	Labels []string
+	Names  []string
+}
+
+// DockerClient is the part of the Docker Engine API the DockerReader uses
+type DockerClient interface {
+	// ListContainers returns the containers matching filter, stopped ones
+	// included
+	ListContainers(ctx context.Context, filter DockerFilter) ([]DockerContainer, error)
+
+	// InspectContainer returns a single container
+	InspectContainer(ctx context.Context, id string) (DockerContainer, error)
+
+	// ContainerLogs follows a container's stdout and stderr, each line
+	// prefixed with its timestamp, starting at since. The stream ends when
+	// the container stops.
+	ContainerLogs(ctx context.Context, id string, since time.Time) (io.ReadCloser, error)
+}
+
+// dockerHTTPClient talks to the Docker Engine API over a unix socket or TCP
+type dockerHTTPClient struct {
+	base   string
+	client *http.Client
+}
+
+// NewDockerClient creates a client for the daemon at host, such as
+// unix:///var/run/docker.sock or tcp://10.0.0.5:2375
+func NewDockerClient(host string) (DockerClient, error) {
+	if host == "" {
+		host = DefaultDockerHost
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		// The host is ignored when dialing the socket
+		return &dockerHTTPClient{base: "http://docker", client: &http.Client{Transport: transport}}, nil
+	case "tcp", "http":
+		return &dockerHTTPClient{base: "http://" + u.Host, client: &http.Client{}}, nil
+	case "https":
+		return &dockerHTTPClient{base: "https://" + u.Host, client: &http.Client{}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported docker host %q (expected unix://, tcp:// or http(s)://)", host)
+	}
+}
+
+// ListContainers lists the containers matching filter
+func (c *dockerHTTPClient) ListContainers(ctx context.Context, filter DockerFilter) ([]DockerContainer, error) {
+	query := url.Values{"all": {"1"}}
+	filters := map[string][]string{}
+	if len(filter.Labels) > 0 {
+		filters["label"] = filter.Labels
+	}
+	if len(filter.Names) > 0 {
+		filters["name"] = filter.Names
+	}
+	if len(filters) > 0 {
+		data, err := json.Marshal(filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode filters: %w", err)
+		}
+		query.Set("filters", string(data))
+	}
+
+	var listed []struct {
+		ID    string   `json:"Id"`
+		Names []string `json:"Names"`
+		Image string   `json:"Image"`
+		State string   `json:"State"`
+	}
+	if err := c.getJSON(ctx, "/containers/json?"+query.Encode(), &listed); err != nil {
+		return nil, err
+	}
+
+	containers := make([]DockerContainer, 0, len(listed))
+	for _, l := range listed {
+		container := DockerContainer{ID: l.ID, Image: l.Image, Running: l.State == "running"}
+		if len(l.Names) > 0 {
+			container.Name = strings.TrimPrefix(l.Names[0], "/")
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// InspectContainer returns a single container
+func (c *dockerHTTPClient) InspectContainer(ctx context.Context, id string) (DockerContainer, error) {
+	var inspected struct {
+		ID     string `json:"Id"`
+		Name   string `json:"Name"`
+		Config struct {
+			Image string `json:"Image"`
+			Tty   bool   `json:"Tty"`
+		} `json:"Config"`
+		State struct {
+			Running bool `json:"Running"`
+		} `json:"State"`
+	}
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(id)+"/json", &inspected); err != nil {
+		return DockerContainer{}, err
+	}
+
+	return DockerContainer{
+		ID:      inspected.ID,
+		Name:    strings.TrimPrefix(inspected.Name, "/"),
+		Image:   inspected.Config.Image,
+		Running: inspected.State.Running,
+		TTY:     inspected.Config.Tty,
+	}, nil
+}
+
+// ContainerLogs follows a container's logs
+func (c *dockerHTTPClient) ContainerLogs(ctx context.Context, id string, since time.Time) (io.ReadCloser, error) {
+	query := url.Values{
+		"follow":     {"1"},
+		"stdout":     {"1"},
+		"stderr":     {"1"},
+		"timestamps": {"1"},
+	}
+	if !since.IsZero() {
+		query.Set("since", dockerTime(since))
+	}
+
+	resp, err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/logs?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// getJSON decodes the response to a short API call
+func (c *dockerHTTPClient) getJSON(ctx context.Context, path string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, dockerRequestTimeout)
+	defer cancel()
+
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode docker response: %w", err)
+	}
+	return nil
+}
+
+// get sends a request, turning error responses into errors
+func (c *dockerHTTPClient) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach docker daemon: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(body))
+		}
+		return nil, fmt.Errorf("docker API returned %s: %s", resp.Status, apiErr.Message)
+	}
+	return resp, nil
+}
+
+// dockerTime formats a time as the API's fractional unix timestamp
+func dockerTime(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10) + "." + fmt.Sprintf("%09d", t.Nanosecond())
+}
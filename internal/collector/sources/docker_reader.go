@@ -0,0 +1,405 @@
+//go:build !no_source_docker
+
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// DockerOptions configures a DockerReader
+type DockerOptions struct {
+	// Filter selects the containers to read. Empty reads every container.
+	Filter DockerFilter
+
+	// ScanInterval is how often containers are listed to pick up new ones.
+	// Defaults to 5s.
+	ScanInterval time.Duration
+
+	// KeepRaw keeps each line exactly as the daemon sent it, timestamp
+	// included, in FieldRaw
+	KeepRaw bool
+}
+
+const (
+	// maxDockerLineSize caps a line built from a container's output. Longer
+	// lines are split.
+	maxDockerLineSize = 1 << 20
+
+	// maxDockerFrameSize rejects frame headers that can't be real, which
+	// means the stream isn't multiplexed
+	maxDockerFrameSize = 16 << 20
+)
+
+// Docker stream types, the first byte of a frame header
+const (
+	dockerStdin     = 0
+	dockerStdout    = 1
+	dockerStderr    = 2
+	dockerSystemErr = 3
+)
+
+// DockerReader streams the logs of running Docker containers, picking up
+// containers that start later. Containers that stop and start again are
+// resumed after the last line read.
+type DockerReader struct {
+	client DockerClient
+	opts   DockerOptions
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	streams map[string]context.CancelFunc // container ID to its stream
+	since   map[string]time.Time          // container ID to its last line
+	started time.Time
+
+	labels SourceLabels
+	stats  sourceStats
+}
+
+// NewDockerReader creates a reader for the containers matching the options'
+// filter, talking to the daemon through client
+func NewDockerReader(client DockerClient, opts DockerOptions) *DockerReader {
+	if opts.ScanInterval <= 0 {
+		opts.ScanInterval = 5 * time.Second
+	}
+
+	return &DockerReader{
+		client:  client,
+		opts:    opts,
+		streams: make(map[string]context.CancelFunc),
+		since:   make(map[string]time.Time),
+	}
+}
+
+// SetLabels names the source, instead of the container name, and labels
+// every entry from it. It must be called before Start.
+func (r *DockerReader) SetLabels(labels SourceLabels) {
+	r.labels = labels
+}
+
+// Start streams the running containers and watches for new ones. Only
+// lines logged after Start are read.
+func (r *DockerReader) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return sourceError(models.AlreadyRunning, "docker reader already running")
+	}
+	r.running = true
+	r.started = time.Now()
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.mu.Unlock()
+
+	if err := r.scan(ctx, out); err != nil {
+		r.Stop()
+		return sourceError(models.IOError, "failed to list containers: %w", err)
+	}
+
+	r.wg.Add(1)
+	go r.scanLoop(ctx, out)
+
+	fmt.Printf("🐳 Reading Docker container logs (%s)\n", r.Name())
+	return nil
+}
+
+// scanLoop lists containers until ctx is cancelled
+func (r *DockerReader) scanLoop(ctx context.Context, out chan<- *models.LogEntry) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.opts.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.scan(ctx, out); err != nil {
+				fmt.Printf("⚠️  Failed to list containers: %v\n", err)
+				r.stats.recordError()
+			}
+		}
+	}
+}
+
+// scan starts streaming containers that are running and not streamed yet,
+// and forgets containers that were removed
+func (r *DockerReader) scan(ctx context.Context, out chan<- *models.LogEntry) error {
+	containers, err := r.client.ListContainers(ctx, r.opts.Filter)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.running {
+		return nil
+	}
+
+	listed := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		listed[container.ID] = true
+		if !container.Running {
+			continue
+		}
+		if _, ok := r.streams[container.ID]; ok {
+			continue
+		}
+
+		since, ok := r.since[container.ID]
+		if ok {
+			since = since.Add(time.Nanosecond)
+		} else {
+			since = r.started
+		}
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		r.streams[container.ID] = cancel
+		r.wg.Add(1)
+		go r.stream(streamCtx, out, container.ID, since)
+	}
+
+	for id := range r.since {
+		if !listed[id] {
+			delete(r.since, id)
+		}
+	}
+	return nil
+}
+
+// stream reads one container's logs until it stops or ctx is cancelled
+func (r *DockerReader) stream(ctx context.Context, out chan<- *models.LogEntry, id string, since time.Time) {
+	defer r.wg.Done()
+	defer func() {
+		r.mu.Lock()
+		if cancel, ok := r.streams[id]; ok {
+			cancel()
+			delete(r.streams, id)
+		}
+		r.mu.Unlock()
+	}()
+
+	err := r.follow(ctx, out, id, since)
+	if err != nil && ctx.Err() == nil {
+		fmt.Printf("⚠️  Failed to read logs of container %s: %v\n", shortContainerID(id), err)
+		r.stats.recordError()
+	}
+}
+
+// follow streams a container's logs into out
+func (r *DockerReader) follow(ctx context.Context, out chan<- *models.LogEntry, id string, since time.Time) error {
+	container, err := r.client.InspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	logs, err := r.client.ContainerLogs(ctx, id, since)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	return readDockerStream(logs, container.TTY, func(stream, line string) bool {
+		entry := dockerEntry(container, stream, line)
+		if r.opts.KeepRaw {
+			keepRaw(entry, line)
+		}
+		r.labels.apply(entry)
+		metrics.EntriesReceived.WithLabelValues(r.Name()).Inc()
+		metrics.BytesReceived.WithLabelValues(r.Name()).Add(int64(len(line)))
+		r.stats.recordBytes(len(line))
+		r.stats.recordEntry()
+
+		if _, ok := entry.Fields[FieldRawTimestamp]; ok {
+			r.mu.Lock()
+			r.since[id] = entry.Timestamp
+			r.mu.Unlock()
+		}
+
+		select {
+		case out <- entry:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// readDockerStream splits a container's log stream into lines, calling emit
+// for each until it returns false. Without a TTY the stream is
+// multiplexed: each frame starts with an 8 byte header holding the stream
+// type and the payload size, and a line may span frames.
+func readDockerStream(r io.Reader, tty bool, emit func(stream, line string) bool) error {
+	if tty {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxDockerLineSize)
+		for scanner.Scan() {
+			if !emit("stdout", strings.TrimSuffix(scanner.Text(), "\r")) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	br := bufio.NewReader(r)
+	pending := make(map[byte][]byte)
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read frame header: %w", err)
+		}
+
+		size := binary.BigEndian.Uint32(header[4:])
+		if size > maxDockerFrameSize {
+			return fmt.Errorf("invalid frame size %d", size)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return fmt.Errorf("failed to read frame: %w", err)
+		}
+
+		var stream string
+		switch header[0] {
+		case dockerStdin, dockerStdout:
+			stream = "stdout"
+		case dockerStderr:
+			stream = "stderr"
+		case dockerSystemErr:
+			return fmt.Errorf("docker daemon: %s", bytes.TrimSpace(payload))
+		default:
+			return fmt.Errorf("unknown stream type %d", header[0])
+		}
+
+		buf := append(pending[header[0]], payload...)
+		for {
+			i := bytes.IndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			if !emit(stream, string(buf[:i])) {
+				return nil
+			}
+			buf = buf[i+1:]
+		}
+		if len(buf) >= maxDockerLineSize {
+			if !emit(stream, string(buf)) {
+				return nil
+			}
+			buf = nil
+		}
+		pending[header[0]] = buf
+	}
+
+	// Output the container wrote without a final newline
+	for streamType, buf := range pending {
+		if len(buf) == 0 {
+			continue
+		}
+		stream := "stdout"
+		if streamType == dockerStderr {
+			stream = "stderr"
+		}
+		if !emit(stream, string(buf)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// dockerEntry converts a line of a container's output, prefixed with its
+// timestamp, into a log entry
+func dockerEntry(container DockerContainer, stream, line string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Source = container.Name
+	entry.Fields["container_id"] = container.ID
+	entry.Fields["container_name"] = container.Name
+	entry.Fields["image"] = container.Image
+	entry.Fields["stream"] = stream
+
+	message := line
+	if raw, rest, ok := strings.Cut(line, " "); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			setEventTime(entry, ts, raw)
+			message = rest
+		}
+	}
+	if _, ok := entry.Fields[FieldRawTimestamp]; !ok {
+		markIngestTime(entry)
+	}
+
+	entry.Message = message
+	entry.Level = detectLevel(message)
+	return entry
+}
+
+// shortContainerID returns the 12 character ID docker shows
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// Containers returns the IDs of the containers currently streamed
+func (r *DockerReader) Containers() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.streams))
+	for id := range r.streams {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Stop stops watching and stops every stream
+func (r *DockerReader) Stop() error {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running = false
+	r.cancel()
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return nil
+}
+
+// Name returns the source name
+func (r *DockerReader) Name() string {
+	var filters []string
+	for _, label := range r.opts.Filter.Labels {
+		filters = append(filters, "label="+label)
+	}
+	for _, name := range r.opts.Filter.Names {
+		filters = append(filters, "name="+name)
+	}
+	if len(filters) == 0 {
+		return "docker"
+	}
+	return "docker:" + strings.Join(filters, ",")
+}
+
+// Stats returns a snapshot of the reader's activity
+func (r *DockerReader) Stats() models.SourceStats {
+	return r.stats.snapshot()
+}
@@ -0,0 +1,277 @@
+//go:build !no_source_docker
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// dockerFrame encodes a payload as one frame of a multiplexed stream
+func dockerFrame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+// fakeDockerDaemon serves canned containers and log streams. Log streams
+// stay open until the request is cancelled, like a followed container.
+type fakeDockerDaemon struct {
+	mu         sync.Mutex
+	containers []map[string]interface{}
+	logs       map[string][]byte
+	since      map[string]string
+	filters    string
+}
+
+func newFakeDockerDaemon(t *testing.T) (*fakeDockerDaemon, DockerClient) {
+	t.Helper()
+	daemon := &fakeDockerDaemon{logs: make(map[string][]byte), since: make(map[string]string)}
+	server := httptest.NewServer(daemon)
+	t.Cleanup(server.Close)
+
+	client, err := NewDockerClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return daemon, client
+}
+
+func (d *fakeDockerDaemon) addContainer(id, name, image string, logs []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.containers = append(d.containers, map[string]interface{}{
+		"Id": id, "Names": []string{"/" + name}, "Image": image, "State": "running",
+	})
+	d.logs[id] = logs
+}
+
+func (d *fakeDockerDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/containers/json":
+		d.filters = r.URL.Query().Get("filters")
+		json.NewEncoder(w).Encode(d.containers)
+
+	case len(parts) == 3 && parts[2] == "json":
+		for _, c := range d.containers {
+			if c["Id"] == parts[1] {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"Id":     c["Id"],
+					"Name":   c["Names"].([]string)[0],
+					"Config": map[string]interface{}{"Image": c["Image"], "Tty": false},
+					"State":  map[string]interface{}{"Running": true},
+				})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such container"}`))
+
+	case len(parts) == 3 && parts[2] == "logs":
+		d.since[parts[1]] = r.URL.Query().Get("since")
+		logs := d.logs[parts[1]]
+		d.mu.Unlock()
+		w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+		w.Write(logs)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		d.mu.Lock()
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestReadDockerStream(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(dockerFrame(dockerStdout, "first line\nsecond "))
+	stream.Write(dockerFrame(dockerStderr, "an error\n"))
+	stream.Write(dockerFrame(dockerStdout, "line\n"))
+	stream.Write(dockerFrame(dockerStdout, "no newline"))
+
+	var got []string
+	err := readDockerStream(&stream, false, func(s, line string) bool {
+		got = append(got, s+": "+line)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"stdout: first line", "stderr: an error", "stdout: second line", "stdout: no newline"}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestReadDockerStream_TTY(t *testing.T) {
+	var got []string
+	err := readDockerStream(strings.NewReader("one\r\ntwo\n"), true, func(s, line string) bool {
+		got = append(got, s+": "+line)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "|") != "stdout: one|stdout: two" {
+		t.Errorf("Expected the raw stream's lines, got %q", got)
+	}
+}
+
+func TestReadDockerStream_SystemError(t *testing.T) {
+	stream := dockerFrame(dockerSystemErr, "log driver does not support reading\n")
+	err := readDockerStream(bytes.NewReader(stream), false, func(string, string) bool { return true })
+	if err == nil || !strings.Contains(err.Error(), "log driver") {
+		t.Errorf("Expected the daemon's error, got %v", err)
+	}
+}
+
+func TestDockerEntry(t *testing.T) {
+	container := DockerContainer{ID: "4f66ad9a0b2e", Name: "web", Image: "nginx:1.25"}
+	entry := dockerEntry(container, "stderr", "2023-10-11T13:55:36.123456789Z upstream error: connection refused")
+
+	if entry.Source != "web" {
+		t.Errorf("Expected the container name as source, got %q", entry.Source)
+	}
+	if entry.Message != "upstream error: connection refused" {
+		t.Errorf("Expected the timestamp stripped, got %q", entry.Message)
+	}
+	if entry.Level != models.LevelError {
+		t.Errorf("Expected ERROR, got %v", entry.Level)
+	}
+	expectedTS := time.Date(2023, 10, 11, 13, 55, 36, 123456789, time.UTC)
+	if !entry.Timestamp.Equal(expectedTS) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTS, entry.Timestamp)
+	}
+	if entry.Fields["image"] != "nginx:1.25" || entry.Fields["container_id"] != "4f66ad9a0b2e" || entry.Fields["stream"] != "stderr" {
+		t.Errorf("Expected container fields, got %v", entry.Fields)
+	}
+}
+
+func TestDockerReader_StreamsContainers(t *testing.T) {
+	daemon, client := newFakeDockerDaemon(t)
+	var logs bytes.Buffer
+	logs.Write(dockerFrame(dockerStdout, "2023-10-11T13:55:36.000000001Z GET /health 200\n"))
+	logs.Write(dockerFrame(dockerStderr, "2023-10-11T13:55:37.000000002Z warning: slow upstream\n"))
+	daemon.addContainer("aaa111", "web", "nginx:1.25", logs.Bytes())
+
+	reader := NewDockerReader(client, DockerOptions{
+		Filter:       DockerFilter{Labels: []string{"logflux=true"}},
+		ScanInterval: 20 * time.Millisecond,
+	})
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	first := receiveEntry(t, out)
+	if first.Message != "GET /health 200" || first.Source != "web" {
+		t.Errorf("Unexpected first entry %q from %q", first.Message, first.Source)
+	}
+	second := receiveEntry(t, out)
+	if second.Fields["stream"] != "stderr" || second.Level != models.LevelWarning {
+		t.Errorf("Expected a stderr warning, got %v %v", second.Fields["stream"], second.Level)
+	}
+
+	daemon.mu.Lock()
+	filters := daemon.filters
+	daemon.mu.Unlock()
+	if filters != `{"label":["logflux=true"]}` {
+		t.Errorf("Expected the label filter sent, got %q", filters)
+	}
+
+	// A container started later is picked up
+	daemon.addContainer("bbb222", "worker", "app:2", dockerFrame(dockerStdout, "2023-10-11T13:56:00Z job done\n"))
+	third := receiveEntry(t, out)
+	if third.Source != "worker" || third.Fields["image"] != "app:2" {
+		t.Errorf("Expected the new container's entry, got %q from %q", third.Message, third.Source)
+	}
+
+	if got := reader.Stats().EntriesProduced; got != 3 {
+		t.Errorf("Expected 3 entries, got %d", got)
+	}
+	if name := reader.Name(); name != "docker:label=logflux=true" {
+		t.Errorf("Expected docker:label=logflux=true, got %q", name)
+	}
+}
+
+func TestDockerReader_KeepRaw(t *testing.T) {
+	daemon, client := newFakeDockerDaemon(t)
+	line := "2023-10-11T13:55:36.000000001Z GET /health 200"
+	daemon.addContainer("aaa111", "web", "nginx:1.25", dockerFrame(dockerStdout, line+"\n"))
+
+	reader := NewDockerReader(client, DockerOptions{ScanInterval: 20 * time.Millisecond, KeepRaw: true})
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	entry := receiveEntry(t, out)
+	if raw := entry.Fields[FieldRaw]; raw != line {
+		t.Errorf("Expected raw %q, got %q", line, raw)
+	}
+}
+
+func TestDockerReader_ResumesAfterLastLine(t *testing.T) {
+	daemon, client := newFakeDockerDaemon(t)
+	daemon.addContainer("aaa111", "web", "nginx", dockerFrame(dockerStdout, "2023-10-11T13:55:36.5Z hello\n"))
+
+	reader := NewDockerReader(client, DockerOptions{ScanInterval: time.Hour})
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	receiveEntry(t, out)
+	reader.Stop()
+
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+	receiveEntry(t, out)
+
+	daemon.mu.Lock()
+	since := daemon.since["aaa111"]
+	daemon.mu.Unlock()
+	last := time.Date(2023, 10, 11, 13, 55, 36, 500000000, time.UTC)
+	if since != dockerTime(last.Add(time.Nanosecond)) {
+		t.Errorf("Expected to resume just after the last line, got since=%q", since)
+	}
+}
+
+func TestDockerReader_DaemonUnreachable(t *testing.T) {
+	client, err := NewDockerClient("unix:///nonexistent/docker.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewDockerReader(client, DockerOptions{}).Start(context.Background(), make(chan *models.LogEntry))
+	var sourceErr *models.SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Code != models.IOError {
+		t.Errorf("Expected an IOError, got %v", err)
+	}
+}
+
+func TestNewDockerClient_InvalidHost(t *testing.T) {
+	if _, err := NewDockerClient("ftp://docker"); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package sources
+
+import (
+	"context"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// WindowsEventLogReader reads events from a Windows Event Log channel. It
+// is only supported on Windows; elsewhere Start fails.
+type WindowsEventLogReader struct {
+	channel string
+	opts    WindowsEventLogOptions
+}
+
+// NewWindowsEventLogReaderWithOptions creates an event log reader with
+// custom options
+func NewWindowsEventLogReaderWithOptions(channel string, opts WindowsEventLogOptions) *WindowsEventLogReader {
+	return &WindowsEventLogReader{channel: channel, opts: opts}
+}
+
+// SetLabels has no effect, the reader never runs
+func (r *WindowsEventLogReader) SetLabels(labels SourceLabels) {}
+
+// Start always fails outside Windows
+func (r *WindowsEventLogReader) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	return sourceError(models.Unsupported, "windows event log is only supported on windows")
+}
+
+// Stop is a no-op
+func (r *WindowsEventLogReader) Stop() error {
+	return nil
+}
+
+// Stats returns empty stats, the reader never runs
+func (r *WindowsEventLogReader) Stats() models.SourceStats {
+	return models.SourceStats{}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package sources
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestWindowsEventLogReader_Unsupported(t *testing.T) {
+	reader := NewWindowsEventLogReader("Application")
+
+	err := reader.Start(context.Background(), make(chan *models.LogEntry, 1))
+	var sourceErr *models.SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Code != models.Unsupported {
+		t.Errorf("Expected an Unsupported error, got %v", err)
+	}
+	if reader.Name() != "eventlog:Application" {
+		t.Errorf("Expected eventlog:Application, got %q", reader.Name())
+	}
+}
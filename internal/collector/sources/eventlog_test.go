@@ -0,0 +1,109 @@
+package sources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// sampleEventXML is an event as EvtRender renders it
+const sampleEventXML = `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event">
+  <System>
+    <Provider Name="Application Error" />
+    <EventID Qualifiers="0">1000</EventID>
+    <Level>2</Level>
+    <TimeCreated SystemTime="2023-10-10T13:55:36.1234567Z" />
+    <EventRecordID>4242</EventRecordID>
+    <Channel>Application</Channel>
+    <Computer>WEB-01</Computer>
+  </System>
+  <EventData>
+    <Data Name="AppName">w3wp.exe</Data>
+    <Data Name="ExceptionCode">c0000005</Data>
+  </EventData>
+</Event>`
+
+func TestEventEntry(t *testing.T) {
+	entry, err := eventEntry(sampleEventXML, "Faulting application name: w3wp.exe\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Level != models.LevelError {
+		t.Errorf("Expected ERROR, got %v", entry.Level)
+	}
+	if entry.Source != "Application Error" {
+		t.Errorf("Expected the provider as source, got %q", entry.Source)
+	}
+	if entry.Message != "Faulting application name: w3wp.exe" {
+		t.Errorf("Expected the formatted message, got %q", entry.Message)
+	}
+	expectedTS := time.Date(2023, 10, 10, 13, 55, 36, 123456700, time.UTC)
+	if !entry.Timestamp.Equal(expectedTS) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTS, entry.Timestamp)
+	}
+
+	expectedFields := map[string]interface{}{
+		"event_id":      uint32(1000),
+		"provider":      "Application Error",
+		"channel":       "Application",
+		"computer":      "WEB-01",
+		"record_id":     uint64(4242),
+		"AppName":       "w3wp.exe",
+		"ExceptionCode": "c0000005",
+	}
+	for name, value := range expectedFields {
+		if entry.Fields[name] != value {
+			t.Errorf("Expected field %s=%v, got %v", name, value, entry.Fields[name])
+		}
+	}
+}
+
+func TestEventEntry_WithoutMessage(t *testing.T) {
+	entry, err := eventEntry(sampleEventXML, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Message != "w3wp.exe c0000005" {
+		t.Errorf("Expected the data values as message, got %q", entry.Message)
+	}
+
+	bare, err := eventEntry(`<Event><System><Provider Name="Service Control Manager"/><EventID>7036</EventID></System></Event>`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bare.Message != "Event 7036 from Service Control Manager" {
+		t.Errorf("Expected a generated message, got %q", bare.Message)
+	}
+	if bare.Level != models.LevelInfo {
+		t.Errorf("Expected level 0 to map to INFO, got %v", bare.Level)
+	}
+	if bare.Fields[FieldTimestampSource] != TimestampSourceIngest {
+		t.Error("Expected an event without a time to be marked with the ingest time")
+	}
+
+	if _, err := eventEntry("not xml", ""); err == nil {
+		t.Error("Expected an error for invalid XML")
+	}
+}
+
+func TestEventLevel(t *testing.T) {
+	tests := []struct {
+		level    uint8
+		expected models.LogLevel
+	}{
+		{0, models.LevelInfo},
+		{1, models.LevelCritical},
+		{2, models.LevelError},
+		{3, models.LevelWarning},
+		{4, models.LevelInfo},
+		{5, models.LevelDebug},
+	}
+
+	for _, tt := range tests {
+		if got := eventLevel(tt.level); got != tt.expected {
+			t.Errorf("Level %d: expected %v, got %v", tt.level, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,450 @@
+//go:build windows
+
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+var (
+	wevtapi                      = syscall.NewLazyDLL("wevtapi.dll")
+	procEvtSubscribe             = wevtapi.NewProc("EvtSubscribe")
+	procEvtNext                  = wevtapi.NewProc("EvtNext")
+	procEvtRender                = wevtapi.NewProc("EvtRender")
+	procEvtClose                 = wevtapi.NewProc("EvtClose")
+	procEvtCreateBookmark        = wevtapi.NewProc("EvtCreateBookmark")
+	procEvtUpdateBookmark        = wevtapi.NewProc("EvtUpdateBookmark")
+	procEvtOpenPublisherMetadata = wevtapi.NewProc("EvtOpenPublisherMetadata")
+	procEvtFormatMessage         = wevtapi.NewProc("EvtFormatMessage")
+
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procCreateEventW = kernel32.NewProc("CreateEventW")
+	procResetEvent   = kernel32.NewProc("ResetEvent")
+)
+
+const (
+	evtSubscribeToFutureEvents     = 1
+	evtSubscribeStartAfterBookmark = 3
+	evtRenderEventXML              = 1
+	evtRenderBookmark              = 2
+	evtFormatMessageEvent          = 1
+
+	errorInsufficientBuffer syscall.Errno = 122
+	errorNoMoreItems        syscall.Errno = 259
+	errorEvtInvalidQuery    syscall.Errno = 15001
+	errorEvtChannelNotFound syscall.Errno = 15007
+
+	// eventBatchSize is how many events are fetched per call
+	eventBatchSize = 64
+
+	// eventWaitTimeout bounds a wait for new events, so Stop is noticed
+	eventWaitTimeout = 500 * time.Millisecond
+)
+
+// renderedEvent is an event as XML with its formatted message
+type renderedEvent struct {
+	xml     string
+	message string
+}
+
+// eventSubscription is a pull subscription to a channel
+type eventSubscription interface {
+	// wait blocks until new events may be available or the timeout passes
+	wait(timeout time.Duration) error
+
+	// next returns up to n pending events, none when it has caught up
+	next(n int) ([]renderedEvent, error)
+
+	// bookmark returns the position after the last event next returned
+	bookmark() (string, error)
+
+	close() error
+}
+
+// subscribeEventLog subscribes to a channel after the bookmark, or to
+// future events without one. Tests replace it with a fake.
+var subscribeEventLog = subscribeWindowsEventLog
+
+// WindowsEventLogReader reads events from a Windows Event Log channel
+type WindowsEventLogReader struct {
+	channel string
+	opts    WindowsEventLogOptions
+
+	mu      sync.Mutex
+	running bool
+	stop    chan struct{} // closed by Stop, one per run
+	run     *runState
+
+	labels SourceLabels
+	stats  sourceStats
+}
+
+// NewWindowsEventLogReaderWithOptions creates an event log reader with
+// custom options
+func NewWindowsEventLogReaderWithOptions(channel string, opts WindowsEventLogOptions) *WindowsEventLogReader {
+	if opts.Query == "" {
+		opts.Query = "*"
+	}
+
+	run := newRunState()
+	run.finish(nil)
+
+	return &WindowsEventLogReader{
+		channel: channel,
+		opts:    opts,
+		run:     run,
+	}
+}
+
+// SetLabels names the source and labels every entry from it. It must
+// be called before Start.
+func (r *WindowsEventLogReader) SetLabels(labels SourceLabels) {
+	r.labels = labels
+}
+
+// Start subscribes to the channel and streams its events
+func (r *WindowsEventLogReader) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return sourceError(models.AlreadyRunning, "event log reader already running")
+	}
+
+	bookmark, err := loadPosition(r.opts.BookmarkPath)
+	if err != nil {
+		return &models.SourceError{Code: models.IOError, Err: err}
+	}
+
+	sub, err := subscribeEventLog(r.channel, r.opts.Query, bookmark)
+	if err != nil {
+		code := models.IOError
+		if errors.Is(err, errorEvtChannelNotFound) || errors.Is(err, errorEvtInvalidQuery) {
+			code = models.Unsupported
+		}
+		return sourceError(code, "failed to subscribe to %s: %w", r.channel, err)
+	}
+
+	r.running = true
+	r.stop = make(chan struct{})
+	r.run = newRunState()
+	go r.readLoop(ctx, out, sub, r.stop, r.run)
+
+	fmt.Printf("🪟 Reading Windows event log channel %s\n", r.channel)
+	return nil
+}
+
+// readLoop streams events until Stop, cancellation or an API failure
+func (r *WindowsEventLogReader) readLoop(ctx context.Context, out chan<- *models.LogEntry, sub eventSubscription, stop chan struct{}, run *runState) {
+	var exitErr error
+	defer func() { run.finish(exitErr) }()
+	defer r.ended(stop)
+	defer sub.close()
+
+	for ctx.Err() == nil && !isClosed(stop) {
+		events, err := sub.next(eventBatchSize)
+		if err != nil {
+			r.stats.recordError()
+			exitErr = sourceError(models.IOError, "failed to read events: %w", err)
+			return
+		}
+
+		if len(events) == 0 {
+			if err := sub.wait(eventWaitTimeout); err != nil {
+				r.stats.recordError()
+				exitErr = sourceError(models.IOError, "failed to wait for events: %w", err)
+				return
+			}
+			continue
+		}
+
+		for _, event := range events {
+			entry, err := eventEntry(event.xml, event.message)
+			if err != nil {
+				fmt.Printf("⚠️  Skipped event on %s: %v\n", r.channel, err)
+				r.stats.recordError()
+				continue
+			}
+			if r.opts.KeepRaw {
+				keepRaw(entry, event.xml)
+			}
+
+			metrics.EntriesReceived.WithLabelValues(r.Name()).Inc()
+			metrics.BytesReceived.WithLabelValues(r.Name()).Add(int64(len(event.xml)))
+			r.stats.recordBytes(len(event.xml))
+			r.stats.recordEntry()
+			r.labels.apply(entry)
+
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			}
+		}
+
+		// Only once the whole batch was handed on, so an interrupted batch
+		// is read again after a restart
+		r.saveBookmark(sub)
+	}
+}
+
+// saveBookmark persists the subscription's position if a path is set
+func (r *WindowsEventLogReader) saveBookmark(sub eventSubscription) {
+	if r.opts.BookmarkPath == "" {
+		return
+	}
+
+	bookmark, err := sub.bookmark()
+	if err == nil {
+		err = savePosition(r.opts.BookmarkPath, bookmark)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Failed to save bookmark for %s: %v\n", r.channel, err)
+		r.stats.recordError()
+	}
+}
+
+// Stop stops reading. The subscription is closed once the loop notices,
+// within eventWaitTimeout.
+func (r *WindowsEventLogReader) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		r.running = false
+		close(r.stop)
+	}
+	return nil
+}
+
+// ended marks the reader stopped when its loop exits by itself, unless
+// it was stopped and started again in the meantime
+func (r *WindowsEventLogReader) ended(stop chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running && r.stop == stop {
+		r.running = false
+		close(stop)
+	}
+}
+
+// isClosed reports whether a stop channel was closed
+func isClosed(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done is closed once the read loop ends
+func (r *WindowsEventLogReader) Done() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.run.done
+}
+
+// Err reports why the read loop died once Done is closed, nil after Stop or
+// cancellation
+func (r *WindowsEventLogReader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.run.Err()
+}
+
+// Stats returns a snapshot of the reader's activity
+func (r *WindowsEventLogReader) Stats() models.SourceStats {
+	return r.stats.snapshot()
+}
+
+// winSubscription is a subscription made through the Event Log API
+type winSubscription struct {
+	handle     syscall.Handle
+	signal     syscall.Handle // set while events are waiting
+	mark       syscall.Handle // bookmark following every event returned
+	publishers map[string]syscall.Handle
+}
+
+func subscribeWindowsEventLog(channel, query, bookmark string) (eventSubscription, error) {
+	channelPtr, err := syscall.UTF16PtrFromString(channel)
+	if err != nil {
+		return nil, err
+	}
+	queryPtr, err := syscall.UTF16PtrFromString(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Manual reset, initially set so events already waiting are read
+	signal, _, err := procCreateEventW.Call(0, 1, 1, 0)
+	if signal == 0 {
+		return nil, fmt.Errorf("CreateEvent: %w", err)
+	}
+
+	var bookmarkPtr *uint16
+	flags := uintptr(evtSubscribeToFutureEvents)
+	if bookmark != "" {
+		if bookmarkPtr, err = syscall.UTF16PtrFromString(bookmark); err != nil {
+			syscall.CloseHandle(syscall.Handle(signal))
+			return nil, err
+		}
+		flags = evtSubscribeStartAfterBookmark
+	}
+	mark, _, err := procEvtCreateBookmark.Call(uintptr(unsafe.Pointer(bookmarkPtr)))
+	if mark == 0 {
+		syscall.CloseHandle(syscall.Handle(signal))
+		return nil, fmt.Errorf("EvtCreateBookmark: %w", err)
+	}
+
+	var after uintptr
+	if bookmark != "" {
+		after = mark
+	}
+	handle, _, err := procEvtSubscribe.Call(0, signal,
+		uintptr(unsafe.Pointer(channelPtr)), uintptr(unsafe.Pointer(queryPtr)),
+		after, 0, 0, flags)
+	if handle == 0 {
+		evtClose(syscall.Handle(mark))
+		syscall.CloseHandle(syscall.Handle(signal))
+		return nil, fmt.Errorf("EvtSubscribe: %w", err)
+	}
+
+	return &winSubscription{
+		handle:     syscall.Handle(handle),
+		signal:     syscall.Handle(signal),
+		mark:       syscall.Handle(mark),
+		publishers: make(map[string]syscall.Handle),
+	}, nil
+}
+
+func (s *winSubscription) wait(timeout time.Duration) error {
+	result, err := syscall.WaitForSingleObject(s.signal, uint32(timeout.Milliseconds()))
+	if result == syscall.WAIT_FAILED {
+		return fmt.Errorf("WaitForSingleObject: %w", err)
+	}
+	return nil
+}
+
+func (s *winSubscription) next(n int) ([]renderedEvent, error) {
+	handles := make([]syscall.Handle, n)
+	var returned uint32
+	ok, _, err := procEvtNext.Call(uintptr(s.handle), uintptr(n),
+		uintptr(unsafe.Pointer(&handles[0])), 0, 0, uintptr(unsafe.Pointer(&returned)))
+	if ok == 0 {
+		if errors.Is(err, errorNoMoreItems) {
+			// Caught up, wait for the signal to be set again
+			procResetEvent.Call(uintptr(s.signal))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("EvtNext: %w", err)
+	}
+
+	events := make([]renderedEvent, 0, returned)
+	for _, h := range handles[:returned] {
+		text, err := evtRender(h, evtRenderEventXML)
+		if err == nil {
+			events = append(events, renderedEvent{xml: text, message: s.message(h, text)})
+		} else {
+			fmt.Printf("⚠️  Failed to render event: %v\n", err)
+		}
+		procEvtUpdateBookmark.Call(uintptr(s.mark), uintptr(h))
+		evtClose(h)
+	}
+	return events, nil
+}
+
+// message formats the event's message with its provider's metadata, empty
+// when the provider has none
+func (s *winSubscription) message(event syscall.Handle, text string) string {
+	parsed, err := parseEventXML(text)
+	if err != nil {
+		return ""
+	}
+	provider := parsed.System.Provider.Name
+
+	meta, ok := s.publishers[provider]
+	if !ok {
+		// Cache misses too, so a provider without metadata is looked up once
+		if name, err := syscall.UTF16PtrFromString(provider); err == nil {
+			h, _, _ := procEvtOpenPublisherMetadata.Call(0, uintptr(unsafe.Pointer(name)), 0, 0, 0)
+			meta = syscall.Handle(h)
+		}
+		s.publishers[provider] = meta
+	}
+	if meta == 0 {
+		return ""
+	}
+
+	// The first call only asks for the size, in characters
+	var used uint32
+	formatted, _, err := procEvtFormatMessage.Call(uintptr(meta), uintptr(event), 0, 0, 0,
+		evtFormatMessageEvent, 0, 0, uintptr(unsafe.Pointer(&used)))
+	if formatted == 0 && !errors.Is(err, errorInsufficientBuffer) {
+		return ""
+	}
+	if used == 0 {
+		return ""
+	}
+	buf := make([]uint16, used)
+	formatted, _, _ = procEvtFormatMessage.Call(uintptr(meta), uintptr(event), 0, 0, 0,
+		evtFormatMessageEvent, uintptr(len(buf)), uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&used)))
+	if formatted == 0 {
+		return ""
+	}
+	return strings.TrimSpace(syscall.UTF16ToString(buf))
+}
+
+func (s *winSubscription) bookmark() (string, error) {
+	return evtRender(s.mark, evtRenderBookmark)
+}
+
+func (s *winSubscription) close() error {
+	for _, meta := range s.publishers {
+		if meta != 0 {
+			evtClose(meta)
+		}
+	}
+	evtClose(s.handle)
+	evtClose(s.mark)
+	return syscall.CloseHandle(s.signal)
+}
+
+// evtRender renders an event or bookmark as XML
+func evtRender(h syscall.Handle, flags uint32) (string, error) {
+	var used, count uint32
+	ok, _, err := procEvtRender.Call(0, uintptr(h), uintptr(flags), 0, 0,
+		uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&count)))
+	if ok == 0 && !errors.Is(err, errorInsufficientBuffer) {
+		return "", fmt.Errorf("EvtRender: %w", err)
+	}
+	if used == 0 {
+		return "", nil
+	}
+
+	// used counts bytes of UTF-16
+	buf := make([]uint16, used/2+1)
+	ok, _, err = procEvtRender.Call(0, uintptr(h), uintptr(flags), uintptr(len(buf)*2),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&count)))
+	if ok == 0 {
+		return "", fmt.Errorf("EvtRender: %w", err)
+	}
+	return syscall.UTF16ToString(buf), nil
+}
+
+func evtClose(h syscall.Handle) {
+	procEvtClose.Call(uintptr(h))
+}
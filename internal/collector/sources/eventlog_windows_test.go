@@ -0,0 +1,158 @@
+//go:build windows
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// fakeEventLog stands in for a channel, handing out its events in order
+type fakeEventLog struct {
+	mu     sync.Mutex
+	events []string // event XML, by record ID - 1
+	after  []string // the bookmark each subscription started after
+}
+
+// add logs an event and returns its record ID
+func (f *fakeEventLog) add(level int, message string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := len(f.events) + 1
+	f.events = append(f.events, fmt.Sprintf(`<Event><System><Provider Name="Test"/><EventID>%d</EventID><Level>%d</Level><EventRecordID>%d</EventRecordID><Channel>Application</Channel></System><EventData><Data>%s</Data></EventData></Event>`,
+		100+id, level, id, message))
+	return id
+}
+
+func (f *fakeEventLog) subscribe(channel, query, bookmark string) (eventSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.after = append(f.after, bookmark)
+	sub := &fakeSubscription{log: f}
+	if bookmark == "" {
+		// Future events only
+		sub.position = len(f.events)
+	} else {
+		fmt.Sscanf(bookmark, "record:%d", &sub.position)
+	}
+	return sub, nil
+}
+
+type fakeSubscription struct {
+	log      *fakeEventLog
+	position int // events returned so far
+}
+
+func (s *fakeSubscription) wait(timeout time.Duration) error {
+	time.Sleep(5 * time.Millisecond)
+	return nil
+}
+
+func (s *fakeSubscription) next(n int) ([]renderedEvent, error) {
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+
+	var events []renderedEvent
+	for s.position < len(s.log.events) && len(events) < n {
+		events = append(events, renderedEvent{xml: s.log.events[s.position]})
+		s.position++
+	}
+	return events, nil
+}
+
+func (s *fakeSubscription) bookmark() (string, error) {
+	return fmt.Sprintf("record:%d", s.position), nil
+}
+
+func (s *fakeSubscription) close() error { return nil }
+
+func useFakeEventLog(t *testing.T) *fakeEventLog {
+	fake := &fakeEventLog{}
+	previous := subscribeEventLog
+	subscribeEventLog = fake.subscribe
+	t.Cleanup(func() { subscribeEventLog = previous })
+	return fake
+}
+
+func TestWindowsEventLogReader_StreamsNewEvents(t *testing.T) {
+	fake := useFakeEventLog(t)
+	fake.add(4, "before start")
+
+	reader := NewWindowsEventLogReader("Application")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	fake.add(2, "disk failure")
+	entry := receiveEntry(t, out)
+	if entry.Message != "disk failure" || entry.Level != models.LevelError {
+		t.Errorf("Expected only the new ERROR event, got %q %v", entry.Message, entry.Level)
+	}
+	if entry.Fields["event_id"] != uint32(102) {
+		t.Errorf("Expected event ID 102, got %v", entry.Fields["event_id"])
+	}
+}
+
+func TestWindowsEventLogReader_ResumesFromBookmark(t *testing.T) {
+	fake := useFakeEventLog(t)
+	bookmarkPath := filepath.Join(t.TempDir(), "application.bookmark")
+	opts := WindowsEventLogOptions{BookmarkPath: bookmarkPath}
+
+	reader := NewWindowsEventLogReaderWithOptions("Application", opts)
+	out := make(chan *models.LogEntry, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	fake.add(4, "first")
+	receiveEntry(t, out)
+	cancel()
+	<-reader.Done()
+
+	// Logged while the reader was down
+	fake.add(3, "second")
+
+	reader = NewWindowsEventLogReaderWithOptions("Application", opts)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	if entry := receiveEntry(t, out); entry.Message != "second" {
+		t.Errorf("Expected to resume with the missed event, got %q", entry.Message)
+	}
+	if got := fake.after[1]; got != "record:1" {
+		t.Errorf("Expected the second subscription after record 1, got %q", got)
+	}
+}
+
+func TestWindowsEventLogReader_AlreadyRunning(t *testing.T) {
+	useFakeEventLog(t)
+	reader := NewWindowsEventLogReader("Application")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := reader.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	if err := reader.Start(ctx, make(chan *models.LogEntry, 1)); err == nil {
+		t.Error("Expected an error starting a running reader")
+	}
+}
@@ -0,0 +1,75 @@
+package sources
+
+import "time"
+
+// DefaultPollPeriod is how often a FileReader checks its file for new data
+const DefaultPollPeriod = 100 * time.Millisecond
+
+// DefaultMaxPollPeriod caps the interval an adaptive FileReader backs off to
+const DefaultMaxPollPeriod = 2 * time.Second
+
+// PollOptions configures how often a FileReader checks its file for new data
+type PollOptions struct {
+	// Period is the interval between checks, 100ms by default. With
+	// Adaptive it is the fast interval used while data keeps arriving.
+	Period time.Duration
+
+	// Adaptive doubles the interval after every check that finds nothing
+	// new, up to MaxPeriod, and drops back to Period as soon as data
+	// arrives. Idle files then cost little CPU while busy ones stay quick.
+	Adaptive bool
+
+	// MaxPeriod caps the adaptive interval, 2s by default
+	MaxPeriod time.Duration
+}
+
+// withDefaults fills in unset periods
+func (o PollOptions) withDefaults() PollOptions {
+	if o.Period <= 0 {
+		o.Period = DefaultPollPeriod
+	}
+	if o.MaxPeriod <= 0 {
+		o.MaxPeriod = DefaultMaxPollPeriod
+	}
+	if o.MaxPeriod < o.Period {
+		o.MaxPeriod = o.Period
+	}
+	return o
+}
+
+// pollClock waits between polls. Tests replace it to step through them.
+type pollClock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock waits on the system clock
+type realClock struct{}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// poller works out the wait before each poll
+type poller struct {
+	opts     PollOptions
+	interval time.Duration
+}
+
+func newPoller(opts PollOptions) *poller {
+	opts = opts.withDefaults()
+	return &poller{opts: opts, interval: opts.Period}
+}
+
+// next returns the wait before the next poll, given whether the last one
+// found new data
+func (p *poller) next(active bool) time.Duration {
+	if !p.opts.Adaptive || active {
+		p.interval = p.opts.Period
+		return p.interval
+	}
+	p.interval *= 2
+	if p.interval > p.opts.MaxPeriod {
+		p.interval = p.opts.MaxPeriod
+	}
+	return p.interval
+}
@@ -0,0 +1,130 @@
+package sources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// stepClock hands out a poll only when the test sends a tick, recording
+// every wait asked for
+type stepClock struct {
+	waits chan time.Duration
+	ticks chan time.Time
+}
+
+func newStepClock() *stepClock {
+	return &stepClock{waits: make(chan time.Duration, 64), ticks: make(chan time.Time)}
+}
+
+func (c *stepClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.ticks
+}
+
+func (c *stepClock) nextWait(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.waits:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the next poll to be scheduled")
+		return 0
+	}
+}
+
+func TestPoller(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     PollOptions
+		activity []bool
+		expected []time.Duration
+	}{
+		{
+			name:     "fixed",
+			opts:     PollOptions{Period: 250 * time.Millisecond},
+			activity: []bool{false, false, true, false},
+			expected: []time.Duration{250 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond},
+		},
+		{
+			name:     "adaptive defaults",
+			opts:     PollOptions{Adaptive: true},
+			activity: []bool{false, false, false, false, false, false, true},
+			expected: []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 1600 * time.Millisecond, 2 * time.Second, 2 * time.Second, 100 * time.Millisecond},
+		},
+		{
+			name:     "max below period",
+			opts:     PollOptions{Period: time.Second, Adaptive: true, MaxPeriod: 10 * time.Millisecond},
+			activity: []bool{false, false},
+			expected: []time.Duration{time.Second, time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPoller(tt.opts)
+			for i, active := range tt.activity {
+				if got := p.next(active); got != tt.expected[i] {
+					t.Errorf("Poll %d: expected %s, got %s", i, tt.expected[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestFileReader_AdaptivePolling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(path)
+	reader.SetPolling(PollOptions{Period: 50 * time.Millisecond, Adaptive: true, MaxPeriod: 300 * time.Millisecond})
+	clock := newStepClock()
+	reader.clock = clock
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	if got := clock.nextWait(t); got != 50*time.Millisecond {
+		t.Fatalf("Expected the first poll after 50ms, got %s", got)
+	}
+
+	// Idle polls back off up to the cap
+	for _, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond} {
+		clock.ticks <- time.Now()
+		if got := clock.nextWait(t); got != expected {
+			t.Errorf("Expected an idle wait of %s, got %s", expected, got)
+		}
+	}
+
+	// New data snaps back to the fast interval
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("hello\n")
+	f.Close()
+
+	clock.ticks <- time.Now()
+	if entry := receiveEntry(t, out); entry.Message != "hello\n" {
+		t.Errorf("Expected hello, got %q", entry.Message)
+	}
+	if got := clock.nextWait(t); got != 50*time.Millisecond {
+		t.Errorf("Expected the wait reset to 50ms after data, got %s", got)
+	}
+
+	clock.ticks <- time.Now()
+	if got := clock.nextWait(t); got != 100*time.Millisecond {
+		t.Errorf("Expected backing off again once idle, got %s", got)
+	}
+}
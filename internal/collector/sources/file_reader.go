@@ -2,34 +2,280 @@ package sources
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/fatihserhatturan/logflux/internal/metrics"
 	"github.com/fatihserhatturan/logflux/pkg/models"
 )
 
+// Supported line formats for FileReader
+const (
+	FormatRaw    = "raw"    // whole line becomes the message
+	FormatJSON   = "json"   // one JSON object per line
+	FormatLogfmt = "logfmt" // key=value pairs, see LogfmtParser
+	FormatRegex  = "regex"  // named capture groups of a regexp
+	FormatCSV    = "csv"    // delimited columns with RFC 4180 quoting
+	FormatTSV    = "tsv"    // tab-separated columns, no quoting
+)
+
+// maxFileLineSize caps a line read from a file. Longer lines are cut to it
+// and marked truncated, the rest of the line is skipped.
+const maxFileLineSize = 1 << 20
+
+// defaultTimestampLayouts are tried when a regex, delimited or JSON reader
+// has no explicit layout
+var defaultTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.Stamp,
+}
+
 // FileReader reads logs from a file continuously
 type FileReader struct {
-	filepath   string
+	filepath string
+	format   string
+
+	// How often the file is checked for new data, and the clock the checks
+	// are timed with
+	poll  PollOptions
+	clock pollClock
+
+	// Regex format only
+	pattern         *regexp.Regexp
+	patternErr      error
+	timestampLayout string
+
+	// CSV and TSV formats only
+	delimiter  rune
+	columns    []string
+	skipHeader bool
+
+	// JSON format only
+	json JSONParser
+
+	// logfmt format only
+	logfmt LogfmtParser
+
+	// Replaces the format's parsing when set
+	parser Parser
+
+	// Copies each line into Fields["_raw"]
+	keepRaw bool
+
+	// Guesses the level of lines left at INFO when set
+	levels models.LevelRules
+
+	// Takes the lines the format fails on instead of them being passed on
+	// raw, when set
+	deadLetter DeadLetter
+
+	// Names the source and tags every entry
+	labels SourceLabels
+
+	// Marks every line as left for the pipeline's parse pool
+	parsePool bool
+
+	// Resuming from acked offsets, only with a checkpoint path
+	checkpointPath string
+
+	// One-shot readers are read once instead of tailed
+	oneShot bool
+
+	// partial holds the start of a line still waiting for its newline, up
+	// to maxFileLineSize, and skipped counts the bytes of it past that.
+	// Only touched by readLoop.
+	partial []byte
+	skipped int
+
+	readerState
+	stats sourceStats
+}
+
+// readerState is what the read loop shares with Start, Stop and the
+// accessors called from other goroutines. All of it is guarded by mu.
+type readerState struct {
+	mu         sync.Mutex
+	file       *os.File
 	offset     int64
-	pollPeriod time.Duration
+	checkpoint *checkpoint
+	run        *runState
+
+	running bool // between Start and Stop
+	looping bool // from Start until the read loop returns, which may be after Stop
 
-	mu      sync.Mutex
-	file    *os.File
-	running bool
+	// Compressed files are read once instead of tailed
+	compressed    bool
+	headerPending bool // the next line is the header
 }
 
 // NewFileReader creates a new file reader
 func NewFileReader(filepath string) *FileReader {
+	return NewFileReaderWithFormat(filepath, FormatRaw)
+}
+
+// NewFileReaderWithFormat creates a file reader that parses lines in the given
+// format ("raw", "json" or "logfmt")
+func NewFileReaderWithFormat(filepath string, format string) *FileReader {
 	return &FileReader{
-		filepath:   filepath,
-		offset:     0,
-		pollPeriod: 100 * time.Millisecond,
+		filepath:    filepath,
+		format:      strings.ToLower(format),
+		poll:        PollOptions{}.withDefaults(),
+		clock:       realClock{},
+		readerState: readerState{run: newRunState()},
+	}
+}
+
+// NewFileReaderOneShot creates a reader that reads the file's existing
+// lines and then stops, closing Done, instead of tailing it
+func NewFileReaderOneShot(filepath string) *FileReader {
+	fr := NewFileReader(filepath)
+	fr.SetOneShot(true)
+	return fr
+}
+
+// JSONOptions configures a JSON file reader
+type JSONOptions struct {
+	// TimestampLayouts are the time.Parse layouts tried in order on string
+	// timestamps. Without any, RFC 3339 and a few other common layouts are
+	// tried. Numbers are read as seconds, milliseconds, microseconds or
+	// nanoseconds since the epoch, whichever fits their magnitude. When
+	// nothing fits the entry keeps its ingest time and is flagged with
+	// timestamp_unparsed.
+	TimestampLayouts []string
+}
+
+// NewFileReaderWithJSON creates a file reader for one JSON object per line
+func NewFileReaderWithJSON(filepath string, opts JSONOptions) *FileReader {
+	fr := NewFileReaderWithFormat(filepath, FormatJSON)
+	fr.json = JSONParser{TimestampLayouts: opts.TimestampLayouts}
+	return fr
+}
+
+// LogfmtOptions configures a logfmt file reader
+type LogfmtOptions struct {
+	// TimestampLayouts are the time.Parse layouts tried in order on
+	// timestamps. Without any, RFC 3339 and a few other common layouts are
+	// tried.
+	TimestampLayouts []string
+}
+
+// NewFileReaderWithLogfmt creates a file reader for logfmt lines, see
+// LogfmtParser
+func NewFileReaderWithLogfmt(filepath string, opts LogfmtOptions) *FileReader {
+	fr := NewFileReaderWithFormat(filepath, FormatLogfmt)
+	fr.logfmt = LogfmtParser{TimestampLayouts: opts.TimestampLayouts}
+	return fr
+}
+
+// NewFileReaderWithRegex creates a file reader that parses each line with a
+// regexp. Named groups level, timestamp, source and message map onto the entry,
+// any other named group goes to Fields. Lines that don't match are kept raw.
+func NewFileReaderWithRegex(filepath string, pattern string) *FileReader {
+	fr := NewFileReaderWithFormat(filepath, FormatRegex)
+	fr.pattern, fr.patternErr = regexp.Compile(pattern)
+	return fr
+}
+
+// NewFileReaderWithDelimited creates a file reader that splits each line on
+// the delimiter and names the values after columns. Columns named level,
+// timestamp, source and message map onto the entry, the rest go to Fields.
+// A tab delimiter splits plainly; any other follows RFC 4180 quoting, though
+// a quoted value can't span lines.
+func NewFileReaderWithDelimited(filepath string, delimiter rune, columns []string) *FileReader {
+	format := FormatCSV
+	if delimiter == '\t' {
+		format = FormatTSV
 	}
+	fr := NewFileReaderWithFormat(filepath, format)
+	fr.delimiter = delimiter
+	fr.columns = columns
+	return fr
+}
+
+// SetSkipHeader skips the first line of a delimited file. When no columns
+// were given, the header's values name them.
+func (fr *FileReader) SetSkipHeader(skip bool) {
+	fr.skipHeader = skip
+}
+
+// SetKeepRaw keeps each line exactly as read, line ending included, in
+// Fields["_raw"]. Off by default as it doubles the memory per entry.
+func (fr *FileReader) SetKeepRaw(keep bool) {
+	fr.keepRaw = keep
+}
+
+// SetLevelRules guesses the level of lines the format leaves at INFO, such
+// as every raw line, from keywords in the message. Nil turns it off.
+func (fr *FileReader) SetLevelRules(rules models.LevelRules) {
+	fr.levels = rules
+}
+
+// SetDeadLetter sends lines the format or parser fails on to dl, with the
+// error, instead of passing them on as raw entries
+func (fr *FileReader) SetDeadLetter(dl DeadLetter) {
+	fr.deadLetter = dl
+}
+
+// SetLabels names the source and labels every entry from it. It must
+// be called before Start.
+func (fr *FileReader) SetLabels(labels SourceLabels) {
+	fr.labels = labels
+}
+
+// SetParsePool leaves parsing to the pipeline's parse pool, marking every
+// entry with FieldUnparsed. Only raw lines can be left to it, so the reader
+// must use the raw format. It must be called before Start.
+func (fr *FileReader) SetParsePool(pool bool) error {
+	if pool && (fr.format != FormatRaw || fr.parser != nil) {
+		return fmt.Errorf("lines in %s format are already parsed", fr.format)
+	}
+	fr.parsePool = pool
+	return nil
+}
+
+// SetPolling changes how often the file is checked for new data. It must
+// be called before Start.
+func (fr *FileReader) SetPolling(opts PollOptions) {
+	fr.poll = opts.withDefaults()
+}
+
+// SetOneShot makes the reader stop at the end of the file, like a
+// compressed one, instead of tailing it. It must be called before Start.
+func (fr *FileReader) SetOneShot(oneShot bool) {
+	fr.oneShot = oneShot
+}
+
+// SetCheckpoint persists the offset of the last acked line to path, so a
+// reader created with the same path resumes after the entries already
+// handled. Entries read but never acked, because a sink failed or the
+// collector stopped first, are read again. It must be called before Start.
+func (fr *FileReader) SetCheckpoint(path string) {
+	fr.checkpointPath = path
+}
+
+// SetParser parses each line with p instead of the reader's format. Lines
+// p fails on are kept raw. It must be called before Start.
+func (fr *FileReader) SetParser(p Parser) {
+	fr.parser = p
+}
+
+// SetTimestampLayout sets the time.Parse layout for the captured timestamp.
+// Without it a few common layouts are tried.
+func (fr *FileReader) SetTimestampLayout(layout string) {
+	fr.timestampLayout = layout
 }
 
 // Start begins reading the file
@@ -37,79 +283,658 @@ func (fr *FileReader) Start(ctx context.Context, out chan<- *models.LogEntry) er
 	fr.mu.Lock()
 	if fr.running {
 		fr.mu.Unlock()
-		return fmt.Errorf("file reader already running")
+		return sourceError(models.AlreadyRunning, "file reader already running")
+	}
+	// Stop doesn't wait for the loop, which may still be sending an entry
+	if fr.looping {
+		fr.mu.Unlock()
+		return sourceError(models.AlreadyRunning, "file reader still stopping")
 	}
 	fr.running = true
+	fr.looping = true
+
+	// A reader started again after its loop ended needs a fresh run
+	if fr.run.finished() {
+		fr.run = newRunState()
+	}
+	run := fr.run
 	fr.mu.Unlock()
 
-	// Open file
+	switch fr.format {
+	case FormatRaw, FormatJSON, FormatLogfmt:
+	case FormatRegex:
+		if fr.pattern == nil {
+			fr.abandon()
+			if fr.patternErr != nil {
+				return sourceError(models.Unsupported, "invalid pattern: %w", fr.patternErr)
+			}
+			return sourceError(models.Unsupported, "regex format requires a pattern")
+		}
+	case FormatCSV, FormatTSV:
+		if len(fr.columns) == 0 && !fr.skipHeader {
+			fr.abandon()
+			return sourceError(models.Unsupported, "%s format requires columns or a header", fr.format)
+		}
+		if fr.format == FormatCSV && fr.delimiter == 0 {
+			fr.delimiter = ','
+		} else if fr.format == FormatTSV {
+			fr.delimiter = '\t'
+		}
+	default:
+		fr.abandon()
+		return sourceError(models.Unsupported, "unsupported format: %s", fr.format)
+	}
+
+	// A new reader resumes from the checkpoint, a restarted one from where
+	// it got to
+	fr.mu.Lock()
+	resume := fr.checkpointPath != "" && fr.checkpoint == nil
+	fr.mu.Unlock()
+	if resume {
+		cp, err := openCheckpoint(fr.checkpointPath)
+		if err != nil {
+			fr.abandon()
+			return &models.SourceError{Code: models.IOError, Err: err}
+		}
+		fr.mu.Lock()
+		fr.checkpoint = cp
+		fr.offset = cp.offset()
+		fr.mu.Unlock()
+	}
+
+	fr.mu.Lock()
+	offset := fr.offset
+	fr.mu.Unlock()
+
+	file, stream, compressed, err := fr.open(offset)
+	if err != nil {
+		fr.abandon()
+		return err
+	}
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	// Stopped while opening, so there's nothing to read
+	if !fr.running {
+		file.Close()
+		fr.looping = false
+		run.finish(nil)
+		return nil
+	}
+	fr.file = file
+	fr.compressed = compressed
+	fr.headerPending = fr.skipHeader && offset == 0
+	// Reading starts again from the offset, at the start of the line
+	fr.partial, fr.skipped = nil, 0
+
+	go fr.readLoop(ctx, out, run, stream)
+	return nil
+}
+
+// abandon undoes a Start that failed before its loop began
+func (fr *FileReader) abandon() {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.running = false
+	fr.looping = false
+}
+
+// open opens the file positioned at offset, returning the stream to read
+// lines from, which decompresses gzip files
+func (fr *FileReader) open(offset int64) (*os.File, io.Reader, bool, error) {
+	file, err := os.Open(fr.filepath)
+	if err != nil {
+		return nil, nil, false, sourceError(models.IOError, "failed to open file: %w", err)
+	}
+
+	compressed, err := isGzipFile(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, false, &models.SourceError{Code: models.IOError, Err: err}
+	}
+	compressed = compressed || strings.HasSuffix(fr.filepath, ".gz")
+
+	// Resuming past the header still needs the column names from it
+	if fr.skipHeader && len(fr.columns) == 0 && offset > 0 {
+		if err := fr.readHeader(compressed); err != nil {
+			file.Close()
+			return nil, nil, false, &models.SourceError{Code: models.IOError, Err: err}
+		}
+	}
+
+	if !compressed {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, nil, false, sourceError(models.IOError, "failed to seek: %w", err)
+		}
+		return file, file, false, nil
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, false, sourceError(models.IOError, "failed to open gzip stream: %w", err)
+	}
+	// The offset counts uncompressed bytes, so skip rather than seek
+	if _, err := io.CopyN(io.Discard, gz, offset); err != nil {
+		file.Close()
+		return nil, nil, false, sourceError(models.IOError, "failed to skip to offset: %w", err)
+	}
+	return file, gz, true, nil
+}
+
+// readHeader names the columns after the file's first line
+func (fr *FileReader) readHeader(compressed bool) error {
 	file, err := os.Open(fr.filepath)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
-	fr.file = file
+	defer file.Close()
 
-	// Seek to offset
-	if fr.offset > 0 {
-		if _, err := fr.file.Seek(fr.offset, 0); err != nil {
-			return fmt.Errorf("failed to seek: %w", err)
+	var stream io.Reader = file
+	if compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
 		}
+		stream = gz
 	}
 
-	go fr.readLoop(ctx, out)
+	line, err := bufio.NewReader(stream).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	fr.columns, _ = fr.splitDelimited(line)
 	return nil
 }
 
+// isGzipFile reports whether the file starts with the gzip magic bytes,
+// leaving the read position at the start
+func isGzipFile(file *os.File) (bool, error) {
+	magic := make([]byte, 2)
+	n, err := io.ReadFull(file, magic)
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return false, fmt.Errorf("failed to seek: %w", seekErr)
+	}
+	if err != nil && n < len(magic) {
+		// Too short to be gzip, e.g. an empty file about to be written to
+		return false, nil
+	}
+	return magic[0] == 0x1f && magic[1] == 0x8b, nil
+}
+
+// Done is closed once the reader stops. Compressed files and one-shot
+// readers stop by themselves after their last line; plain files are tailed
+// until Stop or cancellation.
+func (fr *FileReader) Done() <-chan struct{} {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.run.done
+}
+
+// Err reports why the reader stopped by itself once Done is closed. It is
+// nil after Stop, cancellation or the end of a compressed or one-shot file.
+func (fr *FileReader) Err() error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.run.Err()
+}
+
 // readLoop continuously reads from file
-func (fr *FileReader) readLoop(ctx context.Context, out chan<- *models.LogEntry) {
+func (fr *FileReader) readLoop(ctx context.Context, out chan<- *models.LogEntry, run *runState, stream io.Reader) {
+	var exitErr error
+	defer func() {
+		fr.mu.Lock()
+		fr.looping = false
+		fr.mu.Unlock()
+		run.finish(exitErr)
+	}()
 	defer fr.Stop()
 
-	reader := bufio.NewReader(fr.file)
-	ticker := time.NewTicker(fr.pollPeriod)
-	defer ticker.Stop()
+	reader := bufio.NewReader(stream)
+
+	// Files that won't grow are read straight through without polling
+	if fr.finite() {
+		_, exitErr = fr.readLines(ctx, out, reader)
+		return
+	}
+
+	poll := newPoller(fr.poll)
+	wait := poll.next(true)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			// Try to read lines
-			for {
-				line, err := reader.ReadString('\n')
-				if err != nil {
-					if err == io.EOF {
-						// No more data, wait for next tick
-						break
-					}
-					// Log error but continue
-					fmt.Printf("Error reading file: %v\n", err)
+		case <-fr.clock.After(wait):
+			before := fr.position()
+			more, err := fr.readLines(ctx, out, reader)
+			if !more {
+				exitErr = err
+				return
+			}
+			active := fr.position() != before
+
+			// Caught up with the current file, see if it was rotated under us
+			if file := fr.checkRotation(); file != nil {
+				active = true
+				// The old file won't finish a line it left without a newline
+				if len(fr.partial) > 0 && !fr.emitHeld(ctx, out) {
 					return
 				}
-
-				// Update offset
 				fr.mu.Lock()
-				fr.offset += int64(len(line))
+				fr.restart()
 				fr.mu.Unlock()
+				reader.Reset(file)
+			}
+			wait = poll.next(active)
+		}
+	}
+}
+
+// position is how far the read loop has got, counting an unfinished line
+func (fr *FileReader) position() int64 {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.offset + int64(len(fr.partial)+fr.skipped)
+}
 
-				// Create log entry (simple parsing for now)
-				entry := fr.parseSimpleLine(line)
+// finite reports whether the reader stops at the end of the file rather
+// than tailing it
+func (fr *FileReader) finite() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.compressed || fr.oneShot
+}
 
-				select {
-				case out <- entry:
-				case <-ctx.Done():
-					return
-				}
+// readLines emits lines until it catches up with the file. It returns false
+// once the reader should stop, with the error that killed it if any.
+func (fr *FileReader) readLines(ctx context.Context, out chan<- *models.LogEntry, reader *bufio.Reader) (bool, error) {
+	for {
+		piece, err := reader.ReadSlice('\n')
+		fr.hold(piece)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && fr.finite() {
+			// Emit the last line even without a trailing newline and finish
+			if len(fr.partial) > 0 {
+				fr.emitHeld(ctx, out)
+			}
+			return false, nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				// No more data, keep any unfinished line until the rest of
+				// it is written and wait for next tick
+				return true, nil
+			}
+			fmt.Printf("Error reading file: %v\n", err)
+			fr.stats.recordError()
+			// Stop closes the file, any other failure kills the reader
+			if fr.isRunning() {
+				return false, sourceError(models.IOError, "failed to read file: %w", err)
+			}
+			return false, nil
+		}
+
+		if !fr.emitHeld(ctx, out) {
+			return false, nil
+		}
+	}
+}
+
+// hold adds a piece read of the current line, keeping no more than
+// maxFileLineSize bytes of the line
+func (fr *FileReader) hold(piece []byte) {
+	room := max(maxFileLineSize-len(fr.partial), 0)
+	if len(piece) > room {
+		fr.skipped += len(piece) - room
+		piece = piece[:room]
+	}
+	fr.partial = append(fr.partial, piece...)
+}
+
+// emitHeld emits the line held so far and starts a new one. A line cut to
+// maxFileLineSize is marked truncated. Returns false if ctx is done.
+func (fr *FileReader) emitHeld(ctx context.Context, out chan<- *models.LogEntry) bool {
+	line, skipped := string(fr.partial), fr.skipped
+	fr.partial, fr.skipped = fr.partial[:0], 0
+	return fr.emit(ctx, out, line, skipped)
+}
+
+// emit records a line, of which skipped bytes past its end were cut off, and
+// sends its entry. Returns false if ctx is done.
+func (fr *FileReader) emit(ctx context.Context, out chan<- *models.LogEntry, line string, skipped int) bool {
+	// Update offset
+	fr.mu.Lock()
+	fr.offset += int64(len(line) + skipped)
+	header := fr.headerPending
+	fr.headerPending = false
+	ack := func() {}
+	if fr.checkpoint != nil {
+		ack = fr.checkpoint.track(fr.offset)
+	}
+	fr.mu.Unlock()
+
+	if header {
+		fr.stats.recordBytes(len(line))
+		if len(fr.columns) == 0 {
+			fr.columns, _ = fr.splitDelimited(line)
+		}
+		ack()
+		return true
+	}
+
+	entry, err := fr.tryParseLine(line)
+	if err != nil && fr.deadLetter != nil {
+		fr.stats.recordBytes(len(line))
+		if dlErr := fr.deadLetter.Record(entry.Source, []byte(line), err); dlErr != nil {
+			// Better passed on raw than lost
+			fmt.Printf("⚠️  %v\n", dlErr)
+		} else {
+			ack()
+			return true
+		}
+	}
+	if fr.levels != nil && entry.Level == models.LevelInfo {
+		if level, ok := fr.levels.Infer(entry.Message); ok {
+			entry.Level = level
+		}
+	}
+	if fr.keepRaw {
+		keepRaw(entry, line)
+	}
+	if skipped > 0 {
+		if entry.Fields == nil {
+			entry.Fields = make(map[string]interface{})
+		}
+		entry.Fields[FieldTruncated] = true
+		entry.Fields[FieldOriginalLength] = len(line) + skipped
+	}
+	fr.labels.apply(entry)
+	if fr.parsePool {
+		markUnparsed(entry)
+	}
+	entry.OnAck(ack)
+	metrics.EntriesReceived.WithLabelValues(fr.Name()).Inc()
+	metrics.BytesReceived.WithLabelValues(fr.Name()).Add(int64(len(line) + skipped))
+	fr.stats.recordBytes(len(line) + skipped)
+	fr.stats.recordEntry()
+
+	select {
+	case out <- entry:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// checkRotation detects logrotate-style rotation (path now points to a
+// different inode) and truncation (file shrank below our offset). It
+// returns the file to read from the beginning, leaving the caller to
+// restart.
+// It returns the file to continue reading from, or nil if nothing changed.
+func (fr *FileReader) checkRotation() *os.File {
+	pathInfo, err := os.Stat(fr.filepath)
+	if err != nil {
+		// Path may be missing mid-rotation, check again on next tick
+		return nil
+	}
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	// Stop closed the file, don't open another behind its back
+	if !fr.running {
+		return nil
+	}
+	fileInfo, err := fr.file.Stat()
+	if err != nil {
+		return nil
+	}
+
+	// Rotated: reopen the new file from the beginning
+	if !os.SameFile(pathInfo, fileInfo) {
+		file, err := os.Open(fr.filepath)
+		if err != nil {
+			return nil
+		}
+		fr.file.Close()
+		fr.file = file
+		return file
+	}
+
+	// Truncated: start over from the beginning of the same file
+	if pathInfo.Size() < fr.offset {
+		if _, err := fr.file.Seek(0, io.SeekStart); err != nil {
+			return nil
+		}
+		return fr.file
+	}
+
+	return nil
+}
+
+// restart rewinds to the beginning of a rotated or truncated file. The
+// caller holds fr.mu.
+func (fr *FileReader) restart() {
+	fr.offset = 0
+	fr.headerPending = fr.skipHeader
+	if fr.checkpoint != nil {
+		fr.checkpoint.reset()
+	}
+}
+
+// Why lines the regex and delimited formats can't map were passed on raw
+var (
+	errNoMatch      = errors.New("line doesn't match the pattern")
+	errBadDelimited = errors.New("line is badly quoted")
+)
+
+// parseLine converts a line into a log entry with the parser, or according
+// to the configured format without one
+func (fr *FileReader) parseLine(line string) *models.LogEntry {
+	entry, _ := fr.tryParseLine(line)
+	return entry
+}
+
+// tryParseLine is parseLine that also returns why a line the format
+// should have parsed failed, along with the raw entry to fall back on.
+// Blank lines fall back without an error.
+func (fr *FileReader) tryParseLine(line string) (*models.LogEntry, error) {
+	var err error
+	if fr.parser != nil {
+		var entry *models.LogEntry
+		if entry, err = tryParse(fr.parser, []byte(line), fr.filepath); err == nil {
+			return entry, nil
+		}
+	} else {
+		switch fr.format {
+		case FormatJSON:
+			var entry *models.LogEntry
+			if entry, err = tryParse(fr.json, []byte(line), fr.filepath); err == nil {
+				return entry, nil
 			}
+		case FormatLogfmt:
+			var entry *models.LogEntry
+			if entry, err = tryParse(fr.logfmt, []byte(line), fr.filepath); err == nil {
+				return entry, nil
+			}
+		case FormatRegex:
+			if entry, ok := fr.parseRegexLine(line); ok {
+				return entry, nil
+			}
+			err = errNoMatch
+		case FormatCSV, FormatTSV:
+			if entry, ok := fr.parseDelimitedLine(line); ok {
+				return entry, nil
+			}
+			err = errBadDelimited
 		}
 	}
+
+	// Raw format, or a line that failed to parse
+	entry, _ := RawParser{}.Parse([]byte(line), fr.filepath)
+	if strings.TrimSpace(line) == "" {
+		err = nil
+	}
+	return entry, err
 }
 
-// parseSimpleLine does basic parsing (we'll improve this later)
-func (fr *FileReader) parseSimpleLine(line string) *models.LogEntry {
+// parseRegexLine maps the named groups of the configured pattern into a log
+// entry. Returns false if the line doesn't match.
+func (fr *FileReader) parseRegexLine(line string) (*models.LogEntry, bool) {
+	trimmed := strings.TrimRight(line, "\r\n")
+
+	match := fr.pattern.FindStringSubmatch(trimmed)
+	if match == nil {
+		return nil, false
+	}
+
 	entry := models.NewLogEntry()
 	entry.Source = fr.filepath
-	entry.Message = line
-	return entry
+	entry.Message = trimmed
+	hasMessage := false
+
+	for i, name := range fr.pattern.SubexpNames() {
+		value := match[i]
+		if name == "" || value == "" {
+			continue
+		}
+		if fr.setNamedValue(entry, name, value) {
+			hasMessage = true
+		}
+	}
+
+	// Without a message group the whole line is the message
+	if !hasMessage {
+		entry.Message = trimmed
+	}
+	if _, ok := entry.Fields[FieldRawTimestamp]; !ok {
+		markIngestTime(entry)
+	}
+
+	return entry, true
+}
+
+// setNamedValue maps a captured group or column onto the entry. Level,
+// message, source and timestamp go to the entry itself, anything else, or a
+// value that can't be used, to Fields. Reports whether it set the message.
+func (fr *FileReader) setNamedValue(entry *models.LogEntry, name, value string) bool {
+	switch name {
+	case "level":
+		if level, err := models.ParseLevel(value); err == nil {
+			entry.Level = level
+			return false
+		}
+	case "message":
+		entry.Message = value
+		return true
+	case "source":
+		entry.Source = value
+		return false
+	case "timestamp":
+		if ts, ok := fr.parseTimestamp(value); ok {
+			setEventTime(entry, ts, value)
+			return false
+		}
+	}
+	entry.Fields[name] = value
+	return false
+}
+
+// parseTimestamp parses a captured timestamp with the configured layout, or
+// the default layouts if none is set
+func (fr *FileReader) parseTimestamp(value string) (time.Time, bool) {
+	var layouts []string
+	if fr.timestampLayout != "" {
+		layouts = []string{fr.timestampLayout}
+	}
+	return parseTimestampLayouts(value, layouts)
+}
+
+// parseTimestampLayouts tries each layout in order, or the default layouts
+// if there are none. Times without a zone are local.
+func parseTimestampLayouts(value string, layouts []string) (time.Time, bool) {
+	if len(layouts) == 0 {
+		layouts = defaultTimestampLayouts
+	}
+
+	for _, layout := range layouts {
+		ts, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+		// Layouts without a year parse as year 0
+		return assumeYear(ts, time.Now()), true
+	}
+	return time.Time{}, false
+}
+
+// parseJSONTimestamp parses a JSON timestamp: a string in one of layouts,
+// or a number since the epoch. Strings of digits count as numbers.
+func parseJSONTimestamp(value interface{}, layouts []string) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		if ts, ok := parseTimestampLayouts(v, layouts); ok {
+			return ts, true
+		}
+		if !isDecimal(v) {
+			return time.Time{}, false
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return epochTime(n), true
+	case float64:
+		return epochTime(v), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// epochTime converts a number since the epoch. Its magnitude tells the
+// unit: seconds up to 1e11 (the year 5138), then milliseconds, microseconds
+// and nanoseconds.
+func epochTime(v float64) time.Time {
+	scale := 1.0
+	switch abs := math.Abs(v); {
+	case abs >= 1e17:
+		scale = 1e9
+	case abs >= 1e14:
+		scale = 1e6
+	case abs >= 1e11:
+		scale = 1e3
+	}
+	sec, frac := math.Modf(v / scale)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9)))
+}
+
+// isDecimal reports whether s is digits with an optional fraction
+func isDecimal(s string) bool {
+	digits, dot := 0, false
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+// jsonTimestampText renders a JSON timestamp value as it appeared in the line
+func jsonTimestampText(value interface{}) string {
+	if v, ok := value.(float64); ok {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
 }
 
 // Stop stops the reader
@@ -122,10 +947,20 @@ func (fr *FileReader) Stop() error {
 	}
 
 	fr.running = false
-	if fr.file != nil {
-		return fr.file.Close()
+	if fr.file == nil {
+		// Still opening, Start finds it stopped and lets go of the file
+		return nil
 	}
-	return nil
+	err := fr.file.Close()
+	fr.file = nil
+	return err
+}
+
+// isRunning reports whether Stop hasn't been called since Start
+func (fr *FileReader) isRunning() bool {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.running
 }
 
 // Name returns the source name
@@ -133,6 +968,11 @@ func (fr *FileReader) Name() string {
 	return fmt.Sprintf("file:%s", fr.filepath)
 }
 
+// Stats returns a snapshot of the reader's activity
+func (fr *FileReader) Stats() models.SourceStats {
+	return fr.stats.snapshot()
+}
+
 // GetOffset returns current offset
 func (fr *FileReader) GetOffset() int64 {
 	fr.mu.Lock()
@@ -1,12 +1,26 @@
 package sources
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/internal/pipeline"
 	"github.com/fatihserhatturan/logflux/pkg/models"
 )
 
@@ -103,3 +117,1348 @@ func TestFileReader_ContinuousReading(t *testing.T) {
 		t.Fatal("timeout reading appended line")
 	}
 }
+
+func TestFileReader_JSONFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.log")
+
+	content := `{"level":"error","message":"db down","source":"api","ts":"2024-01-02T15:04:05Z","user_id":42}
+{"msg":"partial entry","request":"abc"}
+{"level":"verbose","message":"unknown level"}
+{"level":"warn","message":"broken
+plain text line
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReaderWithFormat(testFile, FormatJSON)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []*models.LogEntry
+	timeout := time.After(1 * time.Second)
+
+	for i := 0; i < 5; i++ {
+		select {
+		case entry := <-out:
+			entries = append(entries, entry)
+		case <-timeout:
+			t.Fatalf("timeout waiting for entries, got %d", len(entries))
+		}
+	}
+
+	// Full JSON line
+	first := entries[0]
+	if first.Level != models.LevelError {
+		t.Errorf("Expected ERROR level, got %s", first.Level)
+	}
+	if first.Message != "db down" {
+		t.Errorf("Expected message 'db down', got %q", first.Message)
+	}
+	if first.Source != "api" {
+		t.Errorf("Expected source 'api', got %q", first.Source)
+	}
+	expectedTS := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	if !first.Timestamp.Equal(expectedTS) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTS, first.Timestamp)
+	}
+	if first.Fields["user_id"] != float64(42) {
+		t.Errorf("Expected user_id field 42, got %v", first.Fields["user_id"])
+	}
+	if _, ok := first.Fields["level"]; ok {
+		t.Error("Known keys should not be copied into Fields")
+	}
+	if first.Fields[FieldRawTimestamp] != "2024-01-02T15:04:05Z" {
+		t.Errorf("Expected raw timestamp field, got %v", first.Fields[FieldRawTimestamp])
+	}
+	if _, ok := first.Fields[FieldTimestampSource]; ok {
+		t.Error("Extracted timestamp should not be flagged as ingest time")
+	}
+
+	// Partial JSON: msg alias, default level and source
+	second := entries[1]
+	if second.Message != "partial entry" {
+		t.Errorf("Expected message 'partial entry', got %q", second.Message)
+	}
+	if second.Level != models.LevelInfo {
+		t.Errorf("Expected INFO level, got %s", second.Level)
+	}
+	if second.Source != testFile {
+		t.Errorf("Expected source %q, got %q", testFile, second.Source)
+	}
+	if second.Fields["request"] != "abc" {
+		t.Errorf("Expected request field 'abc', got %v", second.Fields["request"])
+	}
+	if second.Fields[FieldTimestampSource] != TimestampSourceIngest {
+		t.Errorf("Expected timestamp_source=ingest, got %v", second.Fields[FieldTimestampSource])
+	}
+
+	// Unknown level falls back to INFO
+	if entries[2].Level != models.LevelInfo {
+		t.Errorf("Expected INFO for unknown level, got %s", entries[2].Level)
+	}
+
+	// Malformed JSON and plain text are kept as raw lines
+	if entries[3].Message != "{\"level\":\"warn\",\"message\":\"broken\n" {
+		t.Errorf("Expected raw malformed line, got %q", entries[3].Message)
+	}
+	if entries[4].Message != "plain text line\n" {
+		t.Errorf("Expected raw plain line, got %q", entries[4].Message)
+	}
+	if entries[4].Fields[FieldTimestampSource] != TimestampSourceIngest {
+		t.Errorf("Expected timestamp_source=ingest for raw line, got %v", entries[4].Fields[FieldTimestampSource])
+	}
+}
+
+func TestFileReader_JSONTimestamps(t *testing.T) {
+	custom := []string{"02/01/2006 15:04:05 MST", time.RFC1123Z}
+	expected := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	millis := time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC)
+
+	tests := []struct {
+		name     string
+		layouts  []string
+		line     string
+		expected time.Time // zero when the timestamp can't be parsed
+	}{
+		{"RFC 3339", nil, `{"ts": "2024-01-02T15:04:05Z"}`, expected},
+		{"RFC 3339 with offset", nil, `{"ts": "2024-01-02T18:04:05+03:00"}`, expected},
+		{"epoch seconds", nil, `{"ts": 1704207845}`, expected},
+		{"epoch seconds with fraction", nil, `{"ts": 1704207845.123}`, millis},
+		{"epoch millis", nil, `{"timestamp": 1704207845123}`, millis},
+		{"epoch micros", nil, `{"ts": 1704207845123000}`, millis},
+		{"epoch nanos", nil, `{"ts": 1704207845123000000}`, millis},
+		{"epoch string", nil, `{"ts": "1704207845123"}`, millis},
+		{"first custom layout", custom, `{"ts": "02/01/2024 15:04:05 UTC"}`, expected},
+		{"second custom layout", custom, `{"ts": "Tue, 02 Jan 2024 18:04:05 +0300"}`, expected},
+		{"custom layouts replace the defaults", custom, `{"ts": "2024-01-02T15:04:05Z"}`, time.Time{}},
+		{"numbers with custom layouts", custom, `{"ts": 1704207845}`, expected},
+		{"unknown format", nil, `{"ts": "yesterday"}`, time.Time{}},
+		{"wrong type", nil, `{"ts": true}`, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewFileReaderWithJSON("app.log", JSONOptions{TimestampLayouts: tt.layouts})
+			before := time.Now()
+			entry := reader.parseLine(tt.line)
+
+			if tt.expected.IsZero() {
+				if entry.Timestamp.Before(before) {
+					t.Errorf("Expected the ingest time, got %v", entry.Timestamp)
+				}
+				if entry.Fields[FieldTimestampUnparsed] != true || entry.Fields[FieldTimestampSource] != TimestampSourceIngest {
+					t.Errorf("Expected the entry flagged, got %v", entry.Fields)
+				}
+				return
+			}
+
+			if entry.Timestamp.Sub(tt.expected).Abs() > time.Microsecond {
+				t.Errorf("Expected %v, got %v", tt.expected, entry.Timestamp.UTC())
+			}
+			if _, ok := entry.Fields[FieldTimestampUnparsed]; ok {
+				t.Errorf("Expected no unparsed flag, got %v", entry.Fields)
+			}
+		})
+	}
+}
+
+func TestFileReader_JSONParser(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.log")
+
+	content := `{"level":"error","message":"db down","user_id":42}
+plain text line
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	reader.SetParser(JSONParser{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	first := receiveEntry(t, out)
+	if first.Message != "db down" || first.Level != models.LevelError || first.Source != testFile {
+		t.Errorf("Expected the JSON line parsed, got %q %s from %q", first.Message, first.Level, first.Source)
+	}
+	if first.Fields["user_id"] != float64(42) {
+		t.Errorf("Expected user_id in fields, got %v", first.Fields)
+	}
+
+	second := receiveEntry(t, out)
+	if second.Message != "plain text line\n" || second.Level != models.LevelInfo {
+		t.Errorf("Expected the plain line kept raw, got %q %s", second.Message, second.Level)
+	}
+}
+
+func TestFileReader_LogfmtFormat(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "app.log")
+	content := `time=2024-01-02T15:04:05Z level=ERROR msg="db down" user=42
+not logfmt at all
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReaderWithLogfmt(testFile, LogfmtOptions{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	first := receiveEntry(t, out)
+	if first.Message != "db down" || first.Level != models.LevelError || first.Fields["user"] != "42" {
+		t.Errorf("Expected the logfmt line parsed, got %q %s with %v", first.Message, first.Level, first.Fields)
+	}
+	if !first.Timestamp.Equal(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected the line's time, got %v", first.Timestamp)
+	}
+
+	second := receiveEntry(t, out)
+	if second.Message != "not logfmt at all\n" {
+		t.Errorf("Expected the plain line kept raw, got %q", second.Message)
+	}
+}
+
+func TestFileReader_UnsupportedFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+	if err := os.WriteFile(testFile, []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReaderWithFormat(testFile, "xml")
+
+	out := make(chan *models.LogEntry, 1)
+	if err := reader.Start(context.Background(), out); err == nil {
+		t.Fatal("Expected error for unsupported format")
+	}
+}
+
+func TestFileReader_RegexFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "access.log")
+
+	content := `127.0.0.1 - frank [10/Oct/2023:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+10.0.0.5 - - [10/Oct/2023:13:55:37 -0700] "POST /login HTTP/1.1" 401 12
+not an access log line
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pattern := `^(?P<client>\S+) \S+ (?P<user>\S+) \[(?P<timestamp>[^\]]+)\] "(?P<message>(?P<method>\S+) (?P<path>\S+) \S+)" (?P<status>\d{3}) (?P<bytes>\d+|-)$`
+	reader := NewFileReaderWithRegex(testFile, pattern)
+	reader.SetTimestampLayout("02/Jan/2006:15:04:05 -0700")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []*models.LogEntry
+	timeout := time.After(1 * time.Second)
+	for i := 0; i < 3; i++ {
+		select {
+		case entry := <-out:
+			entries = append(entries, entry)
+		case <-timeout:
+			t.Fatalf("timeout waiting for entries, got %d", len(entries))
+		}
+	}
+
+	first := entries[0]
+	if first.Message != "GET /apache_pb.gif HTTP/1.0" {
+		t.Errorf("Expected request line as message, got %q", first.Message)
+	}
+	expectedTS := time.Date(2023, 10, 10, 20, 55, 36, 0, time.UTC)
+	if !first.Timestamp.Equal(expectedTS) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTS, first.Timestamp)
+	}
+	expectedFields := map[string]string{
+		"client": "127.0.0.1",
+		"user":   "frank",
+		"method": "GET",
+		"path":   "/apache_pb.gif",
+		"status": "200",
+		"bytes":  "2326",
+	}
+	for name, value := range expectedFields {
+		if first.Fields[name] != value {
+			t.Errorf("Expected field %s=%q, got %v", name, value, first.Fields[name])
+		}
+	}
+	if _, ok := first.Fields["timestamp"]; ok {
+		t.Error("Parsed timestamp should not be copied into Fields")
+	}
+	if first.Source != testFile {
+		t.Errorf("Expected source %q, got %q", testFile, first.Source)
+	}
+
+	if entries[1].Fields["status"] != "401" {
+		t.Errorf("Expected status 401, got %v", entries[1].Fields["status"])
+	}
+
+	// Lines that don't match are kept raw
+	if entries[2].Message != "not an access log line\n" {
+		t.Errorf("Expected raw unmatched line, got %q", entries[2].Message)
+	}
+}
+
+func TestFileReader_RegexParseLine(t *testing.T) {
+	pattern := `^(?P<timestamp>\S+ \S+) \[(?P<level>\w+)\] (?P<source>[\w.-]+): (?P<message>.*)$`
+
+	tests := []struct {
+		name      string
+		layout    string
+		line      string
+		level     models.LogLevel
+		source    string
+		message   string
+		timestamp time.Time
+		fields    map[string]interface{}
+	}{
+		{
+			name:      "default layouts",
+			line:      "2024-01-02 15:04:05 [ERROR] db: connection refused\n",
+			level:     models.LevelError,
+			source:    "db",
+			message:   "connection refused",
+			timestamp: time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local),
+		},
+		{
+			name:      "custom layout",
+			layout:    "02.01.2006 15:04",
+			line:      "02.01.2024 15:04 [warn] api: slow request",
+			level:     models.LevelWarning,
+			source:    "api",
+			message:   "slow request",
+			timestamp: time.Date(2024, 1, 2, 15, 4, 0, 0, time.Local),
+		},
+		{
+			name:    "unparseable values kept in fields",
+			layout:  "2006-01-02",
+			line:    "yesterday noon [LOUD] api: hello",
+			level:   models.LevelInfo,
+			source:  "api",
+			message: "hello",
+			fields:  map[string]interface{}{"timestamp": "yesterday noon", "level": "LOUD"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewFileReaderWithRegex("app.log", pattern)
+			reader.SetTimestampLayout(tt.layout)
+
+			entry := reader.parseLine(tt.line)
+			if entry.Level != tt.level {
+				t.Errorf("Expected level %s, got %s", tt.level, entry.Level)
+			}
+			if entry.Source != tt.source {
+				t.Errorf("Expected source %q, got %q", tt.source, entry.Source)
+			}
+			if entry.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, entry.Message)
+			}
+			if !tt.timestamp.IsZero() && !entry.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("Expected timestamp %v, got %v", tt.timestamp, entry.Timestamp)
+			}
+			for name, value := range tt.fields {
+				if entry.Fields[name] != value {
+					t.Errorf("Expected field %s=%v, got %v", name, value, entry.Fields[name])
+				}
+			}
+		})
+	}
+}
+
+func TestFileReader_InvalidRegex(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+	if err := os.WriteFile(testFile, []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReaderWithRegex(testFile, `(?P<level>[`)
+
+	out := make(chan *models.LogEntry, 1)
+	if err := reader.Start(context.Background(), out); err == nil {
+		t.Fatal("Expected error for invalid pattern")
+	}
+}
+
+// writeGzip writes content gzip-compressed to path
+func writeGzip(t *testing.T, path string, content string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileReader_CSVFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "events.csv")
+
+	content := `time,severity,msg,user
+2023-10-10T13:55:36Z,ERROR,"disk full, retrying",alice
+2023-10-10T13:55:37Z,info,"said ""hi""",bob,extra
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReaderWithDelimited(testFile, ',', []string{"timestamp", "level", "message", "user"})
+	reader.SetSkipHeader(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []*models.LogEntry
+	timeout := time.After(1 * time.Second)
+	for i := 0; i < 2; i++ {
+		select {
+		case entry := <-out:
+			entries = append(entries, entry)
+		case <-timeout:
+			t.Fatalf("timeout waiting for entries, got %d", len(entries))
+		}
+	}
+
+	first := entries[0]
+	if first.Message != "disk full, retrying" {
+		t.Errorf("Expected the quoted comma kept in the message, got %q", first.Message)
+	}
+	if first.Level != models.LevelError {
+		t.Errorf("Expected ERROR level, got %v", first.Level)
+	}
+	expectedTS := time.Date(2023, 10, 10, 13, 55, 36, 0, time.UTC)
+	if !first.Timestamp.Equal(expectedTS) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTS, first.Timestamp)
+	}
+	if first.Fields["user"] != "alice" {
+		t.Errorf("Expected user=alice, got %v", first.Fields["user"])
+	}
+
+	second := entries[1]
+	if second.Message != `said "hi"` {
+		t.Errorf("Expected escaped quotes unescaped, got %q", second.Message)
+	}
+	if second.Fields["column_5"] != "extra" {
+		t.Errorf("Expected the extra value as column_5, got %v", second.Fields)
+	}
+
+	select {
+	case entry := <-out:
+		t.Errorf("Expected the header to be skipped, got extra entry %q", entry.Message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestFileReader_DelimitedParseLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		delimiter rune
+		columns   []string
+		line      string
+		message   string
+		level     models.LogLevel
+		fields    map[string]interface{}
+	}{
+		{
+			name:      "tsv",
+			delimiter: '\t',
+			columns:   []string{"level", "service", "message"},
+			line:      "warn\tapi\tslow \"query\", 2s\n",
+			message:   `slow "query", 2s`,
+			level:     models.LevelWarning,
+			fields:    map[string]interface{}{"service": "api"},
+		},
+		{
+			name:      "semicolons",
+			delimiter: ';',
+			columns:   []string{"level", "message"},
+			line:      `debug;"a;b"`,
+			message:   "a;b",
+			level:     models.LevelDebug,
+			fields:    map[string]interface{}{},
+		},
+		{
+			name:      "no message column",
+			delimiter: ',',
+			columns:   []string{"host", "", "status"},
+			line:      "web-1,skipped,200",
+			message:   "web-1,skipped,200",
+			level:     models.LevelInfo,
+			fields:    map[string]interface{}{"host": "web-1", "status": "200"},
+		},
+		{
+			name:      "bad quoting falls back to raw",
+			delimiter: ',',
+			columns:   []string{"level", "message"},
+			line:      `error,"unterminated`,
+			message:   `error,"unterminated`,
+			level:     models.LevelInfo,
+			fields:    map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewFileReaderWithDelimited("test.csv", tt.delimiter, tt.columns)
+			if tt.delimiter != '\t' {
+				reader.delimiter = tt.delimiter
+			}
+
+			entry := reader.parseLine(tt.line)
+			if entry.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, entry.Message)
+			}
+			if entry.Level != tt.level {
+				t.Errorf("Expected level %v, got %v", tt.level, entry.Level)
+			}
+			for name, value := range tt.fields {
+				if entry.Fields[name] != value {
+					t.Errorf("Expected field %s=%v, got %v", name, value, entry.Fields[name])
+				}
+			}
+			if _, ok := entry.Fields[""]; ok {
+				t.Error("Expected unnamed columns to be dropped")
+			}
+		})
+	}
+}
+
+func TestFileReader_DelimitedHeaderColumns(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "events.tsv")
+
+	content := "level\tmessage\tregion\nerror\tboom\teu\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReaderWithDelimited(testFile, '\t', nil)
+	reader.SetSkipHeader(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case entry := <-out:
+		if entry.Message != "boom" || entry.Level != models.LevelError || entry.Fields["region"] != "eu" {
+			t.Errorf("Expected columns named by the header, got %q %v %v", entry.Message, entry.Level, entry.Fields)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("timeout waiting for entry")
+	}
+}
+
+func TestFileReader_DelimitedRequiresColumns(t *testing.T) {
+	reader := NewFileReaderWithDelimited(filepath.Join(t.TempDir(), "a.csv"), ',', nil)
+	err := reader.Start(context.Background(), make(chan *models.LogEntry, 1))
+	if err == nil {
+		reader.Stop()
+		t.Fatal("Expected an error without columns or a header")
+	}
+}
+
+// switchSink fails every write while failing is set
+type switchSink struct {
+	failing atomic.Bool
+	written atomic.Int64
+}
+
+func (s *switchSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	if s.failing.Load() {
+		return errors.New("sink unavailable")
+	}
+	s.written.Add(1)
+	return nil
+}
+
+func (s *switchSink) Flush() error { return nil }
+func (s *switchSink) Name() string { return "switch" }
+
+// readThroughPipeline runs a checkpointed reader over path into a pipeline
+// writing to sink until want entries were processed, then stops the reader
+func readThroughPipeline(t *testing.T, path, checkpoint string, sink *switchSink, want int64) {
+	t.Helper()
+
+	reader := NewFileReader(path)
+	reader.SetCheckpoint(checkpoint)
+	p := pipeline.NewPipeline([]collector.Sink{sink})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Processed() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %d entries, got %d", want, p.Processed())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	reader.Stop()
+	cancel()
+	p.Stop()
+}
+
+func TestFileReader_CheckpointOnAck(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.log")
+	checkpoint := filepath.Join(tmpDir, "app.ckpt")
+
+	if err := os.WriteFile(testFile, []byte("line 1\nline 2\nline 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sink fails, so nothing is acked and the checkpoint stays put
+	sink := &switchSink{}
+	sink.failing.Store(true)
+	readThroughPipeline(t, testFile, checkpoint, sink, 3)
+
+	cp, err := openCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.offset() != 0 {
+		t.Errorf("Expected the checkpoint not to advance past failed writes, got %d", cp.offset())
+	}
+
+	// A restarted reader reads the same lines again, and this time they land
+	sink.failing.Store(false)
+	readThroughPipeline(t, testFile, checkpoint, sink, 3)
+	if got := sink.written.Load(); got != 3 {
+		t.Errorf("Expected all 3 lines re-read and written, got %d", got)
+	}
+
+	cp, err = openCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.offset() != 21 {
+		t.Errorf("Expected the checkpoint at the end of the file, got %d", cp.offset())
+	}
+
+	// Only lines added since are read after another restart
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("line 4\n")
+	f.Close()
+
+	readThroughPipeline(t, testFile, checkpoint, sink, 1)
+	time.Sleep(200 * time.Millisecond)
+	if got := sink.written.Load(); got != 4 {
+		t.Errorf("Expected only the new line written, got %d in total", got)
+	}
+}
+
+func TestFileReader_Gzip(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+	}{
+		{"gz extension", "app.log.gz"},
+		{"magic bytes only", "app.log.1"},
+	}
+
+	content := "line 1\nline 2\nline 3 without newline"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), tt.filename)
+			writeGzip(t, testFile, content)
+
+			reader := NewFileReader(testFile)
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			out := make(chan *models.LogEntry, 10)
+			if err := reader.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case <-reader.Done():
+			case <-time.After(time.Second):
+				t.Fatal("Expected reader to stop after the end of the compressed file")
+			}
+
+			close(out)
+			var messages []string
+			for entry := range out {
+				messages = append(messages, entry.Message)
+			}
+
+			expected := []string{"line 1\n", "line 2\n", "line 3 without newline"}
+			if len(messages) != len(expected) {
+				t.Fatalf("Expected %d entries, got %d: %q", len(expected), len(messages), messages)
+			}
+			for i, msg := range expected {
+				if messages[i] != msg {
+					t.Errorf("Entry %d: expected %q, got %q", i, msg, messages[i])
+				}
+			}
+
+			// Offset tracks the uncompressed stream
+			if reader.GetOffset() != int64(len(content)) {
+				t.Errorf("Expected offset %d, got %d", len(content), reader.GetOffset())
+			}
+		})
+	}
+}
+
+func TestFileReader_OneShot(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "finished.log")
+	content := "line 1\nline 2\nline 3 without newline"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReaderOneShot(testFile)
+	// Polling would hold the read back well past the test's timeout
+	reader.poll.Period = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-reader.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected reader to stop at the end of the file")
+	}
+	if err := reader.Err(); err != nil {
+		t.Errorf("Expected a clean finish, got %v", err)
+	}
+
+	close(out)
+	var messages []string
+	for entry := range out {
+		messages = append(messages, entry.Message)
+	}
+	expected := []string{"line 1\n", "line 2\n", "line 3 without newline"}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d: %q", len(expected), len(messages), messages)
+	}
+	for i, msg := range expected {
+		if messages[i] != msg {
+			t.Errorf("Entry %d: expected %q, got %q", i, msg, messages[i])
+		}
+	}
+	if reader.GetOffset() != int64(len(content)) {
+		t.Errorf("Expected offset %d, got %d", len(content), reader.GetOffset())
+	}
+}
+
+func TestFileReader_PartialLine(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(testFile, []byte("first\nincomplete"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	if entry := receiveEntry(t, out); entry.Message != "first\n" {
+		t.Errorf("Expected %q, got %q", "first\n", entry.Message)
+	}
+
+	// Held back until the rest of the line is written
+	select {
+	case entry := <-out:
+		t.Fatalf("Expected the unfinished line held back, got %q", entry.Message)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(" line\nnext\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for _, expected := range []string{"incomplete line\n", "next\n"} {
+		if entry := receiveEntry(t, out); entry.Message != expected {
+			t.Errorf("Expected %q, got %q", expected, entry.Message)
+		}
+	}
+	if reader.GetOffset() != int64(len("first\nincomplete line\nnext\n")) {
+		t.Errorf("Expected the offset at the end of the file, got %d", reader.GetOffset())
+	}
+}
+
+func TestFileReader_PartialLineBeforeRotation(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(testFile, []byte("incomplete"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	// Give the reader a tick to see the unfinished line, then rotate
+	time.Sleep(300 * time.Millisecond)
+	if err := os.Rename(testFile, testFile+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(testFile, []byte("fresh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"incomplete", "fresh\n"} {
+		if entry := receiveEntry(t, out); entry.Message != expected {
+			t.Errorf("Expected %q, got %q", expected, entry.Message)
+		}
+	}
+	if reader.GetOffset() != int64(len("fresh\n")) {
+		t.Errorf("Expected the offset in the new file, got %d", reader.GetOffset())
+	}
+}
+
+func TestFileReader_TruncatesLongLines(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "app.log")
+	long := strings.Repeat("x", maxFileLineSize+100) + "\n"
+	if err := os.WriteFile(testFile, []byte(long+"next\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	entry := receiveEntry(t, out)
+	if len(entry.Message) != maxFileLineSize {
+		t.Errorf("Expected the line cut to %d bytes, got %d", maxFileLineSize, len(entry.Message))
+	}
+	if entry.Fields[FieldTruncated] != true {
+		t.Errorf("Expected the entry marked truncated, got %v", entry.Fields)
+	}
+	if entry.Fields[FieldOriginalLength] != len(long) {
+		t.Errorf("Expected original length %d, got %v", len(long), entry.Fields[FieldOriginalLength])
+	}
+
+	next := receiveEntry(t, out)
+	if next.Message != "next\n" {
+		t.Errorf("Expected %q, got %q", "next\n", next.Message)
+	}
+	if _, ok := next.Fields[FieldTruncated]; ok {
+		t.Error("Expected the next line left whole")
+	}
+	if reader.GetOffset() != int64(len(long+"next\n")) {
+		t.Errorf("Expected the offset at the end of the file, got %d", reader.GetOffset())
+	}
+}
+
+func TestFileReader_GzipInvalid(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "broken.log.gz")
+	if err := os.WriteFile(testFile, []byte("not gzip at all\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	out := make(chan *models.LogEntry, 1)
+	if err := reader.Start(context.Background(), out); err == nil {
+		t.Fatal("Expected error for invalid gzip file")
+	}
+}
+
+func TestFileReader_Rotation(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("line 1\nline 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+	collect := func(n int) {
+		timeout := time.After(2 * time.Second)
+		for i := 0; i < n; i++ {
+			select {
+			case entry := <-out:
+				messages = append(messages, entry.Message)
+			case <-timeout:
+				t.Fatalf("timeout waiting for entries, got %v", messages)
+			}
+		}
+	}
+
+	collect(2)
+
+	// Rotate like logrotate: rename, then recreate the same path
+	if err := os.Rename(testFile, testFile+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(testFile, []byte("line 3\nline 4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	collect(2)
+
+	expected := []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"}
+	for i, msg := range expected {
+		if messages[i] != msg {
+			t.Errorf("Entry %d: expected %q, got %q", i, msg, messages[i])
+		}
+	}
+}
+
+func TestFileReader_Truncation(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("a long first line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-out:
+	case <-time.After(1 * time.Second):
+		t.Fatal("timeout reading first line")
+	}
+
+	// Truncate in place (copytruncate) and write a shorter line
+	if err := os.WriteFile(testFile, []byte("short\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case entry := <-out:
+		if entry.Message != "short\n" {
+			t.Errorf("Expected %q, got %q", "short\n", entry.Message)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout reading line after truncation")
+	}
+}
+
+func TestFileReader_ConcurrentAccess(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "busy.log")
+	if err := os.WriteFile(testFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	reader.poll.Period = 5 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 1000)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers watch the offset while the loop advances it
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var last int64
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				offset := reader.GetOffset()
+				if offset < last && offset != 0 {
+					t.Errorf("Expected the offset to only grow between rotations, went from %d to %d", last, offset)
+					return
+				}
+				last = offset
+				reader.Stats()
+				reader.Err()
+			}
+		}()
+	}
+
+	// Appends, with a rotation halfway through to swap the file under them
+	const lines = 200
+	for i := 0; i < lines; i++ {
+		if i == lines/2 {
+			// Wait for the first half so none of it is left in the old file
+			for reader.GetOffset() < int64(lines/2*len("line 000\n")) {
+				time.Sleep(time.Millisecond)
+			}
+			if err := os.Rename(testFile, testFile+".1"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, err := os.OpenFile(testFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(f, "line %03d\n", i)
+		f.Close()
+	}
+
+	for i := 0; i < lines; i++ {
+		receiveEntry(t, out)
+	}
+	if err := reader.Stop(); err != nil {
+		t.Errorf("Expected a clean stop, got %v", err)
+	}
+	close(stop)
+	wg.Wait()
+
+	select {
+	case <-reader.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the loop to end after Stop")
+	}
+	if offset := reader.GetOffset(); offset != int64(lines/2*len("line 000\n")) {
+		t.Errorf("Expected the offset at the end of the new file, got %d", offset)
+	}
+}
+
+func TestFileReader_StopWhileStarting(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < 50; i++ {
+		reader := NewFileReader(testFile)
+		reader.poll.Period = time.Millisecond
+		out := make(chan *models.LogEntry, 10)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			reader.Start(ctx, out)
+		}()
+		go func() {
+			defer wg.Done()
+			reader.Stop()
+		}()
+		wg.Wait()
+		reader.Stop()
+
+		// However the race went the reader ends up stopped for good
+		select {
+		case <-reader.Done():
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the reader to finish")
+		}
+		if err := reader.Start(ctx, out); err != nil {
+			t.Fatalf("Expected a restart after the loop ended, got %v", err)
+		}
+		reader.Stop()
+	}
+}
+
+func TestFileReader_Metrics(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "metrics.log")
+
+	content := "line 1\nline 2\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	entries := metrics.EntriesReceived.WithLabelValues(reader.Name())
+	bytesRead := metrics.BytesReceived.WithLabelValues(reader.Name())
+	entriesBefore, bytesBefore := entries.Value(), bytesRead.Value()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-out:
+		case <-time.After(1 * time.Second):
+			t.Fatal("timeout waiting for entries")
+		}
+	}
+
+	if got := entries.Value() - entriesBefore; got != 2 {
+		t.Errorf("Expected entries counter +2, got +%d", got)
+	}
+	if got := bytesRead.Value() - bytesBefore; got != int64(len(content)) {
+		t.Errorf("Expected bytes counter +%d, got +%d", len(content), got)
+	}
+
+	// The scrape output should carry the per-source series
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	series := fmt.Sprintf(`logflux_entries_received_total{source="file:%s"} %d`, testFile, entries.Value())
+	if !strings.Contains(rec.Body.String(), series) {
+		t.Errorf("Expected %q in scrape output", series)
+	}
+}
+
+func TestFileReader_KeepRaw(t *testing.T) {
+	lines := []string{
+		`{"level":"error","message":"disk full"}` + "\n",
+		`{"message": "spaced",  "user": "bob"}` + "\r\n",
+		"not json at all\n",
+	}
+	testFile := filepath.Join(t.TempDir(), "raw.log")
+	if err := os.WriteFile(testFile, []byte(strings.Join(lines, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keep := range []bool{true, false} {
+		t.Run(fmt.Sprintf("keep=%v", keep), func(t *testing.T) {
+			reader := NewFileReaderWithFormat(testFile, FormatJSON)
+			reader.SetKeepRaw(keep)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			out := make(chan *models.LogEntry, 10)
+			if err := reader.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Stop()
+
+			for _, line := range lines {
+				entry := receiveEntry(t, out)
+				raw, ok := entry.Fields[FieldRaw]
+				if !keep {
+					if ok {
+						t.Errorf("Expected no raw field by default, got %q", raw)
+					}
+					continue
+				}
+				if raw != line {
+					t.Errorf("Expected raw %q, got %q", line, raw)
+				}
+			}
+		})
+	}
+}
+
+func TestFileReader_LevelRules(t *testing.T) {
+	lines := "connection refused: error 111\nWARNING disk at 91%\nrequest served\n"
+	testFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(testFile, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	reader.SetLevelRules(models.DefaultLevelRules)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	for _, expected := range []models.LogLevel{models.LevelError, models.LevelWarning, models.LevelInfo} {
+		if entry := receiveEntry(t, out); entry.Level != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, entry.Message, entry.Level)
+		}
+	}
+}
+
+func TestFileReader_ParsePool(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(testFile, []byte(`{"message":"left raw"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewFileReaderWithFormat(testFile, FormatJSON).SetParsePool(true); err == nil {
+		t.Error("Expected JSON lines not to be left for the parse pool")
+	}
+
+	reader := NewFileReader(testFile)
+	if err := reader.SetParsePool(true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	entry := receiveEntry(t, out)
+	if entry.Message != `{"message":"left raw"}`+"\n" || entry.Fields[FieldUnparsed] != true {
+		t.Errorf("Expected the raw line marked unparsed, got %q %v", entry.Message, entry.Fields)
+	}
+}
+
+func TestFileReader_DeadLetter(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.log")
+	dlqFile := filepath.Join(tmpDir, "dlq.jsonl")
+
+	content := `{"level":"error","message":"db down"}
+{"level":"warn","message":
+plain text line
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := sinks.NewFileSink(dlqFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl := collector.NewDeadLetter(sink)
+
+	reader := NewFileReader(testFile)
+	reader.SetParser(JSONParser{})
+	reader.SetDeadLetter(dl)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry := receiveEntry(t, out); entry.Message != "db down" {
+		t.Errorf("Expected the valid line emitted, got %q", entry.Message)
+	}
+	select {
+	case entry := <-out:
+		t.Errorf("Expected malformed lines kept out of the pipeline, got %q", entry.Message)
+	case <-time.After(200 * time.Millisecond):
+	}
+	reader.Stop()
+
+	if err := dl.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dlqFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 dead-lettered lines, got %d: %s", len(lines), data)
+	}
+	for i, expected := range []string{"{\"level\":\"warn\",\"message\":\n", "plain text line\n"} {
+		var entry models.LogEntry
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Message != expected || entry.Source != testFile {
+			t.Errorf("Expected %q from %q, got %q from %q", expected, testFile, entry.Message, entry.Source)
+		}
+		if reason, _ := entry.Fields[collector.FieldParseError].(string); reason == "" {
+			t.Errorf("Expected the parse error recorded for %q, got %v", expected, entry.Fields)
+		}
+		if entry.Timestamp.IsZero() {
+			t.Errorf("Expected a timestamp for %q", expected)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package sources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+const (
+	// gelfChunkHeaderSize is the magic bytes, 8-byte message ID, sequence and count
+	gelfChunkHeaderSize = 12
+
+	// gelfMaxChunks is the most chunks a GELF message may be split into
+	gelfMaxChunks = 128
+
+	// gelfChunkTimeout is how long an incomplete chunked message is kept
+	gelfChunkTimeout = 5 * time.Second
+
+	// gelfMaxMessageSize caps a reassembled or decompressed message
+	gelfMaxMessageSize = 8 << 20
+
+	// gelfMaxPending and gelfMaxPendingBytes cap the chunked messages
+	// waiting for the rest of their chunks, and the bytes they hold. The
+	// oldest is dropped to make room for a new one.
+	gelfMaxPending      = 1024
+	gelfMaxPendingBytes = 64 << 20
+)
+
+// gelfChunkMagic prefixes every chunk of a chunked GELF message
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+// gelfChunks collects the chunks of one message
+type gelfChunks struct {
+	parts    [][]byte
+	received int
+	size     int
+	started  time.Time
+}
+
+// GELFReceiver receives Graylog Extended Log Format messages over UDP
+type GELFReceiver struct {
+	addr string
+
+	mu      sync.Mutex
+	conn    *net.UDPConn
+	running bool
+	wg      sync.WaitGroup
+
+	// pending holds partially received chunked messages by message ID,
+	// pendingBytes the chunks they hold. Only the read loop touches them.
+	pending      map[[8]byte]*gelfChunks
+	pendingBytes int
+	evicted      atomic.Int64
+
+	labels  SourceLabels
+	keepRaw bool
+	stats   sourceStats
+}
+
+// NewGELFReceiver creates a new GELF receiver
+func NewGELFReceiver(addr string) *GELFReceiver {
+	return &GELFReceiver{
+		addr:    addr,
+		pending: make(map[[8]byte]*gelfChunks),
+	}
+}
+
+// SetLabels names the source and labels every entry from it. It must
+// be called before Start.
+func (gr *GELFReceiver) SetLabels(labels SourceLabels) {
+	gr.labels = labels
+}
+
+// SetKeepRaw keeps each message's JSON exactly as received, decompressed,
+// in FieldRaw. It must be called before Start.
+func (gr *GELFReceiver) SetKeepRaw(keep bool) {
+	gr.keepRaw = keep
+}
+
+// Start begins listening for GELF messages
+func (gr *GELFReceiver) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	if gr.running {
+		return fmt.Errorf("GELF receiver already running")
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", gr.addr)
+	if err != nil {
+		return fmt.Errorf("failed to resolve UDP address: %w", err)
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on UDP: %w", err)
+	}
+
+	gr.conn = conn
+	gr.running = true
+
+	fmt.Printf("📡 GELF receiver listening on UDP %s\n", gr.addr)
+
+	gr.wg.Add(1)
+	go gr.readUDP(ctx, conn, out)
+
+	return nil
+}
+
+// readUDP reads datagrams until the context is cancelled or the connection closes
+func (gr *GELFReceiver) readUDP(ctx context.Context, conn *net.UDPConn, out chan<- *models.LogEntry) {
+	defer gr.wg.Done()
+	defer conn.Close()
+
+	// Large enough for the biggest UDP datagram
+	buffer := make([]byte, 65536)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		// Set read deadline to allow checking context
+		conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+
+		n, _, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				gr.expireChunks(time.Now())
+				continue
+			}
+			// Stop closed the connection
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			fmt.Printf("Error reading GELF: %v\n", err)
+			gr.stats.recordError()
+			continue
+		}
+
+		metrics.BytesReceived.WithLabelValues(gr.Name()).Add(int64(n))
+		gr.stats.recordBytes(n)
+
+		payload, complete := gr.assemble(buffer[:n], time.Now())
+		if !complete {
+			continue
+		}
+
+		data, err := decompressGELF(payload)
+		if err != nil {
+			fmt.Printf("Invalid GELF message: %v\n", err)
+			gr.stats.recordError()
+			continue
+		}
+		entry, err := parseGELF(data)
+		if err != nil {
+			fmt.Printf("Invalid GELF message: %v\n", err)
+			gr.stats.recordError()
+			continue
+		}
+		if gr.keepRaw {
+			keepRaw(entry, string(data))
+		}
+
+		metrics.EntriesReceived.WithLabelValues(gr.Name()).Inc()
+		gr.stats.recordEntry()
+		gr.labels.apply(entry)
+
+		select {
+		case out <- entry:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// assemble returns the full payload once all chunks of a message have
+// arrived. Unchunked datagrams are returned as-is.
+func (gr *GELFReceiver) assemble(datagram []byte, now time.Time) ([]byte, bool) {
+	if !bytes.HasPrefix(datagram, gelfChunkMagic) {
+		return append([]byte(nil), datagram...), true
+	}
+
+	if len(datagram) < gelfChunkHeaderSize {
+		gr.stats.recordError()
+		return nil, false
+	}
+
+	var id [8]byte
+	copy(id[:], datagram[2:10])
+	seq, count := int(datagram[10]), int(datagram[11])
+	if count == 0 || count > gelfMaxChunks || seq >= count {
+		gr.stats.recordError()
+		return nil, false
+	}
+
+	gr.expireChunks(now)
+
+	chunks, ok := gr.pending[id]
+	if !ok {
+		if len(gr.pending) >= gelfMaxPending {
+			gr.evictOldest()
+		}
+		chunks = &gelfChunks{parts: make([][]byte, count), started: now}
+		gr.pending[id] = chunks
+	}
+	if len(chunks.parts) != count {
+		// Conflicting chunk counts for the same ID, drop the message
+		gr.dropPending(id)
+		gr.stats.recordError()
+		return nil, false
+	}
+	if chunks.parts[seq] != nil {
+		return nil, false
+	}
+
+	data := datagram[gelfChunkHeaderSize:]
+	if chunks.size+len(data) > gelfMaxMessageSize {
+		gr.dropPending(id)
+		gr.stats.recordError()
+		return nil, false
+	}
+
+	chunks.parts[seq] = append([]byte(nil), data...)
+	chunks.received++
+	chunks.size += len(data)
+	gr.pendingBytes += len(data)
+
+	if chunks.received < count {
+		for gr.pendingBytes > gelfMaxPendingBytes {
+			gr.evictOldest()
+		}
+		return nil, false
+	}
+
+	gr.dropPending(id)
+	return bytes.Join(chunks.parts, nil), true
+}
+
+// dropPending forgets a chunked message
+func (gr *GELFReceiver) dropPending(id [8]byte) {
+	if chunks, ok := gr.pending[id]; ok {
+		gr.pendingBytes -= chunks.size
+		delete(gr.pending, id)
+	}
+}
+
+// evictOldest drops the chunked message that started longest ago, counting
+// it as evicted
+func (gr *GELFReceiver) evictOldest() {
+	var oldest *gelfChunks
+	var oldestID [8]byte
+	for id, chunks := range gr.pending {
+		if oldest == nil || chunks.started.Before(oldest.started) {
+			oldest, oldestID = chunks, id
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	if gr.evicted.Add(1) == 1 {
+		fmt.Printf("⚠️  Too many incomplete GELF messages pending on %s, dropping the oldest\n", gr.addr)
+	}
+	gr.dropPending(oldestID)
+	gr.stats.recordError()
+}
+
+// expireChunks drops chunked messages that never completed
+func (gr *GELFReceiver) expireChunks(now time.Time) {
+	for id, chunks := range gr.pending {
+		if now.Sub(chunks.started) > gelfChunkTimeout {
+			gr.dropPending(id)
+			gr.stats.recordError()
+		}
+	}
+}
+
+// Evicted returns how many incomplete chunked messages were dropped to stay
+// within the pending limits
+func (gr *GELFReceiver) Evicted() int64 {
+	return gr.evicted.Load()
+}
+
+// decompressGELF inflates zlib and gzip payloads, detected by their magic bytes
+func decompressGELF(payload []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+
+	switch {
+	case len(payload) >= 2 && payload[0] == 0x1f && payload[1] == 0x8b:
+		reader, err = gzip.NewReader(bytes.NewReader(payload))
+	case len(payload) >= 2 && payload[0] == 0x78 && (uint16(payload[0])<<8|uint16(payload[1]))%31 == 0:
+		reader, err = zlib.NewReader(bytes.NewReader(payload))
+	default:
+		return payload, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	defer reader.Close()
+
+	// Read one byte past the limit to detect oversized payloads
+	data, err := io.ReadAll(io.LimitReader(reader, gelfMaxMessageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	if len(data) > gelfMaxMessageSize {
+		return nil, fmt.Errorf("decompressed payload exceeds %d bytes", gelfMaxMessageSize)
+	}
+	return data, nil
+}
+
+// parseGELF decodes a decompressed GELF payload into a log entry.
+// short_message becomes the message, level uses syslog severity numbering
+// and additional "_name" fields are stored without the underscore.
+func parseGELF(data []byte) (*models.LogEntry, error) {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	shortMessage, ok := msg["short_message"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing short_message")
+	}
+
+	entry := models.NewLogEntry()
+	entry.Source = "gelf"
+	entry.Message = shortMessage
+
+	for key, value := range msg {
+		switch key {
+		case "version", "short_message":
+		case "host", "full_message", "facility", "file", "line":
+			entry.Fields[key] = value
+		case "level":
+			if severity, ok := gelfLevel(value); ok {
+				entry.Level = severityLevel(severity)
+			}
+		case "timestamp":
+			if ts, ok := gelfTimestamp(value); ok {
+				setEventTime(entry, ts, strconv.FormatFloat(value.(float64), 'f', -1, 64))
+			}
+		default:
+			// "_id" is reserved by the spec and is ignored
+			if name, ok := strings.CutPrefix(key, "_"); ok && name != "" && name != "id" {
+				entry.Fields[name] = value
+			}
+		}
+	}
+
+	if _, ok := entry.Fields[FieldRawTimestamp]; !ok {
+		markIngestTime(entry)
+	}
+
+	return entry, nil
+}
+
+// gelfLevel reads a syslog severity level (0-7)
+func gelfLevel(value interface{}) (int, bool) {
+	level, ok := value.(float64)
+	if !ok || level < 0 || level > 7 || level != math.Trunc(level) {
+		return 0, false
+	}
+	return int(level), true
+}
+
+// gelfTimestamp reads seconds since the epoch with optional fractional part
+func gelfTimestamp(value interface{}) (time.Time, bool) {
+	seconds, ok := value.(float64)
+	if !ok || seconds <= 0 {
+		return time.Time{}, false
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(math.Round(frac*1e6))*1e3).UTC(), true
+}
+
+// Stop stops the receiver
+func (gr *GELFReceiver) Stop() error {
+	gr.mu.Lock()
+	if !gr.running {
+		gr.mu.Unlock()
+		return nil
+	}
+	gr.running = false
+	if gr.conn != nil {
+		gr.conn.Close()
+	}
+	gr.mu.Unlock()
+
+	// Wait for the read loop
+	gr.wg.Wait()
+
+	return nil
+}
+
+// Addr returns the address the receiver is listening on. Before Start it
+// returns the configured address.
+func (gr *GELFReceiver) Addr() string {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	if gr.conn != nil {
+		return gr.conn.LocalAddr().String()
+	}
+	return gr.addr
+}
+
+// Stats returns a snapshot of the receiver's activity
+func (gr *GELFReceiver) Stats() models.SourceStats {
+	return gr.stats.snapshot()
+}
+
+// Name returns the source name
+func (gr *GELFReceiver) Name() string {
+	return fmt.Sprintf("gelf:udp@%s", gr.addr)
+}
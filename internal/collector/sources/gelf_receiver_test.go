@@ -0,0 +1,265 @@
+package sources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// startGELF starts a receiver on a random port and returns a connected client
+func startGELF(t *testing.T) (*GELFReceiver, net.Conn, chan *models.LogEntry) {
+	t.Helper()
+
+	receiver := NewGELFReceiver("127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { receiver.Stop() })
+
+	conn, err := net.Dial("udp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return receiver, conn, out
+}
+
+// gelfChunk builds one chunk datagram
+func gelfChunk(id string, seq, count int, data []byte) []byte {
+	chunk := append([]byte{0x1e, 0x0f}, id...)
+	chunk = append(chunk, byte(seq), byte(count))
+	return append(chunk, data...)
+}
+
+func receiveEntry(t *testing.T, out <-chan *models.LogEntry) *models.LogEntry {
+	t.Helper()
+	select {
+	case entry := <-out:
+		return entry
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for log entry")
+		return nil
+	}
+}
+
+func TestGELFReceiver_Single(t *testing.T) {
+	receiver, conn, out := startGELF(t)
+
+	msg := `{"version": "1.1", "host": "web-01", "short_message": "Disk almost full", ` +
+		`"full_message": "Disk /dev/sda1 at 95%", "timestamp": 1385053862.3072, "level": 4, ` +
+		`"_user_id": 9001, "_service": "storage", "_id": "ignored"}`
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := receiveEntry(t, out)
+
+	if entry.Message != "Disk almost full" {
+		t.Errorf("Expected short_message as message, got %q", entry.Message)
+	}
+	if entry.Level != models.LevelWarning {
+		t.Errorf("Expected WARNING level, got %s", entry.Level)
+	}
+	if entry.Source != "gelf" {
+		t.Errorf("Expected source 'gelf', got %q", entry.Source)
+	}
+	expectedTS := time.Date(2013, 11, 21, 17, 11, 2, 307200000, time.UTC)
+	if !entry.Timestamp.Equal(expectedTS) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTS, entry.Timestamp)
+	}
+
+	fields := map[string]interface{}{
+		"host":         "web-01",
+		"full_message": "Disk /dev/sda1 at 95%",
+		"user_id":      float64(9001),
+		"service":      "storage",
+	}
+	for key, value := range fields {
+		if entry.Fields[key] != value {
+			t.Errorf("Expected field %s=%v, got %v", key, value, entry.Fields[key])
+		}
+	}
+	if _, ok := entry.Fields["id"]; ok {
+		t.Error("Expected reserved _id to be ignored")
+	}
+
+	if got := receiver.Stats().EntriesProduced; got != 1 {
+		t.Errorf("Expected 1 entry produced, got %d", got)
+	}
+}
+
+func TestGELFReceiver_Chunked(t *testing.T) {
+	_, conn, out := startGELF(t)
+
+	msg := []byte(`{"version": "1.1", "host": "batch-07", "short_message": "Job finished", "level": 6, "_rows": 120000}`)
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(msg)
+	zw.Close()
+	payload := compressed.Bytes()
+
+	// Split into three chunks and send them out of order
+	size := len(payload)/3 + 1
+	var chunks [][]byte
+	for seq := 0; seq < 3; seq++ {
+		end := (seq + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunks = append(chunks, gelfChunk("msgid001", seq, 3, payload[seq*size:end]))
+	}
+	for _, seq := range []int{2, 0, 1} {
+		if _, err := conn.Write(chunks[seq]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entry := receiveEntry(t, out)
+
+	if entry.Message != "Job finished" {
+		t.Errorf("Expected reassembled message, got %q", entry.Message)
+	}
+	if entry.Level != models.LevelInfo {
+		t.Errorf("Expected INFO level, got %s", entry.Level)
+	}
+	if entry.Fields["rows"] != float64(120000) {
+		t.Errorf("Expected rows field, got %v", entry.Fields["rows"])
+	}
+}
+
+func TestGELFReceiver_Gzip(t *testing.T) {
+	_, conn, out := startGELF(t)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(`{"version": "1.1", "host": "h", "short_message": "gzipped", "level": 3}`))
+	gz.Close()
+
+	if _, err := conn.Write(compressed.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := receiveEntry(t, out)
+	if entry.Message != "gzipped" || entry.Level != models.LevelError {
+		t.Errorf("Unexpected entry %q at %s", entry.Message, entry.Level)
+	}
+}
+
+func TestGELFReceiver_KeepRaw(t *testing.T) {
+	receiver := NewGELFReceiver("127.0.0.1:0")
+	receiver.SetKeepRaw(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	conn, err := net.Dial("udp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	message := `{"version": "1.1", "host": "h", "short_message": "kept", "_user": "alice"}`
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(message))
+	gz.Close()
+	if _, err := conn.Write(compressed.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The JSON is kept as sent, only decompressed
+	entry := receiveEntry(t, out)
+	if raw := entry.Fields[FieldRaw]; raw != message {
+		t.Errorf("Expected raw %q, got %q", message, raw)
+	}
+}
+
+func TestGELFReceiver_IncompleteChunksExpire(t *testing.T) {
+	receiver := NewGELFReceiver("127.0.0.1:0")
+
+	now := time.Now()
+	if _, ok := receiver.assemble(gelfChunk("msgid002", 0, 2, []byte(`{"short_`)), now); ok {
+		t.Fatal("Expected message to wait for the second chunk")
+	}
+	if len(receiver.pending) != 1 {
+		t.Fatalf("Expected 1 pending message, got %d", len(receiver.pending))
+	}
+
+	receiver.expireChunks(now.Add(gelfChunkTimeout + time.Second))
+	if len(receiver.pending) != 0 {
+		t.Errorf("Expected incomplete message to expire, got %d pending", len(receiver.pending))
+	}
+	if got := receiver.Stats().Errors; got != 1 {
+		t.Errorf("Expected expired message counted as an error, got %d", got)
+	}
+}
+
+func TestGELFReceiver_PendingCapped(t *testing.T) {
+	receiver := NewGELFReceiver("127.0.0.1:0")
+
+	// A sender spraying first chunks of new messages
+	now := time.Now()
+	for i := 0; i <= gelfMaxPending; i++ {
+		id := fmt.Sprintf("id%06d", i)
+		receiver.assemble(gelfChunk(id, 0, 2, []byte("{")), now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if len(receiver.pending) != gelfMaxPending {
+		t.Errorf("Expected %d pending messages, got %d", gelfMaxPending, len(receiver.pending))
+	}
+	var first [8]byte
+	copy(first[:], "id000000")
+	if _, ok := receiver.pending[first]; ok {
+		t.Error("Expected the oldest message dropped")
+	}
+	if receiver.Evicted() != 1 {
+		t.Errorf("Expected 1 evicted, got %d", receiver.Evicted())
+	}
+
+	// Large chunks are held to a total size as well
+	receiver = NewGELFReceiver("127.0.0.1:0")
+	data := make([]byte, 4<<20)
+	for i := 0; i < gelfMaxPendingBytes/len(data)+1; i++ {
+		id := fmt.Sprintf("id%06d", i)
+		receiver.assemble(gelfChunk(id, 0, 2, data), now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if receiver.pendingBytes > gelfMaxPendingBytes {
+		t.Errorf("Expected at most %d bytes pending, got %d", gelfMaxPendingBytes, receiver.pendingBytes)
+	}
+	if receiver.Evicted() != 1 {
+		t.Errorf("Expected 1 evicted, got %d", receiver.Evicted())
+	}
+}
+
+func TestParseGELF_Invalid(t *testing.T) {
+	tests := []string{
+		`not json`,
+		`{"version": "1.1", "host": "h"}`,
+		`{"short_message": 42}`,
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseGELF([]byte(input)); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		})
+	}
+}
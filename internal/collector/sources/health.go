@@ -0,0 +1,152 @@
+package sources
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthStatus ranks how well the collector, or a part of it, is doing
+type HealthStatus string
+
+// Health statuses, from best to worst. /health answers 503 when unhealthy.
+const (
+	HealthHealthy   HealthStatus = "healthy"
+	HealthDegraded  HealthStatus = "degraded"
+	HealthUnhealthy HealthStatus = "unhealthy"
+)
+
+// Thresholds of the built-in checks
+const (
+	// ChannelDegradedFill is the fill ratio at which a channel is degraded
+	ChannelDegradedFill = 0.8
+
+	// SinkDegradedErrorRate and SinkUnhealthyErrorRate are the recent
+	// sink write error ratios at which sinks are degraded or unhealthy
+	SinkDegradedErrorRate  = 0.1
+	SinkUnhealthyErrorRate = 0.5
+)
+
+// worse reports whether s is worse than other
+func (s HealthStatus) worse(other HealthStatus) bool {
+	rank := map[HealthStatus]int{HealthHealthy: 0, HealthDegraded: 1, HealthUnhealthy: 2}
+	return rank[s] > rank[other]
+}
+
+// ComponentHealth is one component's part of the /health summary
+type ComponentHealth struct {
+	Status HealthStatus `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+}
+
+// HealthCheck reports the current health of one part of the collector
+type HealthCheck func() ComponentHealth
+
+// healthReport is the JSON shape of /health responses
+type healthReport struct {
+	Status     HealthStatus               `json:"status"`
+	Time       string                     `json:"time"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// healthChecks holds named checks. The overall status is the worst of
+// them.
+type healthChecks struct {
+	mu     sync.Mutex
+	checks map[string]HealthCheck
+}
+
+func (h *healthChecks) set(name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.checks == nil {
+		h.checks = make(map[string]HealthCheck)
+	}
+	h.checks[name] = check
+}
+
+// report runs every check
+func (h *healthChecks) report() healthReport {
+	h.mu.Lock()
+	checks := make(map[string]HealthCheck, len(h.checks))
+	names := make([]string, 0, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+		names = append(names, name)
+	}
+	h.mu.Unlock()
+	sort.Strings(names)
+
+	report := healthReport{
+		Status:     HealthHealthy,
+		Time:       time.Now().Format(time.RFC3339),
+		Components: make(map[string]ComponentHealth, len(names)),
+	}
+	for _, name := range names {
+		result := checks[name]()
+		report.Components[name] = result
+		if result.Status.worse(report.Status) {
+			report.Status = result.Status
+		}
+	}
+	return report
+}
+
+// ChannelFillCheck reports degraded when the channel or buffer that fill
+// describes is at least ChannelDegradedFill full, since its producers are
+// about to be held back or dropped
+func ChannelFillCheck(fill func() (used, size int)) HealthCheck {
+	return func() ComponentHealth {
+		used, size := fill()
+		if size <= 0 {
+			return ComponentHealth{Status: HealthHealthy, Detail: "unbuffered"}
+		}
+
+		ratio := float64(used) / float64(size)
+		status := HealthHealthy
+		if ratio >= ChannelDegradedFill {
+			status = HealthDegraded
+		}
+		return ComponentHealth{Status: status, Detail: fmt.Sprintf("%.0f%% full (%d/%d)", ratio*100, used, size)}
+	}
+}
+
+// SinkErrorCheck reports degraded or unhealthy when the share of recent
+// sink writes and sends that failed reaches SinkDegradedErrorRate or
+// SinkUnhealthyErrorRate
+func SinkErrorCheck(errorRate func() float64) HealthCheck {
+	return func() ComponentHealth {
+		rate := errorRate()
+		status := HealthHealthy
+		switch {
+		case rate >= SinkUnhealthyErrorRate:
+			status = HealthUnhealthy
+		case rate >= SinkDegradedErrorRate:
+			status = HealthDegraded
+		}
+		return ComponentHealth{Status: status, Detail: fmt.Sprintf("%.0f%% of recent writes failed", rate*100)}
+	}
+}
+
+// StalenessCheck reports degraded when nothing was received for longer than
+// window. lastActivity returns the zero time until something arrives, which
+// counts from when the check was created.
+func StalenessCheck(lastActivity func() time.Time, window time.Duration) HealthCheck {
+	created := time.Now()
+	return func() ComponentHealth {
+		last := lastActivity()
+		if last.IsZero() {
+			if time.Since(created) > window {
+				return ComponentHealth{Status: HealthDegraded, Detail: fmt.Sprintf("nothing received in %s", window)}
+			}
+			return ComponentHealth{Status: HealthHealthy, Detail: "nothing received yet"}
+		}
+
+		idle := time.Since(last).Truncate(time.Second)
+		if idle > window {
+			return ComponentHealth{Status: HealthDegraded, Detail: fmt.Sprintf("nothing received for %s", idle)}
+		}
+		return ComponentHealth{Status: HealthHealthy, Detail: fmt.Sprintf("last entry %s ago", idle)}
+	}
+}
@@ -0,0 +1,47 @@
+package sources
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    HealthCheck
+		expected HealthStatus
+	}{
+		{"channel with room", ChannelFillCheck(func() (int, int) { return 79, 100 }), HealthHealthy},
+		{"channel filling up", ChannelFillCheck(func() (int, int) { return 80, 100 }), HealthDegraded},
+		{"unbuffered channel", ChannelFillCheck(func() (int, int) { return 0, 0 }), HealthHealthy},
+		{"few sink errors", SinkErrorCheck(func() float64 { return 0.05 }), HealthHealthy},
+		{"some sink errors", SinkErrorCheck(func() float64 { return 0.2 }), HealthDegraded},
+		{"mostly sink errors", SinkErrorCheck(func() float64 { return 0.5 }), HealthUnhealthy},
+		{"recent entry", StalenessCheck(func() time.Time { return time.Now().Add(-time.Second) }, time.Minute), HealthHealthy},
+		{"stale entry", StalenessCheck(func() time.Time { return time.Now().Add(-2 * time.Minute) }, time.Minute), HealthDegraded},
+		{"nothing yet", StalenessCheck(func() time.Time { return time.Time{} }, time.Minute), HealthHealthy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(); got.Status != tt.expected {
+				t.Errorf("Expected %s, got %s (%s)", tt.expected, got.Status, got.Detail)
+			}
+		})
+	}
+}
+
+func TestHealthChecks_WorstWins(t *testing.T) {
+	var checks healthChecks
+	checks.set("a", func() ComponentHealth { return ComponentHealth{Status: HealthDegraded} })
+	checks.set("b", func() ComponentHealth { return ComponentHealth{Status: HealthHealthy} })
+
+	if report := checks.report(); report.Status != HealthDegraded || len(report.Components) != 2 {
+		t.Errorf("Expected degraded with two components, got %+v", report)
+	}
+
+	checks.set("b", func() ComponentHealth { return ComponentHealth{Status: HealthUnhealthy} })
+	if report := checks.report(); report.Status != HealthUnhealthy {
+		t.Errorf("Expected a replaced check to count, got %s", report.Status)
+	}
+}
@@ -1,64 +1,471 @@
 package sources
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
 	"github.com/fatihserhatturan/logflux/pkg/models"
 )
 
+// HTTPReceiverOptions configures an HTTPReceiver
+type HTTPReceiverOptions struct {
+	// SendTimeout is how long a request waits for room in the output channel
+	// before the entry is rejected. Zero rejects immediately when it's full.
+	SendTimeout time.Duration
+
+	// MaxDecompressedSize caps the size of a gzip request body after
+	// decompression, guarding against decompression bombs. Defaults to 10MB.
+	MaxDecompressedSize int64
+
+	// MaxBodyBytes caps a request body as sent, answering 413 beyond it.
+	// On /stream, which may run indefinitely, it caps each line instead.
+	// Defaults to 10MB.
+	MaxBodyBytes int64
+
+	// ReadHeaderTimeout bounds how long a client may take to send the
+	// request headers, so slow clients can't hold connections open.
+	// Defaults to 5s.
+	ReadHeaderTimeout time.Duration
+
+	// AuthToken, when set, must be sent as "Authorization: Bearer <token>"
+	// on the ingest endpoints. Health checks stay open. Browsers can't set
+	// headers on a WebSocket, so the live tail also takes it as a
+	// "bearer.<token>" subprotocol offered next to "logflux", or as the
+	// token query parameter, which ends up in access logs.
+	AuthToken string
+
+	// TailOrigins are the browser origins, e.g. "https://ops.example.com",
+	// allowed to open the live tail besides the receiver's own host. "null"
+	// allows pages opened from disk and "*" allows any origin. Requests
+	// without an Origin header, from non-browser clients, are always
+	// allowed.
+	TailOrigins []string
+
+	// ShutdownTimeout bounds how long Stop waits for in-flight requests to
+	// finish sending their entries. Defaults to 5s. It should be longer than
+	// SendTimeout so waiting requests aren't cut off.
+	ShutdownTimeout time.Duration
+
+	// RejectEmptyMessages answers 400 to entries whose message is empty or
+	// only whitespace instead of accepting them. Off by default.
+	RejectEmptyMessages bool
+
+	// StrictTimestamps answers 400 to entries whose timestamp can't be parsed
+	// or is out of range. Otherwise such entries get the ingest time.
+	StrictTimestamps bool
+
+	// KeepRaw keeps what each entry was read from exactly as received, in
+	// FieldRaw: the body on /logs, the array element on /batch and the
+	// line on /stream. Gzip bodies are kept decompressed.
+	KeepRaw bool
+
+	// Allowlist, when set, answers 403 to requests from outside its
+	// networks, counting them as denied
+	Allowlist *Allowlist
+
+	// TrustedProxies are the proxies whose X-Forwarded-For header names the
+	// client checked against Allowlist. Without them the header is ignored.
+	TrustedProxies *Allowlist
+
+	// PathPrefix mounts every route under a prefix, e.g. "/logflux" serves
+	// /logflux/logs, for receivers behind a gateway
+	PathPrefix string
+
+	// Routes renames the endpoints. Empty names keep the defaults.
+	Routes HTTPRoutes
+
+	// Mux, when set, has the routes registered on it at Start instead of
+	// the receiver listening itself, so it can share an existing server.
+	// The address is then unused and the routes answer 503 while the
+	// receiver is stopped. See Handler for other routers.
+	Mux *http.ServeMux
+}
+
+// HTTPRoutes names the receiver's endpoints, relative to the path prefix
+type HTTPRoutes struct {
+	Logs   string // defaults to "/logs"
+	Batch  string // defaults to "/batch"
+	Stream string // defaults to "/stream"
+	Tail   string // defaults to "/tail"
+	Health string // defaults to "/health"
+	Level  string // defaults to "/config/level"
+}
+
+// withDefaults fills in the default name of every route left empty and
+// puts them all under prefix
+func (r HTTPRoutes) withDefaults(prefix string) HTTPRoutes {
+	route := func(name, def string) string {
+		if name == "" {
+			name = def
+		}
+		return path.Join("/", prefix, name)
+	}
+	return HTTPRoutes{
+		Logs:   route(r.Logs, "/logs"),
+		Batch:  route(r.Batch, "/batch"),
+		Stream: route(r.Stream, "/stream"),
+		Tail:   route(r.Tail, "/tail"),
+		Health: route(r.Health, "/health"),
+		Level:  route(r.Level, "/config/level"),
+	}
+}
+
+// DefaultMaxDecompressedSize is the default cap for decompressed request bodies
+const DefaultMaxDecompressedSize = 10 << 20
+
+// DefaultMaxBodyBytes is the default cap for request bodies
+const DefaultMaxBodyBytes = 10 << 20
+
+// DefaultReadHeaderTimeout is the default time a client has to send headers
+const DefaultReadHeaderTimeout = 5 * time.Second
+
+// DefaultShutdownTimeout is the default time Stop waits for in-flight requests
+const DefaultShutdownTimeout = 5 * time.Second
+
+const (
+	// maxStreamLineSize caps a single NDJSON line on /stream, unless
+	// MaxBodyBytes is lower
+	maxStreamLineSize = 1 << 20
+
+	// idleTimeout is how long a keep-alive connection may sit between
+	// requests
+	idleTimeout = 60 * time.Second
+
+	// maxHeaderBytes caps the request headers
+	maxHeaderBytes = 64 << 10
+
+	// streamIdleTimeout is how long a /stream request may go without a new line
+	streamIdleTimeout = 30 * time.Second
+
+	// tailWriteTimeout bounds a single write to a live-tail viewer
+	tailWriteTimeout = 10 * time.Second
+
+	// tailPingInterval is how often idle live-tail connections are pinged
+	tailPingInterval = 30 * time.Second
+
+	// maxTimestampSkew is how far into the future a payload timestamp may be
+	maxTimestampSkew = 24 * time.Hour
+
+	// levelHeader sets the level of a plain text entry posted to /logs
+	levelHeader = "X-Log-Level"
+)
+
+// minTimestamp is the earliest payload timestamp accepted
+var minTimestamp = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	// tailProtocol is the subprotocol live-tail viewers offer and get back
+	tailProtocol = "logflux"
+
+	// tailTokenProtocol prefixes the auth token in an offered subprotocol
+	tailTokenProtocol = "bearer."
+)
+
+// LevelControl reads and changes the collector's minimum level at runtime
+type LevelControl interface {
+	MinLevel() models.LogLevel
+	SetMinLevel(models.LogLevel)
+}
+
+// levelPayload is the JSON shape of /config/level requests and responses
+type levelPayload struct {
+	MinLevel models.LogLevel `json:"min_level"`
+}
+
+// logPayload is the JSON shape of a log entry posted to the receiver
+type logPayload struct {
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Source    string                 `json:"source"`
+	Fields    map[string]interface{} `json:"fields"`
+	Timestamp json.RawMessage        `json:"timestamp"`
+}
+
+// timestamp parses the optional event time, either an RFC3339 string or
+// epoch milliseconds. It returns the zero time when none was sent.
+func (p *logPayload) timestamp() (time.Time, error) {
+	raw := bytes.TrimSpace(p.Timestamp)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return time.Time{}, nil
+	}
+
+	var ts time.Time
+	if raw[0] == '"' {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return time.Time{}, err
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("expected RFC3339, got %q", value)
+		}
+		ts = parsed
+	} else {
+		millis, err := json.Number(raw).Int64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("expected RFC3339 or epoch milliseconds, got %s", raw)
+		}
+		ts = time.UnixMilli(millis)
+	}
+
+	if ts.Before(minTimestamp) || ts.After(time.Now().Add(maxTimestampSkew)) {
+		return time.Time{}, fmt.Errorf("%s is out of range", ts.UTC().Format(time.RFC3339))
+	}
+	return ts, nil
+}
+
+// validate checks the payload against the receiver's rules and names the
+// field that failed
+func (p *logPayload) validate(opts HTTPReceiverOptions) error {
+	if opts.RejectEmptyMessages && strings.TrimSpace(p.Message) == "" {
+		return fmt.Errorf("message: must not be empty")
+	}
+	if opts.StrictTimestamps {
+		if _, err := p.timestamp(); err != nil {
+			return fmt.Errorf("timestamp: %w", err)
+		}
+	}
+	return nil
+}
+
+// entry converts the payload into a log entry
+func (p *logPayload) entry() *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Message = p.Message
+	entry.Source = p.Source
+	if entry.Source == "" {
+		entry.Source = "http"
+	}
+
+	// Parse level, unknown levels fall back to INFO
+	if level, err := models.ParseLevel(p.Level); err == nil {
+		entry.Level = level
+	}
+
+	if p.Fields != nil {
+		entry.Fields = p.Fields
+	}
+
+	// Invalid timestamps only get this far in lenient mode, where they
+	// fall back to the ingest time
+	if ts, err := p.timestamp(); err == nil && !ts.IsZero() {
+		entry.Timestamp = ts
+	}
+
+	return entry
+}
+
 // HTTPReceiver receives logs via HTTP POST
 type HTTPReceiver struct {
-	addr   string
-	server *http.Server
+	addr     string
+	opts     HTTPReceiverOptions
+	server   *http.Server
+	listener net.Listener
+
+	routes HTTPRoutes
 
 	mu      sync.Mutex
 	running bool
 	out     chan<- *models.LogEntry
+
+	// mounted is set once the routes are on opts.Mux, which can't take
+	// them twice. inflight counts the requests Stop waits for when the
+	// receiver doesn't run its own server.
+	mounted  bool
+	inflight *sync.WaitGroup
+
+	// done is closed once Stop has drained in-flight requests. drainErr
+	// holds the outcome for later Stop calls.
+	done     chan struct{}
+	drainErr error
+
+	// sendMu is held for reading around every send to out. A drain that
+	// times out closes abort, waking sends that wait for room, then sets
+	// aborted under the write lock, so none can start after Done.
+	sendMu  sync.RWMutex
+	abort   chan struct{}
+	aborted bool
+
+	tail     *tailHub
+	upgrader websocket.Upgrader
+	level    LevelControl // serves /config/level when set
+	parser   Parser       // parses plain text bodies when set
+	labels   SourceLabels // names the source and tags every entry
+	allow    func() bool  // rate limit asked before accepting an entry, if set
+	health   healthChecks
+	stats    sourceStats
 }
 
 // NewHTTPReceiver creates a new HTTP receiver
 func NewHTTPReceiver(addr string) *HTTPReceiver {
-	return &HTTPReceiver{
-		addr: addr,
+	return NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{})
+}
+
+// NewHTTPReceiverWithAuth creates an HTTP receiver that requires a bearer token
+func NewHTTPReceiverWithAuth(addr string, token string) *HTTPReceiver {
+	return NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{AuthToken: token})
+}
+
+// NewHTTPReceiverWithOptions creates a new HTTP receiver with custom options
+func NewHTTPReceiverWithOptions(addr string, opts HTTPReceiverOptions) *HTTPReceiver {
+	if opts.MaxDecompressedSize <= 0 {
+		opts.MaxDecompressedSize = DefaultMaxDecompressedSize
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.ReadHeaderTimeout <= 0 {
+		opts.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+
+	hr := &HTTPReceiver{
+		addr:   addr,
+		opts:   opts,
+		routes: opts.Routes.withDefaults(opts.PathPrefix),
+		done:   make(chan struct{}),
+		abort:  make(chan struct{}),
+		tail:   newTailHub(),
+	}
+	hr.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		Subprotocols:    []string{tailProtocol},
+		CheckOrigin:     hr.tailOriginAllowed,
 	}
+	hr.health.set("channel", ChannelFillCheck(hr.outFill))
+	return hr
 }
 
-// Start begins listening for HTTP requests
+// outFill returns how full the output channel is
+func (hr *HTTPReceiver) outFill() (int, int) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	return len(hr.out), cap(hr.out)
+}
+
+// AddHealthCheck adds a component to /health. A check named like an
+// existing one replaces it, so "channel" replaces the check on the output
+// channel's fill, for when the receiver writes into a buffer.
+func (hr *HTTPReceiver) AddHealthCheck(name string, check HealthCheck) {
+	hr.health.set(name, check)
+}
+
+// SetLevelControl exposes the minimum level on /config/level, behind the
+// same auth as the ingest endpoints. It must be called before Start.
+func (hr *HTTPReceiver) SetLevelControl(level LevelControl) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	hr.level = level
+}
+
+// SetParser parses plain text bodies posted to /logs with p instead of
+// taking the body as the message. It must be called before Start.
+func (hr *HTTPReceiver) SetParser(p Parser) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	hr.parser = p
+}
+
+// SetLabels names the source and labels every entry received, whatever
+// source the client gave it. It must be called before Start.
+func (hr *HTTPReceiver) SetLabels(labels SourceLabels) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	hr.labels = labels
+}
+
+// SetAdmission makes every entry ask allow before it is accepted, so a
+// rate limit refuses entries while the client can still be told. Refused
+// entries are answered 429 on /logs and counted as rate limited on /batch
+// and /stream. It must be called before Start.
+func (hr *HTTPReceiver) SetAdmission(allow func() bool) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	hr.allow = allow
+}
+
+// admit reports whether the rate limit, if any, lets another entry in
+func (hr *HTTPReceiver) admit() bool {
+	return hr.allow == nil || hr.allow()
+}
+
+// Start begins listening for HTTP requests. With an empty address or a
+// Mux it only starts serving the routes through the existing server.
 func (hr *HTTPReceiver) Start(ctx context.Context, out chan<- *models.LogEntry) error {
 	hr.mu.Lock()
 	if hr.running {
 		hr.mu.Unlock()
-		return fmt.Errorf("HTTP receiver already running")
+		return sourceError(models.AlreadyRunning, "HTTP receiver already running")
 	}
-	hr.running = true
-	hr.out = out
-	hr.mu.Unlock()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/logs", hr.handleLogs)
-	mux.HandleFunc("/batch", hr.handleBatch)
-	mux.HandleFunc("/health", hr.handleHealth)
+	if hr.opts.Mux != nil && !hr.mounted {
+		handler := hr.handler()
+		for _, pattern := range hr.patterns() {
+			hr.opts.Mux.Handle(pattern, handler)
+		}
+		hr.mounted = true
+	}
+	if hr.opts.Mux != nil || hr.addr == "" {
+		hr.begin(out)
+		hr.mu.Unlock()
+
+		fmt.Printf("📡 HTTP receiver serving through an existing server\n")
+		hr.printRoutes()
+		go func() {
+			<-ctx.Done()
+			hr.Stop()
+		}()
+		return nil
+	}
+
+	// Bind before returning so address errors reach the caller
+	listener, err := net.Listen("tcp", hr.addr)
+	if err != nil {
+		hr.mu.Unlock()
+		return sourceError(listenErrorCode(err), "failed to listen on %s: %w", hr.addr, err)
+	}
 
-	hr.server = &http.Server{
-		Addr:         hr.addr,
-		Handler:      mux,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+	server := &http.Server{
+		Handler:           hr.handler(),
+		ReadHeaderTimeout: hr.opts.ReadHeaderTimeout,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
 	}
 
+	hr.begin(out)
+	hr.server = server
+	hr.listener = listener
+	hr.mu.Unlock()
+
 	fmt.Printf("📡 HTTP receiver listening on %s\n", hr.addr)
-	fmt.Println("   POST /logs   - Single log entry")
-	fmt.Println("   POST /batch  - Batch log entries")
-	fmt.Println("   GET  /health - Health check")
+	hr.printRoutes()
 
 	go func() {
-		if err := hr.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			fmt.Printf("HTTP server error: %v\n", err)
 		}
 	}()
@@ -72,6 +479,145 @@ func (hr *HTTPReceiver) Start(ctx context.Context, out chan<- *models.LogEntry)
 	return nil
 }
 
+// begin marks the receiver running, sending to out. Must be called with
+// hr.mu held.
+func (hr *HTTPReceiver) begin(out chan<- *models.LogEntry) {
+	// A receiver restarted after Stop needs a fresh drain signal
+	select {
+	case <-hr.done:
+		hr.done = make(chan struct{})
+		hr.drainErr = nil
+	default:
+	}
+
+	hr.sendMu.Lock()
+	if hr.aborted {
+		hr.abort = make(chan struct{})
+		hr.aborted = false
+	}
+	hr.sendMu.Unlock()
+
+	hr.tail.reopen()
+	hr.running = true
+	hr.out = out
+	hr.inflight = &sync.WaitGroup{}
+}
+
+// Handler returns the receiver's routes for mounting in an existing
+// server, e.g. with an empty address so Start doesn't listen itself. The
+// routes answer 503 until Start is called and after Stop, and send to the
+// channel given to Start. SetLevelControl must be called first for the
+// level route to be included.
+func (hr *HTTPReceiver) Handler() http.Handler {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	return hr.handler()
+}
+
+// handler builds the receiver's routes. Must be called with hr.mu held.
+func (hr *HTTPReceiver) handler() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, hr.serving(h))
+	}
+	handle(hr.routes.Logs, hr.requireAuth(hr.handleLogs))
+	handle(hr.routes.Batch, hr.requireAuth(hr.handleBatch))
+	handle(hr.routes.Stream, hr.requireAuth(hr.handleStream))
+	handle(hr.routes.Tail, hr.requireToken(hr.handleTail, tailToken))
+	handle(hr.routes.Health, hr.handleHealth)
+	if hr.level != nil {
+		handle(hr.routes.Level, hr.requireAuth(hr.handleLevel))
+	}
+	return hr.allowClients(mux)
+}
+
+// patterns lists the paths the routes are served on. Must be called with
+// hr.mu held.
+func (hr *HTTPReceiver) patterns() []string {
+	patterns := []string{hr.routes.Logs, hr.routes.Batch, hr.routes.Stream, hr.routes.Tail, hr.routes.Health}
+	if hr.level != nil {
+		patterns = append(patterns, hr.routes.Level)
+	}
+	return patterns
+}
+
+// printRoutes lists the endpoints on the console
+func (hr *HTTPReceiver) printRoutes() {
+	fmt.Printf("   POST %s - Single log entry (JSON or text/plain)\n", hr.routes.Logs)
+	fmt.Printf("   POST %s - Batch log entries\n", hr.routes.Batch)
+	fmt.Printf("   POST %s - Newline-delimited JSON stream\n", hr.routes.Stream)
+	fmt.Printf("   GET  %s - WebSocket live tail\n", hr.routes.Tail)
+	fmt.Printf("   GET  %s - Health check\n", hr.routes.Health)
+	if hr.level != nil {
+		fmt.Printf("   GET/POST %s - Minimum log level\n", hr.routes.Level)
+	}
+}
+
+// serving answers 503 while the receiver is stopped and counts the
+// requests it lets through, so Stop can wait for them on a shared mux
+func (hr *HTTPReceiver) serving(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hr.mu.Lock()
+		if !hr.running {
+			hr.mu.Unlock()
+			http.Error(w, "Receiver is not running", http.StatusServiceUnavailable)
+			return
+		}
+		inflight := hr.inflight
+		inflight.Add(1)
+		hr.mu.Unlock()
+
+		defer inflight.Done()
+		next(w, r)
+	}
+}
+
+// requireAuth rejects requests without the configured bearer token.
+// It is a no-op when no token is configured.
+func (hr *HTTPReceiver) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return hr.requireToken(next, headerToken)
+}
+
+// requireToken is requireAuth with the token read by tokenOf
+func (hr *HTTPReceiver) requireToken(next http.HandlerFunc, tokenOf func(*http.Request) (string, bool)) http.HandlerFunc {
+	if hr.opts.AuthToken == "" {
+		return next
+	}
+
+	// Compare digests so the comparison doesn't leak the token length either
+	expected := sha256.Sum256([]byte(hr.opts.AuthToken))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := tokenOf(r)
+		provided := sha256.Sum256([]byte(token))
+
+		if !ok || subtle.ConstantTimeCompare(expected[:], provided[:]) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="logflux"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// allowClients refuses requests from clients outside the allowlist. It is
+// a no-op without one.
+func (hr *HTTPReceiver) allowClients(next http.Handler) http.Handler {
+	if hr.opts.Allowlist == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hr.opts.Allowlist.Allows(clientIP(r, hr.opts.TrustedProxies)) {
+			recordDenied(&hr.stats, hr.Name())
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // handleLogs handles single log entry
 func (hr *HTTPReceiver) handleLogs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -80,65 +626,98 @@ func (hr *HTTPReceiver) handleLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Read body
-	body, err := io.ReadAll(r.Body)
+	body, err := hr.readBody(w, r)
 	if err != nil {
-		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		hr.stats.recordError()
+		writeBodyError(w, err)
 		return
 	}
 	defer r.Body.Close()
+	hr.recordBytes(len(body))
 
-	// Parse JSON
-	var logData struct {
-		Level   string                 `json:"level"`
-		Message string                 `json:"message"`
-		Source  string                 `json:"source"`
-		Fields  map[string]interface{} `json:"fields"`
+	// A plain text body is the message itself, unless a parser is set. JSON
+	// is the default.
+	var entry *models.LogEntry
+	if isPlainText(r) && hr.parser != nil {
+		entry = hr.parseText(r, body)
+		if hr.opts.RejectEmptyMessages && strings.TrimSpace(entry.Message) == "" {
+			hr.stats.recordError()
+			http.Error(w, "Invalid entry: message: must not be empty", http.StatusBadRequest)
+			return
+		}
+	} else {
+		var logData logPayload
+		if isPlainText(r) {
+			logData = textPayload(r, body)
+		} else if err := json.Unmarshal(body, &logData); err != nil {
+			hr.stats.recordError()
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := logData.validate(hr.opts); err != nil {
+			hr.stats.recordError()
+			http.Error(w, fmt.Sprintf("Invalid entry: %v", err), http.StatusBadRequest)
+			return
+		}
+		entry = logData.entry()
 	}
+	hr.keepInput(entry, body)
 
-	if err := json.Unmarshal(body, &logData); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if !hr.admit() {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 		return
 	}
 
-	// Create log entry
-	entry := models.NewLogEntry()
-	entry.Message = logData.Message
-	entry.Source = logData.Source
-	if entry.Source == "" {
-		entry.Source = "http"
+	// Send to channel
+	if !hr.send(r.Context(), entry) {
+		http.Error(w, "Channel full", http.StatusServiceUnavailable)
+		return
 	}
 
-	// Parse level
-	switch logData.Level {
-	case "DEBUG":
-		entry.Level = models.LevelDebug
-	case "INFO":
-		entry.Level = models.LevelInfo
-	case "WARNING", "WARN":
-		entry.Level = models.LevelWarning
-	case "ERROR":
-		entry.Level = models.LevelError
-	case "CRITICAL", "CRIT":
-		entry.Level = models.LevelCritical
-	default:
-		entry.Level = models.LevelInfo
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "accepted",
+		"id":     entry.ID,
+	})
+}
+
+// isPlainText reports whether the request body is text/plain
+func isPlainText(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/plain"
+}
+
+// textPayload builds the payload for a plain text body. The source comes
+// from the source query param and the level from the X-Log-Level header.
+func textPayload(r *http.Request, body []byte) logPayload {
+	return logPayload{
+		Message: strings.TrimRight(string(body), "\r\n"),
+		Source:  r.URL.Query().Get("source"),
+		Level:   r.Header.Get(levelHeader),
 	}
+}
 
-	// Add fields
-	if logData.Fields != nil {
-		entry.Fields = logData.Fields
+// parseText parses a plain text body with the receiver's parser. The source
+// query param is the default source, and the X-Log-Level header overrides
+// the parsed level.
+func (hr *HTTPReceiver) parseText(r *http.Request, body []byte) *models.LogEntry {
+	src := r.URL.Query().Get("source")
+	if src == "" {
+		src = "http"
 	}
 
-	// Send to channel
-	select {
-	case hr.out <- entry:
-		w.WriteHeader(http.StatusAccepted)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "accepted",
-			"id":     entry.ID,
-		})
-	default:
-		http.Error(w, "Channel full", http.StatusServiceUnavailable)
+	entry := parseOrRaw(hr.parser, bytes.TrimRight(body, "\r\n"), src)
+	if level, err := models.ParseLevel(r.Header.Get(levelHeader)); err == nil {
+		entry.Level = level
+	}
+	return entry
+}
+
+// keepInput stores what the entry was read from, if the receiver keeps it
+func (hr *HTTPReceiver) keepInput(entry *models.LogEntry, raw []byte) {
+	if hr.opts.KeepRaw {
+		keepRaw(entry, string(raw))
 	}
 }
 
@@ -149,96 +728,667 @@ func (hr *HTTPReceiver) handleBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
+	body, err := hr.readBody(w, r)
 	if err != nil {
-		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		hr.stats.recordError()
+		writeBodyError(w, err)
 		return
 	}
 	defer r.Body.Close()
+	hr.recordBytes(len(body))
 
-	var logs []struct {
-		Level   string                 `json:"level"`
-		Message string                 `json:"message"`
-		Source  string                 `json:"source"`
-		Fields  map[string]interface{} `json:"fields"`
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		hr.stats.recordError()
+		http.Error(w, "Invalid JSON: expected an array", http.StatusBadRequest)
+		return
+	}
+
+	// Entries are decoded one at a time so a bad one doesn't sink the
+	// rest. ids lines up with the input, dropped and rejected entries stay
+	// null. A syntax error or a cut off body truncates the batch at offset,
+	// the end of the last entry read.
+	accepted, limited := 0, 0
+	truncated, offset := false, int64(0)
+	dropped := []int{}
+	rejected := []batchError{}
+	ids := []interface{}{}
+	reject := func(index int, err string) {
+		hr.stats.recordError()
+		rejected = append(rejected, batchError{Index: index, Error: err})
+	}
+	for i := 0; dec.More(); i++ {
+		ids = append(ids, nil)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			truncated, offset = true, dec.InputOffset()
+			if len(bytes.TrimLeft(body[offset:], " \t\r\n,")) == 0 {
+				// Cut off between entries, there is no entry to reject
+				ids = ids[:i]
+				hr.stats.recordError()
+				break
+			}
+			// A syntax error loses the place in the array, nothing after
+			// it can be read
+			reject(i, "invalid JSON: "+err.Error())
+			break
+		}
+		var payload logPayload
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			reject(i, describeJSONError(err))
+			continue
+		}
+		if err := payload.validate(hr.opts); err != nil {
+			reject(i, err.Error())
+			continue
+		}
+
+		if !hr.admit() {
+			limited++
+			dropped = append(dropped, i)
+			continue
+		}
+		entry := payload.entry()
+		hr.keepInput(entry, raw)
+		if hr.send(r.Context(), entry) {
+			accepted++
+			ids[i] = entry.ID
+		} else {
+			dropped = append(dropped, i)
+		}
+	}
+	if !truncated {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim(']') {
+			hr.stats.recordError()
+			truncated, offset = true, dec.InputOffset()
+		}
+	}
+
+	status, code := "accepted", http.StatusAccepted
+	switch {
+	case limited > 0 && limited == len(ids):
+		status, code = "rate_limited", http.StatusTooManyRequests
+		w.Header().Set("Retry-After", "1")
+	case truncated && accepted == 0:
+		status, code = "rejected", http.StatusBadRequest
+	case truncated:
+		status, code = "partial", http.StatusMultiStatus
+	case len(rejected) == 0:
+	case len(rejected) == len(ids):
+		status, code = "rejected", http.StatusBadRequest
+	default:
+		status, code = "partial", http.StatusMultiStatus
+	}
+
+	response := map[string]interface{}{
+		"status":       status,
+		"total":        len(ids),
+		"accepted":     accepted,
+		"dropped":      dropped,
+		"rate_limited": limited,
+		"rejected":     rejected,
+		"ids":          ids,
+		"truncated":    truncated,
+	}
+	if truncated {
+		response["offset"] = offset
 	}
 
-	if err := json.Unmarshal(body, &logs); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
+
+// batchError reports a batch entry that was rejected
+type batchError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// describeJSONError explains why an entry didn't decode, without Go type
+// names
+func describeJSONError(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return err.Error()
+	}
+	if typeErr.Field == "" {
+		return fmt.Sprintf("expected an object, got %s", typeErr.Value)
+	}
+	return fmt.Sprintf("%s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+}
+
+// handleStream handles newline-delimited JSON, one entry per line. Lines are
+// sent as they arrive so a long-lived request never buffers the whole stream.
+func (hr *HTTPReceiver) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	defer r.Body.Close()
 
-	accepted := 0
-	for _, logData := range logs {
-		entry := models.NewLogEntry()
-		entry.Message = logData.Message
-		entry.Source = logData.Source
-		if entry.Source == "" {
-			entry.Source = "http"
-		}
-
-		switch logData.Level {
-		case "DEBUG":
-			entry.Level = models.LevelDebug
-		case "INFO":
-			entry.Level = models.LevelInfo
-		case "WARNING", "WARN":
-			entry.Level = models.LevelWarning
-		case "ERROR":
-			entry.Level = models.LevelError
-		case "CRITICAL", "CRIT":
-			entry.Level = models.LevelCritical
-		default:
-			entry.Level = models.LevelInfo
+	body, err := hr.streamBody(r)
+	if err != nil {
+		hr.stats.recordError()
+		writeBodyError(w, err)
+		return
+	}
+	defer body.Close()
+
+	// The server read timeout would cut long streams short, so it is replaced
+	// by an idle timeout that moves forward with every line
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(streamIdleTimeout))
+
+	lineLimit := hr.streamLineLimit()
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, min(64*1024, lineLimit)), lineLimit)
+
+	accepted, malformed, dropped, limited := 0, 0, 0, 0
+	for scanner.Scan() {
+		rc.SetReadDeadline(time.Now().Add(streamIdleTimeout))
+
+		received := scanner.Bytes()
+		hr.recordBytes(len(received) + 1)
+
+		line := bytes.TrimSpace(received)
+		if len(line) == 0 {
+			continue
+		}
+
+		var logData logPayload
+		if err := json.Unmarshal(line, &logData); err != nil {
+			hr.stats.recordError()
+			malformed++
+			continue
+		}
+		// Earlier lines are already sent, so invalid ones count as malformed
+		if err := logData.validate(hr.opts); err != nil {
+			hr.stats.recordError()
+			malformed++
+			continue
+		}
+
+		if !hr.admit() {
+			limited++
+			dropped++
+			continue
+		}
+		entry := logData.entry()
+		hr.keepInput(entry, received)
+		if hr.send(r.Context(), entry) {
+			accepted++
+		} else {
+			dropped++
+		}
+	}
+
+	result := map[string]interface{}{
+		"status":       "accepted",
+		"accepted":     accepted,
+		"malformed":    malformed,
+		"dropped":      dropped,
+		"rate_limited": limited,
+	}
+
+	status := http.StatusAccepted
+	if err := scanner.Err(); err != nil {
+		hr.stats.recordError()
+		status = http.StatusBadRequest
+		result["status"] = "error"
+		result["error"] = streamErrorMessage(err, lineLimit)
+	}
+
+	rc.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// streamBody returns the request body for streaming, decompressing gzip
+// payloads on the fly
+func (hr *HTTPReceiver) streamBody(r *http.Request) (io.ReadCloser, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return io.NopCloser(r.Body), nil
+
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, &bodyError{http.StatusBadRequest, "Invalid gzip body"}
+		}
+		return gz, nil
+
+	default:
+		return nil, &bodyError{http.StatusUnsupportedMediaType,
+			fmt.Sprintf("Unsupported Content-Encoding: %s", encoding)}
+	}
+}
+
+// streamLineLimit returns the longest line accepted on /stream
+func (hr *HTTPReceiver) streamLineLimit() int {
+	if hr.opts.MaxBodyBytes < maxStreamLineSize {
+		return int(hr.opts.MaxBodyBytes)
+	}
+	return maxStreamLineSize
+}
+
+// streamErrorMessage describes why a stream stopped early
+func streamErrorMessage(err error, lineLimit int) string {
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Sprintf("Line exceeds %d bytes", lineLimit)
+	}
+	return "Failed to read stream"
+}
+
+// bodyError is a request body problem with the status code to report
+type bodyError struct {
+	status  int
+	message string
+}
+
+func (e *bodyError) Error() string {
+	return e.message
+}
+
+// readBody reads the request body, transparently decompressing gzip payloads
+func (hr *HTTPReceiver) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	return readRequestBody(w, r, hr.opts.MaxBodyBytes, hr.opts.MaxDecompressedSize)
+}
+
+// readRequestBody reads a request body of at most maxBody bytes, or
+// maxDecompressed once gzip payloads are decompressed
+func readRequestBody(w http.ResponseWriter, r *http.Request, maxBody, maxDecompressed int64) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, readBodyError(err, "Failed to read body")
 		}
+		return body, nil
 
-		if logData.Fields != nil {
-			entry.Fields = logData.Fields
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, readBodyError(err, "Invalid gzip body")
 		}
+		defer gz.Close()
+
+		// Read one byte past the limit to detect oversized payloads
+		limit := maxDecompressed
+		body, err := io.ReadAll(io.LimitReader(gz, limit+1))
+		if err != nil {
+			return nil, readBodyError(err, "Invalid gzip body")
+		}
+		if int64(len(body)) > limit {
+			return nil, &bodyError{http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Decompressed body exceeds %d bytes", limit)}
+		}
+		return body, nil
+
+	default:
+		return nil, &bodyError{http.StatusUnsupportedMediaType,
+			fmt.Sprintf("Unsupported Content-Encoding: %s", encoding)}
+	}
+}
+
+// readBodyError describes a failed body read, answering 413 when the body
+// went over MaxBodyBytes
+func readBodyError(err error, message string) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &bodyError{http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Body exceeds %d bytes", tooLarge.Limit)}
+	}
+	return &bodyError{http.StatusBadRequest, message}
+}
+
+// recordBytes counts request body bytes
+func (hr *HTTPReceiver) recordBytes(n int) {
+	metrics.BytesReceived.WithLabelValues(hr.Name()).Add(int64(n))
+	hr.stats.recordBytes(n)
+}
+
+// writeBodyError reports a readBody failure to the client
+func writeBodyError(w http.ResponseWriter, err error) {
+	var be *bodyError
+	if errors.As(err, &be) {
+		http.Error(w, be.message, be.status)
+		return
+	}
+	http.Error(w, "Failed to read body", http.StatusBadRequest)
+}
+
+// send pushes an entry to the output channel, waiting up to SendTimeout for
+// room. It gives up early if the request context is cancelled.
+func (hr *HTTPReceiver) send(ctx context.Context, entry *models.LogEntry) bool {
+	metrics.EntriesReceived.WithLabelValues(hr.Name()).Inc()
+	hr.labels.apply(entry)
+
+	// Encode for live-tail viewers before the entry leaves, later stages
+	// may modify it
+	var frame tailFrame
+	if hr.tail.active() {
+		if data, err := json.Marshal(entry); err == nil {
+			frame = tailFrame{level: entry.Level, data: data}
+		}
+	}
+
+	if hr.trySend(ctx, entry) {
+		hr.stats.recordEntry()
+		if frame.data != nil {
+			hr.tail.publish(frame)
+		}
+		return true
+	}
+	metrics.EntriesDropped.WithLabelValues(metrics.ReasonChannelFull).Inc()
+	return false
+}
+
+// trySend does the actual channel send for send. It fails once Stop has
+// given up draining.
+func (hr *HTTPReceiver) trySend(ctx context.Context, entry *models.LogEntry) bool {
+	hr.sendMu.RLock()
+	defer hr.sendMu.RUnlock()
+	if hr.aborted {
+		return false
+	}
 
+	if hr.opts.SendTimeout <= 0 {
 		select {
 		case hr.out <- entry:
-			accepted++
+			return true
 		default:
-			// Channel full, skip
+			return false
 		}
 	}
 
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":   "accepted",
-		"total":    len(logs),
-		"accepted": accepted,
-	})
+	timer := time.NewTimer(hr.opts.SendTimeout)
+	defer timer.Stop()
+
+	select {
+	case hr.out <- entry:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	case <-hr.abort:
+		return false
+	}
+}
+
+// abortSends makes every send fail from now on, waiting out those already
+// under way
+func (hr *HTTPReceiver) abortSends() {
+	close(hr.abort)
+	hr.sendMu.Lock()
+	hr.aborted = true
+	hr.sendMu.Unlock()
+}
+
+// headerToken reads the token of an "Authorization: Bearer" header
+func headerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// tailToken reads the token of a live-tail request from the header, a
+// "bearer.<token>" subprotocol or the token query parameter
+func tailToken(r *http.Request) (string, bool) {
+	if token, ok := headerToken(r); ok {
+		return token, true
+	}
+	for _, protocol := range websocket.Subprotocols(r) {
+		if token, ok := strings.CutPrefix(protocol, tailTokenProtocol); ok {
+			return token, true
+		}
+	}
+	if query := r.URL.Query(); query.Has("token") {
+		return query.Get("token"), true
+	}
+	return "", false
+}
+
+// tailOriginAllowed reports whether a browser on the request's origin may
+// open the live tail: its own host or one of TailOrigins
+func (hr *HTTPReceiver) tailOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range hr.opts.TailOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// handleTail upgrades to a WebSocket and pushes every new entry as a JSON
+// text frame. The level query parameter limits it to entries at or above
+// that level.
+func (hr *HTTPReceiver) handleTail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var minLevel models.LogLevel
+	if level := r.URL.Query().Get("level"); level != "" {
+		parsed, err := models.ParseLevel(level)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid level: %v", err), http.StatusBadRequest)
+			return
+		}
+		minLevel = parsed
+	}
+
+	client := hr.tail.subscribe(minLevel)
+	if client == nil {
+		http.Error(w, "Receiver is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer hr.tail.unsubscribe(client)
+
+	// Upgrade replies to the client itself on failure
+	conn, err := hr.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// The server's read timeout still applies to the hijacked connection
+	conn.SetReadDeadline(time.Time{})
+
+	// Viewers don't send anything, reading only notices when they leave
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(tailPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case frame, ok := <-client.frames:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "receiver stopped"),
+					time.Now().Add(time.Second))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(tailWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(tailWriteTimeout)); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// handleLevel reports the minimum level on GET and changes it on POST
+func (hr *HTTPReceiver) handleLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		body, err := hr.readBody(w, r)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		defer r.Body.Close()
+
+		var payload levelPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		level, err := models.ParseLevel(string(payload.MinLevel))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid min_level: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		previous := hr.level.MinLevel()
+		hr.level.SetMinLevel(level)
+		fmt.Printf("🎚️  Minimum level changed from %q to %s\n", previous, level)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(levelPayload{MinLevel: hr.level.MinLevel()})
 }
 
-// handleHealth handles health check
+// handleHealth reports the worst status of the health checks, with each
+// component's own. Unhealthy answers 503 so load balancers route around
+// the collector.
 func (hr *HTTPReceiver) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "healthy",
-		"time":   time.Now().Format(time.RFC3339),
-	})
+	report := hr.health.report()
+
+	code := http.StatusOK
+	if report.Status == HealthUnhealthy {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
 }
 
-// Stop stops the receiver
+// Stop shuts the receiver down gracefully. New connections are refused,
+// in-flight requests get up to ShutdownTimeout to finish sending their
+// entries, then Done is closed. If the timeout passes, requests still
+// running can no longer send, the remaining connections are closed and an
+// error is returned, since their entries were lost. Concurrent calls wait
+// for the first one to finish.
 func (hr *HTTPReceiver) Stop() error {
 	hr.mu.Lock()
-	defer hr.mu.Unlock()
-
 	if !hr.running {
-		return nil
-	}
+		started, done := hr.inflight != nil, hr.done
+		hr.mu.Unlock()
+
+		if !started {
+			return nil
+		}
+		<-done
 
+		hr.mu.Lock()
+		defer hr.mu.Unlock()
+		return hr.drainErr
+	}
 	hr.running = false
+	server, inflight, done := hr.server, hr.inflight, hr.done
+	hr.mu.Unlock()
+
+	// Shutdown doesn't track hijacked connections, so close live tails here
+	hr.tail.close()
 
-	if hr.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		return hr.server.Shutdown(ctx)
+	ctx, cancel := context.WithTimeout(context.Background(), hr.opts.ShutdownTimeout)
+	defer cancel()
+
+	var drainErr error
+	if server != nil {
+		// Shutdown closes the listener and waits for active requests
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			drainErr = fmt.Errorf("HTTP receiver did not drain within %s, in-flight entries may be lost: %w",
+				hr.opts.ShutdownTimeout, err)
+		}
+	} else if err := waitGroupContext(ctx, inflight); err != nil {
+		// The mux's owner holds the connections, so all that's left is
+		// to stop waiting
+		drainErr = fmt.Errorf("HTTP receiver did not drain within %s, in-flight entries may be lost: %w",
+			hr.opts.ShutdownTimeout, err)
 	}
 
-	return nil
+	if drainErr != nil {
+		hr.abortSends()
+	}
+
+	hr.mu.Lock()
+	hr.drainErr = drainErr
+	hr.mu.Unlock()
+	close(done)
+
+	return drainErr
+}
+
+// waitGroupContext waits for wg until ctx is done
+func waitGroupContext(ctx context.Context, wg *sync.WaitGroup) error {
+	waited := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(waited)
+	}()
+
+	select {
+	case <-waited:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done is closed once Stop has finished draining in-flight requests, or
+// given up on them. No entries are sent to the output channel after that.
+func (hr *HTTPReceiver) Done() <-chan struct{} {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	return hr.done
+}
+
+// Stats returns a snapshot of the receiver's activity
+func (hr *HTTPReceiver) Stats() models.SourceStats {
+	return hr.stats.snapshot()
+}
+
+// Addr returns the address the receiver is listening on. Before Start it
+// returns the configured address.
+func (hr *HTTPReceiver) Addr() string {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+
+	if hr.listener != nil {
+		return hr.listener.Addr().String()
+	}
+	return hr.addr
 }
 
 // Name returns the source name
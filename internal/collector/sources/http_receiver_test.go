@@ -2,12 +2,23 @@ package sources
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/internal/pipeline"
 	"github.com/fatihserhatturan/logflux/pkg/models"
 )
 
@@ -24,9 +35,7 @@ func TestHTTPReceiver_SingleLog(t *testing.T) {
 	}
 	defer receiver.Stop()
 
-	// Get actual address
-	addr := receiver.server.Addr
-	time.Sleep(100 * time.Millisecond)
+	addr := receiver.Addr()
 
 	// Send test log
 	logData := map[string]interface{}{
@@ -79,8 +88,7 @@ func TestHTTPReceiver_Batch(t *testing.T) {
 	}
 	defer receiver.Stop()
 
-	addr := receiver.server.Addr
-	time.Sleep(100 * time.Millisecond)
+	addr := receiver.Addr()
 
 	// Send batch
 	logs := []map[string]interface{}{
@@ -100,20 +108,178 @@ func TestHTTPReceiver_Batch(t *testing.T) {
 		t.Errorf("Expected status 202, got %d", resp.StatusCode)
 	}
 
-	// Collect entries
-	count := 0
-	timeout := time.After(2 * time.Second)
+	var result struct {
+		Total    int      `json:"total"`
+		Accepted int      `json:"accepted"`
+		IDs      []string `json:"ids"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || result.Accepted != 3 {
+		t.Errorf("Expected 3/3 accepted, got %d/%d", result.Accepted, result.Total)
+	}
+	if len(result.IDs) != 3 {
+		t.Fatalf("Expected 3 IDs, got %v", result.IDs)
+	}
 
-	for count < 3 {
+	// Collect entries, the IDs come back in input order
+	timeout := time.After(2 * time.Second)
+	for i := 0; i < 3; i++ {
 		select {
-		case <-out:
-			count++
+		case entry := <-out:
+			if result.IDs[i] == "" || result.IDs[i] != entry.ID {
+				t.Errorf("Expected ID %q for %q, got %q", entry.ID, entry.Message, result.IDs[i])
+			}
 		case <-timeout:
-			t.Fatalf("Only received %d/3 entries", count)
+			t.Fatalf("Only received %d/3 entries", i)
 		}
 	}
 }
 
+func TestHTTPReceiver_BatchMalformedEntries(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedMessages []string
+		expectedRejected []batchError
+		truncatedAt      int64 // offset the batch was cut at, 0 if it wasn't
+	}{
+		{
+			"wrong type in the middle",
+			`[{"message": "first"}, {"message": 42}, {"message": "third"}]`,
+			http.StatusMultiStatus,
+			[]string{"first", "third"},
+			[]batchError{{Index: 1, Error: "message: expected string, got number"}},
+			0,
+		},
+		{
+			"not an object",
+			`[{"message": "first"}, "second", {"message": "third"}]`,
+			http.StatusMultiStatus,
+			[]string{"first", "third"},
+			[]batchError{{Index: 1, Error: "expected an object, got string"}},
+			0,
+		},
+		{
+			"syntax error stops reading",
+			`[{"message": "first"}, {"message": "second",, {"message": "third"}]`,
+			http.StatusMultiStatus,
+			[]string{"first"},
+			[]batchError{{Index: 1}},
+			21,
+		},
+		{
+			"body cut off in an entry",
+			`[{"message": "first"}, {"message": "sec`,
+			http.StatusMultiStatus,
+			[]string{"first"},
+			[]batchError{{Index: 1}},
+			21,
+		},
+		{
+			"body cut off after an entry",
+			`[{"message": "first"}`,
+			http.StatusMultiStatus,
+			[]string{"first"},
+			nil,
+			21,
+		},
+		{
+			"body cut off after a comma",
+			`[{"message": "first"}, `,
+			http.StatusMultiStatus,
+			[]string{"first"},
+			nil,
+			21,
+		},
+		{
+			"nothing valid",
+			`[{"level": 1}, []]`,
+			http.StatusBadRequest,
+			nil,
+			[]batchError{{Index: 0, Error: "level: expected string, got number"}, {Index: 1, Error: "expected an object, got array"}},
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			receiver := NewHTTPReceiver(addr)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			out := make(chan *models.LogEntry, 10)
+
+			if err := receiver.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			resp, err := http.Post("http://"+addr+"/batch", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			var result struct {
+				Accepted  int          `json:"accepted"`
+				Rejected  []batchError `json:"rejected"`
+				IDs       []*string    `json:"ids"`
+				Truncated bool         `json:"truncated"`
+				Offset    *int64       `json:"offset"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			offset := int64(0)
+			if result.Offset != nil {
+				offset = *result.Offset
+			}
+			if result.Truncated != (tt.truncatedAt != 0) || offset != tt.truncatedAt {
+				t.Errorf("Expected truncated at %d, got truncated=%v at %d", tt.truncatedAt, result.Truncated, offset)
+			}
+
+			if result.Accepted != len(tt.expectedMessages) {
+				t.Errorf("Expected %d accepted, got %d", len(tt.expectedMessages), result.Accepted)
+			}
+			if len(result.Rejected) != len(tt.expectedRejected) {
+				t.Fatalf("Expected rejected %v, got %v", tt.expectedRejected, result.Rejected)
+			}
+			for i, expected := range tt.expectedRejected {
+				got := result.Rejected[i]
+				if got.Index != expected.Index || (expected.Error != "" && got.Error != expected.Error) || got.Error == "" {
+					t.Errorf("Expected rejected %+v, got %+v", expected, got)
+				}
+				if result.IDs[got.Index] != nil {
+					t.Errorf("Expected no ID for rejected index %d", got.Index)
+				}
+			}
+
+			for _, message := range tt.expectedMessages {
+				if entry := receiveEntry(t, out); entry.Message != message {
+					t.Errorf("Expected %q, got %q", message, entry.Message)
+				}
+			}
+			// A batch cut off between entries has no entry to reject
+			errors := int64(len(tt.expectedRejected))
+			if errors == 0 && tt.truncatedAt != 0 {
+				errors = 1
+			}
+			if got := receiver.Stats().Errors; got != errors {
+				t.Errorf("Expected %d errors, got %d", errors, got)
+			}
+		})
+	}
+}
+
 func TestHTTPReceiver_Health(t *testing.T) {
 	receiver := NewHTTPReceiver("127.0.0.1:0")
 
@@ -127,8 +293,7 @@ func TestHTTPReceiver_Health(t *testing.T) {
 	}
 	defer receiver.Stop()
 
-	addr := receiver.server.Addr
-	time.Sleep(100 * time.Millisecond)
+	addr := receiver.Addr()
 
 	resp, err := http.Get("http://" + addr + "/health")
 	if err != nil {
@@ -140,10 +305,1696 @@ func TestHTTPReceiver_Health(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", resp.StatusCode)
 	}
 
-	var health map[string]string
+	var health healthReport
 	json.NewDecoder(resp.Body).Decode(&health)
 
-	if health["status"] != "healthy" {
-		t.Errorf("Expected healthy status, got %s", health["status"])
+	if health.Status != HealthHealthy {
+		t.Errorf("Expected healthy status, got %s", health.Status)
+	}
+}
+
+func TestHTTPReceiver_HealthFullChannel(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{SendTimeout: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Room for two entries and nobody reading
+	out := make(chan *models.LogEntry, 2)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post("http://"+addr+"/logs", "application/json", strings.NewReader(`{"message": "fill"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Degraded still serves
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var health healthReport
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != HealthDegraded {
+		t.Errorf("Expected degraded status, got %s", health.Status)
+	}
+	if channel := health.Components["channel"]; channel.Status != HealthDegraded || channel.Detail != "100% full (2/2)" {
+		t.Errorf("Expected a full channel, got %+v", channel)
+	}
+}
+
+func TestHTTPReceiver_HealthUnhealthy(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+	receiver.AddHealthCheck("sinks", SinkErrorCheck(func() float64 { return 0.75 }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 10)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+	var health healthReport
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != HealthUnhealthy || health.Components["channel"].Status != HealthHealthy {
+		t.Errorf("Expected unhealthy sinks and a healthy channel, got %+v", health)
+	}
+}
+
+func TestHTTPReceiver_StartPortInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	receiver := NewHTTPReceiver(l.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err == nil {
+		receiver.Stop()
+		t.Fatal("Expected error when the port is already in use")
+	}
+
+	// A failed start leaves the receiver free to start again
+	l.Close()
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatalf("Expected restart to succeed, got %v", err)
+	}
+	receiver.Stop()
+}
+
+func TestHTTPReceiver_Addr(t *testing.T) {
+	receiver := NewHTTPReceiver("127.0.0.1:0")
+	if receiver.Addr() != "127.0.0.1:0" {
+		t.Errorf("Expected configured address before start, got %q", receiver.Addr())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	host, port, err := net.SplitHostPort(receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "127.0.0.1" || port == "0" {
+		t.Errorf("Expected a concrete port on 127.0.0.1, got %q", receiver.Addr())
+	}
+}
+
+func TestHTTPReceiver_Routes(t *testing.T) {
+	receiver := NewHTTPReceiverWithOptions("127.0.0.1:0", HTTPReceiverOptions{
+		PathPrefix: "/logflux/",
+		Routes:     HTTPRoutes{Logs: "ingest"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	base := "http://" + receiver.Addr()
+
+	tests := []struct {
+		method   string
+		path     string
+		body     string
+		expected int
+	}{
+		{http.MethodPost, "/logflux/ingest", `{"message":"single"}`, http.StatusAccepted},
+		{http.MethodPost, "/logflux/batch", `[{"message":"batched"}]`, http.StatusAccepted},
+		{http.MethodGet, "/logflux/health", "", http.StatusOK},
+		{http.MethodPost, "/logs", `{"message":"unprefixed"}`, http.StatusNotFound},
+		{http.MethodPost, "/logflux/logs", `{"message":"renamed"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, base+tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.expected {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.expected, resp.StatusCode)
+		}
+	}
+
+	for _, expected := range []string{"single", "batched"} {
+		if entry := receiveEntry(t, out); entry.Message != expected {
+			t.Errorf("Expected %q, got %q", expected, entry.Message)
+		}
+	}
+}
+
+func TestHTTPReceiver_SharedMux(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("app"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	receiver := NewHTTPReceiverWithOptions("", HTTPReceiverOptions{Mux: mux, PathPrefix: "/logflux"})
+	post := func() int {
+		resp, err := http.Post(server.URL+"/logflux/logs", "application/json", strings.NewReader(`{"message":"embedded"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := post(); status != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", status)
+	}
+	if entry := receiveEntry(t, out); entry.Message != "embedded" {
+		t.Errorf("Expected 'embedded', got %q", entry.Message)
+	}
+
+	resp, err := http.Get(server.URL + "/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the server's own route untouched, got %d", resp.StatusCode)
+	}
+
+	if err := receiver.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if status := post(); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 once stopped, got %d", status)
+	}
+
+	// Restarting doesn't register the routes again
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	if status := post(); status != http.StatusAccepted {
+		t.Errorf("Expected status 202 after restart, got %d", status)
+	}
+}
+
+func TestHTTPReceiver_Handler(t *testing.T) {
+	receiver := NewHTTPReceiverWithOptions("", HTTPReceiverOptions{AuthToken: "secret"})
+	server := httptest.NewServer(receiver.Handler())
+	defer server.Close()
+
+	post := func(token string) int {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/logs", strings.NewReader(`{"message":"mounted"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("secret"); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 before Start, got %d", status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	if status := post(""); status != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without the token, got %d", status)
+	}
+	if status := post("secret"); status != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", status)
+	}
+	if entry := receiveEntry(t, out); entry.Message != "mounted" {
+		t.Errorf("Expected 'mounted', got %q", entry.Message)
+	}
+	if stats := receiver.Stats(); stats.EntriesProduced != 1 {
+		t.Errorf("Expected 1 entry received, got %d", stats.EntriesProduced)
+	}
+}
+
+// freeAddr returns a loopback address with an unused port
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// waitForHTTP polls the health endpoint until the receiver is up
+func waitForHTTP(t *testing.T, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := http.Get("http://" + addr + "/health")
+		if err == nil {
+			resp.Body.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("HTTP receiver never came up: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHTTPReceiver_BatchPartialAcceptance(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{SendTimeout: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Room for two entries and nobody reading
+	out := make(chan *models.LogEntry, 2)
+
+	dropped := metrics.EntriesDropped.WithLabelValues(metrics.ReasonChannelFull)
+	droppedBefore := dropped.Value()
+	received := metrics.EntriesReceived.WithLabelValues(receiver.Name())
+	receivedBefore := received.Value()
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	logs := []map[string]interface{}{
+		{"message": "Message 0"},
+		{"message": "Message 1"},
+		{"message": "Message 2"},
+		{"message": "Message 3"},
+	}
+
+	body, _ := json.Marshal(logs)
+	resp, err := http.Post("http://"+addr+"/batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Total    int       `json:"total"`
+		Accepted int       `json:"accepted"`
+		Dropped  []int     `json:"dropped"`
+		IDs      []*string `json:"ids"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Total != 4 || result.Accepted != 2 {
+		t.Errorf("Expected 2/4 accepted, got %d/%d", result.Accepted, result.Total)
+	}
+	if len(result.Dropped) != 2 || result.Dropped[0] != 2 || result.Dropped[1] != 3 {
+		t.Errorf("Expected dropped indices [2 3], got %v", result.Dropped)
+	}
+	if len(result.IDs) != 4 || result.IDs[0] == nil || result.IDs[1] == nil || result.IDs[2] != nil || result.IDs[3] != nil {
+		t.Errorf("Expected IDs for the accepted entries and null for the dropped ones, got %v", result.IDs)
+	}
+
+	if got := dropped.Value() - droppedBefore; got != 2 {
+		t.Errorf("Expected dropped counter +2, got +%d", got)
+	}
+	if got := received.Value() - receivedBefore; got != 4 {
+		t.Errorf("Expected received counter +4, got +%d", got)
+	}
+}
+
+func TestHTTPReceiver_SendTimeoutWaitsForRoom(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{SendTimeout: 2 * time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Unbuffered with a slow consumer: entries only fit by waiting
+	out := make(chan *models.LogEntry)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	received := make(chan int, 1)
+	go func() {
+		count := 0
+		for count < 3 {
+			time.Sleep(50 * time.Millisecond)
+			<-out
+			count++
+		}
+		received <- count
+	}()
+
+	logs := []map[string]interface{}{
+		{"message": "Message 0"},
+		{"message": "Message 1"},
+		{"message": "Message 2"},
+	}
+
+	body, _ := json.Marshal(logs)
+	resp, err := http.Post("http://"+addr+"/batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Accepted int   `json:"accepted"`
+		Dropped  []int `json:"dropped"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Accepted != 3 || len(result.Dropped) != 0 {
+		t.Errorf("Expected all 3 accepted, got %d (dropped %v)", result.Accepted, result.Dropped)
+	}
+
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Consumer did not receive all entries")
+	}
+}
+
+func TestHTTPReceiver_SingleLogRejectedWhenFull(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{SendTimeout: 20 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	body, _ := json.Marshal(map[string]string{"message": "nobody listening"})
+	resp, err := http.Post("http://"+addr+"/logs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+}
+
+// gzipBody compresses data for Content-Encoding: gzip requests
+func gzipBody(t *testing.T, data []byte) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestHTTPReceiver_GzipBatch(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	logs := []map[string]interface{}{
+		{"level": "INFO", "message": "compressed 1"},
+		{"level": "ERROR", "message": "compressed 2"},
+	}
+	data, _ := json.Marshal(logs)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/batch", gzipBody(t, data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	for i := 1; i <= 2; i++ {
+		select {
+		case entry := <-out:
+			expected := fmt.Sprintf("compressed %d", i)
+			if entry.Message != expected {
+				t.Errorf("Expected %q, got %q", expected, entry.Message)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for log entry")
+		}
+	}
+}
+
+func TestHTTPReceiver_GzipBombRejected(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{MaxDecompressedSize: 1024})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	// Highly compressible payload well above the limit once inflated
+	message := strings.Repeat("A", 64*1024)
+	data, _ := json.Marshal(map[string]string{"message": message})
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/logs", gzipBody(t, data))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", resp.StatusCode)
+	}
+
+	select {
+	case <-out:
+		t.Error("Oversized payload should not produce an entry")
+	default:
+	}
+}
+
+func TestHTTPReceiver_MaxBodyBytes(t *testing.T) {
+	receiver := NewHTTPReceiverWithOptions("127.0.0.1:0", HTTPReceiverOptions{MaxBodyBytes: 1024})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	base := "http://" + receiver.Addr()
+
+	big := strings.Repeat("x", 2048)
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		gzip     bool
+		expected int
+	}{
+		{"small single", "/logs", `{"message":"ok"}`, false, http.StatusAccepted},
+		{"small batch", "/batch", `[{"message":"ok"}]`, false, http.StatusAccepted},
+		{"large single", "/logs", `{"message":"` + big + `"}`, false, http.StatusRequestEntityTooLarge},
+		{"large batch", "/batch", `[{"message":"` + big + `"}]`, false, http.StatusRequestEntityTooLarge},
+		{"large text", "/logs", big, false, http.StatusRequestEntityTooLarge},
+		// The limit applies to the body as sent, so this one fits
+		{"large compressible gzip", "/logs", `{"message":"` + big + `"}`, true, http.StatusAccepted},
+		{"stream line too long", "/stream", `{"message":"` + big + `"}` + "\n", false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.gzip {
+				body = gzipBody(t, []byte(tt.body))
+			}
+			req, _ := http.NewRequest(http.MethodPost, base+tt.path, body)
+			contentType := "application/json"
+			if !strings.HasPrefix(tt.body, "{") && !strings.HasPrefix(tt.body, "[") {
+				contentType = "text/plain"
+			}
+			req.Header.Set("Content-Type", contentType)
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, resp.StatusCode)
+			}
+		})
+	}
+
+	if len(out) != 3 {
+		t.Errorf("Expected only the accepted bodies to produce entries, got %d", len(out))
+	}
+}
+
+func TestHTTPReceiver_SlowHeaders(t *testing.T) {
+	receiver := NewHTTPReceiverWithOptions("127.0.0.1:0", HTTPReceiverOptions{ReadHeaderTimeout: 100 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	conn, err := net.Dial("tcp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Headers that never finish
+	if _, err := conn.Write([]byte("POST /logs HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 512)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Fatal("Expected the connection closed for taking too long over its headers")
+			}
+			return
+		}
+	}
+}
+
+func TestHTTPReceiver_UnsupportedEncoding(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/logs", strings.NewReader("{}"))
+	req.Header.Set("Content-Encoding", "br")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status 415, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPReceiver_Auth(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithAuth(addr, "s3cret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	// Health stays open without a token
+	waitForHTTP(t, addr)
+
+	tests := []struct {
+		name           string
+		path           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"valid token", "/logs", "Bearer s3cret", http.StatusAccepted},
+		{"valid token batch", "/batch", "Bearer s3cret", http.StatusAccepted},
+		{"missing header", "/logs", "", http.StatusUnauthorized},
+		{"missing header batch", "/batch", "", http.StatusUnauthorized},
+		{"wrong token", "/logs", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "/logs", "Basic s3cret", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"message": "auth test"}`
+			if tt.path == "/batch" {
+				body = `[{"message": "auth test"}]`
+			}
+
+			req, _ := http.NewRequest(http.MethodPost, "http://"+addr+tt.path, strings.NewReader(body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	// Only the two authorized requests produced entries
+	if len(out) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(out))
+	}
+}
+
+func TestHTTPReceiver_NoAuthByDefault(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	resp, err := http.Post("http://"+addr+"/logs", "application/json", strings.NewReader(`{"message": "open"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPReceiver_ConfigLevel(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithAuth(addr, "s3cret")
+	level := pipeline.NewLevelFilter(models.LevelInfo)
+	receiver.SetLevelControl(level)
+	filter := pipeline.NewFilter(level.Predicate())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	do := func(method, path, body, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, "http://"+addr+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// The admin endpoint sits behind the same auth as ingest
+	if resp := do(http.MethodPost, "/config/level", `{"min_level": "DEBUG"}`, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", resp.StatusCode)
+	}
+	if level.MinLevel() != models.LevelInfo {
+		t.Errorf("Expected an unauthorized request to leave INFO, got %q", level.MinLevel())
+	}
+
+	if resp := do(http.MethodPost, "/config/level", `{"min_level": "verbose"}`, "s3cret"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown level, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodDelete, "/config/level", "", "s3cret"); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", resp.StatusCode)
+	}
+
+	resp := do(http.MethodPost, "/config/level", `{"min_level": "err"}`, "s3cret")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodGet, "/config/level", "", "s3cret")
+	var current levelPayload
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		t.Fatal(err)
+	}
+	if current.MinLevel != models.LevelError {
+		t.Errorf("Expected ERROR, got %q", current.MinLevel)
+	}
+
+	// A WARNING ingested after the change is filtered
+	if resp := do(http.MethodPost, "/logs", `{"level": "warning", "message": "disk at 80%"}`, "s3cret"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+	if entry := receiveEntry(t, out); filter.Allow(entry) {
+		t.Error("Expected the WARNING entry to be filtered at ERROR")
+	}
+}
+
+func TestHTTPReceiver_NoConfigLevelByDefault(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	resp, err := http.Get("http://" + addr + "/config/level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPReceiver_EmptyMessages(t *testing.T) {
+	tests := []struct {
+		name            string
+		reject          bool
+		path            string
+		body            string
+		expectedStatus  int
+		expectedEntries int
+		expectedInError string
+	}{
+		{"lenient single", false, "/logs", `{"message": "  "}`, http.StatusAccepted, 1, ""},
+		{"lenient batch", false, "/batch", `[{"message": "ok"}, {"message": ""}]`, http.StatusAccepted, 2, ""},
+		{"strict single", true, "/logs", `{"message": " \t "}`, http.StatusBadRequest, 0, "message"},
+		{"strict missing message", true, "/logs", `{"level": "ERROR"}`, http.StatusBadRequest, 0, "message"},
+		{"strict batch", true, "/batch", `[{"message": "ok"}, {"message": ""}]`, http.StatusMultiStatus, 1, `{"index":1,"error":"message: must not be empty"}`},
+		{"strict valid", true, "/logs", `{"message": "fine"}`, http.StatusAccepted, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{RejectEmptyMessages: tt.reject})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			out := make(chan *models.LogEntry, 10)
+
+			if err := receiver.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			resp, err := http.Post("http://"+addr+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, resp.StatusCode, body)
+			}
+			if tt.expectedInError != "" && !strings.Contains(string(body), tt.expectedInError) {
+				t.Errorf("Expected response to mention %q, got %q", tt.expectedInError, body)
+			}
+			if len(out) != tt.expectedEntries {
+				t.Errorf("Expected %d entries, got %d", tt.expectedEntries, len(out))
+			}
+		})
+	}
+}
+
+func TestHTTPReceiver_PlainText(t *testing.T) {
+	tests := []struct {
+		name            string
+		contentType     string
+		query           string
+		level           string
+		body            string
+		expectedStatus  int
+		expectedLevel   models.LogLevel
+		expectedSource  string
+		expectedMessage string
+	}{
+		{"with level", "text/plain", "?source=backup.sh", "error", "disk full\n", http.StatusAccepted, models.LevelError, "backup.sh", "disk full"},
+		{"without level", "text/plain; charset=utf-8", "", "", "nightly job done", http.StatusAccepted, models.LevelInfo, "http", "nightly job done"},
+		{"unknown level", "text/plain", "", "loud", "hello\r\n", http.StatusAccepted, models.LevelInfo, "http", "hello"},
+		{"keeps JSON-looking text", "text/plain", "", "", `{"message": "raw"}`, http.StatusAccepted, models.LevelInfo, "http", `{"message": "raw"}`},
+		{"JSON by default", "", "", "", "disk full", http.StatusBadRequest, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			receiver := NewHTTPReceiver(addr)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			out := make(chan *models.LogEntry, 10)
+
+			if err := receiver.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/logs"+tt.query, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.level != "" {
+				req.Header.Set("X-Log-Level", tt.level)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, resp.StatusCode, body)
+			}
+			if tt.expectedStatus != http.StatusAccepted {
+				return
+			}
+
+			entry := receiveEntry(t, out)
+			if entry.Message != tt.expectedMessage {
+				t.Errorf("Expected message %q, got %q", tt.expectedMessage, entry.Message)
+			}
+			if entry.Level != tt.expectedLevel {
+				t.Errorf("Expected level %s, got %s", tt.expectedLevel, entry.Level)
+			}
+			if entry.Source != tt.expectedSource {
+				t.Errorf("Expected source %q, got %q", tt.expectedSource, entry.Source)
+			}
+		})
+	}
+}
+
+func TestHTTPReceiver_PlainTextEmpty(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{RejectEmptyMessages: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	resp, err := http.Post("http://"+addr+"/logs", "text/plain", strings.NewReader("\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an empty text body to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPReceiver_Timestamps(t *testing.T) {
+	event := time.Date(2024, time.March, 5, 10, 30, 0, 250_000_000, time.UTC)
+
+	tests := []struct {
+		name           string
+		strict         bool
+		path           string
+		body           string
+		expectedStatus int
+		expectedTime   time.Time // zero means the ingest time
+	}{
+		{"rfc3339", false, "/logs", `{"message": "m", "timestamp": "2024-03-05T10:30:00.25Z"}`, http.StatusAccepted, event},
+		{"rfc3339 offset", false, "/logs", `{"message": "m", "timestamp": "2024-03-05T12:30:00.25+02:00"}`, http.StatusAccepted, event},
+		{"epoch millis", false, "/logs", fmt.Sprintf(`{"message": "m", "timestamp": %d}`, event.UnixMilli()), http.StatusAccepted, event},
+		{"batch", false, "/batch", `[{"message": "m", "timestamp": "2024-03-05T10:30:00.25Z"}]`, http.StatusAccepted, event},
+		{"missing", false, "/logs", `{"message": "m"}`, http.StatusAccepted, time.Time{}},
+		{"null", true, "/logs", `{"message": "m", "timestamp": null}`, http.StatusAccepted, time.Time{}},
+		{"lenient invalid", false, "/logs", `{"message": "m", "timestamp": "yesterday"}`, http.StatusAccepted, time.Time{}},
+		{"lenient out of range", false, "/logs", `{"message": "m", "timestamp": 1000}`, http.StatusAccepted, time.Time{}},
+		{"strict invalid", true, "/logs", `{"message": "m", "timestamp": "yesterday"}`, http.StatusBadRequest, time.Time{}},
+		{"strict too old", true, "/logs", `{"message": "m", "timestamp": "1999-12-31T23:59:59Z"}`, http.StatusBadRequest, time.Time{}},
+		{"strict far future", true, "/batch", `[{"message": "m", "timestamp": "2999-01-01T00:00:00Z"}]`, http.StatusBadRequest, time.Time{}},
+		{"strict valid", true, "/logs", fmt.Sprintf(`{"message": "m", "timestamp": %d}`, event.UnixMilli()), http.StatusAccepted, event},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{StrictTimestamps: tt.strict})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			out := make(chan *models.LogEntry, 10)
+
+			if err := receiver.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			before := time.Now()
+			resp, err := http.Post("http://"+addr+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, resp.StatusCode, body)
+			}
+			if tt.expectedStatus != http.StatusAccepted {
+				if !strings.Contains(string(body), "timestamp") {
+					t.Errorf("Expected response to mention the timestamp, got %q", body)
+				}
+				return
+			}
+
+			entry := <-out
+			if tt.expectedTime.IsZero() {
+				if entry.Timestamp.Before(before) || entry.Timestamp.After(time.Now()) {
+					t.Errorf("Expected the ingest time, got %v", entry.Timestamp)
+				}
+			} else if !entry.Timestamp.Equal(tt.expectedTime) {
+				t.Errorf("Expected timestamp %v, got %v", tt.expectedTime, entry.Timestamp)
+			}
+		})
+	}
+}
+
+func TestHTTPReceiver_Stream(t *testing.T) {
+	receiver := NewHTTPReceiver("127.0.0.1:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 100)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	pr, pw := io.Pipe()
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.Post("http://"+receiver.Addr()+"/stream", "application/x-ndjson", pr)
+		done <- result{resp, err}
+	}()
+
+	// Entries must arrive while the request is still open
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(pw, `{"level": "WARNING", "message": "line %d", "source": "stream-test"}`+"\n", i)
+		if i == 10 {
+			fmt.Fprint(pw, "{not json\n\n")
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+		select {
+		case entry := <-out:
+			if expected := fmt.Sprintf("line %d", i); entry.Message != expected {
+				t.Errorf("Expected %q, got %q", expected, entry.Message)
+			}
+			if entry.Level != models.LevelWarning || entry.Source != "stream-test" {
+				t.Errorf("Unexpected entry %+v", entry)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Only received %d/50 entries before the stream closed", i)
+		}
+	}
+	pw.Close()
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	defer res.resp.Body.Close()
+
+	if res.resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", res.resp.StatusCode)
+	}
+
+	var body struct {
+		Accepted  int `json:"accepted"`
+		Malformed int `json:"malformed"`
+		Dropped   int `json:"dropped"`
+	}
+	if err := json.NewDecoder(res.resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Accepted != 50 || body.Malformed != 1 || body.Dropped != 0 {
+		t.Errorf("Expected 50 accepted and 1 malformed, got %+v", body)
+	}
+}
+
+func TestHTTPReceiver_KeepRaw(t *testing.T) {
+	receiver := NewHTTPReceiverWithOptions("127.0.0.1:0", HTTPReceiverOptions{KeepRaw: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	tests := []struct {
+		path     string
+		body     string
+		expected []string
+	}{
+		{"/logs", `{"message": "single"}`, []string{`{"message": "single"}`}},
+		{"/batch", `[{"message": "one"}, {"message":"two"}]`, []string{`{"message": "one"}`, `{"message":"two"}`}},
+		{"/stream", "  {\"message\": \"line\"}\t\n", []string{"  {\"message\": \"line\"}\t"}},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Post("http://"+receiver.Addr()+tt.path, "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		for _, expected := range tt.expected {
+			entry := receiveEntry(t, out)
+			if raw := entry.Fields[FieldRaw]; raw != expected {
+				t.Errorf("%s: expected raw %q, got %q", tt.path, expected, raw)
+			}
+		}
+	}
+}
+
+func TestHTTPReceiver_StreamLineTooLong(t *testing.T) {
+	receiver := NewHTTPReceiver("127.0.0.1:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	body := `{"message": "ok"}` + "\n" + strings.Repeat("x", maxStreamLineSize+1) + "\n"
+	resp, err := http.Post("http://"+receiver.Addr()+"/stream", "application/x-ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+	if len(out) != 1 {
+		t.Errorf("Expected the line before the error to be accepted, got %d entries", len(out))
+	}
+}
+
+// openStream starts a /stream request fed by the returned writer and waits
+// until its first entry arrives, so the request is known to be in flight
+func openStream(t *testing.T, receiver *HTTPReceiver, out <-chan *models.LogEntry) (*io.PipeWriter, <-chan *http.Response) {
+	t.Helper()
+
+	pr, pw := io.Pipe()
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Post("http://"+receiver.Addr()+"/stream", "application/x-ndjson", pr)
+		if err != nil {
+			close(responses)
+			return
+		}
+		responses <- resp
+	}()
+
+	fmt.Fprintln(pw, `{"message": "first"}`)
+	select {
+	case <-out:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the first streamed entry")
+	}
+	return pw, responses
+}
+
+func TestHTTPReceiver_StopDrainsInFlightRequests(t *testing.T) {
+	receiver := NewHTTPReceiver("127.0.0.1:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	pw, responses := openStream(t, receiver, out)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- receiver.Stop()
+	}()
+
+	// Stop must wait for the slow request
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned before the request finished: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	fmt.Fprintln(pw, `{"message": "second"}`)
+	pw.Close()
+
+	resp, ok := <-responses
+	if !ok {
+		t.Fatal("Slow request failed during shutdown")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	select {
+	case entry := <-out:
+		if entry.Message != "second" {
+			t.Errorf("Expected the late entry, got %q", entry.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Late entry was dropped")
+	}
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Expected clean drain, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return after the request finished")
+	}
+
+	select {
+	case <-receiver.Done():
+	default:
+		t.Error("Expected Done to be closed after Stop")
+	}
+	if err := receiver.Stop(); err != nil {
+		t.Errorf("Expected repeated Stop to succeed, got %v", err)
+	}
+}
+
+func TestHTTPReceiver_StopDrainTimeout(t *testing.T) {
+	receiver := NewHTTPReceiverWithOptions("127.0.0.1:0", HTTPReceiverOptions{ShutdownTimeout: 100 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	pw, _ := openStream(t, receiver, out)
+	defer pw.Close()
+
+	// The request never finishes, so the drain times out
+	err := receiver.Stop()
+	if err == nil {
+		t.Fatal("Expected drain timeout error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+
+	select {
+	case <-receiver.Done():
+	default:
+		t.Error("Expected Done to be closed after a timed out Stop")
+	}
+}
+
+func TestHTTPReceiver_NoSendAfterDone(t *testing.T) {
+	// On a shared mux Stop can't close the connections, so a request
+	// waiting for room in the channel outlives the drain
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	receiver := NewHTTPReceiverWithOptions("", HTTPReceiverOptions{
+		Mux:             mux,
+		SendTimeout:     10 * time.Second,
+		ShutdownTimeout: 100 * time.Millisecond,
+	})
+	out := make(chan *models.LogEntry)
+	if err := receiver.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	answered := make(chan int, 1)
+	go func() {
+		resp, err := http.Post(server.URL+"/logs", "application/json", strings.NewReader(`{"message":"late"}`))
+		if err != nil {
+			answered <- 0
+			return
+		}
+		resp.Body.Close()
+		answered <- resp.StatusCode
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := receiver.Stop(); err == nil {
+		t.Fatal("Expected drain timeout error")
+	}
+	<-receiver.Done()
+
+	select {
+	case entry := <-out:
+		t.Errorf("Expected no entry after Done, got %q", entry.Message)
+	case <-time.After(200 * time.Millisecond):
+	}
+	select {
+	case code := <-answered:
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("Expected the abandoned request to get 503, got %d", code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the abandoned request to be answered")
+	}
+}
+
+// dialTail opens a live-tail connection. The viewer is registered by the
+// time it returns.
+func dialTail(t *testing.T, addr, query string) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+"/tail"+query, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial /tail: %v", err)
+	}
+	resp.Body.Close()
+	return conn
+}
+
+// readTailEntry reads the next live-tail frame
+func readTailEntry(t *testing.T, conn *websocket.Conn) models.LogEntry {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var entry models.LogEntry
+	if err := conn.ReadJSON(&entry); err != nil {
+		t.Fatalf("Failed to read tail frame: %v", err)
+	}
+	return entry
+}
+
+func TestHTTPReceiver_Tail(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	all := dialTail(t, addr, "")
+	defer all.Close()
+	errorsOnly := dialTail(t, addr, "?level=error")
+	defer errorsOnly.Close()
+
+	body := `[{"level": "INFO", "message": "started"}, {"level": "ERROR", "message": "disk full"}]`
+	resp, err := http.Post("http://"+addr+"/batch", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if entry := readTailEntry(t, all); entry.Message != "started" {
+		t.Errorf("Expected 'started', got %q", entry.Message)
+	}
+	if entry := readTailEntry(t, all); entry.Message != "disk full" {
+		t.Errorf("Expected 'disk full', got %q", entry.Message)
+	}
+
+	entry := readTailEntry(t, errorsOnly)
+	if entry.Message != "disk full" || entry.Level != models.LevelError {
+		t.Errorf("Expected only the ERROR entry, got %s %q", entry.Level, entry.Message)
+	}
+	<-out
+	if sent := <-out; sent.ID != entry.ID {
+		t.Errorf("Expected the tailed entry to match the sent one, got %s and %s", entry.ID, sent.ID)
+	}
+}
+
+func TestHTTPReceiver_TailInvalidLevel(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+"/tail?level=loud", nil)
+	if err == nil {
+		t.Fatal("Expected the handshake to fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %v", resp)
+	}
+}
+
+func TestHTTPReceiver_TailOrigin(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithOptions(addr, HTTPReceiverOptions{TailOrigins: []string{"https://ops.example.com"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"http://" + addr, true},
+		{"https://ops.example.com", true},
+		{"https://evil.example.com", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+"/tail", header)
+		if tt.allowed {
+			if err != nil {
+				t.Errorf("Origin %q: expected the handshake to succeed, got %v", tt.origin, err)
+				continue
+			}
+			conn.Close()
+			continue
+		}
+		if err == nil {
+			conn.Close()
+			t.Errorf("Origin %q: expected the handshake to fail", tt.origin)
+			continue
+		}
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Origin %q: expected status 403, got %v", tt.origin, resp)
+		}
+	}
+}
+
+func TestHTTPReceiver_TailToken(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiverWithAuth(addr, "secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	// Browsers offer the token as a subprotocol and get "logflux" back
+	dialer := websocket.Dialer{Subprotocols: []string{"logflux", "bearer.secret"}}
+	conn, _, err := dialer.Dial("ws://"+addr+"/tail", nil)
+	if err != nil {
+		t.Fatalf("Expected the subprotocol token to be accepted, got %v", err)
+	}
+	if conn.Subprotocol() != "logflux" {
+		t.Errorf("Expected the logflux subprotocol, got %q", conn.Subprotocol())
+	}
+	conn.Close()
+
+	conn, _, err = websocket.DefaultDialer.Dial("ws://"+addr+"/tail?token=secret", nil)
+	if err != nil {
+		t.Fatalf("Expected the query token to be accepted, got %v", err)
+	}
+	conn.Close()
+
+	for _, query := range []string{"", "?token=wrong"} {
+		_, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+"/tail"+query, nil)
+		if err == nil {
+			t.Fatalf("Query %q: expected the handshake to fail", query)
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Query %q: expected status 401, got %v", query, resp)
+		}
+	}
+
+	// Only the tail reads the token from the query
+	resp, err := http.Post("http://"+addr+"/logs?token=secret", "application/json", strings.NewReader(`{"message": "hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected /logs to ignore the query token, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPReceiver_TailDisconnect(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	conn := dialTail(t, addr, "")
+	if receiver.tail.count() != 1 {
+		t.Fatalf("Expected 1 viewer, got %d", receiver.tail.count())
+	}
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for receiver.tail.count() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the viewer to be deregistered after disconnecting")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHTTPReceiver_TailSlowClient(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 1000)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	// The slow viewer never reads and keeps a tiny receive buffer, so its
+	// connection backs up long before the ~10MB below is written to it
+	dialer := websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			if err == nil {
+				conn.(*net.TCPConn).SetReadBuffer(4096)
+			}
+			return conn, err
+		},
+	}
+	slow, resp, err := dialer.Dial("ws://"+addr+"/tail", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	defer slow.Close()
+
+	fast := dialTail(t, addr, "")
+	defer fast.Close()
+	received := make(chan string, 1)
+	go func() {
+		for {
+			var entry models.LogEntry
+			if err := fast.ReadJSON(&entry); err != nil {
+				return
+			}
+			if entry.Message == "last" {
+				received <- entry.Message
+				return
+			}
+		}
+	}()
+
+	big := strings.Repeat("x", 50_000)
+	var batch []map[string]string
+	for i := 0; i < 200; i++ {
+		batch = append(batch, map[string]string{"message": big})
+	}
+	payload, _ := json.Marshal(batch)
+
+	resp, err = http.Post("http://"+addr+"/batch", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	start := time.Now()
+	resp, err = http.Post("http://"+addr+"/logs", "application/json", strings.NewReader(`{"message": "last"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected ingestion not to wait on viewers, took %v", elapsed)
+	}
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the fast viewer to keep receiving while the slow one lags")
+	}
+}
+
+func TestHTTPReceiver_StopClosesTail(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+	waitForHTTP(t, addr)
+
+	conn := dialTail(t, addr, "")
+	defer conn.Close()
+
+	if err := receiver.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected a going-away close, got %v", err)
 	}
 }
@@ -0,0 +1,126 @@
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// JournaldOptions configures a JournaldReader
+type JournaldOptions struct {
+	// Units limits reading to these systemd units. Empty reads the whole
+	// journal.
+	Units []string
+
+	// CursorPath persists the cursor of the last entry read, so a restarted
+	// reader resumes after it. Without it only entries logged after Start
+	// are read.
+	CursorPath string
+
+	// KeepRaw keeps each entry's line of journalctl JSON exactly as read,
+	// in FieldRaw
+	KeepRaw bool
+}
+
+// NewJournaldReader creates a reader for new entries in the systemd journal
+func NewJournaldReader() *JournaldReader {
+	return NewJournaldReaderWithOptions(JournaldOptions{})
+}
+
+// Name returns the source name
+func (r *JournaldReader) Name() string {
+	if len(r.opts.Units) == 0 {
+		return "journald"
+	}
+	return "journald:" + strings.Join(r.opts.Units, ",")
+}
+
+// Journal fields mapped onto the entry itself rather than kept in Fields
+const (
+	journalMessage    = "MESSAGE"
+	journalPriority   = "PRIORITY"
+	journalUnit       = "_SYSTEMD_UNIT"
+	journalHostname   = "_HOSTNAME"
+	journalRealtime   = "__REALTIME_TIMESTAMP"
+	journalCursor     = "__CURSOR"
+	journalIdentifier = "SYSLOG_IDENTIFIER"
+)
+
+// journalEntry converts one line of `journalctl -o json` into a log entry
+// and returns the entry's cursor. Fields that aren't mapped are kept.
+func journalEntry(line []byte) (*models.LogEntry, string, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, "", fmt.Errorf("invalid journal entry: %w", err)
+	}
+
+	entry := models.NewLogEntry()
+	var cursor string
+	for name, value := range raw {
+		text, ok := journalValue(value)
+		switch {
+		case name == journalMessage && ok:
+			entry.Message = text
+		case name == journalPriority && ok:
+			if severity, err := strconv.Atoi(text); err == nil && severity >= 0 && severity <= 7 {
+				entry.Level = severityLevel(severity)
+			} else {
+				entry.Fields[name] = value
+			}
+		case name == journalUnit && ok:
+			entry.Source = text
+		case name == journalHostname && ok:
+			entry.Fields["hostname"] = text
+		case name == journalRealtime && ok:
+			if micros, err := strconv.ParseInt(text, 10, 64); err == nil {
+				setEventTime(entry, time.UnixMicro(micros), text)
+			}
+		case name == journalCursor && ok:
+			cursor = text
+			entry.Fields[name] = text
+		case ok:
+			entry.Fields[name] = text
+		default:
+			entry.Fields[name] = value
+		}
+	}
+
+	// Kernel and non-service messages have no unit
+	if entry.Source == "" {
+		entry.Source, _ = journalValue(raw[journalIdentifier])
+	}
+	if entry.Source == "" {
+		entry.Source = "journald"
+	}
+	if _, ok := entry.Fields[FieldRawTimestamp]; !ok {
+		markIngestTime(entry)
+	}
+
+	return entry, cursor, nil
+}
+
+// journalValue returns a field's value as text. journalctl writes values
+// that aren't valid UTF-8 as arrays of bytes, decoded here; fields set
+// more than once come as arrays of strings and are left as they are.
+func journalValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		data := make([]byte, 0, len(v))
+		for _, b := range v {
+			n, ok := b.(float64)
+			if !ok || n < 0 || n > 255 {
+				return "", false
+			}
+			data = append(data, byte(n))
+		}
+		return string(data), true
+	default:
+		return "", false
+	}
+}
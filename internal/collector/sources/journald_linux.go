@@ -0,0 +1,241 @@
+//go:build linux
+
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// journalctlPath is the journalctl binary run by JournaldReader. Tests
+// point it at a fake.
+var journalctlPath = "journalctl"
+
+const (
+	// maxJournalLineSize caps a single JSON entry from journalctl
+	maxJournalLineSize = 1 << 20
+
+	// cursorSaveInterval is how often the cursor is saved while entries
+	// keep coming. It is always saved when the reader stops.
+	cursorSaveInterval = time.Second
+
+	// journalctlWaitDelay bounds the wait for journalctl's output to close
+	// once it was killed
+	journalctlWaitDelay = time.Second
+)
+
+// JournaldReader reads entries from the systemd journal by following
+// `journalctl -o json`
+type JournaldReader struct {
+	opts JournaldOptions
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	run     *runState
+
+	labels SourceLabels
+	stats  sourceStats
+}
+
+// NewJournaldReaderWithOptions creates a journal reader with custom options
+func NewJournaldReaderWithOptions(opts JournaldOptions) *JournaldReader {
+	run := newRunState()
+	run.finish(nil)
+	return &JournaldReader{opts: opts, run: run}
+}
+
+// SetLabels names the source and labels every entry from it. It must
+// be called before Start.
+func (r *JournaldReader) SetLabels(labels SourceLabels) {
+	r.labels = labels
+}
+
+// Start runs journalctl and streams the entries it prints
+func (r *JournaldReader) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return sourceError(models.AlreadyRunning, "journald reader already running")
+	}
+
+	cursor, err := loadPosition(r.opts.CursorPath)
+	if err != nil {
+		return &models.SourceError{Code: models.IOError, Err: err}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	// The read loop kills journalctl when it stops reading by itself, so
+	// it doesn't sit blocked on a full pipe
+	cmdCtx, kill := context.WithCancel(ctx)
+	cmd := exec.CommandContext(cmdCtx, journalctlPath, r.args(strings.TrimSpace(cursor))...)
+	cmd.WaitDelay = journalctlWaitDelay
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		kill()
+		cancel()
+		return sourceError(models.IOError, "failed to run journalctl: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		kill()
+		cancel()
+		if errors.Is(err, exec.ErrNotFound) {
+			return sourceError(models.Unsupported, "journalctl not found: %w", err)
+		}
+		return sourceError(models.IOError, "failed to run journalctl: %w", err)
+	}
+
+	r.running = true
+	r.cancel = cancel
+	r.run = newRunState()
+
+	go r.readLoop(ctx, out, cmd, kill, stdout, &stderr, cursor, r.run)
+
+	fmt.Printf("📓 Reading the systemd journal (%s)\n", r.Name())
+	return nil
+}
+
+// args builds the journalctl command line, resuming after the cursor if
+// there is one
+func (r *JournaldReader) args(cursor string) []string {
+	args := []string{"--output=json", "--follow", "--no-pager", "--quiet"}
+	if cursor != "" {
+		args = append(args, "--after-cursor="+cursor)
+	} else {
+		args = append(args, "--lines=0")
+	}
+	for _, unit := range r.opts.Units {
+		args = append(args, "--unit="+unit)
+	}
+	return args
+}
+
+// readLoop streams entries until journalctl exits, which it only does by
+// itself when it fails, or its output can't be read, a line too long say
+func (r *JournaldReader) readLoop(ctx context.Context, out chan<- *models.LogEntry, cmd *exec.Cmd, kill context.CancelFunc, stdout io.Reader, stderr *bytes.Buffer, cursor string, run *runState) {
+	var exitErr error
+	defer kill()
+	defer func() { run.finish(exitErr) }()
+	defer r.Stop()
+
+	saved := cursor
+	lastSave := time.Now()
+	defer func() {
+		if cursor != saved {
+			r.saveCursor(cursor)
+		}
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxJournalLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		entry, entryCursor, err := journalEntry(line)
+		if err != nil {
+			fmt.Printf("⚠️  Skipped journal entry: %v\n", err)
+			r.stats.recordError()
+			continue
+		}
+		if r.opts.KeepRaw {
+			keepRaw(entry, string(line))
+		}
+
+		metrics.EntriesReceived.WithLabelValues(r.Name()).Inc()
+		metrics.BytesReceived.WithLabelValues(r.Name()).Add(int64(len(line)))
+		r.stats.recordBytes(len(line))
+		r.stats.recordEntry()
+		r.labels.apply(entry)
+
+		select {
+		case out <- entry:
+		case <-ctx.Done():
+			return
+		}
+
+		if entryCursor != "" {
+			cursor = entryCursor
+		}
+		if cursor != saved && time.Since(lastSave) >= cursorSaveInterval {
+			r.saveCursor(cursor)
+			saved, lastSave = cursor, time.Now()
+		}
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// journalctl is still running, but nothing reads its output
+		kill()
+	}
+
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		// Stopped or cancelled, journalctl was killed on purpose
+		return
+	}
+
+	r.stats.recordError()
+	switch {
+	case scanErr != nil:
+		exitErr = sourceError(models.IOError, "failed to read journalctl output: %w", scanErr)
+	case waitErr != nil:
+		exitErr = sourceError(models.IOError, "journalctl failed: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	default:
+		exitErr = sourceError(models.IOError, "journalctl exited")
+	}
+}
+
+// saveCursor persists the cursor if a path is set
+func (r *JournaldReader) saveCursor(cursor string) {
+	if r.opts.CursorPath == "" {
+		return
+	}
+	if err := savePosition(r.opts.CursorPath, cursor); err != nil {
+		fmt.Printf("⚠️  Failed to save journal cursor: %v\n", err)
+		r.stats.recordError()
+	}
+}
+
+// Stop kills journalctl and stops reading
+func (r *JournaldReader) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		r.running = false
+		r.cancel()
+	}
+	return nil
+}
+
+// Done is closed once the read loop ends and the cursor is saved
+func (r *JournaldReader) Done() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.run.done
+}
+
+// Err reports why the reader died once Done is closed, nil after Stop or
+// cancellation
+func (r *JournaldReader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.run.Err()
+}
+
+// Stats returns a snapshot of the reader's activity
+func (r *JournaldReader) Stats() models.SourceStats {
+	return r.stats.snapshot()
+}
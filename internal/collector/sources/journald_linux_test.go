@@ -0,0 +1,179 @@
+//go:build linux
+
+package sources
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// fakeJournalctl installs a script in place of journalctl that records its
+// arguments and then runs body. It returns the file the arguments go to.
+func fakeJournalctl(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args")
+	script := filepath.Join(dir, "journalctl")
+	content := "#!/bin/sh\necho \"$@\" > " + argsPath + "\n" + body + "\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := journalctlPath
+	journalctlPath = script
+	t.Cleanup(func() { journalctlPath = previous })
+	return argsPath
+}
+
+func TestJournaldReader_ResumesFromCursor(t *testing.T) {
+	dir := t.TempDir()
+	samplePath := filepath.Join(dir, "sample.json")
+	if err := os.WriteFile(samplePath, []byte(sampleJournal), 0644); err != nil {
+		t.Fatal(err)
+	}
+	argsPath := fakeJournalctl(t, "cat "+samplePath+"\nexec sleep 60")
+	cursorPath := filepath.Join(dir, "cursor")
+
+	reader := NewJournaldReaderWithOptions(JournaldOptions{
+		Units:      []string{"nginx.service"},
+		CursorPath: cursorPath,
+	})
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		receiveEntry(t, out)
+	}
+	if err := reader.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	<-reader.Done()
+	if reader.Err() != nil {
+		t.Errorf("Expected no error after Stop, got %v", reader.Err())
+	}
+
+	args, _ := os.ReadFile(argsPath)
+	if !strings.Contains(string(args), "--lines=0") || !strings.Contains(string(args), "--unit=nginx.service") {
+		t.Errorf("Expected only new entries of the unit, got args %q", args)
+	}
+	cursor, err := loadPosition(cursorPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cursor, "i=4ece9") {
+		t.Fatalf("Expected the last entry's cursor saved, got %q", cursor)
+	}
+
+	// A new reader picks up after the saved cursor
+	reader = NewJournaldReaderWithOptions(JournaldOptions{CursorPath: cursorPath})
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	receiveEntry(t, out)
+	reader.Stop()
+	<-reader.Done()
+
+	args, _ = os.ReadFile(argsPath)
+	if !strings.Contains(string(args), "--after-cursor="+cursor) {
+		t.Errorf("Expected to resume after the cursor, got args %q", args)
+	}
+	if strings.Contains(string(args), "--lines=0") {
+		t.Errorf("Expected no --lines=0 when resuming, got args %q", args)
+	}
+}
+
+func TestJournaldReader_KeepRaw(t *testing.T) {
+	samplePath := filepath.Join(t.TempDir(), "sample.json")
+	if err := os.WriteFile(samplePath, []byte(sampleJournal), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fakeJournalctl(t, "cat "+samplePath+"\nexec sleep 60")
+
+	reader := NewJournaldReaderWithOptions(JournaldOptions{KeepRaw: true})
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	expected, _, _ := strings.Cut(sampleJournal, "\n")
+	entry := receiveEntry(t, out)
+	if raw := entry.Fields[FieldRaw]; raw != expected {
+		t.Errorf("Expected raw %q, got %q", expected, raw)
+	}
+}
+
+func TestJournaldReader_JournalctlFails(t *testing.T) {
+	fakeJournalctl(t, "echo 'Failed to open journal: Permission denied' >&2\nexit 1")
+
+	reader := NewJournaldReader()
+	if err := reader.Start(context.Background(), make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-reader.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the reader to end when journalctl exits")
+	}
+
+	var sourceErr *models.SourceError
+	if !errors.As(reader.Err(), &sourceErr) || sourceErr.Code != models.IOError {
+		t.Fatalf("Expected an IOError, got %v", reader.Err())
+	}
+	if !strings.Contains(reader.Err().Error(), "Permission denied") {
+		t.Errorf("Expected journalctl's stderr in the error, got %v", reader.Err())
+	}
+}
+
+func TestJournaldReader_NoJournalctl(t *testing.T) {
+	previous := journalctlPath
+	journalctlPath = "logflux-no-such-journalctl"
+	defer func() { journalctlPath = previous }()
+
+	err := NewJournaldReader().Start(context.Background(), make(chan *models.LogEntry, 1))
+	var sourceErr *models.SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Code != models.Unsupported {
+		t.Errorf("Expected an Unsupported error, got %v", err)
+	}
+}
+
+func TestJournaldReader_LineTooLong(t *testing.T) {
+	// journalctl keeps writing the oversized line after the reader gave up
+	// on it, and has to be killed
+	path := filepath.Join(t.TempDir(), "long.json")
+	long := `{"MESSAGE": "` + strings.Repeat("a", 2*maxJournalLineSize) + `"}` + "\n"
+	if err := os.WriteFile(path, []byte(long), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fakeJournalctl(t, "exec cat "+path)
+
+	reader := NewJournaldReader()
+	if err := reader.Start(context.Background(), make(chan *models.LogEntry, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-reader.Done():
+	case <-time.After(5 * time.Second):
+		reader.Stop()
+		t.Fatal("Expected the reader to end on an oversized line")
+	}
+
+	var sourceErr *models.SourceError
+	if !errors.As(reader.Err(), &sourceErr) || sourceErr.Code != models.IOError {
+		t.Fatalf("Expected an IOError, got %v", reader.Err())
+	}
+	if !errors.Is(reader.Err(), bufio.ErrTooLong) {
+		t.Errorf("Expected the line to be too long, got %v", reader.Err())
+	}
+}
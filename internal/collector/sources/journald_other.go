@@ -0,0 +1,38 @@
+//go:build !linux
+
+package sources
+
+import (
+	"context"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// JournaldReader reads entries from the systemd journal. It is only
+// supported on Linux; elsewhere Start fails.
+type JournaldReader struct {
+	opts JournaldOptions
+}
+
+// NewJournaldReaderWithOptions creates a journal reader with custom options
+func NewJournaldReaderWithOptions(opts JournaldOptions) *JournaldReader {
+	return &JournaldReader{opts: opts}
+}
+
+// SetLabels has no effect, the reader never runs
+func (r *JournaldReader) SetLabels(labels SourceLabels) {}
+
+// Start always fails outside Linux
+func (r *JournaldReader) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	return sourceError(models.Unsupported, "journald is only supported on linux")
+}
+
+// Stop is a no-op
+func (r *JournaldReader) Stop() error {
+	return nil
+}
+
+// Stats returns empty stats, the reader never runs
+func (r *JournaldReader) Stats() models.SourceStats {
+	return models.SourceStats{}
+}
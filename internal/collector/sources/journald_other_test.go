@@ -0,0 +1,21 @@
+//go:build !linux
+
+package sources
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestJournaldReader_Unsupported(t *testing.T) {
+	reader := NewJournaldReader()
+
+	err := reader.Start(context.Background(), make(chan *models.LogEntry, 1))
+	var sourceErr *models.SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Code != models.Unsupported {
+		t.Errorf("Expected an Unsupported error, got %v", err)
+	}
+}
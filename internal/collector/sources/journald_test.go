@@ -0,0 +1,111 @@
+package sources
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// sampleJournal is output captured from `journalctl -o json`: a service
+// message, a kernel message without a unit and a message with bytes that
+// aren't valid UTF-8
+const sampleJournal = `{"__CURSOR":"s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece7;b=6c7c6013a8ba4b3fa1f3d7e4b2d5c4a1;m=139b6b3f6;t=5f5d2a6a5b1c4;x=8b6b7f0d57ad1e1a","__REALTIME_TIMESTAMP":"1697032536123456","__MONOTONIC_TIMESTAMP":"5261669366","_BOOT_ID":"6c7c6013a8ba4b3fa1f3d7e4b2d5c4a1","PRIORITY":"3","_PID":"1234","_COMM":"nginx","SYSLOG_IDENTIFIER":"nginx","_SYSTEMD_UNIT":"nginx.service","_HOSTNAME":"web-01","MESSAGE":"connect() failed (111: Connection refused) while connecting to upstream"}
+{"__CURSOR":"s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece8;b=6c7c6013a8ba4b3fa1f3d7e4b2d5c4a1;m=139b6c001;t=5f5d2a6a5c000;x=1f2e3d4c5b6a7988","__REALTIME_TIMESTAMP":"1697032536181760","_TRANSPORT":"kernel","PRIORITY":"6","SYSLOG_IDENTIFIER":"kernel","_HOSTNAME":"web-01","MESSAGE":"eth0: link up, 1000Mbps, full-duplex"}
+{"__CURSOR":"s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece9;b=6c7c6013a8ba4b3fa1f3d7e4b2d5c4a1;m=139b6c100;t=5f5d2a6a5c0ff;x=0a1b2c3d4e5f6071","__REALTIME_TIMESTAMP":"1697032536182015","PRIORITY":"4","_SYSTEMD_UNIT":"backup.service","_HOSTNAME":"web-01","MESSAGE":[99,97,102,233,32,98,97,99,107,117,112],"TAG":["one","two"]}
+`
+
+func TestJournalEntry(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader(sampleJournal))
+	var entries []*models.LogEntry
+	var cursors []string
+	for scanner.Scan() {
+		entry, cursor, err := journalEntry(scanner.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+		cursors = append(cursors, cursor)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	service := entries[0]
+	if service.Level != models.LevelError {
+		t.Errorf("Expected ERROR, got %v", service.Level)
+	}
+	if service.Source != "nginx.service" {
+		t.Errorf("Expected the unit as source, got %q", service.Source)
+	}
+	if service.Message != "connect() failed (111: Connection refused) while connecting to upstream" {
+		t.Errorf("Unexpected message %q", service.Message)
+	}
+	if service.Fields["hostname"] != "web-01" {
+		t.Errorf("Expected hostname web-01, got %v", service.Fields["hostname"])
+	}
+	expectedTS := time.UnixMicro(1697032536123456)
+	if !service.Timestamp.Equal(expectedTS) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTS, service.Timestamp)
+	}
+	if service.Fields["_PID"] != "1234" || service.Fields["_COMM"] != "nginx" {
+		t.Errorf("Expected unmapped fields to be kept, got %v", service.Fields)
+	}
+	if !strings.Contains(cursors[0], "i=4ece7") {
+		t.Errorf("Expected the entry's cursor, got %q", cursors[0])
+	}
+
+	kernel := entries[1]
+	if kernel.Source != "kernel" {
+		t.Errorf("Expected the identifier as source without a unit, got %q", kernel.Source)
+	}
+	if kernel.Level != models.LevelInfo {
+		t.Errorf("Expected INFO, got %v", kernel.Level)
+	}
+
+	binary := entries[2]
+	if binary.Message != "caf\xe9 backup" {
+		t.Errorf("Expected the byte array decoded, got %q", binary.Message)
+	}
+	if binary.Level != models.LevelWarning {
+		t.Errorf("Expected WARNING, got %v", binary.Level)
+	}
+	if tags, ok := binary.Fields["TAG"].([]interface{}); !ok || len(tags) != 2 {
+		t.Errorf("Expected a repeated field kept as a list, got %#v", binary.Fields["TAG"])
+	}
+}
+
+func TestJournalEntry_Invalid(t *testing.T) {
+	if _, _, err := journalEntry([]byte("not json")); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+
+	entry, cursor, err := journalEntry([]byte(`{"MESSAGE":"hello","PRIORITY":"urgent"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != "" {
+		t.Errorf("Expected no cursor, got %q", cursor)
+	}
+	if entry.Source != "journald" {
+		t.Errorf("Expected the fallback source, got %q", entry.Source)
+	}
+	if entry.Fields["PRIORITY"] != "urgent" {
+		t.Errorf("Expected an unknown priority kept as a field, got %v", entry.Fields["PRIORITY"])
+	}
+	if entry.Timestamp.IsZero() {
+		t.Error("Expected the ingest time without a journal timestamp")
+	}
+}
+
+func TestJournaldReader_Name(t *testing.T) {
+	if got := NewJournaldReader().Name(); got != "journald" {
+		t.Errorf("Expected journald, got %q", got)
+	}
+	reader := NewJournaldReaderWithOptions(JournaldOptions{Units: []string{"nginx.service", "sshd.service"}})
+	if got := reader.Name(); got != "journald:nginx.service,sshd.service" {
+		t.Errorf("Expected the units in the name, got %q", got)
+	}
+}
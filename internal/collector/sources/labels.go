@@ -0,0 +1,32 @@
+package sources
+
+import "github.com/fatihserhatturan/logflux/pkg/models"
+
+// SourceLabels tags every entry from a source, so entries from several
+// sources of the same kind can be told apart by the application they come
+// from rather than by a file path or transport
+type SourceLabels struct {
+	// SourceName replaces the source entries would otherwise carry, such as
+	// the file path or "syslog:udp"
+	SourceName string
+
+	// Labels are added to the fields of every entry, replacing fields of
+	// the same name
+	Labels map[string]string
+}
+
+// apply sets the source name and labels on an entry
+func (l SourceLabels) apply(entry *models.LogEntry) {
+	if l.SourceName != "" {
+		entry.Source = l.SourceName
+	}
+	if len(l.Labels) == 0 {
+		return
+	}
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]interface{}, len(l.Labels))
+	}
+	for name, value := range l.Labels {
+		entry.Fields[name] = value
+	}
+}
@@ -0,0 +1,77 @@
+package sources
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestFileReader_Labels(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "access.log")
+	content := `{"message":"GET /","source":"frontend","app":"overridden"}
+plain line
+{"message":"GET /health"}
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReaderWithFormat(testFile, FormatJSON)
+	reader.SetLabels(SourceLabels{SourceName: "nginx", Labels: map[string]string{"app": "nginx", "env": "prod"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	for i := 0; i < 3; i++ {
+		entry := receiveEntry(t, out)
+		if entry.Source != "nginx" {
+			t.Errorf("Expected source nginx for %q, got %q", entry.Message, entry.Source)
+		}
+		if entry.Fields["app"] != "nginx" || entry.Fields["env"] != "prod" {
+			t.Errorf("Expected the labels on %q, got %v", entry.Message, entry.Fields)
+		}
+	}
+}
+
+func TestSyslogReceiver_Labels(t *testing.T) {
+	template, err := ParseSourceTemplate("{hostname}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", "udp", SyslogReceiverOptions{SourceTemplate: template})
+	receiver.SetLabels(SourceLabels{SourceName: "edge-routers", Labels: map[string]string{"team": "network"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	conn, err := net.Dial("udp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, message := range []string{"<34>Oct 11 22:14:15 router1 bgpd: peer down", "<30>Oct 11 22:14:16 router2 bgpd: peer up"} {
+		if _, err := conn.Write([]byte(message)); err != nil {
+			t.Fatal(err)
+		}
+		entry := receiveEntry(t, out)
+		if entry.Source != "edge-routers" || entry.Fields["team"] != "network" {
+			t.Errorf("Expected the source name and labels over the template, got %q with %v", entry.Source, entry.Fields)
+		}
+	}
+}
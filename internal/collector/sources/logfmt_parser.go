@@ -0,0 +1,176 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// LogfmtParser maps a logfmt line (level=error msg="db down" user=42), as
+// written by Heroku and Go's log/slog text handler, into an entry. Known
+// keys (level/lvl, msg/message, source, ts/time/timestamp) go to the entry
+// itself, everything else ends up in Fields as text. A bare key without a
+// value is true. A key given more than once keeps all its values in order
+// as a list in Fields, a known one also sets the entry from its last. A
+// line without a message keeps the whole line as the message.
+type LogfmtParser struct {
+	// TimestampLayouts are the time.Parse layouts tried in order on
+	// timestamps. Without any, RFC 3339 and a few other common layouts are
+	// tried.
+	TimestampLayouts []string
+}
+
+// logfmtPair is one key and its value, true for a bare key
+type logfmtPair struct {
+	key   string
+	value interface{}
+}
+
+var errNoLogfmtPairs = errors.New("no key=value pairs")
+
+// Parse fails when raw holds no key=value pair or a malformed one
+func (p LogfmtParser) Parse(raw []byte, src string) (*models.LogEntry, error) {
+	line := strings.TrimRight(string(raw), "\r\n")
+	pairs, err := parseLogfmt(line)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group repeated keys, keeping the order keys first appear in
+	var keys []string
+	values := make(map[string][]interface{}, len(pairs))
+	for _, pair := range pairs {
+		if _, ok := values[pair.key]; !ok {
+			keys = append(keys, pair.key)
+		}
+		values[pair.key] = append(values[pair.key], pair.value)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Source = src
+	entry.Message = strings.TrimSpace(line)
+
+	for _, key := range keys {
+		all := values[key]
+		last, _ := all[len(all)-1].(string)
+		used := false
+		switch key {
+		case "level", "lvl":
+			// Unknown levels keep the INFO default
+			if level, err := models.ParseLevel(last); err == nil {
+				entry.Level = level
+				used = true
+			}
+		case "msg":
+			// "message" wins when there are both
+			if _, ok := values["message"]; !ok {
+				entry.Message = last
+				used = true
+			}
+		case "message":
+			entry.Message = last
+			used = true
+		case "source":
+			if last != "" {
+				entry.Source = last
+				used = true
+			}
+		case "ts", "time", "timestamp":
+			if ts, ok := parseTimestampLayouts(last, p.TimestampLayouts); ok {
+				setEventTime(entry, ts, last)
+				used = true
+			} else {
+				entry.Fields[FieldTimestampUnparsed] = true
+			}
+		}
+
+		switch {
+		case len(all) > 1:
+			entry.Fields[key] = all
+		case !used:
+			entry.Fields[key] = all[0]
+		}
+	}
+
+	if _, ok := entry.Fields[FieldRawTimestamp]; !ok {
+		markIngestTime(entry)
+	}
+
+	return entry, nil
+}
+
+// parseLogfmt splits a line into its pairs. Values are unquoted up to the
+// next space, or double quoted with Go escapes. At least one key must have
+// a value so plain text isn't taken for a list of bare keys.
+func parseLogfmt(line string) ([]logfmtPair, error) {
+	var pairs []logfmtPair
+	hasValue := false
+
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("unexpected %q at offset %d", line[i], i)
+		}
+		key := line[start:i]
+
+		if i >= len(line) || line[i] != '=' {
+			if i < len(line) && line[i] == '"' {
+				return nil, fmt.Errorf("unexpected quote in key %q", key)
+			}
+			pairs = append(pairs, logfmtPair{key: key, value: true})
+			continue
+		}
+		i++ // =
+		hasValue = true
+
+		if i < len(line) && line[i] == '"' {
+			end, err := quotedEnd(line, i)
+			if err != nil {
+				return nil, fmt.Errorf("value of %q: %w", key, err)
+			}
+			value, err := strconv.Unquote(line[i:end])
+			if err != nil {
+				return nil, fmt.Errorf("value of %q: invalid escape in %s", key, line[i:end])
+			}
+			pairs = append(pairs, logfmtPair{key: key, value: value})
+			i = end
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		pairs = append(pairs, logfmtPair{key: key, value: line[start:i]})
+	}
+
+	if !hasValue {
+		return nil, errNoLogfmtPairs
+	}
+	return pairs, nil
+}
+
+// quotedEnd returns the offset just past the quoted string starting at
+// start, skipping escaped quotes
+func quotedEnd(line string, start int) (int, error) {
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated quoted value")
+}
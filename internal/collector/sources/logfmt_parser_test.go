@@ -0,0 +1,147 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestLogfmtParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		message string
+		level   models.LogLevel
+		source  string
+		fields  map[string]interface{}
+	}{
+		{
+			name:    "unquoted",
+			line:    "level=error msg=boom user=42\n",
+			message: "boom",
+			level:   models.LevelError,
+			source:  "app.log",
+			fields:  map[string]interface{}{"user": "42"},
+		},
+		{
+			name:    "quoted values with spaces",
+			line:    `level=warn msg="disk almost full" path="/var/lib/data dir" pct=91`,
+			message: "disk almost full",
+			level:   models.LevelWarning,
+			source:  "app.log",
+			fields:  map[string]interface{}{"path": "/var/lib/data dir", "pct": "91"},
+		},
+		{
+			name:    "escapes",
+			line:    `msg="said \"hi\"\tthen left\\" note="line1\nline2" snowman="☃"`,
+			message: "said \"hi\"\tthen left\\",
+			level:   models.LevelInfo,
+			source:  "app.log",
+			fields:  map[string]interface{}{"note": "line1\nline2", "snowman": "☃"},
+		},
+		{
+			name:    "mixed quoted and unquoted with source",
+			line:    `lvl=debug source=api method=GET url="/users?id=7&x=y" status=200 took=1.5ms`,
+			message: `lvl=debug source=api method=GET url="/users?id=7&x=y" status=200 took=1.5ms`,
+			level:   models.LevelDebug,
+			source:  "api",
+			fields:  map[string]interface{}{"method": "GET", "url": "/users?id=7&x=y", "status": "200", "took": "1.5ms"},
+		},
+		{
+			name:    "bare keys and empty values",
+			line:    `msg=retrying cached err= attempt=2`,
+			message: "retrying",
+			level:   models.LevelInfo,
+			source:  "app.log",
+			fields:  map[string]interface{}{"cached": true, "err": "", "attempt": "2"},
+		},
+		{
+			name:    "duplicate keys",
+			line:    `msg=first tag=a tag=b msg=second`,
+			message: "second",
+			level:   models.LevelInfo,
+			source:  "app.log",
+			fields: map[string]interface{}{
+				"tag": []interface{}{"a", "b"},
+				"msg": []interface{}{"first", "second"},
+			},
+		},
+		{
+			name:    "message wins over msg",
+			line:    `message=kept msg=extra`,
+			message: "kept",
+			level:   models.LevelInfo,
+			source:  "app.log",
+			fields:  map[string]interface{}{"msg": "extra"},
+		},
+		{
+			name:    "unknown level",
+			line:    `level=loud msg=hi`,
+			message: "hi",
+			level:   models.LevelInfo,
+			source:  "app.log",
+			fields:  map[string]interface{}{"level": "loud"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := LogfmtParser{}.Parse([]byte(tt.line), "app.log")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Message != tt.message || entry.Level != tt.level || entry.Source != tt.source {
+				t.Errorf("Expected %q %s from %q, got %q %s from %q", tt.message, tt.level, tt.source, entry.Message, entry.Level, entry.Source)
+			}
+			for key, expected := range tt.fields {
+				if !reflect.DeepEqual(entry.Fields[key], expected) {
+					t.Errorf("Expected %s=%#v, got %#v", key, expected, entry.Fields[key])
+				}
+			}
+			for _, key := range []string{"level", "lvl", "source"} {
+				if _, expected := tt.fields[key]; !expected && entry.Fields[key] != nil {
+					t.Errorf("Expected %s on the entry only, got it in fields too", key)
+				}
+			}
+		})
+	}
+}
+
+func TestLogfmtParser_Timestamp(t *testing.T) {
+	entry, err := LogfmtParser{}.Parse([]byte(`time=2024-01-02T15:04:05.123Z level=INFO msg=started`), "app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC)
+	if !entry.Timestamp.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, entry.Timestamp)
+	}
+	if _, ok := entry.Fields["time"]; ok {
+		t.Error("Expected the parsed time on the entry only")
+	}
+
+	entry, err = LogfmtParser{}.Parse([]byte(`ts=yesterday msg=started`), "app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Fields[FieldTimestampUnparsed] != true || entry.Fields["ts"] != "yesterday" {
+		t.Errorf("Expected an unparsed timestamp kept in fields, got %v", entry.Fields)
+	}
+}
+
+func TestLogfmtParser_Invalid(t *testing.T) {
+	for _, line := range []string{
+		"plain text without pairs",
+		"",
+		`msg="unterminated`,
+		`msg="bad \q escape"`,
+		`=value`,
+		`ke"y=value`,
+	} {
+		if _, err := (LogfmtParser{}).Parse([]byte(line), "app.log"); err == nil {
+			t.Errorf("Expected an error for %q", line)
+		}
+	}
+}
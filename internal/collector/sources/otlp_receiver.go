@@ -0,0 +1,487 @@
+//go:build !no_source_otlp
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Fields holding the trace context of entries received over OTLP, as hex
+const (
+	FieldTraceID = "trace_id"
+	FieldSpanID  = "span_id"
+)
+
+// otlpLogsPath is the OTLP/HTTP logs endpoint
+const otlpLogsPath = "/v1/logs"
+
+// OTLPReceiverOptions configures an OTLPReceiver
+type OTLPReceiverOptions struct {
+	// SendTimeout is how long a request waits for room in the output channel
+	// for each record. Records that still don't fit are reported back as
+	// rejected. Defaults to 5s.
+	SendTimeout time.Duration
+
+	// MaxBodyBytes caps a request body as sent. Defaults to 10MB.
+	MaxBodyBytes int64
+
+	// MaxDecompressedSize caps a gzip request body after decompression.
+	// Defaults to 10MB.
+	MaxDecompressedSize int64
+
+	// ReadHeaderTimeout bounds how long a client may take to send the
+	// request headers. Defaults to 5s.
+	ReadHeaderTimeout time.Duration
+
+	// ShutdownTimeout bounds how long Stop waits for in-flight requests.
+	// Defaults to 5s.
+	ShutdownTimeout time.Duration
+}
+
+// OTLPReceiver serves the OTLP/HTTP logs endpoint, so applications and
+// other collectors can export logs with an OpenTelemetry SDK or exporter.
+// Requests may be protobuf or JSON encoded, optionally gzipped. Records map
+// onto entries the way the OTLP sink maps entries onto records.
+type OTLPReceiver struct {
+	addr string
+	opts OTLPReceiverOptions
+
+	mu       sync.Mutex
+	running  bool
+	server   *http.Server
+	listener net.Listener
+	out      chan<- *models.LogEntry
+
+	labels SourceLabels
+	stats  sourceStats
+}
+
+// NewOTLPReceiver creates an OTLP receiver
+func NewOTLPReceiver(addr string) *OTLPReceiver {
+	return NewOTLPReceiverWithOptions(addr, OTLPReceiverOptions{})
+}
+
+// NewOTLPReceiverWithOptions creates an OTLP receiver with custom options
+func NewOTLPReceiverWithOptions(addr string, opts OTLPReceiverOptions) *OTLPReceiver {
+	if opts.SendTimeout <= 0 {
+		opts.SendTimeout = 5 * time.Second
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.MaxDecompressedSize <= 0 {
+		opts.MaxDecompressedSize = DefaultMaxDecompressedSize
+	}
+	if opts.ReadHeaderTimeout <= 0 {
+		opts.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	return &OTLPReceiver{addr: addr, opts: opts}
+}
+
+// SetLabels names the source and labels every entry received. It must be
+// called before Start.
+func (ot *OTLPReceiver) SetLabels(labels SourceLabels) {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	ot.labels = labels
+}
+
+// Start begins listening for export requests
+func (ot *OTLPReceiver) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+
+	if ot.running {
+		return sourceError(models.AlreadyRunning, "OTLP receiver already running")
+	}
+
+	// Bind before returning so address errors reach the caller
+	listener, err := net.Listen("tcp", ot.addr)
+	if err != nil {
+		return sourceError(listenErrorCode(err), "failed to listen on %s: %w", ot.addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(otlpLogsPath, ot.handleLogs)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: ot.opts.ReadHeaderTimeout,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      ot.opts.SendTimeout + 10*time.Second,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+
+	ot.running = true
+	ot.server = server
+	ot.listener = listener
+	ot.out = out
+
+	fmt.Printf("📡 OTLP receiver listening on %s%s\n", ot.addr, otlpLogsPath)
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("OTLP server error: %v\n", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		ot.Stop()
+	}()
+
+	return nil
+}
+
+// otlpEncoding is how a request body and its response are encoded
+type otlpEncoding struct {
+	contentType string
+	unmarshal   func([]byte, *collogspb.ExportLogsServiceRequest) error
+	marshal     func(*collogspb.ExportLogsServiceResponse) ([]byte, error)
+}
+
+var (
+	otlpProtobuf = otlpEncoding{
+		contentType: "application/x-protobuf",
+		unmarshal: func(body []byte, req *collogspb.ExportLogsServiceRequest) error {
+			return proto.Unmarshal(body, req)
+		},
+		marshal: func(resp *collogspb.ExportLogsServiceResponse) ([]byte, error) {
+			return proto.Marshal(resp)
+		},
+	}
+	otlpJSON = otlpEncoding{
+		contentType: "application/json",
+		unmarshal:   unmarshalOTLPJSON,
+		marshal: func(resp *collogspb.ExportLogsServiceResponse) ([]byte, error) {
+			return protojson.Marshal(resp)
+		},
+	}
+)
+
+// handleLogs decodes an export request and sends its records
+func (ot *OTLPReceiver) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var encoding otlpEncoding
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case otlpProtobuf.contentType:
+		encoding = otlpProtobuf
+	case otlpJSON.contentType:
+		encoding = otlpJSON
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported Content-Type: %s", mediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := readRequestBody(w, r, ot.opts.MaxBodyBytes, ot.opts.MaxDecompressedSize)
+	if err != nil {
+		ot.stats.recordError()
+		writeBodyError(w, err)
+		return
+	}
+	metrics.BytesReceived.WithLabelValues(ot.Name()).Add(int64(len(body)))
+	ot.stats.recordBytes(len(body))
+
+	req := &collogspb.ExportLogsServiceRequest{}
+	if err := encoding.unmarshal(body, req); err != nil {
+		ot.stats.recordError()
+		http.Error(w, fmt.Sprintf("Invalid export request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	entries := otlpEntries(req)
+	sent := 0
+	for _, entry := range entries {
+		if !ot.send(r.Context(), entry) {
+			break
+		}
+		sent++
+	}
+
+	// Nothing sent is worth retrying as a whole. Once some records are in,
+	// the rest are reported rejected, a retry would duplicate the others.
+	if sent == 0 && len(entries) > 0 {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Channel full", http.StatusServiceUnavailable)
+		return
+	}
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if rejected := len(entries) - sent; rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: int64(rejected),
+			ErrorMessage:       "channel full",
+		}
+	}
+
+	data, err := encoding.marshal(resp)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", encoding.contentType)
+	w.Write(data)
+}
+
+// send pushes an entry to the output channel, waiting up to SendTimeout
+// for room
+func (ot *OTLPReceiver) send(ctx context.Context, entry *models.LogEntry) bool {
+	metrics.EntriesReceived.WithLabelValues(ot.Name()).Inc()
+
+	ot.mu.Lock()
+	ot.labels.apply(entry)
+	out := ot.out
+	ot.mu.Unlock()
+
+	timer := time.NewTimer(ot.opts.SendTimeout)
+	defer timer.Stop()
+
+	select {
+	case out <- entry:
+		ot.stats.recordEntry()
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	metrics.EntriesDropped.WithLabelValues(metrics.ReasonChannelFull).Inc()
+	return false
+}
+
+// Stop stops accepting requests and waits for in-flight ones
+func (ot *OTLPReceiver) Stop() error {
+	ot.mu.Lock()
+	if !ot.running {
+		ot.mu.Unlock()
+		return nil
+	}
+	ot.running = false
+	server := ot.server
+	ot.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ot.opts.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		return fmt.Errorf("OTLP receiver did not drain within %s, in-flight entries may be lost: %w",
+			ot.opts.ShutdownTimeout, err)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the receiver's activity
+func (ot *OTLPReceiver) Stats() models.SourceStats {
+	return ot.stats.snapshot()
+}
+
+// Addr returns the address the receiver is listening on. Before Start it
+// returns the configured address.
+func (ot *OTLPReceiver) Addr() string {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+
+	if ot.listener != nil {
+		return ot.listener.Addr().String()
+	}
+	return ot.addr
+}
+
+// Name returns the source name
+func (ot *OTLPReceiver) Name() string {
+	return fmt.Sprintf("otlp:%s", ot.addr)
+}
+
+// unmarshalOTLPJSON decodes the OTLP JSON encoding. It is protobuf's JSON
+// mapping except that trace and span IDs are hex rather than base64, so
+// those are rewritten before decoding.
+func unmarshalOTLPJSON(body []byte, req *collogspb.ExportLogsServiceRequest) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+	if err := hexIDsToBase64(doc); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+}
+
+// hexIDsToBase64 rewrites trace and span IDs anywhere in a decoded document
+func hexIDsToBase64(doc interface{}) error {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch key {
+			case "traceId", "trace_id", "spanId", "span_id":
+				id, ok := value.(string)
+				if !ok {
+					continue
+				}
+				raw, err := hex.DecodeString(id)
+				if err != nil {
+					return fmt.Errorf("%s: invalid hex %q", key, id)
+				}
+				v[key] = base64.StdEncoding.EncodeToString(raw)
+			default:
+				if err := hexIDsToBase64(value); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := hexIDsToBase64(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// otlpEntries maps every record of a request onto an entry
+func otlpEntries(req *collogspb.ExportLogsServiceRequest) []*models.LogEntry {
+	var entries []*models.LogEntry
+	for _, resourceLogs := range req.ResourceLogs {
+		resource := resourceLogs.GetResource().GetAttributes()
+		for _, scopeLogs := range resourceLogs.ScopeLogs {
+			for _, record := range scopeLogs.LogRecords {
+				entries = append(entries, otlpEntry(resource, record))
+			}
+		}
+	}
+	return entries
+}
+
+// otlpEntry maps a record onto an entry. Resource attributes and record
+// attributes both become fields, the record's winning on a clash. A
+// "source" attribute, as the OTLP sink sets, becomes the entry's source,
+// falling back to the service name.
+func otlpEntry(resource []*commonpb.KeyValue, record *logspb.LogRecord) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Source = "otlp"
+	entry.Level = otlpLevel(record.SeverityNumber, record.SeverityText)
+
+	for _, kv := range resource {
+		entry.Fields[kv.Key] = otlpValue(kv.Value)
+	}
+	if service, ok := entry.Fields["service.name"].(string); ok && service != "" {
+		entry.Source = service
+	}
+	for _, kv := range record.Attributes {
+		entry.Fields[kv.Key] = otlpValue(kv.Value)
+	}
+	if source, ok := entry.Fields["source"].(string); ok && source != "" {
+		entry.Source = source
+		delete(entry.Fields, "source")
+	}
+
+	switch body := otlpValue(record.Body).(type) {
+	case string:
+		entry.Message = body
+	case nil:
+	default:
+		data, _ := json.Marshal(body)
+		entry.Message = string(data)
+	}
+
+	if len(record.TraceId) > 0 {
+		entry.Fields[FieldTraceID] = hex.EncodeToString(record.TraceId)
+	}
+	if len(record.SpanId) > 0 {
+		entry.Fields[FieldSpanID] = hex.EncodeToString(record.SpanId)
+	}
+
+	switch {
+	case record.TimeUnixNano > 0:
+		entry.Timestamp = time.Unix(0, int64(record.TimeUnixNano))
+	case record.ObservedTimeUnixNano > 0:
+		entry.Timestamp = time.Unix(0, int64(record.ObservedTimeUnixNano))
+		markIngestTime(entry)
+	default:
+		markIngestTime(entry)
+	}
+	return entry
+}
+
+// otlpLevel maps a severity number range onto a level, falling back to the
+// severity text when the number is unspecified
+func otlpLevel(number logspb.SeverityNumber, text string) models.LogLevel {
+	switch {
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return models.LevelCritical
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return models.LevelError
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return models.LevelWarning
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return models.LevelInfo
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return models.LevelDebug
+	}
+	if level, err := models.ParseLevel(text); err == nil {
+		return level
+	}
+	return models.LevelInfo
+}
+
+// otlpValue converts an attribute value into the types fields hold
+func otlpValue(value *commonpb.AnyValue) interface{} {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return v.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]interface{}, len(v.ArrayValue.GetValues()))
+		for i, item := range v.ArrayValue.GetValues() {
+			values[i] = otlpValue(item)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]interface{}, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.Key] = otlpValue(kv.Value)
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,250 @@
+//go:build !no_source_otlp
+
+package sources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func startOTLPReceiver(t *testing.T, out chan *models.LogEntry, opts OTLPReceiverOptions) string {
+	t.Helper()
+	receiver := NewOTLPReceiverWithOptions("127.0.0.1:0", opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { receiver.Stop() })
+	return "http://" + receiver.Addr() + "/v1/logs"
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func otlpRequest(records ...*logspb.LogRecord) *collogspb.ExportLogsServiceRequest {
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				{Key: "service.name", Value: stringValue("checkout")},
+				{Key: "env", Value: stringValue("prod")},
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+		}},
+	}
+}
+
+func postOTLP(t *testing.T, url, contentType string, body []byte) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestOTLPReceiver_Protobuf(t *testing.T) {
+	out := make(chan *models.LogEntry, 10)
+	url := startOTLPReceiver(t, out, OTLPReceiverOptions{})
+
+	ts := time.Unix(1700000000, 123)
+	severities := []struct {
+		number logspb.SeverityNumber
+		text   string
+		level  models.LogLevel
+	}{
+		{logspb.SeverityNumber_SEVERITY_NUMBER_TRACE2, "", models.LevelDebug},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "", models.LevelDebug},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_INFO4, "", models.LevelInfo},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_WARN, "", models.LevelWarning},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_ERROR3, "", models.LevelError},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, "", models.LevelCritical},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "warn", models.LevelWarning},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "", models.LevelInfo},
+	}
+	var records []*logspb.LogRecord
+	for _, severity := range severities {
+		records = append(records, &logspb.LogRecord{
+			TimeUnixNano:   uint64(ts.UnixNano()),
+			SeverityNumber: severity.number,
+			SeverityText:   severity.text,
+			Body:           stringValue("payment failed"),
+		})
+	}
+	records[0].TraceId = []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}
+	records[0].SpanId = []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74}
+	records[0].Attributes = []*commonpb.KeyValue{
+		{Key: "source", Value: stringValue("payments")},
+		{Key: "env", Value: stringValue("canary")},
+		{Key: "status", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 502}}},
+		{Key: "cached", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+		{Key: "user", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+			Values: []*commonpb.KeyValue{{Key: "id", Value: stringValue("42")}},
+		}}}},
+	}
+
+	body, err := proto.Marshal(otlpRequest(records...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := postOTLP(t, url, "application/x-protobuf", body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("Expected a protobuf response, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	for i, severity := range severities {
+		entry := receiveEntry(t, out)
+		if entry.Level != severity.level {
+			t.Errorf("Expected %s for %s %q, got %s", severity.level, severity.number, severity.text, entry.Level)
+		}
+		if entry.Message != "payment failed" || !entry.Timestamp.Equal(ts) {
+			t.Errorf("Expected the body and time mapped, got %q at %v", entry.Message, entry.Timestamp)
+		}
+		if i == 0 {
+			if entry.Source != "payments" {
+				t.Errorf("Expected the source attribute as the source, got %q", entry.Source)
+			}
+			if entry.Fields[FieldTraceID] != "5b8efff798038103d269b633813fc60c" || entry.Fields[FieldSpanID] != "eee19b7ec3c1b174" {
+				t.Errorf("Expected hex trace context, got %v and %v", entry.Fields[FieldTraceID], entry.Fields[FieldSpanID])
+			}
+			if entry.Fields["env"] != "canary" || entry.Fields["status"] != int64(502) || entry.Fields["cached"] != true {
+				t.Errorf("Expected typed attributes over the resource's, got %v", entry.Fields)
+			}
+			if user, ok := entry.Fields["user"].(map[string]interface{}); !ok || user["id"] != "42" {
+				t.Errorf("Expected a nested user, got %#v", entry.Fields["user"])
+			}
+			continue
+		}
+		if entry.Source != "checkout" || entry.Fields["env"] != "prod" {
+			t.Errorf("Expected the service name as source and resource fields, got %q %v", entry.Source, entry.Fields)
+		}
+		if _, ok := entry.Fields[FieldTraceID]; ok {
+			t.Error("Expected no trace ID without one")
+		}
+	}
+}
+
+func TestOTLPReceiver_JSON(t *testing.T) {
+	out := make(chan *models.LogEntry, 10)
+	url := startOTLPReceiver(t, out, OTLPReceiverOptions{})
+
+	body := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+		"scopeLogs":[{"scope":{"name":"app"},"logRecords":[{
+			"timeUnixNano":"1700000000000000123",
+			"severityNumber":17,
+			"severityText":"Error",
+			"traceId":"5b8efff798038103d269b633813fc60c",
+			"spanId":"eee19b7ec3c1b174",
+			"body":{"stringValue":"db down"},
+			"attributes":[{"key":"retries","value":{"intValue":"3"}},{"key":"ratio","value":{"doubleValue":0.5}}]
+		},{
+			"observedTimeUnixNano":1700000001000000000,
+			"severityNumber":"SEVERITY_NUMBER_WARN",
+			"body":{"kvlistValue":{"values":[{"key":"event","value":{"stringValue":"slow"}}]}}
+		}]}]}]}`
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(body))
+	w.Close()
+	req, _ := http.NewRequest(http.MethodPost, url, &gz)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a 200 JSON response, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	entry := receiveEntry(t, out)
+	if entry.Level != models.LevelError || entry.Message != "db down" || entry.Source != "api" {
+		t.Errorf("Expected ERROR db down from api, got %s %q from %q", entry.Level, entry.Message, entry.Source)
+	}
+	if !entry.Timestamp.Equal(time.Unix(1700000000, 123)) {
+		t.Errorf("Expected the record time, got %v", entry.Timestamp)
+	}
+	if entry.Fields[FieldTraceID] != "5b8efff798038103d269b633813fc60c" || entry.Fields[FieldSpanID] != "eee19b7ec3c1b174" {
+		t.Errorf("Expected the hex trace context kept, got %v and %v", entry.Fields[FieldTraceID], entry.Fields[FieldSpanID])
+	}
+	if entry.Fields["retries"] != int64(3) || entry.Fields["ratio"] != 0.5 {
+		t.Errorf("Expected typed attributes, got %v", entry.Fields)
+	}
+
+	entry = receiveEntry(t, out)
+	if entry.Level != models.LevelWarning || entry.Message != `{"event":"slow"}` {
+		t.Errorf("Expected a WARNING with a JSON body, got %s %q", entry.Level, entry.Message)
+	}
+	if !entry.Timestamp.Equal(time.Unix(1700000001, 0)) || entry.Fields[FieldTimestampSource] != TimestampSourceIngest {
+		t.Errorf("Expected the observed time marked as ingest time, got %v %v", entry.Timestamp, entry.Fields)
+	}
+}
+
+func TestOTLPReceiver_Rejects(t *testing.T) {
+	out := make(chan *models.LogEntry, 1)
+	url := startOTLPReceiver(t, out, OTLPReceiverOptions{SendTimeout: 50 * time.Millisecond})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"unsupported content type", "text/plain", "hello", http.StatusUnsupportedMediaType},
+		{"invalid protobuf", "application/x-protobuf", "\xff\xff", http.StatusBadRequest},
+		{"invalid JSON", "application/json", "{", http.StatusBadRequest},
+		{"invalid trace ID", "application/json", `{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"xyz"}]}]}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postOTLP(t, url, tt.contentType, []byte(tt.body))
+			if resp.StatusCode != tt.status {
+				msg, _ := io.ReadAll(resp.Body)
+				t.Errorf("Expected %d, got %d: %s", tt.status, resp.StatusCode, strings.TrimSpace(string(msg)))
+			}
+		})
+	}
+
+	// Two records into a channel with room for one: the second is rejected
+	two := otlpRequest(&logspb.LogRecord{Body: stringValue("a")}, &logspb.LogRecord{Body: stringValue("b")})
+	body, _ := proto.Marshal(two)
+	resp := postOTLP(t, url, "application/x-protobuf", body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 with a partial success, got %d", resp.StatusCode)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	result := &collogspb.ExportLogsServiceResponse{}
+	if err := proto.Unmarshal(data, result); err != nil {
+		t.Fatal(err)
+	}
+	if result.GetPartialSuccess().GetRejectedLogRecords() != 1 {
+		t.Errorf("Expected 1 rejected record, got %v", result.GetPartialSuccess())
+	}
+
+	// Nothing fits at all: the whole request may be retried
+	resp = postOTLP(t, url, "application/x-protobuf", body)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with a full channel, got %d", resp.StatusCode)
+	}
+}
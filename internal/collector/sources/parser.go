@@ -0,0 +1,218 @@
+package sources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Parser turns a raw line or message into a log entry. src names where it
+// was read from and is the entry's source unless the parser finds one in
+// the message. Sources that take a Parser keep what it fails on as a raw
+// entry.
+type Parser interface {
+	Parse(raw []byte, src string) (*models.LogEntry, error)
+}
+
+// FieldRaw holds the untouched line or message an entry was parsed from,
+// for sources asked to keep it
+const FieldRaw = "_raw"
+
+// FieldUnparsed marks an entry whose message is a raw line left for the
+// pipeline's parse pool, which removes it
+const FieldUnparsed = "_unparsed"
+
+// markUnparsed sets the entry's FieldUnparsed
+func markUnparsed(entry *models.LogEntry) {
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]interface{})
+	}
+	entry.Fields[FieldUnparsed] = true
+}
+
+// keepRaw stores raw in the entry's FieldRaw
+func keepRaw(entry *models.LogEntry, raw string) {
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]interface{})
+	}
+	entry.Fields[FieldRaw] = raw
+}
+
+// parseOrRaw parses raw with p, falling back to RawParser when p fails
+func parseOrRaw(p Parser, raw []byte, src string) *models.LogEntry {
+	entry, _ := tryParse(p, raw, src)
+	return entry
+}
+
+// DeadLetter takes input that failed to parse, see collector.DeadLetter.
+// Record returns an error when the input couldn't be kept.
+type DeadLetter interface {
+	Record(source string, raw []byte, err error) error
+}
+
+// tryParse parses raw with p. When p fails it returns why, along with a
+// raw entry to fall back on.
+func tryParse(p Parser, raw []byte, src string) (*models.LogEntry, error) {
+	entry, err := p.Parse(raw, src)
+	if err == nil {
+		return entry, nil
+	}
+	entry, _ = RawParser{}.Parse(raw, src)
+	return entry, err
+}
+
+// RawParser keeps the whole line as the message, timestamped on receipt
+type RawParser struct{}
+
+// Parse never fails
+func (RawParser) Parse(raw []byte, src string) (*models.LogEntry, error) {
+	entry := models.NewLogEntry()
+	entry.Source = src
+	entry.Message = string(raw)
+	markIngestTime(entry)
+	return entry, nil
+}
+
+// JSONParser maps a JSON object into an entry. Known keys (level,
+// message/msg, source, ts/timestamp) go to the entry itself, everything else
+// ends up in Fields. Numeric timestamps are seconds, milliseconds,
+// microseconds or nanoseconds since the epoch, told apart by magnitude.
+type JSONParser struct {
+	// TimestampLayouts are the time.Parse layouts tried in order on string
+	// timestamps. Without any, RFC 3339 and a few other common layouts are
+	// tried.
+	TimestampLayouts []string
+}
+
+// Parse fails when raw is not a JSON object
+func (p JSONParser) Parse(raw []byte, src string) (*models.LogEntry, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, errors.New("not a JSON object")
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(trimmed), &obj); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Source = src
+
+	for key, value := range obj {
+		switch key {
+		case "level":
+			// Unknown levels keep the INFO default
+			if s, ok := value.(string); ok {
+				if level, err := models.ParseLevel(s); err == nil {
+					entry.Level = level
+				}
+				continue
+			}
+		case "message", "msg":
+			// Handled below so "message" wins over "msg"
+			if _, ok := value.(string); ok {
+				continue
+			}
+		case "source":
+			if s, ok := value.(string); ok && s != "" {
+				entry.Source = s
+				continue
+			}
+		case "ts", "timestamp":
+			if ts, ok := parseJSONTimestamp(value, p.TimestampLayouts); ok {
+				setEventTime(entry, ts, jsonTimestampText(value))
+				continue
+			}
+			entry.Fields[FieldTimestampUnparsed] = true
+		}
+		// Unknown key, or a known key with an unusable value
+		entry.Fields[key] = value
+	}
+
+	if msg, ok := obj["message"].(string); ok {
+		entry.Message = msg
+		if extra, ok := obj["msg"].(string); ok {
+			entry.Fields["msg"] = extra
+		}
+	} else if msg, ok := obj["msg"].(string); ok {
+		entry.Message = msg
+	}
+
+	if _, ok := entry.Fields[FieldRawTimestamp]; !ok {
+		markIngestTime(entry)
+	}
+
+	return entry, nil
+}
+
+// SyslogParser decodes syslog messages. RFC 5424 messages (<pri>1 ...) are
+// fully decoded; anything else is treated as RFC 3164 where only the
+// <priority> prefix and timestamp are extracted. A CEF payload in either is
+// decoded into fields. The level comes from the CEF severity or the
+// priority's severity, falling back to keyword detection without either.
+type SyslogParser struct{}
+
+// Parse never fails, a message that isn't syslog is kept whole
+func (SyslogParser) Parse(raw []byte, src string) (*models.LogEntry, error) {
+	message := string(raw)
+
+	entry := models.NewLogEntry()
+	entry.Source = src
+	entry.Message = message
+
+	hasPriority := false
+
+	// Try to extract priority (RFC 3164)
+	if strings.HasPrefix(message, "<") {
+		endIdx := strings.Index(message, ">")
+		if endIdx > 0 && endIdx < 10 {
+			// Priority found, extract it
+			pri := message[1:endIdx]
+			entry.Fields["priority"] = priorityField(pri)
+			message = message[endIdx+1:]
+
+			if facility, severity, ok := decodePriority(pri); ok {
+				hasPriority = true
+				entry.Fields["facility"] = facilityFields[facility]
+				entry.Fields["severity"] = severityFields[severity]
+				entry.Level = severityLevel(severity)
+			}
+		}
+	}
+
+	// Store raw message for later parsing
+	entry.Fields["raw"] = message
+
+	// Text used for level detection
+	text := message
+
+	if msg, err := parseRFC5424(message); err == nil {
+		applyRFC5424(entry, msg)
+		text = msg.Message
+	} else if ts, rawTS, rest, ok := parseRFC3164Timestamp(message, time.Now()); ok {
+		setEventTime(entry, ts, rawTS)
+		applyRFC3164Header(entry, rest)
+	}
+
+	if _, ok := entry.Fields[FieldRawTimestamp]; !ok {
+		markIngestTime(entry)
+	}
+
+	// CEF severity is more specific than the syslog priority
+	if payload, ok := findCEF(text); ok {
+		if msg, err := parseCEF(payload); err == nil && applyCEF(entry, msg) {
+			return entry, nil
+		}
+	}
+
+	if !hasPriority {
+		entry.Level = detectLevel(text)
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,164 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// sameEntry compares everything but the ID, and the timestamp of entries
+// timestamped on receipt
+func sameEntry(t *testing.T, expected, got *models.LogEntry) {
+	t.Helper()
+	if got.Message != expected.Message || got.Level != expected.Level || got.Source != expected.Source {
+		t.Errorf("Expected %q %s from %q, got %q %s from %q",
+			expected.Message, expected.Level, expected.Source, got.Message, got.Level, got.Source)
+	}
+	if !reflect.DeepEqual(got.Fields, expected.Fields) {
+		t.Errorf("Expected fields %v, got %v", expected.Fields, got.Fields)
+	}
+	if _, ok := expected.Fields[FieldRawTimestamp]; ok && !got.Timestamp.Equal(expected.Timestamp) {
+		t.Errorf("Expected timestamp %v, got %v", expected.Timestamp, got.Timestamp)
+	}
+}
+
+func TestRawParser(t *testing.T) {
+	entry, err := RawParser{}.Parse([]byte(`{"message": "kept whole"}`), "app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Message != `{"message": "kept whole"}` || entry.Source != "app.log" || entry.Level != models.LevelInfo {
+		t.Errorf("Expected the line as an INFO message from app.log, got %q %s from %q", entry.Message, entry.Level, entry.Source)
+	}
+	if entry.Fields[FieldTimestampSource] != TimestampSourceIngest {
+		t.Errorf("Expected an ingest timestamp, got %v", entry.Fields)
+	}
+}
+
+func TestJSONParser(t *testing.T) {
+	entry, err := JSONParser{}.Parse([]byte(`{"level":"error","msg":"db down","source":"api","ts":1704207845,"user_id":42}`), "app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Message != "db down" || entry.Source != "api" || entry.Level != models.LevelError {
+		t.Errorf("Expected an ERROR db down from api, got %q %s from %q", entry.Message, entry.Level, entry.Source)
+	}
+	if !entry.Timestamp.Equal(time.Unix(1704207845, 0)) {
+		t.Errorf("Expected the epoch timestamp, got %v", entry.Timestamp)
+	}
+	if entry.Fields["user_id"] != float64(42) {
+		t.Errorf("Expected user_id in fields, got %v", entry.Fields)
+	}
+
+	for _, line := range []string{"plain text", `["array"]`, `{"broken`} {
+		if _, err := (JSONParser{}).Parse([]byte(line), "app.log"); err == nil {
+			t.Errorf("Expected an error for %q", line)
+		}
+	}
+}
+
+func TestSyslogParser(t *testing.T) {
+	entry, err := SyslogParser{}.Parse([]byte("<34>Oct 11 22:14:15 mymachine su: 'su root' failed"), "syslog:udp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Level != models.LevelCritical || entry.Source != "syslog:udp" {
+		t.Errorf("Expected CRITICAL from syslog:udp, got %s from %q", entry.Level, entry.Source)
+	}
+	if entry.Fields["facility"] != "auth" || entry.Fields[FieldRawTimestamp] != "Oct 11 22:14:15" {
+		t.Errorf("Expected the priority and timestamp decoded, got %v", entry.Fields)
+	}
+}
+
+func TestDefaultParsers_MatchSources(t *testing.T) {
+	rawReader := NewFileReader("/var/log/app.log")
+	jsonReader := NewFileReaderWithFormat("/var/log/app.log", FormatJSON)
+	syslog := NewSyslogReceiver("127.0.0.1:0", "udp")
+	parseSyslog := func(line string) *models.LogEntry { return syslog.parseSyslogMessage([]byte(line)) }
+
+	tests := []struct {
+		name   string
+		source func(string) *models.LogEntry
+		parser Parser
+		src    string
+		line   string
+	}{
+		{"raw file", rawReader.parseLine, RawParser{}, "/var/log/app.log", "plain line"},
+		{"json file", jsonReader.parseLine, JSONParser{}, "/var/log/app.log", `{"level":"warn","message":"slow","ts":"2024-01-02T15:04:05Z","ms":812}`},
+		{"json file, plain line", jsonReader.parseLine, RawParser{}, "/var/log/app.log", "not json"},
+		{"syslog 3164", parseSyslog, SyslogParser{}, "syslog:udp", "<34>Oct 11 22:14:15 mymachine su: 'su root' failed"},
+		{"syslog 5424", parseSyslog, SyslogParser{}, "syslog:udp", `<165>1 2003-10-11T22:14:15.003Z host app 1 ID47 [exampleSDID@32473 iut="3"] started`},
+		{"syslog CEF", parseSyslog, SyslogParser{}, "syslog:udp", "<13>CEF:0|Security|IDS|1.0|100|Attack|9|src=10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := tt.parser.Parse([]byte(tt.line), tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameEntry(t, expected, tt.source(tt.line))
+		})
+	}
+}
+
+func TestSyslogReceiver_SetParser(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+	receiver.SetParser(JSONParser{})
+
+	entry := receiver.parseSyslogMessage([]byte(`{"level":"error","message":"from json"}`))
+	if entry.Message != "from json" || entry.Level != models.LevelError {
+		t.Errorf("Expected the JSON parsed, got %q %s", entry.Message, entry.Level)
+	}
+
+	// What the parser rejects is kept raw
+	entry = receiver.parseSyslogMessage([]byte("<34>not json"))
+	if entry.Message != "<34>not json" || entry.Fields["priority"] != nil {
+		t.Errorf("Expected a raw entry, got %q %v", entry.Message, entry.Fields)
+	}
+}
+
+func TestHTTPReceiver_SetParser(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+	receiver.SetParser(SyslogParser{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	resp, err := http.Post("http://"+addr+"/logs?source=relay", "text/plain",
+		strings.NewReader("<165>1 2003-10-11T22:14:15.003Z host app 1 ID47 - started\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	entry := receiveEntry(t, out)
+	if entry.Message != "started" || entry.Source != "app" || entry.Level != models.LevelInfo {
+		t.Errorf("Expected the syslog message parsed, got %q %s from %q", entry.Message, entry.Level, entry.Source)
+	}
+
+	// JSON bodies don't go through the parser
+	resp, err = http.Post("http://"+addr+"/logs", "application/json", strings.NewReader(`{"message": "json"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if entry := receiveEntry(t, out); entry.Message != "json" || entry.Source != "http" {
+		t.Errorf("Expected the JSON payload, got %q from %q", entry.Message, entry.Source)
+	}
+}
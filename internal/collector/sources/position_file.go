@@ -0,0 +1,36 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// loadPosition reads a position saved by savePosition, such as an event
+// log bookmark or a journal cursor. It is empty when none was saved yet.
+func loadPosition(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read position: %w", err)
+	}
+	return string(data), nil
+}
+
+// savePosition replaces the saved position. Writing a temporary file and
+// renaming it over the old one means a crash leaves either position whole.
+func savePosition(path, position string) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(position), 0644); err != nil {
+		return fmt.Errorf("failed to save position: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save position: %w", err)
+	}
+	return nil
+}
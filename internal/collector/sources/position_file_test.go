@@ -0,0 +1,29 @@
+package sources
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPosition_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "application.bookmark")
+
+	if position, err := loadPosition(path); err != nil || position != "" {
+		t.Fatalf("Expected no position yet, got %q (%v)", position, err)
+	}
+
+	const saved = `<BookmarkList><Bookmark Channel="Application" RecordId="4242" IsCurrent="true"/></BookmarkList>`
+	if err := savePosition(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	if position, err := loadPosition(path); err != nil || position != saved {
+		t.Errorf("Expected the saved position, got %q (%v)", position, err)
+	}
+
+	if err := savePosition(path, "s=next"); err != nil {
+		t.Fatal(err)
+	}
+	if position, _ := loadPosition(path); position != "s=next" {
+		t.Errorf("Expected the position replaced, got %q", position)
+	}
+}
@@ -0,0 +1,249 @@
+package sources
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// ReplayOptions configures a ReplayReader
+type ReplayOptions struct {
+	// Speed divides the original gaps between entries, so 2 replays twice
+	// as fast and 0.5 at half speed. Defaults to 1.
+	Speed float64
+
+	// IgnoreTiming replays entries as fast as the pipeline accepts them
+	IgnoreTiming bool
+
+	// KeepRaw keeps each captured line exactly as read in FieldRaw,
+	// replacing any the capture carried
+	KeepRaw bool
+}
+
+// maxReplayLineSize caps one captured entry
+const maxReplayLineSize = 1 << 20
+
+// ReplayReader replays a JSONL capture of log entries, such as the output
+// of the file sink, reproducing the original spacing between timestamps.
+// Replayed entries keep their timestamp, level, source, message and fields
+// but get a fresh ID.
+type ReplayReader struct {
+	path string
+	opts ReplayOptions
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	run     *runState
+
+	labels SourceLabels
+	stats  sourceStats
+}
+
+// NewReplayReader creates a replay reader at the original pace
+func NewReplayReader(path string) *ReplayReader {
+	return NewReplayReaderWithOptions(path, ReplayOptions{})
+}
+
+// NewReplayReaderWithOptions creates a replay reader with custom options
+func NewReplayReaderWithOptions(path string, opts ReplayOptions) *ReplayReader {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+
+	return &ReplayReader{
+		path: path,
+		opts: opts,
+		run:  newRunState(),
+	}
+}
+
+// SetLabels names the source and labels every replayed entry, over what
+// was captured. It must be called before Start.
+func (rr *ReplayReader) SetLabels(labels SourceLabels) {
+	rr.labels = labels
+}
+
+// Start opens the capture and begins replaying it from the start. A reader
+// that finished or was stopped can be started again.
+func (rr *ReplayReader) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if rr.running {
+		return sourceError(models.AlreadyRunning, "replay reader already running")
+	}
+	if rr.cancel != nil && !rr.run.finished() {
+		return sourceError(models.AlreadyRunning, "replay reader still stopping")
+	}
+
+	file, err := os.Open(rr.path)
+	if err != nil {
+		return sourceError(models.IOError, "failed to open file: %w", err)
+	}
+
+	// A reader started again after its loop ended needs a fresh run
+	if rr.run.finished() {
+		rr.run = newRunState()
+	}
+	ctx, rr.cancel = context.WithCancel(ctx)
+	rr.running = true
+
+	go rr.replayLoop(ctx, file, out, rr.run)
+	return nil
+}
+
+// Done is closed once the whole capture was replayed or the reader stopped
+func (rr *ReplayReader) Done() <-chan struct{} {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.run.done
+}
+
+// Err reports why the replay stopped by itself once Done is closed. It is
+// nil after Stop, cancellation or the end of the capture.
+func (rr *ReplayReader) Err() error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.run.Err()
+}
+
+// replayLoop emits the entries, sleeping so each one is sent at its
+// original offset from the first, scaled by Speed
+func (rr *ReplayReader) replayLoop(ctx context.Context, file *os.File, out chan<- *models.LogEntry, run *runState) {
+	var exitErr error
+	defer func() {
+		rr.mu.Lock()
+		rr.running = false
+		rr.cancel()
+		rr.mu.Unlock()
+		run.finish(exitErr)
+	}()
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxReplayLineSize)
+
+	var first time.Time
+	start := time.Now()
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		rr.stats.recordBytes(len(line) + 1)
+		metrics.BytesReceived.WithLabelValues(rr.Name()).Add(int64(len(line) + 1))
+
+		entry, ok := rr.parseLine(line)
+		if !ok {
+			continue
+		}
+		if rr.opts.KeepRaw {
+			keepRaw(entry, string(line))
+		}
+
+		if !rr.opts.IgnoreTiming && !entry.Timestamp.IsZero() {
+			if first.IsZero() {
+				first = entry.Timestamp
+			}
+			offset := time.Duration(float64(entry.Timestamp.Sub(first)) / rr.opts.Speed)
+			if !sleepUntil(ctx, start.Add(offset)) {
+				return
+			}
+		}
+
+		metrics.EntriesReceived.WithLabelValues(rr.Name()).Inc()
+		rr.stats.recordEntry()
+		rr.labels.apply(entry)
+
+		select {
+		case out <- entry:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		rr.stats.recordError()
+		exitErr = sourceError(models.IOError, "failed to read replay file: %w", err)
+	}
+}
+
+// parseLine decodes one captured entry. Blank and malformed lines are skipped.
+func (rr *ReplayReader) parseLine(line []byte) (*models.LogEntry, bool) {
+	if len(line) == 0 {
+		return nil, false
+	}
+
+	var captured models.LogEntry
+	if err := json.Unmarshal(line, &captured); err != nil {
+		rr.stats.recordError()
+		return nil, false
+	}
+
+	entry := models.NewLogEntry()
+	entry.Timestamp = captured.Timestamp
+	entry.Source = captured.Source
+	entry.Message = captured.Message
+	if captured.Level != "" {
+		entry.Level = captured.Level
+	}
+	if captured.Fields != nil {
+		entry.Fields = captured.Fields
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = entry.ReceivedAt
+	}
+
+	return entry, true
+}
+
+// sleepUntil waits until the deadline. It returns false if ctx is done first.
+func sleepUntil(ctx context.Context, deadline time.Time) bool {
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Stop stops the replay and waits for it to end
+func (rr *ReplayReader) Stop() error {
+	rr.mu.Lock()
+	started, run := rr.cancel != nil, rr.run
+	if rr.running {
+		rr.running = false
+		rr.cancel()
+	}
+	rr.mu.Unlock()
+
+	// A reader that was never started has no loop to wait for
+	if started {
+		<-run.done
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the reader's activity
+func (rr *ReplayReader) Stats() models.SourceStats {
+	return rr.stats.snapshot()
+}
+
+// Name returns the source name
+func (rr *ReplayReader) Name() string {
+	return fmt.Sprintf("replay:%s", rr.path)
+}
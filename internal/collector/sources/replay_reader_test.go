@@ -0,0 +1,185 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func writeCapture(t *testing.T, lines string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplayReader_Speed(t *testing.T) {
+	path := writeCapture(t, `{"id":"a","timestamp":"2024-01-15T10:00:00Z","level":"INFO","source":"app","message":"first","fields":{"user":"alice"}}
+{"id":"b","timestamp":"2024-01-15T10:00:01Z","level":"ERROR","source":"app","message":"second"}
+`)
+
+	reader := NewReplayReaderWithOptions(path, ReplayOptions{Speed: 2})
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	first := receiveEntry(t, out)
+	firstAt := time.Now()
+	second := receiveEntry(t, out)
+	gap := time.Since(firstAt)
+
+	if gap < 400*time.Millisecond || gap > 700*time.Millisecond {
+		t.Errorf("Expected entries ~500ms apart at 2x, got %v", gap)
+	}
+
+	if first.Message != "first" || first.Level != models.LevelInfo || first.Source != "app" {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if first.Fields["user"] != "alice" {
+		t.Errorf("Expected fields to be kept, got %v", first.Fields)
+	}
+	if first.ID == "a" {
+		t.Error("Expected replayed entry to get a fresh ID")
+	}
+	expected := time.Date(2024, 1, 15, 10, 0, 1, 0, time.UTC)
+	if !second.Timestamp.Equal(expected) || second.Level != models.LevelError {
+		t.Errorf("Unexpected second entry: %+v", second)
+	}
+
+	select {
+	case <-reader.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected Done to be closed after the capture was replayed")
+	}
+}
+
+func TestReplayReader_IgnoreTiming(t *testing.T) {
+	path := writeCapture(t, `{"timestamp":"2024-01-15T10:00:00Z","message":"first"}
+not json
+{"timestamp":"2024-01-15T11:00:00Z","message":"an hour later"}
+`)
+
+	reader := NewReplayReaderWithOptions(path, ReplayOptions{IgnoreTiming: true})
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	select {
+	case <-reader.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected replay to finish immediately when ignoring timing")
+	}
+
+	if len(out) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(out))
+	}
+	stats := reader.Stats()
+	if stats.EntriesProduced != 2 || stats.Errors != 1 {
+		t.Errorf("Expected 2 entries and 1 error, got %d and %d", stats.EntriesProduced, stats.Errors)
+	}
+}
+
+func TestReplayReader_KeepRaw(t *testing.T) {
+	line := `{"timestamp":"2024-01-15T10:00:00Z","message":"first","fields":{"_raw":"captured"}}`
+	path := writeCapture(t, line+"\n")
+
+	reader := NewReplayReaderWithOptions(path, ReplayOptions{IgnoreTiming: true, KeepRaw: true})
+	out := make(chan *models.LogEntry, 10)
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Stop()
+
+	// The line read replaces what the capture kept
+	entry := receiveEntry(t, out)
+	if raw := entry.Fields[FieldRaw]; raw != line {
+		t.Errorf("Expected raw %q, got %q", line, raw)
+	}
+}
+
+func TestReplayReader_CancelWhileWaiting(t *testing.T) {
+	path := writeCapture(t, `{"timestamp":"2024-01-15T10:00:00Z","message":"first"}
+{"timestamp":"2024-01-15T12:00:00Z","message":"two hours later"}
+`)
+
+	reader := NewReplayReader(path)
+	out := make(chan *models.LogEntry, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	receiveEntry(t, out)
+	cancel()
+
+	select {
+	case <-reader.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected replay to stop when ctx is cancelled")
+	}
+	if len(out) != 0 {
+		t.Errorf("Expected no more entries after cancel, got %d", len(out))
+	}
+	if err := reader.Stop(); err != nil {
+		t.Errorf("Unexpected error from Stop: %v", err)
+	}
+}
+
+func TestReplayReader_MissingFile(t *testing.T) {
+	reader := NewReplayReader(filepath.Join(t.TempDir(), "missing.jsonl"))
+	err := reader.Start(context.Background(), make(chan *models.LogEntry))
+	var sourceErr *models.SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Code != models.IOError {
+		t.Errorf("Expected an I/O error for a missing file, got %v", err)
+	}
+	if err := reader.Stop(); err != nil {
+		t.Errorf("Unexpected error from Stop: %v", err)
+	}
+}
+
+func TestReplayReader_Restart(t *testing.T) {
+	path := writeCapture(t, `{"message":"only"}
+`)
+
+	reader := NewReplayReaderWithOptions(path, ReplayOptions{IgnoreTiming: true})
+	out := make(chan *models.LogEntry, 10)
+	ctx := context.Background()
+
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	err := reader.Start(ctx, out)
+	var sourceErr *models.SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Code != models.AlreadyRunning {
+		t.Errorf("Expected an already running error, got %v", err)
+	}
+	<-reader.Done()
+
+	// Once the capture is done, the reader replays it again
+	for i := 0; i < 2; i++ {
+		if err := reader.Start(ctx, out); err != nil {
+			t.Fatalf("Restart %d failed: %v", i, err)
+		}
+		<-reader.Done()
+		if err := reader.Err(); err != nil {
+			t.Errorf("Expected no error after a full replay, got %v", err)
+		}
+		if err := reader.Stop(); err != nil {
+			t.Errorf("Unexpected error from Stop: %v", err)
+		}
+	}
+	if len(out) != 3 {
+		t.Errorf("Expected the capture replayed 3 times, got %d entries", len(out))
+	}
+}
@@ -0,0 +1,37 @@
+package sources
+
+// runState tracks one run of a source's background loop. err is written
+// before done is closed and only read after, so it needs no lock.
+type runState struct {
+	done chan struct{}
+	err  error
+}
+
+func newRunState() *runState {
+	return &runState{done: make(chan struct{})}
+}
+
+// finish ends the run. A nil err means it ended on request or ran out of
+// input; anything else means it died.
+func (r *runState) finish(err error) {
+	r.err = err
+	close(r.done)
+}
+
+// finished reports whether the run has ended
+func (r *runState) finished() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns why the run died, nil while it is still going
+func (r *runState) Err() error {
+	if !r.finished() {
+		return nil
+	}
+	return r.err
+}
@@ -0,0 +1,25 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// sourceError formats an error and tags it with a code
+func sourceError(code models.SourceErrorCode, format string, args ...interface{}) error {
+	return &models.SourceError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// listenErrorCode tells an address that couldn't be resolved from one that
+// couldn't be bound
+func listenErrorCode(err error) models.SourceErrorCode {
+	var dnsErr *net.DNSError
+	var addrErr *net.AddrError
+	if errors.As(err, &dnsErr) || errors.As(err, &addrErr) {
+		return models.ResolveFailed
+	}
+	return models.BindFailed
+}
@@ -0,0 +1,119 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// starter is the part of a source the failure paths go through
+type starter interface {
+	Start(context.Context, chan<- *models.LogEntry) error
+}
+
+func TestSourceErrors(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	busyUDP, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busyUDP.Close()
+
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		source   func() starter
+		expected models.SourceErrorCode
+	}{
+		{"file missing", func() starter {
+			return NewFileReader(filepath.Join(t.TempDir(), "missing.log"))
+		}, models.IOError},
+		{"file unsupported format", func() starter {
+			return NewFileReaderWithFormat(logFile, "xml")
+		}, models.Unsupported},
+		{"file invalid pattern", func() starter {
+			return NewFileReaderWithRegex(logFile, "(")
+		}, models.Unsupported},
+		{"syslog unsupported protocol", func() starter {
+			return NewSyslogReceiver("127.0.0.1:0", "sctp")
+		}, models.Unsupported},
+		{"syslog udp port in use", func() starter {
+			return NewSyslogReceiver(busyUDP.LocalAddr().String(), "udp")
+		}, models.BindFailed},
+		{"syslog tcp port in use", func() starter {
+			return NewSyslogReceiver(busy.Addr().String(), "tcp")
+		}, models.BindFailed},
+		{"syslog udp bad address", func() starter {
+			return NewSyslogReceiver("127.0.0.1:notaport", "udp")
+		}, models.ResolveFailed},
+		{"http port in use", func() starter {
+			return NewHTTPReceiver(busy.Addr().String())
+		}, models.BindFailed},
+		{"http bad address", func() starter {
+			return NewHTTPReceiver("127.0.0.1:notaport")
+		}, models.ResolveFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := tt.source().Start(ctx, make(chan *models.LogEntry, 10))
+			var sourceErr *models.SourceError
+			if !errors.As(err, &sourceErr) {
+				t.Fatalf("Expected a SourceError, got %v", err)
+			}
+			if sourceErr.Code != tt.expected {
+				t.Errorf("Expected code %s, got %s (%v)", tt.expected, sourceErr.Code, err)
+			}
+		})
+	}
+}
+
+func TestSourceErrors_AlreadyRunning(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := []interface {
+		Start(context.Context, chan<- *models.LogEntry) error
+		Stop() error
+	}{
+		NewFileReader(logFile),
+		NewSyslogReceiver("127.0.0.1:0", "udp"),
+		NewHTTPReceiver("127.0.0.1:0"),
+	}
+
+	for _, source := range sources {
+		ctx, cancel := context.WithCancel(context.Background())
+		out := make(chan *models.LogEntry, 10)
+		if err := source.Start(ctx, out); err != nil {
+			t.Fatal(err)
+		}
+
+		err := source.Start(ctx, out)
+		var sourceErr *models.SourceError
+		if !errors.As(err, &sourceErr) || sourceErr.Code != models.AlreadyRunning {
+			t.Errorf("Expected an AlreadyRunning SourceError from %T, got %v", source, err)
+		}
+
+		source.Stop()
+		cancel()
+	}
+}
@@ -0,0 +1,102 @@
+package sources
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// templateTransport is the placeholder for the receiver's transport label,
+// e.g. "syslog:udp"
+const templateTransport = "transport"
+
+// templateFields are the placeholders a SourceTemplate takes from an
+// entry's fields
+var templateFields = map[string]bool{
+	"hostname": true,
+	"app_name": true,
+	"procid":   true,
+	"msgid":    true,
+	"facility": true,
+	"severity": true,
+}
+
+// SourceTemplate composes an entry's Source from its parsed syslog header,
+// e.g. "{hostname}/{app_name}". Placeholders are {hostname}, {app_name},
+// {procid}, {msgid}, {facility}, {severity} and {transport}. A placeholder
+// without a value renders as "-", and an entry with none of the header
+// placeholders set keeps the transport label.
+type SourceTemplate struct {
+	// parts alternates literal text and placeholder names, starting with
+	// text
+	parts []string
+}
+
+// ParseSourceTemplate parses a template. An empty template yields a nil
+// SourceTemplate, which leaves Source as the parser set it.
+func ParseSourceTemplate(template string) (*SourceTemplate, error) {
+	if template == "" {
+		return nil, nil
+	}
+
+	t := &SourceTemplate{}
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			if strings.IndexByte(rest, '}') >= 0 {
+				return nil, fmt.Errorf("unexpected '}' in template %q", template)
+			}
+			t.parts = append(t.parts, rest)
+			return t, nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed '{' in template %q", template)
+		}
+		end += start
+
+		text, name := rest[:start], rest[start+1:end]
+		if strings.IndexByte(text, '}') >= 0 {
+			return nil, fmt.Errorf("unexpected '}' in template %q", template)
+		}
+		if name != templateTransport && !templateFields[name] {
+			return nil, fmt.Errorf("unknown placeholder {%s} in template %q", name, template)
+		}
+		t.parts = append(t.parts, text, name)
+		rest = rest[end+1:]
+	}
+}
+
+// Render composes the source for entry, received over transport
+func (t *SourceTemplate) Render(entry *models.LogEntry, transport string) string {
+	var b strings.Builder
+	found := false
+	for i, part := range t.parts {
+		if i%2 == 0 {
+			b.WriteString(part)
+			continue
+		}
+		if part == templateTransport {
+			b.WriteString(transport)
+			continue
+		}
+
+		value := ""
+		if v, ok := entry.Fields[part]; ok && v != nil {
+			value = fmt.Sprint(v)
+		}
+		if value == "" {
+			b.WriteString(nilValue)
+			continue
+		}
+		found = true
+		b.WriteString(value)
+	}
+
+	if !found {
+		return transport
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package sources
+
+import (
+	"testing"
+)
+
+func TestSourceTemplate_Render(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		protocol string
+		message  string
+		expected string
+	}{
+		{
+			name:     "rfc 5424",
+			template: "{hostname}/{app_name}",
+			protocol: "udp",
+			message:  "<165>1 2003-10-11T22:14:15.003Z web1 nginx 812 ID47 - upstream timed out",
+			expected: "web1/nginx",
+		},
+		{
+			name:     "rfc 3164",
+			template: "{hostname}/{app_name}",
+			protocol: "udp",
+			message:  "<34>Oct 11 22:14:15 web1 nginx[812]: upstream timed out",
+			expected: "web1/nginx",
+		},
+		{
+			name:     "missing hostname",
+			template: "{hostname}/{app_name}",
+			protocol: "tcp",
+			message:  "<165>1 2003-10-11T22:14:15.003Z - nginx - - - started",
+			expected: "-/nginx",
+		},
+		{
+			name:     "no header falls back to transport",
+			template: "{hostname}/{app_name}",
+			protocol: "tcp",
+			message:  "<13>no header here",
+			expected: "syslog:tcp",
+		},
+		{
+			name:     "priority and transport",
+			template: "{transport}:{facility}.{severity}",
+			protocol: "udp",
+			message:  "<34>Oct 11 22:14:15 mymachine su: 'su root' failed",
+			expected: "syslog:udp:auth.crit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := ParseSourceTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", tt.protocol, SyslogReceiverOptions{SourceTemplate: template})
+
+			entry := receiver.parseSyslogMessage([]byte(tt.message))
+			if entry.Source != tt.expected {
+				t.Errorf("Expected source %q, got %q", tt.expected, entry.Source)
+			}
+		})
+	}
+}
+
+func TestParseSourceTemplate_Invalid(t *testing.T) {
+	for _, template := range []string{"{host}", "{app_name", "app_name}", "{hostname}}"} {
+		if _, err := ParseSourceTemplate(template); err == nil {
+			t.Errorf("Expected an error for %q", template)
+		}
+	}
+
+	template, err := ParseSourceTemplate("")
+	if template != nil || err != nil {
+		t.Errorf("Expected no template for an empty one, got %v, %v", template, err)
+	}
+}
@@ -0,0 +1,74 @@
+package sources
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// sourceStats tracks a source's activity with atomic counters so the hot
+// path never takes a lock
+type sourceStats struct {
+	entries      atomic.Int64
+	bytes        atomic.Int64
+	lastActivity atomic.Int64 // unix nanoseconds
+	errors       atomic.Int64
+	connections  atomic.Int64
+	rejected     atomic.Int64
+	denied       atomic.Int64
+}
+
+// recordEntry counts one produced entry
+func (s *sourceStats) recordEntry() {
+	s.entries.Add(1)
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+// recordBytes counts bytes read from the underlying input
+func (s *sourceStats) recordBytes(n int) {
+	s.bytes.Add(int64(n))
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+// recordError counts a read or parse failure
+func (s *sourceStats) recordError() {
+	s.errors.Add(1)
+}
+
+// connectionOpened counts an accepted connection
+func (s *sourceStats) connectionOpened() {
+	s.connections.Add(1)
+}
+
+// connectionClosed counts a finished connection
+func (s *sourceStats) connectionClosed() {
+	s.connections.Add(-1)
+}
+
+// connectionRejected counts a connection refused because of a limit
+func (s *sourceStats) connectionRejected() {
+	s.rejected.Add(1)
+}
+
+// clientDenied counts a client refused by an allowlist
+func (s *sourceStats) clientDenied() {
+	s.denied.Add(1)
+}
+
+// snapshot returns the current values
+func (s *sourceStats) snapshot() models.SourceStats {
+	stats := models.SourceStats{
+		EntriesProduced: s.entries.Load(),
+		BytesRead:       s.bytes.Load(),
+		Errors:          s.errors.Load(),
+
+		ActiveConnections:   s.connections.Load(),
+		RejectedConnections: s.rejected.Load(),
+		Denied:              s.denied.Load(),
+	}
+	if last := s.lastActivity.Load(); last != 0 {
+		stats.LastActivity = time.Unix(0, last)
+	}
+	return stats
+}
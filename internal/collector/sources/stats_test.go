@@ -0,0 +1,161 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// assertRecentStats checks the produced count and that activity was just now
+func assertRecentStats(t *testing.T, stats models.SourceStats, entries int64, since time.Time) {
+	t.Helper()
+
+	if stats.EntriesProduced != entries {
+		t.Errorf("Expected %d entries produced, got %d", entries, stats.EntriesProduced)
+	}
+	if stats.BytesRead == 0 {
+		t.Error("Expected bytes read to be counted")
+	}
+	if stats.LastActivity.Before(since) || stats.LastActivity.After(time.Now()) {
+		t.Errorf("Expected recent last activity, got %v", stats.LastActivity)
+	}
+}
+
+func TestFileReader_Stats(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.log")
+	content := "line 1\nline 2\nline 3\nline 4\nline 5\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewFileReader(testFile)
+	if !reader.Stats().LastActivity.IsZero() {
+		t.Error("Expected zero last activity before start")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+
+	start := time.Now()
+	if err := reader.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-out:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for entries")
+		}
+	}
+
+	stats := reader.Stats()
+	assertRecentStats(t, stats, 5, start)
+	if stats.BytesRead != int64(len(content)) {
+		t.Errorf("Expected %d bytes read, got %d", len(content), stats.BytesRead)
+	}
+}
+
+func TestSyslogReceiver_Stats(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "tcp")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+
+	client, server := net.Pipe()
+	receiver.wg.Add(1)
+	go receiver.handleTCPConnection(ctx, server, out, make(chan struct{}))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(client, "<13>message %d\n", i)
+		select {
+		case <-out:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for entries")
+		}
+	}
+	client.Close()
+	receiver.wg.Wait()
+
+	assertRecentStats(t, receiver.Stats(), 3, start)
+}
+
+func TestHTTPReceiver_Stats(t *testing.T) {
+	addr := freeAddr(t)
+	receiver := NewHTTPReceiver(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan *models.LogEntry, 10)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	waitForHTTP(t, addr)
+
+	start := time.Now()
+	body, _ := json.Marshal([]map[string]string{{"message": "a"}, {"message": "b"}, {"message": "c"}})
+	resp, err := http.Post("http://"+addr+"/batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Post("http://"+addr+"/logs", "application/json", bytes.NewReader([]byte("{broken")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	stats := receiver.Stats()
+	assertRecentStats(t, stats, 3, start)
+	if stats.Errors != 1 {
+		t.Errorf("Expected 1 error for the invalid request, got %d", stats.Errors)
+	}
+}
+
+func TestDirectoryReader_Stats(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.log", "b.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("one\ntwo\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reader := NewDirectoryReader(filepath.Join(dir, "*.log"))
+	out := make(chan *models.LogEntry, 10)
+
+	start := time.Now()
+	if err := reader.Start(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case <-out:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for entries")
+		}
+	}
+
+	assertRecentStats(t, reader.Stats(), 4, start)
+
+	// Totals survive the readers being stopped
+	reader.Stop()
+	if got := reader.Stats().EntriesProduced; got != 4 {
+		t.Errorf("Expected 4 entries after stop, got %d", got)
+	}
+}
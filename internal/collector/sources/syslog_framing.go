@@ -0,0 +1,220 @@
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTCPMessageSize is the default largest syslog message accepted over TCP
+const maxTCPMessageSize = 65536
+
+// syslogFrame is one message read from a TCP stream
+type syslogFrame struct {
+	// message may share the buffer passed to readSyslogFrame, so it is only
+	// valid until the buffer is used again
+	message []byte
+
+	// size is the length of the whole frame, larger than the message when
+	// the frame was truncated to the size limit
+	size int
+}
+
+// truncated reports whether the frame was cut to fit the size limit
+func (f syslogFrame) truncated() bool {
+	return f.size > len(f.message)
+}
+
+// readSyslogFrame reads one syslog message from a TCP stream (RFC 6587).
+// Frames starting with a digit use octet counting ("<length> <message>"),
+// which allows embedded newlines. Anything else is newline-delimited.
+// Frames over maxLen are truncated and the rest of the frame is skipped.
+// The message is read into buf when it has room, so a connection can reuse
+// one buffer for all its frames.
+func readSyslogFrame(r *bufio.Reader, maxLen int, buf []byte) (syslogFrame, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return syslogFrame{}, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		return readOctetCountedFrame(r, maxLen, buf)
+	}
+	return readNewlineFrame(r, maxLen, buf)
+}
+
+// readOctetCountedFrame reads "<length> <message>" and returns exactly
+// length bytes of message
+func readOctetCountedFrame(r *bufio.Reader, maxLen int, buf []byte) (syslogFrame, error) {
+	header, err := r.ReadString(' ')
+	if err != nil {
+		return syslogFrame{}, fmt.Errorf("incomplete octet count: %w", err)
+	}
+
+	length, err := strconv.Atoi(strings.TrimSuffix(header, " "))
+	if err != nil || length <= 0 {
+		return syslogFrame{}, fmt.Errorf("invalid octet count %q", header)
+	}
+
+	// One byte past the limit shows truncateUTF8 where the next rune starts
+	keep := length
+	if keep > maxLen+1 {
+		keep = maxLen + 1
+	}
+
+	if cap(buf) < keep {
+		buf = make([]byte, keep)
+	}
+	buf = buf[:keep]
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return syslogFrame{}, fmt.Errorf("incomplete frame: %w", err)
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(length-keep)); err != nil {
+		return syslogFrame{}, fmt.Errorf("incomplete frame: %w", err)
+	}
+
+	return syslogFrame{message: truncateUTF8(buf, maxLen), size: length}, nil
+}
+
+// readNewlineFrame reads up to the next LF, stripping the trailing CRLF/LF.
+// A final frame without a newline is returned at EOF.
+func readNewlineFrame(r *bufio.Reader, maxLen int, buf []byte) (syslogFrame, error) {
+	line := buf[:0]
+	size := 0
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if err != bufio.ErrBufferFull {
+			chunk = bytes.TrimRight(chunk, "\r\n")
+		}
+		size += len(chunk)
+
+		// Only the first maxLen bytes are kept, the rest is skipped. One
+		// extra byte shows truncateUTF8 where the next rune starts.
+		if room := maxLen + 1 - len(line); room > 0 {
+			if len(chunk) > room {
+				chunk = chunk[:room]
+			}
+			line = append(line, chunk...)
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			if err == io.EOF && size > 0 {
+				break
+			}
+			return syslogFrame{}, err
+		}
+		break
+	}
+
+	return syslogFrame{message: truncateUTF8(line, maxLen), size: size}, nil
+}
+
+// truncateUTF8 returns the first n bytes of b, backing off so a multi-byte
+// rune is never split
+func truncateUTF8(b []byte, n int) []byte {
+	if n >= len(b) {
+		n = len(b)
+	} else {
+		for n > 0 && !utf8.RuneStart(b[n]) {
+			n--
+		}
+	}
+	return b[:n]
+}
+
+// splitDatagram splits a UDP datagram into its messages, appending them to
+// dst without copying. Some senders pack several newline-separated messages
+// into one datagram; a newline inside the structured data of an RFC 5424
+// message doesn't end it. Empty lines are skipped, and a datagram without
+// newlines is a single message.
+func splitDatagram(dst [][]byte, data []byte) [][]byte {
+	for len(data) > 0 {
+		end := datagramMessageEnd(data)
+		if end == len(data) {
+			return append(dst, data)
+		}
+
+		message := bytes.TrimSuffix(data[:end], []byte("\r"))
+		if len(message) > 0 {
+			dst = append(dst, message)
+		}
+		data = data[end+1:]
+	}
+	return dst
+}
+
+// datagramMessageEnd returns the index of the newline ending the first
+// message in data, or len(data) if it runs to the end
+func datagramMessageEnd(data []byte) int {
+	from := 0
+	if sd := rfc5424SDStart(data); sd >= 0 {
+		from = skipStructuredData(data, sd)
+	}
+	if i := bytes.IndexByte(data[from:], '\n'); i >= 0 {
+		return from + i
+	}
+	return len(data)
+}
+
+// rfc5424SDStart returns where the structured data starts if data begins
+// with an RFC 5424 header on a single line, or -1
+func rfc5424SDStart(data []byte) int {
+	if len(data) == 0 || data[0] != '<' {
+		return -1
+	}
+	i := 1
+	for i < len(data) && data[i] >= '0' && data[i] <= '9' {
+		i++
+	}
+	if !bytes.HasPrefix(data[i:], []byte(">1 ")) {
+		return -1
+	}
+	i += len(">1 ")
+
+	// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+	for field := 0; field < 5; field++ {
+		end := bytes.IndexAny(data[i:], " \n")
+		if end <= 0 || data[i+end] != ' ' {
+			return -1
+		}
+		i += end + 1
+	}
+	return i
+}
+
+// skipStructuredData returns the index just past the structured data
+// starting at i. Quoted parameter values may hold escaped quotes and
+// brackets. Data that never closes runs to the end.
+func skipStructuredData(data []byte, i int) int {
+	if i < len(data) && data[i] == '-' {
+		return i + 1
+	}
+
+	for i < len(data) && data[i] == '[' {
+		quoted := false
+		for i++; i < len(data); i++ {
+			c := data[i]
+			if quoted && c == '\\' {
+				i++
+				continue
+			}
+			if c == '"' {
+				quoted = !quoted
+			} else if c == ']' && !quoted {
+				break
+			}
+		}
+		if i >= len(data) {
+			return len(data)
+		}
+		i++
+	}
+	return i
+}
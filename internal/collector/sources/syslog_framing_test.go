@@ -0,0 +1,312 @@
+package sources
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// octetFrame builds an RFC 6587 octet-counted frame
+func octetFrame(msg string) string {
+	return fmt.Sprintf("%d %s", len(msg), msg)
+}
+
+func TestReadSyslogFrame_MixedFraming(t *testing.T) {
+	multiline := "<34>1 - host app - - - first line\nsecond line"
+	stream := "<34>newline framed\n" +
+		octetFrame(multiline) +
+		octetFrame("<13>octet framed") +
+		"<14>crlf framed\r\n" +
+		"<15>no trailing newline"
+
+	reader := bufio.NewReader(strings.NewReader(stream))
+
+	expected := []string{
+		"<34>newline framed",
+		multiline,
+		"<13>octet framed",
+		"<14>crlf framed",
+		"<15>no trailing newline",
+	}
+
+	// One buffer is reused for every frame, like a connection does
+	var buf []byte
+	for i, want := range expected {
+		got, err := readSyslogFrame(reader, maxTCPMessageSize, buf)
+		if err != nil {
+			t.Fatalf("Frame %d: unexpected error: %v", i, err)
+		}
+		buf = got.message[:0]
+		if string(got.message) != want {
+			t.Errorf("Frame %d: expected %q, got %q", i, want, got.message)
+		}
+		if got.truncated() {
+			t.Errorf("Frame %d: unexpected truncation", i)
+		}
+	}
+
+	if _, err := readSyslogFrame(reader, maxTCPMessageSize, nil); err != io.EOF {
+		t.Errorf("Expected io.EOF after last frame, got %v", err)
+	}
+}
+
+func TestReadSyslogFrame_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"oversized octet count", "99999 <34>msg"},
+		{"truncated octet frame", "50 <34>short"},
+		{"bad octet count", "12abc <34>msg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReaderSize(strings.NewReader(tt.input), 16)
+			if _, err := readSyslogFrame(reader, 100, nil); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestReadSyslogFrame_Truncation(t *testing.T) {
+	long := "<34>" + strings.Repeat("x", 196)
+	// "é" is two bytes, so a cut at byte 100 would split the one at 99-100
+	multibyte := "<34>" + strings.Repeat("a", 95) + "é" + strings.Repeat("b", 50)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		size     int
+	}{
+		{"newline", long + "\r\n", long[:100], 200},
+		{"octet counted", octetFrame(long), long[:100], 200},
+		{"newline multibyte", multibyte + "\n", multibyte[:99], len(multibyte)},
+		{"octet counted multibyte", octetFrame(multibyte), multibyte[:99], len(multibyte)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A small buffer forces lines to span several reads
+			reader := bufio.NewReaderSize(strings.NewReader(tt.input+"<13>next\n"), 16)
+
+			frame, err := readSyslogFrame(reader, 100, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(frame.message) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, frame.message)
+			}
+			if !frame.truncated() || frame.size != tt.size {
+				t.Errorf("Expected truncated frame of %d bytes, got %d", tt.size, frame.size)
+			}
+
+			// The rest of the oversized frame is skipped
+			next, err := readSyslogFrame(reader, 100, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(next.message) != "<13>next" {
+				t.Errorf("Expected the following frame, got %q", next.message)
+			}
+		})
+	}
+}
+
+func TestSyslogReceiver_TCPTruncationWarning(t *testing.T) {
+	receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", "tcp", SyslogReceiverOptions{MaxMessageSize: 1024})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	conn, err := net.Dial("tcp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	msg := "<14>" + strings.Repeat("y", 4000)
+	fmt.Fprintf(conn, "%s\n<14>after\n", msg)
+
+	var entries []*models.LogEntry
+	for i := 0; i < 3; i++ {
+		select {
+		case entry := <-out:
+			entries = append(entries, entry)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for entry %d", i)
+		}
+	}
+
+	if entries[0].Message != msg[:1024] || entries[0].Fields[FieldTruncated] != true {
+		t.Errorf("Expected the truncated message first, got %d bytes with fields %v", len(entries[0].Message), entries[0].Fields[FieldTruncated])
+	}
+	warning := entries[1]
+	if warning.Level != models.LevelWarning {
+		t.Errorf("Expected a WARNING entry, got %s", warning.Level)
+	}
+	if warning.Fields[FieldOriginalLength] != len(msg) {
+		t.Errorf("Expected original length %d, got %v", len(msg), warning.Fields[FieldOriginalLength])
+	}
+	if entries[2].Message != "<14>after" {
+		t.Errorf("Expected the connection to keep working, got %q", entries[2].Message)
+	}
+}
+
+func TestSyslogReceiver_LargeMessageWithRaisedLimits(t *testing.T) {
+	for _, protocol := range []string{"udp", "tcp"} {
+		t.Run(protocol, func(t *testing.T) {
+			receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", protocol, SyslogReceiverOptions{
+				UDPBufferSize:  32768,
+				MaxMessageSize: 32768,
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			out := make(chan *models.LogEntry, 10)
+			if err := receiver.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			conn, err := net.Dial(protocol, receiver.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			// Larger than both the UDP and the old TCP defaults
+			msg := "<14>" + strings.Repeat("z", 20000)
+			if protocol == "tcp" {
+				msg += "\n"
+			}
+			if _, err := conn.Write([]byte(msg)); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case entry := <-out:
+				if entry.Message != strings.TrimSuffix(msg, "\n") {
+					t.Errorf("Expected the full %d byte message, got %d bytes", len(msg), len(entry.Message))
+				}
+				if _, ok := entry.Fields[FieldTruncated]; ok {
+					t.Error("Expected no truncation")
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("Timeout waiting for log entry")
+			}
+		})
+	}
+}
+
+func TestSyslogReceiver_TCPOctetCounting(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "tcp")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan *models.LogEntry, 10)
+
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+	defer cancel()
+
+	conn, err := net.Dial("tcp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	multiline := "<11>1 - host java - - - Exception in thread main\n\tat Foo.bar(Foo.java:10)"
+	stream := octetFrame(multiline) + "<14>plain line\n" + octetFrame("<13>after")
+	if _, err := conn.Write([]byte(stream)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"Exception in thread main\n\tat Foo.bar(Foo.java:10)",
+		"<14>plain line",
+		"<13>after",
+	}
+
+	for i, want := range expected {
+		select {
+		case entry := <-out:
+			if entry.Message != want {
+				t.Errorf("Entry %d: expected %q, got %q", i, want, entry.Message)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for entry %d", i)
+		}
+	}
+}
+
+func TestSplitDatagram(t *testing.T) {
+	tests := []struct {
+		name     string
+		datagram string
+		expected []string
+	}{
+		{"single message", "<34>one message", []string{"<34>one message"}},
+		{"trailing newline", "<34>one message\n", []string{"<34>one message"}},
+		{"packed", "<34>first\r\n<34>second\n\n<34>third", []string{"<34>first", "<34>second", "<34>third"}},
+		{
+			"newline in structured data",
+			"<165>1 - host app - - [a x=\"1\n2\"][b y=\"\\\"]\n\"] msg\n<34>next",
+			[]string{"<165>1 - host app - - [a x=\"1\n2\"][b y=\"\\\"]\n\"] msg", "<34>next"},
+		},
+		{"newline in 5424 message", "<165>1 - host app - - - first\n<34>second", []string{"<165>1 - host app - - - first", "<34>second"}},
+		{"unclosed structured data", "<165>1 - host app - - [a x=\"1\n<34>next", []string{"<165>1 - host app - - [a x=\"1\n<34>next"}},
+		{"brackets in 3164", "<34>su[12]: \"quoted\n<34>next", []string{"<34>su[12]: \"quoted", "<34>next"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, message := range splitDatagram(nil, []byte(tt.datagram)) {
+				got = append(got, string(message))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") || len(got) != len(tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func BenchmarkReadSyslogFrame(b *testing.B) {
+	message := "<34>Oct 11 22:14:15 mymachine su: 'su root' failed"
+	frames := strings.Repeat(message+"\n", 64) + strings.Repeat(fmt.Sprintf("%d %s", len(message), message), 64)
+	input := strings.NewReader(frames)
+	reader := bufio.NewReaderSize(input, 4096)
+
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frame, err := readSyslogFrame(reader, maxTCPMessageSize, buf)
+		if err == io.EOF {
+			input.Reset(frames)
+			reader.Reset(input)
+			continue
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf = frame.message[:0]
+	}
+}
@@ -0,0 +1,300 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// nilValue is the RFC 5424 placeholder for an absent header field
+const nilValue = "-"
+
+// utf8BOM may prefix the MSG part of an RFC 5424 message
+const utf8BOM = "\xEF\xBB\xBF"
+
+var errNotRFC5424 = errors.New("not an RFC 5424 message")
+
+// rfc5424Message holds the parts of an RFC 5424 syslog message
+// (everything after the <PRI> prefix). NILVALUE header fields are left empty.
+type rfc5424Message struct {
+	Timestamp      time.Time // zero if NILVALUE
+	RawTimestamp   string
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string // SD-ID -> param -> value
+	Message        string
+}
+
+// parseRFC5424 parses an RFC 5424 message with the priority already stripped:
+// VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP SD [SP MSG]
+func parseRFC5424(s string) (*rfc5424Message, error) {
+	if !strings.HasPrefix(s, "1 ") {
+		return nil, errNotRFC5424
+	}
+	rest := s[2:]
+
+	// Five space-terminated header fields
+	var header [5]string
+	for i := range header {
+		idx := strings.IndexByte(rest, ' ')
+		if idx <= 0 {
+			return nil, fmt.Errorf("truncated RFC 5424 header")
+		}
+		header[i] = rest[:idx]
+		rest = rest[idx+1:]
+	}
+
+	msg := &rfc5424Message{
+		Hostname: nilToEmpty(header[1]),
+		AppName:  nilToEmpty(header[2]),
+		ProcID:   nilToEmpty(header[3]),
+		MsgID:    nilToEmpty(header[4]),
+	}
+
+	if header[0] != nilValue {
+		ts, err := time.Parse(time.RFC3339Nano, header[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid RFC 5424 timestamp %q: %w", header[0], err)
+		}
+		msg.Timestamp = ts
+		msg.RawTimestamp = header[0]
+	}
+
+	sd, rest, err := parseStructuredData(rest)
+	if err != nil {
+		return nil, err
+	}
+	msg.StructuredData = sd
+
+	switch {
+	case rest == "":
+	case rest[0] == ' ':
+		msg.Message = strings.TrimPrefix(rest[1:], utf8BOM)
+	default:
+		return nil, fmt.Errorf("missing space before RFC 5424 message")
+	}
+
+	return msg, nil
+}
+
+// parseStructuredData parses the STRUCTURED-DATA part and returns the remainder
+func parseStructuredData(s string) (map[string]map[string]string, string, error) {
+	if strings.HasPrefix(s, nilValue) {
+		return nil, s[len(nilValue):], nil
+	}
+	if !strings.HasPrefix(s, "[") {
+		return nil, s, fmt.Errorf("invalid structured data")
+	}
+
+	sd := make(map[string]map[string]string)
+	for strings.HasPrefix(s, "[") {
+		id, params, rest, err := parseSDElement(s)
+		if err != nil {
+			return nil, s, err
+		}
+		sd[id] = params
+		s = rest
+	}
+	return sd, s, nil
+}
+
+// parseSDElement parses one [SD-ID name="value" ...] element
+func parseSDElement(s string) (string, map[string]string, string, error) {
+	// Skip '['
+	i := 1
+
+	start := i
+	for i < len(s) && s[i] != ' ' && s[i] != ']' {
+		i++
+	}
+	if i == start || i >= len(s) {
+		return "", nil, s, fmt.Errorf("invalid SD-ID")
+	}
+	id := s[start:i]
+	params := make(map[string]string)
+
+	for i < len(s) && s[i] == ' ' {
+		i++
+
+		start = i
+		for i < len(s) && s[i] != '=' {
+			i++
+		}
+		if i+1 >= len(s) || s[i+1] != '"' {
+			return "", nil, s, fmt.Errorf("invalid SD-PARAM in %s", id)
+		}
+		name := s[start:i]
+		i += 2 // skip '="'
+
+		// Values without escapes are taken as they are
+		if end := strings.IndexByte(s[i:], '"'); end >= 0 && strings.IndexByte(s[i:i+end], '\\') < 0 {
+			params[name] = s[i : i+end]
+			i += end + 1
+			continue
+		}
+
+		var value strings.Builder
+		for {
+			if i >= len(s) {
+				return "", nil, s, fmt.Errorf("unterminated SD-PARAM value in %s", id)
+			}
+			c := s[i]
+			if c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+				value.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+			if c == '"' {
+				i++
+				break
+			}
+			value.WriteByte(c)
+			i++
+		}
+		params[name] = value.String()
+	}
+
+	if i >= len(s) || s[i] != ']' {
+		return "", nil, s, fmt.Errorf("unterminated SD-ELEMENT %s", id)
+	}
+	return id, params, s[i+1:], nil
+}
+
+// nilToEmpty maps the NILVALUE to an empty string
+func nilToEmpty(s string) string {
+	if s == nilValue {
+		return ""
+	}
+	return s
+}
+
+// maxRFC3164TagLen is the longest TAG RFC 3164 allows
+const maxRFC3164TagLen = 32
+
+// parseRFC3164Header parses the "HOSTNAME TAG[PID]:" header following an RFC
+// 3164 timestamp. Many senders leave out the hostname. Without a tag
+// there is no telling a hostname from the first word of the message, so
+// nothing is returned.
+func parseRFC3164Header(s string) (hostname, tag, procid string) {
+	first, rest, _ := strings.Cut(s, " ")
+	if tag, procid, ok := parseRFC3164Tag(first); ok {
+		return "", tag, procid
+	}
+	if first == "" {
+		return "", "", ""
+	}
+	second, _, _ := strings.Cut(rest, " ")
+	if tag, procid, ok := parseRFC3164Tag(second); ok {
+		return first, tag, procid
+	}
+	return "", "", ""
+}
+
+// parseRFC3164Tag parses a "tag:" or "tag[pid]:" word
+func parseRFC3164Tag(word string) (tag, procid string, ok bool) {
+	word, ok = strings.CutSuffix(word, ":")
+	if !ok {
+		return "", "", false
+	}
+	tag = word
+	if i := strings.IndexByte(word, '['); i >= 0 {
+		if !strings.HasSuffix(word, "]") {
+			return "", "", false
+		}
+		tag, procid = word[:i], word[i+1:len(word)-1]
+	}
+	if tag == "" || len(tag) > maxRFC3164TagLen {
+		return "", "", false
+	}
+	return tag, procid, true
+}
+
+// facilityNames maps syslog facility codes (RFC 5424 section 6.2.1) to names
+var facilityNames = [...]string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// severityNames maps syslog severity codes to their keywords
+var severityNames = [...]string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// facilityFields, severityFields and priorityFields hold the facility and
+// severity names and the PRI values 0 to 191 already boxed, so storing them
+// in Fields doesn't allocate for every message
+var (
+	facilityFields = boxStrings(facilityNames[:])
+	severityFields = boxStrings(severityNames[:])
+	priorityFields = boxStrings(priorityCodes())
+)
+
+func boxStrings(values []string) []interface{} {
+	boxed := make([]interface{}, len(values))
+	for i, v := range values {
+		boxed[i] = v
+	}
+	return boxed
+}
+
+func priorityCodes() []string {
+	codes := make([]string, 192)
+	for i := range codes {
+		codes[i] = strconv.Itoa(i)
+	}
+	return codes
+}
+
+// priorityField returns pri ready to store in Fields, shared when it is
+// written the usual way and boxed afresh otherwise, e.g. with leading zeros
+func priorityField(pri string) interface{} {
+	if value, err := strconv.Atoi(pri); err == nil && value >= 0 && value < len(priorityFields) {
+		if field := priorityFields[value]; field == pri {
+			return field
+		}
+	}
+	return pri
+}
+
+// decodePriority splits a PRI value into facility and severity codes
+func decodePriority(pri string) (facility int, severity int, ok bool) {
+	if pri == "" || len(pri) > 3 {
+		return 0, 0, false
+	}
+
+	value := 0
+	for _, c := range pri {
+		if c < '0' || c > '9' {
+			return 0, 0, false
+		}
+		value = value*10 + int(c-'0')
+	}
+	if value > 191 {
+		return 0, 0, false
+	}
+
+	return value / 8, value % 8, true
+}
+
+// severityLevel maps a syslog severity code to a LogLevel
+func severityLevel(severity int) models.LogLevel {
+	switch severity {
+	case 0, 1, 2: // emerg, alert, crit
+		return models.LevelCritical
+	case 3: // err
+		return models.LevelError
+	case 4: // warning
+		return models.LevelWarning
+	case 5, 6: // notice, info
+		return models.LevelInfo
+	default: // debug
+		return models.LevelDebug
+	}
+}
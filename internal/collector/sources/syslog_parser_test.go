@@ -0,0 +1,287 @@
+package sources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected rfc5424Message
+	}{
+		{
+			name:  "no structured data",
+			input: "1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - \xEF\xBB\xBF'su root' failed for lonvick on /dev/pts/8",
+			expected: rfc5424Message{
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "mymachine.example.com",
+				AppName:   "su",
+				MsgID:     "ID47",
+				Message:   "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			name:  "offset timestamp and procid",
+			input: "1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 myproc 8710 - - %% It's time to make the do-nuts.",
+			expected: rfc5424Message{
+				Timestamp: time.Date(2003, 8, 24, 12, 14, 15, 3000, time.UTC),
+				Hostname:  "192.0.2.1",
+				AppName:   "myproc",
+				ProcID:    "8710",
+				Message:   "%% It's time to make the do-nuts.",
+			},
+		},
+		{
+			name:  "structured data with message",
+			input: `1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"] An application event log entry`,
+			expected: rfc5424Message{
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "mymachine.example.com",
+				AppName:   "evntslog",
+				MsgID:     "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473": {"iut": "3", "eventSource": "Application", "eventID": "1011"},
+				},
+				Message: "An application event log entry",
+			},
+		},
+		{
+			name:  "multiple SD elements without message",
+			input: `1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3"][examplePriority@32473 class="high"]`,
+			expected: rfc5424Message{
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "mymachine.example.com",
+				AppName:   "evntslog",
+				MsgID:     "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473":     {"iut": "3"},
+					"examplePriority@32473": {"class": "high"},
+				},
+			},
+		},
+		{
+			name:  "escaped SD values",
+			input: `1 - host app - - [meta@1 path="C:\\temp" quote="say \"hi\"" bracket="a\]b"] msg`,
+			expected: rfc5424Message{
+				Hostname: "host",
+				AppName:  "app",
+				StructuredData: map[string]map[string]string{
+					"meta@1": {"path": `C:\temp`, "quote": `say "hi"`, "bracket": "a]b"},
+				},
+				Message: "msg",
+			},
+		},
+		{
+			name:     "all NILVALUE",
+			input:    "1 - - - - - -",
+			expected: rfc5424Message{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseRFC5424(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !msg.Timestamp.Equal(tt.expected.Timestamp) {
+				t.Errorf("Expected timestamp %v, got %v", tt.expected.Timestamp, msg.Timestamp)
+			}
+			if msg.Hostname != tt.expected.Hostname {
+				t.Errorf("Expected hostname %q, got %q", tt.expected.Hostname, msg.Hostname)
+			}
+			if msg.AppName != tt.expected.AppName {
+				t.Errorf("Expected app-name %q, got %q", tt.expected.AppName, msg.AppName)
+			}
+			if msg.ProcID != tt.expected.ProcID {
+				t.Errorf("Expected procid %q, got %q", tt.expected.ProcID, msg.ProcID)
+			}
+			if msg.MsgID != tt.expected.MsgID {
+				t.Errorf("Expected msgid %q, got %q", tt.expected.MsgID, msg.MsgID)
+			}
+			if msg.Message != tt.expected.Message {
+				t.Errorf("Expected message %q, got %q", tt.expected.Message, msg.Message)
+			}
+
+			if len(msg.StructuredData) != len(tt.expected.StructuredData) {
+				t.Fatalf("Expected %d SD elements, got %d", len(tt.expected.StructuredData), len(msg.StructuredData))
+			}
+			for id, params := range tt.expected.StructuredData {
+				for name, value := range params {
+					if got := msg.StructuredData[id][name]; got != value {
+						t.Errorf("Expected %s.%s=%q, got %q", id, name, value, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseRFC5424_Invalid(t *testing.T) {
+	tests := []string{
+		"Oct 11 22:14:15 mymachine su: not 5424",
+		"1 2003-10-11T22:14:15.003Z host",
+		"1 not-a-time host app - - - msg",
+		`1 - host app - - [id unterminated="x] msg`,
+		"1 - host app - - garbage",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			if _, err := parseRFC5424(input); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		})
+	}
+}
+
+func TestSyslogReceiver_ParseRFC5424Entry(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	raw := `<163>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3"] An application error occurred`
+	entry := receiver.parseSyslogMessage([]byte(raw))
+
+	if entry.Message != "An application error occurred" {
+		t.Errorf("Expected message without header, got %q", entry.Message)
+	}
+	if entry.Source != "evntslog" {
+		t.Errorf("Expected source 'evntslog', got %q", entry.Source)
+	}
+	expectedTS := time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)
+	if !entry.Timestamp.Equal(expectedTS) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTS, entry.Timestamp)
+	}
+	if entry.Fields["hostname"] != "mymachine.example.com" {
+		t.Errorf("Expected hostname field, got %v", entry.Fields["hostname"])
+	}
+	if entry.Fields["procid"] != "1234" {
+		t.Errorf("Expected procid field, got %v", entry.Fields["procid"])
+	}
+	if entry.Fields["msgid"] != "ID47" {
+		t.Errorf("Expected msgid field, got %v", entry.Fields["msgid"])
+	}
+	if entry.Fields["exampleSDID@32473.iut"] != "3" {
+		t.Errorf("Expected structured data field, got %v", entry.Fields["exampleSDID@32473.iut"])
+	}
+	if entry.Level != models.LevelError {
+		t.Errorf("Expected ERROR level, got %s", entry.Level)
+	}
+	if entry.Fields[FieldRawTimestamp] != "2003-10-11T22:14:15.003Z" {
+		t.Errorf("Expected raw timestamp field, got %v", entry.Fields[FieldRawTimestamp])
+	}
+	if _, ok := entry.Fields[FieldTimestampSource]; ok {
+		t.Error("Extracted timestamp should not be flagged as ingest time")
+	}
+}
+
+func TestSyslogReceiver_ParseRFC5424NilValues(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "tcp")
+
+	before := time.Now()
+	entry := receiver.parseSyslogMessage([]byte("<13>1 - - - - - - hello"))
+
+	if entry.Source != "syslog:tcp" {
+		t.Errorf("Expected transport source for NIL app-name, got %q", entry.Source)
+	}
+	if entry.Timestamp.Before(before) {
+		t.Errorf("Expected receive time for NIL timestamp, got %v", entry.Timestamp)
+	}
+	if _, ok := entry.Fields["hostname"]; ok {
+		t.Error("NIL hostname should not be stored")
+	}
+	if entry.Message != "hello" {
+		t.Errorf("Expected message 'hello', got %q", entry.Message)
+	}
+	if entry.Fields[FieldTimestampSource] != TimestampSourceIngest {
+		t.Errorf("Expected timestamp_source=ingest, got %v", entry.Fields[FieldTimestampSource])
+	}
+}
+
+func TestSyslogReceiver_RFC3164Unchanged(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	raw := "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for user"
+	entry := receiver.parseSyslogMessage([]byte(raw))
+
+	if entry.Message != raw {
+		t.Errorf("Expected raw message %q, got %q", raw, entry.Message)
+	}
+	if entry.Source != "syslog:udp" {
+		t.Errorf("Expected source 'syslog:udp', got %q", entry.Source)
+	}
+}
+
+func TestSyslogReceiver_RFC3164Timestamp(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	now := time.Now()
+	entry := receiver.parseSyslogMessage([]byte("<34>Oct 11 22:14:15 mymachine su: 'su root' failed for user"))
+
+	ts := entry.Timestamp
+	if ts.Month() != time.October || ts.Day() != 11 || ts.Hour() != 22 || ts.Minute() != 14 || ts.Second() != 15 {
+		t.Errorf("Expected Oct 11 22:14:15, got %v", ts)
+	}
+	if ts.Location() != time.Local {
+		t.Errorf("Expected local time, got %v", ts.Location())
+	}
+	if ts.Year() != now.Year() && ts.Year() != now.Year()-1 && ts.Year() != now.Year()+1 {
+		t.Errorf("Expected a year next to %d, got %d", now.Year(), ts.Year())
+	}
+	if entry.Fields[FieldRawTimestamp] != "Oct 11 22:14:15" {
+		t.Errorf("Expected raw timestamp field, got %v", entry.Fields[FieldRawTimestamp])
+	}
+	if _, ok := entry.Fields[FieldTimestampSource]; ok {
+		t.Error("Extracted timestamp should not be flagged as ingest time")
+	}
+}
+
+func TestSyslogReceiver_RFC3164WithoutTimestamp(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	before := time.Now()
+	entry := receiver.parseSyslogMessage([]byte("<13>no timestamp here"))
+
+	if entry.Timestamp.Before(before) {
+		t.Errorf("Expected receive time, got %v", entry.Timestamp)
+	}
+	if entry.Fields[FieldTimestampSource] != TimestampSourceIngest {
+		t.Errorf("Expected timestamp_source=ingest, got %v", entry.Fields[FieldTimestampSource])
+	}
+}
+
+func TestParseRFC3164Header(t *testing.T) {
+	tests := []struct {
+		input    string
+		hostname string
+		tag      string
+		procid   string
+	}{
+		{"mymachine su: 'su root' failed", "mymachine", "su", ""},
+		{"web1 nginx[812]: GET /", "web1", "nginx", "812"},
+		{"sshd[77]: accepted", "", "sshd", "77"},
+		{"just some text", "", "", ""},
+		{"host tag[1: open bracket", "", "", ""},
+		{" su: leading space", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		hostname, tag, procid := parseRFC3164Header(tt.input)
+		if hostname != tt.hostname || tag != tt.tag || procid != tt.procid {
+			t.Errorf("%q: expected (%q, %q, %q), got (%q, %q, %q)", tt.input, tt.hostname, tt.tag, tt.procid, hostname, tag, procid)
+		}
+	}
+}
+
+func TestSyslogReceiver_RFC3164Header(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	entry := receiver.parseSyslogMessage([]byte("<34>Oct 11 22:14:15 web1 nginx[812]: upstream timed out"))
+	if entry.Fields["hostname"] != "web1" || entry.Fields["app_name"] != "nginx" || entry.Fields["procid"] != "812" {
+		t.Errorf("Expected the header in fields, got %v", entry.Fields)
+	}
+}
@@ -4,64 +4,217 @@ package sources
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/fatihserhatturan/logflux/internal/metrics"
 	"github.com/fatihserhatturan/logflux/pkg/models"
 )
 
+// SyslogReceiverOptions configures a SyslogReceiver
+type SyslogReceiverOptions struct {
+	// MaxConnections caps concurrent TCP connections. Connections accepted
+	// beyond it are closed straight away. Defaults to 1000.
+	MaxConnections int
+
+	// UDPBufferSize is the read buffer for UDP datagrams. The kernel
+	// truncates datagrams larger than the buffer without telling the
+	// receiver, so it should cover the largest expected message.
+	// Defaults to 8192, the size RFC 5424 receivers should accept.
+	UDPBufferSize int
+
+	// MaxMessageSize is the largest TCP message accepted. Longer messages
+	// are cut to this size, marked as truncated and followed by a WARNING
+	// entry. Defaults to 65536.
+	MaxMessageSize int
+
+	// DropEmptyMessages drops entries whose message is empty or only
+	// whitespace, counting them in the dropped metric. Off by default.
+	DropEmptyMessages bool
+
+	// KeepRaw keeps each message exactly as received, priority included, in
+	// Fields["_raw"]. Off by default as it doubles the memory per entry.
+	KeepRaw bool
+
+	// Allowlist, when set, drops UDP datagrams and closes TCP connections
+	// from outside its networks, counting them as denied
+	Allowlist *Allowlist
+
+	// SourceTemplate, when set, names each entry's Source from its parsed
+	// header, e.g. "{hostname}/{app_name}", instead of the app-name or the
+	// transport label
+	SourceTemplate *SourceTemplate
+
+	// IdleTimeout closes a TCP connection that sends nothing for this long.
+	// Every read that returns data restarts it. Zero keeps idle connections
+	// open until the peer or Stop closes them, as persistent forwarders
+	// expect.
+	IdleTimeout time.Duration
+
+	// KeepAlive is the period of TCP keepalive probes on accepted
+	// connections, which find peers that vanished without closing. Zero
+	// uses the system default, negative turns them off.
+	KeepAlive time.Duration
+}
+
+const (
+	// DefaultMaxConnections is the default cap on concurrent TCP connections
+	DefaultMaxConnections = 1000
+
+	// DefaultUDPBufferSize is the default UDP read buffer size
+	DefaultUDPBufferSize = 8192
+
+	// DefaultMaxMessageSize is the default largest TCP message
+	DefaultMaxMessageSize = maxTCPMessageSize
+)
+
+const (
+	// FieldTruncated marks an entry whose message was cut short
+	FieldTruncated = "truncated"
+
+	// FieldOriginalLength holds the length in bytes of a truncated message
+	FieldOriginalLength = "original_length"
+)
+
 // SyslogReceiver receives syslog messages over UDP or TCP
 type SyslogReceiver struct {
 	addr     string
 	protocol string // "udp" or "tcp"
+	opts     SyslogReceiverOptions
+
+	// Built once rather than for every message
+	name   string
+	source string
 
 	mu       sync.Mutex
 	listener interface{} // net.PacketConn for UDP, net.Listener for TCP
 	running  bool
 	wg       sync.WaitGroup
+	run      *runState
+
+	// closing is set by Stop so the listener loop can tell its listener
+	// being closed on purpose from it dying
+	closing atomic.Bool
+
+	// parser turns each message into an entry, SyslogParser by default
+	parser Parser
+
+	// deadLetter takes the messages parser fails on when set
+	deadLetter DeadLetter
+
+	// labels name the source and tag every entry, overriding SourceTemplate
+	labels SourceLabels
+
+	// connSlots holds one token per open TCP connection
+	connSlots chan struct{}
+
+	// stopped is closed by Stop so TCP connections blocked reading are
+	// closed rather than waited for
+	stopped chan struct{}
+
+	stats sourceStats
 }
 
 // NewSyslogReceiver creates a new syslog receiver
 func NewSyslogReceiver(addr string, protocol string) *SyslogReceiver {
+	return NewSyslogReceiverWithOptions(addr, protocol, SyslogReceiverOptions{})
+}
+
+// NewSyslogReceiverWithOptions creates a new syslog receiver with custom options
+func NewSyslogReceiverWithOptions(addr string, protocol string, opts SyslogReceiverOptions) *SyslogReceiver {
+	if opts.MaxConnections <= 0 {
+		opts.MaxConnections = DefaultMaxConnections
+	}
+	if opts.UDPBufferSize <= 0 {
+		opts.UDPBufferSize = DefaultUDPBufferSize
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = DefaultMaxMessageSize
+	}
+
+	protocol = strings.ToLower(protocol)
 	return &SyslogReceiver{
-		addr:     addr,
-		protocol: strings.ToLower(protocol),
+		addr:      addr,
+		protocol:  protocol,
+		opts:      opts,
+		name:      fmt.Sprintf("syslog:%s@%s", protocol, addr),
+		source:    "syslog:" + protocol,
+		parser:    SyslogParser{},
+		connSlots: make(chan struct{}, opts.MaxConnections),
+		run:       newRunState(),
 	}
 }
 
+// SetParser parses each message with p instead of as syslog. Messages p
+// fails on are kept raw. It must be called before Start.
+func (sr *SyslogReceiver) SetParser(p Parser) {
+	sr.parser = p
+}
+
+// SetDeadLetter sends messages the parser fails on to dl, with the error,
+// instead of keeping them raw. It must be called before Start.
+func (sr *SyslogReceiver) SetDeadLetter(dl DeadLetter) {
+	sr.deadLetter = dl
+}
+
+// SetLabels names the source and labels every entry from it. A source name
+// takes precedence over SourceTemplate. It must be called before Start.
+func (sr *SyslogReceiver) SetLabels(labels SourceLabels) {
+	sr.labels = labels
+}
+
 // Start begins listening for syslog messages
 func (sr *SyslogReceiver) Start(ctx context.Context, out chan<- *models.LogEntry) error {
 	sr.mu.Lock()
 	if sr.running {
 		sr.mu.Unlock()
-		return fmt.Errorf("syslog receiver already running")
+		return sourceError(models.AlreadyRunning, "syslog receiver already running")
 	}
 	sr.running = true
+	sr.closing.Store(false)
+	if sr.run.finished() {
+		sr.run = newRunState()
+	}
+	run := sr.run
+	stopped := make(chan struct{})
+	sr.stopped = stopped
 	sr.mu.Unlock()
 
+	var err error
 	switch sr.protocol {
 	case "udp":
-		return sr.startUDP(ctx, out)
+		err = sr.startUDP(ctx, out, run)
 	case "tcp":
-		return sr.startTCP(ctx, out)
+		err = sr.startTCP(ctx, out, run, stopped)
 	default:
-		return fmt.Errorf("unsupported protocol: %s", sr.protocol)
+		err = sourceError(models.Unsupported, "unsupported protocol: %s", sr.protocol)
 	}
+
+	// A failed start leaves the receiver free to start again
+	if err != nil {
+		sr.mu.Lock()
+		sr.running = false
+		sr.mu.Unlock()
+	}
+	return err
 }
 
 // startUDP starts UDP listener
-func (sr *SyslogReceiver) startUDP(ctx context.Context, out chan<- *models.LogEntry) error {
+func (sr *SyslogReceiver) startUDP(ctx context.Context, out chan<- *models.LogEntry, run *runState) error {
 	addr, err := net.ResolveUDPAddr("udp", sr.addr)
 	if err != nil {
-		return fmt.Errorf("failed to resolve UDP address: %w", err)
+		return sourceError(models.ResolveFailed, "failed to resolve UDP address: %w", err)
 	}
 
 	conn, err := net.ListenUDP("udp", addr)
 	if err != nil {
-		return fmt.Errorf("failed to listen on UDP: %w", err)
+		return sourceError(models.BindFailed, "failed to listen on UDP: %w", err)
 	}
 
 	sr.mu.Lock()
@@ -71,17 +224,20 @@ func (sr *SyslogReceiver) startUDP(ctx context.Context, out chan<- *models.LogEn
 	fmt.Printf("📡 Syslog receiver listening on UDP %s\n", sr.addr)
 
 	sr.wg.Add(1)
-	go sr.readUDP(ctx, conn, out)
+	go sr.readUDP(ctx, conn, out, run)
 
 	return nil
 }
 
 // readUDP reads from UDP connection
-func (sr *SyslogReceiver) readUDP(ctx context.Context, conn *net.UDPConn, out chan<- *models.LogEntry) {
+func (sr *SyslogReceiver) readUDP(ctx context.Context, conn *net.UDPConn, out chan<- *models.LogEntry, run *runState) {
+	var exitErr error
 	defer sr.wg.Done()
+	defer func() { run.finish(exitErr) }()
 	defer conn.Close()
 
-	buffer := make([]byte, 4096)
+	buffer := make([]byte, sr.opts.UDPBufferSize)
+	var messages [][]byte
 
 	for {
 		select {
@@ -91,19 +247,35 @@ func (sr *SyslogReceiver) readUDP(ctx context.Context, conn *net.UDPConn, out ch
 			// Set read deadline to allow checking context
 			conn.SetReadDeadline(time.Now().Add(1 * time.Second))
 
-			n, _, err := conn.ReadFromUDP(buffer)
+			n, remote, err := conn.ReadFromUDP(buffer)
 			if err != nil {
 				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 					continue
 				}
+				// Closed by Stop, or out from under us
+				if errors.Is(err, net.ErrClosed) {
+					if !sr.closing.Load() {
+						exitErr = sourceError(models.IOError, "UDP listener closed: %w", err)
+					}
+					return
+				}
 				// Log error but continue
 				fmt.Printf("Error reading UDP: %v\n", err)
+				sr.stats.recordError()
+				continue
+			}
+			if !sr.opts.Allowlist.Allows(remote.IP) {
+				recordDenied(&sr.stats, sr.Name())
 				continue
 			}
 
-			if n > 0 {
-				message := string(buffer[:n])
+			messages = splitDatagram(messages[:0], buffer[:n])
+			for _, message := range messages {
 				entry := sr.parseSyslogMessage(message)
+				sr.recordReceived(len(message))
+				if !sr.keep(entry) {
+					continue
+				}
 
 				select {
 				case out <- entry:
@@ -116,10 +288,11 @@ func (sr *SyslogReceiver) readUDP(ctx context.Context, conn *net.UDPConn, out ch
 }
 
 // startTCP starts TCP listener
-func (sr *SyslogReceiver) startTCP(ctx context.Context, out chan<- *models.LogEntry) error {
-	listener, err := net.Listen("tcp", sr.addr)
+func (sr *SyslogReceiver) startTCP(ctx context.Context, out chan<- *models.LogEntry, run *runState, stopped <-chan struct{}) error {
+	lc := net.ListenConfig{KeepAlive: sr.opts.KeepAlive}
+	listener, err := lc.Listen(ctx, "tcp", sr.addr)
 	if err != nil {
-		return fmt.Errorf("failed to listen on TCP: %w", err)
+		return sourceError(listenErrorCode(err), "failed to listen on TCP: %w", err)
 	}
 
 	sr.mu.Lock()
@@ -129,14 +302,16 @@ func (sr *SyslogReceiver) startTCP(ctx context.Context, out chan<- *models.LogEn
 	fmt.Printf("📡 Syslog receiver listening on TCP %s\n", sr.addr)
 
 	sr.wg.Add(1)
-	go sr.acceptTCP(ctx, listener, out)
+	go sr.acceptTCP(ctx, listener, out, run, stopped)
 
 	return nil
 }
 
 // acceptTCP accepts TCP connections
-func (sr *SyslogReceiver) acceptTCP(ctx context.Context, listener net.Listener, out chan<- *models.LogEntry) {
+func (sr *SyslogReceiver) acceptTCP(ctx context.Context, listener net.Listener, out chan<- *models.LogEntry, run *runState, stopped <-chan struct{}) {
+	var exitErr error
 	defer sr.wg.Done()
+	defer func() { run.finish(exitErr) }()
 	defer listener.Close()
 
 	for {
@@ -154,95 +329,254 @@ func (sr *SyslogReceiver) acceptTCP(ctx context.Context, listener net.Listener,
 				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 					continue
 				}
+				// Closed by Stop, or out from under us
+				if errors.Is(err, net.ErrClosed) {
+					if !sr.closing.Load() {
+						exitErr = sourceError(models.IOError, "TCP listener closed: %w", err)
+					}
+					return
+				}
 				// Log error but continue
 				fmt.Printf("Error accepting connection: %v\n", err)
 				continue
 			}
 
+			if !sr.opts.Allowlist.allowsAddr(conn.RemoteAddr()) {
+				conn.Close()
+				recordDenied(&sr.stats, sr.Name())
+				continue
+			}
+
+			// Refuse connections over the limit rather than queueing them
+			select {
+			case sr.connSlots <- struct{}{}:
+			default:
+				conn.Close()
+				sr.stats.connectionRejected()
+				continue
+			}
+
 			// Handle connection in separate goroutine
+			sr.stats.connectionOpened()
 			sr.wg.Add(1)
-			go sr.handleTCPConnection(ctx, conn, out)
+			go func() {
+				defer func() {
+					<-sr.connSlots
+					sr.stats.connectionClosed()
+				}()
+				sr.handleTCPConnection(ctx, conn, out, stopped)
+			}()
 		}
 	}
 }
 
 // handleTCPConnection handles a single TCP connection
-func (sr *SyslogReceiver) handleTCPConnection(ctx context.Context, conn net.Conn, out chan<- *models.LogEntry) {
+func (sr *SyslogReceiver) handleTCPConnection(ctx context.Context, conn net.Conn, out chan<- *models.LogEntry, stopped <-chan struct{}) {
 	defer sr.wg.Done()
 	defer conn.Close()
 
-	scanner := bufio.NewScanner(conn)
-	scanner.Buffer(make([]byte, 4096), 65536)
+	// Reads may block for as long as the connection is idle, so closing it
+	// is what ends them on cancellation or Stop
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		case <-finished:
+			return
+		}
+		conn.Close()
+	}()
+
+	reader := bufio.NewReaderSize(idleReader{conn: conn, timeout: sr.opts.IdleTimeout}, 4096)
+	// Messages are copied out by the parser, so one buffer does for all
+	var buf []byte
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-stopped:
+			return
 		default:
-			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
-
-			if !scanner.Scan() {
-				if err := scanner.Err(); err != nil {
-					fmt.Printf("Error scanning TCP: %v\n", err)
+			frame, err := readSyslogFrame(reader, sr.opts.MaxMessageSize, buf)
+			if err != nil {
+				var netErr net.Error
+				switch {
+				case err == io.EOF, errors.Is(err, net.ErrClosed):
+				case errors.As(err, &netErr) && netErr.Timeout():
+					fmt.Printf("Closing idle TCP connection from %s\n", conn.RemoteAddr())
+				default:
+					fmt.Printf("Error reading TCP: %v\n", err)
+					sr.stats.recordError()
 				}
 				return
 			}
 
-			message := scanner.Text()
-			if message == "" {
+			buf = frame.message[:0]
+			if len(frame.message) == 0 {
 				continue
 			}
 
-			entry := sr.parseSyslogMessage(message)
+			entry := sr.parseSyslogMessage(frame.message)
+			sr.recordReceived(frame.size)
+			if !sr.keep(entry) {
+				continue
+			}
 
-			select {
-			case out <- entry:
-			case <-ctx.Done():
-				return
+			entries := []*models.LogEntry{entry}
+			if frame.truncated() {
+				entry.Fields[FieldTruncated] = true
+				entry.Fields[FieldOriginalLength] = frame.size
+				entries = append(entries, sr.truncationWarning(frame, conn.RemoteAddr()))
+			}
+
+			for _, e := range entries {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}
 }
 
-// parseSyslogMessage parses a basic syslog message
-// Format: <priority>timestamp hostname tag: message
-// For now, we'll do simple parsing. We'll improve this in the parser phase.
-func (sr *SyslogReceiver) parseSyslogMessage(raw string) *models.LogEntry {
+// idleReader reads from a connection, pushing its read deadline back
+// before each read so only a connection silent for the whole timeout is
+// cut off
+type idleReader struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r idleReader) Read(p []byte) (int, error) {
+	if r.timeout > 0 {
+		r.conn.SetReadDeadline(time.Now().Add(r.timeout))
+	}
+	return r.conn.Read(p)
+}
+
+// truncationWarning describes a TCP message that was cut to MaxMessageSize
+func (sr *SyslogReceiver) truncationWarning(frame syslogFrame, remote net.Addr) *models.LogEntry {
 	entry := models.NewLogEntry()
-	entry.Source = fmt.Sprintf("syslog:%s", sr.protocol)
-	entry.Message = raw
-
-	// Try to extract priority (RFC 3164)
-	if strings.HasPrefix(raw, "<") {
-		endIdx := strings.Index(raw, ">")
-		if endIdx > 0 && endIdx < 10 {
-			// Priority found, extract it
-			entry.Fields["priority"] = raw[1:endIdx]
-			raw = raw[endIdx+1:]
-		}
+	entry.Source = sr.source
+	entry.Level = models.LevelWarning
+	entry.Message = fmt.Sprintf("syslog message truncated from %d to %d bytes", frame.size, len(frame.message))
+	entry.Fields[FieldOriginalLength] = frame.size
+	entry.Fields["max_message_size"] = sr.opts.MaxMessageSize
+	if remote != nil {
+		entry.Fields["remote_addr"] = remote.String()
 	}
+	markIngestTime(entry)
+	sr.labels.apply(entry)
+	sr.stats.recordEntry()
+	return entry
+}
 
-	// Store raw message for later parsing
-	entry.Fields["raw"] = raw
-
-	// Simple level detection based on keywords
-	lowerMsg := strings.ToLower(raw)
-	switch {
-	case strings.Contains(lowerMsg, "crit") || strings.Contains(lowerMsg, "emerg") || strings.Contains(lowerMsg, "alert"):
-		entry.Level = models.LevelCritical
-	case strings.Contains(lowerMsg, "err") || strings.Contains(lowerMsg, "error"):
-		entry.Level = models.LevelError
-	case strings.Contains(lowerMsg, "warn"):
-		entry.Level = models.LevelWarning
-	case strings.Contains(lowerMsg, "debug"):
-		entry.Level = models.LevelDebug
-	default:
-		entry.Level = models.LevelInfo
+// keep reports whether the entry passes validation. Dropped entries are
+// counted in the dropped metric.
+func (sr *SyslogReceiver) keep(entry *models.LogEntry) bool {
+	if entry == nil {
+		return false
+	}
+	if sr.opts.DropEmptyMessages && strings.TrimSpace(entry.Message) == "" {
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonEmptyMessage).Inc()
+		return false
 	}
+	return true
+}
+
+// recordReceived updates the metrics for one received message
+func (sr *SyslogReceiver) recordReceived(bytes int) {
+	name := sr.Name()
+	metrics.EntriesReceived.WithLabelValues(name).Inc()
+	metrics.BytesReceived.WithLabelValues(name).Add(int64(bytes))
+	sr.stats.recordBytes(bytes)
+	sr.stats.recordEntry()
+}
+
+// Stats returns a snapshot of the receiver's activity
+func (sr *SyslogReceiver) Stats() models.SourceStats {
+	return sr.stats.snapshot()
+}
 
+// parseSyslogMessage parses a syslog message with the receiver's parser.
+// Returns nil when the parser failed and the message was dead-lettered.
+func (sr *SyslogReceiver) parseSyslogMessage(raw []byte) *models.LogEntry {
+	entry, err := tryParse(sr.parser, raw, sr.source)
+	if err != nil && sr.deadLetter != nil {
+		dlErr := sr.deadLetter.Record(sr.source, raw, err)
+		if dlErr == nil {
+			return nil
+		}
+		// Better passed on raw than lost
+		fmt.Printf("⚠️  %v\n", dlErr)
+	}
+	if sr.opts.SourceTemplate != nil {
+		entry.Source = sr.opts.SourceTemplate.Render(entry, sr.source)
+	}
+	if sr.opts.KeepRaw {
+		keepRaw(entry, string(raw))
+	}
+	sr.labels.apply(entry)
 	return entry
 }
 
+// detectLevel guesses the level from keywords in the message, ignoring
+// case, falling back to INFO
+func detectLevel(text string) models.LogLevel {
+	if level, ok := models.DefaultLevelRules.Infer(text); ok {
+		return level
+	}
+	return models.LevelInfo
+}
+
+// applyRFC3164Header copies the hostname, tag and PID of an RFC 3164 header
+// into the entry's fields, named as for RFC 5424. The message is left whole.
+func applyRFC3164Header(entry *models.LogEntry, s string) {
+	hostname, tag, procid := parseRFC3164Header(s)
+	if hostname != "" {
+		entry.Fields["hostname"] = hostname
+	}
+	if tag != "" {
+		entry.Fields["app_name"] = tag
+	}
+	if procid != "" {
+		entry.Fields["procid"] = procid
+	}
+}
+
+// applyRFC5424 copies the decoded RFC 5424 parts into the entry
+func applyRFC5424(entry *models.LogEntry, msg *rfc5424Message) {
+	entry.Message = msg.Message
+
+	if !msg.Timestamp.IsZero() {
+		setEventTime(entry, msg.Timestamp, msg.RawTimestamp)
+	}
+	if msg.AppName != "" {
+		entry.Source = msg.AppName
+		entry.Fields["app_name"] = msg.AppName
+	}
+	if msg.Hostname != "" {
+		entry.Fields["hostname"] = msg.Hostname
+	}
+	if msg.ProcID != "" {
+		entry.Fields["procid"] = msg.ProcID
+	}
+	if msg.MsgID != "" {
+		entry.Fields["msgid"] = msg.MsgID
+	}
+
+	// Structured data params become "<sd-id>.<param>" fields
+	for id, params := range msg.StructuredData {
+		for name, value := range params {
+			entry.Fields[id+"."+name] = value
+		}
+	}
+}
+
 // Stop stops the receiver
 func (sr *SyslogReceiver) Stop() error {
 	sr.mu.Lock()
@@ -253,6 +587,8 @@ func (sr *SyslogReceiver) Stop() error {
 	}
 
 	sr.running = false
+	sr.closing.Store(true)
+	close(sr.stopped)
 
 	// Close listener
 	if sr.listener != nil {
@@ -270,7 +606,38 @@ func (sr *SyslogReceiver) Stop() error {
 	return nil
 }
 
+// Done is closed once the listener loop exits, after Stop, cancellation or
+// the listener failing
+func (sr *SyslogReceiver) Done() <-chan struct{} {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.run.done
+}
+
+// Err reports why the listener loop died once Done is closed. It is nil
+// after Stop or cancellation.
+func (sr *SyslogReceiver) Err() error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.run.Err()
+}
+
+// Addr returns the address the receiver is listening on. Before Start it
+// returns the configured address.
+func (sr *SyslogReceiver) Addr() string {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	switch l := sr.listener.(type) {
+	case net.PacketConn:
+		return l.LocalAddr().String()
+	case net.Listener:
+		return l.Addr().String()
+	}
+	return sr.addr
+}
+
 // Name returns the source name
 func (sr *SyslogReceiver) Name() string {
-	return fmt.Sprintf("syslog:%s@%s", sr.protocol, sr.addr)
+	return sr.name
 }
@@ -4,10 +4,13 @@ package sources
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/fatihserhatturan/logflux/internal/metrics"
 	"github.com/fatihserhatturan/logflux/pkg/models"
 )
 
@@ -26,13 +29,7 @@ func TestSyslogReceiver_UDP(t *testing.T) {
 	}
 	defer receiver.Stop()
 
-	// Get actual address (since we used port 0)
-	receiver.mu.Lock()
-	actualAddr := receiver.listener.(*net.UDPConn).LocalAddr().String()
-	receiver.mu.Unlock()
-
-	// Give it a moment to start
-	time.Sleep(100 * time.Millisecond)
+	actualAddr := receiver.Addr()
 
 	// Send test message
 	conn, err := net.Dial("udp", actualAddr)
@@ -60,6 +57,76 @@ func TestSyslogReceiver_UDP(t *testing.T) {
 	}
 }
 
+func TestSyslogReceiver_KeepRaw(t *testing.T) {
+	for _, protocol := range []string{"udp", "tcp"} {
+		t.Run(protocol, func(t *testing.T) {
+			receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", protocol, SyslogReceiverOptions{KeepRaw: true})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			out := make(chan *models.LogEntry, 10)
+			if err := receiver.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			conn, err := net.Dial(protocol, receiver.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			testMsg := "<165>1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 [exampleSDID@32473 iut=\"3\"]  two  spaces"
+			if _, err := conn.Write([]byte(testMsg + "\n")); err != nil {
+				t.Fatal(err)
+			}
+
+			entry := receiveEntry(t, out)
+			if raw := entry.Fields[FieldRaw]; raw != testMsg {
+				t.Errorf("Expected raw %q, got %q", testMsg, raw)
+			}
+			if entry.Message == testMsg {
+				t.Errorf("Expected the message parsed out of %q", testMsg)
+			}
+		})
+	}
+}
+
+func TestSyslogReceiver_UDPPackedDatagram(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	conn, err := net.Dial("udp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	datagram := "<34>Oct 11 22:14:15 mymachine su: first\n" +
+		"<165>1 2003-10-11T22:14:15.003Z host app - - [meta note=\"two\nlines\"] second\n" +
+		"<13>Oct 11 22:14:16 mymachine cron: third\n"
+	if _, err := conn.Write([]byte(datagram)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"<34>Oct 11 22:14:15 mymachine su: first", "second", "<13>Oct 11 22:14:16 mymachine cron: third"} {
+		if entry := receiveEntry(t, out); entry.Message != expected {
+			t.Errorf("Expected message %q, got %q", expected, entry.Message)
+		}
+	}
+	if got := receiver.Stats().EntriesProduced; got != 3 {
+		t.Errorf("Expected 3 entries, got %d", got)
+	}
+}
+
 func TestSyslogReceiver_TCP(t *testing.T) {
 	receiver := NewSyslogReceiver("127.0.0.1:0", "tcp")
 
@@ -73,12 +140,7 @@ func TestSyslogReceiver_TCP(t *testing.T) {
 	}
 	defer receiver.Stop()
 
-	// Get actual address
-	receiver.mu.Lock()
-	actualAddr := receiver.listener.(net.Listener).Addr().String()
-	receiver.mu.Unlock()
-
-	time.Sleep(100 * time.Millisecond)
+	actualAddr := receiver.Addr()
 
 	// Connect and send message
 	conn, err := net.Dial("tcp", actualAddr)
@@ -116,11 +178,7 @@ func TestSyslogReceiver_MultipleMessages(t *testing.T) {
 	}
 	defer receiver.Stop()
 
-	receiver.mu.Lock()
-	actualAddr := receiver.listener.(*net.UDPConn).LocalAddr().String()
-	receiver.mu.Unlock()
-
-	time.Sleep(100 * time.Millisecond)
+	actualAddr := receiver.Addr()
 
 	conn, err := net.Dial("udp", actualAddr)
 	if err != nil {
@@ -154,22 +212,239 @@ func TestSyslogReceiver_MultipleMessages(t *testing.T) {
 	}
 }
 
+func TestSyslogReceiver_Addr(t *testing.T) {
+	for _, protocol := range []string{"udp", "tcp"} {
+		t.Run(protocol, func(t *testing.T) {
+			receiver := NewSyslogReceiver("127.0.0.1:0", protocol)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			_, port, err := net.SplitHostPort(receiver.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if port == "0" {
+				t.Errorf("Expected a concrete port, got %q", receiver.Addr())
+			}
+		})
+	}
+}
+
+func TestSyslogReceiver_MaxConnections(t *testing.T) {
+	receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", "tcp", SyslogReceiverOptions{MaxConnections: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	send := func(conn net.Conn, msg string) {
+		t.Helper()
+		if _, err := fmt.Fprintf(conn, "<13>%s\n", msg); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case entry := <-out:
+			if entry.Message != "<13>"+msg {
+				t.Errorf("Expected %q, got %q", msg, entry.Message)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", msg)
+		}
+	}
+
+	var conns []net.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", receiver.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		send(conn, fmt.Sprintf("hello from %d", i))
+		conns = append(conns, conn)
+	}
+
+	// The third connection is accepted and closed straight away
+	extra, err := net.Dial("tcp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer extra.Close()
+	extra.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := extra.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the excess connection to be closed, got %v", err)
+	}
+
+	// Existing connections keep working
+	send(conns[0], "still here")
+	send(conns[1], "me too")
+
+	stats := receiver.Stats()
+	if stats.ActiveConnections != 2 {
+		t.Errorf("Expected 2 active connections, got %d", stats.ActiveConnections)
+	}
+	if stats.RejectedConnections != 1 {
+		t.Errorf("Expected 1 rejected connection, got %d", stats.RejectedConnections)
+	}
+
+	// Closing a connection frees its slot
+	conns[0].Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for receiver.Stats().ActiveConnections != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 1 active connection after close, got %d", receiver.Stats().ActiveConnections)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	conn, err := net.Dial("tcp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	send(conn, "new connection")
+}
+
+func TestSyslogReceiver_IdleConnection(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "tcp")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	receive := func(msg string) {
+		t.Helper()
+		if _, err := fmt.Fprintf(conn, "<13>%s\n", msg); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case entry := <-out:
+			if entry.Message != "<13>"+msg {
+				t.Errorf("Expected %q, got %q", msg, entry.Message)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", msg)
+		}
+	}
+
+	// Silence longer than the old fixed 5s deadline doesn't close it
+	receive("before")
+	time.Sleep(5500 * time.Millisecond)
+	receive("after")
+
+	// Stop closes the idle connection rather than waiting on it
+	done := make(chan error, 1)
+	go func() {
+		done <- receiver.Stop()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Stop failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stop waited on the idle connection")
+	}
+}
+
+func TestSyslogReceiver_IdleTimeout(t *testing.T) {
+	receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", "tcp", SyslogReceiverOptions{IdleTimeout: 300 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan *models.LogEntry, 10)
+	if err := receiver.Start(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Stop()
+
+	conn, err := net.Dial("tcp", receiver.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Activity keeps pushing the timeout back, past its length in total
+	for i := 0; i < 6; i++ {
+		if _, err := fmt.Fprintf(conn, "<13>message %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	for i := 0; i < 6; i++ {
+		select {
+		case <-out:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected 6 entries, got %d", i)
+		}
+	}
+
+	// Going quiet gets the connection closed
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("Expected the idle connection to be closed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the connection closed after about 300ms, took %s", elapsed)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for receiver.Stats().ActiveConnections != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected no active connections, got %d", receiver.Stats().ActiveConnections)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if errs := receiver.Stats().Errors; errs != 0 {
+		t.Errorf("Expected an idle timeout not to count as an error, got %d", errs)
+	}
+}
+
 func TestSyslogReceiver_LevelDetection(t *testing.T) {
 	tests := []struct {
 		message       string
 		expectedLevel models.LogLevel
 	}{
-		{"<34>Error occurred in system", models.LevelError},
-		{"<34>Warning: disk space low", models.LevelWarning},
-		{"<34>Critical system failure", models.LevelCritical},
-		{"<34>Debug information", models.LevelDebug},
-		{"<34>Normal operation", models.LevelInfo},
+		// No priority: keyword heuristic
+		{"Error occurred in system", models.LevelError},
+		{"Warning: disk space low", models.LevelWarning},
+		{"Critical system failure", models.LevelCritical},
+		{"Debug information", models.LevelDebug},
+		{"Normal operation", models.LevelInfo},
+		// Priority severity wins over keywords
+		{"<34>Normal operation", models.LevelCritical},
+		{"<11>Normal operation", models.LevelError},
+		{"<12>Normal operation", models.LevelWarning},
+		{"<14>Error in the word only", models.LevelInfo},
+		{"<15>Normal operation", models.LevelDebug},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.message, func(t *testing.T) {
 			receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
-			entry := receiver.parseSyslogMessage(tt.message)
+			entry := receiver.parseSyslogMessage([]byte(tt.message))
 
 			if entry.Level != tt.expectedLevel {
 				t.Errorf("Expected level %s, got %s", tt.expectedLevel, entry.Level)
@@ -178,6 +453,81 @@ func TestSyslogReceiver_LevelDetection(t *testing.T) {
 	}
 }
 
+func TestDetectLevel_IgnoresCase(t *testing.T) {
+	// What detectLevel did by lowering the whole message first
+	lowered := func(text string) models.LogLevel {
+		lower := strings.ToLower(text)
+		switch {
+		case strings.Contains(lower, "crit") || strings.Contains(lower, "emerg") || strings.Contains(lower, "alert"):
+			return models.LevelCritical
+		case strings.Contains(lower, "err"):
+			return models.LevelError
+		case strings.Contains(lower, "warn"):
+			return models.LevelWarning
+		case strings.Contains(lower, "debug"):
+			return models.LevelDebug
+		default:
+			return models.LevelInfo
+		}
+	}
+
+	for _, text := range []string{
+		"", "ERROR", "eRrOr in module", "WaRn", "DEBUGGING", "Emergency", "ALERT!", "er", "war n",
+		"CRİT", "ＥＲＲＯＲ", "\xffERR\xfe", "ünïcödé wärnïng", "prefix-DeBuG-suffix",
+	} {
+		if got, want := detectLevel(text), lowered(text); got != want {
+			t.Errorf("%q: expected %s, got %s", text, want, got)
+		}
+	}
+}
+
+func TestSyslogReceiver_PriorityDecoding(t *testing.T) {
+	tests := []struct {
+		message          string
+		expectedFacility string
+		expectedSeverity string
+		expectedLevel    models.LogLevel
+	}{
+		{"<34>Oct 11 22:14:15 mymachine su: 'su root' failed", "auth", "crit", models.LevelCritical},
+		{"<0>kernel panic", "kern", "emerg", models.LevelCritical},
+		{"<3>kernel oops", "kern", "err", models.LevelError},
+		{"<165>1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 myproc 8710 - - note", "local4", "notice", models.LevelInfo},
+		{"<191>debugging", "local7", "debug", models.LevelDebug},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+			entry := receiver.parseSyslogMessage([]byte(tt.message))
+
+			if entry.Fields["facility"] != tt.expectedFacility {
+				t.Errorf("Expected facility %s, got %v", tt.expectedFacility, entry.Fields["facility"])
+			}
+			if entry.Fields["severity"] != tt.expectedSeverity {
+				t.Errorf("Expected severity %s, got %v", tt.expectedSeverity, entry.Fields["severity"])
+			}
+			if entry.Level != tt.expectedLevel {
+				t.Errorf("Expected level %s, got %s", tt.expectedLevel, entry.Level)
+			}
+		})
+	}
+}
+
+func TestSyslogReceiver_InvalidPriority(t *testing.T) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+
+	// Out of range / non-numeric priorities fall back to keyword detection
+	for _, msg := range []string{"<192>disk error", "<ab>disk error"} {
+		entry := receiver.parseSyslogMessage([]byte(msg))
+		if _, ok := entry.Fields["facility"]; ok {
+			t.Errorf("%q: facility should not be set", msg)
+		}
+		if entry.Level != models.LevelError {
+			t.Errorf("%q: expected ERROR from keywords, got %s", msg, entry.Level)
+		}
+	}
+}
+
 func TestSyslogReceiver_GracefulShutdown(t *testing.T) {
 	receiver := NewSyslogReceiver("127.0.0.1:0", "tcp")
 
@@ -188,8 +538,6 @@ func TestSyslogReceiver_GracefulShutdown(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	time.Sleep(100 * time.Millisecond)
-
 	// Signal shutdown
 	cancel()
 	time.Sleep(100 * time.Millisecond)
@@ -223,11 +571,7 @@ func BenchmarkSyslogReceiver_UDP(b *testing.B) {
 	}
 	defer receiver.Stop()
 
-	receiver.mu.Lock()
-	actualAddr := receiver.listener.(*net.UDPConn).LocalAddr().String()
-	receiver.mu.Unlock()
-
-	time.Sleep(100 * time.Millisecond)
+	actualAddr := receiver.Addr()
 
 	conn, err := net.Dial("udp", actualAddr)
 	if err != nil {
@@ -243,6 +587,7 @@ func BenchmarkSyslogReceiver_UDP(b *testing.B) {
 
 	testMsg := []byte("<34>Oct 11 22:14:15 mymachine test message")
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -250,3 +595,129 @@ func BenchmarkSyslogReceiver_UDP(b *testing.B) {
 		}
 	})
 }
+
+// syslogBenchMessages cover the parse paths: priority with an RFC 3164
+// timestamp, keyword level detection without one, and RFC 5424
+var syslogBenchMessages = []struct {
+	name    string
+	message string
+}{
+	{"rfc3164", "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8"},
+	{"keywords", "Oct 11 22:14:15 mymachine app: connection reset by peer, retrying without debug"},
+	{"rfc5424", "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut=\"3\"] An application event"},
+}
+
+func BenchmarkSyslogReceiver_ParseDatagram(b *testing.B) {
+	receiver := NewSyslogReceiver("127.0.0.1:0", "udp")
+	for _, bm := range syslogBenchMessages {
+		b.Run(bm.name, func(b *testing.B) {
+			datagram := []byte(bm.message)
+			var messages [][]byte
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				messages = splitDatagram(messages[:0], datagram)
+				for _, message := range messages {
+					receiver.parseSyslogMessage(message)
+				}
+			}
+		})
+	}
+}
+
+func TestSyslogReceiver_EmptyMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		drop     bool
+		expected []string
+	}{
+		{"lenient", false, []string{"  \t ", "<13>after blank"}},
+		{"strict", true, []string{"<13>after blank"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := NewSyslogReceiverWithOptions("127.0.0.1:0", "udp", SyslogReceiverOptions{DropEmptyMessages: tt.drop})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			out := make(chan *models.LogEntry, 10)
+
+			if err := receiver.Start(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Stop()
+
+			dropped := metrics.EntriesDropped.WithLabelValues(metrics.ReasonEmptyMessage)
+			before := dropped.Value()
+
+			conn, err := net.Dial("udp", receiver.Addr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.Write([]byte("  \t "))
+			conn.Write([]byte("<13>after blank"))
+
+			for _, expected := range tt.expected {
+				select {
+				case entry := <-out:
+					if entry.Message != expected {
+						t.Errorf("Expected %q, got %q", expected, entry.Message)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatal("Timeout waiting for log entry")
+				}
+			}
+
+			wantDropped := int64(0)
+			if tt.drop {
+				wantDropped = 1
+			}
+			if got := dropped.Value() - before; got != wantDropped {
+				t.Errorf("Expected dropped counter +%d, got +%d", wantDropped, got)
+			}
+		})
+	}
+}
+
+func TestSyslogReceiver_ListenerDied(t *testing.T) {
+	for _, protocol := range []string{"udp", "tcp"} {
+		t.Run(protocol, func(t *testing.T) {
+			receiver := NewSyslogReceiver("127.0.0.1:0", protocol)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+				t.Fatal(err)
+			}
+
+			// Close the listener without going through Stop
+			receiver.mu.Lock()
+			receiver.listener.(io.Closer).Close()
+			receiver.mu.Unlock()
+
+			select {
+			case <-receiver.Done():
+			case <-time.After(3 * time.Second):
+				t.Fatal("Expected Done to close when the listener dies")
+			}
+			if receiver.Err() == nil {
+				t.Error("Expected Err to report the dead listener")
+			}
+
+			// A dead receiver can be started again
+			receiver.Stop()
+			if err := receiver.Start(ctx, make(chan *models.LogEntry, 1)); err != nil {
+				t.Fatalf("Expected restart to succeed, got %v", err)
+			}
+			if err := receiver.Stop(); err != nil {
+				t.Fatal(err)
+			}
+			<-receiver.Done()
+			if err := receiver.Err(); err != nil {
+				t.Errorf("Expected no error after Stop, got %v", err)
+			}
+		})
+	}
+}
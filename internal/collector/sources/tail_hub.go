@@ -0,0 +1,117 @@
+package sources
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// tailClientBuffer is how many frames a live-tail viewer may fall behind
+// before frames are dropped for it
+const tailClientBuffer = 256
+
+// tailFrame is an encoded entry on its way to live-tail viewers
+type tailFrame struct {
+	level models.LogLevel
+	data  []byte
+}
+
+// tailClient is one live-tail viewer
+type tailClient struct {
+	minLevel models.LogLevel // empty for all levels
+	frames   chan []byte
+	dropped  atomic.Int64
+}
+
+// tailHub fans entries out to live-tail viewers. Publishing never blocks:
+// a viewer whose buffer is full misses frames instead of stalling ingestion
+// or the other viewers.
+type tailHub struct {
+	mu      sync.RWMutex
+	clients map[*tailClient]struct{}
+	closed  bool
+}
+
+func newTailHub() *tailHub {
+	return &tailHub{
+		clients: make(map[*tailClient]struct{}),
+	}
+}
+
+// active reports whether anyone is watching, so callers can skip encoding
+func (h *tailHub) active() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients) > 0
+}
+
+// subscribe registers a viewer. It returns nil once the hub is closed.
+func (h *tailHub) subscribe(minLevel models.LogLevel) *tailClient {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	client := &tailClient{
+		minLevel: minLevel,
+		frames:   make(chan []byte, tailClientBuffer),
+	}
+	h.clients[client] = struct{}{}
+	return client
+}
+
+// unsubscribe removes a viewer and closes its frame channel
+func (h *tailHub) unsubscribe(client *tailClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.frames)
+	}
+}
+
+// publish hands a frame to every viewer interested in its level
+func (h *tailHub) publish(frame tailFrame) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		if client.minLevel != "" && !frame.level.AtLeast(client.minLevel) {
+			continue
+		}
+		select {
+		case client.frames <- frame.data:
+		default:
+			client.dropped.Add(1)
+		}
+	}
+}
+
+// close disconnects every viewer and refuses new ones until reopen
+func (h *tailHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for client := range h.clients {
+		delete(h.clients, client)
+		close(client.frames)
+	}
+}
+
+// reopen lets viewers subscribe again after a restart
+func (h *tailHub) reopen() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = false
+}
+
+// count returns the number of connected viewers
+func (h *tailHub) count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
@@ -0,0 +1,56 @@
+package sources
+
+import (
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestTailHub_DropsForFullClients(t *testing.T) {
+	hub := newTailHub()
+	slow := hub.subscribe("")
+	fast := hub.subscribe("")
+
+	for i := 0; i < tailClientBuffer+10; i++ {
+		hub.publish(tailFrame{level: models.LevelInfo, data: []byte("{}")})
+		<-fast.frames
+	}
+
+	if got := slow.dropped.Load(); got != 10 {
+		t.Errorf("Expected 10 frames dropped for the slow client, got %d", got)
+	}
+	if got := fast.dropped.Load(); got != 0 {
+		t.Errorf("Expected nothing dropped for the fast client, got %d", got)
+	}
+}
+
+func TestTailHub_LevelFilter(t *testing.T) {
+	hub := newTailHub()
+	client := hub.subscribe(models.LevelWarning)
+
+	hub.publish(tailFrame{level: models.LevelInfo, data: []byte("info")})
+	hub.publish(tailFrame{level: models.LevelError, data: []byte("error")})
+
+	if len(client.frames) != 1 || string(<-client.frames) != "error" {
+		t.Error("Expected only the ERROR frame")
+	}
+}
+
+func TestTailHub_Close(t *testing.T) {
+	hub := newTailHub()
+	client := hub.subscribe("")
+
+	hub.close()
+	if _, ok := <-client.frames; ok {
+		t.Error("Expected the client's frames to be closed")
+	}
+	hub.unsubscribe(client)
+
+	if hub.subscribe("") != nil {
+		t.Error("Expected a closed hub to refuse viewers")
+	}
+	hub.reopen()
+	if hub.subscribe("") == nil {
+		t.Error("Expected a reopened hub to accept viewers")
+	}
+}
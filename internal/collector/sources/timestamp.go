@@ -0,0 +1,76 @@
+package sources
+
+import (
+	"strings"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Fields recording where an entry's timestamp came from
+const (
+	// FieldRawTimestamp holds the timestamp exactly as it appeared in the log
+	FieldRawTimestamp = "raw_timestamp"
+
+	// FieldTimestampSource is set to TimestampSourceIngest when no event time
+	// could be extracted and Timestamp is the time the entry was received
+	FieldTimestampSource  = "timestamp_source"
+	TimestampSourceIngest = "ingest"
+
+	// FieldTimestampUnparsed is set to true when the log had a timestamp
+	// that couldn't be parsed, which stays in Fields under its own key
+	FieldTimestampUnparsed = "timestamp_unparsed"
+)
+
+// setEventTime sets the entry's timestamp from the log itself and keeps the
+// original text for auditing
+func setEventTime(entry *models.LogEntry, ts time.Time, raw string) {
+	entry.Timestamp = ts
+	entry.Fields[FieldRawTimestamp] = raw
+}
+
+// markIngestTime flags an entry whose timestamp is the receive time
+func markIngestTime(entry *models.LogEntry) {
+	entry.Fields[FieldTimestampSource] = TimestampSourceIngest
+}
+
+// parseRFC3164Timestamp parses the "Mmm dd hh:mm:ss" timestamp at the start
+// of an RFC 3164 message. Returns the time, the raw timestamp text and the
+// remainder of the message.
+func parseRFC3164Timestamp(s string, now time.Time) (time.Time, string, string, bool) {
+	if len(s) < len(time.Stamp) {
+		return time.Time{}, "", s, false
+	}
+
+	raw := s[:len(time.Stamp)]
+	ts, err := time.ParseInLocation(time.Stamp, raw, now.Location())
+	if err != nil {
+		return time.Time{}, "", s, false
+	}
+
+	rest := strings.TrimPrefix(s[len(time.Stamp):], " ")
+	return assumeYear(ts, now), raw, rest, true
+}
+
+// assumeYear fills in the year for timestamps whose layout has none. The
+// current year is used unless that puts the time well into the future, which
+// happens for e.g. a "Dec 31" line read on January 1st.
+func assumeYear(ts time.Time, now time.Time) time.Time {
+	if ts.Year() != 0 {
+		return ts
+	}
+
+	year := now.Year()
+	withYear := func(y int) time.Time {
+		return time.Date(y, ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(), ts.Location())
+	}
+
+	candidate := withYear(year)
+	switch {
+	case candidate.After(now.AddDate(0, 1, 0)):
+		return withYear(year - 1)
+	case candidate.Before(now.AddDate(0, -11, 0)):
+		return withYear(year + 1)
+	}
+	return candidate
+}
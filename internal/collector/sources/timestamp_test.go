@@ -0,0 +1,94 @@
+package sources
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRFC3164Timestamp(t *testing.T) {
+	now := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    string
+		now      time.Time
+		expected time.Time
+		raw      string
+		rest     string
+		ok       bool
+	}{
+		{
+			name:     "current year",
+			input:    "Oct 11 22:14:15 mymachine su: failed",
+			now:      now,
+			expected: time.Date(2024, 10, 11, 22, 14, 15, 0, time.UTC),
+			raw:      "Oct 11 22:14:15",
+			rest:     "mymachine su: failed",
+			ok:       true,
+		},
+		{
+			name:     "space padded day",
+			input:    "Nov  5 01:02:03 host app: hi",
+			now:      now,
+			expected: time.Date(2024, 11, 5, 1, 2, 3, 0, time.UTC),
+			raw:      "Nov  5 01:02:03",
+			rest:     "host app: hi",
+			ok:       true,
+		},
+		{
+			name:     "last year's line read in january",
+			input:    "Dec 31 23:59:59 host app: bye",
+			now:      time.Date(2025, 1, 1, 0, 0, 5, 0, time.UTC),
+			expected: time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+			raw:      "Dec 31 23:59:59",
+			rest:     "host app: bye",
+			ok:       true,
+		},
+		{
+			name:     "next year's line from a fast clock",
+			input:    "Jan  1 00:00:01 host app: hi",
+			now:      time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC),
+			expected: time.Date(2025, 1, 1, 0, 0, 1, 0, time.UTC),
+			raw:      "Jan  1 00:00:01",
+			rest:     "host app: hi",
+			ok:       true,
+		},
+		{
+			name:  "no timestamp",
+			input: "kernel panic at the disco",
+			now:   now,
+			rest:  "kernel panic at the disco",
+		},
+		{
+			name:  "too short",
+			input: "Oct 11",
+			now:   now,
+			rest:  "Oct 11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, raw, rest, ok := parseRFC3164Timestamp(tt.input, tt.now)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+			}
+			if !ts.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, ts)
+			}
+			if raw != tt.raw {
+				t.Errorf("Expected raw %q, got %q", tt.raw, raw)
+			}
+			if rest != tt.rest {
+				t.Errorf("Expected rest %q, got %q", tt.rest, rest)
+			}
+		})
+	}
+}
+
+func TestAssumeYear_KeepsExplicitYear(t *testing.T) {
+	ts := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	if got := assumeYear(ts, time.Now()); !got.Equal(ts) {
+		t.Errorf("Expected %v, got %v", ts, got)
+	}
+}
@@ -0,0 +1,248 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// SupervisorOptions configures a Supervisor
+type SupervisorOptions struct {
+	// MaxRestarts is how many restarts are allowed within Window before the
+	// supervisor gives up on the source. Defaults to 5.
+	MaxRestarts int
+
+	// Window is the period restarts are counted over. Defaults to 1m.
+	Window time.Duration
+
+	// BaseDelay is the wait before the first restart, doubled for every
+	// restart already in the window. Defaults to 1s.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the wait between restarts. Defaults to 30s.
+	MaxDelay time.Duration
+}
+
+// exitingSource is implemented by sources whose background loop can end on
+// its own. Err is non-nil when it died rather than finished.
+type exitingSource interface {
+	Done() <-chan struct{}
+	Err() error
+}
+
+// Supervisor restarts a source whose background loop dies while its context
+// is still live, backing off between attempts. Once the source fails more
+// than MaxRestarts times within Window it is left stopped. Sources that
+// don't report how they exited are passed through unsupervised.
+type Supervisor struct {
+	source Source
+	opts   SupervisorOptions
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error // why supervision gave up
+
+	// restartTimes holds the recent restarts, only touched by supervise
+	restartTimes []time.Time
+	restarts     atomic.Int64
+}
+
+// NewSupervisor wraps a source with restart-on-failure supervision
+func NewSupervisor(source Source, opts SupervisorOptions) *Supervisor {
+	if opts.MaxRestarts <= 0 {
+		opts.MaxRestarts = 5
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = time.Second
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 30 * time.Second
+	}
+
+	done := make(chan struct{})
+	close(done)
+
+	return &Supervisor{
+		source: source,
+		opts:   opts,
+		done:   done,
+	}
+}
+
+// Start starts the source and begins supervising it. A failure to start
+// the first time is returned as is.
+func (s *Supervisor) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return &models.SourceError{Code: models.AlreadyRunning, Err: fmt.Errorf("supervisor already running")}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	if err := s.source.Start(ctx, out); err != nil {
+		cancel()
+		return err
+	}
+
+	s.running = true
+	s.cancel = cancel
+	s.err = nil
+
+	if exiting, ok := s.source.(exitingSource); ok {
+		s.done = make(chan struct{})
+		go s.supervise(ctx, out, exiting, s.done)
+	}
+	return nil
+}
+
+// supervise waits for the source to exit and restarts it if it died
+func (s *Supervisor) supervise(ctx context.Context, out chan<- *models.LogEntry, source exitingSource, done chan struct{}) {
+	defer close(done)
+
+	for {
+		select {
+		case <-source.Done():
+		case <-ctx.Done():
+			return
+		}
+
+		err := source.Err()
+		if ctx.Err() != nil || err == nil {
+			// Stopped, cancelled or out of input
+			return
+		}
+
+		fmt.Printf("⚠️  Source %s failed: %v\n", s.source.Name(), err)
+		if err := s.restart(ctx, out); err != nil {
+			if ctx.Err() == nil {
+				fmt.Printf("❌ Giving up on source %s: %v\n", s.source.Name(), err)
+				s.mu.Lock()
+				s.err = err
+				s.mu.Unlock()
+			}
+			return
+		}
+		fmt.Printf("🔁 Source %s restarted\n", s.source.Name())
+	}
+}
+
+// restart starts the source again, retrying failed starts until it comes
+// up, the restart budget runs out or ctx is done
+func (s *Supervisor) restart(ctx context.Context, out chan<- *models.LogEntry) error {
+	for {
+		if !s.allowRestart() {
+			return fmt.Errorf("restarted %d times within %s", s.opts.MaxRestarts, s.opts.Window)
+		}
+
+		timer := time.NewTimer(s.delay())
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		s.restartTimes = append(s.restartTimes, time.Now())
+		s.restarts.Add(1)
+
+		// Release whatever the dead run still holds before starting over
+		s.source.Stop()
+		err := s.source.Start(ctx, out)
+		if err == nil {
+			return nil
+		}
+
+		var sourceErr *models.SourceError
+		if errors.As(err, &sourceErr) && !sourceErr.Retryable() {
+			return err
+		}
+		fmt.Printf("⚠️  Source %s failed to restart: %v\n", s.source.Name(), err)
+	}
+}
+
+// allowRestart forgets restarts older than the window and reports whether
+// another one fits
+func (s *Supervisor) allowRestart() bool {
+	cutoff := time.Now().Add(-s.opts.Window)
+	recent := s.restartTimes[:0]
+	for _, t := range s.restartTimes {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	s.restartTimes = recent
+	return len(recent) < s.opts.MaxRestarts
+}
+
+// delay returns the backoff before the next restart, doubling with every
+// restart still in the window
+func (s *Supervisor) delay() time.Duration {
+	d := s.opts.BaseDelay
+	for i := 0; i < len(s.restartTimes) && d < s.opts.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > s.opts.MaxDelay {
+		d = s.opts.MaxDelay
+	}
+	return d
+}
+
+// Stop stops supervising and stops the source
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = false
+	s.cancel()
+	done := s.done
+	s.mu.Unlock()
+
+	// Wait out a restart in progress so it can't race the stop
+	<-done
+	return s.source.Stop()
+}
+
+// Done is closed once supervision ends: the source was stopped, finished
+// on its own, or failed too often
+func (s *Supervisor) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// Err returns why the supervisor gave up on the source, nil if it didn't
+func (s *Supervisor) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Source returns the supervised source
+func (s *Supervisor) Source() Source {
+	return s.source
+}
+
+// Name returns the supervised source's name
+func (s *Supervisor) Name() string {
+	return s.source.Name()
+}
+
+// Stats returns the source's stats along with the restart count
+func (s *Supervisor) Stats() models.SourceStats {
+	stats := s.source.Stats()
+	stats.Restarts = s.restarts.Load()
+	return stats
+}
@@ -0,0 +1,245 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// dyingSource dies shortly after each of its first failures starts, then
+// stays up until stopped
+type dyingSource struct {
+	failures int
+	startErr error // returned by Start after the first one, when set
+
+	mu     sync.Mutex
+	starts int
+	done   chan struct{}
+	err    error
+	stop   chan struct{}
+}
+
+func (d *dyingSource) Start(ctx context.Context, out chan<- *models.LogEntry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.starts++
+	if d.starts > 1 && d.startErr != nil {
+		return d.startErr
+	}
+
+	done, stop := make(chan struct{}), make(chan struct{})
+	d.done, d.stop, d.err = done, stop, nil
+
+	die := d.starts <= d.failures
+	go func() {
+		var err error
+		select {
+		case <-time.After(10 * time.Millisecond):
+			if die {
+				err = errors.New("listener died")
+			} else {
+				select {
+				case <-stop:
+				case <-ctx.Done():
+				}
+			}
+		case <-stop:
+		case <-ctx.Done():
+		}
+		d.mu.Lock()
+		d.err = err
+		d.mu.Unlock()
+		close(done)
+	}()
+	return nil
+}
+
+func (d *dyingSource) Stop() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		select {
+		case <-d.stop:
+		default:
+			close(d.stop)
+		}
+	}
+	return nil
+}
+
+func (d *dyingSource) Done() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.done
+}
+
+func (d *dyingSource) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+func (d *dyingSource) Name() string              { return "dying" }
+func (d *dyingSource) Stats() models.SourceStats { return models.SourceStats{EntriesProduced: 7} }
+
+func (d *dyingSource) startCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.starts
+}
+
+func TestSupervisor_RestartsUntilStable(t *testing.T) {
+	source := &dyingSource{failures: 2}
+	supervisor := NewSupervisor(source, SupervisorOptions{BaseDelay: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := supervisor.Start(ctx, make(chan *models.LogEntry)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for source.startCount() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 3 starts, got %d", source.startCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Once up, it stays up
+	time.Sleep(100 * time.Millisecond)
+	if got := source.startCount(); got != 3 {
+		t.Errorf("Expected the source to stay up after 3 starts, got %d", got)
+	}
+
+	stats := supervisor.Stats()
+	if stats.Restarts != 2 {
+		t.Errorf("Expected 2 restarts, got %d", stats.Restarts)
+	}
+	if stats.EntriesProduced != 7 {
+		t.Errorf("Expected the source's own stats, got %+v", stats)
+	}
+
+	if err := supervisor.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if supervisor.Err() != nil {
+		t.Errorf("Expected no error, got %v", supervisor.Err())
+	}
+}
+
+func TestSupervisor_GivesUp(t *testing.T) {
+	source := &dyingSource{failures: 100}
+	supervisor := NewSupervisor(source, SupervisorOptions{
+		MaxRestarts: 3,
+		Window:      time.Minute,
+		BaseDelay:   time.Millisecond,
+	})
+
+	if err := supervisor.Start(context.Background(), make(chan *models.LogEntry)); err != nil {
+		t.Fatal(err)
+	}
+	defer supervisor.Stop()
+
+	select {
+	case <-supervisor.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the supervisor to give up")
+	}
+
+	if supervisor.Err() == nil {
+		t.Error("Expected an error explaining why it gave up")
+	}
+	if got := source.startCount(); got != 4 {
+		t.Errorf("Expected the first start plus 3 restarts, got %d", got)
+	}
+	if got := supervisor.Stats().Restarts; got != 3 {
+		t.Errorf("Expected 3 restarts, got %d", got)
+	}
+}
+
+func TestSupervisor_NoRestartOnCancel(t *testing.T) {
+	source := &dyingSource{}
+	supervisor := NewSupervisor(source, SupervisorOptions{BaseDelay: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := supervisor.Start(ctx, make(chan *models.LogEntry)); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	select {
+	case <-supervisor.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected supervision to end on cancel")
+	}
+	if got := source.startCount(); got != 1 {
+		t.Errorf("Expected no restart after cancel, got %d starts", got)
+	}
+	supervisor.Stop()
+}
+
+func TestSupervisor_NoRestartOnStop(t *testing.T) {
+	source := &dyingSource{}
+	supervisor := NewSupervisor(source, SupervisorOptions{BaseDelay: time.Millisecond})
+
+	if err := supervisor.Start(context.Background(), make(chan *models.LogEntry)); err != nil {
+		t.Fatal(err)
+	}
+	if err := supervisor.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if got := source.startCount(); got != 1 {
+		t.Errorf("Expected no restart after Stop, got %d starts", got)
+	}
+}
+
+func TestSupervisor_UnsupportedStopsRestarts(t *testing.T) {
+	source := &dyingSource{
+		failures: 1,
+		startErr: &models.SourceError{Code: models.Unsupported, Err: errors.New("bad config")},
+	}
+	supervisor := NewSupervisor(source, SupervisorOptions{BaseDelay: time.Millisecond})
+
+	if err := supervisor.Start(context.Background(), make(chan *models.LogEntry)); err != nil {
+		t.Fatal(err)
+	}
+	defer supervisor.Stop()
+
+	select {
+	case <-supervisor.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the supervisor to give up")
+	}
+
+	var sourceErr *models.SourceError
+	if !errors.As(supervisor.Err(), &sourceErr) || sourceErr.Code != models.Unsupported {
+		t.Errorf("Expected the Unsupported error, got %v", supervisor.Err())
+	}
+	if got := source.startCount(); got != 2 {
+		t.Errorf("Expected a single restart attempt, got %d starts", got)
+	}
+}
+
+func TestSupervisor_PassesThroughUnsupervisedSources(t *testing.T) {
+	source := &fakeSource{name: "plain"}
+	supervisor := NewSupervisor(source, SupervisorOptions{})
+
+	if err := supervisor.Start(context.Background(), make(chan *models.LogEntry)); err != nil {
+		t.Fatal(err)
+	}
+	if err := supervisor.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if !source.started || !source.stopped {
+		t.Error("Expected the source to be started and stopped")
+	}
+}
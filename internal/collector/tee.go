@@ -0,0 +1,227 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// ErrTeeStopped is returned by writes to a stopped TeeSink
+var ErrTeeStopped = errors.New("tee stopped")
+
+// TeeOptions configures a TeeSink
+type TeeOptions struct {
+	// QueueSize is how many entries an inner sink may fall behind before
+	// Write waits for it
+	QueueSize int
+
+	// FailFast makes Write fail, without queueing the entry, once an inner
+	// sink failed a write since the last call. By default failures are only
+	// reported by Flush and the other sinks carry on.
+	FailFast bool
+}
+
+// TeeSink writes every entry to several sinks at once. Each inner sink has
+// its own queue and goroutine, so a slow or failing sink doesn't hold back
+// the others until its queue is full. Like other buffering sinks it accepts
+// an entry once it is queued.
+type TeeSink struct {
+	branches []*teeBranch
+	opts     TeeOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// mu is held for reading while queueing, stopped closes the queues
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// teeItem is a queued entry, or a flush request when flushed is set
+type teeItem struct {
+	entry   *models.LogEntry
+	flushed chan error
+}
+
+// teeBranch feeds one inner sink
+type teeBranch struct {
+	sink  Sink
+	queue chan teeItem
+
+	mu     sync.Mutex
+	failed int64 // write failures not yet reported
+	last   error // latest of them
+
+	failures atomic.Int64
+}
+
+// NewTeeSink creates a tee over the given sinks and starts feeding them
+func NewTeeSink(sinks []Sink, opts TeeOptions) *TeeSink {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &TeeSink{
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, sink := range sinks {
+		b := &teeBranch{sink: sink, queue: make(chan teeItem, opts.QueueSize)}
+		t.branches = append(t.branches, b)
+		t.wg.Add(1)
+		go t.feed(b)
+	}
+	return t
+}
+
+// feed writes a branch's queue to its sink until Stop closes it
+func (t *TeeSink) feed(b *teeBranch) {
+	defer t.wg.Done()
+	for item := range b.queue {
+		t.handle(b, item)
+	}
+}
+
+func (t *TeeSink) handle(b *teeBranch, item teeItem) {
+	if item.flushed != nil {
+		item.flushed <- b.sink.Flush()
+		return
+	}
+	if err := b.sink.Write(t.ctx, item.entry); err != nil {
+		b.failures.Add(1)
+		b.mu.Lock()
+		b.failed++
+		b.last = err
+		b.mu.Unlock()
+	}
+}
+
+// takeError returns the branch's unreported write failures, if any
+func (b *teeBranch) takeError() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failed == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%s: %d writes failed, last: %w", b.sink.Name(), b.failed, b.last)
+	b.failed, b.last = 0, nil
+	return err
+}
+
+// takeErrors joins every branch's unreported write failures
+func (t *TeeSink) takeErrors() error {
+	var errs []error
+	for _, b := range t.branches {
+		if err := b.takeError(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Write queues the entry for every inner sink, waiting for sinks whose
+// queue is full. With FailFast it first reports inner write failures.
+func (t *TeeSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	if t.opts.FailFast {
+		if err := t.takeErrors(); err != nil {
+			return err
+		}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.stopped {
+		return ErrTeeStopped
+	}
+
+	for _, b := range t.branches {
+		select {
+		case b.queue <- teeItem{entry: entry}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Flush waits for every inner sink to write what is queued for it and
+// flushes them all at once. It reports the write failures since the last
+// report along with any flush errors.
+func (t *TeeSink) Flush() error {
+	results := make([]chan error, len(t.branches))
+	t.mu.RLock()
+	for i, b := range t.branches {
+		results[i] = make(chan error, 1)
+		if t.stopped {
+			results[i] <- b.sink.Flush()
+		} else {
+			b.queue <- teeItem{flushed: results[i]}
+		}
+	}
+	t.mu.RUnlock()
+
+	var errs []error
+	for i, b := range t.branches {
+		if err := <-results[i]; err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush %s: %w", b.sink.Name(), err))
+		}
+	}
+	// Write failures are known once everything queued was written
+	return errors.Join(append([]error{t.takeErrors()}, errs...)...)
+}
+
+// Stop writes out the queued entries, then stops the inner sinks that hold
+// resources
+func (t *TeeSink) Stop() error {
+	t.mu.Lock()
+	if !t.stopped {
+		t.stopped = true
+		for _, b := range t.branches {
+			close(b.queue)
+		}
+	}
+	t.mu.Unlock()
+	t.wg.Wait()
+	t.cancel()
+
+	var errs []error
+	for _, b := range t.branches {
+		if stopper, ok := b.sink.(interface{ Stop() error }); ok {
+			if err := stopper.Stop(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to stop %s: %w", b.sink.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Name returns the sink identifier
+func (t *TeeSink) Name() string {
+	return "tee"
+}
+
+// Sinks returns the inner sinks
+func (t *TeeSink) Sinks() []Sink {
+	sinks := make([]Sink, len(t.branches))
+	for i, b := range t.branches {
+		sinks[i] = b.sink
+	}
+	return sinks
+}
+
+// Failures returns how many writes each inner sink failed, by sink name
+func (t *TeeSink) Failures() map[string]int64 {
+	failures := make(map[string]int64, len(t.branches))
+	for _, b := range t.branches {
+		failures[b.sink.Name()] += b.failures.Load()
+	}
+	return failures
+}
@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// gatedSink holds every write until gate is closed
+type gatedSink struct {
+	gate chan struct{}
+}
+
+func (s *gatedSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	<-s.gate
+	return nil
+}
+
+func (s *gatedSink) Flush() error { return nil }
+func (s *gatedSink) Name() string { return "gated" }
+
+func teeWrite(t *testing.T, tee *TeeSink, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		entry := models.NewLogEntry()
+		entry.Message = fmt.Sprintf("entry %d", i)
+		if err := tee.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTeeSink_FailingSinkIsIsolated(t *testing.T) {
+	failing := &flakySink{failures: 1 << 30, err: errors.New("disk full")}
+	recording := &recordingSink{}
+	tee := NewTeeSink([]Sink{failing, recording}, TeeOptions{})
+	defer tee.Stop()
+
+	teeWrite(t, tee, 50)
+
+	err := tee.Flush()
+	if err == nil || !strings.Contains(err.Error(), "flaky: 50 writes failed, last: disk full") {
+		t.Errorf("Expected the failures reported by Flush, got %v", err)
+	}
+	messages := recording.messages()
+	if len(messages) != 50 || messages[0] != "entry 0" || messages[49] != "entry 49" {
+		t.Errorf("Expected every entry written to the healthy sink in order, got %d", len(messages))
+	}
+	if failures := tee.Failures(); failures["flaky"] != 50 || failures["recording"] != 0 {
+		t.Errorf("Expected 50 failures for flaky only, got %v", failures)
+	}
+
+	// Failures are reported once
+	if err := tee.Flush(); err != nil {
+		t.Errorf("Expected nothing new to report, got %v", err)
+	}
+}
+
+func TestTeeSink_SlowSinkDoesNotHoldOthers(t *testing.T) {
+	gated := &gatedSink{gate: make(chan struct{})}
+	recording := &recordingSink{}
+	tee := NewTeeSink([]Sink{gated, recording}, TeeOptions{QueueSize: 10})
+
+	done := make(chan struct{})
+	go func() {
+		teeWrite(t, tee, 8)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected writes to be queued while the slow sink waits")
+	}
+	waitForDelivered(t, recording, 8)
+
+	close(gated.gate)
+	if err := tee.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tee.Write(context.Background(), models.NewLogEntry()); !errors.Is(err, ErrTeeStopped) {
+		t.Errorf("Expected ErrTeeStopped, got %v", err)
+	}
+}
+
+func TestTeeSink_FullQueueWaits(t *testing.T) {
+	gated := &gatedSink{gate: make(chan struct{})}
+	tee := NewTeeSink([]Sink{gated}, TeeOptions{QueueSize: 2})
+	defer func() {
+		close(gated.gate)
+		tee.Stop()
+	}()
+
+	// One entry is being written, two are queued
+	teeWrite(t, tee, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tee.Write(ctx, models.NewLogEntry()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the write to wait for the full queue, got %v", err)
+	}
+}
+
+func TestTeeSink_FailFast(t *testing.T) {
+	failing := &flakySink{failures: 1 << 30, err: errors.New("disk full")}
+	tee := NewTeeSink([]Sink{failing, &recordingSink{}}, TeeOptions{FailFast: true})
+	defer tee.Stop()
+
+	teeWrite(t, tee, 1)
+	deadline := time.Now().Add(time.Second)
+	for tee.Failures()["flaky"] == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := tee.Write(context.Background(), models.NewLogEntry()); err == nil {
+		t.Error("Expected the next write to fail")
+	}
+	if attempts, _ := failing.counts(); attempts != 1 {
+		t.Errorf("Expected the failed write not queued, got %d attempts", attempts)
+	}
+}
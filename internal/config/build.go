@@ -0,0 +1,480 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+	"github.com/fatihserhatturan/logflux/internal/pipeline"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Components holds the objects built from a Config
+type Components struct {
+	Sources    []collector.Source
+	Sinks      []collector.Sink
+	Predicates []pipeline.Predicate
+	Level      *pipeline.LevelFilter       // nil without min_level, also in Predicates
+	Redactor   *pipeline.Redactor          // nil when nothing is redacted
+	Deduper    *pipeline.Deduper           // nil when dedup is off
+	Multiline  *pipeline.MultilineCombiner // nil when lines aren't stitched
+	Templates  *pipeline.TemplateMiner     // nil when templates aren't mined
+	Sampler    *pipeline.Sampler           // nil when nothing is sampled
+	Enricher   *pipeline.Enricher          // nil when no fields are added
+	Coercer    *pipeline.Coercer           // nil without a field schema
+	Flattener  *pipeline.Flattener         // nil when fields keep their shape
+	Parser     *pipeline.ParsePool         // nil when every source parses inline
+	Levels     *pipeline.LevelInferrer     // nil when levels aren't guessed
+	Truncator  *pipeline.Truncator         // nil when messages keep their length
+	Alerters   []*pipeline.Alerter
+
+	// DeadLetter takes what sources and the parse pool fail to parse, nil
+	// when it's passed on raw. It is stopped once they are done.
+	DeadLetter *collector.DeadLetter
+
+	// SinkChains holds each configured sink in order, wrapped in its retry
+	// and queue but not routed, for a reload to reuse the unchanged ones
+	SinkChains []collector.Sink
+
+	// RateLimits maps source names to their rate limit
+	RateLimits map[string]collector.RateLimit
+}
+
+// Build constructs the sources, sinks and filters described by the config.
+// A config without sinks gets a stdout sink. When anything fails to build,
+// the sinks built before it are stopped.
+func (c *Config) Build() (_ *Components, err error) {
+	components := &Components{}
+	defer func() {
+		if err != nil {
+			components.release()
+		}
+	}()
+
+	if c.DeadLetter != nil {
+		sink, err := buildSink("dead_letter", *c.DeadLetter)
+		if err != nil {
+			return nil, err
+		}
+		components.DeadLetter = collector.NewDeadLetter(sink)
+	}
+
+	for i, src := range c.Sources {
+		key := fmt.Sprintf("sources[%d]", i)
+		source, err := buildSource(key, src)
+		if err != nil {
+			return nil, err
+		}
+		if components.DeadLetter != nil {
+			if dl, ok := source.(interface{ SetDeadLetter(sources.DeadLetter) }); ok {
+				dl.SetDeadLetter(components.DeadLetter)
+			}
+		}
+		if src.SourceName != "" || len(src.Labels) > 0 {
+			labeled, ok := source.(interface{ SetLabels(sources.SourceLabels) })
+			if !ok {
+				return nil, fmt.Errorf("%s: source_name and labels are not supported on %s sources", key, src.Type)
+			}
+			labeled.SetLabels(sources.SourceLabels{SourceName: src.SourceName, Labels: src.Labels})
+		}
+		if src.Parse == ParsePool {
+			pooled, ok := source.(interface{ SetParsePool(bool) error })
+			if !ok {
+				return nil, fmt.Errorf("%s.parse: pool is not supported on %s sources", key, src.Type)
+			}
+			if err := pooled.SetParsePool(true); err != nil {
+				return nil, fmt.Errorf("%s.parse: %w", key, err)
+			}
+		}
+		if src.Restart != nil {
+			opts, err := src.Restart.options()
+			if err != nil {
+				return nil, fmt.Errorf("%s.restart.%w", key, err)
+			}
+			source = collector.NewSupervisor(source, opts)
+		}
+		components.Sources = append(components.Sources, source)
+
+		if src.RateLimit != nil {
+			if components.RateLimits == nil {
+				components.RateLimits = make(map[string]collector.RateLimit)
+			}
+			components.RateLimits[source.Name()] = src.RateLimit.limit()
+		}
+	}
+
+	if err := c.buildSinks(components); err != nil {
+		return nil, err
+	}
+	if err := c.buildStages(components); err != nil {
+		return nil, err
+	}
+
+	if c.Filters.Dedup != nil {
+		opts, err := c.Filters.Dedup.options()
+		if err != nil {
+			return nil, fmt.Errorf("filters.dedup.%w", err)
+		}
+		components.Deduper = pipeline.NewDeduper(opts)
+	}
+
+	if c.Filters.Multiline != nil {
+		combiner, err := c.Filters.Multiline.build()
+		if err != nil {
+			return nil, fmt.Errorf("filters.multiline: %w", err)
+		}
+		components.Multiline = combiner
+	}
+
+	if c.Filters.Templates != nil {
+		opts, err := c.Filters.Templates.options()
+		if err != nil {
+			return nil, fmt.Errorf("filters.templates.%w", err)
+		}
+		components.Templates = pipeline.NewTemplateMiner(opts)
+	}
+
+	if c.Filters.Sample != nil {
+		opts, err := c.Filters.Sample.options()
+		if err != nil {
+			return nil, fmt.Errorf("filters.sample.%w", err)
+		}
+		components.Sampler = pipeline.NewSampler(opts)
+	}
+
+	if c.Filters.Enrich != nil {
+		enricher, err := pipeline.NewEnricher(c.Filters.Enrich.options())
+		if err != nil {
+			return nil, fmt.Errorf("filters.enrich: %w", err)
+		}
+		components.Enricher = enricher
+	}
+
+	if c.Filters.Parse != nil {
+		pool, err := c.Filters.Parse.pool(components.DeadLetter)
+		if err != nil {
+			return nil, fmt.Errorf("filters.parse.%w", err)
+		}
+		components.Parser = pool
+	}
+
+	return components, nil
+}
+
+// release stops the sinks and dead letter built so far, which already hold
+// files, connections and flush goroutines, for when a later component
+// fails to build
+func (components *Components) release() {
+	stopBuilt(components.Sinks)
+	components.Sinks, components.SinkChains = nil, nil
+	if components.DeadLetter != nil {
+		components.DeadLetter.Stop()
+		components.DeadLetter = nil
+	}
+}
+
+// stopBuilt stops sinks that were built but never written to
+func stopBuilt(built []collector.Sink) {
+	for _, sink := range built {
+		if stopper, ok := sink.(interface{ Stop() error }); ok {
+			stopper.Stop()
+		}
+	}
+}
+
+// BuildStages builds only the filters applied to one entry at a time (the
+// minimum level, redaction, schema, flattening, level inference and
+// alerts), for a running collector to swap in. Sources, sinks and the
+// filters that run over the stream are left out.
+func (c *Config) BuildStages() (*Components, error) {
+	components := &Components{}
+	if err := c.buildStages(components); err != nil {
+		return nil, err
+	}
+	return components, nil
+}
+
+// buildSinks builds the sinks with their retry, queue and routing. When
+// one fails, those built before it are stopped.
+func (c *Config) buildSinks(components *Components) error {
+	for i, s := range c.Sinks {
+		sink, err := c.buildSinkChain(fmt.Sprintf("sinks[%d]", i), s)
+		if err != nil {
+			stopBuilt(components.SinkChains)
+			components.SinkChains = nil
+			return err
+		}
+		components.SinkChains = append(components.SinkChains, sink)
+	}
+	routed, err := c.routeSinks(components.SinkChains)
+	if err != nil {
+		stopBuilt(components.SinkChains)
+		components.SinkChains = nil
+		return err
+	}
+	components.Sinks = routed
+	return nil
+}
+
+// routeSinks puts the chains built for c.Sinks behind the router, if there
+// is one, giving what the pipeline writes to. Without sinks that is stdout.
+func (c *Config) routeSinks(chains []collector.Sink) ([]collector.Sink, error) {
+	routed := append([]collector.Sink(nil), chains...)
+	if c.Routing != nil {
+		named := make(map[string]collector.Sink)
+		for i, s := range c.Sinks {
+			if s.Name != "" {
+				named[s.Name] = chains[i]
+			}
+		}
+		router, err := c.Routing.build(named)
+		if err != nil {
+			return nil, err
+		}
+		routed = routedSinks(chains, router)
+	}
+	if len(routed) == 0 {
+		routed = append(routed, sinks.NewStdoutSink())
+	}
+	return routed, nil
+}
+
+// buildSinkChain builds one sink wrapped in its retrier and queue,
+// stopping what was built of it when a wrapper fails
+func (c *Config) buildSinkChain(key string, s ComponentConfig) (collector.Sink, error) {
+	sink, err := buildSink(key, s)
+	if err != nil {
+		return nil, err
+	}
+	c.setResourceFields(sink, s)
+	if s.Retry != nil {
+		retrier, err := buildRetrier(key, sink, s.Retry)
+		if err != nil {
+			stopBuilt([]collector.Sink{sink})
+			return nil, err
+		}
+		sink = retrier
+	}
+	if s.Queue != nil {
+		opts, err := s.Queue.options()
+		if err != nil {
+			stopBuilt([]collector.Sink{sink})
+			return nil, fmt.Errorf("%s.queue.%w", key, err)
+		}
+		queue, err := collector.NewDiskQueue(sink, opts)
+		if err != nil {
+			stopBuilt([]collector.Sink{sink})
+			return nil, fmt.Errorf("%s.queue: %w", key, err)
+		}
+		sink = queue
+	}
+	return sink, nil
+}
+
+// setResourceFields makes the fields filters.enrich adds the resource
+// attributes of a sink that has them, unless its resource_fields are set
+func (c *Config) setResourceFields(sink collector.Sink, s ComponentConfig) {
+	resource, ok := sink.(interface{ SetResourceFields([]string) })
+	if !ok || c.Filters.Enrich == nil {
+		return
+	}
+	if _, set := s.Params["resource_fields"]; set {
+		return
+	}
+	resource.SetResourceFields(c.Filters.Enrich.fieldNames())
+}
+
+// buildStages builds the filters applied to one entry at a time
+func (c *Config) buildStages(components *Components) error {
+	if c.Filters.MinLevel != "" {
+		level, err := models.ParseLevel(c.Filters.MinLevel)
+		if err != nil {
+			return fmt.Errorf("filters.min_level: %w", err)
+		}
+		components.Level = pipeline.NewLevelFilter(level)
+		components.Predicates = append(components.Predicates, components.Level.Predicate())
+	}
+
+	if c.Filters.Redact != nil {
+		redactor, err := pipeline.NewRedactor(c.Filters.Redact.options())
+		if err != nil {
+			return fmt.Errorf("filters.redact: %w", err)
+		}
+		components.Redactor = redactor
+	}
+
+	if c.Filters.Schema != nil {
+		schema, err := c.Filters.Schema.schema()
+		if err != nil {
+			return fmt.Errorf("filters.schema.%w", err)
+		}
+		coercer, err := pipeline.NewCoercer(schema)
+		if err != nil {
+			return fmt.Errorf("filters.schema.%w", err)
+		}
+		components.Coercer = coercer
+	}
+
+	if c.Filters.Truncate != nil {
+		truncator, err := pipeline.NewTruncator(c.Filters.Truncate.options())
+		if err != nil {
+			return fmt.Errorf("filters.truncate.%w", err)
+		}
+		components.Truncator = truncator
+	}
+
+	if c.Filters.Flatten != nil {
+		opts, err := c.Filters.Flatten.options()
+		if err != nil {
+			return fmt.Errorf("filters.flatten.%w", err)
+		}
+		components.Flattener = pipeline.NewFlattener(opts)
+	}
+
+	if c.Filters.InferLevel != nil {
+		opts, err := c.Filters.InferLevel.options()
+		if err != nil {
+			return fmt.Errorf("filters.infer_level.%w", err)
+		}
+		components.Levels = pipeline.NewLevelInferrer(opts)
+	}
+
+	for i, alert := range c.Alerts {
+		opts, err := alert.options()
+		if err != nil {
+			return fmt.Errorf("alerts[%d].%w", i, err)
+		}
+		alerter, err := pipeline.NewAlerter(opts)
+		if err != nil {
+			return fmt.Errorf("alerts[%d]: %w", i, err)
+		}
+		components.Alerters = append(components.Alerters, alerter)
+	}
+	return nil
+}
+
+// buildSource builds a source with its type's registered factory
+func buildSource(key string, c ComponentConfig) (collector.Source, error) {
+	factory, _, ok := GetSource(c.Type)
+	if !ok {
+		return nil, fmt.Errorf("%s.type: unknown source type %q", key, c.Type)
+	}
+	return factory(Params{key: key, values: c.Params})
+}
+
+// buildRetrier wraps a sink with its retry policy and dead-letter sink
+func buildRetrier(key string, sink collector.Sink, c *RetryConfig) (collector.Sink, error) {
+	opts, err := c.options()
+	if err != nil {
+		return nil, fmt.Errorf("%s.retry.%w", key, err)
+	}
+	if c.DeadLetter != nil {
+		opts.DeadLetter, err = buildSink(key+".retry.dead_letter", *c.DeadLetter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return collector.NewRetrier(sink, opts), nil
+}
+
+// buildSink builds a sink with its type's registered factory
+func buildSink(key string, c ComponentConfig) (collector.Sink, error) {
+	factory, _, ok := GetSink(c.Type)
+	if !ok {
+		return nil, fmt.Errorf("%s.type: unknown sink type %q", key, c.Type)
+	}
+	return factory(Params{key: key, values: c.Params})
+}
+
+// build creates the router over the named sinks
+func (r *RoutingConfig) build(named map[string]collector.Sink) (*pipeline.Router, error) {
+	lookup := func(names []string) []collector.Sink {
+		sinks := make([]collector.Sink, 0, len(names))
+		for _, name := range names {
+			sinks = append(sinks, named[name])
+		}
+		return sinks
+	}
+
+	routes := make([]pipeline.Route, len(r.Routes))
+	for i, route := range r.Routes {
+		predicates, err := route.predicates()
+		if err != nil {
+			return nil, fmt.Errorf("routing.routes[%d].%w", i, err)
+		}
+		routes[i] = pipeline.Route{Name: route.Name, Match: predicates, Sinks: lookup(route.Sinks)}
+	}
+
+	return pipeline.NewRouter(routes, pipeline.RouterOptions{
+		FirstMatch: strings.EqualFold(r.Mode, "first"),
+		Default:    lookup(r.Default),
+	}), nil
+}
+
+// routedSinks replaces the sinks the router writes to with the router,
+// keeping the ones it doesn't in place
+func routedSinks(all []collector.Sink, router *pipeline.Router) []collector.Sink {
+	routed := make(map[collector.Sink]bool)
+	for _, sink := range router.Sinks() {
+		routed[sink] = true
+	}
+
+	result := []collector.Sink{router}
+	for _, sink := range all {
+		if !routed[sink] {
+			result = append(result, sink)
+		}
+	}
+	return result
+}
+
+// allowlist returns an optional list of CIDRs as a receiver allowlist
+func (p Params) allowlist(name string) (*sources.Allowlist, error) {
+	cidrs, err := p.StringsOr(name, nil)
+	if err != nil {
+		return nil, err
+	}
+	allowlist, err := sources.ParseAllowlist(cidrs)
+	if err != nil {
+		return nil, p.Errorf(name, "%v", err)
+	}
+	return allowlist, nil
+}
+
+// polling reads how often a file source checks its files for new data
+func (p Params) polling() (sources.PollOptions, error) {
+	period, err := p.DurationOr("poll_period", 0)
+	if err != nil {
+		return sources.PollOptions{}, err
+	}
+	adaptive, err := p.BoolOr("adaptive_poll", false)
+	if err != nil {
+		return sources.PollOptions{}, err
+	}
+	max, err := p.DurationOr("max_poll_period", 0)
+	if err != nil {
+		return sources.PollOptions{}, err
+	}
+	if max > 0 && period > max {
+		return sources.PollOptions{}, p.Errorf("max_poll_period", "must not be below poll_period %s", period)
+	}
+	return sources.PollOptions{Period: period, Adaptive: adaptive, MaxPeriod: max}, nil
+}
+
+// compression reads the codec a sink compresses its output with. A sink
+// whose receiver only decodes some codecs names them in supported.
+func (p Params) compression(supported ...string) (string, error) {
+	codec, err := p.StringOr("compression", sinks.CompressionNone)
+	if err != nil {
+		return "", err
+	}
+	if err := sinks.CheckCompression(codec); err != nil {
+		return "", p.Errorf("compression", "%v", err)
+	}
+	if len(supported) > 0 && codec != sinks.CompressionNone && !contains(supported, codec) {
+		return "", p.Errorf("compression", "only %s is supported, got %q", strings.Join(supported, " or "), codec)
+	}
+	return codec, nil
+}
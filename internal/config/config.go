@@ -0,0 +1,865 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+	"github.com/fatihserhatturan/logflux/internal/pipeline"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Config describes a collector setup: where logs come from, which filters
+// apply, and where they are shipped to
+type Config struct {
+	Sources []ComponentConfig `yaml:"sources"`
+	Sinks   []ComponentConfig `yaml:"sinks"`
+	Filters FilterConfig      `yaml:"filters"`
+	Routing *RoutingConfig    `yaml:"routing"`
+	Alerts  []AlertConfig     `yaml:"alerts"`
+
+	// DeadLetter takes the input parsers fail on instead of it being
+	// passed on raw
+	DeadLetter *ComponentConfig `yaml:"dead_letter"`
+}
+
+// ComponentConfig describes a single source or sink
+type ComponentConfig struct {
+	Name      string                 `yaml:"name"` // sinks only, for routing
+	Type      string                 `yaml:"type"`
+	Params    map[string]interface{} `yaml:"params"`
+	RateLimit *RateLimitConfig       `yaml:"rate_limit"` // sources only
+	Retry     *RetryConfig           `yaml:"retry"`      // sinks only
+	Queue     *QueueConfig           `yaml:"queue"`      // sinks only
+	Restart   *RestartConfig         `yaml:"restart"`    // sources only
+
+	// Parse is "pool" to leave parsing the raw lines a source reads to
+	// filters.parse. Sources only.
+	Parse string `yaml:"parse"`
+
+	// SourceName replaces the file path or transport entries carry as
+	// their source, and Labels are added to their fields. Sources only.
+	SourceName string            `yaml:"source_name"`
+	Labels     map[string]string `yaml:"labels"`
+}
+
+// RoutingConfig sends entries to the named sinks of the routes they match.
+// Sinks no route or default names keep receiving every entry.
+type RoutingConfig struct {
+	Mode    string        `yaml:"mode"` // "all" (default) or "first"
+	Routes  []RouteConfig `yaml:"routes"`
+	Default []string      `yaml:"default"`
+}
+
+// RouteConfig sends the entries matching its conditions to its sinks
+type RouteConfig struct {
+	Name        string `yaml:"name"`
+	MatchConfig `yaml:",inline"`
+	Sinks       []string `yaml:"sinks"`
+}
+
+// MatchConfig selects entries on every condition given, for routes and
+// alerts
+type MatchConfig struct {
+	MinLevel string            `yaml:"min_level"`
+	Source   string            `yaml:"source"` // regexp
+	Fields   map[string]string `yaml:"fields"`
+}
+
+// predicates converts the conditions into pipeline predicates
+func (m *MatchConfig) predicates() ([]pipeline.Predicate, error) {
+	var predicates []pipeline.Predicate
+	if m.MinLevel != "" {
+		level, err := models.ParseLevel(m.MinLevel)
+		if err != nil {
+			return nil, fmt.Errorf("min_level: %w", err)
+		}
+		predicates = append(predicates, pipeline.FilterMinLevel(level))
+	}
+	if m.Source != "" {
+		pattern, err := regexp.Compile(m.Source)
+		if err != nil {
+			return nil, fmt.Errorf("source: %w", err)
+		}
+		predicates = append(predicates, pipeline.FilterSource(pattern))
+	}
+	for name, value := range m.Fields {
+		predicates = append(predicates, pipeline.FilterField(name, value))
+	}
+	return predicates, nil
+}
+
+// AlertConfig calls a webhook when the entries matching its conditions
+// reach a threshold within a window
+type AlertConfig struct {
+	Name        string `yaml:"name"`
+	MatchConfig `yaml:",inline"`
+	Threshold   int    `yaml:"threshold"`
+	Window      string `yaml:"window"`
+	Cooldown    string `yaml:"cooldown"`
+	Samples     int    `yaml:"samples"`
+	Webhook     string `yaml:"webhook"`
+}
+
+// options converts the config into pipeline.AlerterOptions
+func (a *AlertConfig) options() (pipeline.AlerterOptions, error) {
+	opts := pipeline.AlerterOptions{Name: a.Name, Threshold: a.Threshold, MaxSamples: a.Samples}
+	if a.Threshold <= 0 {
+		return opts, fmt.Errorf("threshold: must be positive")
+	}
+	if a.Samples < 0 {
+		return opts, fmt.Errorf("samples: must not be negative")
+	}
+	if a.Webhook == "" {
+		return opts, fmt.Errorf("webhook: required")
+	}
+	if u, err := url.Parse(a.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return opts, fmt.Errorf("webhook: invalid URL %q", a.Webhook)
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"window", a.Window, &opts.Window},
+		{"cooldown", a.Cooldown, &opts.Cooldown},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return opts, fmt.Errorf("%s: invalid duration %q", d.name, d.value)
+		}
+		*d.dest = parsed
+	}
+
+	predicates, err := a.predicates()
+	if err != nil {
+		return opts, err
+	}
+	opts.Match = predicates
+	opts.Action = pipeline.NewWebhookAction(a.Webhook, nil)
+	return opts, nil
+}
+
+// validate checks the routes' conditions and that every sink they name
+// exists
+func (r *RoutingConfig) validate(sinkNames map[string]bool) error {
+	switch strings.ToLower(r.Mode) {
+	case "", "all", "first":
+	default:
+		return fmt.Errorf("routing.mode: unsupported mode %q (expected all or first)", r.Mode)
+	}
+	if len(r.Routes) == 0 {
+		return fmt.Errorf("routing.routes: at least one route is required")
+	}
+
+	checkSinks := func(key string, names []string) error {
+		for _, name := range names {
+			if !sinkNames[name] {
+				return fmt.Errorf("%s: unknown sink %q", key, name)
+			}
+		}
+		return nil
+	}
+	for i, route := range r.Routes {
+		key := fmt.Sprintf("routing.routes[%d]", i)
+		if _, err := route.predicates(); err != nil {
+			return fmt.Errorf("%s.%w", key, err)
+		}
+		if len(route.Sinks) == 0 {
+			return fmt.Errorf("%s.sinks: at least one sink is required", key)
+		}
+		if err := checkSinks(key+".sinks", route.Sinks); err != nil {
+			return err
+		}
+	}
+	return checkSinks("routing.default", r.Default)
+}
+
+// RestartConfig describes how a failed source is restarted
+type RestartConfig struct {
+	MaxRestarts int    `yaml:"max_restarts"`
+	Window      string `yaml:"window"`
+	BaseDelay   string `yaml:"base_delay"`
+	MaxDelay    string `yaml:"max_delay"`
+}
+
+// options converts the config into collector.SupervisorOptions
+func (r *RestartConfig) options() (collector.SupervisorOptions, error) {
+	opts := collector.SupervisorOptions{MaxRestarts: r.MaxRestarts}
+	if r.MaxRestarts < 0 {
+		return opts, fmt.Errorf("max_restarts: must not be negative")
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"window", r.Window, &opts.Window},
+		{"base_delay", r.BaseDelay, &opts.BaseDelay},
+		{"max_delay", r.MaxDelay, &opts.MaxDelay},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return opts, fmt.Errorf("%s: invalid duration %q", d.name, d.value)
+		}
+		*d.dest = parsed
+	}
+	return opts, nil
+}
+
+// QueueConfig describes the disk queue buffering a sink
+type QueueConfig struct {
+	Dir         string `yaml:"dir"`
+	SegmentSize int64  `yaml:"segment_size"`
+	MaxSize     int64  `yaml:"max_size"`
+	RetryDelay  string `yaml:"retry_delay"`
+	BatchSize   int    `yaml:"batch_size"`
+}
+
+// options converts the config into collector.DiskQueueOptions
+func (q *QueueConfig) options() (collector.DiskQueueOptions, error) {
+	opts := collector.DiskQueueOptions{Dir: q.Dir, SegmentSize: q.SegmentSize, MaxSize: q.MaxSize, BatchSize: q.BatchSize}
+	if q.Dir == "" {
+		return opts, fmt.Errorf("dir: required")
+	}
+	if q.SegmentSize < 0 || q.MaxSize < 0 {
+		return opts, fmt.Errorf("segment_size, max_size: must not be negative")
+	}
+	if q.BatchSize < 0 {
+		return opts, fmt.Errorf("batch_size: must not be negative")
+	}
+	if q.RetryDelay != "" {
+		delay, err := time.ParseDuration(q.RetryDelay)
+		if err != nil {
+			return opts, fmt.Errorf("retry_delay: invalid duration %q", q.RetryDelay)
+		}
+		opts.RetryDelay = delay
+	}
+	return opts, nil
+}
+
+// RetryConfig describes how a sink's failed writes are retried
+type RetryConfig struct {
+	MaxAttempts int              `yaml:"max_attempts"`
+	BaseDelay   string           `yaml:"base_delay"`
+	MaxDelay    string           `yaml:"max_delay"`
+	Jitter      float64          `yaml:"jitter"`
+	DeadLetter  *ComponentConfig `yaml:"dead_letter"`
+}
+
+// options converts the config into collector.RetryOptions, without the
+// dead-letter sink
+func (r *RetryConfig) options() (collector.RetryOptions, error) {
+	opts := collector.RetryOptions{MaxAttempts: r.MaxAttempts, Jitter: r.Jitter}
+	if r.MaxAttempts < 0 {
+		return opts, fmt.Errorf("max_attempts: must not be negative")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return opts, fmt.Errorf("jitter: must be between 0 and 1, got %v", r.Jitter)
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"base_delay", r.BaseDelay, &opts.BaseDelay},
+		{"max_delay", r.MaxDelay, &opts.MaxDelay},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return opts, fmt.Errorf("%s: invalid duration %q", d.name, d.value)
+		}
+		*d.dest = parsed
+	}
+	return opts, nil
+}
+
+// validate checks the retry settings and the dead-letter sink
+func (r *RetryConfig) validate(key string) error {
+	if _, err := r.options(); err != nil {
+		return fmt.Errorf("%s.retry.%w", key, err)
+	}
+	if r.DeadLetter != nil {
+		return validateDeadLetter(key+".retry.dead_letter", *r.DeadLetter)
+	}
+	return nil
+}
+
+// validateDeadLetter checks a dead-letter sink, which is a plain sink
+func validateDeadLetter(key string, c ComponentConfig) error {
+	if err := validateComponent(key, "sink", c, sinkSpec); err != nil {
+		return err
+	}
+	if c.RateLimit != nil || c.Retry != nil || c.Queue != nil || c.Restart != nil {
+		return fmt.Errorf("%s: rate_limit, retry, queue and restart are not supported on dead letter sinks", key)
+	}
+	return nil
+}
+
+// RateLimitConfig limits how many entries per second a source may emit. In
+// drop mode the HTTP receiver answers 429 for entries over the limit; other
+// sources have already taken them from their clients, so they are dropped
+// and counted in logflux_entries_rate_limited_total.
+type RateLimitConfig struct {
+	PerSecond float64 `yaml:"per_second"`
+	Burst     int     `yaml:"burst"`
+	Mode      string  `yaml:"mode"` // "drop" (default) or "block"
+}
+
+// limit converts the config into a collector.RateLimit
+func (r *RateLimitConfig) limit() collector.RateLimit {
+	return collector.RateLimit{
+		PerSecond: r.PerSecond,
+		Burst:     r.Burst,
+		Mode:      collector.RateLimitMode(strings.ToLower(r.Mode)),
+	}
+}
+
+// validate checks the rate limit settings
+func (r *RateLimitConfig) validate(key string) error {
+	if r.PerSecond <= 0 {
+		return fmt.Errorf("%s.rate_limit.per_second: must be positive", key)
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("%s.rate_limit.burst: must not be negative", key)
+	}
+	switch collector.RateLimitMode(strings.ToLower(r.Mode)) {
+	case "", collector.RateLimitDrop, collector.RateLimitBlock:
+		return nil
+	default:
+		return fmt.Errorf("%s.rate_limit.mode: unsupported mode %q (expected drop or block)", key, r.Mode)
+	}
+}
+
+// FilterConfig describes the filter rules applied between sources and sinks
+type FilterConfig struct {
+	MinLevel   string            `yaml:"min_level"`
+	Redact     *RedactConfig     `yaml:"redact"`
+	Dedup      *DedupConfig      `yaml:"dedup"`
+	Multiline  *MultilineConfig  `yaml:"multiline"`
+	Templates  *TemplatesConfig  `yaml:"templates"`
+	Sample     SampleConfig      `yaml:"sample"`
+	Enrich     *EnrichConfig     `yaml:"enrich"`
+	Schema     SchemaConfig      `yaml:"schema"`
+	Flatten    *FlattenConfig    `yaml:"flatten"`
+	Parse      *ParseConfig      `yaml:"parse"`
+	InferLevel *InferLevelConfig `yaml:"infer_level"`
+	Truncate   *TruncateConfig   `yaml:"truncate"`
+}
+
+// InferLevelConfig describes guessing entries' levels from keywords in
+// their messages
+type InferLevelConfig struct {
+	Rules     []LevelRuleConfig `yaml:"rules"`     // in order, defaults to the built-in keywords
+	Overwrite bool              `yaml:"overwrite"` // also for entries not at INFO
+}
+
+// LevelRuleConfig maps a keyword to the level of messages containing it
+type LevelRuleConfig struct {
+	Keyword string `yaml:"keyword"`
+	Level   string `yaml:"level"`
+}
+
+// options converts the config into pipeline.LevelInferrerOptions
+func (l *InferLevelConfig) options() (pipeline.LevelInferrerOptions, error) {
+	opts := pipeline.LevelInferrerOptions{Overwrite: l.Overwrite}
+	for i, rule := range l.Rules {
+		if rule.Keyword == "" {
+			return opts, fmt.Errorf("rules[%d].keyword: required", i)
+		}
+		level, err := models.ParseLevel(rule.Level)
+		if err != nil {
+			return opts, fmt.Errorf("rules[%d].level: %w", i, err)
+		}
+		opts.Rules = append(opts.Rules, models.LevelRule{Keyword: rule.Keyword, Level: level})
+	}
+	return opts, nil
+}
+
+// ParsePool is the parse setting of sources whose lines filters.parse
+// parses
+const ParsePool = "pool"
+
+// ParseConfig describes parsing entries that sources with parse: pool read
+// raw across a pool of workers
+type ParseConfig struct {
+	Format  string `yaml:"format"`  // "json", "logfmt" or "syslog"
+	Workers int    `yaml:"workers"` // defaults to the number of CPUs
+	Ordered bool   `yaml:"ordered"`
+}
+
+// pool builds the pipeline.ParsePool, dead-lettering failures to dl if set
+func (p *ParseConfig) pool(dl *collector.DeadLetter) (*pipeline.ParsePool, error) {
+	var parser pipeline.Parser
+	switch strings.ToLower(p.Format) {
+	case sources.FormatJSON:
+		parser = sources.JSONParser{}
+	case sources.FormatLogfmt:
+		parser = sources.LogfmtParser{}
+	case "syslog":
+		parser = sources.SyslogParser{}
+	default:
+		return nil, fmt.Errorf("format: unsupported format %q (expected json, logfmt or syslog)", p.Format)
+	}
+	if p.Workers < 0 {
+		return nil, fmt.Errorf("workers: must not be negative, got %d", p.Workers)
+	}
+	return pipeline.NewParsePool(parser, pipeline.ParsePoolOptions{
+		Workers:    p.Workers,
+		Ordered:    p.Ordered,
+		DeadLetter: dl,
+		Marker:     sources.FieldUnparsed,
+	}), nil
+}
+
+// FlattenConfig describes how entry fields are reshaped for the sinks
+type FlattenConfig struct {
+	Mode      string `yaml:"mode"` // "flatten" (default) or "expand"
+	Separator string `yaml:"separator"`
+	Arrays    string `yaml:"arrays"` // "index" (default) or "keep"
+}
+
+// options converts the config into pipeline.FlattenerOptions
+func (f *FlattenConfig) options() (pipeline.FlattenerOptions, error) {
+	opts := pipeline.FlattenerOptions{}
+	switch strings.ToLower(f.Mode) {
+	case "", "flatten":
+	case "expand":
+		opts.Expand = true
+	default:
+		return opts, fmt.Errorf("mode: unsupported mode %q (expected flatten or expand)", f.Mode)
+	}
+
+	arrays, err := models.ParseArrayMode(f.Arrays)
+	if err != nil {
+		return opts, fmt.Errorf("arrays: %w", err)
+	}
+	opts.Separator = f.Separator
+	opts.Arrays = arrays
+	return opts, nil
+}
+
+// TruncateConfig describes cutting messages over a length
+type TruncateConfig struct {
+	MaxBytes int    `yaml:"max_bytes"`
+	Marker   string `yaml:"marker"` // %d is replaced by the bytes cut
+}
+
+// options converts the config into pipeline.TruncatorOptions
+func (t *TruncateConfig) options() pipeline.TruncatorOptions {
+	return pipeline.TruncatorOptions{MaxBytes: t.MaxBytes, Marker: t.Marker}
+}
+
+// SchemaConfig maps field names to the type their values are coerced to
+type SchemaConfig map[string]string
+
+// schema converts the config into the field types a pipeline.Coercer takes
+func (s SchemaConfig) schema() (map[string]pipeline.FieldType, error) {
+	schema := make(map[string]pipeline.FieldType, len(s))
+	for name, typeName := range s {
+		t, err := pipeline.ParseFieldType(typeName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		schema[name] = t
+	}
+	return schema, nil
+}
+
+// EnrichConfig describes the fields added to every entry
+type EnrichConfig struct {
+	Fields   map[string]string `yaml:"fields"`
+	Hostname bool              `yaml:"hostname"`
+}
+
+// options converts the config into pipeline.EnricherOptions
+func (e *EnrichConfig) options() pipeline.EnricherOptions {
+	return pipeline.EnricherOptions{
+		Fields:   e.Fields,
+		Hostname: e.Hostname,
+	}
+}
+
+// fieldNames returns the names of the fields added, sorted
+func (e *EnrichConfig) fieldNames() []string {
+	names := make([]string, 0, len(e.Fields)+1)
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	if e.Hostname {
+		names = append(names, pipeline.FieldHostname)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SampleConfig maps level names, or "default" for the rest, to the fraction
+// of entries kept
+type SampleConfig map[string]float64
+
+// options converts the config into pipeline.SamplerOptions
+func (s SampleConfig) options() (pipeline.SamplerOptions, error) {
+	opts := pipeline.SamplerOptions{Rates: make(map[models.LogLevel]float64)}
+	for name, rate := range s {
+		if !(rate >= 0 && rate <= 1) {
+			return opts, fmt.Errorf("%s: rate must be between 0 and 1, got %v", name, rate)
+		}
+		if strings.EqualFold(name, "default") {
+			rate := rate
+			opts.Default = &rate
+			continue
+		}
+		level, err := models.ParseLevel(name)
+		if err != nil {
+			return opts, fmt.Errorf("%s: %w", name, err)
+		}
+		opts.Rates[level] = rate
+	}
+	return opts, nil
+}
+
+// TemplatesConfig describes how messages are clustered into templates
+type TemplatesConfig struct {
+	Depth       int     `yaml:"depth"`
+	MaxChildren int     `yaml:"max_children"`
+	Similarity  float64 `yaml:"similarity"`
+	MaxClusters int     `yaml:"max_clusters"`
+}
+
+// options converts the config into pipeline.TemplateMinerOptions
+func (t *TemplatesConfig) options() (pipeline.TemplateMinerOptions, error) {
+	opts := pipeline.TemplateMinerOptions{
+		Depth:       t.Depth,
+		MaxChildren: t.MaxChildren,
+		Similarity:  t.Similarity,
+		MaxClusters: t.MaxClusters,
+	}
+	if t.Depth != 0 && t.Depth < 3 {
+		return opts, fmt.Errorf("depth: must be at least 3, got %d", t.Depth)
+	}
+	if t.Similarity < 0 || t.Similarity > 1 {
+		return opts, fmt.Errorf("similarity: must be between 0 and 1, got %v", t.Similarity)
+	}
+	return opts, nil
+}
+
+// MultilineConfig describes how lines are stitched into multiline entries
+type MultilineConfig struct {
+	StartPattern string `yaml:"start_pattern"`
+	MaxLines     int    `yaml:"max_lines"`
+	MaxWait      string `yaml:"max_wait"`
+}
+
+// build creates the combiner described by the config
+func (m *MultilineConfig) build() (*pipeline.MultilineCombiner, error) {
+	opts := pipeline.MultilineOptions{StartPattern: m.StartPattern, MaxLines: m.MaxLines}
+	if m.MaxWait != "" {
+		wait, err := time.ParseDuration(m.MaxWait)
+		if err != nil {
+			return nil, fmt.Errorf("max_wait: invalid duration %q", m.MaxWait)
+		}
+		opts.MaxWait = wait
+	}
+	return pipeline.NewMultilineCombiner(opts)
+}
+
+// DedupConfig describes how identical entries are collapsed
+type DedupConfig struct {
+	Window  string `yaml:"window"`
+	MaxKeys int    `yaml:"max_keys"`
+}
+
+// options converts the config into pipeline.DeduperOptions
+func (d *DedupConfig) options() (pipeline.DeduperOptions, error) {
+	opts := pipeline.DeduperOptions{MaxKeys: d.MaxKeys}
+	if d.Window != "" {
+		window, err := time.ParseDuration(d.Window)
+		if err != nil {
+			return opts, fmt.Errorf("window: invalid duration %q", d.Window)
+		}
+		opts.Window = window
+	}
+	return opts, nil
+}
+
+// RedactConfig describes what to scrub from entries before they are shipped
+type RedactConfig struct {
+	Fields   []string `yaml:"fields"`
+	Patterns []string `yaml:"patterns"`
+	Presets  []string `yaml:"presets"`
+}
+
+// options converts the config into pipeline.RedactorOptions
+func (r *RedactConfig) options() pipeline.RedactorOptions {
+	return pipeline.RedactorOptions{
+		Fields:   r.Fields,
+		Patterns: r.Patterns,
+		Presets:  r.Presets,
+	}
+}
+
+// Load reads and validates a YAML (or JSON) config file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes and validates config data. JSON is accepted as well since
+// it is a subset of YAML.
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks that every source and sink has the params its type requires
+func (c *Config) Validate() error {
+	if len(c.Sources) == 0 {
+		return fmt.Errorf("sources: at least one source is required")
+	}
+
+	if c.DeadLetter != nil {
+		if err := validateDeadLetter("dead_letter", *c.DeadLetter); err != nil {
+			return err
+		}
+	}
+
+	pooled := false
+	for i, src := range c.Sources {
+		key := fmt.Sprintf("sources[%d]", i)
+		if err := validateComponent(key, "source", src, sourceSpec); err != nil {
+			return err
+		}
+		if src.RateLimit != nil {
+			if err := src.RateLimit.validate(key); err != nil {
+				return err
+			}
+		}
+		if src.Retry != nil {
+			return fmt.Errorf("%s.retry: only supported on sinks", key)
+		}
+		if src.Queue != nil {
+			return fmt.Errorf("%s.queue: only supported on sinks", key)
+		}
+		if src.Restart != nil {
+			if _, err := src.Restart.options(); err != nil {
+				return fmt.Errorf("%s.restart.%w", key, err)
+			}
+		}
+		if src.Name != "" {
+			return fmt.Errorf("%s.name: only supported on sinks", key)
+		}
+		switch src.Parse {
+		case "":
+		case ParsePool:
+			if c.Filters.Parse == nil {
+				return fmt.Errorf("%s.parse: pool requires filters.parse", key)
+			}
+			pooled = true
+		default:
+			return fmt.Errorf("%s.parse: unsupported value %q (expected pool)", key, src.Parse)
+		}
+	}
+	if c.Filters.Parse != nil && !pooled {
+		return fmt.Errorf("filters.parse: no source has parse: pool")
+	}
+
+	sinkNames := make(map[string]bool)
+
+	for i, sink := range c.Sinks {
+		key := fmt.Sprintf("sinks[%d]", i)
+		if err := validateComponent(key, "sink", sink, sinkSpec); err != nil {
+			return err
+		}
+		if sink.RateLimit != nil {
+			return fmt.Errorf("%s.rate_limit: only supported on sources", key)
+		}
+		if sink.Restart != nil {
+			return fmt.Errorf("%s.restart: only supported on sources", key)
+		}
+		if sink.SourceName != "" || len(sink.Labels) > 0 {
+			return fmt.Errorf("%s: source_name and labels are only supported on sources", key)
+		}
+		if sink.Parse != "" {
+			return fmt.Errorf("%s.parse: only supported on sources", key)
+		}
+		if sink.Retry != nil {
+			if err := sink.Retry.validate(key); err != nil {
+				return err
+			}
+		}
+		if sink.Queue != nil {
+			if _, err := sink.Queue.options(); err != nil {
+				return fmt.Errorf("%s.queue.%w", key, err)
+			}
+		}
+		if sink.Name != "" {
+			if sinkNames[sink.Name] {
+				return fmt.Errorf("%s.name: duplicate sink name %q", key, sink.Name)
+			}
+			sinkNames[sink.Name] = true
+		}
+	}
+
+	if c.Routing != nil {
+		if err := c.Routing.validate(sinkNames); err != nil {
+			return err
+		}
+	}
+
+	for i, alert := range c.Alerts {
+		if _, err := alert.options(); err != nil {
+			return fmt.Errorf("alerts[%d].%w", i, err)
+		}
+	}
+
+	if c.Filters.MinLevel != "" {
+		if _, err := models.ParseLevel(c.Filters.MinLevel); err != nil {
+			return fmt.Errorf("filters.min_level: %w", err)
+		}
+	}
+
+	if c.Filters.Redact != nil {
+		if _, err := pipeline.NewRedactor(c.Filters.Redact.options()); err != nil {
+			return fmt.Errorf("filters.redact: %w", err)
+		}
+	}
+
+	if c.Filters.Dedup != nil {
+		if _, err := c.Filters.Dedup.options(); err != nil {
+			return fmt.Errorf("filters.dedup.%w", err)
+		}
+	}
+
+	if c.Filters.Multiline != nil {
+		if _, err := c.Filters.Multiline.build(); err != nil {
+			return fmt.Errorf("filters.multiline: %w", err)
+		}
+	}
+
+	if c.Filters.Templates != nil {
+		if _, err := c.Filters.Templates.options(); err != nil {
+			return fmt.Errorf("filters.templates.%w", err)
+		}
+	}
+
+	if c.Filters.Sample != nil {
+		if _, err := c.Filters.Sample.options(); err != nil {
+			return fmt.Errorf("filters.sample.%w", err)
+		}
+	}
+
+	if c.Filters.Enrich != nil {
+		if _, err := pipeline.NewEnricher(c.Filters.Enrich.options()); err != nil {
+			return fmt.Errorf("filters.enrich: %w", err)
+		}
+	}
+
+	if c.Filters.Schema != nil {
+		if _, err := c.Filters.Schema.schema(); err != nil {
+			return fmt.Errorf("filters.schema.%w", err)
+		}
+	}
+
+	if c.Filters.Flatten != nil {
+		if _, err := c.Filters.Flatten.options(); err != nil {
+			return fmt.Errorf("filters.flatten.%w", err)
+		}
+	}
+
+	if c.Filters.Parse != nil {
+		if _, err := c.Filters.Parse.pool(nil); err != nil {
+			return fmt.Errorf("filters.parse.%w", err)
+		}
+	}
+
+	if c.Filters.InferLevel != nil {
+		if _, err := c.Filters.InferLevel.options(); err != nil {
+			return fmt.Errorf("filters.infer_level.%w", err)
+		}
+	}
+
+	if c.Filters.Truncate != nil {
+		if _, err := pipeline.NewTruncator(c.Filters.Truncate.options()); err != nil {
+			return fmt.Errorf("filters.truncate.%w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateComponent checks the type is known and required params are present
+func validateComponent(key, kind string, c ComponentConfig, lookup func(string) (ParamSpec, bool)) error {
+	if c.Type == "" {
+		return fmt.Errorf("%s.type: required", key)
+	}
+
+	spec, ok := lookup(c.Type)
+	if !ok {
+		types := SourceTypes()
+		if kind == "sink" {
+			types = SinkTypes()
+		}
+		return fmt.Errorf("%s.type: unknown %s type %q (available in this build: %s)", key, kind, c.Type, strings.Join(types, ", "))
+	}
+
+	for _, name := range spec.Required {
+		value, ok := c.Params[name]
+		if !ok || value == nil || value == "" {
+			return fmt.Errorf("%s.params.%s: required for %s %s", key, name, c.Type, kind)
+		}
+	}
+
+	known := append(append([]string{}, spec.Required...), spec.Optional...)
+	for name := range c.Params {
+		if !contains(known, name) {
+			if kind == "source" && name == "keep_raw" {
+				return fmt.Errorf("%s.params.keep_raw: not supported on %s sources, whose records arrive structured rather than as raw text", key, c.Type)
+			}
+			return fmt.Errorf("%s.params.%s: unknown param for %s %s (expected one of: %s)",
+				key, name, c.Type, kind, strings.Join(known, ", "))
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1460 @@
+//go:build !no_sink_elasticsearch && !no_sink_file && !no_sink_kafka && !no_sink_loki && !no_sink_memory && !no_sink_otlp && !no_sink_stdout && !no_source_directory && !no_source_docker && !no_source_eventlog && !no_source_file && !no_source_gelf && !no_source_http && !no_source_journald && !no_source_otlp && !no_source_replay && !no_source_syslog
+
+// The configs tested here use every built-in backend, so builds leaving any
+// out skip them
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+	"github.com/fatihserhatturan/logflux/internal/pipeline"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestLoad_FileSourceAndFileSink(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	outPath := filepath.Join(dir, "out", "logs.jsonl")
+
+	content := `
+sources:
+  - type: file
+    params:
+      path: ` + logPath + `
+      format: json
+sinks:
+  - type: file
+    params:
+      path: ` + outPath + `
+      max_size: 1048576
+      flush_interval: 500ms
+filters:
+  min_level: WARNING
+`
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(components.Sources) != 1 {
+		t.Fatalf("Expected 1 source, got %d", len(components.Sources))
+	}
+	if _, ok := components.Sources[0].(*sources.FileReader); !ok {
+		t.Errorf("Expected *sources.FileReader, got %T", components.Sources[0])
+	}
+	if components.Sources[0].Name() != "file:"+logPath {
+		t.Errorf("Unexpected source name %q", components.Sources[0].Name())
+	}
+
+	if len(components.Sinks) != 1 {
+		t.Fatalf("Expected 1 sink, got %d", len(components.Sinks))
+	}
+	fileSink, ok := components.Sinks[0].(*sinks.FileSink)
+	if !ok {
+		t.Fatalf("Expected *sinks.FileSink, got %T", components.Sinks[0])
+	}
+	defer fileSink.Stop()
+	if fileSink.Name() != "file:"+outPath {
+		t.Errorf("Unexpected sink name %q", fileSink.Name())
+	}
+
+	if len(components.Predicates) != 1 {
+		t.Fatalf("Expected 1 predicate, got %d", len(components.Predicates))
+	}
+	info := models.NewLogEntry()
+	if components.Predicates[0](info) {
+		t.Error("INFO entry should be filtered at min_level WARNING")
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	cfg, err := Parse([]byte(`{"sources": [{"type": "syslog", "params": {"protocol": "udp", "address": ":5140"}}, {"type": "gelf", "params": {"address": ":12201"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := components.Sources[0].(*sources.SyslogReceiver); !ok {
+		t.Errorf("Expected *sources.SyslogReceiver, got %T", components.Sources[0])
+	}
+	if _, ok := components.Sources[1].(*sources.GELFReceiver); !ok {
+		t.Errorf("Expected *sources.GELFReceiver, got %T", components.Sources[1])
+	}
+	// No sinks configured defaults to stdout
+	if _, ok := components.Sinks[0].(*sinks.StdoutSink); !ok {
+		t.Errorf("Expected default *sinks.StdoutSink, got %T", components.Sinks[0])
+	}
+}
+
+func TestBuild_OTLPSource(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - type: otlp
+    params: {address: ':4318', send_timeout: 2s}
+    labels: {team: payments}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := components.Sources[0].(*sources.OTLPReceiver); !ok {
+		t.Errorf("Expected *sources.OTLPReceiver, got %T", components.Sources[0])
+	}
+}
+
+func TestBuild_Redact(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  redact:
+    fields: [password]
+    presets: [email]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Redactor == nil {
+		t.Fatal("Expected a redactor")
+	}
+
+	entry := models.NewLogEntry()
+	entry.Message = "mail alice@example.com"
+	entry.Fields["password"] = "secret"
+	components.Redactor.Apply(entry)
+
+	if entry.Message != "mail ***" {
+		t.Errorf("Expected email masked, got %q", entry.Message)
+	}
+	if _, ok := entry.Fields["password"]; ok {
+		t.Error("Expected password field dropped")
+	}
+}
+
+func TestBuild_RateLimit(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - type: http
+    params: {address: ':8080'}
+    rate_limit: {per_second: 500, burst: 1000, mode: Block}
+  - type: http
+    params: {address: ':8081'}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(components.RateLimits) != 1 {
+		t.Fatalf("Expected 1 rate limit, got %d", len(components.RateLimits))
+	}
+	limit, ok := components.RateLimits[components.Sources[0].Name()]
+	if !ok {
+		t.Fatalf("Expected rate limit keyed by %q", components.Sources[0].Name())
+	}
+	if limit.PerSecond != 500 || limit.Burst != 1000 || limit.Mode != collector.RateLimitBlock {
+		t.Errorf("Unexpected rate limit %+v", limit)
+	}
+}
+
+func TestBuild_Dedup(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  dedup: {window: 2s, max_keys: 100}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Deduper == nil {
+		t.Error("Expected a deduper")
+	}
+	if components.Multiline != nil {
+		t.Error("Expected no multiline combiner when none is configured")
+	}
+	if components.Templates != nil {
+		t.Error("Expected no template miner when none is configured")
+	}
+	if components.Redactor != nil {
+		t.Error("Expected no redactor when none is configured")
+	}
+}
+
+func TestParse_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		expectedKey string
+	}{
+		{
+			name:        "no sources",
+			config:      `sinks: [{type: stdout}]`,
+			expectedKey: "sources:",
+		},
+		{
+			name:        "missing type",
+			config:      `sources: [{params: {path: a.log}}]`,
+			expectedKey: "sources[0].type",
+		},
+		{
+			name:        "unknown source type",
+			config:      `sources: [{type: kafka}]`,
+			expectedKey: "sources[0].type",
+		},
+		{
+			name:        "missing file path",
+			config:      `sources: [{type: file}]`,
+			expectedKey: "sources[0].params.path",
+		},
+		{
+			name:        "missing syslog address",
+			config:      "sources:\n  - type: http\n    params: {address: ':8080'}\n  - type: syslog\n    params: {protocol: udp}",
+			expectedKey: "sources[1].params.address",
+		},
+		{
+			name:        "unknown param",
+			config:      `sources: [{type: http, params: {address: ':8080', port: 80}}]`,
+			expectedKey: "sources[0].params.port",
+		},
+		{
+			name:        "keep_raw on otlp",
+			config:      `sources: [{type: otlp, params: {address: ':4318', keep_raw: true}}]`,
+			expectedKey: "sources[0].params.keep_raw",
+		},
+		{
+			name:        "missing sink path",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: file}]}`,
+			expectedKey: "sinks[0].params.path",
+		},
+		{
+			name:        "missing elasticsearch url",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: elasticsearch}]}`,
+			expectedKey: "sinks[0].params.url",
+		},
+		{
+			name:        "bad level",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {min_level: LOUD}}`,
+			expectedKey: "filters.min_level",
+		},
+		{
+			name:        "bad redact preset",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {redact: {presets: [ssn]}}}`,
+			expectedKey: "filters.redact",
+		},
+		{
+			name:        "bad dedup window",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {dedup: {window: often}}}`,
+			expectedKey: "filters.dedup.window",
+		},
+		{
+			name:        "bad multiline pattern",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {multiline: {start_pattern: '('}}}`,
+			expectedKey: "filters.multiline",
+		},
+		{
+			name:        "bad template similarity",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {templates: {similarity: 1.5}}}`,
+			expectedKey: "filters.templates.similarity",
+		},
+		{
+			name:        "bad flatten mode",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {flatten: {mode: squash}}}`,
+			expectedKey: "filters.flatten.mode",
+		},
+		{
+			name:        "bad flatten arrays",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {flatten: {arrays: join}}}`,
+			expectedKey: "filters.flatten.arrays",
+		},
+		{
+			name:        "bad parse format",
+			config:      `{sources: [{type: file, params: {path: app.log}, parse: pool}], filters: {parse: {format: xml}}}`,
+			expectedKey: "filters.parse.format",
+		},
+		{
+			name:        "negative parse workers",
+			config:      `{sources: [{type: file, params: {path: app.log}, parse: pool}], filters: {parse: {format: json, workers: -1}}}`,
+			expectedKey: "filters.parse.workers",
+		},
+		{
+			name:        "parse pool without filters.parse",
+			config:      `sources: [{type: file, params: {path: app.log}, parse: pool}]`,
+			expectedKey: "sources[0].parse",
+		},
+		{
+			name:        "unknown parse setting",
+			config:      `{sources: [{type: file, params: {path: app.log}, parse: inline}], filters: {parse: {format: json}}}`,
+			expectedKey: "sources[0].parse",
+		},
+		{
+			name:        "filters.parse without a pooled source",
+			config:      `{sources: [{type: file, params: {path: app.log}}], filters: {parse: {format: json}}}`,
+			expectedKey: "filters.parse",
+		},
+		{
+			name:        "parse on sink",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, parse: pool}]}`,
+			expectedKey: "sinks[0].parse",
+		},
+		{
+			name:        "unknown inferred level",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {infer_level: {rules: [{keyword: oops, level: loud}]}}}`,
+			expectedKey: "filters.infer_level.rules[0].level",
+		},
+		{
+			name:        "empty level keyword",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {infer_level: {rules: [{level: error}]}}}`,
+			expectedKey: "filters.infer_level.rules[0].keyword",
+		},
+		{
+			name:        "truncate without max bytes",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {truncate: {marker: '...'}}}`,
+			expectedKey: "filters.truncate.max_bytes",
+		},
+		{
+			name:        "bad sample rate",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {sample: {DEBUG: 2}}}`,
+			expectedKey: "filters.sample.DEBUG",
+		},
+		{
+			name:        "negative default sample rate",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {sample: {default: -1}}}`,
+			expectedKey: "filters.sample.default",
+		},
+		{
+			name:        "route to unknown sink",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{name: store, type: stdout}], routing: {routes: [{min_level: error, sinks: [alerts]}]}}`,
+			expectedKey: "routing.routes[0].sinks",
+		},
+		{
+			name:        "bad route source",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{name: store, type: stdout}], routing: {routes: [{source: '[', sinks: [store]}]}}`,
+			expectedKey: "routing.routes[0].source",
+		},
+		{
+			name:        "bad routing mode",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{name: store, type: stdout}], routing: {mode: any, routes: [{sinks: [store]}]}}`,
+			expectedKey: "routing.mode",
+		},
+		{
+			name:        "duplicate sink name",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{name: store, type: stdout}, {name: store, type: memory}]}`,
+			expectedKey: "sinks[1].name",
+		},
+		{
+			name:        "bad schema type",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {schema: {status: integer}}}`,
+			expectedKey: "filters.schema.status",
+		},
+		{
+			name:        "bad sample level",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], filters: {sample: {LOUD: 0.5}}}`,
+			expectedKey: "filters.sample.LOUD",
+		},
+		{
+			name:        "alert without threshold",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], alerts: [{name: errors, webhook: 'http://hooks.local/alert'}]}`,
+			expectedKey: "alerts[0].threshold",
+		},
+		{
+			name:        "alert without webhook",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], alerts: [{name: errors, threshold: 10}]}`,
+			expectedKey: "alerts[0].webhook",
+		},
+		{
+			name:        "bad alert window",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], alerts: [{threshold: 10, window: soon, webhook: 'http://hooks.local/alert'}]}`,
+			expectedKey: "alerts[0].window",
+		},
+		{
+			name:        "bad alert level",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], alerts: [{threshold: 10, min_level: loud, webhook: 'http://hooks.local/alert'}]}`,
+			expectedKey: "alerts[0].min_level",
+		},
+		{
+			name:        "bad retry jitter",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, retry: {jitter: 2}}]}`,
+			expectedKey: "sinks[0].retry.jitter",
+		},
+		{
+			name:        "bad retry delay",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, retry: {base_delay: soon}}]}`,
+			expectedKey: "sinks[0].retry.base_delay",
+		},
+		{
+			name:        "bad dead letter",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, retry: {dead_letter: {type: file}}}]}`,
+			expectedKey: "sinks[0].retry.dead_letter.params.path",
+		},
+		{
+			name:        "top-level dead letter with retry",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], dead_letter: {type: stdout, retry: {max_attempts: 2}}}`,
+			expectedKey: "dead_letter",
+		},
+		{
+			name:        "labels on sink",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, labels: {app: web}}]}`,
+			expectedKey: "sinks[0]",
+		},
+		{
+			name:        "queue without dir",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, queue: {max_size: 1024}}]}`,
+			expectedKey: "sinks[0].queue.dir",
+		},
+		{
+			name:        "bad restart window",
+			config:      `sources: [{type: http, params: {address: ':8080'}, restart: {window: often}}]`,
+			expectedKey: "sources[0].restart.window",
+		},
+		{
+			name:        "restart on sink",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, restart: {max_restarts: 3}}]}`,
+			expectedKey: "sinks[0].restart",
+		},
+		{
+			name:        "bad rate limit",
+			config:      `sources: [{type: http, params: {address: ':8080'}, rate_limit: {per_second: 0}}]`,
+			expectedKey: "sources[0].rate_limit.per_second",
+		},
+		{
+			name:        "bad rate limit mode",
+			config:      `sources: [{type: http, params: {address: ':8080'}, rate_limit: {per_second: 10, mode: queue}}]`,
+			expectedKey: "sources[0].rate_limit.mode",
+		},
+		{
+			name:        "rate limit on sink",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, rate_limit: {per_second: 10}}]}`,
+			expectedKey: "sinks[0].rate_limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config))
+			if err == nil {
+				t.Fatal("Expected validation error")
+			}
+			if !strings.Contains(err.Error(), tt.expectedKey) {
+				t.Errorf("Expected error mentioning %q, got %q", tt.expectedKey, err)
+			}
+		})
+	}
+}
+
+func TestBuild_InvalidParamValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		expectedKey string
+	}{
+		{
+			name:        "bad format",
+			config:      `sources: [{type: file, params: {path: a.log, format: xml}}]`,
+			expectedKey: "sources[0].params.format",
+		},
+		{
+			name:        "regex without pattern",
+			config:      `sources: [{type: file, params: {path: a.log, format: regex}}]`,
+			expectedKey: "sources[0].params.pattern",
+		},
+		{
+			name:        "bad pattern",
+			config:      `sources: [{type: file, params: {path: a.log, format: regex, pattern: '(?P<level>['}}]`,
+			expectedKey: "sources[0].params.pattern",
+		},
+		{
+			name:        "csv without columns",
+			config:      `sources: [{type: file, params: {path: a.csv, format: csv}}]`,
+			expectedKey: "sources[0].params",
+		},
+		{
+			name:        "bad csv delimiter",
+			config:      `sources: [{type: file, params: {path: a.csv, format: csv, columns: [level, message], delimiter: '||'}}]`,
+			expectedKey: "sources[0].params.delimiter",
+		},
+		{
+			name:        "bad json timestamp layouts",
+			config:      `sources: [{type: file, params: {path: a.log, format: json, timestamp_layouts: 3}}]`,
+			expectedKey: "sources[0].params.timestamp_layouts",
+		},
+		{
+			name:        "bad directory pattern",
+			config:      `sources: [{type: directory, params: {pattern: '/var/log/[', format: raw}}]`,
+			expectedKey: "sources[0].params.pattern",
+		},
+		{
+			name:        "bad protocol",
+			config:      `sources: [{type: syslog, params: {protocol: sctp, address: ':514'}}]`,
+			expectedKey: "sources[0].params.protocol",
+		},
+		{
+			name:        "bad syslog allowlist",
+			config:      `sources: [{type: syslog, params: {protocol: udp, address: ':514', allow: [10.0.0.0/33]}}]`,
+			expectedKey: "sources[0].params.allow",
+		},
+		{
+			name:        "bad keep raw",
+			config:      `sources: [{type: file, params: {path: app.log, keep_raw: sometimes}}]`,
+			expectedKey: "sources[0].params.keep_raw",
+		},
+		{
+			name:        "parse pool on parsed lines",
+			config:      `{sources: [{type: file, params: {path: app.log, format: json}, parse: pool}], filters: {parse: {format: json}}}`,
+			expectedKey: "sources[0].parse",
+		},
+		{
+			name:        "parse pool on a receiver",
+			config:      `{sources: [{type: http, params: {address: ':8080'}, parse: pool}], filters: {parse: {format: json}}}`,
+			expectedKey: "sources[0].parse",
+		},
+		{
+			name:        "unknown source template placeholder",
+			config:      `sources: [{type: syslog, params: {protocol: udp, address: ':514', source_template: '{host}'}}]`,
+			expectedKey: "sources[0].params.source_template",
+		},
+		{
+			name:        "absolute partition",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: file, params: {path: logs, partition: /2006/01/02.jsonl}}]}`,
+			expectedKey: "sinks[0].params.partition",
+		},
+		{
+			name:        "unknown http route",
+			config:      `sources: [{type: http, params: {address: ':8080', routes: {ingest: /in}}}]`,
+			expectedKey: "sources[0].params.routes",
+		},
+		{
+			name:        "bad trusted proxy",
+			config:      `sources: [{type: http, params: {address: ':8080', allow: [10.0.0.0/8], trusted_proxies: [proxy]}}]`,
+			expectedKey: "sources[0].params.trusted_proxies",
+		},
+		{
+			name:        "bad replay speed",
+			config:      `sources: [{type: replay, params: {path: capture.jsonl, speed: 0}}]`,
+			expectedKey: "sources[0].params.speed",
+		},
+		{
+			name:        "bad ignore_timing",
+			config:      `sources: [{type: replay, params: {path: capture.jsonl, ignore_timing: sometimes}}]`,
+			expectedKey: "sources[0].params.ignore_timing",
+		},
+		{
+			name:        "bad stdout format",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, params: {format: xml}}]}`,
+			expectedKey: "sinks[0].params.format",
+		},
+		{
+			name:        "bad stdout color",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, params: {color: rainbow}}]}`,
+			expectedKey: "sinks[0].params.color",
+		},
+		{
+			name:        "bad stdout template",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: stdout, params: {template: '{{.Message'}}]}`,
+			expectedKey: "sinks[0].params.template",
+		},
+		{
+			name:        "bad file template",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: file, params: {path: out.log, template: '{{end}}'}}]}`,
+			expectedKey: "sinks[0].params.template",
+		},
+		{
+			name:        "bad duration",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: file, params: {path: out.jsonl, flush_interval: soon}}]}`,
+			expectedKey: "sinks[0].params.flush_interval",
+		},
+		{
+			name:        "bad batch size",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: elasticsearch, params: {url: 'http://localhost:9200', batch_size: many}}]}`,
+			expectedKey: "sinks[0].params.batch_size",
+		},
+		{
+			name:        "max poll period below poll period",
+			config:      `{sources: [{type: file, params: {path: /var/log/app.log, poll_period: 1s, adaptive_poll: true, max_poll_period: 500ms}}]}`,
+			expectedKey: "sources[0].params.max_poll_period",
+		},
+		{
+			name:        "unknown otlp protocol",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: otlp, params: {endpoint: 'localhost:4317', protocol: thrift}}]}`,
+			expectedKey: "sinks[0].params.protocol",
+		},
+		{
+			name:        "negative syslog idle timeout",
+			config:      `{sources: [{type: syslog, params: {protocol: tcp, address: ':5140', idle_timeout: -1s}}]}`,
+			expectedKey: "sources[0].params.idle_timeout",
+		},
+		{
+			name:        "unknown file compression",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: file, params: {path: out.jsonl, compression: lz4}}]}`,
+			expectedKey: "sinks[0].params.compression",
+		},
+		{
+			name:        "zstd otlp over grpc",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: otlp, params: {endpoint: 'localhost:4317', protocol: grpc, compression: zstd}}]}`,
+			expectedKey: "sinks[0].params.compression",
+		},
+		{
+			name:        "zstd loki",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: loki, params: {url: 'http://localhost:3100', compression: zstd}}]}`,
+			expectedKey: "sinks[0].params.compression",
+		},
+		{
+			name:        "snappy elasticsearch",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: elasticsearch, params: {url: 'http://localhost:9200', compression: snappy}}]}`,
+			expectedKey: "sinks[0].params.compression",
+		},
+		{
+			name:        "bad loki labels",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: loki, params: {url: 'http://localhost:3100', labels: [job]}}]}`,
+			expectedKey: "sinks[0].params.labels",
+		},
+		{
+			name:        "bad kafka acks",
+			config:      `{sources: [{type: http, params: {address: ':8080'}}], sinks: [{type: kafka, params: {brokers: ['localhost:9092'], topic: logs, required_acks: most}}]}`,
+			expectedKey: "sinks[0]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = cfg.Build()
+			if err == nil {
+				t.Fatal("Expected build error")
+			}
+			if !strings.Contains(err.Error(), tt.expectedKey) {
+				t.Errorf("Expected error mentioning %q, got %q", tt.expectedKey, err)
+			}
+		})
+	}
+}
+
+func TestBuild_Templates(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  templates: {depth: 5, similarity: 0.5}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Templates == nil {
+		t.Fatal("Expected a template miner")
+	}
+
+	entry := models.NewLogEntry()
+	entry.Message = "worker 12 restarted"
+	components.Templates.Apply(entry)
+	if entry.Fields[pipeline.FieldTemplate] != "worker <NUM> restarted" {
+		t.Errorf("Unexpected template %v", entry.Fields[pipeline.FieldTemplate])
+	}
+}
+
+func TestBuild_Sample(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  sample: {DEBUG: 0, info: 0.1, default: 1.0}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Sampler == nil {
+		t.Fatal("Expected a sampler")
+	}
+
+	if components.Sampler.Keep(&models.LogEntry{Level: models.LevelDebug, Message: "noise"}) {
+		t.Error("Expected DEBUG entries to be dropped")
+	}
+	if !components.Sampler.Keep(&models.LogEntry{Level: models.LevelError, Message: "failure"}) {
+		t.Error("Expected ERROR entries to be kept at the default rate")
+	}
+}
+
+func TestBuild_SampleDefaultZero(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  sample: {ERROR: 1, default: 0}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if components.Sampler.Keep(&models.LogEntry{Level: models.LevelInfo, Message: "chatter"}) {
+		t.Error("Expected INFO entries to be dropped at a default rate of 0")
+	}
+	if !components.Sampler.Keep(&models.LogEntry{Level: models.LevelError, Message: "failure"}) {
+		t.Error("Expected ERROR entries to be kept at their own rate")
+	}
+}
+
+func TestBuild_Enrich(t *testing.T) {
+	t.Setenv("LOGFLUX_TEST_ENV", "production")
+
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  enrich:
+    fields: {environment: '${LOGFLUX_TEST_ENV}', datacenter: eu-1}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Enricher == nil {
+		t.Fatal("Expected an enricher")
+	}
+
+	entry := models.NewLogEntry()
+	components.Enricher.Apply(entry)
+	if entry.Fields["environment"] != "production" || entry.Fields["datacenter"] != "eu-1" {
+		t.Errorf("Unexpected fields %v", entry.Fields)
+	}
+}
+
+func TestBuild_Flatten(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  flatten: {separator: _, arrays: keep}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Flattener == nil {
+		t.Fatal("Expected a flattener")
+	}
+
+	entry := models.NewLogEntry()
+	entry.Fields["http"] = map[string]interface{}{"status": 200, "tags": []interface{}{"a"}}
+	components.Flattener.Apply(entry)
+	if entry.Fields["http_status"] != 200 || len(entry.Fields["http_tags"].([]interface{})) != 1 {
+		t.Errorf("Expected flattened fields with arrays kept, got %v", entry.Fields)
+	}
+}
+
+func TestBuild_Truncate(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  truncate: {max_bytes: 4, marker: ' [%d more]'}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Truncator == nil {
+		t.Fatal("Expected a truncator")
+	}
+
+	entry := models.NewLogEntry()
+	entry.Message = "stack dump"
+	components.Truncator.Apply(entry)
+	if entry.Message != "stac [6 more]" || entry.Fields[pipeline.FieldOriginalLength] != 10 {
+		t.Errorf("Expected the message cut to 4 bytes, got %q %v", entry.Message, entry.Fields)
+	}
+}
+
+func TestBuild_Parse(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - {type: file, params: {path: app.log}, parse: pool}
+  - {type: file, params: {path: api.log, format: json}}
+filters:
+  parse: {format: json, workers: 2, ordered: true}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Parser == nil {
+		t.Fatal("Expected a parse pool")
+	}
+
+	raw := models.NewLogEntry()
+	raw.Source = "app.log"
+	raw.Message = `{"level":"error","message":"disk full"}` + "\n"
+	raw.Fields[sources.FieldUnparsed] = true
+	entry := components.Parser.Parse(raw)
+	if entry.Message != "disk full" || entry.Level != models.LevelError {
+		t.Errorf("Expected the JSON line parsed, got %q at %s", entry.Message, entry.Level)
+	}
+
+	// Entries from the JSON source were parsed as they were read
+	parsed := models.NewLogEntry()
+	parsed.Source = "api.log"
+	parsed.Message = `{"message":"quoted in a parsed message"}`
+	if entry := components.Parser.Parse(parsed); entry != parsed || entry.Message != parsed.Message {
+		t.Errorf("Expected the entry from the JSON source left alone, got %q", entry.Message)
+	}
+}
+
+func TestBuild_DeadLetter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unparsed.jsonl")
+	cfg, err := Parse([]byte(fmt.Sprintf(`
+sources: [{type: file, params: {path: app.log}, parse: pool}]
+filters:
+  parse: {format: json}
+dead_letter: {type: file, params: {path: %q}}
+`, path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.DeadLetter == nil {
+		t.Fatal("Expected a dead letter")
+	}
+
+	raw := models.NewLogEntry()
+	raw.Source = "app.log"
+	raw.Message = "not json\n"
+	raw.Fields[sources.FieldUnparsed] = true
+	if entry := components.Parser.Parse(raw); entry != nil {
+		t.Errorf("Expected the line dead-lettered, got %q", entry.Message)
+	}
+	if err := components.DeadLetter.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"parse_error"`) {
+		t.Errorf("Expected the line with its parse error in the dead letter file, got %s", data)
+	}
+}
+
+func TestBuild_Logfmt(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: file, params: {path: app.log}, parse: pool}]
+filters:
+  parse: {format: logfmt}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := components.Sources[0].(*sources.FileReader); !ok {
+		t.Fatalf("Expected *sources.FileReader, got %T", components.Sources[0])
+	}
+
+	raw := models.NewLogEntry()
+	raw.Message = `level=warn msg="slow query" ms=812`
+	raw.Fields[sources.FieldUnparsed] = true
+	entry := components.Parser.Parse(raw)
+	if entry.Message != "slow query" || entry.Level != models.LevelWarning || entry.Fields["ms"] != "812" {
+		t.Errorf("Expected the logfmt line parsed, got %q at %s with %v", entry.Message, entry.Level, entry.Fields)
+	}
+}
+
+func TestBuild_Labels(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"access.log", "app.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("request served\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := Parse([]byte(fmt.Sprintf(`
+sources:
+  - type: file
+    params: {path: %q, one_shot: true}
+    source_name: nginx
+    labels: {app: nginx, tier: edge}
+  - type: file
+    params: {path: %q, one_shot: true}
+    labels: {app: api}
+`, filepath.Join(dir, "access.log"), filepath.Join(dir, "app.log"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for i, expected := range []struct{ source, app string }{
+		{"nginx", "nginx"},
+		{filepath.Join(dir, "app.log"), "api"},
+	} {
+		out := make(chan *models.LogEntry, 1)
+		if err := components.Sources[i].Start(ctx, out); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case entry := <-out:
+			if entry.Source != expected.source || entry.Fields["app"] != expected.app {
+				t.Errorf("Expected app %s from %q, got %v from %q", expected.app, expected.source, entry.Fields["app"], entry.Source)
+			}
+		case <-ctx.Done():
+			t.Fatal("Timeout waiting for an entry")
+		}
+		components.Sources[i].Stop()
+	}
+}
+
+func TestBuild_InferLevel(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: file, params: {path: app.log, infer_level: true}}]
+filters:
+  infer_level:
+    rules:
+      - {keyword: oom, level: critical}
+      - {keyword: slow, level: warn}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Levels == nil {
+		t.Fatal("Expected a level inferrer")
+	}
+
+	entry := models.NewLogEntry()
+	entry.Message = "slow query then OOM"
+	components.Levels.Apply(entry)
+	if entry.Level != models.LevelCritical {
+		t.Errorf("Expected the first rule's CRITICAL, got %s", entry.Level)
+	}
+}
+
+func TestBuild_Schema(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  schema: {status: int, latency: float, cached: bool}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components.Coercer == nil {
+		t.Fatal("Expected a coercer")
+	}
+
+	entry := models.NewLogEntry()
+	entry.Fields["status"] = "503"
+	entry.Fields["latency"] = "0.25"
+	entry.Fields["cached"] = "false"
+	components.Coercer.Apply(entry)
+	if entry.Fields["status"] != int64(503) || entry.Fields["latency"] != 0.25 || entry.Fields["cached"] != false {
+		t.Errorf("Unexpected fields %v", entry.Fields)
+	}
+}
+
+func TestBuild_Retry(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := Parse([]byte(fmt.Sprintf(`
+sources: [{type: http, params: {address: ':8080'}}]
+sinks:
+  - type: loki
+    params: {url: 'http://localhost:3100'}
+    retry:
+      max_attempts: 5
+      base_delay: 50ms
+      max_delay: 2s
+      jitter: 0.2
+      dead_letter: {type: file, params: {path: %q}}
+`, filepath.Join(dir, "dead.jsonl"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	retrier, ok := components.Sinks[0].(*collector.Retrier)
+	if !ok {
+		t.Fatalf("Expected *collector.Retrier, got %T", components.Sinks[0])
+	}
+	defer retrier.Stop()
+	if retrier.Name() != "loki:http://localhost:3100" {
+		t.Errorf("Expected the wrapped sink's name, got %q", retrier.Name())
+	}
+}
+
+func TestBuild_OTLP(t *testing.T) {
+	var mu sync.Mutex
+	var resource map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		export := &collogspb.ExportLogsServiceRequest{}
+		if err := proto.Unmarshal(body, export); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		resource = make(map[string]string)
+		for _, kv := range export.ResourceLogs[0].Resource.Attributes {
+			resource[kv.Key] = kv.Value.GetStringValue()
+		}
+	}))
+	defer server.Close()
+
+	cfg, err := Parse([]byte(fmt.Sprintf(`
+sources: [{type: http, params: {address: ':8080'}}]
+filters:
+  enrich: {fields: {env: prod, region: eu}}
+sinks:
+  - type: otlp
+    params: {endpoint: %q, resource: {service.name: checkout}}
+`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := components.Sinks[0]
+	defer sink.(interface{ Stop() error }).Stop()
+
+	// The enriched fields become resource attributes by default
+	entry := models.NewLogEntry()
+	entry.Fields["env"] = "prod"
+	entry.Fields["region"] = "eu"
+	if err := sink.Write(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.(interface{ Flush() error }).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := map[string]string{"service.name": "checkout", "env": "prod", "region": "eu"}
+	if !reflect.DeepEqual(resource, expected) {
+		t.Errorf("Expected resource %v, got %v", expected, resource)
+	}
+}
+
+func TestBuild_Compression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	cfg, err := Parse([]byte(fmt.Sprintf(`
+sources: [{type: http, params: {address: ':8080'}}]
+sinks:
+  - type: file
+    params: {path: %q, compression: zstd}
+  - type: loki
+    params: {url: 'http://localhost:3100', compression: gzip}
+`, path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileSink, ok := components.Sinks[0].(*sinks.FileSink)
+	if !ok {
+		t.Fatalf("Expected *sinks.FileSink, got %T", components.Sinks[0])
+	}
+	defer fileSink.Stop()
+	if fileSink.Name() != "file:"+path+".zst" {
+		t.Errorf("Expected the zstd extension on the file sink, got %s", fileSink.Name())
+	}
+	lokiSink, ok := components.Sinks[1].(*sinks.LokiSink)
+	if !ok {
+		t.Fatalf("Expected *sinks.LokiSink, got %T", components.Sinks[1])
+	}
+	defer lokiSink.Stop()
+}
+
+func TestBuild_Queue(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := Parse([]byte(fmt.Sprintf(`
+sources: [{type: http, params: {address: ':8080'}}]
+sinks:
+  - type: stdout
+    retry: {max_attempts: 2}
+    queue: {dir: %q, max_size: 1048576, retry_delay: 2s}
+`, dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue, ok := components.Sinks[0].(*collector.DiskQueue)
+	if !ok {
+		t.Fatalf("Expected *collector.DiskQueue, got %T", components.Sinks[0])
+	}
+	defer queue.Stop()
+
+	if queue.Name() != "stdout" {
+		t.Errorf("Expected the wrapped sink's name, got %q", queue.Name())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "commit")); err != nil {
+		t.Errorf("Expected the queue to be created in %s: %v", dir, err)
+	}
+}
+
+func TestBuild_Restart(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - type: syslog
+    params: {protocol: udp, address: '127.0.0.1:0'}
+    restart: {max_restarts: 3, window: 10m, base_delay: 500ms, max_delay: 1m}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supervisor, ok := components.Sources[0].(*collector.Supervisor)
+	if !ok {
+		t.Fatalf("Expected *collector.Supervisor, got %T", components.Sources[0])
+	}
+	if _, ok := supervisor.Source().(*sources.SyslogReceiver); !ok {
+		t.Errorf("Expected a supervised *sources.SyslogReceiver, got %T", supervisor.Source())
+	}
+	if supervisor.Name() != "syslog:udp@127.0.0.1:0" {
+		t.Errorf("Expected the wrapped source's name, got %q", supervisor.Name())
+	}
+}
+
+func TestBuild_Routing(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - type: http
+    params: {address: ':8080'}
+sinks:
+  - name: alerts
+    type: memory
+  - name: storage
+    type: memory
+  - type: stdout
+routing:
+  mode: first
+  routes:
+    - name: errors
+      min_level: error
+      sinks: [alerts]
+  default: [storage]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(components.Sinks) != 2 {
+		t.Fatalf("Expected the router and the unrouted stdout sink, got %d sinks", len(components.Sinks))
+	}
+	router, ok := components.Sinks[0].(*pipeline.Router)
+	if !ok {
+		t.Fatalf("Expected *pipeline.Router, got %T", components.Sinks[0])
+	}
+	if _, ok := components.Sinks[1].(*sinks.StdoutSink); !ok {
+		t.Errorf("Expected the stdout sink kept, got %T", components.Sinks[1])
+	}
+
+	alerts := router.Sinks()[0].(*sinks.MemorySink)
+	storage := router.Sinks()[1].(*sinks.MemorySink)
+
+	for _, level := range []models.LogLevel{models.LevelError, models.LevelInfo, models.LevelInfo} {
+		entry := models.NewLogEntry()
+		entry.Level = level
+		if err := router.Write(context.Background(), entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored := len(storage.Recent(sinks.RecentFilter{}))
+	if got := len(alerts.Recent(sinks.RecentFilter{})); got != 1 || stored != 2 {
+		t.Errorf("Expected 1 alert and 2 stored entries, got %d and %d", got, stored)
+	}
+}
+
+func TestBuild_EventLog(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - type: eventlog
+    params: {channel: System, query: '*[System[Level<=2]]', bookmark: system.bookmark}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := components.Sources[0].(*sources.WindowsEventLogReader); !ok {
+		t.Fatalf("Expected *sources.WindowsEventLogReader, got %T", components.Sources[0])
+	}
+	if name := components.Sources[0].Name(); name != "eventlog:System" {
+		t.Errorf("Expected eventlog:System, got %q", name)
+	}
+}
+
+func TestBuild_Journald(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - type: journald
+    params: {units: [nginx.service, sshd.service], cursor: journal.cursor}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := components.Sources[0].(*sources.JournaldReader); !ok {
+		t.Fatalf("Expected *sources.JournaldReader, got %T", components.Sources[0])
+	}
+	if name := components.Sources[0].Name(); name != "journald:nginx.service,sshd.service" {
+		t.Errorf("Expected journald:nginx.service,sshd.service, got %q", name)
+	}
+}
+
+func TestBuild_Docker(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - type: docker
+    params: {labels: [logflux=true], names: [api], scan_interval: 10s}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := components.Sources[0].(*sources.DockerReader); !ok {
+		t.Fatalf("Expected *sources.DockerReader, got %T", components.Sources[0])
+	}
+	if name := components.Sources[0].Name(); name != "docker:label=logflux=true,name=api" {
+		t.Errorf("Expected docker:label=logflux=true,name=api, got %q", name)
+	}
+
+	cfg, err = Parse([]byte(`
+sources:
+  - type: docker
+    params: {host: "ftp://docker"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Build(); err == nil {
+		t.Error("Expected an error for an unsupported host")
+	}
+}
+
+func TestBuild_Alerts(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  - type: http
+    params: {address: ':8080'}
+alerts:
+  - name: error-spike
+    min_level: error
+    source: '^api'
+    threshold: 50
+    window: 1m
+    cooldown: 10m
+    samples: 3
+    webhook: http://hooks.local/alert
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(components.Alerters) != 1 {
+		t.Fatalf("Expected 1 alerter, got %d", len(components.Alerters))
+	}
+	if len(cfg.Alerts[0].Fields) != 0 || cfg.Alerts[0].MinLevel != "error" || cfg.Alerts[0].Source != "^api" {
+		t.Errorf("Expected the match conditions inline, got %+v", cfg.Alerts[0].MatchConfig)
+	}
+}
+
+func TestConfig_RestartRequired(t *testing.T) {
+	current, err := Parse([]byte(`
+sources: [{type: syslog, params: {address: ':514', protocol: udp}}]
+sinks: [{type: stdout}]
+filters: {min_level: info, dedup: {window: 1m}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		config       string
+		restart      []string
+		sinksChanged bool
+	}{
+		{
+			name: "min level",
+			config: `
+sources: [{type: syslog, params: {address: ':514', protocol: udp}}]
+sinks: [{type: stdout}]
+filters: {min_level: error, dedup: {window: 1m}}
+`,
+		},
+		{
+			name: "sink params",
+			config: `
+sources: [{type: syslog, params: {address: ':514', protocol: udp}}]
+sinks: [{type: stdout, params: {format: json}}]
+filters: {min_level: info, dedup: {window: 1m}}
+`,
+			sinksChanged: true,
+		},
+		{
+			name: "listener address and dedup",
+			config: `
+sources: [{type: syslog, params: {address: ':1514', protocol: udp}}]
+sinks: [{type: stdout}]
+filters: {min_level: info, dedup: {window: 5m}}
+`,
+			restart: []string{"sources", "filters.dedup"},
+		},
+		{
+			name: "inferred levels",
+			config: `
+sources: [{type: syslog, params: {address: ':514', protocol: udp}}]
+sinks: [{type: stdout}]
+filters: {min_level: info, dedup: {window: 1m}, infer_level: {overwrite: true}}
+`,
+			restart: []string{"filters.infer_level"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := Parse([]byte(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if restart := current.RestartRequired(next); strings.Join(restart, ",") != strings.Join(tt.restart, ",") {
+				t.Errorf("Expected %v to require a restart, got %v", tt.restart, restart)
+			}
+			if changed := current.SinksChanged(next); changed != tt.sinksChanged {
+				t.Errorf("Expected sinks changed %v, got %v", tt.sinksChanged, changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Params wraps a component's raw param map with typed accessors whose
+// errors point at the offending key
+type Params struct {
+	key    string
+	values map[string]interface{}
+}
+
+// Key returns where the component is in the config, e.g. "sinks[2]"
+func (p Params) Key() string {
+	return p.key
+}
+
+// Errorf returns an error pointing at the named param. It wraps like
+// fmt.Errorf.
+func (p Params) Errorf(name, format string, args ...interface{}) error {
+	return fmt.Errorf("%s.params.%s: "+format, append([]interface{}{p.key, name}, args...)...)
+}
+
+// String returns a required string param
+func (p Params) String(name string) (string, error) {
+	value, ok := p.values[name]
+	if !ok || value == nil {
+		return "", p.Errorf(name, "required")
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", p.Errorf(name, "expected string, got %T", value)
+	}
+	if s == "" {
+		return "", p.Errorf(name, "required")
+	}
+	return s, nil
+}
+
+// StringOr returns an optional string param
+func (p Params) StringOr(name, def string) (string, error) {
+	if _, ok := p.values[name]; !ok {
+		return def, nil
+	}
+	return p.String(name)
+}
+
+// IntOr returns an optional integer param
+func (p Params) IntOr(name string, def int) (int, error) {
+	value, ok := p.values[name]
+	if !ok || value == nil {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, p.Errorf(name, "expected integer, got %v", v)
+		}
+		return int(v), nil
+	default:
+		return 0, p.Errorf(name, "expected integer, got %T", value)
+	}
+}
+
+// FloatOr returns an optional number param
+func (p Params) FloatOr(name string, def float64) (float64, error) {
+	value, ok := p.values[name]
+	if !ok || value == nil {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, p.Errorf(name, "expected number, got %T", value)
+	}
+}
+
+// BoolOr returns an optional boolean param
+func (p Params) BoolOr(name string, def bool) (bool, error) {
+	value, ok := p.values[name]
+	if !ok || value == nil {
+		return def, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, p.Errorf(name, "expected boolean, got %T", value)
+	}
+	return b, nil
+}
+
+// DurationOr returns an optional duration param written like "1s" or "500ms"
+func (p Params) DurationOr(name string, def time.Duration) (time.Duration, error) {
+	value, ok := p.values[name]
+	if !ok || value == nil {
+		return def, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return 0, p.Errorf(name, "expected duration string, got %T", value)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, p.Errorf(name, "invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// StringsOr returns an optional list of strings
+func (p Params) StringsOr(name string, def []string) ([]string, error) {
+	value, ok := p.values[name]
+	if !ok || value == nil {
+		return def, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, p.Errorf(name, "expected list, got %T", value)
+	}
+	out := make([]string, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, p.Errorf(name, "expected string at index %d, got %T", i, item)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+// StringMapOr returns an optional string-to-string map
+func (p Params) StringMapOr(name string, def map[string]string) (map[string]string, error) {
+	value, ok := p.values[name]
+	if !ok || value == nil {
+		return def, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, p.Errorf(name, "expected map, got %T", value)
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, p.Errorf(name, "expected string value for %q, got %T", k, v)
+		}
+		out[k] = s
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+)
+
+// ParamSpec lists the required and optional params for a component type
+type ParamSpec struct {
+	Required []string
+	Optional []string
+}
+
+// SourceFactory builds a source from its params. Errors should point at the
+// offending param, see Params.Errorf.
+type SourceFactory func(p Params) (collector.Source, error)
+
+// SinkFactory builds a sink from its params
+type SinkFactory func(p Params) (collector.Sink, error)
+
+// The component types configs can use. Each backend registers itself in an
+// init function of its own file, so a build can leave backends out with
+// build tags such as no_sink_kafka.
+var registry = struct {
+	mu      sync.RWMutex
+	sources map[string]sourceRegistration
+	sinks   map[string]sinkRegistration
+}{
+	sources: make(map[string]sourceRegistration),
+	sinks:   make(map[string]sinkRegistration),
+}
+
+type sourceRegistration struct {
+	spec    ParamSpec
+	factory SourceFactory
+}
+
+type sinkRegistration struct {
+	spec    ParamSpec
+	factory SinkFactory
+}
+
+// RegisterSource makes a source type available to configs. It panics if
+// the type is already registered.
+func RegisterSource(name string, spec ParamSpec, factory SourceFactory) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.sources[name]; ok {
+		panic(fmt.Sprintf("config: source type %q registered twice", name))
+	}
+	registry.sources[name] = sourceRegistration{spec: spec, factory: factory}
+}
+
+// RegisterSink makes a sink type available to configs. It panics if the
+// type is already registered.
+func RegisterSink(name string, spec ParamSpec, factory SinkFactory) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.sinks[name]; ok {
+		panic(fmt.Sprintf("config: sink type %q registered twice", name))
+	}
+	registry.sinks[name] = sinkRegistration{spec: spec, factory: factory}
+}
+
+// GetSource returns the factory and params of a source type
+func GetSource(name string) (SourceFactory, ParamSpec, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	r, ok := registry.sources[name]
+	return r.factory, r.spec, ok
+}
+
+// GetSink returns the factory and params of a sink type
+func GetSink(name string) (SinkFactory, ParamSpec, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	r, ok := registry.sinks[name]
+	return r.factory, r.spec, ok
+}
+
+// SourceTypes returns the registered source types, sorted
+func SourceTypes() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.sources))
+	for name := range registry.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SinkTypes returns the registered sink types, sorted
+func SinkTypes() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.sinks))
+	for name := range registry.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sourceSpec and sinkSpec look up a type's params for validateComponent
+func sourceSpec(name string) (ParamSpec, bool) {
+	_, spec, ok := GetSource(name)
+	return spec, ok
+}
+
+func sinkSpec(name string) (ParamSpec, bool) {
+	_, spec, ok := GetSink(name)
+	return spec, ok
+}
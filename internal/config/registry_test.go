@@ -0,0 +1,189 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// fakeSource and fakeSink stand in for backends registered from outside
+// this package
+type fakeSource struct{ topic string }
+
+func (f *fakeSource) Start(ctx context.Context, out chan<- *models.LogEntry) error { return nil }
+func (f *fakeSource) Stop() error                                                  { return nil }
+func (f *fakeSource) Name() string                                                 { return "fake:" + f.topic }
+func (f *fakeSource) Stats() models.SourceStats                                    { return models.SourceStats{} }
+
+type fakeSink struct{ copies int }
+
+func (f *fakeSink) Write(ctx context.Context, entry *models.LogEntry) error { return nil }
+func (f *fakeSink) Flush() error                                            { return nil }
+func (f *fakeSink) Name() string                                            { return "fake" }
+
+// stoppableSink counts how often sinks it built were stopped
+type stoppableSink struct{ fakeSink }
+
+var stoppedSinks atomic.Int32
+
+func (s *stoppableSink) Stop() error {
+	stoppedSinks.Add(1)
+	return nil
+}
+
+func init() {
+	RegisterSource("fake", ParamSpec{Required: []string{"topic"}}, func(p Params) (collector.Source, error) {
+		topic, err := p.String("topic")
+		if err != nil {
+			return nil, err
+		}
+		return &fakeSource{topic: topic}, nil
+	})
+	RegisterSink("fake", ParamSpec{Optional: []string{"copies"}}, func(p Params) (collector.Sink, error) {
+		copies, err := p.IntOr("copies", 1)
+		if err != nil {
+			return nil, err
+		}
+		if copies <= 0 {
+			return nil, p.Errorf("copies", "must be positive, got %d", copies)
+		}
+		return &fakeSink{copies: copies}, nil
+	})
+	RegisterSink("stoppable", ParamSpec{}, func(p Params) (collector.Sink, error) {
+		return &stoppableSink{}, nil
+	})
+}
+
+func TestRegistry_BuildRegisteredTypes(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources: [{type: fake, params: {topic: orders}}]
+sinks: [{type: fake, params: {copies: 3}}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, ok := components.Sources[0].(*fakeSource)
+	if !ok || source.topic != "orders" {
+		t.Errorf("Expected the fake source for orders, got %#v", components.Sources[0])
+	}
+	sink, ok := components.Sinks[0].(*fakeSink)
+	if !ok || sink.copies != 3 {
+		t.Errorf("Expected the fake sink with 3 copies, got %#v", components.Sinks[0])
+	}
+}
+
+func TestRegistry_ParamSpecAndErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{"missing required", `sources: [{type: fake}]`, "sources[0].params.topic: required"},
+		{"unknown param", `sources: [{type: fake, params: {topic: a, partition: 1}}]`, "sources[0].params.partition: unknown param"},
+		{"factory error", `{sources: [{type: fake, params: {topic: a}}], sinks: [{type: fake, params: {copies: 0}}]}`, "sinks[0].params.copies: must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.config))
+			if err == nil {
+				_, err = cfg.Build()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestBuild_StopsBuiltSinksOnError(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"later sink fails", `{sources: [{type: fake, params: {topic: a}}], sinks: [{type: stoppable}, {type: stoppable}, {type: fake, params: {copies: 0}}]}`},
+		{"queue fails", `{sources: [{type: fake, params: {topic: a}}], sinks: [{type: stoppable}, {type: stoppable, queue: {dir: /dev/null/queue}}]}`},
+		{"dead letter and sink", `{sources: [{type: fake, params: {topic: a}}], dead_letter: {type: stoppable}, sinks: [{type: stoppable}, {type: fake, params: {copies: 0}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stoppedSinks.Store(0)
+			cfg, err := Parse([]byte(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cfg.Build(); err == nil {
+				t.Fatal("Expected the build to fail")
+			}
+			if n := stoppedSinks.Load(); n != 2 {
+				t.Errorf("Expected the 2 sinks built before the failure stopped, got %d", n)
+			}
+		})
+	}
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	if _, spec, ok := GetSource("fake"); !ok || spec.Required[0] != "topic" {
+		t.Errorf("Expected the fake source registered, got %v", spec)
+	}
+	if _, _, ok := GetSink("nope"); ok {
+		t.Error("Expected no sink named nope")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a type twice to panic")
+		}
+	}()
+	RegisterSink("fake", ParamSpec{}, nil)
+}
+
+func TestRebuildSinks_RestoresStoppedOnError(t *testing.T) {
+	queue := t.TempDir()
+	current, err := Parse([]byte(fmt.Sprintf(`{sources: [{type: fake, params: {topic: a}}], sinks: [{type: stoppable, queue: {dir: %q}}, {type: fake}]}`, queue)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, err := current.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := components.SinkChains[1]
+
+	// The successor shares the queue, so the old sink is stopped first,
+	// then fails to build
+	next, err := Parse([]byte(fmt.Sprintf(`{sources: [{type: fake, params: {topic: a}}], sinks: [{type: fake, params: {copies: 0}, queue: {dir: %q}}, {type: fake}]}`, queue)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stoppedSinks.Store(0)
+	restored, err := current.RebuildSinks(next, components.SinkChains)
+	if err == nil || !strings.Contains(err.Error(), "sinks[0].params.copies") {
+		t.Fatalf("Expected the successor's error, got %v", err)
+	}
+	if restored == nil {
+		t.Fatal("Expected the stopped sink built again")
+	}
+	defer stopBuilt(restored.Sinks)
+
+	if n := stoppedSinks.Load(); n != 1 {
+		t.Errorf("Expected the replaced sink stopped before its successor was built, got %d stops", n)
+	}
+	if restored.SinkChains[0] == components.SinkChains[0] {
+		t.Error("Expected a new sink in place of the stopped one")
+	}
+	if restored.SinkChains[1] != kept {
+		t.Error("Expected the unchanged sink kept")
+	}
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+)
+
+// RestartRequired lists the sections that differ in next but can't be
+// swapped into a running collector: the sources, whose listeners and files
+// are held open, the dead letter they write to, and the filters that run
+// over the stream between them and the pipeline
+func (c *Config) RestartRequired(next *Config) []string {
+	sections := []struct {
+		name      string
+		old, next interface{}
+	}{
+		{"sources", c.Sources, next.Sources},
+		{"dead_letter", c.DeadLetter, next.DeadLetter},
+		{"filters.multiline", c.Filters.Multiline, next.Filters.Multiline},
+		{"filters.parse", c.Filters.Parse, next.Filters.Parse},
+		{"filters.infer_level", c.Filters.InferLevel, next.Filters.InferLevel},
+		{"filters.sample", c.Filters.Sample, next.Filters.Sample},
+		{"filters.dedup", c.Filters.Dedup, next.Filters.Dedup},
+		{"filters.templates", c.Filters.Templates, next.Filters.Templates},
+		{"filters.enrich", c.Filters.Enrich, next.Filters.Enrich},
+	}
+
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.old, section.next) {
+			changed = append(changed, section.name)
+		}
+	}
+	return changed
+}
+
+// SinksChanged reports whether next describes different sinks or routing,
+// so they have to be built afresh
+func (c *Config) SinksChanged(next *Config) bool {
+	return !reflect.DeepEqual(c.Sinks, next.Sinks) || !reflect.DeepEqual(c.Routing, next.Routing)
+}
+
+// RebuildSinks builds next's sinks for a collector running the chains built
+// for c.Sinks. A sink whose config is unchanged at the same index is
+// reused, keeping its connections and buffered writes, and only the others
+// are built afresh. A new sink that shares a path or directory with one
+// being replaced is built only once the old one is stopped. Should it then
+// fail to build, the stopped sinks are built again from c and returned
+// along with the error, for the collector to swap in for them.
+func (c *Config) RebuildSinks(next *Config, chains []collector.Sink) (*Components, error) {
+	built := make([]collector.Sink, len(next.Sinks))
+	var replaced []int // indexes into c.Sinks
+	for i := range c.Sinks {
+		if i < len(next.Sinks) && reflect.DeepEqual(c.Sinks[i], next.Sinks[i]) {
+			built[i] = chains[i]
+		} else {
+			replaced = append(replaced, i)
+		}
+	}
+
+	var fresh []collector.Sink
+	var waiting []int // indexes into next.Sinks
+	sharing := make(map[int]bool)
+	for i, s := range next.Sinks {
+		if built[i] != nil {
+			continue
+		}
+		shares := false
+		for _, j := range replaced {
+			if sharesFiles(c.Sinks[j], s) {
+				sharing[j] = true
+				shares = true
+			}
+		}
+		if shares {
+			waiting = append(waiting, i)
+			continue
+		}
+		sink, err := next.buildSinkChain(fmt.Sprintf("sinks[%d]", i), s)
+		if err != nil {
+			stopBuilt(fresh)
+			return nil, err
+		}
+		built[i] = sink
+		fresh = append(fresh, sink)
+	}
+
+	var stopped []int
+	for _, j := range replaced {
+		if sharing[j] {
+			stopBuilt(chains[j : j+1])
+			stopped = append(stopped, j)
+		}
+	}
+	for _, i := range waiting {
+		sink, err := next.buildSinkChain(fmt.Sprintf("sinks[%d]", i), next.Sinks[i])
+		if err != nil {
+			stopBuilt(fresh)
+			return c.restoreSinks(chains, stopped, err)
+		}
+		built[i] = sink
+		fresh = append(fresh, sink)
+	}
+
+	routed, err := next.routeSinks(built)
+	if err != nil {
+		stopBuilt(fresh)
+		return c.restoreSinks(chains, stopped, err)
+	}
+	return &Components{Sinks: routed, SinkChains: built}, nil
+}
+
+// restoreSinks builds the sinks of c at the given indexes again, after
+// they were stopped for a reload that failed with cause. It returns c's
+// sinks with them in place, or nil when nothing was stopped.
+func (c *Config) restoreSinks(chains []collector.Sink, stopped []int, cause error) (*Components, error) {
+	if len(stopped) == 0 {
+		return nil, cause
+	}
+
+	restored := append([]collector.Sink(nil), chains...)
+	var rebuilt []collector.Sink
+	errs := []error{cause}
+	for _, j := range stopped {
+		sink, err := c.buildSinkChain(fmt.Sprintf("sinks[%d]", j), c.Sinks[j])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore sinks[%d]: %w", j, err))
+			continue
+		}
+		restored[j] = sink
+		rebuilt = append(rebuilt, sink)
+	}
+	routed, err := c.routeSinks(restored)
+	if err != nil {
+		stopBuilt(rebuilt)
+		return nil, errors.Join(append(errs, err)...)
+	}
+	return &Components{Sinks: routed, SinkChains: restored}, errors.Join(errs...)
+}
+
+// sharesFiles reports whether two sink configs name a common file or
+// directory, which the old sink has to let go of before the new one opens
+// it
+func sharesFiles(old, next ComponentConfig) bool {
+	nextPaths := sinkPaths(next)
+	for _, path := range sinkPaths(old) {
+		for _, nextPath := range nextPaths {
+			if path == nextPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sinkPaths returns the files and directories a sink config writes to
+func sinkPaths(c ComponentConfig) []string {
+	var paths []string
+	if path, ok := c.Params["path"].(string); ok && path != "" {
+		paths = append(paths, filepath.Clean(path))
+	}
+	if c.Queue != nil && c.Queue.Dir != "" {
+		paths = append(paths, filepath.Clean(c.Queue.Dir))
+	}
+	if c.Retry != nil && c.Retry.DeadLetter != nil {
+		paths = append(paths, sinkPaths(*c.Retry.DeadLetter)...)
+	}
+	return paths
+}
@@ -0,0 +1,66 @@
+//go:build !no_sink_elasticsearch
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+)
+
+func init() {
+	RegisterSink("elasticsearch", ParamSpec{
+		Required: []string{"url"},
+		Optional: []string{"index", "batch_size", "flush_interval", "max_retries", "fingerprint", "fingerprint_fields", "compression"},
+	}, buildElasticsearchSink)
+}
+
+func buildElasticsearchSink(p Params) (collector.Sink, error) {
+	url, err := p.String("url")
+	if err != nil {
+		return nil, err
+	}
+	index, err := p.StringOr("index", "")
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := p.IntOr("batch_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := p.DurationOr("flush_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxRetries, err := p.IntOr("max_retries", 0)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := p.BoolOr("fingerprint", false)
+	if err != nil {
+		return nil, err
+	}
+	fingerprintFields, err := p.StringsOr("fingerprint_fields", nil)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := p.compression(sinks.CompressionGzip)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := sinks.NewESSink(sinks.ESSinkOptions{
+		URL:               url,
+		Index:             index,
+		BatchSize:         batchSize,
+		FlushInterval:     flushInterval,
+		MaxRetries:        maxRetries,
+		Fingerprint:       fingerprint,
+		FingerprintFields: fingerprintFields,
+		Compression:       compression,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Key(), err)
+	}
+	return sink, nil
+}
@@ -0,0 +1,69 @@
+//go:build !no_sink_file
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+)
+
+func init() {
+	RegisterSink("file", ParamSpec{
+		Required: []string{"path"},
+		Optional: []string{"max_size", "flush_interval", "template", "partition", "max_open_files", "compression"},
+	}, buildFileSink)
+}
+
+func buildFileSink(p Params) (collector.Sink, error) {
+	path, err := p.String("path")
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := p.IntOr("max_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := p.DurationOr("flush_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := p.StringOr("template", "")
+	if err != nil {
+		return nil, err
+	}
+	if tmpl != "" {
+		if _, err := sinks.NewFormatter(tmpl); err != nil {
+			return nil, p.Errorf("template", "%v", err)
+		}
+	}
+	partition, err := p.StringOr("partition", "")
+	if err != nil {
+		return nil, err
+	}
+	if filepath.IsAbs(partition) {
+		return nil, p.Errorf("partition", "must be relative to path, got %q", partition)
+	}
+	maxOpen, err := p.IntOr("max_open_files", 0)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := p.compression()
+	if err != nil {
+		return nil, err
+	}
+	sink, err := sinks.NewFileSinkWithOptions(path, sinks.FileSinkOptions{
+		MaxSize:       int64(maxSize),
+		FlushInterval: flushInterval,
+		Template:      tmpl,
+		Partition:     partition,
+		MaxOpenFiles:  maxOpen,
+		Compression:   compression,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Key(), err)
+	}
+	return sink, nil
+}
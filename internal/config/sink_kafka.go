@@ -0,0 +1,51 @@
+//go:build !no_sink_kafka
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+)
+
+func init() {
+	RegisterSink("kafka", ParamSpec{
+		Required: []string{"brokers", "topic"},
+		Optional: []string{"batch_size", "flush_interval", "required_acks"},
+	}, buildKafkaSink)
+}
+
+func buildKafkaSink(p Params) (collector.Sink, error) {
+	brokers, err := p.StringsOr("brokers", nil)
+	if err != nil {
+		return nil, err
+	}
+	topic, err := p.String("topic")
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := p.IntOr("batch_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := p.DurationOr("flush_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+	acks, err := p.StringOr("required_acks", sinks.AcksAll)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := sinks.NewKafkaSink(sinks.KafkaSinkOptions{
+		Brokers:       brokers,
+		Topic:         topic,
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		RequiredAcks:  acks,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Key(), err)
+	}
+	return sink, nil
+}
@@ -0,0 +1,61 @@
+//go:build !no_sink_loki
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+)
+
+func init() {
+	RegisterSink("loki", ParamSpec{
+		Required: []string{"url"},
+		Optional: []string{"labels", "label_fields", "batch_size", "flush_interval", "max_retries", "compression"},
+	}, buildLokiSink)
+}
+
+func buildLokiSink(p Params) (collector.Sink, error) {
+	url, err := p.String("url")
+	if err != nil {
+		return nil, err
+	}
+	labels, err := p.StringMapOr("labels", nil)
+	if err != nil {
+		return nil, err
+	}
+	labelFields, err := p.StringsOr("label_fields", nil)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := p.IntOr("batch_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := p.DurationOr("flush_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxRetries, err := p.IntOr("max_retries", 0)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := p.compression(sinks.CompressionGzip)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := sinks.NewLokiSink(sinks.LokiSinkOptions{
+		URL:           url,
+		Labels:        labels,
+		LabelFields:   labelFields,
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		MaxRetries:    maxRetries,
+		Compression:   compression,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Key(), err)
+	}
+	return sink, nil
+}
@@ -0,0 +1,23 @@
+//go:build !no_sink_memory
+
+package config
+
+import (
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+)
+
+func init() {
+	RegisterSink("memory", ParamSpec{Optional: []string{"capacity"}}, buildMemorySink)
+}
+
+func buildMemorySink(p Params) (collector.Sink, error) {
+	capacity, err := p.IntOr("capacity", sinks.DefaultMemoryCapacity)
+	if err != nil {
+		return nil, err
+	}
+	if capacity <= 0 {
+		return nil, p.Errorf("capacity", "must be positive, got %d", capacity)
+	}
+	return sinks.NewMemorySink(capacity), nil
+}
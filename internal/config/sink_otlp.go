@@ -0,0 +1,77 @@
+//go:build !no_sink_otlp
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+)
+
+func init() {
+	RegisterSink("otlp", ParamSpec{
+		Required: []string{"endpoint"},
+		Optional: []string{"protocol", "headers", "resource", "resource_fields", "batch_size", "flush_interval", "timeout", "compression"},
+	}, buildOTLPSink)
+}
+
+func buildOTLPSink(p Params) (collector.Sink, error) {
+	endpoint, err := p.String("endpoint")
+	if err != nil {
+		return nil, err
+	}
+	protocol, err := p.StringOr("protocol", sinks.OTLPProtocolHTTP)
+	if err != nil {
+		return nil, err
+	}
+	if protocol != sinks.OTLPProtocolHTTP && protocol != sinks.OTLPProtocolGRPC {
+		return nil, p.Errorf("protocol", "expected %s or %s, got %q", sinks.OTLPProtocolHTTP, sinks.OTLPProtocolGRPC, protocol)
+	}
+	headers, err := p.StringMapOr("headers", nil)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := p.StringMapOr("resource", nil)
+	if err != nil {
+		return nil, err
+	}
+	resourceFields, err := p.StringsOr("resource_fields", nil)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := p.IntOr("batch_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := p.DurationOr("flush_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := p.DurationOr("timeout", 0)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := p.compression()
+	if err != nil {
+		return nil, err
+	}
+	if protocol == sinks.OTLPProtocolGRPC && compression != sinks.CompressionNone && compression != sinks.CompressionGzip {
+		return nil, p.Errorf("compression", "only gzip is supported over %s, got %q", protocol, compression)
+	}
+	sink, err := sinks.NewOTLPSink(sinks.OTLPSinkOptions{
+		Endpoint:       endpoint,
+		Protocol:       protocol,
+		Headers:        headers,
+		Resource:       resource,
+		ResourceFields: resourceFields,
+		BatchSize:      batchSize,
+		FlushInterval:  flushInterval,
+		Timeout:        timeout,
+		Compression:    compression,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Key(), err)
+	}
+	return sink, nil
+}
@@ -0,0 +1,50 @@
+//go:build !no_sink_stdout
+
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sinks"
+)
+
+func init() {
+	RegisterSink("stdout", ParamSpec{
+		Optional: []string{"format", "color", "template"},
+	}, buildStdoutSink)
+}
+
+func buildStdoutSink(p Params) (collector.Sink, error) {
+	format, err := p.StringOr("format", sinks.FormatText)
+	if err != nil {
+		return nil, err
+	}
+	format = strings.ToLower(format)
+	if format != sinks.FormatText && format != sinks.FormatJSON {
+		return nil, p.Errorf("format", "unsupported format %q", format)
+	}
+	color, err := p.StringOr("color", sinks.ColorAuto)
+	if err != nil {
+		return nil, err
+	}
+	color = strings.ToLower(color)
+	if color != sinks.ColorAuto && color != sinks.ColorAlways && color != sinks.ColorNever {
+		return nil, p.Errorf("color", "unsupported mode %q (expected auto, always or never)", color)
+	}
+	tmpl, err := p.StringOr("template", "")
+	if err != nil {
+		return nil, err
+	}
+	if tmpl != "" {
+		sink, err := sinks.NewStdoutSinkWithTemplate(os.Stdout, tmpl)
+		if err != nil {
+			return nil, p.Errorf("template", "%v", err)
+		}
+		return sink, nil
+	}
+	sink := sinks.NewStdoutSinkWithFormat(os.Stdout, format)
+	sink.SetColor(color)
+	return sink, nil
+}
@@ -0,0 +1,56 @@
+//go:build !no_source_directory
+
+package config
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func init() {
+	RegisterSource("directory", ParamSpec{
+		Required: []string{"pattern"},
+		Optional: []string{"format", "keep_raw", "infer_level", "poll_period", "adaptive_poll", "max_poll_period"},
+	}, buildDirectorySource)
+}
+
+func buildDirectorySource(p Params) (collector.Source, error) {
+	pattern, err := p.String("pattern")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := filepath.Glob(pattern); err != nil {
+		return nil, p.Errorf("pattern", "invalid pattern %q", pattern)
+	}
+	format, err := p.StringOr("format", sources.FormatRaw)
+	if err != nil {
+		return nil, err
+	}
+	format = strings.ToLower(format)
+	if format != sources.FormatRaw && format != sources.FormatJSON && format != sources.FormatLogfmt {
+		return nil, p.Errorf("format", "unsupported format %q", format)
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	inferLevel, err := p.BoolOr("infer_level", false)
+	if err != nil {
+		return nil, err
+	}
+	polling, err := p.polling()
+	if err != nil {
+		return nil, err
+	}
+	reader := sources.NewDirectoryReaderWithFormat(pattern, format)
+	reader.SetPolling(polling)
+	reader.SetKeepRaw(raw)
+	if inferLevel {
+		reader.SetLevelRules(models.DefaultLevelRules)
+	}
+	return reader, nil
+}
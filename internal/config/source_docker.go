@@ -0,0 +1,46 @@
+//go:build !no_source_docker
+
+package config
+
+import (
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+)
+
+func init() {
+	RegisterSource("docker", ParamSpec{
+		Optional: []string{"host", "labels", "names", "scan_interval", "keep_raw"},
+	}, buildDockerSource)
+}
+
+func buildDockerSource(p Params) (collector.Source, error) {
+	host, err := p.StringOr("host", sources.DefaultDockerHost)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := p.StringsOr("labels", nil)
+	if err != nil {
+		return nil, err
+	}
+	names, err := p.StringsOr("names", nil)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := p.DurationOr("scan_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sources.NewDockerClient(host)
+	if err != nil {
+		return nil, p.Errorf("host", "%v", err)
+	}
+	return sources.NewDockerReader(client, sources.DockerOptions{
+		Filter:       sources.DockerFilter{Labels: labels, Names: names},
+		ScanInterval: interval,
+		KeepRaw:      raw,
+	}), nil
+}
@@ -0,0 +1,39 @@
+//go:build !no_source_eventlog
+
+package config
+
+import (
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+)
+
+func init() {
+	RegisterSource("eventlog", ParamSpec{
+		Required: []string{"channel"},
+		Optional: []string{"query", "bookmark", "keep_raw"},
+	}, buildEventlogSource)
+}
+
+func buildEventlogSource(p Params) (collector.Source, error) {
+	channel, err := p.String("channel")
+	if err != nil {
+		return nil, err
+	}
+	query, err := p.StringOr("query", "")
+	if err != nil {
+		return nil, err
+	}
+	bookmark, err := p.StringOr("bookmark", "")
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	return sources.NewWindowsEventLogReaderWithOptions(channel, sources.WindowsEventLogOptions{
+		Query:        query,
+		BookmarkPath: bookmark,
+		KeepRaw:      raw,
+	}), nil
+}
@@ -0,0 +1,130 @@
+//go:build !no_source_file
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func init() {
+	RegisterSource("file", ParamSpec{
+		Required: []string{"path"},
+		Optional: []string{"format", "pattern", "timestamp_layout", "timestamp_layouts", "columns", "header", "delimiter", "checkpoint", "keep_raw", "one_shot", "infer_level", "poll_period", "adaptive_poll", "max_poll_period"},
+	}, buildFileSource)
+}
+
+func buildFileSource(p Params) (collector.Source, error) {
+	path, err := p.String("path")
+	if err != nil {
+		return nil, err
+	}
+	format, err := p.StringOr("format", sources.FormatRaw)
+	if err != nil {
+		return nil, err
+	}
+	format = strings.ToLower(format)
+	checkpoint, err := p.StringOr("checkpoint", "")
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	oneShot, err := p.BoolOr("one_shot", false)
+	if err != nil {
+		return nil, err
+	}
+	inferLevel, err := p.BoolOr("infer_level", false)
+	if err != nil {
+		return nil, err
+	}
+	polling, err := p.polling()
+	if err != nil {
+		return nil, err
+	}
+
+	var reader *sources.FileReader
+	switch format {
+	case sources.FormatRaw:
+		reader = sources.NewFileReaderWithFormat(path, format)
+
+	case sources.FormatJSON:
+		layouts, err := p.StringsOr("timestamp_layouts", nil)
+		if err != nil {
+			return nil, err
+		}
+		reader = sources.NewFileReaderWithJSON(path, sources.JSONOptions{TimestampLayouts: layouts})
+
+	case sources.FormatLogfmt:
+		layouts, err := p.StringsOr("timestamp_layouts", nil)
+		if err != nil {
+			return nil, err
+		}
+		reader = sources.NewFileReaderWithLogfmt(path, sources.LogfmtOptions{TimestampLayouts: layouts})
+
+	case sources.FormatRegex:
+		pattern, err := p.String("pattern")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, p.Errorf("pattern", "%w", err)
+		}
+		layout, err := p.StringOr("timestamp_layout", "")
+		if err != nil {
+			return nil, err
+		}
+		reader = sources.NewFileReaderWithRegex(path, pattern)
+		reader.SetTimestampLayout(layout)
+
+	case sources.FormatCSV, sources.FormatTSV:
+		columns, err := p.StringsOr("columns", nil)
+		if err != nil {
+			return nil, err
+		}
+		header, err := p.BoolOr("header", false)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 && !header {
+			return nil, fmt.Errorf("%s.params: %s format requires columns or header", p.Key(), format)
+		}
+		delimiter := '\t'
+		if format == sources.FormatCSV {
+			d, err := p.StringOr("delimiter", ",")
+			if err != nil {
+				return nil, err
+			}
+			runes := []rune(d)
+			if len(runes) != 1 || runes[0] == '"' || runes[0] == '\n' || runes[0] == '\r' {
+				return nil, p.Errorf("delimiter", "must be a single character other than a quote or newline, got %q", d)
+			}
+			delimiter = runes[0]
+		}
+		layout, err := p.StringOr("timestamp_layout", "")
+		if err != nil {
+			return nil, err
+		}
+		reader = sources.NewFileReaderWithDelimited(path, delimiter, columns)
+		reader.SetSkipHeader(header)
+		reader.SetTimestampLayout(layout)
+
+	default:
+		return nil, p.Errorf("format", "unsupported format %q", format)
+	}
+	reader.SetCheckpoint(checkpoint)
+	reader.SetPolling(polling)
+	reader.SetKeepRaw(raw)
+	reader.SetOneShot(oneShot)
+	if inferLevel {
+		reader.SetLevelRules(models.DefaultLevelRules)
+	}
+	return reader, nil
+}
@@ -0,0 +1,29 @@
+//go:build !no_source_gelf
+
+package config
+
+import (
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+)
+
+func init() {
+	RegisterSource("gelf", ParamSpec{
+		Required: []string{"address"},
+		Optional: []string{"keep_raw"},
+	}, buildGelfSource)
+}
+
+func buildGelfSource(p Params) (collector.Source, error) {
+	addr, err := p.String("address")
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	receiver := sources.NewGELFReceiver(addr)
+	receiver.SetKeepRaw(raw)
+	return receiver, nil
+}
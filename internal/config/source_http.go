@@ -0,0 +1,100 @@
+//go:build !no_source_http
+
+package config
+
+import (
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+)
+
+func init() {
+	RegisterSource("http", ParamSpec{
+		Required: []string{"address"},
+		Optional: []string{"reject_empty_messages", "strict_timestamps", "allow", "trusted_proxies", "path_prefix", "routes", "max_body_bytes", "read_header_timeout", "tail_origins", "keep_raw"},
+	}, buildHttpSource)
+}
+
+func buildHttpSource(p Params) (collector.Source, error) {
+	addr, err := p.String("address")
+	if err != nil {
+		return nil, err
+	}
+	rejectEmpty, err := p.BoolOr("reject_empty_messages", false)
+	if err != nil {
+		return nil, err
+	}
+	strictTimestamps, err := p.BoolOr("strict_timestamps", false)
+	if err != nil {
+		return nil, err
+	}
+	allow, err := p.allowlist("allow")
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := p.allowlist("trusted_proxies")
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := p.StringOr("path_prefix", "")
+	if err != nil {
+		return nil, err
+	}
+	routes, err := httpRoutes(p)
+	if err != nil {
+		return nil, err
+	}
+	maxBody, err := p.IntOr("max_body_bytes", 0)
+	if err != nil {
+		return nil, err
+	}
+	headerTimeout, err := p.DurationOr("read_header_timeout", 0)
+	if err != nil {
+		return nil, err
+	}
+	tailOrigins, err := p.StringsOr("tail_origins", nil)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	return sources.NewHTTPReceiverWithOptions(addr, sources.HTTPReceiverOptions{
+		RejectEmptyMessages: rejectEmpty,
+		StrictTimestamps:    strictTimestamps,
+		Allowlist:           allow,
+		TrustedProxies:      proxies,
+		PathPrefix:          prefix,
+		Routes:              routes,
+		MaxBodyBytes:        int64(maxBody),
+		ReadHeaderTimeout:   headerTimeout,
+		TailOrigins:         tailOrigins,
+		KeepRaw:             raw,
+	}), nil
+}
+
+// httpRoutes reads the routes param, a map from endpoint to its path
+func httpRoutes(p Params) (sources.HTTPRoutes, error) {
+	names, err := p.StringMapOr("routes", nil)
+	if err != nil {
+		return sources.HTTPRoutes{}, err
+	}
+
+	var routes sources.HTTPRoutes
+	fields := map[string]*string{
+		"logs":   &routes.Logs,
+		"batch":  &routes.Batch,
+		"stream": &routes.Stream,
+		"tail":   &routes.Tail,
+		"health": &routes.Health,
+		"level":  &routes.Level,
+	}
+	for endpoint, path := range names {
+		field, ok := fields[endpoint]
+		if !ok {
+			return sources.HTTPRoutes{}, p.Errorf("routes", "unknown endpoint %q", endpoint)
+		}
+		*field = path
+	}
+	return routes, nil
+}
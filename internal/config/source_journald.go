@@ -0,0 +1,34 @@
+//go:build !no_source_journald
+
+package config
+
+import (
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+)
+
+func init() {
+	RegisterSource("journald", ParamSpec{
+		Optional: []string{"units", "cursor", "keep_raw"},
+	}, buildJournaldSource)
+}
+
+func buildJournaldSource(p Params) (collector.Source, error) {
+	units, err := p.StringsOr("units", nil)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := p.StringOr("cursor", "")
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	return sources.NewJournaldReaderWithOptions(sources.JournaldOptions{
+		Units:      units,
+		CursorPath: cursor,
+		KeepRaw:    raw,
+	}), nil
+}
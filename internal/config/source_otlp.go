@@ -0,0 +1,34 @@
+//go:build !no_source_otlp
+
+package config
+
+import (
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+)
+
+func init() {
+	RegisterSource("otlp", ParamSpec{
+		Required: []string{"address"},
+		Optional: []string{"send_timeout", "max_body_bytes"},
+	}, buildOTLPSource)
+}
+
+func buildOTLPSource(p Params) (collector.Source, error) {
+	addr, err := p.String("address")
+	if err != nil {
+		return nil, err
+	}
+	sendTimeout, err := p.DurationOr("send_timeout", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxBody, err := p.IntOr("max_body_bytes", 0)
+	if err != nil {
+		return nil, err
+	}
+	return sources.NewOTLPReceiverWithOptions(addr, sources.OTLPReceiverOptions{
+		SendTimeout:  sendTimeout,
+		MaxBodyBytes: int64(maxBody),
+	}), nil
+}
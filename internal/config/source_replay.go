@@ -0,0 +1,42 @@
+//go:build !no_source_replay
+
+package config
+
+import (
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+)
+
+func init() {
+	RegisterSource("replay", ParamSpec{
+		Required: []string{"path"},
+		Optional: []string{"speed", "ignore_timing", "keep_raw"},
+	}, buildReplaySource)
+}
+
+func buildReplaySource(p Params) (collector.Source, error) {
+	path, err := p.String("path")
+	if err != nil {
+		return nil, err
+	}
+	speed, err := p.FloatOr("speed", 1)
+	if err != nil {
+		return nil, err
+	}
+	if speed <= 0 {
+		return nil, p.Errorf("speed", "must be positive, got %v", speed)
+	}
+	ignoreTiming, err := p.BoolOr("ignore_timing", false)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	return sources.NewReplayReaderWithOptions(path, sources.ReplayOptions{
+		Speed:        speed,
+		IgnoreTiming: ignoreTiming,
+		KeepRaw:      raw,
+	}), nil
+}
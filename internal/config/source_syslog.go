@@ -0,0 +1,86 @@
+//go:build !no_source_syslog
+
+package config
+
+import (
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/collector/sources"
+)
+
+func init() {
+	RegisterSource("syslog", ParamSpec{
+		Required: []string{"protocol", "address"},
+		Optional: []string{"max_connections", "udp_buffer_size", "max_message_size", "drop_empty_messages", "keep_raw", "allow", "source_template", "idle_timeout", "keepalive"},
+	}, buildSyslogSource)
+}
+
+func buildSyslogSource(p Params) (collector.Source, error) {
+	protocol, err := p.String("protocol")
+	if err != nil {
+		return nil, err
+	}
+	protocol = strings.ToLower(protocol)
+	if protocol != "udp" && protocol != "tcp" {
+		return nil, p.Errorf("protocol", "unsupported protocol %q", protocol)
+	}
+	addr, err := p.String("address")
+	if err != nil {
+		return nil, err
+	}
+	maxConns, err := p.IntOr("max_connections", 0)
+	if err != nil {
+		return nil, err
+	}
+	bufferSize, err := p.IntOr("udp_buffer_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxMessage, err := p.IntOr("max_message_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	dropEmpty, err := p.BoolOr("drop_empty_messages", false)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.BoolOr("keep_raw", false)
+	if err != nil {
+		return nil, err
+	}
+	allow, err := p.allowlist("allow")
+	if err != nil {
+		return nil, err
+	}
+	text, err := p.StringOr("source_template", "")
+	if err != nil {
+		return nil, err
+	}
+	template, err := sources.ParseSourceTemplate(text)
+	if err != nil {
+		return nil, p.Errorf("source_template", "%v", err)
+	}
+	idleTimeout, err := p.DurationOr("idle_timeout", 0)
+	if err != nil {
+		return nil, err
+	}
+	if idleTimeout < 0 {
+		return nil, p.Errorf("idle_timeout", "must not be negative, got %s", idleTimeout)
+	}
+	keepAlive, err := p.DurationOr("keepalive", 0)
+	if err != nil {
+		return nil, err
+	}
+	return sources.NewSyslogReceiverWithOptions(addr, protocol, sources.SyslogReceiverOptions{
+		MaxConnections:    maxConns,
+		UDPBufferSize:     bufferSize,
+		MaxMessageSize:    maxMessage,
+		DropEmptyMessages: dropEmpty,
+		KeepRaw:           raw,
+		Allowlist:         allow,
+		SourceTemplate:    template,
+		IdleTimeout:       idleTimeout,
+		KeepAlive:         keepAlive,
+	}), nil
+}
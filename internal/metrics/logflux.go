@@ -0,0 +1,59 @@
+package metrics
+
+import "net/http"
+
+// Default is the registry the collector's own metrics live in
+var Default = NewRegistry()
+
+// Collector metrics
+var (
+	// EntriesReceived counts entries produced by each source
+	EntriesReceived = Default.NewCounterVec("logflux_entries_received_total",
+		"Log entries produced by sources.", "source")
+
+	// EntriesDropped counts entries that never reached a sink, by reason
+	EntriesDropped = Default.NewCounterVec("logflux_entries_dropped_total",
+		"Log entries dropped before reaching a sink.", "reason")
+
+	// EntriesRateLimited counts entries each source lost to its rate limit
+	EntriesRateLimited = Default.NewCounterVec("logflux_entries_rate_limited_total",
+		"Log entries dropped by a source's rate limit.", "source")
+
+	// BytesReceived counts raw bytes read by each source
+	BytesReceived = Default.NewCounterVec("logflux_bytes_received_total",
+		"Raw bytes read by sources.", "source")
+
+	// SourcesDenied counts clients refused by a receiver's allowlist
+	SourcesDenied = Default.NewCounterVec("logflux_sources_denied_total",
+		"Connections, datagrams and requests refused by a receiver's allowlist.", "source")
+
+	// PipelineLatency tracks the time from receipt to a successful sink write
+	PipelineLatency = Default.NewHistogram("logflux_pipeline_latency_seconds",
+		"Time from receiving an entry to writing it to the sinks.", DefaultLatencyBuckets)
+
+	// EntrySize tracks the size of the messages entering the pipeline
+	EntrySize = Default.NewHistogram("logflux_entry_size_bytes",
+		"Size of log entry messages in bytes.", DefaultSizeBuckets)
+)
+
+// Drop reasons used with EntriesDropped
+const (
+	ReasonChannelFull  = "channel_full"
+	ReasonFiltered     = "filtered"
+	ReasonSinkError    = "sink_error"
+	ReasonRateLimited  = "rate_limited"
+	ReasonSampled      = "sampled"
+	ReasonQueueFull    = "queue_full"
+	ReasonEmptyMessage = "empty_message"
+	ReasonUnrouted     = "unrouted"
+)
+
+// Handler serves the default registry
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// StatsHandler serves the default registry's histogram percentiles as JSON
+func StatsHandler() http.Handler {
+	return Default.StatsHandler()
+}
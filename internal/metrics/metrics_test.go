@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	return rec.Body.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	received := r.NewCounterVec("test_entries_total", "Test entries.", "source")
+
+	received.WithLabelValues("file:/var/log/app.log").Inc()
+	received.WithLabelValues("file:/var/log/app.log").Add(2)
+	received.WithLabelValues(`http:"quoted"`).Inc()
+	received.WithLabelValues("ignored").Add(-5)
+
+	if got := received.WithLabelValues("file:/var/log/app.log").Value(); got != 3 {
+		t.Errorf("Expected 3, got %d", got)
+	}
+
+	output := scrape(t, r)
+
+	expected := []string{
+		"# HELP test_entries_total Test entries.",
+		"# TYPE test_entries_total counter",
+		`test_entries_total{source="file:/var/log/app.log"} 3`,
+		`test_entries_total{source="http:\"quoted\""} 1`,
+		`test_entries_total{source="ignored"} 0`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected line %q in output:\n%s", line, output)
+		}
+	}
+}
+
+func TestCounterVec_NoLabels(t *testing.T) {
+	r := NewRegistry()
+	total := r.NewCounterVec("test_total", "Test total.")
+	total.WithLabelValues().Add(7)
+
+	if output := scrape(t, r); !strings.Contains(output, "test_total 7\n") {
+		t.Errorf("Expected unlabelled series, got:\n%s", output)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("test_latency_seconds", "Test latency.", []float64{1, 0.1})
+
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	if h.Count() != 3 {
+		t.Errorf("Expected 3 observations, got %d", h.Count())
+	}
+
+	output := scrape(t, r)
+
+	expected := []string{
+		"# TYPE test_latency_seconds histogram",
+		`test_latency_seconds_bucket{le="0.1"} 1`,
+		`test_latency_seconds_bucket{le="1"} 2`,
+		`test_latency_seconds_bucket{le="+Inf"} 3`,
+		"test_latency_seconds_sum 3.55",
+		"test_latency_seconds_count 3",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected line %q in output:\n%s", line, output)
+		}
+	}
+}
+
+func TestDefaultMetricsRegistered(t *testing.T) {
+	EntriesReceived.WithLabelValues("test").Inc()
+	EntriesDropped.WithLabelValues(ReasonFiltered).Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	output := rec.Body.String()
+
+	for _, name := range []string{
+		"logflux_entries_received_total",
+		"logflux_entries_dropped_total",
+		"logflux_bytes_received_total",
+		"logflux_pipeline_latency_seconds",
+		"logflux_entry_size_bytes",
+	} {
+		if !strings.Contains(output, "# TYPE "+name) {
+			t.Errorf("Expected %s in default registry", name)
+		}
+	}
+}
+
+func TestHistogram_Quantile(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("test_size_bytes", "Test sizes.", DefaultSizeBuckets)
+
+	if h.Quantile(0.5) != 0 {
+		t.Errorf("Expected 0 without observations, got %v", h.Quantile(0.5))
+	}
+
+	// 90 small messages, 9 medium ones and a single huge one
+	for i := 0; i < 90; i++ {
+		h.Observe(100)
+	}
+	for i := 0; i < 9; i++ {
+		h.Observe(3000)
+	}
+	h.Observe(5 << 20)
+
+	tests := []struct {
+		q        float64
+		min, max float64
+	}{
+		{0.5, 64, 128},
+		{0.9, 64, 128},
+		{0.95, 2048, 4096},
+		{0.99, 2048, 4096},
+		{1, 1 << 20, 1 << 20}, // above the highest bound
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q); got < tt.min || got > tt.max {
+			t.Errorf("Expected p%v between %v and %v, got %v", tt.q*100, tt.min, tt.max, got)
+		}
+	}
+
+	summary := h.Summary()
+	if summary.Count != 100 || summary.P50 != h.Quantile(0.5) || summary.P99 != h.Quantile(0.99) {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
+
+func TestRegistry_StatsHandler(t *testing.T) {
+	r := NewRegistry()
+	latency := r.NewHistogram("test_latency_seconds", "Test latency.", DefaultLatencyBuckets)
+	r.NewCounterVec("test_total", "Not a histogram.")
+
+	for i := 0; i < 100; i++ {
+		latency.Observe(0.003)
+	}
+
+	rec := httptest.NewRecorder()
+	r.StatsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var stats map[string]Summary
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("Expected only the histogram, got %v", stats)
+	}
+	summary := stats["test_latency_seconds"]
+	if summary.Count != 100 {
+		t.Errorf("Expected 100 observations, got %d", summary.Count)
+	}
+	for name, p := range map[string]float64{"p50": summary.P50, "p90": summary.P90, "p99": summary.P99} {
+		if p < 0.001 || p > 0.005 {
+			t.Errorf("Expected %s between 1ms and 5ms, got %v", name, p)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	r.StatsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stats", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rec.Code)
+	}
+}
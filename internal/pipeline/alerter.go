@@ -0,0 +1,199 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// alertActionTimeout bounds a single alert action such as a webhook call
+const alertActionTimeout = 10 * time.Second
+
+// Alert describes a threshold crossing, as sent to an AlertAction
+type Alert struct {
+	Name        string    `json:"name"`
+	Count       int       `json:"count"`
+	Threshold   int       `json:"threshold"`
+	Window      string    `json:"window"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Samples     []string  `json:"samples"` // the latest matching messages
+}
+
+// AlertAction is invoked when an Alerter fires
+type AlertAction func(ctx context.Context, alert Alert) error
+
+// AlerterOptions configures an Alerter
+type AlerterOptions struct {
+	// Name identifies the alert in its payload
+	Name string
+
+	// Match selects the entries that are counted. Every predicate must
+	// match; without any, every entry is counted.
+	Match []Predicate
+
+	// Threshold is the count within Window at which the alert fires
+	Threshold int
+
+	// Window is the sliding period entries are counted over. Defaults to 1m.
+	Window time.Duration
+
+	// Cooldown is the minimum time between two alerts. Defaults to Window.
+	Cooldown time.Duration
+
+	// MaxSamples is how many matching messages an alert carries. Defaults
+	// to 5.
+	MaxSamples int
+
+	// Action is invoked, outside the pipeline, when the alert fires
+	Action AlertAction
+}
+
+// Alerter counts the entries matching its predicates over a sliding window
+// and invokes an action when the count reaches a threshold, such as an ERROR
+// rate spike. After firing it stays quiet for the cooldown. Entries pass
+// through unchanged.
+type Alerter struct {
+	opts AlerterOptions
+
+	mu        sync.Mutex
+	hits      []time.Time // arrival times within the window, oldest first
+	samples   []string
+	lastFired time.Time
+
+	fired  atomic.Int64
+	failed atomic.Int64
+	now    func() time.Time
+}
+
+// NewAlerter creates an alerter
+func NewAlerter(opts AlerterOptions) (*Alerter, error) {
+	if opts.Threshold <= 0 {
+		return nil, fmt.Errorf("threshold must be positive, got %d", opts.Threshold)
+	}
+	if opts.Action == nil {
+		return nil, fmt.Errorf("an action is required")
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = opts.Window
+	}
+	if opts.MaxSamples <= 0 {
+		opts.MaxSamples = 5
+	}
+
+	return &Alerter{opts: opts, now: time.Now}, nil
+}
+
+// Observe counts the entry if it matches and fires the alert once the
+// threshold is reached outside the cooldown
+func (a *Alerter) Observe(entry *models.LogEntry) {
+	for _, p := range a.opts.Match {
+		if !p(entry) {
+			return
+		}
+	}
+
+	a.mu.Lock()
+	now := a.now()
+	start := now.Add(-a.opts.Window)
+
+	expired := 0
+	for expired < len(a.hits) && !a.hits[expired].After(start) {
+		expired++
+	}
+	a.hits = append(a.hits[expired:], now)
+
+	a.samples = append(a.samples, entry.Message)
+	if len(a.samples) > a.opts.MaxSamples {
+		a.samples = a.samples[len(a.samples)-a.opts.MaxSamples:]
+	}
+
+	if len(a.hits) < a.opts.Threshold || (!a.lastFired.IsZero() && now.Sub(a.lastFired) < a.opts.Cooldown) {
+		a.mu.Unlock()
+		return
+	}
+
+	a.lastFired = now
+	alert := Alert{
+		Name:        a.opts.Name,
+		Count:       len(a.hits),
+		Threshold:   a.opts.Threshold,
+		Window:      a.opts.Window.String(),
+		WindowStart: start,
+		WindowEnd:   now,
+		Samples:     append([]string(nil), a.samples...),
+	}
+	a.mu.Unlock()
+
+	a.fired.Add(1)
+	go a.fire(alert)
+}
+
+// fire runs the action, logging a failure
+func (a *Alerter) fire(alert Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertActionTimeout)
+	defer cancel()
+
+	fmt.Printf("🚨 Alert %s: %d matching entries within %s\n", alert.Name, alert.Count, alert.Window)
+	if err := a.opts.Action(ctx, alert); err != nil {
+		a.failed.Add(1)
+		fmt.Printf("⚠️  Alert %s action failed: %v\n", alert.Name, err)
+	}
+}
+
+// Stage returns the alerter as a pipeline stage
+func (a *Alerter) Stage() Stage {
+	return Transform(a.Observe)
+}
+
+// Fired returns how many times the alert has fired
+func (a *Alerter) Fired() int64 {
+	return a.fired.Load()
+}
+
+// Failed returns how many of the alert's actions failed
+func (a *Alerter) Failed() int64 {
+	return a.failed.Load()
+}
+
+// NewWebhookAction returns an action that POSTs the alert as JSON to url.
+// A nil client uses http.DefaultClient.
+func NewWebhookAction(url string, client *http.Client) AlertAction {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context, alert Alert) error {
+		body, err := json.Marshal(alert)
+		if err != nil {
+			return fmt.Errorf("failed to marshal alert: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send webhook: %w", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook returned %s", resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// fakeClock is a settable time source for the alerter
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func alertEntry(level models.LogLevel, msg string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Level = level
+	entry.Message = msg
+	return entry
+}
+
+// webhookServer records the alerts POSTed to it
+func webhookServer(t *testing.T) (*httptest.Server, <-chan Alert) {
+	t.Helper()
+	alerts := make(chan Alert, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Invalid webhook body: %v", err)
+		}
+		alerts <- alert
+	}))
+	t.Cleanup(server.Close)
+	return server, alerts
+}
+
+// expectNoAlert fails if an alert arrives shortly
+func expectNoAlert(t *testing.T, alerts <-chan Alert) {
+	t.Helper()
+	select {
+	case alert := <-alerts:
+		t.Fatalf("Expected no alert, got %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func receiveAlert(t *testing.T, alerts <-chan Alert) Alert {
+	t.Helper()
+	select {
+	case alert := <-alerts:
+		return alert
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the webhook")
+		return Alert{}
+	}
+}
+
+func TestAlerter_FiresOnceThenCoolsDown(t *testing.T) {
+	server, alerts := webhookServer(t)
+	clock := &fakeClock{t: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)}
+
+	alerter, err := NewAlerter(AlerterOptions{
+		Name:       "error-spike",
+		Match:      []Predicate{FilterMinLevel(models.LevelError)},
+		Threshold:  3,
+		Window:     time.Minute,
+		Cooldown:   5 * time.Minute,
+		MaxSamples: 2,
+		Action:     NewWebhookAction(server.URL, nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerter.now = clock.now
+	stage := alerter.Stage()
+
+	observe := func(entry *models.LogEntry) {
+		if _, keep := stage(entry); !keep {
+			t.Fatal("Expected the alerter to keep every entry")
+		}
+	}
+
+	observe(alertEntry(models.LevelError, "db timeout 1"))
+	observe(alertEntry(models.LevelInfo, "request served"))
+	clock.advance(10 * time.Second)
+	observe(alertEntry(models.LevelError, "db timeout 2"))
+	expectNoAlert(t, alerts)
+
+	clock.advance(10 * time.Second)
+	observe(alertEntry(models.LevelCritical, "db timeout 3"))
+	alert := receiveAlert(t, alerts)
+	if alert.Name != "error-spike" || alert.Count != 3 || alert.Threshold != 3 || alert.Window != "1m0s" {
+		t.Errorf("Unexpected alert %+v", alert)
+	}
+	if len(alert.Samples) != 2 || alert.Samples[0] != "db timeout 2" || alert.Samples[1] != "db timeout 3" {
+		t.Errorf("Expected the latest 2 messages as samples, got %q", alert.Samples)
+	}
+	if !alert.WindowEnd.Equal(clock.t) || !alert.WindowStart.Equal(clock.t.Add(-time.Minute)) {
+		t.Errorf("Expected the window to end now, got %v to %v", alert.WindowStart, alert.WindowEnd)
+	}
+
+	// Still above the threshold, but within the cooldown
+	for i := 0; i < 10; i++ {
+		clock.advance(time.Second)
+		observe(alertEntry(models.LevelError, "db timeout"))
+	}
+	expectNoAlert(t, alerts)
+	if alerter.Fired() != 1 {
+		t.Errorf("Expected 1 alert, got %d", alerter.Fired())
+	}
+
+	// After the cooldown the old errors are out of the window
+	clock.advance(5 * time.Minute)
+	observe(alertEntry(models.LevelError, "db timeout"))
+	expectNoAlert(t, alerts)
+
+	observe(alertEntry(models.LevelError, "db timeout"))
+	observe(alertEntry(models.LevelError, "db timeout"))
+	if alert := receiveAlert(t, alerts); alert.Count != 3 {
+		t.Errorf("Expected a second alert counting 3, got %+v", alert)
+	}
+	if alerter.Fired() != 2 {
+		t.Errorf("Expected 2 alerts, got %d", alerter.Fired())
+	}
+}
+
+func TestAlerter_SlidingWindow(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	fired := make(chan Alert, 10)
+	alerter, err := NewAlerter(AlerterOptions{
+		Threshold: 2,
+		Window:    time.Minute,
+		Action: func(ctx context.Context, alert Alert) error {
+			fired <- alert
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerter.now = clock.now
+
+	// Two errors further apart than the window never fire
+	for i := 0; i < 5; i++ {
+		alerter.Observe(alertEntry(models.LevelError, "spread out"))
+		clock.advance(time.Minute)
+	}
+	expectNoAlert(t, fired)
+	if alerter.Fired() != 0 {
+		t.Errorf("Expected no alerts, got %d", alerter.Fired())
+	}
+}
+
+func TestAlerter_WebhookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewWebhookAction(server.URL, nil)(context.Background(), Alert{Name: "test"})
+	if err == nil {
+		t.Error("Expected an error for a failed webhook")
+	}
+}
+
+func TestNewAlerter_Invalid(t *testing.T) {
+	action := func(context.Context, Alert) error { return nil }
+	if _, err := NewAlerter(AlerterOptions{Action: action}); err == nil {
+		t.Error("Expected an error without a threshold")
+	}
+	if _, err := NewAlerter(AlerterOptions{Threshold: 1}); err == nil {
+		t.Error("Expected an error without an action")
+	}
+}
@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"container/list"
+	"context"
+	"hash/fnv"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// FieldDuplicateCount is set on an entry that stands for several identical ones
+const FieldDuplicateCount = "duplicate_count"
+
+// DeduperOptions configures a Deduper
+type DeduperOptions struct {
+	// Window is how long identical entries are collapsed after the first one
+	Window time.Duration
+
+	// MaxKeys bounds how many distinct entries are tracked at once. When
+	// full, the oldest group is emitted early to make room.
+	MaxKeys int
+}
+
+// dedupGroup is an entry being held back while its duplicates are counted
+type dedupGroup struct {
+	key     uint64
+	entry   *models.LogEntry
+	count   int
+	expires time.Time
+}
+
+// Deduper collapses identical entries (same level, source and message) seen
+// within a time window into one entry carrying a duplicate count
+type Deduper struct {
+	opts   DeduperOptions
+	groups map[uint64]*list.Element
+	order  *list.List // oldest group first
+}
+
+// NewDeduper creates a deduper
+func NewDeduper(opts DeduperOptions) *Deduper {
+	if opts.Window <= 0 {
+		opts.Window = time.Second
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = 10000
+	}
+
+	return &Deduper{
+		opts:   opts,
+		groups: make(map[uint64]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// Run reads entries from in and writes deduplicated entries to out until in
+// is closed or ctx is done, then emits what it still holds and closes out.
+// After cancellation held entries are only emitted if out has room.
+func (d *Deduper) Run(ctx context.Context, in <-chan *models.LogEntry, out chan<- *models.LogEntry) {
+	defer close(out)
+
+	tick := d.opts.Window / 4
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.drain(out)
+			return
+
+		case entry, ok := <-in:
+			if !ok {
+				d.flush(ctx, out, time.Time{})
+				return
+			}
+			if evicted := d.add(entry, time.Now()); evicted != nil {
+				if !emit(ctx, out, evicted) {
+					return
+				}
+			}
+
+		case now := <-ticker.C:
+			if !d.flush(ctx, out, now) {
+				return
+			}
+		}
+	}
+}
+
+// add records an entry. It returns a group evicted to stay within MaxKeys.
+func (d *Deduper) add(entry *models.LogEntry, now time.Time) *models.LogEntry {
+	key := dedupKey(entry)
+
+	if elem, ok := d.groups[key]; ok {
+		group := elem.Value.(*dedupGroup)
+		group.count++
+		group.entry.MergeAcks(entry)
+		return nil
+	}
+
+	var evicted *models.LogEntry
+	if d.order.Len() >= d.opts.MaxKeys {
+		evicted = d.remove(d.order.Front())
+	}
+
+	group := &dedupGroup{key: key, entry: entry, count: 1, expires: now.Add(d.opts.Window)}
+	d.groups[key] = d.order.PushBack(group)
+	return evicted
+}
+
+// flush emits every group whose window closed before now, or all groups
+// if now is zero. Returns false if ctx was cancelled while emitting.
+func (d *Deduper) flush(ctx context.Context, out chan<- *models.LogEntry, now time.Time) bool {
+	for elem := d.order.Front(); elem != nil; elem = d.order.Front() {
+		if !now.IsZero() && elem.Value.(*dedupGroup).expires.After(now) {
+			// Groups are in expiry order, the rest are still open
+			break
+		}
+		if !emit(ctx, out, d.remove(elem)) {
+			return false
+		}
+	}
+	return true
+}
+
+// drain emits held groups without blocking, oldest first
+func (d *Deduper) drain(out chan<- *models.LogEntry) {
+	for elem := d.order.Front(); elem != nil; elem = d.order.Front() {
+		select {
+		case out <- d.remove(elem):
+		default:
+			return
+		}
+	}
+}
+
+// remove drops a group and returns its aggregated entry
+func (d *Deduper) remove(elem *list.Element) *models.LogEntry {
+	group := d.order.Remove(elem).(*dedupGroup)
+	delete(d.groups, group.key)
+
+	if group.count > 1 {
+		group.entry.Fields[FieldDuplicateCount] = group.count
+	}
+	return group.entry
+}
+
+// emit sends an entry unless ctx is cancelled first
+func emit(ctx context.Context, out chan<- *models.LogEntry, entry *models.LogEntry) bool {
+	select {
+	case out <- entry:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// dedupKey hashes the parts of an entry that make it a duplicate
+func dedupKey(entry *models.LogEntry) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(entry.Level))
+	h.Write([]byte{0})
+	h.Write([]byte(entry.Source))
+	h.Write([]byte{0})
+	h.Write([]byte(entry.Message))
+	return h.Sum64()
+}
@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func dedupEntry(msg string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Source = "app"
+	entry.Message = msg
+	return entry
+}
+
+// collect reads from out until it is closed
+func collect(t *testing.T, out <-chan *models.LogEntry) []*models.LogEntry {
+	t.Helper()
+
+	var entries []*models.LogEntry
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case entry, ok := <-out:
+			if !ok {
+				return entries
+			}
+			entries = append(entries, entry)
+		case <-timeout:
+			t.Fatal("timeout waiting for deduper to finish")
+		}
+	}
+}
+
+func TestDeduper_CollapsesDuplicates(t *testing.T) {
+	deduper := NewDeduper(DeduperOptions{Window: time.Minute})
+
+	in := make(chan *models.LogEntry, 200)
+	out := make(chan *models.LogEntry, 200)
+	go deduper.Run(context.Background(), in, out)
+
+	for i := 0; i < 100; i++ {
+		in <- dedupEntry("connection reset")
+	}
+	in <- dedupEntry("something else")
+	close(in)
+
+	entries := collect(t, out)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Message != "connection reset" || entries[0].Fields[FieldDuplicateCount] != 100 {
+		t.Errorf("Expected duplicate_count=100, got %v", entries[0].Fields[FieldDuplicateCount])
+	}
+	if _, ok := entries[1].Fields[FieldDuplicateCount]; ok {
+		t.Error("Expected no duplicate_count on a unique entry")
+	}
+}
+
+func TestDeduper_KeyIncludesLevelAndSource(t *testing.T) {
+	deduper := NewDeduper(DeduperOptions{Window: time.Minute})
+
+	in := make(chan *models.LogEntry, 10)
+	out := make(chan *models.LogEntry, 10)
+	go deduper.Run(context.Background(), in, out)
+
+	a := dedupEntry("same")
+	b := dedupEntry("same")
+	b.Level = models.LevelError
+	c := dedupEntry("same")
+	c.Source = "other"
+	in <- a
+	in <- b
+	in <- c
+	close(in)
+
+	if entries := collect(t, out); len(entries) != 3 {
+		t.Errorf("Expected 3 distinct entries, got %d", len(entries))
+	}
+}
+
+func TestDeduper_WindowFlush(t *testing.T) {
+	deduper := NewDeduper(DeduperOptions{Window: 50 * time.Millisecond})
+
+	in := make(chan *models.LogEntry)
+	out := make(chan *models.LogEntry, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go deduper.Run(ctx, in, out)
+
+	for i := 0; i < 5; i++ {
+		in <- dedupEntry("tick")
+	}
+
+	select {
+	case entry := <-out:
+		if entry.Fields[FieldDuplicateCount] != 5 {
+			t.Errorf("Expected duplicate_count=5, got %v", entry.Fields[FieldDuplicateCount])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected entry to be emitted when the window closed")
+	}
+
+	// A new window starts after the flush
+	in <- dedupEntry("tick")
+	select {
+	case entry := <-out:
+		if _, ok := entry.Fields[FieldDuplicateCount]; ok {
+			t.Error("Expected a fresh window to start with a single entry")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected second window to be emitted")
+	}
+}
+
+func TestDeduper_EvictsOldestWhenFull(t *testing.T) {
+	deduper := NewDeduper(DeduperOptions{Window: time.Minute, MaxKeys: 3})
+
+	in := make(chan *models.LogEntry)
+	out := make(chan *models.LogEntry, 10)
+	go deduper.Run(context.Background(), in, out)
+
+	for i := 0; i < 4; i++ {
+		in <- dedupEntry(fmt.Sprintf("msg %d", i))
+	}
+
+	select {
+	case entry := <-out:
+		if entry.Message != "msg 0" {
+			t.Errorf("Expected oldest entry to be evicted, got %q", entry.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected eviction when MaxKeys is exceeded")
+	}
+
+	close(in)
+	if entries := collect(t, out); len(entries) != 3 {
+		t.Errorf("Expected remaining 3 entries on close, got %d", len(entries))
+	}
+	if len(deduper.groups) != 0 || deduper.order.Len() != 0 {
+		t.Error("Expected deduper state to be empty after close")
+	}
+}
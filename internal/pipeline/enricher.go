@@ -0,0 +1,82 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// FieldHostname holds the name of the host the collector runs on
+const FieldHostname = "hostname"
+
+// EnricherOptions configures an Enricher
+type EnricherOptions struct {
+	// Fields are added to every entry. Values may reference environment
+	// variables as ${VAR}, expanded once when the enricher is created.
+	Fields map[string]string
+
+	// Hostname adds the collector's hostname as the hostname field
+	Hostname bool
+}
+
+// Enricher tags every entry with static fields such as environment or
+// datacenter. Fields already set on an entry are never overwritten.
+type Enricher struct {
+	fields map[string]string
+}
+
+// NewEnricher creates an enricher, expanding environment variables and
+// looking up the hostname if asked to
+func NewEnricher(opts EnricherOptions) (*Enricher, error) {
+	fields := make(map[string]string, len(opts.Fields)+1)
+	for key, value := range opts.Fields {
+		fields[key] = os.ExpandEnv(value)
+	}
+
+	if opts.Hostname {
+		if _, ok := fields[FieldHostname]; !ok {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get hostname: %w", err)
+			}
+			fields[FieldHostname] = hostname
+		}
+	}
+
+	return &Enricher{fields: fields}, nil
+}
+
+// Apply adds the enricher's fields that the entry doesn't already have
+func (e *Enricher) Apply(entry *models.LogEntry) {
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]interface{}, len(e.fields))
+	}
+	for key, value := range e.fields {
+		if _, ok := entry.Fields[key]; !ok {
+			entry.Fields[key] = value
+		}
+	}
+}
+
+// Run reads entries from in, enriches them and writes them to out until in
+// is closed or ctx is done, then closes out
+func (e *Enricher) Run(ctx context.Context, in <-chan *models.LogEntry, out chan<- *models.LogEntry) {
+	defer close(out)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-in:
+			if !ok {
+				return
+			}
+			e.Apply(entry)
+			if !emit(ctx, out, entry) {
+				return
+			}
+		}
+	}
+}
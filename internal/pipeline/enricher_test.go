@@ -0,0 +1,125 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestEnricher_EnvExpansion(t *testing.T) {
+	t.Setenv("LOGFLUX_TEST_DC", "eu-west-1")
+
+	e, err := NewEnricher(EnricherOptions{Fields: map[string]string{
+		"datacenter":  "${LOGFLUX_TEST_DC}",
+		"environment": "production",
+		"zone":        "${LOGFLUX_TEST_DC}-a",
+		"unset":       "${LOGFLUX_TEST_UNSET}",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := models.NewLogEntry()
+	e.Apply(entry)
+
+	expected := map[string]interface{}{
+		"datacenter":  "eu-west-1",
+		"environment": "production",
+		"zone":        "eu-west-1-a",
+		"unset":       "",
+	}
+	for key, value := range expected {
+		if entry.Fields[key] != value {
+			t.Errorf("Expected %s=%q, got %v", key, value, entry.Fields[key])
+		}
+	}
+}
+
+func TestEnricher_DoesNotOverwrite(t *testing.T) {
+	e, err := NewEnricher(EnricherOptions{Fields: map[string]string{
+		"environment": "production",
+		"team":        "payments",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Fields["environment"] = "staging"
+	e.Apply(entry)
+
+	if entry.Fields["environment"] != "staging" {
+		t.Errorf("Expected existing field to be kept, got %v", entry.Fields["environment"])
+	}
+	if entry.Fields["team"] != "payments" {
+		t.Errorf("Expected team to be added, got %v", entry.Fields["team"])
+	}
+
+	// Entries without fields get a map
+	bare := &models.LogEntry{Message: "no fields"}
+	e.Apply(bare)
+	if bare.Fields["team"] != "payments" {
+		t.Errorf("Expected team on an entry without fields, got %v", bare.Fields)
+	}
+}
+
+func TestEnricher_Hostname(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("hostname unavailable: %v", err)
+	}
+
+	e, err := NewEnricher(EnricherOptions{Hostname: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := models.NewLogEntry()
+	e.Apply(entry)
+	if entry.Fields[FieldHostname] != hostname {
+		t.Errorf("Expected hostname %q, got %v", hostname, entry.Fields[FieldHostname])
+	}
+
+	// A configured hostname wins over the detected one
+	e, err = NewEnricher(EnricherOptions{Hostname: true, Fields: map[string]string{FieldHostname: "web-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry = models.NewLogEntry()
+	e.Apply(entry)
+	if entry.Fields[FieldHostname] != "web-1" {
+		t.Errorf("Expected configured hostname, got %v", entry.Fields[FieldHostname])
+	}
+}
+
+func TestEnricher_Run(t *testing.T) {
+	e, err := NewEnricher(EnricherOptions{Fields: map[string]string{"environment": "production"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan *models.LogEntry, 1)
+	out := make(chan *models.LogEntry, 1)
+	in <- models.NewLogEntry()
+	close(in)
+
+	done := make(chan struct{})
+	go func() {
+		e.Run(context.Background(), in, out)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after input closed")
+	}
+
+	entry := <-out
+	if entry.Fields["environment"] != "production" {
+		t.Errorf("Expected enriched entry, got %v", entry.Fields)
+	}
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Predicate reports whether an entry should continue down the pipeline
+type Predicate func(*models.LogEntry) bool
+
+// FilterMinLevel keeps entries at or above the given level
+func FilterMinLevel(min models.LogLevel) Predicate {
+	return func(entry *models.LogEntry) bool {
+		return entry.Level.AtLeast(min)
+	}
+}
+
+// FilterSource keeps entries whose source matches the pattern
+func FilterSource(pattern *regexp.Regexp) Predicate {
+	return func(entry *models.LogEntry) bool {
+		return pattern.MatchString(entry.Source)
+	}
+}
+
+// FilterField keeps entries whose field has the given value, compared as
+// text so a field decoded as the number 500 matches "500"
+func FilterField(name, value string) Predicate {
+	return func(entry *models.LogEntry) bool {
+		v, ok := entry.Fields[name]
+		return ok && v != nil && fmt.Sprint(v) == value
+	}
+}
+
+// Filter applies a list of predicates and counts the entries it drops
+type Filter struct {
+	predicates []Predicate
+	dropped    atomic.Int64
+}
+
+// NewFilter creates a filter that keeps entries matching every predicate
+func NewFilter(predicates ...Predicate) *Filter {
+	return &Filter{
+		predicates: predicates,
+	}
+}
+
+// Allow reports whether the entry passes all predicates.
+// Rejected entries are counted as dropped.
+func (f *Filter) Allow(entry *models.LogEntry) bool {
+	for _, p := range f.predicates {
+		if !p(entry) {
+			f.dropped.Add(1)
+			return false
+		}
+	}
+	return true
+}
+
+// Dropped returns how many entries have been rejected so far
+func (f *Filter) Dropped() int64 {
+	return f.dropped.Load()
+}
+
+// LevelFilter is a minimum level that can be changed while entries flow
+// through it, so verbosity can be adjusted without a restart
+type LevelFilter struct {
+	min atomic.Value // models.LogLevel
+}
+
+// NewLevelFilter creates a level filter. An empty level keeps everything.
+func NewLevelFilter(min models.LogLevel) *LevelFilter {
+	f := &LevelFilter{}
+	f.min.Store(min)
+	return f
+}
+
+// MinLevel returns the current minimum level, empty when nothing is filtered
+func (f *LevelFilter) MinLevel() models.LogLevel {
+	return f.min.Load().(models.LogLevel)
+}
+
+// SetMinLevel changes the minimum level. Entries checked afterwards use
+// the new level, entries already past the filter are unaffected.
+func (f *LevelFilter) SetMinLevel(min models.LogLevel) {
+	f.min.Store(min)
+}
+
+// Predicate returns a predicate that checks entries against the current
+// minimum level
+func (f *LevelFilter) Predicate() Predicate {
+	return func(entry *models.LogEntry) bool {
+		min := f.MinLevel()
+		return min == "" || entry.Level.AtLeast(min)
+	}
+}
@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func newEntry(level models.LogLevel) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Level = level
+	return entry
+}
+
+func TestFilterMinLevel(t *testing.T) {
+	tests := []struct {
+		min      models.LogLevel
+		level    models.LogLevel
+		expected bool
+	}{
+		{models.LevelInfo, models.LevelDebug, false},
+		{models.LevelInfo, models.LevelInfo, true},
+		{models.LevelInfo, models.LevelError, true},
+		{models.LevelWarning, models.LevelInfo, false},
+		{models.LevelCritical, models.LevelCritical, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.level)+"@"+string(tt.min), func(t *testing.T) {
+			keep := FilterMinLevel(tt.min)
+			if got := keep(newEntry(tt.level)); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilter_CountsDropped(t *testing.T) {
+	filter := NewFilter(FilterMinLevel(models.LevelInfo))
+
+	if filter.Allow(newEntry(models.LevelDebug)) {
+		t.Error("DEBUG entry should be dropped at MinLevel=INFO")
+	}
+	if !filter.Allow(newEntry(models.LevelError)) {
+		t.Error("ERROR entry should pass at MinLevel=INFO")
+	}
+	if filter.Allow(newEntry(models.LevelDebug)) {
+		t.Error("DEBUG entry should be dropped at MinLevel=INFO")
+	}
+
+	if filter.Dropped() != 2 {
+		t.Errorf("Expected 2 dropped entries, got %d", filter.Dropped())
+	}
+}
+
+func TestFilter_AllPredicatesMustPass(t *testing.T) {
+	fromAPI := func(entry *models.LogEntry) bool {
+		return entry.Source == "api"
+	}
+	filter := NewFilter(FilterMinLevel(models.LevelWarning), fromAPI)
+
+	entry := newEntry(models.LevelError)
+	entry.Source = "worker"
+	if filter.Allow(entry) {
+		t.Error("Entry failing one predicate should be dropped")
+	}
+
+	entry.Source = "api"
+	if !filter.Allow(entry) {
+		t.Error("Entry passing every predicate should be kept")
+	}
+
+	// No predicates keeps everything
+	if !NewFilter().Allow(newEntry(models.LevelDebug)) {
+		t.Error("Empty filter should keep every entry")
+	}
+}
+
+func TestLevelFilter_SetMinLevel(t *testing.T) {
+	level := NewLevelFilter("")
+	filter := NewFilter(level.Predicate())
+
+	if !filter.Allow(newEntry(models.LevelDebug)) {
+		t.Error("DEBUG entry should pass without a minimum level")
+	}
+
+	level.SetMinLevel(models.LevelError)
+	if level.MinLevel() != models.LevelError {
+		t.Errorf("Expected ERROR, got %q", level.MinLevel())
+	}
+	if filter.Allow(newEntry(models.LevelWarning)) {
+		t.Error("WARNING entry should be dropped once MinLevel=ERROR")
+	}
+	if !filter.Allow(newEntry(models.LevelCritical)) {
+		t.Error("CRITICAL entry should pass at MinLevel=ERROR")
+	}
+
+	level.SetMinLevel(models.LevelDebug)
+	if !filter.Allow(newEntry(models.LevelWarning)) {
+		t.Error("WARNING entry should pass again at MinLevel=DEBUG")
+	}
+}
@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// FlattenerOptions configures a Flattener
+type FlattenerOptions struct {
+	models.FlattenOptions
+
+	// Expand nests dotted keys instead of flattening nested maps
+	Expand bool
+}
+
+// Flattener reshapes every entry's fields for sinks that need flat
+// documents, such as CSV files, or the inverse for ones that want them
+// nested
+type Flattener struct {
+	opts FlattenerOptions
+}
+
+// NewFlattener creates a flattener
+func NewFlattener(opts FlattenerOptions) *Flattener {
+	return &Flattener{opts: opts}
+}
+
+// Apply flattens or expands the entry's fields
+func (f *Flattener) Apply(entry *models.LogEntry) {
+	if len(entry.Fields) == 0 {
+		return
+	}
+	if f.opts.Expand {
+		entry.Fields = models.ExpandFields(entry.Fields, f.opts.FlattenOptions)
+	} else {
+		entry.Fields = models.FlattenFields(entry.Fields, f.opts.FlattenOptions)
+	}
+}
+
+// Stage returns the flattener as a pipeline stage
+func (f *Flattener) Stage() Stage {
+	return Transform(f.Apply)
+}
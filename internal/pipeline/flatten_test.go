@@ -0,0 +1,36 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestFlattener(t *testing.T) {
+	entry := models.NewLogEntry()
+	entry.Fields["http"] = map[string]interface{}{
+		"request": map[string]interface{}{"method": "GET"},
+	}
+
+	NewFlattener(FlattenerOptions{}).Apply(entry)
+	if entry.Fields["http.request.method"] != "GET" || len(entry.Fields) != 1 {
+		t.Fatalf("Expected flattened fields, got %v", entry.Fields)
+	}
+
+	expand := NewFlattener(FlattenerOptions{Expand: true})
+	if _, keep := expand.Stage()(entry); !keep {
+		t.Fatal("Expected the stage to keep the entry")
+	}
+	http, _ := entry.Fields["http"].(map[string]interface{})
+	request, _ := http["request"].(map[string]interface{})
+	if request["method"] != "GET" {
+		t.Errorf("Expected expanded fields, got %v", entry.Fields)
+	}
+
+	// Entries without fields are left alone
+	empty := &models.LogEntry{}
+	expand.Apply(empty)
+	if empty.Fields != nil {
+		t.Errorf("Expected nil fields kept, got %v", empty.Fields)
+	}
+}
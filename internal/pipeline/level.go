@@ -0,0 +1,65 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// LevelInferrerOptions configures a LevelInferrer
+type LevelInferrerOptions struct {
+	// Rules are checked in order against the message. Defaults to
+	// models.DefaultLevelRules.
+	Rules models.LevelRules
+
+	// Overwrite infers the level of every entry. Otherwise only entries at
+	// INFO, the level sources give lines they know nothing about, are
+	// changed.
+	Overwrite bool
+}
+
+// LevelInferrer sets entries' levels from keywords in their messages, for
+// sources such as plain log files that don't carry a level of their own
+type LevelInferrer struct {
+	opts LevelInferrerOptions
+}
+
+// NewLevelInferrer creates a level inferrer
+func NewLevelInferrer(opts LevelInferrerOptions) *LevelInferrer {
+	if len(opts.Rules) == 0 {
+		opts.Rules = models.DefaultLevelRules
+	}
+	return &LevelInferrer{opts: opts}
+}
+
+// Apply sets the entry's level from the first rule matching its message,
+// leaving it alone when none does
+func (li *LevelInferrer) Apply(entry *models.LogEntry) {
+	if !li.opts.Overwrite && entry.Level != models.LevelInfo && entry.Level != "" {
+		return
+	}
+	if level, ok := li.opts.Rules.Infer(entry.Message); ok {
+		entry.Level = level
+	}
+}
+
+// Run reads entries from in, sets their levels and writes them to out until
+// in is closed or ctx is done, then closes out
+func (li *LevelInferrer) Run(ctx context.Context, in <-chan *models.LogEntry, out chan<- *models.LogEntry) {
+	defer close(out)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-in:
+			if !ok {
+				return
+			}
+			li.Apply(entry)
+			if !emit(ctx, out, entry) {
+				return
+			}
+		}
+	}
+}
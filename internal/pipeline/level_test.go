@@ -0,0 +1,44 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestLevelInferrer_Apply(t *testing.T) {
+	rules := models.LevelRules{
+		{Keyword: "panic", Level: models.LevelCritical},
+		{Keyword: "retry", Level: models.LevelWarning},
+	}
+
+	tests := []struct {
+		name      string
+		rules     models.LevelRules
+		overwrite bool
+		level     models.LogLevel
+		message   string
+		expected  models.LogLevel
+	}{
+		{"default rules", nil, false, models.LevelInfo, "connection error", models.LevelError},
+		{"custom rules", rules, false, models.LevelInfo, "will retry in 5s", models.LevelWarning},
+		{"first rule wins", rules, false, models.LevelInfo, "panic, no retry", models.LevelCritical},
+		{"no match", rules, false, models.LevelInfo, "connection error", models.LevelInfo},
+		{"level kept", rules, false, models.LevelDebug, "panic", models.LevelDebug},
+		{"overwrite", rules, true, models.LevelDebug, "panic", models.LevelCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inferrer := NewLevelInferrer(LevelInferrerOptions{Rules: tt.rules, Overwrite: tt.overwrite})
+			entry := models.NewLogEntry()
+			entry.Level = tt.level
+			entry.Message = tt.message
+
+			inferrer.Apply(entry)
+			if entry.Level != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, entry.Level)
+			}
+		})
+	}
+}
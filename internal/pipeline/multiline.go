@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// MultilineOptions configures a MultilineCombiner
+type MultilineOptions struct {
+	// StartPattern matches the first line of an entry. Lines that don't
+	// match are appended to the previous entry from the same source.
+	StartPattern string
+
+	// MaxLines caps how many lines are combined into one entry
+	MaxLines int
+
+	// MaxWait caps how long an entry is held waiting for more lines
+	MaxWait time.Duration
+}
+
+// pendingEntry is an entry still collecting continuation lines
+type pendingEntry struct {
+	entry   *models.LogEntry
+	lines   int
+	started time.Time
+}
+
+// MultilineCombiner stitches entries that span several physical lines, such
+// as stack traces, back into one entry
+type MultilineCombiner struct {
+	start   *regexp.Regexp
+	opts    MultilineOptions
+	pending map[string]*pendingEntry // by source
+}
+
+// NewMultilineCombiner creates a combiner for the given start pattern
+func NewMultilineCombiner(opts MultilineOptions) (*MultilineCombiner, error) {
+	if opts.StartPattern == "" {
+		return nil, fmt.Errorf("start pattern required")
+	}
+	start, err := regexp.Compile(opts.StartPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start pattern: %w", err)
+	}
+	if opts.MaxLines <= 0 {
+		opts.MaxLines = 500
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = time.Second
+	}
+
+	return &MultilineCombiner{
+		start:   start,
+		opts:    opts,
+		pending: make(map[string]*pendingEntry),
+	}, nil
+}
+
+// Run reads entries from in and writes combined entries to out until in is
+// closed or ctx is done, then emits what it still holds and closes out.
+// After cancellation held entries are only emitted if out has room.
+func (mc *MultilineCombiner) Run(ctx context.Context, in <-chan *models.LogEntry, out chan<- *models.LogEntry) {
+	defer close(out)
+
+	tick := mc.opts.MaxWait / 4
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			for source, p := range mc.pending {
+				select {
+				case out <- p.entry:
+					delete(mc.pending, source)
+				default:
+					return
+				}
+			}
+			return
+
+		case entry, ok := <-in:
+			if !ok {
+				mc.flushExpired(ctx, out, time.Time{})
+				return
+			}
+			for _, done := range mc.add(entry, time.Now()) {
+				if !emit(ctx, out, done) {
+					return
+				}
+			}
+
+		case now := <-ticker.C:
+			if !mc.flushExpired(ctx, out, now) {
+				return
+			}
+		}
+	}
+}
+
+// add takes the next line and returns any entries that are now complete
+func (mc *MultilineCombiner) add(entry *models.LogEntry, now time.Time) []*models.LogEntry {
+	var done []*models.LogEntry
+
+	p, ok := mc.pending[entry.Source]
+	if ok && !mc.start.MatchString(entry.Message) {
+		appendLine(p.entry, entry.Message)
+		p.entry.MergeAcks(entry)
+		p.lines++
+		if p.lines >= mc.opts.MaxLines {
+			delete(mc.pending, entry.Source)
+			done = append(done, p.entry)
+		}
+		return done
+	}
+
+	// A start line completes the previous entry. A continuation line with
+	// nothing before it starts an entry of its own.
+	if ok {
+		done = append(done, p.entry)
+	}
+	mc.pending[entry.Source] = &pendingEntry{entry: entry, lines: 1, started: now}
+	if mc.opts.MaxLines == 1 {
+		delete(mc.pending, entry.Source)
+		done = append(done, entry)
+	}
+	return done
+}
+
+// flushExpired emits entries held longer than MaxWait before now, or all of
+// them if now is zero. Returns false if ctx was cancelled while emitting.
+func (mc *MultilineCombiner) flushExpired(ctx context.Context, out chan<- *models.LogEntry, now time.Time) bool {
+	for source, p := range mc.pending {
+		if !now.IsZero() && now.Sub(p.started) < mc.opts.MaxWait {
+			continue
+		}
+		delete(mc.pending, source)
+		if !emit(ctx, out, p.entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendLine adds a continuation line to the entry's message
+func appendLine(entry *models.LogEntry, line string) {
+	if entry.Message != "" && !strings.HasSuffix(entry.Message, "\n") {
+		entry.Message += "\n"
+	}
+	entry.Message += line
+}
@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+const timestampStart = `^\d{4}-\d{2}-\d{2} `
+
+func lineEntry(source, msg string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Source = source
+	entry.Message = msg
+	return entry
+}
+
+func TestMultilineCombiner_JavaException(t *testing.T) {
+	combiner, err := NewMultilineCombiner(MultilineOptions{StartPattern: timestampStart, MaxWait: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{
+		"2024-01-02 15:04:05 INFO Starting request\n",
+		"2024-01-02 15:04:06 ERROR Request failed\n",
+		"java.lang.NullPointerException: user is null\n",
+		"\tat com.example.UserService.load(UserService.java:42)\n",
+		"\tat com.example.Handler.handle(Handler.java:17)\n",
+		"Caused by: java.io.IOException: connection reset\n",
+		"\t... 12 more\n",
+		"2024-01-02 15:04:07 INFO Next request\n",
+	}
+
+	in := make(chan *models.LogEntry, len(lines))
+	out := make(chan *models.LogEntry, len(lines))
+	for _, line := range lines {
+		in <- lineEntry("app.log", line)
+	}
+	close(in)
+
+	combiner.Run(context.Background(), in, out)
+
+	var entries []*models.LogEntry
+	for entry := range out {
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	expected := strings.Join(lines[1:7], "")
+	if entries[1].Message != expected {
+		t.Errorf("Expected stitched exception:\n%s\ngot:\n%s", expected, entries[1].Message)
+	}
+	if entries[2].Message != lines[7] {
+		t.Errorf("Expected next entry to start fresh, got %q", entries[2].Message)
+	}
+}
+
+func TestMultilineCombiner_PerSource(t *testing.T) {
+	combiner, err := NewMultilineCombiner(MultilineOptions{StartPattern: `^START`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	combiner.add(lineEntry("a", "START a"), now)
+	combiner.add(lineEntry("b", "START b"), now)
+	combiner.add(lineEntry("a", "more a"), now)
+	combiner.add(lineEntry("b", "more b"), now)
+
+	if got := combiner.pending["a"].entry.Message; got != "START a\nmore a" {
+		t.Errorf("Expected lines joined per source, got %q", got)
+	}
+	if got := combiner.pending["b"].entry.Message; got != "START b\nmore b" {
+		t.Errorf("Expected lines joined per source, got %q", got)
+	}
+}
+
+func TestMultilineCombiner_MaxLines(t *testing.T) {
+	combiner, err := NewMultilineCombiner(MultilineOptions{StartPattern: `^START`, MaxLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	var done []*models.LogEntry
+	for _, line := range []string{"never", "matches", "the", "start", "pattern"} {
+		done = append(done, combiner.add(lineEntry("a", line), now)...)
+	}
+
+	if len(done) != 1 {
+		t.Fatalf("Expected 1 entry cut at max lines, got %d", len(done))
+	}
+	if done[0].Message != "never\nmatches\nthe" {
+		t.Errorf("Expected first 3 lines, got %q", done[0].Message)
+	}
+}
+
+func TestMultilineCombiner_MaxWait(t *testing.T) {
+	combiner, err := NewMultilineCombiner(MultilineOptions{StartPattern: `^START`, MaxWait: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make(chan *models.LogEntry)
+	out := make(chan *models.LogEntry, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go combiner.Run(ctx, in, out)
+
+	in <- lineEntry("a", "START only line")
+
+	select {
+	case entry := <-out:
+		if entry.Message != "START only line" {
+			t.Errorf("Unexpected message %q", entry.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected held entry to be emitted after max wait")
+	}
+}
+
+func TestNewMultilineCombiner_Errors(t *testing.T) {
+	if _, err := NewMultilineCombiner(MultilineOptions{}); err == nil {
+		t.Error("Expected error for missing start pattern")
+	}
+	if _, err := NewMultilineCombiner(MultilineOptions{StartPattern: "("}); err == nil {
+		t.Error("Expected error for invalid start pattern")
+	}
+}
@@ -0,0 +1,216 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Parser turns a raw message into an entry. The parsers in the sources
+// package satisfy it.
+type Parser interface {
+	Parse(raw []byte, src string) (*models.LogEntry, error)
+}
+
+// ParsePoolOptions configures a ParsePool
+type ParsePoolOptions struct {
+	// Workers is how many entries are parsed at once. Defaults to the
+	// number of CPUs.
+	Workers int
+
+	// Ordered passes entries on in the order they arrived, holding back
+	// ones parsed early. Otherwise each is passed on as soon as it's parsed.
+	Ordered bool
+
+	// DeadLetter, when set, takes the entries the parser fails on instead
+	// of them being passed on unchanged. With a Marker, those are only ever
+	// entries read raw.
+	DeadLetter *collector.DeadLetter
+
+	// Marker, when set, is the field sources mark the entries they read raw
+	// with. Only those are parsed, with the field removed, and the rest are
+	// passed on as they are. Otherwise every entry is parsed.
+	Marker string
+}
+
+// ParsePool parses entries read raw by their sources across several
+// goroutines, so an expensive parser such as a large regexp doesn't hold
+// up reading. Each entry's message is parsed with its source as src.
+// Entries the parser fails on are passed on unchanged.
+type ParsePool struct {
+	parser Parser
+	opts   ParsePoolOptions
+
+	parsed atomic.Int64
+	failed atomic.Int64
+}
+
+// NewParsePool creates a parse pool
+func NewParsePool(parser Parser, opts ParsePoolOptions) *ParsePool {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	return &ParsePool{parser: parser, opts: opts}
+}
+
+// Parse parses one entry. The parsed entry keeps the raw one's ID, receipt
+// time and acks, along with any fields the parser didn't set. Returns nil
+// when the parser failed and the entry was dead-lettered, and the entry
+// itself when it lacks the marker.
+func (pp *ParsePool) Parse(entry *models.LogEntry) *models.LogEntry {
+	if pp.opts.Marker != "" {
+		if _, ok := entry.Fields[pp.opts.Marker]; !ok {
+			return entry
+		}
+		delete(entry.Fields, pp.opts.Marker)
+	}
+
+	parsed, err := pp.parser.Parse([]byte(entry.Message), entry.Source)
+	if err == nil && parsed == nil {
+		err = errors.New("parser returned no entry")
+	}
+	if err != nil {
+		pp.failed.Add(1)
+		if pp.opts.DeadLetter == nil {
+			return entry
+		}
+		if dlErr := pp.opts.DeadLetter.Record(entry.Source, []byte(entry.Message), err); dlErr != nil {
+			// Better passed on unchanged than lost
+			fmt.Printf("⚠️  %v\n", dlErr)
+			return entry
+		}
+		entry.Ack()
+		return nil
+	}
+	pp.parsed.Add(1)
+
+	parsed.ID = entry.ID
+	parsed.ReceivedAt = entry.ReceivedAt
+	if len(entry.Fields) > 0 && parsed.Fields == nil {
+		parsed.Fields = make(map[string]interface{}, len(entry.Fields))
+	}
+	for key, value := range entry.Fields {
+		if _, ok := parsed.Fields[key]; !ok {
+			parsed.Fields[key] = value
+		}
+	}
+	parsed.MergeAcks(entry)
+	return parsed
+}
+
+// Run reads entries from in, parses them and writes them to out until in
+// is closed or ctx is done, then closes out
+func (pp *ParsePool) Run(ctx context.Context, in <-chan *models.LogEntry, out chan<- *models.LogEntry) {
+	defer close(out)
+	if pp.opts.Ordered {
+		pp.runOrdered(ctx, in, out)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < pp.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case entry, ok := <-in:
+					if !ok {
+						return
+					}
+					parsed := pp.Parse(entry)
+					if parsed != nil && !emit(ctx, out, parsed) {
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// parseJob is an entry waiting for a worker, with the slot its parsed
+// entry goes into
+type parseJob struct {
+	entry  *models.LogEntry
+	parsed chan *models.LogEntry
+}
+
+// runOrdered hands entries to the workers while queueing their slots in
+// arrival order, then passes the parsed entries on in that order
+func (pp *ParsePool) runOrdered(ctx context.Context, in <-chan *models.LogEntry, out chan<- *models.LogEntry) {
+	jobs := make(chan parseJob)
+	// Bounds how far the workers get ahead of the slowest entry
+	slots := make(chan chan *models.LogEntry, pp.opts.Workers*2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < pp.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.parsed <- pp.Parse(job.entry)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(slots)
+		defer close(jobs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case entry, ok := <-in:
+				if !ok {
+					return
+				}
+				job := parseJob{entry: entry, parsed: make(chan *models.LogEntry, 1)}
+				select {
+				case slots <- job.parsed:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case jobs <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	for slot := range slots {
+		var entry *models.LogEntry
+		select {
+		case entry = <-slot:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		if entry != nil && !emit(ctx, out, entry) {
+			wg.Wait()
+			return
+		}
+	}
+	wg.Wait()
+}
+
+// Parsed returns how many entries were parsed
+func (pp *ParsePool) Parsed() int64 {
+	return pp.parsed.Load()
+}
+
+// Failed returns how many entries the parser failed on, passed on
+// unchanged or dead-lettered
+func (pp *ParsePool) Failed() int64 {
+	return pp.failed.Load()
+}
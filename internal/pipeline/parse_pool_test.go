@@ -0,0 +1,304 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// numberParser parses messages holding a number into an entry with the
+// number in Fields, taking longer for some numbers so workers finish out of
+// order. Messages starting with "bad" fail.
+type numberParser struct {
+	delay time.Duration
+}
+
+func (p numberParser) Parse(raw []byte, src string) (*models.LogEntry, error) {
+	message := string(raw)
+	if strings.HasPrefix(message, "bad") {
+		return nil, errors.New("not a number")
+	}
+	n, err := strconv.Atoi(message)
+	if err != nil {
+		return nil, err
+	}
+	if n%7 == 0 {
+		time.Sleep(p.delay)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Source = src
+	entry.Message = "parsed " + message
+	entry.Fields["n"] = n
+	return entry, nil
+}
+
+// runParsePool sends messages through a pool and collects what comes out
+// once the input is closed
+func runParsePool(t *testing.T, pool *ParsePool, messages []string) []*models.LogEntry {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	in := make(chan *models.LogEntry)
+	out := make(chan *models.LogEntry, 10)
+	go pool.Run(ctx, in, out)
+
+	go func() {
+		defer close(in)
+		for _, message := range messages {
+			entry := models.NewLogEntry()
+			entry.Source = "app"
+			entry.Message = message
+			in <- entry
+		}
+	}()
+
+	var entries []*models.LogEntry
+	for entry := range out {
+		entries = append(entries, entry)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Timeout waiting for the pool to finish")
+	}
+	return entries
+}
+
+func TestParsePool_NoEntriesLost(t *testing.T) {
+	messages := make([]string, 1000)
+	for i := range messages {
+		messages[i] = strconv.Itoa(i)
+	}
+
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%v", ordered), func(t *testing.T) {
+			pool := NewParsePool(numberParser{delay: time.Millisecond}, ParsePoolOptions{Workers: 8, Ordered: ordered})
+			entries := runParsePool(t, pool, messages)
+
+			if len(entries) != len(messages) {
+				t.Fatalf("Expected %d entries, got %d", len(messages), len(entries))
+			}
+			seen := make(map[int]bool)
+			inOrder := true
+			for i, entry := range entries {
+				n, _ := entry.Fields["n"].(int)
+				if seen[n] {
+					t.Errorf("Expected each entry once, got %d twice", n)
+				}
+				seen[n] = true
+				if n != i {
+					inOrder = false
+				}
+			}
+			if ordered && !inOrder {
+				t.Error("Expected the entries in the order they arrived")
+			}
+			if pool.Parsed() != int64(len(messages)) || pool.Failed() != 0 {
+				t.Errorf("Expected %d parsed and none failed, got %d and %d", len(messages), pool.Parsed(), pool.Failed())
+			}
+		})
+	}
+}
+
+func TestParsePool_Parse(t *testing.T) {
+	pool := NewParsePool(numberParser{}, ParsePoolOptions{})
+
+	raw := models.NewLogEntry()
+	raw.Source = "app"
+	raw.Message = "42"
+	raw.Fields["remote_addr"] = "10.0.0.1"
+	raw.Fields["n"] = "raw"
+	acked := false
+	raw.OnAck(func() { acked = true })
+
+	parsed := pool.Parse(raw)
+	if parsed == raw || parsed.Message != "parsed 42" || parsed.Source != "app" {
+		t.Fatalf("Expected a parsed entry from app, got %q from %q", parsed.Message, parsed.Source)
+	}
+	if parsed.ID != raw.ID || !parsed.ReceivedAt.Equal(raw.ReceivedAt) {
+		t.Error("Expected the raw entry's ID and receipt time kept")
+	}
+	if parsed.Fields["n"] != 42 || parsed.Fields["remote_addr"] != "10.0.0.1" {
+		t.Errorf("Expected parsed fields to win over the raw ones they share, got %v", parsed.Fields)
+	}
+	parsed.Ack()
+	if !acked {
+		t.Error("Expected acking the parsed entry to ack the raw one")
+	}
+
+	bad := models.NewLogEntry()
+	bad.Message = "bad input"
+	if got := pool.Parse(bad); got != bad {
+		t.Errorf("Expected the entry passed on unchanged, got %q", got.Message)
+	}
+	if pool.Parsed() != 1 || pool.Failed() != 1 {
+		t.Errorf("Expected 1 parsed and 1 failed, got %d and %d", pool.Parsed(), pool.Failed())
+	}
+}
+
+func TestParsePool_Marker(t *testing.T) {
+	pool := NewParsePool(numberParser{}, ParsePoolOptions{Marker: "_unparsed"})
+
+	raw := models.NewLogEntry()
+	raw.Message = "7"
+	raw.Fields["_unparsed"] = true
+	parsed := pool.Parse(raw)
+	if parsed.Message != "parsed 7" {
+		t.Errorf("Expected the marked entry parsed, got %q", parsed.Message)
+	}
+	if _, ok := parsed.Fields["_unparsed"]; ok {
+		t.Error("Expected the marker removed once parsed")
+	}
+
+	// Entries their sources parsed already are left alone
+	done := models.NewLogEntry()
+	done.Message = "8"
+	if got := pool.Parse(done); got != done || got.Message != "8" {
+		t.Errorf("Expected the unmarked entry passed on as it is, got %q", got.Message)
+	}
+	if pool.Parsed() != 1 || pool.Failed() != 0 {
+		t.Errorf("Expected 1 parsed and none failed, got %d and %d", pool.Parsed(), pool.Failed())
+	}
+}
+
+// deadLetterSink records what is dead-lettered to it
+type deadLetterSink struct {
+	written []*models.LogEntry
+}
+
+func (s *deadLetterSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	s.written = append(s.written, entry)
+	return nil
+}
+
+func (s *deadLetterSink) Flush() error { return nil }
+func (s *deadLetterSink) Name() string { return "dead-letter" }
+
+func TestParsePool_DeadLetter(t *testing.T) {
+	sink := &deadLetterSink{}
+	pool := NewParsePool(numberParser{}, ParsePoolOptions{DeadLetter: collector.NewDeadLetter(sink)})
+
+	bad := models.NewLogEntry()
+	bad.Source = "app"
+	bad.Message = "bad input"
+	acked := false
+	bad.OnAck(func() { acked = true })
+
+	if got := pool.Parse(bad); got != nil {
+		t.Errorf("Expected the entry dead-lettered instead of passed on, got %q", got.Message)
+	}
+	if !acked {
+		t.Error("Expected the dead-lettered entry acked")
+	}
+	if len(sink.written) != 1 {
+		t.Fatalf("Expected 1 dead-lettered entry, got %d", len(sink.written))
+	}
+	if entry := sink.written[0]; entry.Message != "bad input" || entry.Source != "app" || entry.Fields[collector.FieldParseError] != "not a number" {
+		t.Errorf("Expected the raw input with its error, got %q from %q with %v", entry.Message, entry.Source, entry.Fields)
+	}
+
+	entries := runParsePool(t, pool, []string{"1", "bad", "2"})
+	if len(entries) != 2 {
+		t.Errorf("Expected only the parsed entries out of the pool, got %d", len(entries))
+	}
+	if pool.Failed() != 2 {
+		t.Errorf("Expected 2 failed, got %d", pool.Failed())
+	}
+}
+
+func TestParsePool_DeadLettersOnlyMarked(t *testing.T) {
+	sink := &deadLetterSink{}
+	pool := NewParsePool(numberParser{}, ParsePoolOptions{DeadLetter: collector.NewDeadLetter(sink), Marker: "_unparsed"})
+
+	// A source that parsed its own lines can still carry a message the
+	// pool's parser rejects
+	parsed := models.NewLogEntry()
+	parsed.Message = "bad, but parsed by its source"
+	if got := pool.Parse(parsed); got != parsed {
+		t.Error("Expected the unmarked entry passed on")
+	}
+
+	raw := models.NewLogEntry()
+	raw.Message = "bad raw line"
+	raw.Fields["_unparsed"] = true
+	if got := pool.Parse(raw); got != nil {
+		t.Errorf("Expected the marked entry dead-lettered, got %q", got.Message)
+	}
+
+	if len(sink.written) != 1 || sink.written[0].Message != "bad raw line" {
+		t.Errorf("Expected only the raw line dead-lettered, got %d entries", len(sink.written))
+	}
+}
+
+func TestParsePool_Cancel(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%v", ordered), func(t *testing.T) {
+			pool := NewParsePool(numberParser{}, ParsePoolOptions{Workers: 4, Ordered: ordered})
+			ctx, cancel := context.WithCancel(context.Background())
+
+			in := make(chan *models.LogEntry, 10)
+			out := make(chan *models.LogEntry) // nobody reads
+			for i := 0; i < 10; i++ {
+				entry := models.NewLogEntry()
+				entry.Message = strconv.Itoa(i)
+				in <- entry
+			}
+
+			done := make(chan struct{})
+			go func() {
+				pool.Run(ctx, in, out)
+				close(done)
+			}()
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("Expected Run to return once cancelled")
+			}
+		})
+	}
+}
+
+// BenchmarkParsePool parses with a parser that waits 100µs on every 7th
+// message, standing in for an expensive one
+func BenchmarkParsePool(b *testing.B) {
+	for _, bm := range []struct {
+		workers int
+		ordered bool
+	}{
+		{1, false},
+		{8, false},
+		{8, true},
+	} {
+		b.Run(fmt.Sprintf("workers=%d/ordered=%v", bm.workers, bm.ordered), func(b *testing.B) {
+			pool := NewParsePool(numberParser{delay: 100 * time.Microsecond}, ParsePoolOptions{Workers: bm.workers, Ordered: bm.ordered})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			in := make(chan *models.LogEntry, 100)
+			out := make(chan *models.LogEntry, 100)
+			go pool.Run(ctx, in, out)
+
+			b.ResetTimer()
+			go func() {
+				for i := 0; i < b.N; i++ {
+					entry := models.NewLogEntry()
+					entry.Message = strconv.Itoa(i)
+					in <- entry
+				}
+				close(in)
+			}()
+			for range out {
+			}
+		})
+	}
+}
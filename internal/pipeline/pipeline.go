@@ -0,0 +1,465 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Stage transforms an entry on its way to the sinks. Returning false drops
+// the entry and skips the remaining stages.
+type Stage func(*models.LogEntry) (*models.LogEntry, bool)
+
+// FilterStage turns a filter into a stage
+func FilterStage(f *Filter) Stage {
+	return func(entry *models.LogEntry) (*models.LogEntry, bool) {
+		return entry, f.Allow(entry)
+	}
+}
+
+// Transform turns an in-place modification, such as Redactor.Apply, into a
+// stage that keeps every entry
+func Transform(apply func(*models.LogEntry)) Stage {
+	return func(entry *models.LogEntry) (*models.LogEntry, bool) {
+		apply(entry)
+		return entry, true
+	}
+}
+
+// Pipeline runs entries from an input channel through an ordered list of
+// stages and fans the survivors out to every sink
+type Pipeline struct {
+	// config is swapped whole by Reconfigure so an entry never sees half
+	// of a new config. Process holds swapping shared while it uses one.
+	config   atomic.Pointer[pipelineConfig]
+	swapping sync.RWMutex
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	processed atomic.Int64
+	dropped   atomic.Int64
+	writes    recentWrites
+
+	// unflushed holds the acks of entries every sink accepted, until the
+	// sinks of the config they were written under are flushed. flushMu is
+	// held from taking them to acking them, so a flush only releases acks
+	// its own sinks answer for. ackInterval is how often a running
+	// pipeline flushes while some are held.
+	acksMu      sync.Mutex
+	unflushed   []heldAck
+	flushMu     sync.Mutex
+	ackInterval time.Duration
+	unacked     atomic.Int64
+}
+
+// heldAck is the ack of an entry written to the sinks of config
+type heldAck struct {
+	config *pipelineConfig
+	ack    func()
+}
+
+// defaultAckInterval is how often written entries are flushed and acked
+const defaultAckInterval = time.Second
+
+// maxHeldAcks caps the acks waiting on a flush. While the sinks keep
+// failing to flush, acks past it are given up and their entries left
+// unacked, so their sources deliver them again.
+const maxHeldAcks = 100000
+
+// pipelineConfig is what a running pipeline can swap
+type pipelineConfig struct {
+	stages []Stage
+	sinks  []collector.Sink
+
+	// observed are the sinks that report their background sends. Their
+	// writes only buffer, so only failed ones count towards the error rate.
+	observed map[collector.Sink]bool
+}
+
+// sinkErrorWindow is how far back SinkErrorRate looks, in seconds
+const sinkErrorWindow = 60
+
+// recentWrites counts sink writes and sends, and how many failed, per
+// second over the last sinkErrorWindow seconds
+type recentWrites struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	seconds [sinkErrorWindow]writeCounts
+}
+
+// writeCounts are the writes of one second
+type writeCounts struct {
+	second int64
+	total  int
+	failed int
+}
+
+func (w *recentWrites) record(failed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now().Unix()
+	counts := &w.seconds[now%sinkErrorWindow]
+	if counts.second != now {
+		*counts = writeCounts{second: now}
+	}
+	counts.total++
+	if failed {
+		counts.failed++
+	}
+}
+
+func (w *recentWrites) errorRate() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now().Unix()
+	total, failed := 0, 0
+	for _, counts := range w.seconds {
+		if now-counts.second < sinkErrorWindow {
+			total += counts.total
+			failed += counts.failed
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(failed) / float64(total)
+}
+
+// recordSend counts the outcome of a sink's background send
+func (p *Pipeline) recordSend(err error) {
+	p.writes.record(err != nil)
+}
+
+// newConfig builds a config, asking every sink to report its background
+// sends
+func (p *Pipeline) newConfig(sinks []collector.Sink, stages []Stage) *pipelineConfig {
+	observed := make(map[collector.Sink]bool)
+	for _, sink := range sinks {
+		if observer, ok := sink.(collector.SendObserver); ok && observer.ObserveSends(p.recordSend) {
+			observed[sink] = true
+		}
+	}
+	return &pipelineConfig{stages: stages, sinks: sinks, observed: observed}
+}
+
+// NewPipeline creates a pipeline writing to the given sinks
+func NewPipeline(sinks []collector.Sink, stages ...Stage) *Pipeline {
+	p := &Pipeline{done: make(chan struct{}), ackInterval: defaultAckInterval}
+	p.writes.now = time.Now
+	p.config.Store(p.newConfig(sinks, stages))
+	return p
+}
+
+// Start begins processing entries from in until it is closed or ctx is done
+func (p *Pipeline) Start(ctx context.Context, in <-chan *models.LogEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return fmt.Errorf("pipeline already running")
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.running = true
+
+	go p.run(ctx, in)
+	return nil
+}
+
+func (p *Pipeline) run(ctx context.Context, in <-chan *models.LogEntry) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.ackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry, ok := <-in:
+			if !ok {
+				return
+			}
+			p.Process(ctx, entry)
+		case <-ticker.C:
+			p.flushAcks()
+		case <-ctx.Done():
+			p.drain(context.WithoutCancel(ctx), in)
+			return
+		}
+	}
+}
+
+// drain writes the entries still buffered in the input so they aren't lost
+// on shutdown. It doesn't wait for new ones.
+func (p *Pipeline) drain(ctx context.Context, in <-chan *models.LogEntry) {
+	for {
+		select {
+		case entry, ok := <-in:
+			if !ok {
+				return
+			}
+			p.Process(ctx, entry)
+		default:
+			return
+		}
+	}
+}
+
+// Process runs one entry through the stages and writes it to every sink.
+// It reports whether the entry survived the stages. The entry is acked once
+// every sink accepted it and was flushed, or when a stage drops it; a failed
+// write or flush leaves it unacked so its source can deliver it again.
+func (p *Pipeline) Process(ctx context.Context, entry *models.LogEntry) bool {
+	p.swapping.RLock()
+	defer p.swapping.RUnlock()
+	config := p.config.Load()
+
+	p.processed.Add(1)
+	metrics.EntrySize.Observe(float64(len(entry.Message)))
+
+	for _, stage := range config.stages {
+		next, keep := stage(entry)
+		if !keep || next == nil {
+			p.dropped.Add(1)
+			metrics.EntriesDropped.WithLabelValues(metrics.ReasonFiltered).Inc()
+			entry.Ack()
+			return false
+		}
+		next.MergeAcks(entry)
+		entry = next
+	}
+
+	failed := false
+	for _, sink := range config.sinks {
+		err := sink.Write(ctx, entry)
+		if err != nil || !config.observed[sink] {
+			p.writes.record(err != nil)
+		}
+		if err != nil {
+			fmt.Printf("❌ Failed to write to %s: %v\n", sink.Name(), err)
+			failed = true
+		}
+	}
+	if !failed {
+		p.holdAcks(config, entry)
+		metrics.PipelineLatency.Observe(time.Since(entry.ReceivedAt).Seconds())
+	}
+	return true
+}
+
+// Stop stops processing, writes out buffered entries, then flushes every
+// sink and stops the ones that hold resources
+func (p *Pipeline) Stop() error {
+	p.mu.Lock()
+	if p.running {
+		p.running = false
+		p.cancel()
+		p.mu.Unlock()
+		<-p.done
+	} else {
+		p.mu.Unlock()
+	}
+
+	p.flushAcks()
+	return stopSinks(p.config.Load().sinks)
+}
+
+// holdAcks keeps the entry's acks until the sinks of the config it was
+// written under are flushed, as sinks that batch or buffer may still lose
+// it
+func (p *Pipeline) holdAcks(config *pipelineConfig, entry *models.LogEntry) {
+	ack := entry.TakeAcks()
+	if ack == nil {
+		return
+	}
+	p.acksMu.Lock()
+	defer p.acksMu.Unlock()
+	if len(p.unflushed) >= maxHeldAcks {
+		if p.unacked.Add(1) == 1 {
+			fmt.Printf("⚠️  %d entries wait on a sink flush, leaving new ones unacked\n", len(p.unflushed))
+		}
+		return
+	}
+	p.unflushed = append(p.unflushed, heldAck{config: config, ack: ack})
+}
+
+// flushAcks flushes the sinks of every config entries are held under and
+// acks the entries whose sinks all flushed. The rest stay held for the next
+// flush.
+func (p *Pipeline) flushAcks() {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.acksMu.Lock()
+	held := p.unflushed
+	p.unflushed = nil
+	p.acksMu.Unlock()
+	if len(held) == 0 {
+		return
+	}
+
+	flushed := make(map[*pipelineConfig]bool)
+	var kept []heldAck
+	for _, h := range held {
+		ok, tried := flushed[h.config]
+		if !tried {
+			ok = p.flushSinks(h.config)
+			flushed[h.config] = ok
+		}
+		if ok {
+			h.ack()
+		} else {
+			kept = append(kept, h)
+		}
+	}
+	if len(kept) > 0 {
+		p.acksMu.Lock()
+		p.unflushed = append(kept, p.unflushed...)
+		p.acksMu.Unlock()
+	}
+}
+
+// flushSinks flushes the sinks of config, reporting whether all of them
+// flushed
+func (p *Pipeline) flushSinks(config *pipelineConfig) bool {
+	for _, sink := range config.sinks {
+		err := sink.Flush()
+		if !config.observed[sink] {
+			p.writes.record(err != nil)
+		}
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// dropAcks gives up the held acks of config, leaving their entries
+// unacked
+func (p *Pipeline) dropAcks(config *pipelineConfig) {
+	p.acksMu.Lock()
+	defer p.acksMu.Unlock()
+	kept := p.unflushed[:0]
+	for _, h := range p.unflushed {
+		if h.config == config {
+			p.unacked.Add(1)
+		} else {
+			kept = append(kept, h)
+		}
+	}
+	p.unflushed = kept
+}
+
+// Reconfigure swaps in new stages and sinks while entries keep flowing.
+// Entries being processed finish with the old ones, then the old sinks
+// that aren't among the new, behind a router or not, are flushed and
+// stopped. Entries written to the old sinks that fail to flush by then are
+// left unacked.
+func (p *Pipeline) Reconfigure(sinks []collector.Sink, stages ...Stage) error {
+	old := p.config.Swap(p.newConfig(sinks, stages))
+
+	// Wait out the entries that loaded the old config
+	p.swapping.Lock()
+	p.swapping.Unlock()
+
+	// No flush may ack entries of the old config once its sinks are
+	// stopped
+	p.flushAcks()
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+	defer p.dropAcks(old)
+
+	// A sink may move behind a new router or out of a retired one, so
+	// routers are looked into
+	kept := make(map[collector.Sink]bool, len(sinks))
+	for _, sink := range sinks {
+		kept[sink] = true
+		if router, ok := sink.(*Router); ok {
+			for _, routed := range router.Sinks() {
+				kept[routed] = true
+			}
+		}
+	}
+	var retired []collector.Sink
+	for _, sink := range old.sinks {
+		if kept[sink] {
+			continue
+		}
+		if router, ok := sink.(*Router); ok {
+			// Only the routed sinks hold resources
+			for _, routed := range router.Sinks() {
+				if !kept[routed] {
+					retired = append(retired, routed)
+				}
+			}
+			continue
+		}
+		retired = append(retired, sink)
+	}
+	return stopSinks(retired)
+}
+
+// stopSinks flushes every sink and stops the ones that hold resources
+func stopSinks(sinks []collector.Sink) error {
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush %s: %w", sink.Name(), err))
+		}
+		if stopper, ok := sink.(interface{ Stop() error }); ok {
+			if err := stopper.Stop(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to stop %s: %w", sink.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sinks returns the sinks the pipeline writes to
+func (p *Pipeline) Sinks() []collector.Sink {
+	return p.config.Load().sinks
+}
+
+// Stages returns the stages entries go through before the sinks
+func (p *Pipeline) Stages() []Stage {
+	return p.config.Load().stages
+}
+
+// Done is closed once the pipeline stopped reading, after its input was
+// closed and every entry processed, or after it was stopped
+func (p *Pipeline) Done() <-chan struct{} {
+	return p.done
+}
+
+// Processed returns how many entries entered the pipeline
+func (p *Pipeline) Processed() int64 {
+	return p.processed.Load()
+}
+
+// SinkErrorRate returns the share of sink writes, flushes and background
+// sends in the last minute that failed
+func (p *Pipeline) SinkErrorRate() float64 {
+	return p.writes.errorRate()
+}
+
+// Unacked returns how many entries were written but left unacked, as their
+// sinks failed to flush for too long or were stopped first
+func (p *Pipeline) Unacked() int64 {
+	return p.unacked.Load()
+}
+
+// Dropped returns how many entries a stage dropped
+func (p *Pipeline) Dropped() int64 {
+	return p.dropped.Load()
+}
@@ -0,0 +1,447 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// captureSink keeps every entry written to it
+type captureSink struct {
+	name     string
+	err      error // returned by Write when set
+	flushErr error // returned by Flush when set
+
+	mu      sync.Mutex
+	entries []*models.LogEntry
+	flushed bool
+	stopped bool
+}
+
+func (s *captureSink) Write(ctx context.Context, entry *models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *captureSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushed = true
+	return s.flushErr
+}
+
+func (s *captureSink) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	return nil
+}
+
+func (s *captureSink) Name() string { return s.name }
+
+func (s *captureSink) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := make([]string, len(s.entries))
+	for i, entry := range s.entries {
+		messages[i] = entry.Message
+	}
+	return messages
+}
+
+func TestPipeline_FilterAndFanOut(t *testing.T) {
+	first := &captureSink{name: "first"}
+	second := &captureSink{name: "second"}
+
+	filter := NewFilter(FilterMinLevel(models.LevelWarning))
+	mark := Transform(func(entry *models.LogEntry) {
+		entry.Fields["seen"] = true
+	})
+	p := NewPipeline([]collector.Sink{first, second}, FilterStage(filter), mark)
+
+	in := make(chan *models.LogEntry, 10)
+	if err := p.Start(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+
+	levels := []models.LogLevel{models.LevelDebug, models.LevelError, models.LevelInfo, models.LevelWarning}
+	for i, level := range levels {
+		entry := newEntry(level)
+		entry.Message = string(rune('a' + i))
+		in <- entry
+	}
+	close(in)
+
+	select {
+	case <-p.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the pipeline to finish once its input closed")
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sink := range []*captureSink{first, second} {
+		messages := sink.messages()
+		if len(messages) != 2 || messages[0] != "b" || messages[1] != "d" {
+			t.Errorf("Expected %s to receive [b d], got %v", sink.name, messages)
+		}
+		for _, entry := range sink.entries {
+			if entry.Fields["seen"] != true {
+				t.Errorf("Expected %s to receive transformed entries", sink.name)
+			}
+		}
+		if !sink.flushed || !sink.stopped {
+			t.Errorf("Expected %s to be flushed and stopped", sink.name)
+		}
+	}
+
+	if p.Processed() != 4 {
+		t.Errorf("Expected 4 processed, got %d", p.Processed())
+	}
+	if p.Dropped() != 2 {
+		t.Errorf("Expected 2 dropped, got %d", p.Dropped())
+	}
+}
+
+func TestPipeline_StageReplacesEntry(t *testing.T) {
+	sink := &captureSink{name: "sink"}
+	rewrite := func(entry *models.LogEntry) (*models.LogEntry, bool) {
+		replaced := models.NewLogEntry()
+		replaced.Message = "rewritten " + entry.Message
+		return replaced, true
+	}
+	p := NewPipeline([]collector.Sink{sink}, rewrite)
+
+	entry := models.NewLogEntry()
+	entry.Message = "original"
+	if !p.Process(context.Background(), entry) {
+		t.Fatal("Expected the entry to survive")
+	}
+
+	if messages := sink.messages(); len(messages) != 1 || messages[0] != "rewritten original" {
+		t.Errorf("Expected the replaced entry, got %v", messages)
+	}
+}
+
+func TestPipeline_Reconfigure(t *testing.T) {
+	kept := &captureSink{name: "kept"}
+	retired := &captureSink{name: "retired"}
+	added := &captureSink{name: "added"}
+	p := NewPipeline([]collector.Sink{kept, retired})
+
+	process := func(message string) bool {
+		entry := models.NewLogEntry()
+		entry.Message = message
+		return p.Process(context.Background(), entry)
+	}
+	process("before")
+
+	dropMarked := func(entry *models.LogEntry) (*models.LogEntry, bool) {
+		return entry, entry.Message != "dropped"
+	}
+	if err := p.Reconfigure([]collector.Sink{kept, added}, dropMarked); err != nil {
+		t.Fatal(err)
+	}
+	if process("dropped") {
+		t.Error("Expected the new stage to drop the entry")
+	}
+	process("after")
+
+	if messages := kept.messages(); len(messages) != 2 || messages[1] != "after" {
+		t.Errorf("Expected the kept sink to see both kept entries, got %v", messages)
+	}
+	if messages := added.messages(); len(messages) != 1 || messages[0] != "after" {
+		t.Errorf("Expected the added sink to see only the entry after the swap, got %v", messages)
+	}
+	if messages := retired.messages(); len(messages) != 1 {
+		t.Errorf("Expected the retired sink to see only the entry before the swap, got %v", messages)
+	}
+	if !retired.flushed || !retired.stopped {
+		t.Error("Expected the retired sink flushed and stopped")
+	}
+	if kept.stopped {
+		t.Error("Expected the kept sink left running")
+	}
+	if sinks := p.Sinks(); len(sinks) != 2 || sinks[1] != added {
+		t.Errorf("Expected the new sinks, got %v", sinks)
+	}
+}
+
+func TestPipeline_StopDrainsBufferedEntries(t *testing.T) {
+	sink := &captureSink{name: "sink"}
+	p := NewPipeline([]collector.Sink{sink})
+
+	// Fill the input before starting so entries are still buffered when
+	// Stop cancels the pipeline
+	in := make(chan *models.LogEntry, 100)
+	for i := 0; i < 100; i++ {
+		in <- models.NewLogEntry()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Start(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(sink.messages()); got != 100 {
+		t.Errorf("Expected all 100 buffered entries to be written, got %d", got)
+	}
+	if !sink.flushed {
+		t.Error("Expected the sink to be flushed")
+	}
+}
+
+func TestPipeline_StartTwice(t *testing.T) {
+	p := NewPipeline(nil)
+	in := make(chan *models.LogEntry)
+
+	if err := p.Start(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	if err := p.Start(context.Background(), in); err == nil {
+		t.Error("Expected an error starting a running pipeline")
+	}
+}
+
+func TestPipeline_Acks(t *testing.T) {
+	tests := []struct {
+		name         string
+		level        models.LogLevel
+		failing      bool
+		failingFlush bool
+		beforeFlush  bool
+		expected     bool
+	}{
+		{"written", models.LevelError, false, false, false, true},
+		{"filtered", models.LevelDebug, false, false, true, true},
+		{"sink failed", models.LevelError, true, false, false, false},
+		{"flush failed", models.LevelError, false, true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			second := &captureSink{name: "second"}
+			if tt.failing {
+				second.err = errors.New("connection refused")
+			}
+			if tt.failingFlush {
+				second.flushErr = errors.New("connection refused")
+			}
+			sinks := []collector.Sink{&captureSink{name: "first"}, second}
+			p := NewPipeline(sinks, FilterStage(NewFilter(FilterMinLevel(models.LevelInfo))))
+
+			acks := 0
+			entry := newEntry(tt.level)
+			entry.OnAck(func() { acks++ })
+			p.Process(context.Background(), entry)
+
+			// Written entries are only acked once the sinks flushed them
+			if acked := acks > 0; acked != tt.beforeFlush {
+				t.Errorf("Expected acked=%v before the flush, got %v", tt.beforeFlush, acked)
+			}
+			p.Stop()
+			if acked := acks > 0; acked != tt.expected {
+				t.Errorf("Expected acked=%v, got %v", tt.expected, acked)
+			}
+			if acks > 1 {
+				t.Errorf("Expected a single ack, got %d", acks)
+			}
+		})
+	}
+}
+
+func TestPipeline_AcksOnFlushInterval(t *testing.T) {
+	sink := &captureSink{name: "sink", flushErr: errors.New("connection refused")}
+	p := NewPipeline([]collector.Sink{sink})
+	p.ackInterval = 10 * time.Millisecond
+
+	in := make(chan *models.LogEntry, 1)
+	if err := p.Start(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	var acked atomic.Bool
+	entry := newEntry(models.LevelInfo)
+	entry.OnAck(func() { acked.Store(true) })
+	in <- entry
+
+	// The entry stays unacked while the flush fails, and is acked once one
+	// succeeds
+	time.Sleep(50 * time.Millisecond)
+	if acked.Load() {
+		t.Fatal("Expected no ack while the flush fails")
+	}
+	sink.mu.Lock()
+	sink.flushErr = nil
+	sink.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !acked.Load() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !acked.Load() {
+		t.Error("Expected the entry acked after a successful flush")
+	}
+}
+
+func TestPipeline_ReconfigureAcksOnlyFlushedSinks(t *testing.T) {
+	old := &captureSink{name: "old", flushErr: errors.New("connection refused")}
+	p := NewPipeline([]collector.Sink{old})
+
+	oldAcked := false
+	entry := newEntry(models.LevelInfo)
+	entry.OnAck(func() { oldAcked = true })
+	p.Process(context.Background(), entry)
+
+	// The new sink flushing says nothing of what the old one kept
+	replacement := &captureSink{name: "new"}
+	if err := p.Reconfigure([]collector.Sink{replacement}); err == nil {
+		t.Error("Expected the old sink's failed flush reported")
+	}
+	newAcked := false
+	entry = newEntry(models.LevelInfo)
+	entry.OnAck(func() { newAcked = true })
+	p.Process(context.Background(), entry)
+	p.flushAcks()
+
+	if oldAcked {
+		t.Error("Expected the entry on the old sink left unacked")
+	}
+	if !newAcked {
+		t.Error("Expected the entry on the new sink acked")
+	}
+	if p.Unacked() != 1 {
+		t.Errorf("Expected 1 unacked, got %d", p.Unacked())
+	}
+}
+
+func TestPipeline_CapsHeldAcks(t *testing.T) {
+	sink := &captureSink{name: "sink", flushErr: errors.New("connection refused")}
+	p := NewPipeline([]collector.Sink{sink})
+
+	for i := 0; i <= maxHeldAcks; i++ {
+		entry := newEntry(models.LevelInfo)
+		entry.OnAck(func() {})
+		p.Process(context.Background(), entry)
+	}
+	if len(p.unflushed) != maxHeldAcks {
+		t.Errorf("Expected %d held acks, got %d", maxHeldAcks, len(p.unflushed))
+	}
+	if p.Unacked() != 1 {
+		t.Errorf("Expected 1 unacked, got %d", p.Unacked())
+	}
+}
+
+func TestPipeline_SinkErrorRate(t *testing.T) {
+	failing := &captureSink{name: "failing", err: errors.New("connection refused")}
+	p := NewPipeline([]collector.Sink{&captureSink{name: "ok"}, failing})
+	now := time.Now()
+	p.writes.now = func() time.Time { return now }
+
+	if rate := p.SinkErrorRate(); rate != 0 {
+		t.Errorf("Expected 0 before any write, got %v", rate)
+	}
+
+	for i := 0; i < 10; i++ {
+		p.Process(context.Background(), newEntry(models.LevelInfo))
+	}
+	if rate := p.SinkErrorRate(); rate != 0.5 {
+		t.Errorf("Expected half the writes failed, got %v", rate)
+	}
+
+	// Only the last minute counts
+	failing.mu.Lock()
+	failing.err = nil
+	failing.mu.Unlock()
+	now = now.Add(30 * time.Second)
+	p.Process(context.Background(), newEntry(models.LevelInfo))
+	if rate := p.SinkErrorRate(); rate != 10.0/22 {
+		t.Errorf("Expected 10 of 22 writes failed, got %v", rate)
+	}
+	now = now.Add(31 * time.Second)
+	if rate := p.SinkErrorRate(); rate != 0 {
+		t.Errorf("Expected the old failures forgotten, got %v", rate)
+	}
+}
+
+// observedSink only buffers on Write and reports its background sends
+type observedSink struct {
+	captureSink
+	observe func(err error)
+}
+
+func (s *observedSink) ObserveSends(observe func(err error)) bool {
+	s.observe = observe
+	return true
+}
+
+func TestPipeline_SinkErrorRateCountsSends(t *testing.T) {
+	sink := &observedSink{captureSink: captureSink{name: "batching"}}
+	p := NewPipeline([]collector.Sink{sink})
+
+	// Buffering writes don't count, the sends behind them do
+	for i := 0; i < 10; i++ {
+		p.Process(context.Background(), newEntry(models.LevelInfo))
+	}
+	if rate := p.SinkErrorRate(); rate != 0 {
+		t.Errorf("Expected 0 before any send, got %v", rate)
+	}
+	sink.observe(errors.New("connection refused"))
+	sink.observe(nil)
+	if rate := p.SinkErrorRate(); rate != 0.5 {
+		t.Errorf("Expected half the sends failed, got %v", rate)
+	}
+
+	// So do flushes of sinks that don't send in the background
+	plain := &captureSink{name: "file", flushErr: errors.New("disk full")}
+	p = NewPipeline([]collector.Sink{plain})
+	entry := newEntry(models.LevelInfo)
+	entry.OnAck(func() {})
+	p.Process(context.Background(), entry)
+	p.Stop()
+	if rate := p.SinkErrorRate(); rate != 0.5 {
+		t.Errorf("Expected the failed flush counted, got %v", rate)
+	}
+}
+
+func TestPipeline_SizeAndLatency(t *testing.T) {
+	failing := &captureSink{name: "failing", err: errors.New("connection refused")}
+	good := NewPipeline([]collector.Sink{&captureSink{name: "ok"}})
+	bad := NewPipeline([]collector.Sink{failing})
+
+	sizes := metrics.EntrySize.Count()
+	latencies := metrics.PipelineLatency.Count()
+
+	entry := newEntry(models.LevelInfo)
+	entry.Message = "0123456789"
+	good.Process(context.Background(), entry)
+	bad.Process(context.Background(), newEntry(models.LevelInfo))
+
+	if got := metrics.EntrySize.Count() - sizes; got != 2 {
+		t.Errorf("Expected 2 sizes observed, got %d", got)
+	}
+	// Only the successful write counts towards latency
+	if got := metrics.PipelineLatency.Count() - latencies; got != 1 {
+		t.Errorf("Expected 1 latency observed, got %d", got)
+	}
+}
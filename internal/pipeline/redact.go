@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// RedactMask replaces text matched by a redaction pattern
+const RedactMask = "***"
+
+// RedactPresets are built-in patterns that can be enabled by name
+var RedactPresets = map[string]string{
+	// 13-19 digits, optionally grouped with spaces or dashes
+	"credit_card": `\b(?:\d[ -]?){12,18}\d\b`,
+	"email":       `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+}
+
+// RedactorOptions configures a Redactor
+type RedactorOptions struct {
+	// Fields lists field names (case-insensitive) removed from Fields,
+	// including inside nested objects
+	Fields []string
+
+	// Patterns are regexps whose matches are replaced with RedactMask in the
+	// message and in string field values
+	Patterns []string
+
+	// Presets enables built-in patterns by name, see RedactPresets
+	Presets []string
+}
+
+// Redactor scrubs sensitive data from entries before they reach a sink
+type Redactor struct {
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor compiles the redaction rules
+func NewRedactor(opts RedactorOptions) (*Redactor, error) {
+	r := &Redactor{
+		fields: make(map[string]bool, len(opts.Fields)),
+	}
+
+	for _, name := range opts.Fields {
+		r.fields[strings.ToLower(name)] = true
+	}
+
+	for _, name := range opts.Presets {
+		pattern, ok := RedactPresets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction preset %q (expected one of: %s)", name, presetNames())
+		}
+		r.patterns = append(r.patterns, regexp.MustCompile(pattern))
+	}
+
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+// presetNames lists the preset names for error messages
+func presetNames() string {
+	names := make([]string, 0, len(RedactPresets))
+	for name := range RedactPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Apply redacts the entry in place
+func (r *Redactor) Apply(entry *models.LogEntry) {
+	entry.Message = r.redactString(entry.Message)
+	r.redactMap(entry.Fields)
+}
+
+// redactMap drops sensitive keys and scrubs the remaining values
+func (r *Redactor) redactMap(m map[string]interface{}) {
+	for key, value := range m {
+		if r.fields[strings.ToLower(key)] {
+			delete(m, key)
+			continue
+		}
+		m[key] = r.redactValue(value)
+	}
+}
+
+// redactValue scrubs strings and walks into nested objects and arrays
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case map[string]interface{}:
+		r.redactMap(v)
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redactValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// redactString masks every pattern match in s
+func (r *Redactor) redactString(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, RedactMask)
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestRedactor_MessageAndFields(t *testing.T) {
+	redactor, err := NewRedactor(RedactorOptions{
+		Fields:  []string{"password"},
+		Presets: []string{"credit_card"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Message = "payment with card 4111 1111 1111 1111 accepted"
+	entry.Fields["password"] = "hunter2"
+	entry.Fields["user"] = "alice"
+
+	redactor.Apply(entry)
+
+	if entry.Message != "payment with card *** accepted" {
+		t.Errorf("Expected card to be masked, got %q", entry.Message)
+	}
+	if _, ok := entry.Fields["password"]; ok {
+		t.Error("Expected password field to be dropped")
+	}
+	if entry.Fields["user"] != "alice" {
+		t.Errorf("Expected user field untouched, got %v", entry.Fields["user"])
+	}
+}
+
+func TestRedactor_NestedFields(t *testing.T) {
+	redactor, err := NewRedactor(RedactorOptions{
+		Fields:  []string{"Token"},
+		Presets: []string{"email"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Fields["request"] = map[string]interface{}{
+		"token": "abc123",
+		"from":  "alice@example.com",
+		"tags":  []interface{}{"bob@example.org", 42},
+	}
+
+	redactor.Apply(entry)
+
+	request := entry.Fields["request"].(map[string]interface{})
+	if _, ok := request["token"]; ok {
+		t.Error("Expected nested token field to be dropped")
+	}
+	if request["from"] != RedactMask {
+		t.Errorf("Expected nested email to be masked, got %v", request["from"])
+	}
+	tags := request["tags"].([]interface{})
+	if tags[0] != RedactMask || tags[1] != 42 {
+		t.Errorf("Expected array email masked and number kept, got %v", tags)
+	}
+}
+
+func TestRedactor_Patterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     RedactorOptions
+		input    string
+		expected string
+	}{
+		{"custom pattern", RedactorOptions{Patterns: []string{`api_key=\w+`}}, "call api_key=s3cr3t ok", "call *** ok"},
+		{"dashed card", RedactorOptions{Presets: []string{"credit_card"}}, "card 5500-0000-0000-0004", "card ***"},
+		{"short number kept", RedactorOptions{Presets: []string{"credit_card"}}, "order 12345 shipped", "order 12345 shipped"},
+		{"email", RedactorOptions{Presets: []string{"email"}}, "contact a.b+c@mail.example.com now", "contact *** now"},
+		{"no rules", RedactorOptions{}, "4111111111111111", "4111111111111111"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, err := NewRedactor(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := models.NewLogEntry()
+			entry.Message = tt.input
+			redactor.Apply(entry)
+			if entry.Message != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, entry.Message)
+			}
+		})
+	}
+}
+
+func TestNewRedactor_Errors(t *testing.T) {
+	if _, err := NewRedactor(RedactorOptions{Presets: []string{"ssn"}}); err == nil {
+		t.Error("Expected error for unknown preset")
+	}
+	if _, err := NewRedactor(RedactorOptions{Patterns: []string{"("}}); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
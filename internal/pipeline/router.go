@@ -0,0 +1,150 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Route sends the entries matching every one of its predicates to its
+// sinks. A route without predicates matches everything.
+type Route struct {
+	Name  string
+	Match []Predicate
+	Sinks []collector.Sink
+}
+
+// matches reports whether the entry passes all of the route's predicates
+func (r *Route) matches(entry *models.LogEntry) bool {
+	for _, p := range r.Match {
+		if !p(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// RouterOptions configures a Router
+type RouterOptions struct {
+	// FirstMatch sends an entry only to the first route it matches. By
+	// default it goes to every route it matches.
+	FirstMatch bool
+
+	// Default receives the entries no route matched. Without it they are
+	// dropped.
+	Default []collector.Sink
+}
+
+// Router is a sink that sends each entry to the sinks of the routes it
+// matches, so errors can page someone while everything is still stored.
+// An entry is written at most once to a sink shared by several routes.
+type Router struct {
+	routes []Route
+	opts   RouterOptions
+	sinks  []collector.Sink // every distinct sink, routes first
+
+	unrouted atomic.Int64
+}
+
+// NewRouter creates a router over the given routes, tried in order
+func NewRouter(routes []Route, opts RouterOptions) *Router {
+	r := &Router{routes: routes, opts: opts}
+
+	seen := make(map[collector.Sink]bool)
+	add := func(sinks []collector.Sink) {
+		for _, sink := range sinks {
+			if !seen[sink] {
+				seen[sink] = true
+				r.sinks = append(r.sinks, sink)
+			}
+		}
+	}
+	for _, route := range routes {
+		add(route.Sinks)
+	}
+	add(opts.Default)
+
+	return r
+}
+
+// Write sends the entry to the sinks of every matching route, or of the
+// first one with FirstMatch, falling back to the default sinks. Every
+// target is written to even if one fails.
+func (r *Router) Write(ctx context.Context, entry *models.LogEntry) error {
+	var targets []collector.Sink
+	for i := range r.routes {
+		if !r.routes[i].matches(entry) {
+			continue
+		}
+		targets = append(targets, r.routes[i].Sinks...)
+		if r.opts.FirstMatch {
+			break
+		}
+	}
+
+	if targets == nil {
+		targets = r.opts.Default
+	}
+	if len(targets) == 0 {
+		r.unrouted.Add(1)
+		metrics.EntriesDropped.WithLabelValues(metrics.ReasonUnrouted).Inc()
+		return nil
+	}
+
+	var errs []error
+	written := make(map[collector.Sink]bool, len(targets))
+	for _, sink := range targets {
+		if written[sink] {
+			continue
+		}
+		written[sink] = true
+		if err := sink.Write(ctx, entry); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush flushes every sink the router writes to
+func (r *Router) Flush() error {
+	var errs []error
+	for _, sink := range r.sinks {
+		if err := sink.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush %s: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stop stops the sinks that hold resources
+func (r *Router) Stop() error {
+	var errs []error
+	for _, sink := range r.sinks {
+		if stopper, ok := sink.(interface{ Stop() error }); ok {
+			if err := stopper.Stop(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to stop %s: %w", sink.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Name returns the sink identifier
+func (r *Router) Name() string {
+	return "router"
+}
+
+// Sinks returns every sink the router writes to
+func (r *Router) Sinks() []collector.Sink {
+	return r.sinks
+}
+
+// Unrouted returns how many entries matched no route and had no default
+func (r *Router) Unrouted() int64 {
+	return r.unrouted.Load()
+}
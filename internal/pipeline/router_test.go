@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/internal/collector"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// routedEntries are written to every router in the tests below
+func routedEntries() []*models.LogEntry {
+	entries := []struct {
+		message string
+		level   models.LogLevel
+		source  string
+	}{
+		{"disk failed", models.LevelError, "api"},
+		{"user deleted", models.LevelInfo, "audit"},
+		{"audit write failed", models.LevelCritical, "audit"},
+		{"request served", models.LevelInfo, "api"},
+	}
+
+	var out []*models.LogEntry
+	for _, e := range entries {
+		entry := newEntry(e.level)
+		entry.Message = e.message
+		entry.Source = e.source
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestRouter_Distribution(t *testing.T) {
+	tests := []struct {
+		name       string
+		firstMatch bool
+		alerts     []string
+		audit      []string
+		storage    []string
+	}{
+		{
+			name:    "fan out",
+			alerts:  []string{"disk failed", "audit write failed"},
+			audit:   []string{"user deleted", "audit write failed"},
+			storage: []string{"request served"},
+		},
+		{
+			name:       "first match",
+			firstMatch: true,
+			alerts:     []string{"disk failed", "audit write failed"},
+			audit:      []string{"user deleted"},
+			storage:    []string{"request served"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := &captureSink{name: "alerts"}
+			audit := &captureSink{name: "audit"}
+			storage := &captureSink{name: "storage"}
+
+			router := NewRouter([]Route{
+				{Name: "errors", Match: []Predicate{FilterMinLevel(models.LevelError)}, Sinks: []collector.Sink{alerts}},
+				{Name: "audit", Match: []Predicate{FilterSource(regexp.MustCompile(`^audit$`))}, Sinks: []collector.Sink{audit}},
+			}, RouterOptions{FirstMatch: tt.firstMatch, Default: []collector.Sink{storage}})
+
+			for _, entry := range routedEntries() {
+				if err := router.Write(context.Background(), entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, c := range []struct {
+				sink     *captureSink
+				expected []string
+			}{{alerts, tt.alerts}, {audit, tt.audit}, {storage, tt.storage}} {
+				if got := c.sink.messages(); !reflect.DeepEqual(got, c.expected) {
+					t.Errorf("Expected %s to get %v, got %v", c.sink.name, c.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRouter_SharedSinkWrittenOnce(t *testing.T) {
+	shared := &captureSink{name: "shared"}
+	router := NewRouter([]Route{
+		{Match: []Predicate{FilterMinLevel(models.LevelError)}, Sinks: []collector.Sink{shared}},
+		{Match: []Predicate{FilterField("team", "storage")}, Sinks: []collector.Sink{shared}},
+	}, RouterOptions{})
+
+	entry := newEntry(models.LevelError)
+	entry.Fields["team"] = "storage"
+	if err := router.Write(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(shared.messages()); got != 1 {
+		t.Errorf("Expected a single write, got %d", got)
+	}
+	if got := len(router.Sinks()); got != 1 {
+		t.Errorf("Expected 1 distinct sink, got %d", got)
+	}
+}
+
+func TestRouter_Unrouted(t *testing.T) {
+	alerts := &captureSink{name: "alerts"}
+	router := NewRouter([]Route{
+		{Match: []Predicate{FilterMinLevel(models.LevelError)}, Sinks: []collector.Sink{alerts}},
+	}, RouterOptions{})
+
+	if err := router.Write(context.Background(), newEntry(models.LevelInfo)); err != nil {
+		t.Fatal(err)
+	}
+	if router.Unrouted() != 1 {
+		t.Errorf("Expected 1 unrouted entry, got %d", router.Unrouted())
+	}
+	if len(alerts.messages()) != 0 {
+		t.Error("Expected nothing written")
+	}
+}
+
+func TestRouter_WritesPastFailures(t *testing.T) {
+	failing := &captureSink{name: "failing", err: errors.New("connection refused")}
+	healthy := &captureSink{name: "healthy"}
+	router := NewRouter([]Route{
+		{Sinks: []collector.Sink{failing, healthy}},
+	}, RouterOptions{})
+
+	if err := router.Write(context.Background(), newEntry(models.LevelInfo)); err == nil {
+		t.Error("Expected the failed write to be reported")
+	}
+	if len(healthy.messages()) != 1 {
+		t.Error("Expected the healthy sink to still get the entry")
+	}
+
+	if err := router.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if !healthy.flushed || !healthy.stopped || !failing.stopped {
+		t.Error("Expected every sink flushed and stopped")
+	}
+}
+
+func TestFilterField(t *testing.T) {
+	status := FilterField("status", "500")
+
+	entry := newEntry(models.LevelInfo)
+	if status(entry) {
+		t.Error("Expected an entry without the field to be rejected")
+	}
+	entry.Fields["status"] = float64(500)
+	if !status(entry) {
+		t.Error("Expected a numeric 500 to match")
+	}
+	entry.Fields["status"] = "502"
+	if status(entry) {
+		t.Error("Expected 502 not to match")
+	}
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// SamplerOptions configures a Sampler
+type SamplerOptions struct {
+	// Rates maps levels to the fraction of their entries that is kept, from
+	// 0 (drop all) to 1 (keep all)
+	Rates map[models.LogLevel]float64
+
+	// Default is the rate for levels not in Rates. Nil keeps them all, 0
+	// drops them.
+	Default *float64
+}
+
+// Sampler keeps a fraction of the entries of each level to cut down noisy
+// levels such as DEBUG. The decision is derived from a hash of the message,
+// so repeats of the same line are consistently kept or dropped.
+type Sampler struct {
+	opts        SamplerOptions
+	defaultRate float64
+	sampled     atomic.Int64
+}
+
+// NewSampler creates a sampler
+func NewSampler(opts SamplerOptions) *Sampler {
+	defaultRate := 1.0
+	if opts.Default != nil {
+		defaultRate = *opts.Default
+	}
+
+	return &Sampler{opts: opts, defaultRate: defaultRate}
+}
+
+// Keep reports whether the entry survives sampling. Dropped entries are
+// counted in the dropped metric with the sampled reason.
+func (s *Sampler) Keep(entry *models.LogEntry) bool {
+	rate, ok := s.opts.Rates[entry.Level]
+	if !ok {
+		rate = s.defaultRate
+	}
+	if rate >= 1 || sampleValue(entry) < rate {
+		return true
+	}
+
+	s.sampled.Add(1)
+	metrics.EntriesDropped.WithLabelValues(metrics.ReasonSampled).Inc()
+	return false
+}
+
+// Run reads entries from in and writes the ones that survive sampling to out
+// until in is closed or ctx is done, then closes out
+func (s *Sampler) Run(ctx context.Context, in <-chan *models.LogEntry, out chan<- *models.LogEntry) {
+	defer close(out)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-in:
+			if !ok {
+				return
+			}
+			if !s.Keep(entry) {
+				entry.Ack()
+				continue
+			}
+			if !emit(ctx, out, entry) {
+				return
+			}
+		}
+	}
+}
+
+// Sampled returns how many entries have been sampled out so far
+func (s *Sampler) Sampled() int64 {
+	return s.sampled.Load()
+}
+
+// sampleValue maps an entry to [0, 1). Entries with a message hash to a fixed
+// value; empty messages fall back to a random one.
+func sampleValue(entry *models.LogEntry) float64 {
+	if entry.Message == "" {
+		return rand.Float64()
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(entry.Message))
+	return float64(h.Sum64()>>11) / float64(uint64(1)<<53)
+}
@@ -0,0 +1,127 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/internal/metrics"
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestSampler_Rate(t *testing.T) {
+	s := NewSampler(SamplerOptions{
+		Rates: map[models.LogLevel]float64{models.LevelDebug: 0.01},
+	})
+	dropped := metrics.EntriesDropped.WithLabelValues(metrics.ReasonSampled)
+	before := dropped.Value()
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		entry := models.NewLogEntry()
+		entry.Level = models.LevelDebug
+		entry.Message = fmt.Sprintf("cache lookup key=user:%d hit=false", i)
+		if s.Keep(entry) {
+			kept++
+		}
+	}
+
+	if kept < 50 || kept > 150 {
+		t.Errorf("Expected roughly 100 of 10000 entries to survive, got %d", kept)
+	}
+	if s.Sampled() != int64(10000-kept) {
+		t.Errorf("Expected %d sampled out, got %d", 10000-kept, s.Sampled())
+	}
+	if got := dropped.Value() - before; got != int64(10000-kept) {
+		t.Errorf("Expected dropped metric to grow by %d, got %d", 10000-kept, got)
+	}
+}
+
+func TestSampler_KeepsOtherLevels(t *testing.T) {
+	s := NewSampler(SamplerOptions{
+		Rates: map[models.LogLevel]float64{models.LevelDebug: 0},
+	})
+
+	for _, level := range []models.LogLevel{models.LevelInfo, models.LevelWarning, models.LevelError, models.LevelCritical} {
+		entry := models.NewLogEntry()
+		entry.Level = level
+		entry.Message = "something happened"
+		if !s.Keep(entry) {
+			t.Errorf("Expected %s to be kept at the default rate", level)
+		}
+	}
+
+	entry := models.NewLogEntry()
+	entry.Level = models.LevelDebug
+	entry.Message = "something happened"
+	if s.Keep(entry) {
+		t.Error("Expected DEBUG to be dropped at rate 0")
+	}
+}
+
+func TestSampler_DefaultZero(t *testing.T) {
+	rate := 0.0
+	s := NewSampler(SamplerOptions{
+		Rates:   map[models.LogLevel]float64{models.LevelError: 1},
+		Default: &rate,
+	})
+
+	if !s.Keep(&models.LogEntry{Level: models.LevelError, Message: "failure"}) {
+		t.Error("Expected ERROR to be kept at its own rate")
+	}
+	for _, level := range []models.LogLevel{models.LevelDebug, models.LevelInfo, models.LevelWarning} {
+		if s.Keep(&models.LogEntry{Level: level, Message: "something happened"}) {
+			t.Errorf("Expected %s to be dropped at a default rate of 0", level)
+		}
+	}
+}
+
+func TestSampler_Deterministic(t *testing.T) {
+	rate := 0.5
+	s := NewSampler(SamplerOptions{Default: &rate})
+
+	for i := 0; i < 20; i++ {
+		message := fmt.Sprintf("request %d served", i)
+		first := s.Keep(&models.LogEntry{Level: models.LevelInfo, Message: message})
+		for j := 0; j < 5; j++ {
+			if s.Keep(&models.LogEntry{Level: models.LevelInfo, Message: message}) != first {
+				t.Fatalf("Expected %q to be consistently kept or dropped", message)
+			}
+		}
+	}
+}
+
+func TestSampler_Run(t *testing.T) {
+	s := NewSampler(SamplerOptions{
+		Rates: map[models.LogLevel]float64{models.LevelDebug: 0},
+	})
+
+	in := make(chan *models.LogEntry, 3)
+	out := make(chan *models.LogEntry, 3)
+
+	in <- &models.LogEntry{Level: models.LevelDebug, Message: "noise"}
+	in <- &models.LogEntry{Level: models.LevelError, Message: "failure"}
+	in <- &models.LogEntry{Level: models.LevelDebug, Message: "more noise"}
+	close(in)
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(context.Background(), in, out)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after input closed")
+	}
+
+	var messages []string
+	for entry := range out {
+		messages = append(messages, entry.Message)
+	}
+	if len(messages) != 1 || messages[0] != "failure" {
+		t.Errorf("Expected only the ERROR entry, got %v", messages)
+	}
+}
@@ -0,0 +1,195 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// FieldType is the type a schema expects a field to have
+type FieldType string
+
+// Field types
+const (
+	FieldString FieldType = "string"
+	FieldInt    FieldType = "int"
+	FieldFloat  FieldType = "float"
+	FieldBool   FieldType = "bool"
+)
+
+// ParseFieldType parses a field type name, case-insensitively
+func ParseFieldType(s string) (FieldType, error) {
+	switch t := FieldType(strings.ToLower(strings.TrimSpace(s))); t {
+	case FieldString, FieldInt, FieldFloat, FieldBool:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown field type %q (expected string, int, float or bool)", s)
+	}
+}
+
+// Coercer converts field values to the types a schema expects, so a field
+// is never a string in one entry and a number in the next by the time it
+// reaches a sink. Values that can't be converted are removed.
+type Coercer struct {
+	schema   map[string]FieldType
+	failures atomic.Int64
+}
+
+// NewCoercer creates a coercer for the given field types
+func NewCoercer(schema map[string]FieldType) (*Coercer, error) {
+	for name, t := range schema {
+		if _, err := ParseFieldType(string(t)); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return &Coercer{schema: schema}, nil
+}
+
+// Apply coerces the entry's schematized fields in place. Other fields are
+// left untouched.
+func (c *Coercer) Apply(entry *models.LogEntry) {
+	for name, t := range c.schema {
+		value, ok := entry.Fields[name]
+		if !ok || value == nil {
+			continue
+		}
+
+		coerced, err := coerce(value, t)
+		if err != nil {
+			c.failures.Add(1)
+			delete(entry.Fields, name)
+			fmt.Printf("⚠️  Dropped field %q from entry %s: %v\n", name, entry.ID, err)
+			continue
+		}
+		entry.Fields[name] = coerced
+	}
+}
+
+// Stage returns the coercer as a pipeline stage
+func (c *Coercer) Stage() Stage {
+	return Transform(c.Apply)
+}
+
+// Failures returns how many values couldn't be coerced
+func (c *Coercer) Failures() int64 {
+	return c.failures.Load()
+}
+
+// coerce converts a decoded JSON value to the given type
+func coerce(value interface{}, t FieldType) (interface{}, error) {
+	switch t {
+	case FieldString:
+		return coerceString(value)
+	case FieldInt:
+		return coerceInt(value)
+	case FieldFloat:
+		return coerceFloat(value)
+	case FieldBool:
+		return coerceBool(value)
+	default:
+		return nil, fmt.Errorf("unknown field type %q", t)
+	}
+}
+
+func coerceString(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return fmt.Sprint(v), nil
+	default:
+		// Objects and arrays keep their structure as JSON text
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %T to string: %w", value, err)
+		}
+		return string(data), nil
+	}
+}
+
+func coerceInt(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		if v != math.Trunc(v) || v > math.MaxInt64 || v < math.MinInt64 {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return coerceInt(string(v))
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", v)
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to int", value)
+	}
+}
+
+func coerceFloat(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return coerceFloat(string(v))
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%q is not a number", v)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to float", value)
+	}
+}
+
+func coerceBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+		return nil, fmt.Errorf("%v is not a boolean", v)
+	case int:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+		return nil, fmt.Errorf("%v is not a boolean", v)
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", v)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to bool", value)
+	}
+}
@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestCoercer_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		t        FieldType
+		value    interface{}
+		expected interface{} // nil means the field is removed
+	}{
+		{"string to int", FieldInt, "123", int64(123)},
+		{"padded string to int", FieldInt, " 42 ", int64(42)},
+		{"whole float to int", FieldInt, float64(7), int64(7)},
+		{"fraction to int", FieldInt, 7.5, nil},
+		{"word to int", FieldInt, "many", nil},
+		{"bool to int", FieldInt, true, nil},
+		{"string to float", FieldFloat, "0.25", 0.25},
+		{"int to float", FieldFloat, 3, float64(3)},
+		{"NaN to float", FieldFloat, "NaN", nil},
+		{"string to bool", FieldBool, "true", true},
+		{"one to bool", FieldBool, float64(1), true},
+		{"two to bool", FieldBool, float64(2), nil},
+		{"word to bool", FieldBool, "maybe", nil},
+		{"number to string", FieldString, float64(404), "404"},
+		{"fraction to string", FieldString, 0.5, "0.5"},
+		{"bool to string", FieldString, false, "false"},
+		{"object to string", FieldString, map[string]interface{}{"a": float64(1)}, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coercer, err := NewCoercer(map[string]FieldType{"field": tt.t})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entry := models.NewLogEntry()
+			entry.Fields["field"] = tt.value
+			coercer.Apply(entry)
+
+			got, ok := entry.Fields["field"]
+			if tt.expected == nil {
+				if ok {
+					t.Errorf("Expected the field to be removed, got %#v", got)
+				}
+				if coercer.Failures() != 1 {
+					t.Errorf("Expected 1 failure, got %d", coercer.Failures())
+				}
+				return
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %#v, got %#v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCoercer_LeavesOtherFieldsAlone(t *testing.T) {
+	coercer, err := NewCoercer(map[string]FieldType{"status": FieldInt, "missing": FieldBool})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := models.NewLogEntry()
+	entry.Fields["status"] = "200"
+	entry.Fields["path"] = "/health"
+	entry.Fields["latency"] = "12ms"
+	coercer.Apply(entry)
+
+	if entry.Fields["status"] != int64(200) {
+		t.Errorf("Expected status to be coerced, got %#v", entry.Fields["status"])
+	}
+	if entry.Fields["path"] != "/health" || entry.Fields["latency"] != "12ms" {
+		t.Errorf("Expected unschematized fields untouched, got %v", entry.Fields)
+	}
+	if _, ok := entry.Fields["missing"]; ok {
+		t.Error("Expected absent fields to stay absent")
+	}
+	if coercer.Failures() != 0 {
+		t.Errorf("Expected no failures, got %d", coercer.Failures())
+	}
+}
+
+func TestNewCoercer_UnknownType(t *testing.T) {
+	if _, err := NewCoercer(map[string]FieldType{"field": "date"}); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
+
+func TestParseFieldType(t *testing.T) {
+	if got, err := ParseFieldType(" Int "); err != nil || got != FieldInt {
+		t.Errorf("Expected int, got %q (%v)", got, err)
+	}
+	if _, err := ParseFieldType("integer"); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
@@ -0,0 +1,286 @@
+package pipeline
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+const (
+	// FieldTemplateID holds the ID of the template an entry was matched to
+	FieldTemplateID = "template_id"
+
+	// FieldTemplate holds the message with variable tokens masked
+	FieldTemplate = "template"
+
+	// TemplateWildcard stands for a token that varies between messages
+	TemplateWildcard = "<*>"
+)
+
+// templateMasks replace well-known variable tokens before clustering.
+// Order matters: more specific patterns run first.
+var templateMasks = []struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<UUID>"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<IP>"},
+	{regexp.MustCompile(`\b0[xX][0-9a-fA-F]+\b`), "<HEX>"},
+	{regexp.MustCompile(`[-+]?\b\d+(?:\.\d+)?\b`), "<NUM>"},
+}
+
+// TemplateMinerOptions configures a TemplateMiner
+type TemplateMinerOptions struct {
+	// Depth is the depth of the parse tree, including the root and the
+	// token-count level, so Depth-2 leading tokens pick the leaf
+	Depth int
+
+	// MaxChildren bounds the children of each tree node. Tokens beyond it
+	// share a wildcard child.
+	MaxChildren int
+
+	// Similarity is the fraction of matching tokens needed to join an
+	// existing template rather than start a new one
+	Similarity float64
+
+	// MaxClusters bounds how many templates are kept. When full, the least
+	// recently matched template is forgotten.
+	MaxClusters int
+}
+
+// templateCluster is one template and the leaf it lives in
+type templateCluster struct {
+	id     string
+	tokens []string
+	leaf   *templateNode
+}
+
+// templateNode is a node of the parse tree. Inner nodes route by token and
+// leaves hold clusters.
+type templateNode struct {
+	children map[string]*templateNode
+	clusters []*list.Element
+}
+
+// TemplateMiner tags entries with the template of their message using the
+// Drain algorithm: messages are routed through a fixed-depth tree by token
+// count and leading tokens, then matched to the most similar template in the
+// leaf. Tokens that differ between matched messages become wildcards.
+type TemplateMiner struct {
+	opts TemplateMinerOptions
+
+	mu    sync.Mutex
+	root  *templateNode
+	order *list.List // least recently matched cluster first
+}
+
+// NewTemplateMiner creates a template miner
+func NewTemplateMiner(opts TemplateMinerOptions) *TemplateMiner {
+	if opts.Depth < 3 {
+		opts.Depth = 4
+	}
+	if opts.MaxChildren <= 0 {
+		opts.MaxChildren = 100
+	}
+	if opts.Similarity <= 0 || opts.Similarity > 1 {
+		opts.Similarity = 0.4
+	}
+	if opts.MaxClusters <= 0 {
+		opts.MaxClusters = 1000
+	}
+
+	return &TemplateMiner{
+		opts:  opts,
+		root:  newTemplateNode(),
+		order: list.New(),
+	}
+}
+
+func newTemplateNode() *templateNode {
+	return &templateNode{children: make(map[string]*templateNode)}
+}
+
+// Apply sets the template ID and template fields on the entry
+func (m *TemplateMiner) Apply(entry *models.LogEntry) {
+	tokens := templateTokens(entry.Message)
+
+	m.mu.Lock()
+	cluster := m.match(tokens)
+	id, template := cluster.id, strings.Join(cluster.tokens, " ")
+	m.mu.Unlock()
+
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]interface{})
+	}
+	entry.Fields[FieldTemplateID] = id
+	entry.Fields[FieldTemplate] = template
+}
+
+// Run reads entries from in, tags them and writes them to out until in is
+// closed or ctx is done, then closes out
+func (m *TemplateMiner) Run(ctx context.Context, in <-chan *models.LogEntry, out chan<- *models.LogEntry) {
+	defer close(out)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-in:
+			if !ok {
+				return
+			}
+			m.Apply(entry)
+			if !emit(ctx, out, entry) {
+				return
+			}
+		}
+	}
+}
+
+// Templates returns the number of templates currently kept
+func (m *TemplateMiner) Templates() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// templateTokens masks variable tokens and splits the message on whitespace
+func templateTokens(message string) []string {
+	for _, mask := range templateMasks {
+		message = mask.pattern.ReplaceAllString(message, mask.placeholder)
+	}
+	return strings.Fields(message)
+}
+
+// match finds or creates the cluster for the tokens and marks it as recently used
+func (m *TemplateMiner) match(tokens []string) *templateCluster {
+	leaf := m.leaf(tokens)
+
+	var best *list.Element
+	bestSim, bestParams := -1.0, -1
+	for _, elem := range leaf.clusters {
+		cluster := elem.Value.(*templateCluster)
+		if len(cluster.tokens) != len(tokens) {
+			// Only possible under the wildcard token-count node
+			continue
+		}
+		sim, params := similarity(cluster.tokens, tokens)
+		if sim > bestSim || (sim == bestSim && params > bestParams) {
+			best, bestSim, bestParams = elem, sim, params
+		}
+	}
+
+	if best != nil && bestSim >= m.opts.Similarity {
+		cluster := best.Value.(*templateCluster)
+		for i, token := range tokens {
+			if cluster.tokens[i] != token {
+				cluster.tokens[i] = TemplateWildcard
+			}
+		}
+		m.order.MoveToBack(best)
+		return cluster
+	}
+
+	if m.order.Len() >= m.opts.MaxClusters {
+		m.evict(m.order.Front())
+	}
+
+	cluster := &templateCluster{
+		id:     templateID(tokens),
+		tokens: append([]string(nil), tokens...),
+		leaf:   leaf,
+	}
+	leaf.clusters = append(leaf.clusters, m.order.PushBack(cluster))
+	return cluster
+}
+
+// leaf walks the tree by token count and leading tokens, adding nodes as needed
+func (m *TemplateMiner) leaf(tokens []string) *templateNode {
+	node := m.child(m.root, fmt.Sprint(len(tokens)))
+
+	for depth := 0; depth < m.opts.Depth-2 && depth < len(tokens); depth++ {
+		token := tokens[depth]
+		if hasVariable(token) {
+			token = TemplateWildcard
+		}
+		node = m.child(node, token)
+	}
+
+	return node
+}
+
+// child returns the child for the token, falling back to the wildcard child
+// once the node is full. One slot is kept free for the wildcard child.
+func (m *TemplateMiner) child(node *templateNode, token string) *templateNode {
+	if child, ok := node.children[token]; ok {
+		return child
+	}
+	if len(node.children) >= m.opts.MaxChildren-1 {
+		token = TemplateWildcard
+		if child, ok := node.children[token]; ok {
+			return child
+		}
+	}
+
+	child := newTemplateNode()
+	node.children[token] = child
+	return child
+}
+
+// evict forgets a cluster. Emptied tree nodes are kept; they are bounded by
+// MaxChildren and Depth.
+func (m *TemplateMiner) evict(elem *list.Element) {
+	cluster := elem.Value.(*templateCluster)
+	leaf := cluster.leaf
+	for i, e := range leaf.clusters {
+		if e == elem {
+			leaf.clusters = append(leaf.clusters[:i], leaf.clusters[i+1:]...)
+			break
+		}
+	}
+	m.order.Remove(elem)
+}
+
+// similarity returns the fraction of positions where the template and tokens
+// agree, and how many wildcards the template has as a tie-breaker
+func similarity(template, tokens []string) (float64, int) {
+	if len(tokens) == 0 {
+		return 1, 0
+	}
+
+	same, params := 0, 0
+	for i, token := range template {
+		switch {
+		case token == TemplateWildcard:
+			params++
+		case token == tokens[i]:
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens)), params
+}
+
+// hasVariable reports whether a token looks like a variable value
+func hasVariable(token string) bool {
+	if strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">") {
+		return true
+	}
+	return strings.ContainsAny(token, "0123456789")
+}
+
+// templateID derives a cluster ID from the template it started with, so the
+// same first message always yields the same ID
+func templateID(tokens []string) string {
+	h := fnv.New64a()
+	for _, token := range tokens {
+		h.Write([]byte(token))
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
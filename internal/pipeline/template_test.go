@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func minedEntry(m *TemplateMiner, message string) *models.LogEntry {
+	entry := models.NewLogEntry()
+	entry.Message = message
+	m.Apply(entry)
+	return entry
+}
+
+func TestTemplateMiner_NumericIDsShareTemplate(t *testing.T) {
+	m := NewTemplateMiner(TemplateMinerOptions{})
+
+	a := minedEntry(m, "User 1042 logged in from 10.0.0.7")
+	b := minedEntry(m, "User 77 logged in from 192.168.1.20")
+
+	if a.Fields[FieldTemplateID] != b.Fields[FieldTemplateID] {
+		t.Errorf("Expected same template_id, got %v and %v", a.Fields[FieldTemplateID], b.Fields[FieldTemplateID])
+	}
+	if b.Fields[FieldTemplate] != "User <NUM> logged in from <IP>" {
+		t.Errorf("Unexpected template %q", b.Fields[FieldTemplate])
+	}
+	if a.Message != "User 1042 logged in from 10.0.0.7" {
+		t.Errorf("Expected message untouched, got %q", a.Message)
+	}
+}
+
+func TestTemplateMiner_Masks(t *testing.T) {
+	m := NewTemplateMiner(TemplateMinerOptions{})
+
+	entry := minedEntry(m, "request 3f2504e0-4f89-11d3-9a0c-0305e82c3301 took 12.5 ms at 0xdeadbeef")
+	expected := "request <UUID> took <NUM> ms at <HEX>"
+	if entry.Fields[FieldTemplate] != expected {
+		t.Errorf("Expected template %q, got %q", expected, entry.Fields[FieldTemplate])
+	}
+}
+
+func TestTemplateMiner_VariableWordsBecomeWildcards(t *testing.T) {
+	m := NewTemplateMiner(TemplateMinerOptions{})
+
+	a := minedEntry(m, "Connection closed by peer alice")
+	b := minedEntry(m, "Connection closed by peer bob")
+
+	if a.Fields[FieldTemplateID] != b.Fields[FieldTemplateID] {
+		t.Error("Expected messages differing in one word to share a template")
+	}
+	if b.Fields[FieldTemplate] != "Connection closed by peer <*>" {
+		t.Errorf("Unexpected template %q", b.Fields[FieldTemplate])
+	}
+	// The ID stays the same as the template generalizes
+	c := minedEntry(m, "Connection closed by peer carol")
+	if c.Fields[FieldTemplateID] != a.Fields[FieldTemplateID] {
+		t.Error("Expected template_id to be stable")
+	}
+}
+
+func TestTemplateMiner_DifferentMessages(t *testing.T) {
+	m := NewTemplateMiner(TemplateMinerOptions{})
+
+	tests := []string{
+		"Disk quota exceeded for volume data",
+		"Cache miss for key session",
+		"Disk quota exceeded",
+	}
+
+	ids := make(map[interface{}]string)
+	for _, message := range tests {
+		entry := minedEntry(m, message)
+		if other, ok := ids[entry.Fields[FieldTemplateID]]; ok {
+			t.Errorf("Expected %q and %q to have different templates", message, other)
+		}
+		ids[entry.Fields[FieldTemplateID]] = message
+	}
+	if m.Templates() != len(tests) {
+		t.Errorf("Expected %d templates, got %d", len(tests), m.Templates())
+	}
+}
+
+func TestTemplateMiner_MaxClusters(t *testing.T) {
+	m := NewTemplateMiner(TemplateMinerOptions{MaxClusters: 3})
+
+	for i := 0; i < 10; i++ {
+		minedEntry(m, fmt.Sprintf("event kind%c happened", 'a'+i))
+		minedEntry(m, fmt.Sprintf("completely different shape %c here now", 'a'+i))
+	}
+
+	if m.Templates() > 3 {
+		t.Errorf("Expected at most 3 templates, got %d", m.Templates())
+	}
+}
+
+func TestTemplateMiner_MaxChildren(t *testing.T) {
+	m := NewTemplateMiner(TemplateMinerOptions{MaxChildren: 2, Similarity: 1})
+
+	for _, word := range []string{"alpha", "beta", "gamma", "delta"} {
+		minedEntry(m, word+" started")
+	}
+
+	if n := len(m.root.children["2"].children); n > 2 {
+		t.Errorf("Expected at most 2 children, got %d", n)
+	}
+}
+
+func TestTemplateMiner_Run(t *testing.T) {
+	m := NewTemplateMiner(TemplateMinerOptions{})
+
+	in := make(chan *models.LogEntry, 2)
+	out := make(chan *models.LogEntry, 2)
+
+	in <- &models.LogEntry{Message: "job 1 done"}
+	in <- &models.LogEntry{Message: "job 2 done"}
+	close(in)
+
+	done := make(chan struct{})
+	go func() {
+		m.Run(context.Background(), in, out)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after input closed")
+	}
+
+	var ids []interface{}
+	for entry := range out {
+		ids = append(ids, entry.Fields[FieldTemplateID])
+	}
+	if len(ids) != 2 || ids[0] != ids[1] {
+		t.Errorf("Expected 2 entries with the same template, got %v", ids)
+	}
+}
+
+func TestTemplateMiner_ManyMessageLengths(t *testing.T) {
+	m := NewTemplateMiner(TemplateMinerOptions{MaxChildren: 2})
+
+	// Lengths beyond MaxChildren share the wildcard length node
+	message := "word"
+	var first interface{}
+	for i := 0; i < 5; i++ {
+		entry := minedEntry(m, message)
+		if i == 0 {
+			first = entry.Fields[FieldTemplateID]
+		} else if entry.Fields[FieldTemplateID] == first {
+			t.Errorf("Expected %q to get its own template", message)
+		}
+		message += " word"
+	}
+}
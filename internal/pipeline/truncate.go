@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// Fields set on an entry whose message was cut, the same ones the syslog
+// receiver sets on messages over its size limit
+const (
+	FieldTruncated      = "truncated"
+	FieldOriginalLength = "original_length"
+)
+
+// DefaultTruncateMarker is appended to a truncated message
+const DefaultTruncateMarker = "…[truncated %d bytes]"
+
+// TruncatorOptions configures a Truncator
+type TruncatorOptions struct {
+	// MaxBytes is the longest message kept, not counting the marker
+	MaxBytes int
+
+	// Marker is appended to a cut message, with any %d replaced by the
+	// number of bytes cut. Defaults to DefaultTruncateMarker.
+	Marker string
+}
+
+// Truncator cuts messages longer than a byte limit, such as stack dumps or
+// base64 blobs, so they can't blow memory budgets or sinks' size limits.
+// Messages are cut on a rune boundary, the original length goes into
+// Fields.
+type Truncator struct {
+	opts TruncatorOptions
+}
+
+// NewTruncator creates a truncator
+func NewTruncator(opts TruncatorOptions) (*Truncator, error) {
+	if opts.MaxBytes <= 0 {
+		return nil, fmt.Errorf("max_bytes: must be positive, got %d", opts.MaxBytes)
+	}
+	if opts.Marker == "" {
+		opts.Marker = DefaultTruncateMarker
+	}
+	return &Truncator{opts: opts}, nil
+}
+
+// Apply cuts the entry's message if it is over the limit
+func (t *Truncator) Apply(entry *models.LogEntry) {
+	if len(entry.Message) <= t.opts.MaxBytes {
+		return
+	}
+	kept := truncateString(entry.Message, t.opts.MaxBytes)
+	cut := len(entry.Message) - len(kept)
+
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]interface{})
+	}
+	// A message cut before, e.g. by the syslog receiver, keeps the length
+	// it had first
+	if _, ok := entry.Fields[FieldOriginalLength]; !ok {
+		entry.Fields[FieldOriginalLength] = len(entry.Message)
+	}
+	entry.Fields[FieldTruncated] = true
+
+	// Concatenating copies what is kept, so the long original can be freed
+	entry.Message = kept + strings.ReplaceAll(t.opts.Marker, "%d", strconv.Itoa(cut))
+}
+
+// Stage returns the truncator as a pipeline stage
+func (t *Truncator) Stage() Stage {
+	return Transform(t.Apply)
+}
+
+// truncateString returns the first n bytes of s, backing off so a
+// multi-byte rune is never split
+func truncateString(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
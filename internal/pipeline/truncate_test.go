@@ -0,0 +1,95 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+func TestTruncator(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		maxBytes int
+		marker   string
+		expected string
+		original int // 0 when the message is kept whole
+	}{
+		{"under the limit", "short", 10, "", "short", 0},
+		{"at the limit", "exactly10!", 10, "", "exactly10!", 0},
+		{"ascii", "abcdefghijkl", 5, "", "abcde…[truncated 7 bytes]", 12},
+		// é is 2 bytes: a cut in its middle backs off to before it
+		{"two-byte rune at the boundary", "abcdé", 5, "", "abcd…[truncated 2 bytes]", 6},
+		{"two-byte rune ending at the limit", "abcdéf", 6, "", "abcdé…[truncated 1 bytes]", 7},
+		// € is 3 bytes, 😀 is 4
+		{"three-byte rune", "€€€", 4, "", "€…[truncated 6 bytes]", 9},
+		{"four-byte rune", "a😀b", 4, "", "a…[truncated 5 bytes]", 6},
+		{"first rune over the limit", "😀😀", 3, "", "…[truncated 8 bytes]", 8},
+		{"custom marker", "abcdefghij", 4, " [+%d]", "abcd [+6]", 10},
+		{"marker without count", "abcdefghij", 4, "...", "abcd...", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncator, err := NewTruncator(TruncatorOptions{MaxBytes: tt.maxBytes, Marker: tt.marker})
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := models.NewLogEntry()
+			entry.Message = tt.message
+			if _, keep := truncator.Stage()(entry); !keep {
+				t.Fatal("Expected the stage to keep the entry")
+			}
+
+			if entry.Message != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, entry.Message)
+			}
+			if !utf8.ValidString(entry.Message) {
+				t.Errorf("Expected valid UTF-8, got %q", entry.Message)
+			}
+			if tt.original == 0 {
+				if _, ok := entry.Fields[FieldTruncated]; ok {
+					t.Error("Expected no truncation fields on a message kept whole")
+				}
+				return
+			}
+			if entry.Fields[FieldTruncated] != true || entry.Fields[FieldOriginalLength] != tt.original {
+				t.Errorf("Expected truncated with original length %d, got %v", tt.original, entry.Fields)
+			}
+		})
+	}
+}
+
+func TestTruncator_KeepsFirstOriginalLength(t *testing.T) {
+	truncator, err := NewTruncator(TruncatorOptions{MaxBytes: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cut before by a source with a larger limit
+	entry := &models.LogEntry{
+		Message: strings.Repeat("x", 20),
+		Fields:  map[string]interface{}{FieldTruncated: true, FieldOriginalLength: 100},
+	}
+	truncator.Apply(entry)
+	if entry.Fields[FieldOriginalLength] != 100 {
+		t.Errorf("Expected the first original length kept, got %v", entry.Fields[FieldOriginalLength])
+	}
+
+	// Entries without fields get them
+	entry = &models.LogEntry{Message: strings.Repeat("x", 20)}
+	truncator.Apply(entry)
+	if entry.Fields[FieldOriginalLength] != 20 {
+		t.Errorf("Expected original length 20, got %v", entry.Fields)
+	}
+}
+
+func TestNewTruncator_Invalid(t *testing.T) {
+	for _, max := range []int{0, -1} {
+		if _, err := NewTruncator(TruncatorOptions{MaxBytes: max}); err == nil {
+			t.Errorf("Expected an error for max_bytes %d", max)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+// OnAck registers a callback to run once the entry has been handled: written
+// and flushed by every sink, or dropped on purpose by a filter, sampler or
+// rate limit. Sources use it to advance a checkpoint only past entries that
+// are safe. Entries from sources that don't register one ack as a no-op.
+func (e *LogEntry) OnAck(fn func()) {
+	e.acks = append(e.acks, fn)
+}
+
+// Ack runs the entry's ack callbacks. Only the first call has any effect.
+func (e *LogEntry) Ack() {
+	acks := e.acks
+	e.acks = nil
+	for _, fn := range acks {
+		fn()
+	}
+}
+
+// MergeAcks moves other's ack callbacks onto e, for stages that fold one
+// entry into another. Acking e then acks both.
+func (e *LogEntry) MergeAcks(other *LogEntry) {
+	if other == e {
+		return
+	}
+	e.acks = append(e.acks, other.acks...)
+	other.acks = nil
+}
+
+// TakeAcks moves the entry's ack callbacks into a func that runs them, for
+// acking it later without holding on to the entry. It returns nil if there
+// are none.
+func (e *LogEntry) TakeAcks() func() {
+	if len(e.acks) == 0 {
+		return nil
+	}
+	held := &LogEntry{acks: e.acks}
+	e.acks = nil
+	return held.Ack
+}
@@ -0,0 +1,62 @@
+package models
+
+import "testing"
+
+func TestLogEntry_Ack(t *testing.T) {
+	acks := 0
+	entry := NewLogEntry()
+	entry.OnAck(func() { acks++ })
+	entry.OnAck(func() { acks++ })
+
+	entry.Ack()
+	entry.Ack()
+
+	if acks != 2 {
+		t.Errorf("Expected each callback to run once, got %d calls", acks)
+	}
+}
+
+func TestLogEntry_AckWithoutCallbacks(t *testing.T) {
+	// Entries from sources that don't ack must be safe to ack
+	NewLogEntry().Ack()
+}
+
+func TestLogEntry_MergeAcks(t *testing.T) {
+	var acked []string
+	first, second := NewLogEntry(), NewLogEntry()
+	first.OnAck(func() { acked = append(acked, "first") })
+	second.OnAck(func() { acked = append(acked, "second") })
+
+	first.MergeAcks(second)
+	first.MergeAcks(first)
+	second.Ack()
+	if len(acked) != 0 {
+		t.Errorf("Expected the merged entry's acks to move, got %v", acked)
+	}
+
+	first.Ack()
+	if len(acked) != 2 || acked[0] != "first" || acked[1] != "second" {
+		t.Errorf("Expected both acks, got %v", acked)
+	}
+}
+
+func TestLogEntry_TakeAcks(t *testing.T) {
+	if NewLogEntry().TakeAcks() != nil {
+		t.Error("Expected nil for an entry without callbacks")
+	}
+
+	acks := 0
+	entry := NewLogEntry()
+	entry.OnAck(func() { acks++ })
+
+	ack := entry.TakeAcks()
+	entry.Ack()
+	if acks != 0 {
+		t.Errorf("Expected the taken acks not to run with the entry's, got %d calls", acks)
+	}
+	ack()
+	ack()
+	if acks != 1 {
+		t.Errorf("Expected the callback to run once, got %d calls", acks)
+	}
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ArrayMode says how arrays are treated when flattening fields
+type ArrayMode string
+
+const (
+	// ArraysIndex flattens array elements under their index, tags.0, tags.1
+	ArraysIndex ArrayMode = "index"
+
+	// ArraysKeep leaves arrays as values
+	ArraysKeep ArrayMode = "keep"
+)
+
+// FlattenOptions configures FlattenFields and ExpandFields
+type FlattenOptions struct {
+	// Separator joins the keys of nested maps. Defaults to ".".
+	Separator string
+
+	// Arrays says how arrays are treated. Defaults to ArraysIndex.
+	Arrays ArrayMode
+}
+
+func (o FlattenOptions) withDefaults() FlattenOptions {
+	if o.Separator == "" {
+		o.Separator = "."
+	}
+	if o.Arrays == "" {
+		o.Arrays = ArraysIndex
+	}
+	return o
+}
+
+// ParseArrayMode validates an array mode name. Empty means ArraysIndex.
+func ParseArrayMode(s string) (ArrayMode, error) {
+	switch mode := ArrayMode(strings.ToLower(s)); mode {
+	case "":
+		return ArraysIndex, nil
+	case ArraysIndex, ArraysKeep:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported array mode %q (expected index or keep)", s)
+	}
+}
+
+// FlattenFields turns nested maps, such as decoded JSON objects, into
+// separator-joined keys: {"http": {"status": 200}} becomes
+// {"http.status": 200}. Empty maps and arrays are kept as values so
+// ExpandFields can restore them. When a key that already contains the
+// separator collides with a flattened one, the existing key wins.
+func FlattenFields(fields map[string]interface{}, opts FlattenOptions) map[string]interface{} {
+	opts = opts.withDefaults()
+	flat := make(map[string]interface{}, len(fields))
+	for _, key := range sortedKeys(fields) {
+		flattenValue(flat, key, fields[key], opts)
+	}
+	return flat
+}
+
+func flattenValue(flat map[string]interface{}, key string, value interface{}, opts FlattenOptions) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			for _, k := range sortedKeys(v) {
+				flattenValue(flat, key+opts.Separator+k, v[k], opts)
+			}
+			return
+		}
+	case []interface{}:
+		if len(v) > 0 && opts.Arrays == ArraysIndex {
+			for i, item := range v {
+				flattenValue(flat, key+opts.Separator+strconv.Itoa(i), item, opts)
+			}
+			return
+		}
+	}
+	flat[key] = value
+}
+
+// ExpandFields is the inverse of FlattenFields, nesting keys on the
+// separator. With ArraysIndex, maps whose keys are exactly 0 to n-1 become
+// arrays. A key whose path runs into a value that isn't a map is left as
+// it is.
+func ExpandFields(fields map[string]interface{}, opts FlattenOptions) map[string]interface{} {
+	opts = opts.withDefaults()
+	nested := make(map[string]interface{}, len(fields))
+
+	for _, key := range sortedKeys(fields) {
+		// Copied so expanding later keys into it leaves the input alone
+		value := copyMaps(fields[key])
+		if !insertPath(nested, strings.Split(key, opts.Separator), value) {
+			nested[key] = value
+		}
+	}
+
+	if opts.Arrays == ArraysIndex {
+		for key, value := range nested {
+			nested[key] = toArrays(value)
+		}
+	}
+	return nested
+}
+
+// insertPath sets value at the path, creating maps on the way. It reports
+// false when something other than a map is in the way.
+func insertPath(m map[string]interface{}, path []string, value interface{}) bool {
+	for _, part := range path[:len(path)-1] {
+		next, ok := m[part]
+		if !ok {
+			child := make(map[string]interface{})
+			m[part] = child
+			m = child
+			continue
+		}
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		m = child
+	}
+
+	last := path[len(path)-1]
+	if existing, ok := m[last]; ok {
+		// Keep nested values already expanded under this key
+		if _, isMap := existing.(map[string]interface{}); isMap {
+			return false
+		}
+	}
+	m[last] = value
+	return true
+}
+
+// toArrays turns maps keyed 0 to n-1 into arrays, depth first
+func toArrays(value interface{}) interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	for key, child := range m {
+		m[key] = toArrays(child)
+	}
+
+	if len(m) == 0 {
+		return m
+	}
+	items := make([]interface{}, len(m))
+	for key, child := range m {
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(m) || strconv.Itoa(i) != key {
+			return m
+		}
+		items[i] = child
+	}
+	return items
+}
+
+// copyMaps deep copies nested maps, sharing everything else
+func copyMaps(value interface{}) interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	copied := make(map[string]interface{}, len(m))
+	for key, child := range m {
+		copied[key] = copyMaps(child)
+	}
+	return copied
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// nestedFields is three levels deep, with arrays of values and of objects
+func nestedFields() map[string]interface{} {
+	return map[string]interface{}{
+		"user": "alice",
+		"http": map[string]interface{}{
+			"status": float64(200),
+			"request": map[string]interface{}{
+				"method":  "GET",
+				"headers": map[string]interface{}{"accept": "*/*"},
+			},
+		},
+		"tags":  []interface{}{"a", "b"},
+		"spans": []interface{}{map[string]interface{}{"id": "x"}, map[string]interface{}{"id": "y"}},
+		"empty": map[string]interface{}{},
+		"none":  []interface{}{},
+	}
+}
+
+func TestFlattenFields(t *testing.T) {
+	flat := FlattenFields(nestedFields(), FlattenOptions{})
+	expected := map[string]interface{}{
+		"user":                        "alice",
+		"http.status":                 float64(200),
+		"http.request.method":         "GET",
+		"http.request.headers.accept": "*/*",
+		"tags.0":                      "a",
+		"tags.1":                      "b",
+		"spans.0.id":                  "x",
+		"spans.1.id":                  "y",
+		"empty":                       map[string]interface{}{},
+		"none":                        []interface{}{},
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("Expected %v, got %v", expected, flat)
+	}
+}
+
+func TestFlattenFields_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts FlattenOptions
+	}{
+		{"defaults", FlattenOptions{}},
+		{"underscore", FlattenOptions{Separator: "_"}},
+		{"arrays kept", FlattenOptions{Separator: "/", Arrays: ArraysKeep}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := nestedFields()
+			flat := FlattenFields(original, tt.opts)
+			for key, value := range flat {
+				if _, ok := value.(map[string]interface{}); ok && key != "empty" {
+					t.Errorf("Expected %s flattened, got %v", key, value)
+				}
+			}
+
+			expanded := ExpandFields(flat, tt.opts)
+			if !reflect.DeepEqual(expanded, original) {
+				t.Errorf("Expected the round trip to restore %v, got %v", original, expanded)
+			}
+			if !reflect.DeepEqual(original, nestedFields()) {
+				t.Error("Expected the input left alone")
+			}
+		})
+	}
+}
+
+func TestFlattenFields_ArraysKeep(t *testing.T) {
+	flat := FlattenFields(map[string]interface{}{
+		"a": map[string]interface{}{"tags": []interface{}{"x"}},
+	}, FlattenOptions{Arrays: ArraysKeep})
+	if !reflect.DeepEqual(flat["a.tags"], []interface{}{"x"}) {
+		t.Errorf("Expected the array kept whole, got %v", flat)
+	}
+}
+
+func TestFlattenFields_Collisions(t *testing.T) {
+	// The key already written flat wins
+	flat := FlattenFields(map[string]interface{}{
+		"a.b": "flat",
+		"a":   map[string]interface{}{"b": "nested"},
+	}, FlattenOptions{})
+	if flat["a.b"] != "flat" || len(flat) != 1 {
+		t.Errorf("Expected the flat key to win, got %v", flat)
+	}
+
+	// A path that runs into a value stays flat rather than replacing it
+	expanded := ExpandFields(map[string]interface{}{
+		"a":   "value",
+		"a.b": "child",
+		"c.0": "first",
+		"c.2": "gap",
+	}, FlattenOptions{})
+	expected := map[string]interface{}{
+		"a":   "value",
+		"a.b": "child",
+		"c":   map[string]interface{}{"0": "first", "2": "gap"},
+	}
+	if !reflect.DeepEqual(expanded, expected) {
+		t.Errorf("Expected %v, got %v", expected, expanded)
+	}
+}
+
+func TestParseArrayMode(t *testing.T) {
+	if mode, err := ParseArrayMode(""); err != nil || mode != ArraysIndex {
+		t.Errorf("Expected index by default, got %q, %v", mode, err)
+	}
+	if mode, err := ParseArrayMode("KEEP"); err != nil || mode != ArraysKeep {
+		t.Errorf("Expected keep, got %q, %v", mode, err)
+	}
+	if _, err := ParseArrayMode("join"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
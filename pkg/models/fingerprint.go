@@ -0,0 +1,66 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+)
+
+// Parts of an entry that can go into its fingerprint. Any other name is
+// looked up in Fields.
+const (
+	FingerprintTimestamp = "timestamp"
+	FingerprintLevel     = "level"
+	FingerprintSource    = "source"
+	FingerprintMessage   = "message"
+)
+
+// DefaultFingerprintFields are the parts Fingerprint hashes
+var DefaultFingerprintFields = []string{FingerprintTimestamp, FingerprintLevel, FingerprintSource, FingerprintMessage}
+
+// Fingerprint returns a digest of the entry's timestamp, level, source and
+// message. Unlike ID it is the same for every copy of an event, so sinks
+// can use it to overwrite a redelivered entry rather than store it twice.
+func (e *LogEntry) Fingerprint() string {
+	return e.FingerprintOf(DefaultFingerprintFields...)
+}
+
+// FingerprintOf returns a digest of the given parts of the entry, in the
+// order given, e.g. leaving out the timestamp when a source stamps retries
+// with the time they were sent
+func (e *LogEntry) FingerprintOf(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		writePart(h, part)
+
+		switch part {
+		case FingerprintTimestamp:
+			writePart(h, strconv.FormatInt(e.Timestamp.UnixNano(), 10))
+		case FingerprintLevel:
+			writePart(h, string(e.Level))
+		case FingerprintSource:
+			writePart(h, e.Source)
+		case FingerprintMessage:
+			writePart(h, e.Message)
+		default:
+			// A missing field differs from an empty one
+			if value, ok := e.Fields[part]; ok {
+				h.Write([]byte{1})
+				writePart(h, fmt.Sprintf("%T:%v", value, value))
+			} else {
+				h.Write([]byte{0})
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// writePart writes s length-prefixed so adjacent parts can't run together
+func writePart(h hash.Hash, s string) {
+	var size [binary.MaxVarintLen64]byte
+	h.Write(size[:binary.PutUvarint(size[:], uint64(len(s)))])
+	h.Write([]byte(s))
+}
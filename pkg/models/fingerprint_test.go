@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func fingerprintEntry(message string) *LogEntry {
+	entry := NewLogEntry()
+	entry.Timestamp = time.Date(2024, 1, 15, 10, 30, 0, 123, time.UTC)
+	entry.Level = LevelError
+	entry.Source = "app"
+	entry.Message = message
+	return entry
+}
+
+func TestLogEntry_FingerprintSameEvent(t *testing.T) {
+	first := fingerprintEntry("disk full")
+	second := fingerprintEntry("disk full")
+	// A redelivered copy gets a new ID, a different receipt time and
+	// possibly another zone for the same instant
+	second.Timestamp = second.Timestamp.In(time.FixedZone("UTC+3", 3*3600))
+	second.Fields["attempt"] = 2
+
+	if first.ID == second.ID {
+		t.Fatal("Expected distinct IDs")
+	}
+	if first.Fingerprint() != second.Fingerprint() {
+		t.Errorf("Expected equal fingerprints, got %s and %s", first.Fingerprint(), second.Fingerprint())
+	}
+	if len(first.Fingerprint()) != 32 {
+		t.Errorf("Expected 32 hex digits, got %q", first.Fingerprint())
+	}
+}
+
+func TestLogEntry_FingerprintDiffers(t *testing.T) {
+	base := fingerprintEntry("disk full")
+	tests := []struct {
+		name   string
+		change func(*LogEntry)
+	}{
+		{"timestamp", func(e *LogEntry) { e.Timestamp = e.Timestamp.Add(time.Nanosecond) }},
+		{"level", func(e *LogEntry) { e.Level = LevelWarning }},
+		{"source", func(e *LogEntry) { e.Source = "api" }},
+		{"message", func(e *LogEntry) { e.Message = "disk ful" }},
+		// Moving text between parts must not produce the same input
+		{"shifted boundary", func(e *LogEntry) { e.Source, e.Message = "appdisk", " full" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := fingerprintEntry("disk full")
+			tt.change(entry)
+			if entry.Fingerprint() == base.Fingerprint() {
+				t.Errorf("Expected a different fingerprint after changing the %s", tt.name)
+			}
+		})
+	}
+
+	seen := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		fp := fingerprintEntry(fmt.Sprintf("request %d failed", i)).Fingerprint()
+		if prev, ok := seen[fp]; ok {
+			t.Fatalf("Entries %d and %d collide", prev, i)
+		}
+		seen[fp] = i
+	}
+}
+
+func TestLogEntry_FingerprintOf(t *testing.T) {
+	first := fingerprintEntry("disk full")
+	second := fingerprintEntry("disk full")
+	second.Timestamp = second.Timestamp.Add(time.Minute)
+
+	if first.Fingerprint() == second.Fingerprint() {
+		t.Error("Expected the retry's timestamp to change the default fingerprint")
+	}
+	parts := []string{FingerprintLevel, FingerprintSource, FingerprintMessage}
+	if first.FingerprintOf(parts...) != second.FingerprintOf(parts...) {
+		t.Error("Expected equal fingerprints without the timestamp")
+	}
+
+	// Other names come from Fields, telling missing from empty and types apart
+	first.Fields["request_id"] = "42"
+	second.Fields["request_id"] = 42
+	if first.FingerprintOf("request_id") == second.FingerprintOf("request_id") {
+		t.Error("Expected a string and an int field to differ")
+	}
+	second.Fields["request_id"] = ""
+	if fingerprintEntry("").FingerprintOf("request_id") == second.FingerprintOf("request_id") {
+		t.Error("Expected a missing field to differ from an empty one")
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// idGenerator produces ULIDs. The monotonic entropy source is not safe for
+// concurrent use, so every call goes through the mutex.
+var idGenerator = struct {
+	mu      sync.Mutex
+	entropy *ulid.MonotonicEntropy
+}{
+	entropy: ulid.Monotonic(rand.Reader, 0),
+}
+
+// newID returns a time-sortable ULID for the given timestamp
+func newID(t time.Time) string {
+	idGenerator.mu.Lock()
+	defer idGenerator.mu.Unlock()
+	return ulid.MustNew(ulid.Timestamp(t), idGenerator.entropy).String()
+}
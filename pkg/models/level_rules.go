@@ -0,0 +1,126 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LevelRule maps a keyword found in a message to a level
+type LevelRule struct {
+	Keyword string
+	Level   LogLevel
+}
+
+// LevelRules infers levels from keywords in a message. Rules are checked in
+// order and the first whose keyword appears anywhere in the text wins, so
+// "err" matches both "ERROR" and "stderr". Case is ignored.
+type LevelRules []LevelRule
+
+// DefaultLevelRules are the keywords sources guess levels from, most
+// severe first
+var DefaultLevelRules = LevelRules{
+	{Keyword: "crit", Level: LevelCritical},
+	{Keyword: "emerg", Level: LevelCritical},
+	{Keyword: "alert", Level: LevelCritical},
+	{Keyword: "err", Level: LevelError}, // error too
+	{Keyword: "warn", Level: LevelWarning},
+	{Keyword: "debug", Level: LevelDebug},
+}
+
+// Infer returns the level of the first rule matching text
+func (r LevelRules) Infer(text string) (LogLevel, bool) {
+	unicodeText := !isASCII(text)
+	if unicodeText {
+		// Lowering first maps İ to i, which case folding leaves apart
+		text = strings.ToLower(text)
+	}
+	for _, rule := range r {
+		var match bool
+		if unicodeText || !isASCII(rule.Keyword) {
+			match = containsFoldUnicode(text, rule.Keyword)
+		} else {
+			match = containsFold(text, rule.Keyword)
+		}
+		if match {
+			return rule.Level, true
+		}
+	}
+	return "", false
+}
+
+// containsFold reports whether text contains keyword, ignoring ASCII case.
+// Unlike lowering text first it doesn't allocate.
+func containsFold(text, keyword string) bool {
+	if keyword == "" {
+		return false
+	}
+	for i := 0; i+len(keyword) <= len(text); i++ {
+		j := 0
+		for j < len(keyword) && lowerASCII(text[i+j]) == lowerASCII(keyword[j]) {
+			j++
+		}
+		if j == len(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFoldUnicode is containsFold for text or keywords beyond ASCII.
+// Runes match under simple case folding, as in strings.EqualFold, so the
+// Kelvin sign matches k and ſ matches s.
+func containsFoldUnicode(text, keyword string) bool {
+	if keyword == "" {
+		return false
+	}
+	for i := range text {
+		if hasPrefixFold(text[i:], keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPrefixFold reports whether s starts with prefix under simple case
+// folding. Folded runes may differ in length, so they are compared one by
+// one.
+func hasPrefixFold(s, prefix string) bool {
+	for _, want := range prefix {
+		got, size := utf8.DecodeRuneInString(s)
+		if size == 0 || !equalFoldRune(got, want) {
+			return false
+		}
+		s = s[size:]
+	}
+	return true
+}
+
+// equalFoldRune reports whether a and b are the same letter ignoring case
+func equalFoldRune(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestLevelRules_Infer(t *testing.T) {
+	custom := LevelRules{
+		{Keyword: "timeout", Level: LevelWarning},
+		{Keyword: "FAILED", Level: LevelError},
+		{Keyword: "failed over", Level: LevelInfo},
+	}
+
+	tests := []struct {
+		name     string
+		rules    LevelRules
+		text     string
+		expected LogLevel
+		ok       bool
+	}{
+		{"critical before error", DefaultLevelRules, "critical error in worker", LevelCritical, true},
+		{"error before warning", DefaultLevelRules, "warning: error rate high", LevelError, true},
+		{"ignores case", DefaultLevelRules, "Disk WARN threshold", LevelWarning, true},
+		{"no keyword", DefaultLevelRules, "request served", "", false},
+		{"custom keyword", custom, "upstream timeout", LevelWarning, true},
+		{"custom keyword case", custom, "job failed", LevelError, true},
+		{"earlier custom rule wins", custom, "primary failed over", LevelError, true},
+		{"default keywords unused", custom, "critical error", "", false},
+		{"kelvin sign", LevelRules{{Keyword: "killed", Level: LevelError}}, "worker \u212AILLED by OOM", LevelError, true},
+		{"dotted capital i", DefaultLevelRules, "CRİTİCAL: disk full", LevelCritical, true},
+		{"long s", LevelRules{{Keyword: "stack", Level: LevelError}}, "ſtack overflow", LevelError, true},
+		{"non-ASCII keyword", LevelRules{{Keyword: "ошибка", Level: LevelError}}, "ОШИБКА: диск", LevelError, true},
+		{"non-ASCII text without keyword", DefaultLevelRules, "запрос обслужен", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, ok := tt.rules.Infer(tt.text)
+			if level != tt.expected || ok != tt.ok {
+				t.Errorf("Expected %q (%v), got %q (%v)", tt.expected, tt.ok, level, ok)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -15,6 +17,54 @@ const (
 	LevelCritical LogLevel = "CRITICAL"
 )
 
+// String returns the level name
+func (l LogLevel) String() string {
+	return string(l)
+}
+
+// Severity returns the numeric severity of the level (DEBUG=0 ... CRITICAL=4).
+// Unknown levels return -1 so they never satisfy AtLeast.
+func (l LogLevel) Severity() int {
+	switch l {
+	case LevelDebug:
+		return 0
+	case LevelInfo:
+		return 1
+	case LevelWarning:
+		return 2
+	case LevelError:
+		return 3
+	case LevelCritical:
+		return 4
+	default:
+		return -1
+	}
+}
+
+// AtLeast reports whether l is as severe as other or more
+func (l LogLevel) AtLeast(other LogLevel) bool {
+	return l.Severity() >= 0 && l.Severity() >= other.Severity()
+}
+
+// ParseLevel converts a level name into a LogLevel.
+// Matching is case-insensitive and accepts common aliases (WARN, CRIT, ...).
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEBUG", "TRACE":
+		return LevelDebug, nil
+	case "INFO", "INFORMATION", "NOTICE":
+		return LevelInfo, nil
+	case "WARNING", "WARN":
+		return LevelWarning, nil
+	case "ERROR", "ERR":
+		return LevelError, nil
+	case "CRITICAL", "CRIT", "FATAL", "EMERG", "ALERT", "PANIC":
+		return LevelCritical, nil
+	default:
+		return "", fmt.Errorf("unknown log level: %q", s)
+	}
+}
+
 // LogEntry represents a single log entry
 type LogEntry struct {
 	ID        string                 `json:"id"`
@@ -23,13 +73,24 @@ type LogEntry struct {
 	Source    string                 `json:"source"`
 	Message   string                 `json:"message"`
 	Fields    map[string]interface{} `json:"fields,omitempty"`
+
+	// ReceivedAt is when the collector created the entry. Unlike Timestamp it
+	// is never replaced by a parsed event time, so it can measure latency.
+	ReceivedAt time.Time `json:"-"`
+
+	// acks run once the entry has been handled, see OnAck
+	acks []func()
 }
 
 // NewLogEntry creates a new log entry with defaults
 func NewLogEntry() *LogEntry {
+	now := time.Now()
 	return &LogEntry{
-		Timestamp: time.Now(),
+		ID:        newID(now),
+		Timestamp: now,
 		Level:     LevelInfo,
 		Fields:    make(map[string]interface{}),
+
+		ReceivedAt: now,
 	}
 }
@@ -1,8 +1,11 @@
 package models
 
 import (
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/oklog/ulid/v2"
 )
 
 func TestNewLogEntry(t *testing.T) {
@@ -50,3 +53,140 @@ func TestLogLevelConstants(t *testing.T) {
 		})
 	}
 }
+
+func TestNewLogEntry_UniqueID(t *testing.T) {
+	first := NewLogEntry()
+	second := NewLogEntry()
+
+	if first.ID == "" || second.ID == "" {
+		t.Fatal("ID should be set")
+	}
+
+	if first.ID == second.ID {
+		t.Errorf("Expected different IDs, both were %s", first.ID)
+	}
+
+	if len(first.ID) != 26 {
+		t.Errorf("Expected 26 char ULID, got %d chars (%s)", len(first.ID), first.ID)
+	}
+
+	if _, err := ulid.ParseStrict(first.ID); err != nil {
+		t.Errorf("ID is not a valid ULID: %v", err)
+	}
+
+	// IDs should sort the same way entries were created
+	if first.ID >= second.ID {
+		t.Errorf("Expected %s < %s", first.ID, second.ID)
+	}
+}
+
+func TestNewLogEntry_ConcurrentIDs(t *testing.T) {
+	const workers = 8
+	const perWorker = 500
+
+	var mu sync.Mutex
+	seen := make(map[string]bool, workers*perWorker)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				id := NewLogEntry().ID
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != workers*perWorker {
+		t.Errorf("Expected %d unique IDs, got %d", workers*perWorker, len(seen))
+	}
+}
+
+func TestLogLevel_Severity(t *testing.T) {
+	tests := []struct {
+		level    LogLevel
+		expected int
+	}{
+		{LevelDebug, 0},
+		{LevelInfo, 1},
+		{LevelWarning, 2},
+		{LevelError, 3},
+		{LevelCritical, 4},
+		{LogLevel("BOGUS"), -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.level), func(t *testing.T) {
+			if got := tt.level.Severity(); got != tt.expected {
+				t.Errorf("Expected severity %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLogLevel_AtLeast(t *testing.T) {
+	tests := []struct {
+		level    LogLevel
+		min      LogLevel
+		expected bool
+	}{
+		{LevelError, LevelWarning, true},
+		{LevelWarning, LevelWarning, true},
+		{LevelInfo, LevelWarning, false},
+		{LevelDebug, LevelDebug, true},
+		{LevelCritical, LevelDebug, true},
+		{LogLevel("BOGUS"), LevelDebug, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.level)+">="+string(tt.min), func(t *testing.T) {
+			if got := tt.level.AtLeast(tt.min); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected LogLevel
+		wantErr  bool
+	}{
+		{"DEBUG", LevelDebug, false},
+		{"info", LevelInfo, false},
+		{"WARNING", LevelWarning, false},
+		{"WARN", LevelWarning, false},
+		{"warn", LevelWarning, false},
+		{"ERROR", LevelError, false},
+		{"CRITICAL", LevelCritical, false},
+		{"CRIT", LevelCritical, false},
+		{" error ", LevelError, false},
+		{"", "", true},
+		{"VERBOSE", "", true},
+		{"123", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			level, err := ParseLevel(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got level %s", tt.input, level)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if level != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, level)
+			}
+		})
+	}
+}
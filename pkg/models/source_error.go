@@ -0,0 +1,71 @@
+package models
+
+// SourceErrorCode classifies why a source failed
+type SourceErrorCode int
+
+// Source error codes
+const (
+	// BindFailed means the source couldn't listen on its address, for
+	// example because the port is in use or permission was denied
+	BindFailed SourceErrorCode = iota + 1
+
+	// ResolveFailed means the source's address couldn't be resolved
+	ResolveFailed
+
+	// AlreadyRunning means Start was called on a running source
+	AlreadyRunning
+
+	// Unsupported means the source was configured with an unsupported
+	// protocol, format or pattern
+	Unsupported
+
+	// IOError means reading the source's input failed
+	IOError
+)
+
+// String returns the code's name
+func (c SourceErrorCode) String() string {
+	switch c {
+	case BindFailed:
+		return "bind_failed"
+	case ResolveFailed:
+		return "resolve_failed"
+	case AlreadyRunning:
+		return "already_running"
+	case Unsupported:
+		return "unsupported"
+	case IOError:
+		return "io_error"
+	default:
+		return "unknown"
+	}
+}
+
+// SourceError is returned by sources that fail to start, so callers can
+// tell a busy port from a bad config with errors.As
+type SourceError struct {
+	Code SourceErrorCode
+	Err  error
+}
+
+// Error returns the underlying error's message
+func (e *SourceError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether starting the source again may succeed without
+// changing its config. A bound port may be freed and a file may appear, but
+// an unsupported setting stays unsupported.
+func (e *SourceError) Retryable() bool {
+	switch e.Code {
+	case BindFailed, ResolveFailed, IOError:
+		return true
+	default:
+		return false
+	}
+}
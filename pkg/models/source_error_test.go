@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSourceError_Unwrap(t *testing.T) {
+	cause := errors.New("address already in use")
+	err := fmt.Errorf("http:127.0.0.1:8080: %w", &SourceError{Code: BindFailed, Err: cause})
+
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) {
+		t.Fatal("Expected errors.As to find the SourceError")
+	}
+	if sourceErr.Code != BindFailed {
+		t.Errorf("Expected BindFailed, got %s", sourceErr.Code)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected the cause to be reachable")
+	}
+	if sourceErr.Error() != cause.Error() {
+		t.Errorf("Expected message %q, got %q", cause.Error(), sourceErr.Error())
+	}
+}
+
+func TestSourceError_Retryable(t *testing.T) {
+	tests := []struct {
+		code     SourceErrorCode
+		expected bool
+	}{
+		{BindFailed, true},
+		{ResolveFailed, true},
+		{IOError, true},
+		{AlreadyRunning, false},
+		{Unsupported, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			err := &SourceError{Code: tt.code, Err: errors.New("boom")}
+			if got := err.Retryable(); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// SourceStats is a snapshot of a source's activity
+type SourceStats struct {
+	EntriesProduced int64     `json:"entries_produced"`
+	BytesRead       int64     `json:"bytes_read"`
+	LastActivity    time.Time `json:"last_activity"` // zero if nothing was received yet
+	Errors          int64     `json:"errors"`
+
+	// Connection counts, only reported by connection-oriented sources
+	ActiveConnections   int64 `json:"active_connections,omitempty"`
+	RejectedConnections int64 `json:"rejected_connections,omitempty"`
+
+	// Denied counts connections, datagrams and requests from clients
+	// outside the receiver's allowlist
+	Denied int64 `json:"denied,omitempty"`
+
+	// Restarts counts how often a supervisor restarted the source
+	Restarts int64 `json:"restarts,omitempty"`
+}
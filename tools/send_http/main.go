@@ -23,6 +23,8 @@ func main() {
 		sendBatch()
 	case "health":
 		checkHealth()
+	case "replay":
+		replayFile()
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -173,10 +175,12 @@ func printUsage() {
 	fmt.Println("  single  - Send a single log entry")
 	fmt.Println("  batch   - Send multiple log entries")
 	fmt.Println("  health  - Check server health")
+	fmt.Println("  replay  - Stream a JSONL file of log entries to /batch")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  send_http single localhost:8080 ERROR 'Connection failed'")
 	fmt.Println("  send_http single localhost:8080 INFO 'User logged in'")
 	fmt.Println("  send_http batch localhost:8080")
 	fmt.Println("  send_http health localhost:8080")
+	fmt.Println("  send_http replay -batch-size 500 -rate 2000 localhost:8080 archive.jsonl")
 }
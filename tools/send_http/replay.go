@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// replayOptions configures a replay
+type replayOptions struct {
+	// URL is the /batch endpoint entries are posted to
+	URL string
+
+	// BatchSize is how many entries go in each request
+	BatchSize int
+
+	// Rate caps entries sent per second. Zero sends as fast as the
+	// receiver answers.
+	Rate float64
+
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// replayResult totals a replay
+type replayResult struct {
+	Batches  int
+	Sent     int
+	Accepted int
+	Failed   int // rejected or dropped by the receiver, or not valid JSON
+}
+
+// batchResponse is the part of the /batch response a replay counts
+type batchResponse struct {
+	Total    int `json:"total"`
+	Accepted int `json:"accepted"`
+}
+
+// maxLineSize is the longest JSONL line a replay reads
+const maxLineSize = 16 * 1024 * 1024
+
+// replay reads LogEntry objects, one JSON object per line, and posts them
+// to the batch endpoint. Lines are streamed, so only one batch is held in
+// memory whatever the size of the input. It stops at the first request that
+// can't be sent at all; batches the receiver refuses are counted as failed
+// and the replay goes on.
+func replay(ctx context.Context, r io.Reader, opts replayOptions) (replayResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	var result replayResult
+	var batch []json.RawMessage
+	start := time.Now()
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if opts.Rate > 0 {
+			// Pace by the total sent so far, so slow requests don't add up
+			due := start.Add(time.Duration(float64(result.Sent) / opts.Rate * float64(time.Second)))
+			select {
+			case <-time.After(time.Until(due)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		accepted, err := postBatch(ctx, opts, batch)
+		if err != nil {
+			return err
+		}
+		result.Batches++
+		result.Sent += len(batch)
+		result.Accepted += accepted
+		result.Failed += len(batch) - accepted
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if !json.Valid(text) {
+			fmt.Printf("⚠️  Skipping line %d: not valid JSON\n", line)
+			result.Failed++
+			continue
+		}
+
+		// The scanner reuses its buffer, so the line is copied out
+		batch = append(batch, append(json.RawMessage(nil), text...))
+		if len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read input: %w", err)
+	}
+	return result, flush()
+}
+
+// postBatch sends entries as one JSON array and returns how many the
+// receiver accepted
+func postBatch(ctx context.Context, opts replayOptions, batch []json.RawMessage) (int, error) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Accepted, partial and rejected batches all report their counts
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusMultiStatus, http.StatusBadRequest:
+		var counts batchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&counts); err == nil {
+			return counts.Accepted, nil
+		}
+	}
+	fmt.Printf("⚠️  Server returned status %d for a batch of %d\n", resp.StatusCode, len(batch))
+	return 0, nil
+}
+
+func replayFile() {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	batchSize := flags.Int("batch-size", 100, "entries per request")
+	rate := flags.Float64("rate", 0, "entries per second, 0 for no limit")
+	flags.Parse(os.Args[2:])
+
+	if flags.NArg() < 2 {
+		fmt.Println("Usage: send_http replay [-batch-size N] [-rate N] <address> <file.jsonl|->")
+		fmt.Println("Example: send_http replay -batch-size 500 -rate 2000 localhost:8080 archive.jsonl")
+		os.Exit(1)
+	}
+	if *batchSize <= 0 || *rate < 0 {
+		fmt.Println("❌ -batch-size must be positive and -rate must not be negative")
+		os.Exit(1)
+	}
+
+	address := flags.Arg(0)
+	input := os.Stdin
+	if path := flags.Arg(1); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("❌ Failed to open file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	result, err := replay(ctx, input, replayOptions{
+		URL:       fmt.Sprintf("http://%s/batch", address),
+		BatchSize: *batchSize,
+		Rate:      *rate,
+	})
+	elapsed := time.Since(start)
+
+	fmt.Printf("📊 Replayed %d entries in %d batches in %s (%.0f/s)\n",
+		result.Sent, result.Batches, elapsed.Round(time.Millisecond), float64(result.Sent)/elapsed.Seconds())
+	fmt.Printf("   Accepted: %d, Failed: %d\n", result.Accepted, result.Failed)
+	if err != nil {
+		fmt.Printf("❌ Replay stopped: %v\n", err)
+		os.Exit(1)
+	}
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
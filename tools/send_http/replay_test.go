@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatihserhatturan/logflux/pkg/models"
+)
+
+// batchServer answers like the /batch endpoint, rejecting entries whose
+// message is "bad", and records the size of every batch
+type batchServer struct {
+	mu      sync.Mutex
+	sizes   []int
+	entries []models.LogEntry
+	status  int // forced status, 0 to answer normally
+}
+
+func (bs *batchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/batch" || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	var batch []models.LogEntry
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, "bad body", http.StatusBadRequest)
+		return
+	}
+
+	bs.mu.Lock()
+	bs.sizes = append(bs.sizes, len(batch))
+	bs.entries = append(bs.entries, batch...)
+	status := bs.status
+	bs.mu.Unlock()
+
+	if status != 0 {
+		http.Error(w, "unavailable", status)
+		return
+	}
+	accepted := 0
+	for _, entry := range batch {
+		if entry.Message != "bad" {
+			accepted++
+		}
+	}
+	code := http.StatusAccepted
+	if accepted < len(batch) {
+		code = http.StatusMultiStatus
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"total": len(batch), "accepted": accepted})
+}
+
+// jsonl renders messages as LogEntry lines
+func jsonl(t *testing.T, messages ...string) string {
+	t.Helper()
+
+	var b strings.Builder
+	for _, msg := range messages {
+		entry := models.NewLogEntry()
+		entry.Message = msg
+		line, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestReplay_BatchSizes(t *testing.T) {
+	bs := &batchServer{}
+	server := httptest.NewServer(bs)
+	defer server.Close()
+
+	var messages []string
+	for i := 0; i < 25; i++ {
+		messages = append(messages, fmt.Sprintf("message %d", i))
+	}
+
+	result, err := replay(context.Background(), strings.NewReader(jsonl(t, messages...)), replayOptions{
+		URL:       server.URL + "/batch",
+		BatchSize: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := replayResult{Batches: 3, Sent: 25, Accepted: 25}
+	if result != expected {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+	if fmt.Sprint(bs.sizes) != "[10 10 5]" {
+		t.Errorf("Expected batches of [10 10 5], got %v", bs.sizes)
+	}
+	for i, entry := range bs.entries {
+		if entry.Message != messages[i] {
+			t.Errorf("Entry %d: expected %q, got %q", i, messages[i], entry.Message)
+		}
+	}
+}
+
+func TestReplay_Failures(t *testing.T) {
+	bs := &batchServer{}
+	server := httptest.NewServer(bs)
+	defer server.Close()
+
+	// A line that isn't JSON is skipped, blank lines are ignored
+	input := jsonl(t, "one", "bad", "three") + "not json\n\n" + jsonl(t, "four")
+
+	result, err := replay(context.Background(), strings.NewReader(input), replayOptions{
+		URL:       server.URL + "/batch",
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := replayResult{Batches: 2, Sent: 4, Accepted: 3, Failed: 2}
+	if result != expected {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	// A batch refused outright fails whole, and the replay goes on
+	bs.status = http.StatusServiceUnavailable
+	result, err = replay(context.Background(), strings.NewReader(jsonl(t, "a", "b", "c")), replayOptions{
+		URL:       server.URL + "/batch",
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = replayResult{Batches: 2, Sent: 3, Failed: 3}
+	if result != expected {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestReplay_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL + "/batch"
+	server.Close()
+
+	if _, err := replay(context.Background(), strings.NewReader(jsonl(t, "lost")), replayOptions{URL: url}); err == nil {
+		t.Error("Expected an error when the receiver can't be reached")
+	}
+}
+
+func TestReplay_Rate(t *testing.T) {
+	bs := &batchServer{}
+	server := httptest.NewServer(bs)
+	defer server.Close()
+
+	// 30 entries at 200/s take at least 100ms to send the last batch of 10
+	var messages []string
+	for i := 0; i < 30; i++ {
+		messages = append(messages, "paced")
+	}
+	start := time.Now()
+	result, err := replay(context.Background(), strings.NewReader(jsonl(t, messages...)), replayOptions{
+		URL:       server.URL + "/batch",
+		BatchSize: 10,
+		Rate:      200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the rate limit to take at least 100ms, took %s", elapsed)
+	}
+	if result.Accepted != 30 {
+		t.Errorf("Expected 30 accepted, got %d", result.Accepted)
+	}
+}
+
+func TestReplay_Streams(t *testing.T) {
+	bs := &batchServer{}
+	server := httptest.NewServer(bs)
+	defer server.Close()
+
+	// Entries are written as the replay reads them, never all at once
+	const n = 5000
+	line := jsonl(t, "streamed")
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < n; i++ {
+			io.WriteString(pw, line)
+		}
+		pw.Close()
+	}()
+
+	result, err := replay(context.Background(), pr, replayOptions{
+		URL:       server.URL + "/batch",
+		BatchSize: 250,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != n || result.Accepted != n || result.Batches != n/250 {
+		t.Errorf("Expected %d entries in %d batches, got %+v", n, n/250, result)
+	}
+	for _, size := range bs.sizes {
+		if size != 250 {
+			t.Errorf("Expected batches of 250, got %d", size)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// facilities and severities name the parts of a syslog priority
+var (
+	facilities = map[string]int{
+		"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+		"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+		"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+		"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+	}
+	severities = map[string]int{
+		"emerg": 0, "alert": 1, "crit": 2, "err": 3,
+		"warning": 4, "notice": 5, "info": 6, "debug": 7,
+	}
+)
+
+// parsePriority combines a facility and severity, each a name or number,
+// into a syslog priority
+func parsePriority(facility, severity string) (int, error) {
+	f, err := lookupCode(facilities, facility, 23)
+	if err != nil {
+		return 0, fmt.Errorf("facility: %w", err)
+	}
+	s, err := lookupCode(severities, severity, 7)
+	if err != nil {
+		return 0, fmt.Errorf("severity: %w", err)
+	}
+	return f*8 + s, nil
+}
+
+func lookupCode(names map[string]int, value string, max int) (int, error) {
+	if code, ok := names[strings.ToLower(value)]; ok {
+		return code, nil
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 0 || code > max {
+		return 0, fmt.Errorf("unknown value %q", value)
+	}
+	return code, nil
+}
+
+// forwarderOptions configures a forwarder
+type forwarderOptions struct {
+	// Network is "udp" or "tcp"
+	Network string
+	Address string
+
+	// Priority is put in front of every message
+	Priority int
+
+	// Header adds an RFC 3164 timestamp, hostname and tag after the
+	// priority
+	Header   bool
+	Hostname string
+	Tag      string
+
+	// MaxBackoff caps the wait between TCP reconnection attempts
+	MaxBackoff time.Duration
+}
+
+// forwarder sends lines as syslog messages, one datagram each over UDP
+// and newline terminated over TCP
+type forwarder struct {
+	opts forwarderOptions
+	conn net.Conn
+	now  func() time.Time
+
+	// hungUp is closed once the receiver closes the TCP connection
+	hungUp chan struct{}
+}
+
+func newForwarder(opts forwarderOptions) *forwarder {
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	if opts.Tag == "" {
+		opts.Tag = "send_syslog"
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 5 * time.Second
+	}
+	return &forwarder{opts: opts, now: time.Now}
+}
+
+// format renders a line as a syslog message
+func (f *forwarder) format(line string) string {
+	if !f.opts.Header {
+		return fmt.Sprintf("<%d>%s", f.opts.Priority, line)
+	}
+	return fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+		f.opts.Priority, f.now().Format(time.Stamp), f.opts.Hostname, f.opts.Tag, os.Getpid(), line)
+}
+
+// send forwards one line. Over TCP a dropped connection is redialled,
+// backing off while the receiver is away, until ctx is done.
+func (f *forwarder) send(ctx context.Context, line string) error {
+	message := f.format(line)
+	if f.opts.Network == "tcp" {
+		message += "\n"
+	}
+
+	backoff := 100 * time.Millisecond
+	for {
+		err := f.write(message)
+		if err == nil {
+			return nil
+		}
+		if f.opts.Network != "tcp" {
+			return err
+		}
+
+		fmt.Printf("🔌 Connection to %s lost (%v), retrying in %s\n", f.opts.Address, err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		if backoff > f.opts.MaxBackoff {
+			backoff = f.opts.MaxBackoff
+		}
+	}
+}
+
+// write sends a message on the current connection, dialling first if
+// there is none or the receiver has closed it
+func (f *forwarder) write(message string) error {
+	if f.conn != nil && f.closedByReceiver() {
+		f.conn.Close()
+		f.conn = nil
+	}
+	if f.conn == nil {
+		conn, err := net.DialTimeout(f.opts.Network, f.opts.Address, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		f.conn = conn
+		if f.opts.Network == "tcp" {
+			f.watch(conn)
+		}
+	}
+
+	if _, err := io.WriteString(f.conn, message); err != nil {
+		f.conn.Close()
+		f.conn = nil
+		return err
+	}
+	return nil
+}
+
+// watch notices the receiver closing a TCP connection. Receivers never
+// write back, so a read only returns once the connection is gone. Without
+// it the first message after a hang up is written without an error and
+// lost.
+func (f *forwarder) watch(conn net.Conn) {
+	hungUp := make(chan struct{})
+	f.hungUp = hungUp
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(hungUp)
+	}()
+}
+
+// closedByReceiver reports whether the receiver has closed the TCP
+// connection
+func (f *forwarder) closedByReceiver() bool {
+	if f.hungUp == nil {
+		return false
+	}
+	select {
+	case <-f.hungUp:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the connection
+func (f *forwarder) Close() error {
+	if f.conn == nil {
+		return nil
+	}
+	return f.conn.Close()
+}
+
+// followOptions configures follow
+type followOptions struct {
+	// FromStart sends the lines already in the file before new ones
+	FromStart bool
+
+	// PollPeriod is how often the file is checked for new lines
+	PollPeriod time.Duration
+}
+
+// follow forwards each line appended to path until ctx is done. A file
+// that is truncated or replaced, as by rotation, is read again from the
+// start.
+func follow(ctx context.Context, path string, fw *forwarder, opts followOptions) error {
+	if opts.PollPeriod <= 0 {
+		opts.PollPeriod = 200 * time.Millisecond
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { file.Close() }()
+
+	var offset int64
+	if !opts.FromStart {
+		if offset, err = file.Seek(0, io.SeekEnd); err != nil {
+			return fmt.Errorf("failed to seek: %w", err)
+		}
+	}
+
+	reader := bufio.NewReader(file)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+		if err == nil {
+			line = strings.TrimRight(partial+line, "\r\n")
+			partial = ""
+			if line == "" {
+				continue
+			}
+			if err := fw.send(ctx, line); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != io.EOF {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		// Keep a line still being written until its newline arrives
+		partial += line
+
+		// Everything in the old file has been read by now, so switching
+		// loses nothing
+		reopen, err := replaced(file, path, offset)
+		if err != nil {
+			return err
+		}
+		if reopen {
+			file.Close()
+			if file, err = os.Open(path); err != nil {
+				return fmt.Errorf("failed to reopen file: %w", err)
+			}
+			reader.Reset(file)
+			offset, partial = 0, ""
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opts.PollPeriod):
+		}
+	}
+}
+
+// replaced reports whether the file at path is no longer the open one, or
+// has been truncated below what was read
+func replaced(file *os.File, path string, offset int64) (bool, error) {
+	current, err := os.Stat(path)
+	if err != nil {
+		// Between rotation and the new file being created
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+	open, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return !os.SameFile(open, current) || current.Size() < offset, nil
+}
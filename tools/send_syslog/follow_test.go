@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tcpReceiver collects newline-framed messages from every connection
+type tcpReceiver struct {
+	listener net.Listener
+	messages chan string
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newTCPReceiver(t *testing.T) *tcpReceiver {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &tcpReceiver{listener: listener, messages: make(chan string, 100)}
+	t.Cleanup(func() {
+		listener.Close()
+		r.dropAll()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			r.mu.Lock()
+			r.conns = append(r.conns, conn)
+			r.mu.Unlock()
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					r.messages <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	return r
+}
+
+// dropAll closes every connection accepted so far
+func (r *tcpReceiver) dropAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, conn := range r.conns {
+		conn.Close()
+	}
+}
+
+func (r *tcpReceiver) accepted() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.conns)
+}
+
+// expectMessages waits for each of expected to arrive in order
+func expectMessages(t *testing.T, messages <-chan string, expected ...string) {
+	t.Helper()
+	for _, want := range expected {
+		select {
+		case got := <-messages:
+			if got != want {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", want)
+		}
+	}
+}
+
+// startFollow follows path in the background until the test ends
+func startFollow(t *testing.T, path string, fw *forwarder, opts followOptions) {
+	t.Helper()
+
+	opts.PollPeriod = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- follow(ctx, path, fw, opts) }()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("follow failed: %v", err)
+		}
+		fw.Close()
+	})
+}
+
+func appendLines(t *testing.T, path string, text string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollow_TCP(t *testing.T) {
+	receiver := newTCPReceiver(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLines(t, path, "first\nsecond\n")
+
+	fw := newForwarder(forwarderOptions{
+		Network:  "tcp",
+		Address:  receiver.listener.Addr().String(),
+		Priority: 134,
+		Header:   true,
+		Hostname: "web1",
+		Tag:      "app",
+	})
+	fw.now = func() time.Time { return time.Date(2024, time.March, 5, 9, 4, 5, 0, time.UTC) }
+	startFollow(t, path, fw, followOptions{FromStart: true})
+
+	header := fmt.Sprintf("<134>Mar  5 09:04:05 web1 app[%d]: ", os.Getpid())
+	expectMessages(t, receiver.messages, header+"first", header+"second")
+
+	// A line is only sent once its newline is written
+	appendLines(t, path, "thi")
+	time.Sleep(50 * time.Millisecond)
+	appendLines(t, path, "rd\n\nfourth\n")
+	expectMessages(t, receiver.messages, header+"third", header+"fourth")
+}
+
+func TestFollow_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			messages <- string(buf[:n])
+		}
+	}()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLines(t, path, "already there\n")
+
+	fw := newForwarder(forwarderOptions{Network: "udp", Address: conn.LocalAddr().String(), Priority: 13})
+	startFollow(t, path, fw, followOptions{})
+
+	// Lines written before following began are skipped
+	time.Sleep(100 * time.Millisecond)
+	appendLines(t, path, "one\ntwo\nthree\n")
+	expectMessages(t, messages, "<13>one", "<13>two", "<13>three")
+}
+
+func TestFollow_Reconnect(t *testing.T) {
+	receiver := newTCPReceiver(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLines(t, path, "")
+
+	fw := newForwarder(forwarderOptions{Network: "tcp", Address: receiver.listener.Addr().String(), Priority: 13})
+	startFollow(t, path, fw, followOptions{FromStart: true})
+
+	appendLines(t, path, "before drop\n")
+	expectMessages(t, receiver.messages, "<13>before drop")
+
+	// The receiver hangs up, the next lines go over a new connection
+	receiver.dropAll()
+	time.Sleep(50 * time.Millisecond)
+	appendLines(t, path, "after drop\nand more\n")
+	expectMessages(t, receiver.messages, "<13>after drop", "<13>and more")
+	if n := receiver.accepted(); n != 2 {
+		t.Errorf("Expected 2 connections, got %d", n)
+	}
+}
+
+func TestFollow_Rotation(t *testing.T) {
+	receiver := newTCPReceiver(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLines(t, path, "")
+
+	fw := newForwarder(forwarderOptions{Network: "tcp", Address: receiver.listener.Addr().String(), Priority: 13})
+	startFollow(t, path, fw, followOptions{FromStart: true})
+
+	appendLines(t, path, "old file\n")
+	expectMessages(t, receiver.messages, "<13>old file")
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, path, "new file\n")
+	expectMessages(t, receiver.messages, "<13>new file")
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		facility string
+		severity string
+		expected int
+		wantErr  bool
+	}{
+		{"user", "notice", 13, false},
+		{"local0", "info", 134, false},
+		{"KERN", "emerg", 0, false},
+		{"4", "3", 35, false},
+		{"local9", "info", 0, true},
+		{"user", "8", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePriority(tt.facility, tt.severity)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePriority(%q, %q): expected error %v, got %v", tt.facility, tt.severity, tt.wantErr, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parsePriority(%q, %q): expected %d, got %d", tt.facility, tt.severity, tt.expected, got)
+		}
+	}
+}
@@ -1,22 +1,54 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
 	"time"
 )
 
 func main() {
-	if len(os.Args) < 4 {
-		fmt.Println("Usage: send_syslog <udp|tcp> <address> <message>")
-		fmt.Println("Example: send_syslog udp localhost:5140 \"<34>Test message\"")
+	flags := flag.NewFlagSet("send_syslog", flag.ExitOnError)
+	followPath := flags.String("follow", "", "file to tail, forwarding each new line as a message")
+	fromStart := flags.Bool("from-start", false, "with -follow, send the lines already in the file first")
+	facility := flags.String("facility", "user", "facility of followed lines, a name such as local0 or a number")
+	severity := flags.String("severity", "notice", "severity of followed lines, a name such as err or a number")
+	header := flags.Bool("header", false, "with -follow, add an RFC 3164 timestamp, hostname and tag")
+	tag := flags.String("tag", "send_syslog", "tag in the RFC 3164 header")
+	flags.Usage = printUsage
+	flags.Parse(os.Args[1:])
+
+	if *followPath != "" {
+		if flags.NArg() < 2 {
+			printUsage()
+			os.Exit(1)
+		}
+		priority, err := parsePriority(*facility, *severity)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		followFile(*followPath, *fromStart, forwarderOptions{
+			Network:  flags.Arg(0),
+			Address:  flags.Arg(1),
+			Priority: priority,
+			Header:   *header,
+			Tag:      *tag,
+		})
+		return
+	}
+
+	if flags.NArg() < 3 {
+		printUsage()
 		os.Exit(1)
 	}
 
-	protocol := os.Args[1]
-	address := os.Args[2]
-	message := os.Args[3]
+	protocol := flags.Arg(0)
+	address := flags.Arg(1)
+	message := flags.Arg(2)
 
 	switch protocol {
 	case "udp":
@@ -29,6 +61,32 @@ func main() {
 	}
 }
 
+func printUsage() {
+	fmt.Println("Usage: send_syslog <udp|tcp> <address> <message>")
+	fmt.Println("       send_syslog -follow <file> [-from-start] [-facility F] [-severity S] [-header] <udp|tcp> <address>")
+	fmt.Println("Example: send_syslog udp localhost:5140 \"<34>Test message\"")
+	fmt.Println("Example: send_syslog -follow /var/log/app.log -facility local0 -header tcp localhost:5140")
+}
+
+func followFile(path string, fromStart bool, opts forwarderOptions) {
+	if opts.Network != "udp" && opts.Network != "tcp" {
+		fmt.Printf("Unknown protocol: %s\n", opts.Network)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fw := newForwarder(opts)
+	defer fw.Close()
+
+	fmt.Printf("👀 Forwarding new lines of %s to %s %s\n", path, opts.Network, opts.Address)
+	if err := follow(ctx, path, fw, followOptions{FromStart: fromStart}); err != nil && ctx.Err() == nil {
+		fmt.Printf("❌ Error following: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func sendUDP(address, message string) {
 	conn, err := net.Dial("udp", address)
 	if err != nil {